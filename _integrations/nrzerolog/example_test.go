@@ -0,0 +1,29 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzerolog_test
+
+import (
+	"context"
+	"os"
+
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/nrzerolog"
+	"github.com/rs/zerolog"
+)
+
+func Example() {
+	cfg := newrelic.NewConfig("Example App", "__YOUR_NEWRELIC_LICENSE_KEY__")
+	cfg.DistributedTracer.Enabled = true
+	app, _ := newrelic.NewApplication(cfg)
+
+	// Install the hook on your zerolog logger:
+	logger := zerolog.New(os.Stdout).Hook(nrzerolog.Hook{})
+
+	txn := app.StartTransaction("example", nil, nil)
+	defer txn.End()
+
+	// Log with the transaction's context to add the linking metadata:
+	ctx := newrelic.NewContext(context.Background(), txn)
+	logger.Info().Ctx(ctx).Msg("Hello New Relic!")
+}
@@ -0,0 +1,100 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrzerolog links https://github.com/rs/zerolog logs with New Relic
+// transactions.
+//
+// Install a Hook on your logger to add the linking metadata of the current
+// transaction to each log line and to notice error, fatal, and panic level
+// logs as errors on the transaction:
+//
+//	logger := zerolog.New(os.Stdout).Hook(nrzerolog.Hook{})
+//
+// The Hook finds the transaction in the context of the log event.
+// Therefore, the Transaction must be added to a context and the context
+// passed to the logger.  For example, this logging call
+//
+//	logger.Info().Msg("Hello New Relic!")
+//
+// must be transformed to include the context, such as:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	logger.Info().Ctx(ctx).Msg("Hello New Relic!")
+//
+// Alternatively, use TransactionHook to create a logger for a single
+// transaction:
+//
+//	txnLogger := logger.Hook(nrzerolog.TransactionHook(txn))
+//	txnLogger.Info().Msg("Hello New Relic!")
+//
+// The linking metadata keys are those found in the `logcontext` package
+// (https://godoc.org/github.com/newrelic/go-agent/_integrations/logcontext/#pkg-constants).
+// For the best linking experience be sure to enable Distributed Tracing so
+// that the trace.id and span.id keys are added.
+//
+// Requires v1.29.0 of the zerolog package or newer.
+package nrzerolog
+
+import (
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/_integrations/logcontext"
+	"github.com/newrelic/go-agent/internal"
+	"github.com/rs/zerolog"
+)
+
+func init() { internal.TrackUsage("integration", "logcontext", "zerolog") }
+
+// Hook is a zerolog.Hook which adds the linking metadata of a transaction to
+// log events and notices error, fatal, and panic level log events as errors
+// on the transaction.  The zero value finds the transaction in the event's
+// context.
+type Hook struct {
+	txn newrelic.Transaction
+}
+
+// TransactionHook returns a Hook which uses the transaction given rather
+// than the transaction found in the event's context.
+func TransactionHook(txn newrelic.Transaction) Hook {
+	return Hook{txn: txn}
+}
+
+// Run implements zerolog.Hook.
+func (h Hook) Run(e *zerolog.Event, level zerolog.Level, msg string) {
+	txn := h.txn
+	if nil == txn {
+		txn = newrelic.FromContext(e.GetCtx())
+	}
+	if nil == txn {
+		return
+	}
+
+	md := txn.GetLinkingMetadata()
+	addField(e, logcontext.KeyTraceID, md.TraceID)
+	addField(e, logcontext.KeySpanID, md.SpanID)
+	addField(e, logcontext.KeyEntityName, md.EntityName)
+	addField(e, logcontext.KeyEntityType, md.EntityType)
+	addField(e, logcontext.KeyEntityGUID, md.EntityGUID)
+	addField(e, logcontext.KeyHostname, md.Hostname)
+
+	if noticeLevel(level) {
+		txn.NoticeError(newrelic.Error{
+			Message: msg,
+			Class:   "zerolog." + level.String(),
+		})
+	}
+}
+
+func addField(e *zerolog.Event, key, val string) {
+	if "" != val {
+		e.Str(key, val)
+	}
+}
+
+func noticeLevel(level zerolog.Level) bool {
+	switch level {
+	case zerolog.ErrorLevel, zerolog.FatalLevel, zerolog.PanicLevel:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrzerolog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"testing"
+
+	newrelic "github.com/newrelic/go-agent"
+	"github.com/newrelic/go-agent/internal"
+	"github.com/newrelic/go-agent/internal/integrationsupport"
+	"github.com/newrelic/go-agent/internal/sysinfo"
+	"github.com/rs/zerolog"
+)
+
+func decodeLine(t *testing.T, out *bytes.Buffer) map[string]interface{} {
+	var line map[string]interface{}
+	if err := json.Unmarshal(out.Bytes(), &line); nil != err {
+		t.Fatal("failed to unmarshal log output:", err)
+	}
+	return line
+}
+
+func TestHookNoTransaction(t *testing.T) {
+	out := &bytes.Buffer{}
+	logger := zerolog.New(out).Hook(Hook{})
+	logger.Info().Ctx(context.Background()).Msg("Hello World!")
+	line := decodeLine(t, out)
+	if len(line) != 2 || line["message"] != "Hello World!" {
+		t.Error(line)
+	}
+}
+
+func TestHookContext(t *testing.T) {
+	app := integrationsupport.NewTestApp(
+		func(reply *internal.ConnectReply) {
+			reply.AdaptiveSampler = internal.SampleEverything{}
+			reply.TraceIDGenerator = internal.NewTraceIDGenerator(12345)
+		},
+		func(cfg *newrelic.Config) {
+			cfg.DistributedTracer.Enabled = true
+			cfg.CrossApplicationTracer.Enabled = false
+		})
+	txn := app.StartTransaction("hello", nil, nil)
+	ctx := newrelic.NewContext(context.Background(), txn)
+	host, _ := sysinfo.Hostname()
+
+	out := &bytes.Buffer{}
+	logger := zerolog.New(out).Hook(Hook{})
+	logger.Info().Ctx(ctx).Msg("Hello World!")
+	txn.End()
+
+	line := decodeLine(t, out)
+	md := txn.GetLinkingMetadata()
+	for key, val := range map[string]interface{}{
+		"trace.id":    md.TraceID,
+		"span.id":     md.SpanID,
+		"entity.name": integrationsupport.SampleAppName,
+		"entity.type": "SERVICE",
+		"hostname":    host,
+	} {
+		if line[key] != val {
+			t.Errorf("value for key %s is incorrect: actual=%v expected=%v", key, line[key], val)
+		}
+	}
+	app.ExpectErrors(t, []internal.WantError{})
+}
+
+func TestHookNoticeErrors(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("hello", nil, nil)
+
+	out := &bytes.Buffer{}
+	logger := zerolog.New(out).Hook(TransactionHook(txn))
+	logger.Warn().Msg("careful")
+	logger.Error().Msg("oops")
+	txn.End()
+
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "oops",
+		Klass:   "zerolog.error",
+	}})
+}
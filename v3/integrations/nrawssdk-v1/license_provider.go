@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrawssdk
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+// SecretValueGetter is implemented by *secretsmanager.SecretsManager.
+type SecretValueGetter interface {
+	GetSecretValueWithContext(aws.Context, *secretsmanager.GetSecretValueInput, ...request.Option) (*secretsmanager.GetSecretValueOutput, error)
+}
+
+// LicenseProvider returns a newrelic.Config.LicenseProvider which reads the
+// license from the AWS Secrets Manager secret with the given id each time it
+// is called.  If key is empty, the secret's value is the license.  Otherwise
+// the secret's value is a JSON object and the license is the string value of
+// the given key:
+//
+//	client := secretsmanager.New(session.Must(session.NewSession()))
+//	app, err := newrelic.NewApplication(
+//		newrelic.ConfigAppName("Example App"),
+//		newrelic.ConfigLicenseProvider(nrawssdk.LicenseProvider(client, "prod/newrelic", "license_key")),
+//	)
+func LicenseProvider(client SecretValueGetter, secretID, key string) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		out, err := client.GetSecretValueWithContext(ctx, &secretsmanager.GetSecretValueInput{
+			SecretId: aws.String(secretID),
+		})
+		if nil != err {
+			return "", err
+		}
+		if nil == out.SecretString {
+			return "", fmt.Errorf("secret %s has no string value", secretID)
+		}
+		if "" == key {
+			return *out.SecretString, nil
+		}
+		var values map[string]interface{}
+		if err := json.Unmarshal([]byte(*out.SecretString), &values); nil != err {
+			return "", fmt.Errorf("unable to parse secret %s: %v", secretID, err)
+		}
+		license, ok := values[key].(string)
+		if !ok {
+			return "", fmt.Errorf("secret %s does not contain string key %q", secretID, key)
+		}
+		return license, nil
+	}
+}
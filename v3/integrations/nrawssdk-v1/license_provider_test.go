@@ -0,0 +1,56 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrawssdk
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/request"
+	"github.com/aws/aws-sdk-go/service/secretsmanager"
+)
+
+type fakeSecrets map[string]string
+
+func (f fakeSecrets) GetSecretValueWithContext(ctx aws.Context, in *secretsmanager.GetSecretValueInput, opts ...request.Option) (*secretsmanager.GetSecretValueOutput, error) {
+	out := &secretsmanager.GetSecretValueOutput{}
+	if value, ok := f[*in.SecretId]; ok {
+		out.SecretString = aws.String(value)
+	}
+	return out, nil
+}
+
+var _ SecretValueGetter = &secretsmanager.SecretsManager{}
+
+func TestLicenseProvider(t *testing.T) {
+	secrets := fakeSecrets{
+		"plain": "my-license",
+		"json":  `{"license_key":"my-license","other":1}`,
+	}
+	testcases := []struct {
+		id      string
+		key     string
+		license string
+		errMsg  string
+	}{
+		{id: "plain", license: "my-license"},
+		{id: "json", key: "license_key", license: "my-license"},
+		{id: "json", key: "other", errMsg: `secret json does not contain string key "other"`},
+		{id: "plain", key: "license_key", errMsg: "unable to parse secret plain: invalid character 'm' looking for beginning of value"},
+		{id: "missing", errMsg: "secret missing has no string value"},
+	}
+	for _, tc := range testcases {
+		license, err := LicenseProvider(secrets, tc.id, tc.key)(context.Background())
+		if "" != tc.errMsg {
+			if nil == err || err.Error() != tc.errMsg {
+				t.Error(tc.id, tc.key, err)
+			}
+			continue
+		}
+		if nil != err || license != tc.license {
+			t.Error(tc.id, tc.key, license, err)
+		}
+	}
+}
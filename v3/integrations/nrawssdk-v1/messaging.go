@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrawssdk
+
+import (
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/service/sns"
+	"github.com/aws/aws-sdk-go/service/sqs"
+	"github.com/newrelic/go-agent/v3/internal/awssupport"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// InsertSQSMessageAttributes adds the distributed tracing payload of the
+// Transaction to the message attributes of an SQS message and returns the
+// attributes.  Use it with sqs.SendMessageInput and
+// sqs.SendMessageBatchRequestEntry:
+//
+//	input := &sqs.SendMessageInput{
+//	    QueueUrl:    aws.String(queueURL),
+//	    MessageBody: aws.String("order 42"),
+//	}
+//	input.MessageAttributes = nrawssdk.InsertSQSMessageAttributes(txn, input.MessageAttributes)
+//
+// The payload uses up to three of the ten message attributes SQS allows,
+// and is not added if the message already has too many attributes.
+func InsertSQSMessageAttributes(txn *newrelic.Transaction, attrs map[string]*sqs.MessageAttributeValue) map[string]*sqs.MessageAttributeValue {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	dt := awssupport.DistributedTraceAttributes(txn, names)
+	if 0 == len(dt) {
+		return attrs
+	}
+	if nil == attrs {
+		attrs = make(map[string]*sqs.MessageAttributeValue, len(dt))
+	}
+	for name, value := range dt {
+		attrs[name] = &sqs.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	return attrs
+}
+
+// InsertSNSMessageAttributes adds the distributed tracing payload of the
+// Transaction to the message attributes of an SNS message and returns the
+// attributes.  Use it with sns.PublishInput:
+//
+//	input := &sns.PublishInput{
+//	    TopicArn: aws.String(topicARN),
+//	    Message:  aws.String("order 42"),
+//	}
+//	input.MessageAttributes = nrawssdk.InsertSNSMessageAttributes(txn, input.MessageAttributes)
+//
+// StartSQSTransaction accepts the payload of messages delivered to SQS
+// queues by SNS, with or without raw message delivery.
+func InsertSNSMessageAttributes(txn *newrelic.Transaction, attrs map[string]*sns.MessageAttributeValue) map[string]*sns.MessageAttributeValue {
+	names := make([]string, 0, len(attrs))
+	for name := range attrs {
+		names = append(names, name)
+	}
+	dt := awssupport.DistributedTraceAttributes(txn, names)
+	if 0 == len(dt) {
+		return attrs
+	}
+	if nil == attrs {
+		attrs = make(map[string]*sns.MessageAttributeValue, len(dt))
+	}
+	for name, value := range dt {
+		attrs[name] = &sns.MessageAttributeValue{
+			DataType:    aws.String("String"),
+			StringValue: aws.String(value),
+		}
+	}
+	return attrs
+}
+
+// StartSQSTransaction starts a transaction for a message received from the
+// SQS queue with the URL.  The transaction is named after the queue and
+// accepts the distributed tracing payload added by
+// InsertSQSMessageAttributes or InsertSNSMessageAttributes.  The message
+// attributes must be requested when receiving messages:
+//
+//	out, err := client.ReceiveMessage(&sqs.ReceiveMessageInput{
+//	    QueueUrl:              aws.String(queueURL),
+//	    MessageAttributeNames: []*string{aws.String("All")},
+//	})
+//	for _, msg := range out.Messages {
+//	    txn := nrawssdk.StartSQSTransaction(app, queueURL, msg)
+//	    // ... process the message ...
+//	    txn.End()
+//	}
+//
+// The caller must end the transaction.
+func StartSQSTransaction(app *newrelic.Application, queueURL string, msg *sqs.Message) *newrelic.Transaction {
+	if nil == msg {
+		return nil
+	}
+	attrs := make(map[string]string, len(msg.MessageAttributes))
+	for name, value := range msg.MessageAttributes {
+		if nil != value && nil != value.StringValue {
+			attrs[name] = *value.StringValue
+		}
+	}
+	return awssupport.StartSQSTransaction(app, queueURL, attrs, aws.StringValue(msg.Body))
+}
@@ -2,6 +2,10 @@
 // SPDX-License-Identifier: Apache-2.0
 
 // Package nrawssdk instruments https://github.com/aws/aws-sdk-go requests.
+//
+// Use InsertSQSMessageAttributes and InsertSNSMessageAttributes when sending
+// messages, and StartSQSTransaction when receiving them, so that distributed
+// traces continue through SQS queues and SNS topics.
 package nrawssdk
 
 import (
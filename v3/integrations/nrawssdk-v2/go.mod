@@ -8,5 +8,5 @@ require (
 	// v0.8.0 is the earliest aws-sdk-go-v2 version where
 	// dynamodb.DescribeTableRequest.Send takes a context.Context parameter.
 	github.com/aws/aws-sdk-go-v2 v0.8.0
-	github.com/newrelic/go-agent/v3 v3.0.0
+	github.com/newrelic/go-agent/v3 v3.13.0
 )
@@ -0,0 +1,105 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrawssdk
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sns"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+)
+
+const testQueueURL = "https://sqs.us-west-2.amazonaws.com/123456789012/orders"
+
+func messagingTestApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+}
+
+func TestSQSMessageAttributes(t *testing.T) {
+	app := messagingTestApp()
+	txn := app.StartTransaction("produce")
+	input := &sqs.SendMessageInput{
+		MessageBody: aws.String("order 42"),
+		MessageAttributes: map[string]sqs.MessageAttributeValue{
+			"user": {DataType: aws.String("String"), StringValue: aws.String("kept")},
+		},
+	}
+	input.MessageAttributes = InsertSQSMessageAttributes(txn, input.MessageAttributes)
+	txn.End()
+
+	if 4 != len(input.MessageAttributes) {
+		t.Error(input.MessageAttributes)
+	}
+	for _, name := range []string{"newrelic", "traceparent", "tracestate"} {
+		v := input.MessageAttributes[name]
+		if "String" != aws.StringValue(v.DataType) || "" == aws.StringValue(v.StringValue) {
+			t.Error(name, v)
+		}
+	}
+
+	msg := sqs.Message{
+		Body:              input.MessageBody,
+		MessageAttributes: input.MessageAttributes,
+	}
+	StartSQSTransaction(app.Application, testQueueURL, msg).End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/produce",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":                     "OtherTransaction/Go/Message/SQS/Queue/Named/orders",
+				"guid":                     internal.MatchAnything,
+				"traceId":                  internal.MatchAnything,
+				"priority":                 internal.MatchAnything,
+				"sampled":                  internal.MatchAnything,
+				"parent.type":              "App",
+				"parent.account":           "123",
+				"parent.app":               "456",
+				"parent.transportType":     "Queue",
+				"parent.transportDuration": internal.MatchAnything,
+				"parentId":                 internal.MatchAnything,
+				"parentSpanId":             internal.MatchAnything,
+			},
+		},
+	})
+}
+
+func TestSNSMessageAttributes(t *testing.T) {
+	app := messagingTestApp()
+	txn := app.StartTransaction("produce")
+	input := &sns.PublishInput{Message: aws.String("order 42")}
+	input.MessageAttributes = InsertSNSMessageAttributes(txn, input.MessageAttributes)
+	txn.End()
+
+	if 3 != len(input.MessageAttributes) {
+		t.Error(input.MessageAttributes)
+	}
+	if v := input.MessageAttributes["traceparent"]; "" == aws.StringValue(v.StringValue) {
+		t.Error(v)
+	}
+}
+
+func TestMessageAttributesNoTransaction(t *testing.T) {
+	if attrs := InsertSQSMessageAttributes(nil, nil); nil != attrs {
+		t.Error(attrs)
+	}
+	if attrs := InsertSNSMessageAttributes(nil, nil); nil != attrs {
+		t.Error(attrs)
+	}
+}
@@ -0,0 +1,10 @@
+module github.com/newrelic/go-agent/v3/integrations/nrbackoff
+
+// As of Nov 2020, go 1.13 is in the backoff go.mod file:
+// https://github.com/cenkalti/backoff/blob/v4/go.mod
+go 1.13
+
+require (
+	github.com/cenkalti/backoff/v4 v4.1.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
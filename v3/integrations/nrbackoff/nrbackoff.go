@@ -0,0 +1,104 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrbackoff instruments retries made using
+// https://github.com/cenkalti/backoff.
+//
+// Use Retry and RetryNotify in place of backoff.Retry and
+// backoff.RetryNotify.  Instead of:
+//
+//	err := backoff.Retry(operation, b)
+//
+// Use:
+//
+//	err := nrbackoff.Retry(txn, "fetchConfig", operation, b)
+//
+// Each call creates a segment with the given name which covers every
+// attempt, including the time spent waiting between attempts.  It has the
+// following attributes:
+//
+//	retry.attempts  the number of attempts made
+//	retry.outcome   "success" if the last attempt succeeded, otherwise
+//	                "failure"
+//
+// Each attempt creates a child segment named "{name}/attempt" with the
+// following attributes:
+//
+//	retry.attempt   the attempt number, starting at 1
+//	retry.delay     the time in seconds waited before the attempt
+//	retry.outcome   "success" or "error"
+//	retry.error     the error returned by the attempt, if any
+package nrbackoff
+
+import (
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "retry", "backoff") }
+
+// These attributes are added to the segments created by Retry and
+// RetryNotify.
+const (
+	AttributeAttempts = "retry.attempts"
+	AttributeAttempt  = "retry.attempt"
+	AttributeDelay    = "retry.delay"
+	AttributeOutcome  = "retry.outcome"
+	AttributeError    = "retry.error"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+	outcomeFailure = "failure"
+)
+
+// Retry calls backoff.Retry, recording the operation and each attempt as
+// segments.  The Transaction may be nil, in which case no segments are
+// created.
+func Retry(txn *newrelic.Transaction, name string, o backoff.Operation, b backoff.BackOff) error {
+	return RetryNotify(txn, name, o, b, nil)
+}
+
+// RetryNotify calls backoff.RetryNotify, recording the operation and each
+// attempt as segments.  The Transaction may be nil, in which case no
+// segments are created.
+func RetryNotify(txn *newrelic.Transaction, name string, o backoff.Operation, b backoff.BackOff, notify backoff.Notify) error {
+	if nil == txn {
+		return backoff.RetryNotify(o, b, notify)
+	}
+	seg := txn.StartSegment(name)
+	attempts := 0
+	var delay time.Duration
+	err := backoff.RetryNotify(func() error {
+		attempts++
+		s := txn.StartSegment(name + "/attempt")
+		s.AddAttribute(AttributeAttempt, attempts)
+		s.AddAttribute(AttributeDelay, delay.Seconds())
+		err := o()
+		if nil != err {
+			s.AddAttribute(AttributeOutcome, outcomeError)
+			s.AddAttribute(AttributeError, err.Error())
+		} else {
+			s.AddAttribute(AttributeOutcome, outcomeSuccess)
+		}
+		s.End()
+		return err
+	}, b, func(err error, next time.Duration) {
+		delay = next
+		if nil != notify {
+			notify(err, next)
+		}
+	})
+	seg.AddAttribute(AttributeAttempts, attempts)
+	if nil != err {
+		seg.AddAttribute(AttributeOutcome, outcomeFailure)
+	} else {
+		seg.AddAttribute(AttributeOutcome, outcomeSuccess)
+	}
+	seg.End()
+	return err
+}
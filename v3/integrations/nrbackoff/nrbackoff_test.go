@@ -0,0 +1,131 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrbackoff
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+)
+
+var errUnavailable = errors.New("unavailable")
+
+func attemptEvent(attempt int, err error) internal.WantEvent {
+	attrs := map[string]interface{}{
+		AttributeAttempt: attempt,
+		AttributeDelay:   internal.MatchAnything,
+		AttributeOutcome: "success",
+	}
+	if nil != err {
+		attrs[AttributeOutcome] = "error"
+		attrs[AttributeError] = err.Error()
+	}
+	return internal.WantEvent{
+		Intrinsics: map[string]interface{}{
+			"name":     "Custom/fetch/attempt",
+			"category": "generic",
+			"parentId": internal.MatchAnything,
+		},
+		UserAttributes: attrs,
+	}
+}
+
+func TestRetry(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("hello")
+	calls := 0
+	var notified []time.Duration
+	err := RetryNotify(txn, "fetch", func() error {
+		calls++
+		if calls < 3 {
+			return errUnavailable
+		}
+		return nil
+	}, backoff.NewConstantBackOff(time.Millisecond), func(err error, d time.Duration) {
+		notified = append(notified, d)
+	})
+	txn.End()
+	if nil != err {
+		t.Error(err)
+	}
+	if len(notified) != 2 {
+		t.Error(notified)
+	}
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		attemptEvent(1, errUnavailable),
+		attemptEvent(2, errUnavailable),
+		attemptEvent(3, nil),
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/fetch",
+				"category": "generic",
+				"parentId": internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeAttempts: 3,
+				AttributeOutcome:  "success",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+				"transaction.name": "OtherTransaction/Go/hello",
+			},
+		},
+	})
+}
+
+func TestRetryFailure(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("hello")
+	err := Retry(txn, "fetch", func() error {
+		return errUnavailable
+	}, backoff.WithMaxRetries(backoff.NewConstantBackOff(time.Millisecond), 1))
+	txn.End()
+	if err != errUnavailable {
+		t.Error(err)
+	}
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		attemptEvent(1, errUnavailable),
+		attemptEvent(2, errUnavailable),
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/fetch",
+				"category": "generic",
+				"parentId": internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeAttempts: 2,
+				AttributeOutcome:  "failure",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+				"transaction.name": "OtherTransaction/Go/hello",
+			},
+		},
+	})
+}
+
+func TestRetryNilTransaction(t *testing.T) {
+	calls := 0
+	err := Retry(nil, "fetch", func() error {
+		calls++
+		return nil
+	}, &backoff.ZeroBackOff{})
+	if nil != err || 1 != calls {
+		t.Error(err, calls)
+	}
+}
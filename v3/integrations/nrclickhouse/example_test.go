@@ -0,0 +1,47 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrclickhouse_test
+
+import (
+	"context"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/newrelic/go-agent/v3/integrations/nrclickhouse"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+
+	conn, err := nrclickhouse.Open(&clickhouse.Options{
+		Addr: []string{"127.0.0.1:9000"},
+		Auth: clickhouse.Auth{Database: "analytics"},
+	}, nrclickhouse.WithQueryParameters(true))
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close()
+
+	txn := app.StartTransaction("recordEvent")
+	defer txn.End()
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	if err := conn.Exec(ctx, "INSERT INTO events (id, name) VALUES (?, ?)", 1, "signup"); err != nil {
+		txn.NoticeError(err)
+	}
+
+	batch, err := conn.PrepareBatch(ctx, "INSERT INTO events")
+	if err != nil {
+		panic(err)
+	}
+	for i := 2; i < 10; i++ {
+		batch.Append(i, "visit")
+	}
+	if err := batch.Send(); err != nil {
+		txn.NoticeError(err)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrclickhouse
+
+go 1.18
+
+require (
+	github.com/ClickHouse/clickhouse-go/v2 v2.15.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
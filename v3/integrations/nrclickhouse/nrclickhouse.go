@@ -0,0 +1,286 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrclickhouse instruments ClickHouse calls made using the native API
+// of https://github.com/ClickHouse/clickhouse-go version 2.
+//
+// Open the connection using Open in place of clickhouse.Open, or wrap a
+// connection which is already open using Wrap:
+//
+//	opts := &clickhouse.Options{
+//		Addr: []string{"127.0.0.1:9000"},
+//		Auth: clickhouse.Auth{Database: "analytics"},
+//	}
+//	conn, err := nrclickhouse.Open(opts)
+//
+// Then provide a context containing a newrelic.Transaction to the calls to
+// be timed:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	err := conn.Exec(ctx, "INSERT INTO events (id) VALUES (?)", 1)
+//
+// Each call of Select, Query, QueryRow, Exec, and AsyncInsert is timed by a
+// DatastoreSegment whose operation, collection, and database are parsed from
+// the query using ParseQuery.  Query times only the call itself, not the
+// reading of the rows returned.  Sending a batch created by PrepareBatch is
+// timed by a segment using the query of the batch.  The database name of
+// segments is the database of the options unless the query names the
+// database of its table, eg. "SELECT count() FROM analytics.events".
+//
+// The arguments of queries are not recorded unless the WithQueryParameters
+// option is provided.
+package nrclickhouse
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
+)
+
+func init() { internal.TrackUsage("integration", "datastore", "clickhouse") }
+
+// AttributeBatchRows is the number of rows sent by a batch.  It is added to
+// the segments of batches.
+const AttributeBatchRows = "db.clickhouse.batch_rows"
+
+type conn struct {
+	driver.Conn
+	segment newrelic.DatastoreSegment
+	// parameters controls whether the arguments of queries are recorded
+	// as query parameters.
+	parameters bool
+	// obfuscate replaces the query parameters recorded with "?".
+	obfuscate bool
+}
+
+// Option configures the connection returned by Open and Wrap.
+type Option func(*conn)
+
+// WithQueryParameters records the arguments of each query as the query
+// parameters of its segment.  Arguments created using clickhouse.Named are
+// recorded using their names, and other arguments using their position, eg.
+// "$1".  If obfuscate is true, every value is replaced by "?", so that only
+// the names of the parameters are sent to New Relic.
+//
+// Query parameters are subject to the agent's rules for query parameters:
+// they are only sent to New Relic within slow query traces, and they are
+// dropped when Config.DatastoreTracer.QueryParameters.Enabled is false, when
+// high security mode is enabled, or when the record_sql security policy is
+// set.
+func WithQueryParameters(obfuscate bool) Option {
+	return func(c *conn) {
+		c.parameters = true
+		c.obfuscate = obfuscate
+	}
+}
+
+// Open opens a connection using clickhouse.Open, and returns it wrapped
+// using Wrap.
+func Open(opts *clickhouse.Options, connOpts ...Option) (driver.Conn, error) {
+	c, err := clickhouse.Open(opts)
+	if nil != err {
+		return nil, err
+	}
+	return Wrap(c, opts, connOpts...), nil
+}
+
+// Wrap returns a driver.Conn which times the calls of c made with a context
+// containing a Transaction.  The options are those used to open c.  They
+// are optional.  Provide them to get instance metrics broken out by host and
+// port, and to record the database name.  When the options contain several
+// addresses, the first is used.
+func Wrap(c driver.Conn, opts *clickhouse.Options, connOpts ...Option) driver.Conn {
+	w := &conn{Conn: c}
+	w.segment.Product = newrelic.DatastoreClickHouse
+	if nil != opts {
+		w.segment.DatabaseName = opts.Auth.Database
+		if len(opts.Addr) > 0 {
+			if host, port, err := net.SplitHostPort(opts.Addr[0]); nil == err {
+				w.segment.Host = host
+				w.segment.PortPathOrID = port
+			}
+		}
+	}
+	for _, opt := range connOpts {
+		opt(w)
+	}
+	return w
+}
+
+var (
+	firstWordRegex = regexp.MustCompile(`^\s*(\w+)`)
+	// tableRegex matches the table named by a query, and the database
+	// which qualifies it.
+	tableRegex = regexp.MustCompile("(?is)\\s(?:from|into|table(?:\\s+if(?:\\s+not)?\\s+exists)?)\\s+[`\"]?(\\w+)[`\"]?(?:\\.[`\"]?(\\w+)[`\"]?)?")
+	// operations are the ClickHouse statements not recognized by
+	// sqlparse.ParseQuery.
+	operations = map[string]bool{
+		"optimize": true,
+		"truncate": true,
+		"rename":   true,
+		"system":   true,
+	}
+)
+
+// ParseQuery sets the Operation, Collection, and DatabaseName of the segment
+// from the ClickHouse query.  The DatabaseName is only set if the query
+// qualifies its table with a database, eg. "analytics.events".
+func ParseQuery(segment *newrelic.DatastoreSegment, query string) {
+	sqlparse.ParseQuery(segment, query)
+	if "" == segment.Operation {
+		if m := firstWordRegex.FindStringSubmatch(query); nil != m {
+			if op := strings.ToLower(m[1]); operations[op] {
+				segment.Operation = op
+			}
+		}
+	}
+	m := tableRegex.FindStringSubmatch(query)
+	if nil == m {
+		return
+	}
+	database, table := m[1], m[2]
+	if "" == table {
+		database, table = "", m[1]
+	}
+	if "" == segment.Collection {
+		segment.Collection = table
+	}
+	if "" != database && table == segment.Collection {
+		segment.DatabaseName = database
+	}
+}
+
+// start starts the segment of the query if the context contains a
+// Transaction.
+func (c *conn) start(ctx context.Context, query string, args []interface{}) *newrelic.DatastoreSegment {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return nil
+	}
+	s := c.segment
+	s.ParameterizedQuery = query
+	ParseQuery(&s, query)
+	if c.parameters && len(args) > 0 {
+		s.QueryParameters = c.queryParameters(args)
+	}
+	s.StartTime = txn.StartSegmentNow()
+	return &s
+}
+
+func (c *conn) queryParameters(args []interface{}) map[string]interface{} {
+	params := make(map[string]interface{}, len(args))
+	for i, arg := range args {
+		key := "$" + strconv.Itoa(i+1)
+		value := arg
+		switch v := arg.(type) {
+		case driver.NamedValue:
+			key, value = v.Name, v.Value
+		case driver.NamedDateValue:
+			key, value = v.Name, v.Value
+		}
+		if c.obfuscate {
+			params[key] = "?"
+		} else {
+			params[key] = parameterValue(value)
+		}
+	}
+	return params
+}
+
+// parameterValue converts the value of an argument into a string, number, or
+// boolean.
+func parameterValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string, bool,
+		uint8, uint16, uint32, uint64, int8, int16, int32, int64,
+		float32, float64, uint, int, uintptr:
+		return v
+	case time.Time:
+		return v.Format(time.RFC3339Nano)
+	case nil:
+		return "NULL"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// Select implements driver.Conn.  It times the query.
+func (c *conn) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	s := c.start(ctx, query, args)
+	err := c.Conn.Select(ctx, dest, query, args...)
+	s.End()
+	return err
+}
+
+// Query implements driver.Conn.  It times the query, but not the reading of
+// the rows.
+func (c *conn) Query(ctx context.Context, query string, args ...interface{}) (driver.Rows, error) {
+	s := c.start(ctx, query, args)
+	rows, err := c.Conn.Query(ctx, query, args...)
+	s.End()
+	return rows, err
+}
+
+// QueryRow implements driver.Conn.  It times the query.
+func (c *conn) QueryRow(ctx context.Context, query string, args ...interface{}) driver.Row {
+	s := c.start(ctx, query, args)
+	row := c.Conn.QueryRow(ctx, query, args...)
+	s.End()
+	return row
+}
+
+// Exec implements driver.Conn.  It times the query.
+func (c *conn) Exec(ctx context.Context, query string, args ...interface{}) error {
+	s := c.start(ctx, query, args)
+	err := c.Conn.Exec(ctx, query, args...)
+	s.End()
+	return err
+}
+
+// AsyncInsert implements driver.Conn.  It times the insert.
+func (c *conn) AsyncInsert(ctx context.Context, query string, wait bool, args ...interface{}) error {
+	s := c.start(ctx, query, args)
+	err := c.Conn.AsyncInsert(ctx, query, wait, args...)
+	s.End()
+	return err
+}
+
+// PrepareBatch implements driver.Conn.  It returns a batch whose Send is
+// timed using the context provided.
+func (c *conn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	b, err := c.Conn.PrepareBatch(ctx, query, opts...)
+	if nil != err {
+		return nil, err
+	}
+	return &batch{Batch: b, conn: c, ctx: ctx, query: query}, nil
+}
+
+// batch times the sending of a batch using the context provided to
+// PrepareBatch.
+type batch struct {
+	driver.Batch
+	conn  *conn
+	ctx   context.Context
+	query string
+}
+
+// Send implements driver.Batch.  It times the sending of the batch.
+func (b *batch) Send() error {
+	s := b.conn.start(b.ctx, b.query, nil)
+	if nil != s {
+		s.AddAttribute(AttributeBatchRows, b.Batch.Rows())
+	}
+	err := b.Batch.Send()
+	s.End()
+	return err
+}
@@ -0,0 +1,240 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrclickhouse
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2"
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// testConn records the queries it is called with.  Methods which are not
+// overridden panic.
+type testConn struct {
+	driver.Conn
+	queries []string
+}
+
+func (c *testConn) Exec(ctx context.Context, query string, args ...interface{}) error {
+	c.queries = append(c.queries, query)
+	return nil
+}
+
+func (c *testConn) Select(ctx context.Context, dest interface{}, query string, args ...interface{}) error {
+	c.queries = append(c.queries, query)
+	return nil
+}
+
+func (c *testConn) PrepareBatch(ctx context.Context, query string, opts ...driver.PrepareBatchOption) (driver.Batch, error) {
+	c.queries = append(c.queries, query)
+	return &testBatch{}, nil
+}
+
+type testBatch struct {
+	driver.Batch
+	rows int
+	sent bool
+}
+
+func (b *testBatch) Append(v ...interface{}) error {
+	b.rows++
+	return nil
+}
+
+func (b *testBatch) Rows() int { return b.rows }
+
+func (b *testBatch) Send() error {
+	b.sent = true
+	return nil
+}
+
+var testOptions = &clickhouse.Options{
+	Addr: []string{"clickhouse.example.com:9000", "clickhouse2.example.com:9000"},
+	Auth: clickhouse.Auth{Database: "analytics"},
+}
+
+func TestParseQuery(t *testing.T) {
+	testcases := []struct {
+		query      string
+		operation  string
+		collection string
+		database   string
+	}{
+		{query: "SELECT count() FROM events", operation: "select", collection: "events"},
+		{query: "SELECT count() FROM logs.events WHERE id = ?", operation: "select", collection: "events", database: "logs"},
+		{query: "INSERT INTO `logs`.`events` (id) VALUES (?)", operation: "insert", collection: "events", database: "logs"},
+		{query: "ALTER TABLE logs.events DELETE WHERE id = 1", operation: "alter", collection: "events", database: "logs"},
+		{query: "CREATE TABLE IF NOT EXISTS events (id UInt64) ENGINE = MergeTree ORDER BY id", operation: "create", collection: "events"},
+		{query: "OPTIMIZE TABLE logs.events FINAL", operation: "optimize", collection: "events", database: "logs"},
+		{query: "TRUNCATE TABLE events", operation: "truncate", collection: "events"},
+		{query: "SYSTEM FLUSH LOGS", operation: "system"},
+		{query: "not a query"},
+	}
+	for _, tc := range testcases {
+		var s newrelic.DatastoreSegment
+		ParseQuery(&s, tc.query)
+		if s.Operation != tc.operation || s.Collection != tc.collection || s.DatabaseName != tc.database {
+			t.Errorf("%q: operation=%q collection=%q database=%q", tc.query, s.Operation, s.Collection, s.DatabaseName)
+		}
+	}
+}
+
+func TestExec(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("exec")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	tc := &testConn{}
+	conn := Wrap(tc, testOptions)
+	if err := conn.Exec(ctx, "INSERT INTO logs.events (id) VALUES (?)", 1); nil != err {
+		t.Fatal(err)
+	}
+	txn.End()
+
+	if len(tc.queries) != 1 {
+		t.Error(tc.queries)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/ClickHouse/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/ClickHouse/insert", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/ClickHouse/events/insert", Scope: "OtherTransaction/Go/exec", Forced: false, Data: nil},
+		{Name: "Datastore/instance/ClickHouse/clickhouse.example.com/9000", Scope: "", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/ClickHouse/events/insert",
+				"category":  "datastore",
+				"component": "ClickHouse",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "INSERT INTO logs.events (id) VALUES (?)",
+				"db.instance":   "logs",
+				"db.collection": "events",
+				"peer.address":  "clickhouse.example.com:9000",
+				"peer.hostname": "clickhouse.example.com",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/exec",
+				"transaction.name": "OtherTransaction/Go/exec",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestQueryParameters(t *testing.T) {
+	testcases := []struct {
+		obfuscate bool
+		params    map[string]interface{}
+	}{
+		{obfuscate: false, params: map[string]interface{}{"$1": 7, "since": "2020-01-02T03:04:05Z"}},
+		{obfuscate: true, params: map[string]interface{}{"$1": "?", "since": "?"}},
+	}
+	for _, tc := range testcases {
+		app := integrationsupport.NewTestApp(nil, func(cfg *newrelic.Config) {
+			cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		})
+		txn := app.StartTransaction("select")
+		ctx := newrelic.NewContext(context.Background(), txn)
+
+		query := "SELECT * FROM events WHERE id = ? AND time > {since:DateTime}"
+		since := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+		conn := Wrap(&testConn{}, testOptions, WithQueryParameters(tc.obfuscate))
+		var dest []struct{}
+		if err := conn.Select(ctx, &dest, query, 7, clickhouse.Named("since", since)); nil != err {
+			t.Fatal(err)
+		}
+		txn.End()
+
+		app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+			Count:        1,
+			MetricName:   "Datastore/statement/ClickHouse/events/select",
+			Query:        query,
+			TxnName:      "OtherTransaction/Go/select",
+			DatabaseName: "analytics",
+			Host:         "clickhouse.example.com",
+			PortPathOrID: "9000",
+			Params:       tc.params,
+		}})
+	}
+}
+
+func TestBatchSend(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("batch")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	conn := Wrap(&testConn{}, nil)
+	b, err := conn.PrepareBatch(ctx, "INSERT INTO events")
+	if nil != err {
+		t.Fatal(err)
+	}
+	b.Append(1)
+	b.Append(2)
+	if err := b.Send(); nil != err {
+		t.Fatal(err)
+	}
+	if !b.(*batch).Batch.(*testBatch).sent {
+		t.Error("batch not sent")
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/statement/ClickHouse/events/insert", Scope: "OtherTransaction/Go/batch", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/ClickHouse/events/insert",
+				"category":  "datastore",
+				"component": "ClickHouse",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeBatchRows: 2,
+			},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "INSERT INTO events",
+				"db.collection": "events",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/batch",
+				"transaction.name": "OtherTransaction/Go/batch",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestNoTransaction(t *testing.T) {
+	tc := &testConn{}
+	conn := Wrap(tc, testOptions)
+	if err := conn.Exec(context.Background(), "SELECT 1"); nil != err {
+		t.Error(err)
+	}
+	if len(tc.queries) != 1 {
+		t.Error(tc.queries)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrcron_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrcron"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/robfig/cron/v3"
+)
+
+func refreshCache(ctx context.Context) {
+	txn := newrelic.FromContext(ctx)
+	defer txn.StartSegment("fetch").End()
+	time.Sleep(100 * time.Millisecond)
+}
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+	c := cron.New()
+	nrcron.AddFunc(app, c, "refreshCache", "@every 1m", refreshCache)
+
+	// Skip the runs which start while the previous run is in progress.
+	job := nrcron.NewJob(app, "rebuildIndex", "@hourly", func(ctx context.Context) {
+		time.Sleep(2 * time.Hour)
+	})
+	job.SkipIfStillRunning = true
+	c.AddJob("@hourly", job)
+
+	c.Start()
+	defer c.Stop()
+}
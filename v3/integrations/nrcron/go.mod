@@ -0,0 +1,10 @@
+module github.com/newrelic/go-agent/v3/integrations/nrcron
+
+// As of Nov 2020, go 1.12 is in the cron go.mod file:
+// https://github.com/robfig/cron/blob/master/go.mod
+go 1.12
+
+require (
+	github.com/newrelic/go-agent/v3 v3.13.0
+	github.com/robfig/cron/v3 v3.0.1
+)
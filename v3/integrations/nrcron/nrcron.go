@@ -0,0 +1,106 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrcron instruments jobs scheduled using
+// https://github.com/robfig/cron.
+//
+// Each run of a job is recorded as a background transaction named after the
+// job.  Use AddFunc and AddJob in place of the Cron methods.  Instead of:
+//
+//	c.AddFunc("@every 1m", refreshCache)
+//
+// Use:
+//
+//	nrcron.AddFunc(app, c, "refreshCache", "@every 1m", func(ctx context.Context) {
+//		refreshCache(ctx)
+//	})
+//
+// The transaction is added to the context passed to the function, so it can
+// be retrieved using newrelic.FromContext.  The transactions have the
+// following attributes:
+//
+//	job.schedule   the schedule of the job, eg. "@every 1m"
+//	job.duration   the time in seconds taken by the run
+//	job.overlap    true if the previous run of the job was still in progress
+//	job.skipped    true if the run was skipped because the previous run was
+//	               still in progress, see Job.SkipIfStillRunning
+package nrcron
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/robfig/cron/v3"
+)
+
+func init() { internal.TrackUsage("integration", "scheduler", "cron") }
+
+// These attributes are added to the transactions created by Job.
+const (
+	AttributeSchedule = integrationsupport.AttributeJobSchedule
+	AttributeDuration = integrationsupport.AttributeJobDuration
+	AttributeOverlap  = integrationsupport.AttributeJobOverlap
+	AttributeSkipped  = integrationsupport.AttributeJobSkipped
+)
+
+// Job is a cron.Job which records each run as a background transaction.
+// Create it using NewJob or WrapJob.
+type Job struct {
+	// SkipIfStillRunning skips the runs of the job which start while the
+	// previous run is still in progress.  Skipped runs are recorded as
+	// transactions with the job.skipped attribute.
+	SkipIfStillRunning bool
+
+	app      *newrelic.Application
+	name     string
+	schedule string
+	fn       func(ctx context.Context)
+	running  int32
+}
+
+// NewJob creates a Job which calls fn.  The name is used as the name of the
+// transactions and the schedule is recorded as their job.schedule attribute.
+// The Application may be nil, in which case no transactions are created.
+func NewJob(app *newrelic.Application, name string, schedule string, fn func(ctx context.Context)) *Job {
+	return &Job{
+		app:      app,
+		name:     name,
+		schedule: schedule,
+		fn:       fn,
+	}
+}
+
+// WrapJob creates a Job which runs job.
+func WrapJob(app *newrelic.Application, name string, schedule string, job cron.Job) *Job {
+	return NewJob(app, name, schedule, func(context.Context) { job.Run() })
+}
+
+// AddFunc adds a Job which calls fn to c using the schedule spec.
+func AddFunc(app *newrelic.Application, c *cron.Cron, name string, spec string, fn func(ctx context.Context)) (cron.EntryID, error) {
+	return c.AddJob(spec, NewJob(app, name, spec, fn))
+}
+
+// AddJob adds a Job which runs job to c using the schedule spec.
+func AddJob(app *newrelic.Application, c *cron.Cron, name string, spec string, job cron.Job) (cron.EntryID, error) {
+	return c.AddJob(spec, WrapJob(app, name, spec, job))
+}
+
+// Run implements cron.Job.
+func (j *Job) Run() {
+	running := int32(1)
+	if !j.SkipIfStillRunning {
+		running = atomic.AddInt32(&j.running, 1)
+	} else if !atomic.CompareAndSwapInt32(&j.running, 0, 1) {
+		integrationsupport.SkipScheduledJob(j.app, j.name, j.schedule)
+		return
+	}
+	defer atomic.AddInt32(&j.running, -1)
+
+	integrationsupport.RunScheduledJob(j.app, j.name, j.schedule, running > 1, func(ctx context.Context) {
+		newrelic.FromContext(ctx).AddAttribute(AttributeSkipped, false)
+		j.fn(ctx)
+	})
+}
@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrcron
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func jobEvent(overlap bool, skipped bool) internal.WantEvent {
+	attrs := map[string]interface{}{
+		AttributeSchedule: "@every 1m",
+		AttributeOverlap:  overlap,
+		AttributeSkipped:  skipped,
+	}
+	if !skipped {
+		attrs[AttributeDuration] = internal.MatchAnything
+	}
+	return internal.WantEvent{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/refresh",
+		},
+		UserAttributes: attrs,
+	}
+}
+
+func TestJobRun(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	var found bool
+	job := NewJob(app.Application, "refresh", "@every 1m", func(ctx context.Context) {
+		found = nil != newrelic.FromContext(ctx)
+	})
+	job.Run()
+	if !found {
+		t.Error("transaction not added to context")
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{jobEvent(false, false)})
+}
+
+// runOverlapping runs the job while a previous run is in progress.
+func runOverlapping(job *Job, release chan struct{}) {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		job.Run()
+	}()
+	<-release
+	job.Run()
+	release <- struct{}{}
+	<-done
+}
+
+func TestJobOverlap(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	release := make(chan struct{})
+	calls := 0
+	job := NewJob(app.Application, "refresh", "@every 1m", func(ctx context.Context) {
+		calls++
+		if 1 == calls {
+			release <- struct{}{}
+			<-release
+		}
+	})
+	runOverlapping(job, release)
+	if 2 != calls {
+		t.Error(calls)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		jobEvent(true, false),
+		jobEvent(false, false),
+	})
+}
+
+func TestJobSkipIfStillRunning(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	release := make(chan struct{})
+	calls := 0
+	job := NewJob(app.Application, "refresh", "@every 1m", func(ctx context.Context) {
+		calls++
+		release <- struct{}{}
+		<-release
+	})
+	job.SkipIfStillRunning = true
+	runOverlapping(job, release)
+	if 1 != calls {
+		t.Error(calls)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		jobEvent(true, true),
+		jobEvent(false, false),
+	})
+}
+
+type countJob int
+
+func (c *countJob) Run() { *c++ }
+
+func TestWrapJobNilApplication(t *testing.T) {
+	var c countJob
+	WrapJob(nil, "refresh", "@every 1m", &c).Run()
+	if 1 != c {
+		t.Error(c)
+	}
+}
@@ -46,6 +46,7 @@ func TestBasicRoute(t *testing.T) {
 			"httpResponseCode":             "200",
 			"http.statusCode":              "200",
 			"request.method":               "GET",
+			"http.flavor":                  "1.1",
 			"response.headers.contentType": "text/html",
 			"request.uri":                  "/hello",
 		},
@@ -172,6 +173,7 @@ func TestReturnsHTTPError(t *testing.T) {
 			"httpResponseCode": "418",
 			"http.statusCode":  "418",
 			"request.method":   "GET",
+			"http.flavor":      "1.1",
 			"request.uri":      "/hello",
 		},
 		UserAttributes: map[string]interface{}{},
@@ -208,6 +210,7 @@ func TestReturnsError(t *testing.T) {
 			"httpResponseCode": "500",
 			"http.statusCode":  "500",
 			"request.method":   "GET",
+			"http.flavor":      "1.1",
 			"request.uri":      "/hello",
 		},
 		UserAttributes: map[string]interface{}{},
@@ -244,6 +247,7 @@ func TestResponseCode(t *testing.T) {
 			"httpResponseCode":             "418",
 			"http.statusCode":              "418",
 			"request.method":               "GET",
+			"http.flavor":                  "1.1",
 			"response.headers.contentType": "text/html",
 			"request.uri":                  "/hello",
 		},
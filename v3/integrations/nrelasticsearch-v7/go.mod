@@ -6,5 +6,5 @@ go 1.11
 
 require (
 	github.com/elastic/go-elasticsearch/v7 v7.5.0
-	github.com/newrelic/go-agent/v3 v3.0.0
+	github.com/newrelic/go-agent/v3 v3.13.0
 )
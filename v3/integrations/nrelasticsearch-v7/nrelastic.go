@@ -4,10 +4,20 @@
 // Package nrelasticsearch instruments https://github.com/elastic/go-elasticsearch.
 //
 // Use this package to instrument your elasticsearch v7 calls without having to
-// manually create DatastoreSegments.
+// manually create DatastoreSegments.  The index and operation of each call
+// are parsed from its request path.  The "took" time and the total number of
+// hits of search responses are recorded as the AttributeTook and
+// AttributeHits segment attributes.
+//
+// Since https://github.com/opensearch-project/opensearch-go shares the
+// elasticsearch client's transport and REST API, its calls may be
+// instrumented using NewOpenSearchRoundTripper.
 package nrelasticsearch
 
 import (
+	"bytes"
+	"encoding/json"
+	"io"
 	"net/http"
 	"strings"
 
@@ -17,6 +27,15 @@ import (
 
 func init() { internal.TrackUsage("integration", "datastore", "elasticsearch") }
 
+// These attributes are added to the segments of search calls.
+const (
+	// AttributeTook is the "took" time of the search in milliseconds, as
+	// reported by the cluster.
+	AttributeTook = "db.elasticsearch.took"
+	// AttributeHits is the total number of documents matching the search.
+	AttributeHits = "db.elasticsearch.hits"
+)
+
 func parseRequest(r *http.Request) (segment newrelic.DatastoreSegment) {
 
 	segment.StartTime = newrelic.FromContext(r.Context()).StartSegmentNow()
@@ -156,21 +175,163 @@ func parseRequest(r *http.Request) (segment newrelic.DatastoreSegment) {
 	return
 }
 
-type roundtripper struct{ original http.RoundTripper }
+// searchOperations are the operations whose responses contain took and
+// hits.
+var searchOperations = map[string]bool{
+	"search":          true,
+	"scroll":          true,
+	"search_template": true,
+}
+
+// searchResponse is the part of a search response recorded as attributes.
+// The total hits is an object containing the value since Elasticsearch 7,
+// and a number before.
+type searchResponse struct {
+	Took *int64 `json:"took"`
+	Hits *struct {
+		Total json.RawMessage `json:"total"`
+	} `json:"hits"`
+}
+
+func (r searchResponse) totalHits() (int64, bool) {
+	if nil == r.Hits || 0 == len(r.Hits.Total) {
+		return 0, false
+	}
+	var total struct {
+		Value int64 `json:"value"`
+	}
+	if err := json.Unmarshal(r.Hits.Total, &total); nil == err {
+		return total.Value, true
+	}
+	var value int64
+	if err := json.Unmarshal(r.Hits.Total, &value); nil == err {
+		return value, true
+	}
+	return 0, false
+}
+
+// searchResponsePeekLimit is the number of bytes at the start of a search
+// response which are read to find its took and hits.  Elasticsearch writes
+// them before the documents, so the rest of the body is left to the client.
+const searchResponsePeekLimit = 16 * 1024
+
+// peekedBody replays the bytes read from the start of a response body
+// before the rest of the body.
+type peekedBody struct {
+	io.Reader
+	io.Closer
+}
+
+// peekSearchResponse decodes the took and hits.total fields of a search
+// response.  Elasticsearch writes took before hits, so decoding stops once
+// hits.total has been found or the documents are reached.
+func peekSearchResponse(dec *json.Decoder) (sr searchResponse) {
+	if tok, err := dec.Token(); nil != err || json.Delim('{') != tok {
+		return
+	}
+	for dec.More() {
+		key, err := dec.Token()
+		if nil != err {
+			return
+		}
+		switch key {
+		case "took":
+			var took int64
+			if nil != dec.Decode(&took) {
+				return
+			}
+			sr.Took = &took
+		case "hits":
+			if tok, err := dec.Token(); nil != err || json.Delim('{') != tok {
+				return
+			}
+			for dec.More() {
+				key, err := dec.Token()
+				if nil != err || "hits" == key {
+					return
+				}
+				var value json.RawMessage
+				if nil != dec.Decode(&value) {
+					return
+				}
+				if "total" == key {
+					sr.Hits = &struct {
+						Total json.RawMessage `json:"total"`
+					}{Total: value}
+					return
+				}
+			}
+			return
+		default:
+			var value json.RawMessage
+			if nil != dec.Decode(&value) {
+				return
+			}
+		}
+	}
+	return
+}
+
+// addSearchAttributes reads the start of the body of a search response to
+// add its took and hits to the segment.  Only the bytes needed to find them
+// are read; the body is replaced so that the client reads these bytes again
+// followed by the rest of the response.
+func addSearchAttributes(segment *newrelic.DatastoreSegment, resp *http.Response) {
+	if nil == resp || nil == resp.Body || http.StatusOK != resp.StatusCode {
+		return
+	}
+	var peeked bytes.Buffer
+	dec := json.NewDecoder(io.TeeReader(io.LimitReader(resp.Body, searchResponsePeekLimit), &peeked))
+	sr := peekSearchResponse(dec)
+	resp.Body = peekedBody{
+		Reader: io.MultiReader(&peeked, resp.Body),
+		Closer: resp.Body,
+	}
+	if nil != sr.Took {
+		segment.AddAttribute(AttributeTook, *sr.Took)
+	}
+	if hits, ok := sr.totalHits(); ok {
+		segment.AddAttribute(AttributeHits, hits)
+	}
+}
+
+type roundtripper struct {
+	original http.RoundTripper
+	product  newrelic.DatastoreProduct
+}
 
 func (t roundtripper) RoundTrip(r *http.Request) (*http.Response, error) {
 	segment := parseRequest(r)
+	segment.Product = t.product
 	defer segment.End()
 
-	return t.original.RoundTrip(r)
+	resp, err := t.original.RoundTrip(r)
+	if nil == err && searchOperations[segment.Operation] &&
+		nil != newrelic.FromContext(r.Context()) {
+		addSearchAttributes(&segment, resp)
+	}
+	return resp, err
 }
 
 // NewRoundTripper creates a new http.RoundTripper to instrument elasticsearch
 // calls.  If an http.RoundTripper parameter is not provided, then the returned
 // http.RoundTripper will delegate to http.DefaultTransport.
 func NewRoundTripper(original http.RoundTripper) http.RoundTripper {
+	return newRoundTripper(original, newrelic.DatastoreElasticsearch)
+}
+
+// NewOpenSearchRoundTripper creates a new http.RoundTripper to instrument
+// opensearch-go calls.  Use it as the Transport of the opensearch.Config.
+// Segments are recorded with the newrelic.DatastoreOpenSearch product.  If an
+// http.RoundTripper parameter is not provided, then the returned
+// http.RoundTripper will delegate to http.DefaultTransport.
+func NewOpenSearchRoundTripper(original http.RoundTripper) http.RoundTripper {
+	return newRoundTripper(original, newrelic.DatastoreOpenSearch)
+}
+
+func newRoundTripper(original http.RoundTripper, product newrelic.DatastoreProduct) http.RoundTripper {
 	if nil == original {
 		original = http.DefaultTransport
 	}
-	return roundtripper{original: original}
+	return roundtripper{original: original, product: product}
 }
@@ -5,7 +5,10 @@ package nrelasticsearch
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/url"
 	"strings"
@@ -502,3 +505,164 @@ func TestInfoRequest(t *testing.T) {
 	})
 
 }
+
+func TestSearchAttributes(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	body := `{"took":12,"hits":{"total":{"value":3,"relation":"eq"},"hits":[]}}`
+	rt := NewRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{
+			StatusCode: http.StatusOK,
+			Body:       ioutil.NopCloser(strings.NewReader(body)),
+		}, nil
+	}))
+	req, _ := http.NewRequest("POST", "http://localhost:9200/myindex/_search", nil)
+	resp, err := rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != body {
+		t.Error(string(b))
+	}
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/Elasticsearch/myindex/search",
+				"category":  "datastore",
+				"component": "Elasticsearch",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeTook: 12,
+				AttributeHits: 3,
+			},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "'search' on 'myindex' using 'Elasticsearch'",
+				"db.collection": "myindex",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/txnName",
+				"transaction.name": "OtherTransaction/Go/txnName",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSearchTotalHits(t *testing.T) {
+	testcases := []struct {
+		body   string
+		hits   int64
+		hasHit bool
+	}{
+		{body: `{"hits":{"total":{"value":3,"relation":"eq"}}}`, hits: 3, hasHit: true},
+		{body: `{"hits":{"total":5}}`, hits: 5, hasHit: true},
+		{body: `{"hits":{}}`},
+		{body: `{"took":1}`},
+		{body: `{"took":1,"_shards":{"total":1},"hits":{"max_score":1,"total":2,"hits":[]}}`, hits: 2, hasHit: true},
+		{body: `{"hits":{"hits":[],"total":2}}`},
+		{body: `not json`},
+	}
+	for _, tc := range testcases {
+		sr := peekSearchResponse(json.NewDecoder(strings.NewReader(tc.body)))
+		if hits, ok := sr.totalHits(); hits != tc.hits || ok != tc.hasHit {
+			t.Error(tc.body, hits, ok)
+		}
+	}
+}
+
+type countingReader struct {
+	r io.Reader
+	n int
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += n
+	return n, err
+}
+
+func (c *countingReader) Close() error { return nil }
+
+func TestSearchAttributesLargeResponse(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	body := `{"took":7,"hits":{"total":{"value":1000,"relation":"eq"},"hits":[` +
+		strings.Repeat(`{"_id":"1"},`, 100000) + `{"_id":"1"}]}}`
+	src := &countingReader{r: strings.NewReader(body)}
+	rt := NewRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return &http.Response{StatusCode: http.StatusOK, Body: src}, nil
+	}))
+	req, _ := http.NewRequest("POST", "http://localhost:9200/myindex/_search", nil)
+	resp, err := rt.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if src.n > searchResponsePeekLimit {
+		t.Error("body read by the round tripper", src.n)
+	}
+	if b, _ := ioutil.ReadAll(resp.Body); string(b) != body {
+		t.Error("body changed", len(b), len(body))
+	}
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/Elasticsearch/myindex/search",
+				"category":  "datastore",
+				"component": "Elasticsearch",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeTook: 7,
+				AttributeHits: 1000,
+			},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "'search' on 'myindex' using 'Elasticsearch'",
+				"db.collection": "myindex",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/txnName",
+				"transaction.name": "OtherTransaction/Go/txnName",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestOpenSearchRoundTripper(t *testing.T) {
+	app := createTestApp()
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	rt := NewOpenSearchRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		return nil, errSomething
+	}))
+	req, _ := http.NewRequest("GET", "http://localhost:9200/myindex/_doc/1", nil)
+	if _, err := rt.RoundTrip(req.WithContext(ctx)); err != errSomething {
+		t.Fatal(err)
+	}
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/OpenSearch/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/OpenSearch/get", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/OpenSearch/myindex/get", Scope: "OtherTransaction/Go/txnName", Forced: false, Data: nil},
+	})
+}
@@ -0,0 +1,10 @@
+module github.com/newrelic/go-agent/v3/integrations/nrfiber
+
+// As of Dec 2023, the fiber go.mod file uses 1.20:
+// https://github.com/gofiber/fiber/blob/v2.52.0/go.mod
+go 1.20
+
+require (
+	github.com/gofiber/fiber/v2 v2.52.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
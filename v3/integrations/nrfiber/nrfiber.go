@@ -0,0 +1,149 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrfiber instruments applications using
+// https://github.com/gofiber/fiber v2.
+//
+// Use this package to instrument inbound requests handled by a fiber.App.
+//
+//	app := fiber.New()
+//	// Add the nrfiber middleware before other middlewares or routes:
+//	app.Use(nrfiber.Middleware(nrApp))
+//
+// Each request is recorded by a transaction named after the method and the
+// pattern of the route which handled it, eg. "GET /users/:id".  Requests
+// which no route handled are named "NotFoundHandler".  The transaction is
+// added to the user context of the fiber.Ctx, so that it can be accessed
+// using FromContext, and is provided to calls made with c.UserContext():
+//
+//	app.Get("/users/:id", func(c *fiber.Ctx) error {
+//		txn := nrfiber.FromContext(c)
+//		txn.AddAttribute("userId", c.Params("id"))
+//		return c.SendString(c.Params("id"))
+//	})
+//
+// Example: https://github.com/newrelic/go-agent/tree/master/v3/integrations/nrfiber/example/main.go
+package nrfiber
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "framework", "fiber") }
+
+// FromContext returns the Transaction from the user context of the
+// fiber.Ctx if present, and nil otherwise.
+func FromContext(c *fiber.Ctx) *newrelic.Transaction {
+	return newrelic.FromContext(c.UserContext())
+}
+
+// webRequest converts the fasthttp request of the fiber.Ctx.  Values are
+// copied since fasthttp reuses its buffers once the request has been
+// handled.
+func webRequest(c *fiber.Ctx) newrelic.WebRequest {
+	hdrs := make(http.Header)
+	c.Request().Header.VisitAll(func(key, value []byte) {
+		hdrs.Add(string(key), string(value))
+	})
+	transport := newrelic.TransportHTTP
+	if "https" == c.Protocol() {
+		transport = newrelic.TransportHTTPS
+	}
+	// Like the URL of a net/http server request, the URL contains only
+	// the path and the query.
+	u, _ := url.Parse(c.OriginalURL())
+	r := newrelic.WebRequest{
+		Header:    hdrs,
+		URL:       u,
+		Method:    c.Method(),
+		Transport: transport,
+		Host:      c.Hostname(),
+		Proto:     string(c.Request().Header.Protocol()),
+		TLS:       c.Context().TLSConnectionState(),
+	}
+	if addr := c.Context().RemoteAddr(); nil != addr {
+		r.RemoteAddr = addr.String()
+	}
+	return r
+}
+
+// responseHeaderWriter provides the response headers of the fiber.Ctx to
+// Transaction.SetWebResponse, which records them when WriteHeader is called.
+type responseHeaderWriter struct {
+	header http.Header
+}
+
+func (w responseHeaderWriter) Header() http.Header         { return w.header }
+func (w responseHeaderWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w responseHeaderWriter) WriteHeader(code int)        {}
+
+// transactionName names the transaction after the route which handled the
+// request.  If the route is still the route of the middleware, no route
+// matched the request.
+func transactionName(c *fiber.Ctx, middleware *fiber.Route, err error) string {
+	route := c.Route()
+	var e *fiber.Error
+	if route == middleware && errors.As(err, &e) && fiber.StatusNotFound == e.Code {
+		return "NotFoundHandler"
+	}
+	return c.Method() + " " + route.Path
+}
+
+// statusCode returns the status code of the response.  Errors returned by
+// the handlers are turned into responses by the fiber.App's ErrorHandler
+// after the middleware has returned, so their status code is the one which
+// fiber.DefaultErrorHandler would set.
+func statusCode(c *fiber.Ctx, err error) int {
+	if nil == err {
+		return c.Response().StatusCode()
+	}
+	var e *fiber.Error
+	if errors.As(err, &e) {
+		return e.Code
+	}
+	return fiber.StatusInternalServerError
+}
+
+// Middleware creates fiber middleware that instruments requests.
+//
+//	app := fiber.New()
+//	// Add the nrfiber middleware before other middlewares or routes:
+//	app.Use(nrfiber.Middleware(nrApp))
+func Middleware(app *newrelic.Application) fiber.Handler {
+	if nil == app {
+		return func(c *fiber.Ctx) error {
+			return c.Next()
+		}
+	}
+
+	return func(c *fiber.Ctx) (err error) {
+		// The route is not known until a handler has been matched, so
+		// the transaction is named once the handlers have returned or
+		// panicked.
+		middleware := c.Route()
+		txn := app.StartTransaction(c.Method() + " " + c.Path())
+		defer txn.End()
+		defer func() {
+			txn.SetName(transactionName(c, middleware, err))
+		}()
+
+		txn.SetWebRequest(webRequest(c))
+		c.SetUserContext(newrelic.NewContext(c.UserContext(), txn))
+
+		err = c.Next()
+
+		hdrs := make(http.Header)
+		c.Response().Header.VisitAll(func(key, value []byte) {
+			hdrs.Add(string(key), string(value))
+		})
+		txn.SetWebResponse(responseHeaderWriter{header: hdrs}).WriteHeader(statusCode(c, err))
+
+		return err
+	}
+}
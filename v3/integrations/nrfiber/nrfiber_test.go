@@ -0,0 +1,169 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrfiber
+
+import (
+	"errors"
+	"io"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+)
+
+func TestBasicRoute(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+
+	f := fiber.New()
+	f.Use(Middleware(app.Application))
+	f.Get("/users/:id", func(c *fiber.Ctx) error {
+		c.Set("Content-Type", "text/plain")
+		return c.SendString("Hello, " + c.Params("id"))
+	})
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/users/123?remove=me", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "Hello, 123" {
+		t.Error("wrong response body", string(body))
+	}
+	app.ExpectTxnMetrics(t, internal.WantTxn{
+		Name:  "GET /users/:id",
+		IsWeb: true,
+	})
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/GET /users/:id",
+			"nr.apdexPerfZone": "S",
+		},
+		AgentAttributes: map[string]interface{}{
+			"httpResponseCode":             "200",
+			"http.statusCode":              "200",
+			"request.method":               "GET",
+			"http.flavor":                  "1.1",
+			"request.headers.host":         "example.com",
+			"response.headers.contentType": "text/plain",
+			"request.uri":                  "/users/123",
+		},
+		UserAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestNilApp(t *testing.T) {
+	f := fiber.New()
+	f.Use(Middleware(nil))
+	f.Get("/hello", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/hello", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if body, _ := io.ReadAll(resp.Body); string(body) != "Hello, World!" {
+		t.Error("wrong response body", string(body))
+	}
+}
+
+func TestTransactionContext(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+
+	f := fiber.New()
+	f.Use(Middleware(app.Application))
+	f.Get("/hello", func(c *fiber.Ctx) error {
+		txn := FromContext(c)
+		txn.NoticeError(errors.New("ooops"))
+		return c.SendString("Hello, World!")
+	})
+
+	if _, err := f.Test(httptest.NewRequest("GET", "/hello", nil)); err != nil {
+		t.Fatal(err)
+	}
+	app.ExpectTxnMetrics(t, internal.WantTxn{
+		Name:      "GET /hello",
+		IsWeb:     true,
+		NumErrors: 1,
+	})
+}
+
+func TestNotFoundHandler(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+
+	f := fiber.New()
+	f.Use(Middleware(app.Application))
+	f.Get("/hello", func(c *fiber.Ctx) error {
+		return c.SendString("Hello, World!")
+	})
+
+	resp, err := f.Test(httptest.NewRequest("GET", "/goodbye", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if resp.StatusCode != fiber.StatusNotFound {
+		t.Error("wrong status code", resp.StatusCode)
+	}
+	app.ExpectTxnMetrics(t, internal.WantTxn{
+		Name:  "NotFoundHandler",
+		IsWeb: true,
+	})
+}
+
+func TestHandlerReturnsNotFound(t *testing.T) {
+	// A route which returns a not found error still names the
+	// transaction.
+	app := integrationsupport.NewBasicTestApp()
+
+	f := fiber.New()
+	f.Use(Middleware(app.Application))
+	f.Get("/users/:id", func(c *fiber.Ctx) error {
+		return fiber.ErrNotFound
+	})
+
+	if _, err := f.Test(httptest.NewRequest("GET", "/users/123", nil)); err != nil {
+		t.Fatal(err)
+	}
+	app.ExpectTxnMetrics(t, internal.WantTxn{
+		Name:  "GET /users/:id",
+		IsWeb: true,
+	})
+}
+
+func TestHandlerError(t *testing.T) {
+	testcases := []struct {
+		err     error
+		code    string
+		message string
+	}{
+		{err: fiber.NewError(503, "unavailable"), code: "503", message: "Service Unavailable"},
+		{err: errors.New("ooops"), code: "500", message: "Internal Server Error"},
+	}
+	for _, tc := range testcases {
+		app := integrationsupport.NewBasicTestApp()
+
+		f := fiber.New()
+		f.Use(Middleware(app.Application))
+		f.Get("/hello", func(c *fiber.Ctx) error {
+			return tc.err
+		})
+
+		if _, err := f.Test(httptest.NewRequest("GET", "/hello", nil)); err != nil {
+			t.Fatal(err)
+		}
+		app.ExpectTxnMetrics(t, internal.WantTxn{
+			Name:      "GET /hello",
+			IsWeb:     true,
+			NumErrors: 1,
+		})
+		app.ExpectErrorEvents(t, []internal.WantEvent{{
+			Intrinsics: map[string]interface{}{
+				"error.class":     tc.code,
+				"error.message":   tc.message,
+				"transactionName": "WebTransaction/Go/GET /hello",
+			},
+		}})
+	}
+}
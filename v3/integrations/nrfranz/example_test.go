@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrfranz_test
+
+import (
+	"context"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrfranz"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigDistributedTracerEnabled(true),
+	)
+	cl, err := kgo.NewClient(
+		kgo.SeedBrokers("localhost:9092"),
+		kgo.ConsumerGroup("fulfillment"),
+		kgo.ConsumeTopics("orders"),
+		kgo.WithHooks(nrfranz.NewHooks()),
+	)
+	if err != nil {
+		panic(err)
+	}
+	defer cl.Close()
+
+	// Produce a record in a transaction.
+	txn := app.StartTransaction("placeOrder")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	if err := nrfranz.ProduceSync(ctx, cl, &kgo.Record{Topic: "orders", Value: []byte("order 42")}).FirstErr(); err != nil {
+		txn.NoticeError(err)
+	}
+	txn.End()
+
+	// Consume records, each in its own transaction.
+	for {
+		fetches := nrfranz.PollFetches(context.Background(), cl)
+		if fetches.IsClientClosed() {
+			return
+		}
+		fetches.EachRecord(func(r *kgo.Record) {
+			txn := nrfranz.StartConsumeTransaction(app, r)
+			defer txn.End()
+			// ... process the record ...
+		})
+	}
+}
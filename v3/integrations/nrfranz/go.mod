@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrfranz
+
+go 1.18
+
+require (
+	github.com/newrelic/go-agent/v3 v3.13.0
+	github.com/twmb/franz-go v1.15.0
+)
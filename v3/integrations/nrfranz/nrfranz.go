@@ -0,0 +1,170 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrfranz instruments Kafka clients created using
+// https://github.com/twmb/franz-go.
+//
+// Add the Hooks to the client's options:
+//
+//	cl, err := kgo.NewClient(
+//		kgo.SeedBrokers("localhost:9092"),
+//		kgo.WithHooks(nrfranz.NewHooks()),
+//	)
+//
+// Records produced with a context containing a Transaction are timed by a
+// MessageProducerSegment, from when the record is buffered until it has
+// been written or has failed, and the distributed tracing headers of the
+// segment are added to the record's headers.  Use ProduceSync in place of
+// the client's method to also time the whole batch of records:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	results := nrfranz.ProduceSync(ctx, cl, &kgo.Record{Topic: "orders", Value: value})
+//
+// Use PollFetches in place of the client's method to time each poll in the
+// Transaction of the context, if any.  Call StartConsumeTransaction when the
+// processing of each record starts, and end the transaction once it has
+// been processed.  The transaction accepts the distributed tracing headers
+// found in the record's headers:
+//
+//	fetches := nrfranz.PollFetches(ctx, cl)
+//	fetches.EachRecord(func(r *kgo.Record) {
+//		txn := nrfranz.StartConsumeTransaction(app, r)
+//		defer txn.End()
+//		// ... process the record ...
+//	})
+package nrfranz
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func init() { internal.TrackUsage("integration", "messagebroker", "franz-go") }
+
+const library = integrationsupport.KafkaLibrary
+
+// These attributes are added to the transactions created by
+// StartConsumeTransaction.
+const (
+	AttributePartition = integrationsupport.AttributeKafkaPartition
+	AttributeOffset    = integrationsupport.AttributeKafkaOffset
+)
+
+// AttributeRecords is added to the segments of ProduceSync and PollFetches.
+// It is the number of records produced or fetched.
+const AttributeRecords = "kafka.records"
+
+// Hooks instruments the records produced by a kgo.Client.  Create it using
+// NewHooks.
+type Hooks struct{}
+
+var (
+	_ kgo.HookProduceRecordBuffered   = (*Hooks)(nil)
+	_ kgo.HookProduceRecordUnbuffered = (*Hooks)(nil)
+)
+
+// NewHooks creates Hooks which time the records produced with a context
+// containing a Transaction.
+func NewHooks() *Hooks {
+	return &Hooks{}
+}
+
+type segmentKey struct{}
+
+// OnProduceRecordBuffered implements kgo.HookProduceRecordBuffered.  It
+// starts the MessageProducerSegment of the record.
+func (h *Hooks) OnProduceRecordBuffered(r *kgo.Record) {
+	txn := newrelic.FromContext(r.Context)
+	if nil == txn {
+		return
+	}
+	// Records are written, and so their segments end, in other
+	// goroutines.
+	txn = txn.NewGoroutine()
+	s := &newrelic.MessageProducerSegment{
+		StartTime:       txn.StartSegmentNow(),
+		Library:         library,
+		DestinationType: newrelic.MessageTopic,
+		DestinationName: r.Topic,
+	}
+	InsertDistributedTraceHeaders(txn, r)
+	r.Context = context.WithValue(r.Context, segmentKey{}, s)
+}
+
+// OnProduceRecordUnbuffered implements kgo.HookProduceRecordUnbuffered.  It
+// ends the MessageProducerSegment of the record.
+func (h *Hooks) OnProduceRecordUnbuffered(r *kgo.Record, err error) {
+	if nil == r.Context {
+		return
+	}
+	if s, ok := r.Context.Value(segmentKey{}).(*newrelic.MessageProducerSegment); ok {
+		s.End()
+	}
+}
+
+// ProduceSync calls cl.ProduceSync, timing the batch of records using a
+// segment of the Transaction in the context, if any.
+func ProduceSync(ctx context.Context, cl *kgo.Client, rs ...*kgo.Record) kgo.ProduceResults {
+	s := newrelic.FromContext(ctx).StartSegment("Kafka/ProduceSync")
+	defer s.End()
+	s.AddAttribute(AttributeRecords, len(rs))
+	return cl.ProduceSync(ctx, rs...)
+}
+
+// PollFetches calls cl.PollFetches, timing the poll using a segment of the
+// Transaction in the context, if any.
+func PollFetches(ctx context.Context, cl *kgo.Client) kgo.Fetches {
+	s := newrelic.FromContext(ctx).StartSegment("Kafka/PollFetches")
+	defer s.End()
+	fetches := cl.PollFetches(ctx)
+	s.AddAttribute(AttributeRecords, fetches.NumRecords())
+	return fetches
+}
+
+// InsertDistributedTraceHeaders adds the distributed tracing headers of the
+// Transaction to the record's headers, replacing any added previously.
+func InsertDistributedTraceHeaders(txn *newrelic.Transaction, r *kgo.Record) {
+	if nil == txn || nil == r {
+		return
+	}
+	hdrs := integrationsupport.KafkaDistributedTraceHeaders(txn)
+	headers := r.Headers[:0]
+	for _, h := range r.Headers {
+		if !integrationsupport.IsKafkaDistributedTraceHeader(hdrs, h.Key) {
+			headers = append(headers, h)
+		}
+	}
+	for key, values := range hdrs {
+		for _, value := range values {
+			headers = append(headers, kgo.RecordHeader{Key: key, Value: []byte(value)})
+		}
+	}
+	r.Headers = headers
+}
+
+// StartConsumeTransaction starts a transaction for consuming the record.
+// Call it when the processing of the record starts, and end the transaction
+// once the record has been processed.  The transaction is named after the
+// record's topic and accepts the distributed tracing headers found in its
+// headers.  The Application may be nil, in which case nil is returned.
+func StartConsumeTransaction(app *newrelic.Application, r *kgo.Record) *newrelic.Transaction {
+	if nil == app || nil == r {
+		return nil
+	}
+	hdrs := http.Header{}
+	for _, h := range r.Headers {
+		hdrs.Add(h.Key, string(h.Value))
+	}
+	return integrationsupport.StartKafkaConsumeTransaction(app, integrationsupport.KafkaRecord{
+		Topic:     r.Topic,
+		Key:       r.Key,
+		Partition: r.Partition,
+		Offset:    r.Offset,
+		Headers:   hdrs,
+	})
+}
@@ -0,0 +1,180 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrfranz
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/twmb/franz-go/pkg/kgo"
+)
+
+func testApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+}
+
+func header(r *kgo.Record, key string) (string, int) {
+	var value string
+	count := 0
+	for _, h := range r.Headers {
+		if key == h.Key {
+			value = string(h.Value)
+			count++
+		}
+	}
+	return value, count
+}
+
+func TestProduceRecord(t *testing.T) {
+	app := testApp()
+	hooks := NewHooks()
+	txn := app.StartTransaction("produce")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	records := []*kgo.Record{
+		{Topic: "orders", Context: ctx, Headers: []kgo.RecordHeader{{Key: "traceparent", Value: []byte("stale")}}},
+		{Topic: "payments", Context: ctx},
+	}
+	for _, r := range records {
+		hooks.OnProduceRecordBuffered(r)
+	}
+	// Records are written concurrently, in any order.
+	var wg sync.WaitGroup
+	for i := len(records) - 1; i >= 0; i-- {
+		wg.Add(1)
+		go func(r *kgo.Record) {
+			defer wg.Done()
+			hooks.OnProduceRecordUnbuffered(r, nil)
+		}(records[i])
+	}
+	wg.Wait()
+	txn.End()
+
+	for _, r := range records {
+		if v, n := header(r, "traceparent"); 1 != n || "stale" == v {
+			t.Error(r.Topic, v, n)
+		}
+		if _, n := header(r, "newrelic"); 1 != n {
+			t.Error(r.Topic, n)
+		}
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/orders", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/orders", Scope: "OtherTransaction/Go/produce", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/payments", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/payments", Scope: "OtherTransaction/Go/produce", Forced: false, Data: []float64{1}},
+	})
+}
+
+func TestProduceRecordWithoutTransaction(t *testing.T) {
+	hooks := NewHooks()
+	r := &kgo.Record{Topic: "orders"}
+	hooks.OnProduceRecordBuffered(r)
+	hooks.OnProduceRecordUnbuffered(r, errors.New("unable to produce"))
+	if 0 != len(r.Headers) || nil != r.Context {
+		t.Error(r.Headers, r.Context)
+	}
+}
+
+func producedRecord(app integrationsupport.ExpectApp) *kgo.Record {
+	txn := app.StartTransaction("produce")
+	r := &kgo.Record{
+		Topic:     "orders",
+		Key:       []byte("order-42"),
+		Partition: 3,
+		Offset:    7,
+	}
+	InsertDistributedTraceHeaders(txn, r)
+	txn.End()
+	return r
+}
+
+func TestConsumeRecord(t *testing.T) {
+	app := testApp()
+	r := producedRecord(app)
+	txn := StartConsumeTransaction(app.Application, r)
+	if nil == txn {
+		t.Fatal("transaction not started")
+	}
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/produce",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":                     "OtherTransaction/Go/Message/Kafka/Topic/Named/orders",
+				"guid":                     internal.MatchAnything,
+				"traceId":                  internal.MatchAnything,
+				"priority":                 internal.MatchAnything,
+				"sampled":                  internal.MatchAnything,
+				"parent.type":              "App",
+				"parent.account":           "123",
+				"parent.app":               "456",
+				"parent.transportType":     "Kafka",
+				"parent.transportDuration": internal.MatchAnything,
+				"parentId":                 internal.MatchAnything,
+				"parentSpanId":             internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributePartition: 3,
+				AttributeOffset:    7,
+			},
+			AgentAttributes: map[string]interface{}{
+				"message.routingKey": "order-42",
+			},
+		},
+	})
+}
+
+func TestStartConsumeTransactionNilApp(t *testing.T) {
+	if txn := StartConsumeTransaction(nil, &kgo.Record{Topic: "orders"}); nil != txn {
+		t.Error(txn)
+	}
+}
+
+func TestProduceSyncAndPollFetches(t *testing.T) {
+	app := testApp()
+	cl, err := kgo.NewClient(kgo.WithHooks(NewHooks()))
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer cl.Close()
+
+	txn := app.StartTransaction("batches")
+	ctx, cancel := context.WithCancel(newrelic.NewContext(context.Background(), txn))
+	// The context is canceled so that nothing waits for a broker.
+	cancel()
+	ProduceSync(ctx, cl, &kgo.Record{Topic: "orders"})
+	PollFetches(ctx, cl)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/Kafka/ProduceSync", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "Custom/Kafka/ProduceSync", Scope: "OtherTransaction/Go/batches", Forced: false, Data: []float64{1}},
+		{Name: "Custom/Kafka/PollFetches", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "Custom/Kafka/PollFetches", Scope: "OtherTransaction/Go/batches", Forced: false, Data: []float64{1}},
+	})
+
+	// Without a Transaction, the calls are not timed.
+	ctx, cancel = context.WithCancel(context.Background())
+	cancel()
+	ProduceSync(ctx, cl)
+	PollFetches(ctx, cl)
+}
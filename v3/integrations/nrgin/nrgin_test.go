@@ -270,6 +270,7 @@ func TestStatusCodes(t *testing.T) {
 			"httpResponseCode":             expectCode,
 			"http.statusCode":              expectCode,
 			"request.method":               "GET",
+			"http.flavor":                  "1.1",
 			"request.uri":                  "/err",
 			"response.headers.contentType": "text/plain; charset=utf-8",
 		},
@@ -319,6 +320,7 @@ func TestNoResponseBody(t *testing.T) {
 			"httpResponseCode": expectCode,
 			"http.statusCode":  expectCode,
 			"request.method":   "GET",
+			"http.flavor":      "1.1",
 			"request.uri":      "/nobody",
 		},
 	}})
@@ -0,0 +1,36 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgobreaker_test
+
+import (
+	"io/ioutil"
+	"net/http"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrgobreaker"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/sony/gobreaker"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		// The name is used as the name of the downstream service.
+		Name:          "payments",
+		OnStateChange: nrgobreaker.OnStateChange(app, nil),
+	})
+
+	body, err := nrgobreaker.Execute(app, cb, func() (interface{}, error) {
+		resp, err := http.Get("http://payments.example.com/status")
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		return ioutil.ReadAll(resp.Body)
+	})
+	_, _ = body, err
+}
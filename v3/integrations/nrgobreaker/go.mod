@@ -0,0 +1,10 @@
+module github.com/newrelic/go-agent/v3/integrations/nrgobreaker
+
+// As of Nov 2020, go 1.12 is in the gobreaker go.mod file:
+// https://github.com/sony/gobreaker/blob/master/go.mod
+go 1.12
+
+require (
+	github.com/newrelic/go-agent/v3 v3.13.0
+	github.com/sony/gobreaker v0.4.1
+)
@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrgobreaker reports the state of https://github.com/sony/gobreaker
+// circuit breakers.
+//
+// Circuit breaker state transitions are recorded using
+// Application.RecordCircuitBreakerTransition
+// (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#Application.RecordCircuitBreakerTransition)
+// and calls rejected by an open circuit breaker are recorded using
+// Application.RecordCircuitBreakerShortCircuits
+// (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#Application.RecordCircuitBreakerShortCircuits).
+// The circuit breaker's name is used as the name of the downstream service,
+// so give each circuit breaker the name of the service it protects.
+//
+// Use OnStateChange as the circuit breaker's OnStateChange setting:
+//
+//	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+//		Name:          "payments",
+//		OnStateChange: nrgobreaker.OnStateChange(app, nil),
+//	})
+//
+// Then use Execute in place of the circuit breaker's Execute method:
+//
+//	body, err := nrgobreaker.Execute(app, cb, func() (interface{}, error) {
+//		return callPayments()
+//	})
+package nrgobreaker
+
+import (
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/sony/gobreaker"
+)
+
+func init() { internal.TrackUsage("integration", "circuitbreaker", "gobreaker") }
+
+// OnStateChange returns a function for the OnStateChange field of
+// gobreaker.Settings which records each state transition.  If next is not
+// nil, it is called after the transition is recorded.
+func OnStateChange(app *newrelic.Application, next func(name string, from gobreaker.State, to gobreaker.State)) func(name string, from gobreaker.State, to gobreaker.State) {
+	return func(name string, from gobreaker.State, to gobreaker.State) {
+		app.RecordCircuitBreakerTransition(name, state(from), state(to))
+		if nil != next {
+			next(name, from, to)
+		}
+	}
+}
+
+func state(s gobreaker.State) newrelic.CircuitBreakerState {
+	switch s {
+	case gobreaker.StateClosed:
+		return newrelic.CircuitBreakerClosed
+	case gobreaker.StateOpen:
+		return newrelic.CircuitBreakerOpen
+	case gobreaker.StateHalfOpen:
+		return newrelic.CircuitBreakerHalfOpen
+	default:
+		return newrelic.CircuitBreakerState(s.String())
+	}
+}
+
+// Execute calls cb.Execute and records the call as short-circuited if it
+// was rejected by the circuit breaker, either because the circuit breaker
+// is open or because too many requests were made while it is half-open.
+func Execute(app *newrelic.Application, cb *gobreaker.CircuitBreaker, req func() (interface{}, error)) (interface{}, error) {
+	result, err := cb.Execute(req)
+	if err == gobreaker.ErrOpenState || err == gobreaker.ErrTooManyRequests {
+		app.RecordCircuitBreakerShortCircuits(cb.Name(), 1)
+	}
+	return result, err
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgobreaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/sony/gobreaker"
+)
+
+var errDownstream = errors.New("downstream failure")
+
+func fail() (interface{}, error) { return nil, errDownstream }
+
+func TestExecute(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	var transitions []gobreaker.State
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name: "payments",
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= 1
+		},
+		OnStateChange: OnStateChange(app.Application, func(name string, from gobreaker.State, to gobreaker.State) {
+			transitions = append(transitions, to)
+		}),
+	})
+
+	if _, err := Execute(app.Application, cb, fail); err != errDownstream {
+		t.Error(err)
+	}
+	if _, err := Execute(app.Application, cb, fail); err != gobreaker.ErrOpenState {
+		t.Error(err)
+	}
+	if len(transitions) != 1 || transitions[0] != gobreaker.StateOpen {
+		t.Error(transitions)
+	}
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments/State/open", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/payments/ShortCircuited", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/all/ShortCircuited", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "CircuitBreakerStateChange",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"service": "payments",
+			"from":    "closed",
+			"to":      "open",
+		},
+	}})
+}
+
+func TestOnStateChangeNilApplication(t *testing.T) {
+	fn := OnStateChange(nil, nil)
+	fn("payments", gobreaker.StateClosed, gobreaker.StateOpen)
+}
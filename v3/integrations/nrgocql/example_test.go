@@ -0,0 +1,42 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgocql_test
+
+import (
+	"context"
+
+	"github.com/gocql/gocql"
+	"github.com/newrelic/go-agent/v3/integrations/nrgocql"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+
+	startup := app.StartTransaction("startup")
+	observer := nrgocql.NewObserver()
+	observer.ConnectTransaction = startup
+	cluster := gocql.NewCluster("127.0.0.1")
+	cluster.Keyspace = "example"
+	cluster.QueryObserver = observer
+	cluster.BatchObserver = observer
+	cluster.ConnectObserver = observer
+	session, err := cluster.CreateSession()
+	startup.End()
+	if err != nil {
+		panic(err)
+	}
+	defer session.Close()
+
+	txn := app.StartTransaction("createUser")
+	defer txn.End()
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	if err := session.Query("INSERT INTO users (id, name) VALUES (?, ?)", 1, "gopher").WithContext(ctx).Exec(); err != nil {
+		txn.NoticeError(err)
+	}
+}
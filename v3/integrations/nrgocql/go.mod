@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrgocql
+
+go 1.13
+
+require (
+	github.com/gocql/gocql v1.6.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
@@ -0,0 +1,161 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrgocql instruments Cassandra calls made using
+// https://github.com/gocql/gocql.
+//
+// Set the observers of the cluster config to an Observer created by
+// NewObserver:
+//
+//	cluster := gocql.NewCluster("127.0.0.1")
+//	observer := nrgocql.NewObserver()
+//	cluster.QueryObserver = observer
+//	cluster.BatchObserver = observer
+//	session, err := cluster.CreateSession()
+//
+// Then provide a context containing a newrelic.Transaction to the queries
+// and batches to be timed:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	err := session.Query("SELECT name FROM users WHERE id = ?", id).WithContext(ctx).Exec()
+//
+// Each attempt of a query is timed by a DatastoreSegment whose operation and
+// collection are parsed from the CQL, and whose database name is the
+// keyspace.  Each attempt of a batch is timed by a single segment with the
+// operation "batch".  Segments include the host and port of the Cassandra
+// node which served the attempt.
+//
+// gocql does not provide a context to the ConnectObserver, so connections
+// are only timed when the Observer's ConnectTransaction is set, eg. to a
+// transaction which times the startup of the application.
+package nrgocql
+
+import (
+	"context"
+	"net"
+	"strings"
+
+	"github.com/gocql/gocql"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
+)
+
+func init() { internal.TrackUsage("integration", "datastore", "gocql") }
+
+// These attributes are added to the segments of queries and batches.
+const (
+	// AttributeRows is the number of rows returned by a query.
+	AttributeRows = "db.cassandra.rows"
+	// AttributeAttempt is the number of the attempt, starting at zero,
+	// when a query or batch is retried.
+	AttributeAttempt = "db.cassandra.attempt"
+)
+
+// Observer times the queries, batches and connections of a gocql session
+// using DatastoreSegments.  Create it using NewObserver.
+type Observer struct {
+	// ParseQuery sets the Operation and Collection of the segment of each
+	// query.  By default it is sqlparse.ParseQuery.
+	ParseQuery func(segment *newrelic.DatastoreSegment, query string)
+	// ConnectTransaction is the transaction which records the
+	// connections made to Cassandra nodes.  If it is nil, connections are
+	// not recorded.
+	ConnectTransaction *newrelic.Transaction
+}
+
+var (
+	_ gocql.QueryObserver   = (*Observer)(nil)
+	_ gocql.BatchObserver   = (*Observer)(nil)
+	_ gocql.ConnectObserver = (*Observer)(nil)
+)
+
+// NewObserver creates an Observer to be assigned to the QueryObserver,
+// BatchObserver, and ConnectObserver of a gocql.ClusterConfig.
+func NewObserver() *Observer {
+	return &Observer{ParseQuery: sqlparse.ParseQuery}
+}
+
+// baseSegment returns a segment with the keyspace and the location of the
+// host.
+func baseSegment(keyspace string, host *gocql.HostInfo) newrelic.DatastoreSegment {
+	s := newrelic.DatastoreSegment{
+		Product:      newrelic.DatastoreCassandra,
+		DatabaseName: keyspace,
+	}
+	if nil != host {
+		if h, port, err := net.SplitHostPort(host.HostnameAndPort()); nil == err {
+			s.Host = h
+			s.PortPathOrID = port
+		}
+	}
+	return s
+}
+
+func (o *Observer) parseQuery(s *newrelic.DatastoreSegment, query string) {
+	if nil != o.ParseQuery {
+		o.ParseQuery(s, query)
+	}
+}
+
+// ObserveQuery implements gocql.QueryObserver.  It records the segment of
+// the query attempt if the context contains a Transaction.
+func (o *Observer) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return
+	}
+	s := baseSegment(q.Keyspace, q.Host)
+	s.ParameterizedQuery = q.Statement
+	o.parseQuery(&s, q.Statement)
+	s.StartTime = txn.StartSegmentAt(q.Start)
+	s.AddAttribute(AttributeRows, q.Rows)
+	if q.Attempt > 0 {
+		s.AddAttribute(AttributeAttempt, q.Attempt)
+	}
+	s.EndAt(q.End)
+}
+
+// ObserveBatch implements gocql.BatchObserver.  It records the segment of
+// the batch attempt if the context contains a Transaction.  The collection
+// of the segment is set if every statement of the batch uses the same
+// collection.
+func (o *Observer) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return
+	}
+	s := baseSegment(b.Keyspace, b.Host)
+	s.Operation = "batch"
+	s.ParameterizedQuery = strings.Join(b.Statements, "; ")
+	for i, stmt := range b.Statements {
+		var parsed newrelic.DatastoreSegment
+		o.parseQuery(&parsed, stmt)
+		if 0 == i {
+			s.Collection = parsed.Collection
+		} else if s.Collection != parsed.Collection {
+			s.Collection = ""
+			break
+		}
+	}
+	s.StartTime = txn.StartSegmentAt(b.Start)
+	if b.Attempt > 0 {
+		s.AddAttribute(AttributeAttempt, b.Attempt)
+	}
+	s.EndAt(b.End)
+}
+
+// ObserveConnect implements gocql.ConnectObserver.  It records the segment
+// of the connection if the ConnectTransaction is set.
+func (o *Observer) ObserveConnect(c gocql.ObservedConnect) {
+	if nil == o.ConnectTransaction {
+		return
+	}
+	// Connections are made by the goroutines of the session's connection
+	// pool.
+	txn := o.ConnectTransaction.NewGoroutine()
+	s := baseSegment("", c.Host)
+	s.Operation = "connect"
+	s.StartTime = txn.StartSegmentAt(c.Start)
+	s.EndAt(c.End)
+}
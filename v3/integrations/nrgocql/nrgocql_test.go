@@ -0,0 +1,126 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgocql
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func TestObserveQuery(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("query")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	end := time.Now()
+	NewObserver().ObserveQuery(ctx, gocql.ObservedQuery{
+		Keyspace:  "shop",
+		Statement: "SELECT name FROM shop.users WHERE id = ?",
+		Start:     end.Add(-2 * time.Second),
+		End:       end,
+		Rows:      3,
+		Attempt:   1,
+	})
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/Cassandra/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Cassandra/select", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Cassandra/users/select", Scope: "OtherTransaction/Go/query", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/Cassandra/users/select",
+				"category":  "datastore",
+				"component": "Cassandra",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeRows:    3,
+				AttributeAttempt: 1,
+			},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "SELECT name FROM shop.users WHERE id = ?",
+				"db.instance":   "shop",
+				"db.collection": "users",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/query",
+				"transaction.name": "OtherTransaction/Go/query",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestObserveBatch(t *testing.T) {
+	testcases := []struct {
+		statements []string
+		metric     string
+	}{
+		{
+			statements: []string{"INSERT INTO users (id) VALUES (?)", "UPDATE users SET name = ? WHERE id = ?"},
+			metric:     "Datastore/statement/Cassandra/users/batch",
+		},
+		{
+			statements: []string{"INSERT INTO users (id) VALUES (?)", "INSERT INTO orders (id) VALUES (?)"},
+			metric:     "Datastore/operation/Cassandra/batch",
+		},
+	}
+	for _, tc := range testcases {
+		app := integrationsupport.NewBasicTestApp()
+		txn := app.StartTransaction("batch")
+		ctx := newrelic.NewContext(context.Background(), txn)
+
+		now := time.Now()
+		NewObserver().ObserveBatch(ctx, gocql.ObservedBatch{
+			Keyspace:   "shop",
+			Statements: tc.statements,
+			Start:      now.Add(-time.Second),
+			End:        now,
+		})
+		txn.End()
+
+		app.ExpectMetricsPresent(t, []internal.WantMetric{
+			{Name: tc.metric, Scope: "OtherTransaction/Go/batch", Forced: false, Data: nil},
+		})
+	}
+}
+
+func TestObserveConnect(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("startup")
+
+	observer := NewObserver()
+	now := time.Now()
+	connect := gocql.ObservedConnect{Start: now.Add(-time.Second), End: now}
+	// Connections are not recorded without a ConnectTransaction.
+	observer.ObserveConnect(connect)
+	observer.ConnectTransaction = txn
+	observer.ObserveConnect(connect)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/operation/Cassandra/connect", Scope: "OtherTransaction/Go/startup", Forced: false, Data: []float64{1}},
+	})
+}
+
+func TestWithoutTransaction(t *testing.T) {
+	observer := NewObserver()
+	observer.ObserveQuery(context.Background(), gocql.ObservedQuery{Statement: "SELECT * FROM users"})
+	observer.ObserveBatch(context.Background(), gocql.ObservedBatch{Statements: []string{"SELECT * FROM users"}})
+}
@@ -0,0 +1,34 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgocron_test
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/newrelic/go-agent/v3/integrations/nrgocron"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func refreshCache(ctx context.Context) {
+	txn := newrelic.FromContext(ctx)
+	defer txn.StartSegment("fetch").End()
+	time.Sleep(100 * time.Millisecond)
+}
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+	s := gocron.NewScheduler(time.UTC)
+
+	s.Every(1).Minute().Name("refreshCache").DoWithJobDetails(nrgocron.WrapFunc(app, func(ctx context.Context, job gocron.Job) {
+		refreshCache(ctx)
+	}))
+
+	s.StartAsync()
+	defer s.Stop()
+}
@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrgocron
+
+go 1.16
+
+require (
+	github.com/go-co-op/gocron v1.37.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
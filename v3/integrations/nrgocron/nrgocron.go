@@ -0,0 +1,83 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrgocron instruments jobs scheduled using
+// https://github.com/go-co-op/gocron.
+//
+// Each run of a job is recorded as a background transaction named after the
+// gocron.Job, ie. the name given using Scheduler.Name or Job.Name, or else
+// the name of the function.  Wrap the job's function using WrapFunc and
+// schedule it using Scheduler.DoWithJobDetails.  Instead of:
+//
+//	s.Every(1).Minute().Do(refreshCache)
+//
+// Use:
+//
+//	s.Every(1).Minute().Name("refreshCache").DoWithJobDetails(nrgocron.WrapFunc(app, func(ctx context.Context, job gocron.Job) {
+//		refreshCache(ctx)
+//	}))
+//
+// The transaction is added to the context passed to the function, so it can
+// be retrieved using newrelic.FromContext.  The transactions have the
+// following attributes:
+//
+//	job.schedule   the schedule of the gocron.Job, eg. "every 1 minutes"
+//	job.duration   the time in seconds taken by the run
+//	job.overlap    true if a previous run of the job was still in progress
+//
+// Runs which gocron skips, eg. in singleton mode, are not recorded.
+package nrgocron
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/go-co-op/gocron"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "scheduler", "gocron") }
+
+// These attributes are added to the transactions created by WrapFunc.
+const (
+	AttributeSchedule = integrationsupport.AttributeJobSchedule
+	AttributeDuration = integrationsupport.AttributeJobDuration
+	AttributeOverlap  = integrationsupport.AttributeJobOverlap
+)
+
+// WrapFunc returns a job function which records each run of fn as a
+// background transaction.  Schedule it using Scheduler.DoWithJobDetails,
+// which passes the gocron.Job to the function.  The Application may be nil,
+// in which case no transactions are created.
+func WrapFunc(app *newrelic.Application, fn func(ctx context.Context, job gocron.Job)) func(job gocron.Job) {
+	return func(job gocron.Job) {
+		// The run has been counted as started, but not as finished.
+		overlap := job.RunCount()-job.FinishedRunCount() > 1
+		integrationsupport.RunScheduledJob(app, job.GetName(), schedule(job), overlap, func(ctx context.Context) {
+			fn(ctx, job)
+		})
+	}
+}
+
+// schedule describes the schedule of the job, eg. "every 2 days at 09:00".
+// The interval of jobs scheduled using a time.Duration and the expression
+// of jobs scheduled using Scheduler.Cron are not available, so only their
+// unit, "duration" or "crontab", is returned.
+func schedule(job gocron.Job) string {
+	unit := job.ScheduledUnit()
+	interval := job.ScheduledInterval()
+	if interval <= 0 {
+		return unit
+	}
+	s := fmt.Sprintf("every %d %s", interval, unit)
+	switch unit {
+	case "days", "weeks", "months":
+		if times := job.ScheduledAtTimes(); len(times) > 0 {
+			s += " at " + strings.Join(times, ";")
+		}
+	}
+	return s
+}
@@ -0,0 +1,72 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgocron
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/go-co-op/gocron"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func TestWrapFunc(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	s := gocron.NewScheduler(time.UTC)
+	var found bool
+	_, err := s.Every(1).Minute().Name("refresh").DoWithJobDetails(WrapFunc(app.Application, func(ctx context.Context, job gocron.Job) {
+		found = nil != newrelic.FromContext(ctx)
+	}))
+	if nil != err {
+		t.Fatal(err)
+	}
+	s.RunAll()
+	if !found {
+		t.Error("transaction not added to context")
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/refresh",
+		},
+		UserAttributes: map[string]interface{}{
+			AttributeSchedule: "every 1 minutes",
+			AttributeOverlap:  false,
+			AttributeDuration: internal.MatchAnything,
+		},
+	}})
+}
+
+func TestSchedule(t *testing.T) {
+	s := gocron.NewScheduler(time.UTC)
+	fn := func(gocron.Job) {}
+	daily, _ := s.Every(2).Days().At("09:00").At("11:30").DoWithJobDetails(fn)
+	hourly, _ := s.Every(1).Hour().DoWithJobDetails(fn)
+	cron, _ := s.Cron("*/5 * * * *").DoWithJobDetails(fn)
+	testcases := []struct {
+		job    *gocron.Job
+		expect string
+	}{
+		{job: daily, expect: "every 2 days at 09:00;11:30"},
+		{job: hourly, expect: "every 1 hours"},
+		{job: cron, expect: "crontab"},
+	}
+	for _, tc := range testcases {
+		if out := schedule(*tc.job); out != tc.expect {
+			t.Error(out, tc.expect)
+		}
+	}
+}
+
+func TestWrapFuncNilApplication(t *testing.T) {
+	s := gocron.NewScheduler(time.UTC)
+	calls := 0
+	s.Every(1).Minute().DoWithJobDetails(WrapFunc(nil, func(context.Context, gocron.Job) { calls++ }))
+	s.RunAll()
+	if 1 != calls {
+		t.Error(calls)
+	}
+}
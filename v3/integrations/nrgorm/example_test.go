@@ -0,0 +1,39 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgorm_test
+
+import (
+	"context"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrgorm"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"gorm.io/gorm"
+)
+
+type User struct {
+	ID   uint
+	Name string
+}
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+
+	// db is opened using the driver of your database, eg.
+	// gorm.Open(postgres.Open(dsn), &gorm.Config{}).
+	var db *gorm.DB
+	if err := db.Use(nrgorm.NewPlugin()); err != nil {
+		panic(err)
+	}
+
+	txn := app.StartTransaction("createUser")
+	defer txn.End()
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	if err := db.WithContext(ctx).Create(&User{Name: "gopher"}).Error; err != nil {
+		txn.NoticeError(err)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrgorm
+
+go 1.18
+
+require (
+	github.com/newrelic/go-agent/v3 v3.13.0
+	gorm.io/gorm v1.25.0
+)
@@ -0,0 +1,157 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrgorm instruments database calls made using https://gorm.io/gorm
+// version 2.
+//
+// Unlike instrumenting the database driver, which only sees SQL, this
+// package uses GORM callbacks so that each segment is named by the GORM
+// operation performed.  Register the plugin with the gorm.DB:
+//
+//	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+//	if err != nil {
+//		panic(err)
+//	}
+//	if err := db.Use(nrgorm.NewPlugin()); err != nil {
+//		panic(err)
+//	}
+//
+// Then provide a context containing a newrelic.Transaction to the calls to
+// be timed:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	db.WithContext(ctx).Create(&User{Name: "gopher"})
+//
+// Each Create, Query, Update, and Delete is timed by a DatastoreSegment with
+// the operation "create", "query", "update", or "delete" and the statement's
+// table as its collection.  The operation and collection of Raw, Exec, Row,
+// and Rows calls are parsed from their SQL.  The table and the number of
+// rows affected are added to each segment as the AttributeTable and
+// AttributeRowsAffected attributes.
+package nrgorm
+
+import (
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
+	"gorm.io/gorm"
+)
+
+func init() { internal.TrackUsage("integration", "datastore", "gorm") }
+
+// These attributes are added to the segments of GORM operations.
+const (
+	AttributeTable        = "gorm.table"
+	AttributeRowsAffected = "gorm.rowsAffected"
+)
+
+const segmentKey = "newrelic:segment"
+
+// Plugin registers the GORM callbacks which time each operation.  Create it
+// using NewPlugin.
+type Plugin struct{}
+
+var _ gorm.Plugin = Plugin{}
+
+// NewPlugin creates a Plugin to be registered using gorm.DB.Use.
+func NewPlugin() Plugin { return Plugin{} }
+
+// Name implements gorm.Plugin.
+func (Plugin) Name() string { return "newrelic" }
+
+// Initialize implements gorm.Plugin.  It registers callbacks before and
+// after GORM's create, query, update, delete, row, and raw callbacks.
+func (Plugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	for _, err := range []error{
+		cb.Create().Before("gorm:create").Register("newrelic:before_create", startSegment("create")),
+		cb.Create().After("gorm:create").Register("newrelic:after_create", endSegment(true)),
+		cb.Query().Before("gorm:query").Register("newrelic:before_query", startSegment("query")),
+		cb.Query().After("gorm:query").Register("newrelic:after_query", endSegment(true)),
+		cb.Update().Before("gorm:update").Register("newrelic:before_update", startSegment("update")),
+		cb.Update().After("gorm:update").Register("newrelic:after_update", endSegment(true)),
+		cb.Delete().Before("gorm:delete").Register("newrelic:before_delete", startSegment("delete")),
+		cb.Delete().After("gorm:delete").Register("newrelic:after_delete", endSegment(true)),
+		// The rows of Row and Rows calls are read after the callbacks
+		// have run, so no count of rows is recorded.
+		cb.Row().Before("gorm:row").Register("newrelic:before_row", startSegment("")),
+		cb.Row().After("gorm:row").Register("newrelic:after_row", endSegment(false)),
+		cb.Raw().Before("gorm:raw").Register("newrelic:before_raw", startSegment("")),
+		cb.Raw().After("gorm:raw").Register("newrelic:after_raw", endSegment(true)),
+	} {
+		if nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// product returns the datastore product of the dialector's name.
+func product(db *gorm.DB) newrelic.DatastoreProduct {
+	if nil == db.Config || nil == db.Dialector {
+		return ""
+	}
+	switch name := db.Dialector.Name(); name {
+	case "mysql":
+		return newrelic.DatastoreMySQL
+	case "postgres":
+		return newrelic.DatastorePostgres
+	case "sqlite":
+		return newrelic.DatastoreSQLite
+	case "sqlserver":
+		return newrelic.DatastoreMSSQL
+	default:
+		return newrelic.DatastoreProduct(name)
+	}
+}
+
+// startSegment returns a callback which starts a segment with the
+// operation if the statement's context contains a Transaction.  If the
+// operation is empty, it is parsed from the statement's SQL.
+func startSegment(operation string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if nil == db.Statement {
+			return
+		}
+		txn := newrelic.FromContext(db.Statement.Context)
+		if nil == txn {
+			return
+		}
+		db.InstanceSet(segmentKey, &newrelic.DatastoreSegment{
+			StartTime: txn.StartSegmentNow(),
+			Product:   product(db),
+			Operation: operation,
+		})
+	}
+}
+
+// endSegment returns a callback which ends the segment started by
+// startSegment.
+func endSegment(countRows bool) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		if nil == db.Statement {
+			return
+		}
+		v, _ := db.InstanceGet(segmentKey)
+		s, ok := v.(*newrelic.DatastoreSegment)
+		if !ok {
+			return
+		}
+		db.InstanceSet(segmentKey, nil)
+		query := db.Statement.SQL.String()
+		s.ParameterizedQuery = query
+		if "" == s.Operation {
+			sqlparse.ParseQuery(s, query)
+		}
+		if table := db.Statement.Table; "" != table {
+			if "" == s.Collection {
+				s.Collection = table
+			}
+			s.AddAttribute(AttributeTable, table)
+		}
+		if countRows {
+			s.AddAttribute(AttributeRowsAffected, db.RowsAffected)
+		}
+		s.End()
+	}
+}
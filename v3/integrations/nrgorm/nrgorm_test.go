@@ -0,0 +1,141 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgorm
+
+import (
+	"context"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"gorm.io/gorm"
+)
+
+// testDialector overrides the Name of a gorm.Dialector.  Its other methods
+// are not called.
+type testDialector struct {
+	gorm.Dialector
+	name string
+}
+
+func (d testDialector) Name() string { return d.name }
+
+func testDB(ctx context.Context, dialector, table, sql string) *gorm.DB {
+	db := &gorm.DB{
+		Config:    &gorm.Config{Dialector: testDialector{name: dialector}},
+		Statement: &gorm.Statement{Context: ctx, Table: table},
+	}
+	db.Statement.SQL.WriteString(sql)
+	return db
+}
+
+func TestProduct(t *testing.T) {
+	testcases := []struct {
+		dialector string
+		expect    newrelic.DatastoreProduct
+	}{
+		{dialector: "mysql", expect: newrelic.DatastoreMySQL},
+		{dialector: "postgres", expect: newrelic.DatastorePostgres},
+		{dialector: "sqlite", expect: newrelic.DatastoreSQLite},
+		{dialector: "sqlserver", expect: newrelic.DatastoreMSSQL},
+		{dialector: "clickhouse", expect: newrelic.DatastoreProduct("clickhouse")},
+	}
+	for _, tc := range testcases {
+		if p := product(testDB(nil, tc.dialector, "", "")); p != tc.expect {
+			t.Errorf("dialector %s: expected %s got %s", tc.dialector, tc.expect, p)
+		}
+	}
+	if p := product(&gorm.DB{Config: &gorm.Config{}}); "" != p {
+		t.Error(p)
+	}
+}
+
+func TestCreate(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("create")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	db := testDB(ctx, "postgres", "users", `INSERT INTO "users" ("name") VALUES ($1)`)
+	startSegment("create")(db)
+	db.RowsAffected = 2
+	endSegment(true)(db)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/Postgres/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Postgres/create", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Postgres/users/create", Scope: "OtherTransaction/Go/create", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/Postgres/users/create",
+				"category":  "datastore",
+				"component": "Postgres",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeTable:        "users",
+				AttributeRowsAffected: 2,
+			},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  `INSERT INTO "users" ("name") VALUES ($1)`,
+				"db.collection": "users",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/create",
+				"transaction.name": "OtherTransaction/Go/create",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestRaw(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("raw")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	db := testDB(ctx, "mysql", "", "UPDATE orders SET status = ? WHERE id = ?")
+	startSegment("")(db)
+	endSegment(true)(db)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/operation/MySQL/update", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/MySQL/orders/update", Scope: "OtherTransaction/Go/raw", Forced: false, Data: nil},
+	})
+}
+
+func TestWithoutTransaction(t *testing.T) {
+	db := testDB(context.Background(), "postgres", "users", "SELECT * FROM users")
+	startSegment("query")(db)
+	if v, ok := db.InstanceGet(segmentKey); ok && nil != v {
+		t.Error("segment started without a transaction", v)
+	}
+	endSegment(true)(db)
+}
+
+func TestSegmentEndedOnce(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("query")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	db := testDB(ctx, "postgres", "users", "SELECT * FROM users")
+	startSegment("query")(db)
+	endSegment(true)(db)
+	endSegment(true)(db)
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/statement/Postgres/users/query", Scope: "OtherTransaction/Go/query", Forced: false, Data: []float64{1}},
+	})
+}
@@ -8,7 +8,9 @@ require (
 	// protobuf v1.3.0 is the earliest version using modules, we use v1.3.1
 	// because all dependencies were removed in this version.
 	github.com/golang/protobuf v1.3.1
-	github.com/newrelic/go-agent/v3 v3.0.0
+	// v3.13.0 is required for the gRPC message size and compression
+	// attributes.
+	github.com/newrelic/go-agent/v3 v3.13.0
 	// v1.15.0 is the earliest version of grpc using modules.
 	google.golang.org/grpc v1.15.0
 )
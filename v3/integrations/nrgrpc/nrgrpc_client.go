@@ -9,6 +9,9 @@ import (
 	"net/http"
 	"net/url"
 	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
 
 	newrelic "github.com/newrelic/go-agent/v3/newrelic"
 	"google.golang.org/grpc"
@@ -33,10 +36,16 @@ func getURL(method, target string) *url.URL {
 }
 
 // startClientSegment starts an ExternalSegment and adds Distributed Trace
-// headers to the outgoing grpc metadata in the context.
-func startClientSegment(ctx context.Context, method, target string) (*newrelic.ExternalSegment, context.Context) {
+// headers to the outgoing grpc metadata in the context.  The segment of a
+// stream is started on a new goroutine of the transaction, since the stream
+// may outlive segments started after it, and may be ended by another
+// goroutine.
+func startClientSegment(ctx context.Context, method, target string, stream bool) (*newrelic.ExternalSegment, context.Context) {
 	var seg *newrelic.ExternalSegment
 	if txn := newrelic.FromContext(ctx); nil != txn {
+		if stream {
+			txn = txn.NewGoroutine()
+		}
 		seg = newrelic.StartExternalSegment(txn, nil)
 
 		method = strings.TrimPrefix(method, "/")
@@ -84,23 +93,76 @@ func startClientSegment(ctx context.Context, method, target string) (*newrelic.E
 // This interceptor only instruments unary calls.  You must use both
 // UnaryClientInterceptor and StreamClientInterceptor to instrument unary and
 // streaming calls.  These interceptors add headers to the call metadata if
-// distributed tracing is enabled.
+// distributed tracing is enabled.  The size and number of the request and
+// response messages and the compression of the request are added to the
+// segment as attributes.
 func UnaryClientInterceptor(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
-	seg, ctx := startClientSegment(ctx, method, cc.Target())
-	defer seg.End()
-	return invoker(ctx, method, req, reply, cc, opts...)
+	seg, ctx := startClientSegment(ctx, method, cc.Target(), false)
+	stats := &messageStats{compression: clientCompression(opts)}
+	stats.addRequest(req)
+	err := invoker(ctx, method, req, reply, cc, opts...)
+	if nil == err {
+		stats.addResponse(reply)
+	}
+	endClientSegment(seg, stats)
+	return err
+}
+
+func endClientSegment(seg *newrelic.ExternalSegment, stats *messageStats) {
+	if nil == seg {
+		return
+	}
+	for key, val := range stats.attributes() {
+		seg.AddAttribute(key, val)
+	}
+	seg.End()
 }
 
 type wrappedClientStream struct {
 	grpc.ClientStream
 	segment       *newrelic.ExternalSegment
+	stats         *messageStats
+	metrics       *streamMetrics
 	isUnaryServer bool
+	// done is closed when the segment is ended.
+	done    chan struct{}
+	endOnce sync.Once
+}
+
+// end ends the segment of the stream and records the number of messages.
+// It is called when the stream has finished or failed, and when its context
+// is done, so only the first call has any effect.
+func (s *wrappedClientStream) end() {
+	s.endOnce.Do(func() {
+		close(s.done)
+		s.metrics.record(atomic.LoadInt64(&s.stats.requestMessages), atomic.LoadInt64(&s.stats.responseMessages))
+		endClientSegment(s.segment, s.stats)
+	})
 }
 
-func (s wrappedClientStream) RecvMsg(m interface{}) error {
+func (s *wrappedClientStream) SendMsg(m interface{}) error {
+	start := time.Now()
+	err := s.ClientStream.SendMsg(m)
+	if nil == err {
+		s.stats.addRequest(m)
+		s.metrics.recordSend(time.Since(start))
+	} else if io.EOF != err {
+		// io.EOF means the stream was aborted, and the status is
+		// returned by RecvMsg, which ends the segment.
+		s.end()
+	}
+	return err
+}
+
+func (s *wrappedClientStream) RecvMsg(m interface{}) error {
+	start := time.Now()
 	err := s.ClientStream.RecvMsg(m)
-	if err == io.EOF || s.isUnaryServer {
-		s.segment.End()
+	if nil == err {
+		s.stats.addResponse(m)
+		s.metrics.recordReceive(time.Since(start))
+	}
+	if nil != err || s.isUnaryServer {
+		s.end()
 	}
 	return err
 }
@@ -126,16 +188,41 @@ func (s wrappedClientStream) RecvMsg(m interface{}) error {
 // This interceptor only instruments streaming calls.  You must use both
 // UnaryClientInterceptor and StreamClientInterceptor to instrument unary and
 // streaming calls.  These interceptors add headers to the call metadata if
-// distributed tracing is enabled.
+// distributed tracing is enabled.  The size and number of the request and
+// response messages and the compression of the request are added to the
+// segment as attributes.
+//
+// The segment ends when the stream returns an error from RecvMsg, including
+// io.EOF once the stream has finished, when it receives the response of a
+// call which does not stream responses, or when the context of the call is
+// done.  The messages of the stream are also recorded as custom metrics, see
+// the package documentation.
 func StreamClientInterceptor(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
-	seg, ctx := startClientSegment(ctx, method, cc.Target())
+	seg, ctx := startClientSegment(ctx, method, cc.Target(), true)
 	s, err := streamer(ctx, desc, cc, method, opts...)
 	if err != nil {
+		// The segment is not ended, since no stream was created.
 		return s, err
 	}
-	return wrappedClientStream{
+	if nil == seg {
+		return s, nil
+	}
+	cs := &wrappedClientStream{
 		segment:       seg,
 		ClientStream:  s,
+		stats:         &messageStats{compression: clientCompression(opts)},
+		metrics:       newStreamMetrics(newrelic.FromContext(ctx).Application(), "Client", method),
 		isUnaryServer: !desc.ServerStreams,
-	}, nil
+		done:          make(chan struct{}),
+	}
+	// gRPC cancels the stream when its context is done, which may happen
+	// before the stream's messages have been read.
+	go func() {
+		select {
+		case <-ctx.Done():
+			cs.end()
+		case <-cs.done:
+		}
+	}()
+	return cs, nil
 }
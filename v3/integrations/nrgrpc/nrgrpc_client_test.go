@@ -13,7 +13,9 @@ import (
 	"github.com/newrelic/go-agent/v3/internal"
 	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
 	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 func TestGetURL(t *testing.T) {
@@ -131,7 +133,7 @@ func TestUnaryClientInterceptor(t *testing.T) {
 				"parentId":  internal.MatchAnything,
 				"span.kind": "client",
 			},
-			UserAttributes:  map[string]interface{}{},
+			UserAttributes:  messageAttributes(1, 1),
 			AgentAttributes: map[string]interface{}{},
 		},
 		{
@@ -215,6 +217,10 @@ func TestUnaryStreamClientInterceptor(t *testing.T) {
 		{Name: "External/bufnet/gRPC/TestApplication/DoUnaryStream", Scope: "OtherTransaction/Go/UnaryStream", Forced: false, Data: nil},
 		{Name: "Supportability/DistributedTrace/CreatePayload/Success", Scope: "", Forced: true, Data: nil},
 		{Name: "Supportability/TraceContext/Create/Success", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -225,7 +231,7 @@ func TestUnaryStreamClientInterceptor(t *testing.T) {
 				"parentId":  internal.MatchAnything,
 				"span.kind": "client",
 			},
-			UserAttributes:  map[string]interface{}{},
+			UserAttributes:  messageAttributes(1, 3),
 			AgentAttributes: map[string]interface{}{},
 		},
 		{
@@ -307,6 +313,10 @@ func TestStreamUnaryClientInterceptor(t *testing.T) {
 		{Name: "External/bufnet/gRPC/TestApplication/DoStreamUnary", Scope: "OtherTransaction/Go/StreamUnary", Forced: false, Data: nil},
 		{Name: "Supportability/DistributedTrace/CreatePayload/Success", Scope: "", Forced: true, Data: nil},
 		{Name: "Supportability/TraceContext/Create/Success", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -317,7 +327,7 @@ func TestStreamUnaryClientInterceptor(t *testing.T) {
 				"parentId":  internal.MatchAnything,
 				"span.kind": "client",
 			},
-			UserAttributes:  map[string]interface{}{},
+			UserAttributes:  messageAttributes(3, 1),
 			AgentAttributes: map[string]interface{}{},
 		},
 		{
@@ -412,6 +422,10 @@ func TestStreamStreamClientInterceptor(t *testing.T) {
 		{Name: "External/bufnet/gRPC/TestApplication/DoStreamStream", Scope: "OtherTransaction/Go/StreamStream", Forced: false, Data: nil},
 		{Name: "Supportability/DistributedTrace/CreatePayload/Success", Scope: "", Forced: true, Data: nil},
 		{Name: "Supportability/TraceContext/Create/Success", Scope: "", Forced: true, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -422,7 +436,7 @@ func TestStreamStreamClientInterceptor(t *testing.T) {
 				"parentId":  internal.MatchAnything,
 				"span.kind": "client",
 			},
-			UserAttributes:  map[string]interface{}{},
+			UserAttributes:  messageAttributes(3, 3),
 			AgentAttributes: map[string]interface{}{},
 		},
 		{
@@ -580,8 +594,10 @@ func TestClientStreamingError(t *testing.T) {
 				"transaction.name": "OtherTransaction/Go/UnaryStream",
 				"nr.entryPoint":    true,
 			},
-			UserAttributes:  map[string]interface{}{},
-			AgentAttributes: map[string]interface{}{},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"context.deadline_exceeded": true,
+			},
 		},
 	})
 	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
@@ -597,3 +613,75 @@ func TestClientStreamingError(t *testing.T) {
 		},
 	}})
 }
+
+func TestClientStreamMessageMetrics(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("StreamStream")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	s, conn := newTestServerAndConn(t, nil)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	stream, err := client.DoStreamStream(ctx)
+	if nil != err {
+		t.Fatal("client call to DoStreamStream failed", err)
+	}
+	for i := 0; i < 2; i++ {
+		if err := stream.Send(&testapp.Message{Text: "Hello DoStreamStream"}); err != nil {
+			t.Fatal("failure to Send", err)
+		}
+		if _, err := stream.Recv(); err != nil {
+			t.Fatal("failure to Recv", err)
+		}
+	}
+	stream.CloseSend()
+	if _, err := stream.Recv(); err != io.EOF {
+		t.Fatal("stream did not end", err)
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/bufnet/gRPC/TestApplication/DoStreamStream", Scope: "OtherTransaction/Go/StreamStream", Forced: false, Data: []float64{1}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Send", Scope: "", Forced: false, Data: []float64{2}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Receive", Scope: "", Forced: false, Data: []float64{2}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+}
+
+func TestClientStreamCanceled(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("StreamStream")
+	ctx, cancel := context.WithCancel(newrelic.NewContext(context.Background(), txn))
+
+	s, conn := newTestServerAndConn(t, nil)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	stream, err := client.DoStreamStream(ctx)
+	if nil != err {
+		t.Fatal("client call to DoStreamStream failed", err)
+	}
+	if err := stream.Send(&testapp.Message{Text: "Hello DoStreamStream"}); err != nil {
+		t.Fatal("failure to Send", err)
+	}
+	if _, err := stream.Recv(); err != nil {
+		t.Fatal("failure to Recv", err)
+	}
+	cancel()
+	// Both the cancellation of the context and the failure of Recv end
+	// the segment, which must only be recorded once.
+	if _, err := stream.Recv(); status.Code(err) != codes.Canceled {
+		t.Fatal("stream was not canceled", err)
+	}
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/bufnet/gRPC/TestApplication/DoStreamStream", Scope: "OtherTransaction/Go/StreamStream", Forced: false, Data: []float64{1}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+	})
+}
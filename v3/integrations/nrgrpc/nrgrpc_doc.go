@@ -40,6 +40,27 @@
 // Full server example:
 // https://github.com/newrelic/go-agent/blob/master/v3/integrations/nrgrpc/example/server/server.go
 //
+// The server interceptors accept options which name the transactions, set
+// their tracing detail, and choose how status codes are recorded.  Options
+// passed to WithService apply only to the calls of that service, so each
+// service of a server can have its own policies.  Example:
+//
+//	opts := []nrgrpc.HandlerOption{
+//		nrgrpc.WithService("grpc.health.v1.Health",
+//			nrgrpc.WithTracingDetail(newrelic.TracingDetailMetrics)),
+//	}
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(nrgrpc.UnaryServerInterceptor(app, opts...)),
+//		grpc.StreamInterceptor(nrgrpc.StreamServerInterceptor(app, opts...)),
+//	)
+//
+// Calls which should not create transactions at all, such as health checks
+// and reflection, can be excluded using WithIgnoredMethods:
+//
+//	opts := []nrgrpc.HandlerOption{
+//		nrgrpc.WithIgnoredMethods("/grpc.health.v1.Health/*", "/grpc.reflection.*/*"),
+//	}
+//
 // Client
 //
 // To instrument a gRPC client, follow these two steps:
@@ -63,6 +84,37 @@
 //
 // Full client example:
 // https://github.com/newrelic/go-agent/blob/master/v3/integrations/nrgrpc/example/client/client.go
+//
+// Message Attributes
+//
+// Server transactions and client segments have the following attributes
+// describing the messages of the call:
+//
+//	grpc.request.size       total size in bytes of the request messages
+//	grpc.response.size      total size in bytes of the response messages
+//	grpc.request.messages   number of request messages
+//	grpc.response.messages  number of response messages
+//	grpc.compression        compression codec of the request messages
+//
+// Sizes are the serialized size of the protobuf messages before compression.
+// They are agent attributes of server transactions, which can be configured
+// using Config.Attributes like the agent's other attributes.
+//
+// Stream Metrics
+//
+// Each stream is recorded by its own server transaction or client segment.
+// A client segment ends when the stream finishes or fails, or when the
+// context of the stream is done, whichever happens first.  Streams also
+// record the following custom metrics, where side is "Client" or "Server"
+// and method is the full method name without its leading slash:
+//
+//	Custom/gRPC/{side}/Stream/{method}/Send              duration of each message sent
+//	Custom/gRPC/{side}/Stream/{method}/Receive           duration of each message received
+//	Custom/gRPC/{side}/Stream/{method}/MessagesSent      number of messages sent by the stream
+//	Custom/gRPC/{side}/Stream/{method}/MessagesReceived  number of messages received by the stream
+//
+// Durations are in seconds, and only messages which were sent or received
+// successfully are timed.
 package nrgrpc
 
 import "github.com/newrelic/go-agent/v3/internal"
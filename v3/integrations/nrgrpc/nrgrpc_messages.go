@@ -0,0 +1,176 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgrpc
+
+import (
+	"context"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"google.golang.org/grpc"
+)
+
+// These attributes are added to server transactions and client external
+// segments.  Message sizes are the serialized size of protobuf messages
+// before compression.  Messages which are not protobuf messages are counted
+// but their size is not recorded.
+const (
+	// AttributeRequestSize is the total size in bytes of the request
+	// messages.
+	AttributeRequestSize = newrelic.AttributeGRPCRequestSize
+	// AttributeResponseSize is the total size in bytes of the response
+	// messages.
+	AttributeResponseSize = newrelic.AttributeGRPCResponseSize
+	// AttributeRequestMessages is the number of request messages.
+	AttributeRequestMessages = newrelic.AttributeGRPCRequestMessages
+	// AttributeResponseMessages is the number of response messages.
+	AttributeResponseMessages = newrelic.AttributeGRPCResponseMessages
+	// AttributeCompression is the compression codec applied to the request
+	// messages, eg. "gzip", or "identity" if they were not compressed.
+	AttributeCompression = newrelic.AttributeGRPCCompression
+)
+
+// identityCompression is the name gRPC uses for uncompressed messages.
+const identityCompression = "identity"
+
+// messageStats accumulates the number and size of the messages of a call.
+// Its fields are accessed atomically since gRPC allows one goroutine to send
+// messages while another receives them.
+type messageStats struct {
+	requestSize      int64
+	responseSize     int64
+	requestMessages  int64
+	responseMessages int64
+	compression      string
+}
+
+func messageSize(m interface{}) int64 {
+	if pm, ok := m.(proto.Message); ok {
+		return int64(proto.Size(pm))
+	}
+	return 0
+}
+
+func (ms *messageStats) addRequest(m interface{}) {
+	atomic.AddInt64(&ms.requestMessages, 1)
+	atomic.AddInt64(&ms.requestSize, messageSize(m))
+}
+
+func (ms *messageStats) addResponse(m interface{}) {
+	atomic.AddInt64(&ms.responseMessages, 1)
+	atomic.AddInt64(&ms.responseSize, messageSize(m))
+}
+
+// attributes returns the attributes to add to the transaction or segment.
+func (ms *messageStats) attributes() map[string]interface{} {
+	attrs := map[string]interface{}{
+		AttributeRequestSize:      atomic.LoadInt64(&ms.requestSize),
+		AttributeResponseSize:     atomic.LoadInt64(&ms.responseSize),
+		AttributeRequestMessages:  atomic.LoadInt64(&ms.requestMessages),
+		AttributeResponseMessages: atomic.LoadInt64(&ms.responseMessages),
+	}
+	if "" != ms.compression {
+		attrs[AttributeCompression] = ms.compression
+	}
+	return attrs
+}
+
+// streamMetrics records the custom metrics of a stream:
+//
+//	Custom/gRPC/{Client|Server}/Stream/{method}/Send
+//	Custom/gRPC/{Client|Server}/Stream/{method}/Receive
+//	Custom/gRPC/{Client|Server}/Stream/{method}/MessagesSent
+//	Custom/gRPC/{Client|Server}/Stream/{method}/MessagesReceived
+//
+// Send and Receive record the duration in seconds of each message sent and
+// received successfully.  MessagesSent and MessagesReceived record the
+// number of messages.  The durations are aggregated as the messages are
+// sent and received, and every metric is recorded once the stream has
+// ended.  A nil streamMetrics records nothing.
+type streamMetrics struct {
+	app    *newrelic.Application
+	prefix string
+
+	sync.Mutex
+	send     newrelic.CustomMetricSummary
+	receive  newrelic.CustomMetricSummary
+	recorded bool
+}
+
+func newStreamMetrics(app *newrelic.Application, side, method string) *streamMetrics {
+	if nil == app {
+		return nil
+	}
+	return &streamMetrics{
+		app:    app,
+		prefix: "gRPC/" + side + "/Stream/" + strings.TrimPrefix(method, "/") + "/",
+	}
+}
+
+func (sm *streamMetrics) recordSend(d time.Duration) {
+	if nil != sm {
+		sm.Lock()
+		sm.send.Add(d.Seconds())
+		sm.Unlock()
+	}
+}
+
+func (sm *streamMetrics) recordReceive(d time.Duration) {
+	if nil != sm {
+		sm.Lock()
+		sm.receive.Add(d.Seconds())
+		sm.Unlock()
+	}
+}
+
+// record records the metrics of the stream.  Only the first call has any
+// effect.
+func (sm *streamMetrics) record(sent, received int64) {
+	if nil == sm {
+		return
+	}
+	sm.Lock()
+	defer sm.Unlock()
+
+	if sm.recorded {
+		return
+	}
+	sm.recorded = true
+	sm.app.RecordCustomMetricSummary(sm.prefix+"Send", sm.send)
+	sm.app.RecordCustomMetricSummary(sm.prefix+"Receive", sm.receive)
+	sm.app.RecordCustomMetric(sm.prefix+"MessagesSent", float64(sent))
+	sm.app.RecordCustomMetric(sm.prefix+"MessagesReceived", float64(received))
+}
+
+// serverCompression returns the compression codec of the inbound messages
+// of the call, or an empty string if it cannot be determined.
+func serverCompression(ctx context.Context) string {
+	// The stream provided by gRPC also reports its compression, although
+	// grpc.ServerTransportStream does not include the method.
+	s, ok := grpc.ServerTransportStreamFromContext(ctx).(interface {
+		RecvCompress() string
+	})
+	if !ok {
+		return ""
+	}
+	if c := s.RecvCompress(); "" != c {
+		return c
+	}
+	return identityCompression
+}
+
+// clientCompression returns the compression codec set by the call options.
+func clientCompression(opts []grpc.CallOption) string {
+	compression := identityCompression
+	for _, o := range opts {
+		if c, ok := o.(grpc.CompressorCallOption); ok {
+			compression = c.CompressorType
+		}
+	}
+	return compression
+}
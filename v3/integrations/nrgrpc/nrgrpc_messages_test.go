@@ -0,0 +1,106 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgrpc
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/encoding/gzip"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrgrpc/testapp"
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestMessageStatsAttributes(t *testing.T) {
+	stats := &messageStats{compression: "gzip"}
+	stats.addRequest(&testapp.Message{Text: "hello"})
+	stats.addRequest(&testapp.Message{Text: "hi"})
+	stats.addResponse("not a protobuf message")
+	expect := map[string]interface{}{
+		AttributeRequestSize:      int64(11),
+		AttributeResponseSize:     int64(0),
+		AttributeRequestMessages:  int64(2),
+		AttributeResponseMessages: int64(1),
+		AttributeCompression:      "gzip",
+	}
+	if attrs := stats.attributes(); !reflect.DeepEqual(attrs, expect) {
+		t.Error(attrs)
+	}
+}
+
+func TestClientCompression(t *testing.T) {
+	if c := clientCompression(nil); "identity" != c {
+		t.Error(c)
+	}
+	opts := []grpc.CallOption{grpc.MaxCallRecvMsgSize(1024), grpc.UseCompressor(gzip.Name)}
+	if c := clientCompression(opts); "gzip" != c {
+		t.Error(c)
+	}
+}
+
+func TestServerCompression(t *testing.T) {
+	if c := serverCompression(context.Background()); "" != c {
+		t.Error(c)
+	}
+
+	app := testApp()
+	s, conn := newTestServerAndConn(t, app.Application)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	_, err := client.DoUnaryUnary(context.Background(), &testapp.Message{Text: "hello"}, grpc.UseCompressor(gzip.Name))
+	if nil != err {
+		t.Fatal("unable to call client DoUnaryUnary", err)
+	}
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"guid":             internal.MatchAnything,
+			"name":             "WebTransaction/Go/TestApplication/DoUnaryUnary",
+			"nr.apdexPerfZone": internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			AttributeRequestSize:          7,
+			AttributeResponseSize:         internal.MatchAnything,
+			AttributeRequestMessages:      1,
+			AttributeResponseMessages:     1,
+			AttributeCompression:          "gzip",
+			"httpResponseCode":            0,
+			"http.statusCode":             0,
+			"request.headers.contentType": "application/grpc",
+			"request.method":              "TestApplication/DoUnaryUnary",
+			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnary",
+		},
+	}})
+}
+
+func TestStreamMetricsRecordedOnce(t *testing.T) {
+	app := testApp()
+	sm := newStreamMetrics(app.Application, "Server", "/TestApplication/DoStreamStream")
+	sm.recordSend(1 * time.Second)
+	sm.recordSend(3 * time.Second)
+	sm.recordReceive(2 * time.Second)
+	sm.record(2, 1)
+	sm.record(2, 1)
+	sm.recordReceive(2 * time.Second)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/Send", Scope: "", Forced: false, Data: []float64{2, 4, 4, 1, 3, 10}},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/Receive", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+	})
+
+	var nilMetrics *streamMetrics
+	nilMetrics.recordSend(time.Second)
+	nilMetrics.record(1, 1)
+}
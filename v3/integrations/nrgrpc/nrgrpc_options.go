@@ -0,0 +1,211 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgrpc
+
+import (
+	"context"
+	"path"
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// HandlerOption configures the transactions created by
+// UnaryServerInterceptor and StreamServerInterceptor.  Options apply to
+// every call unless they are passed to WithService.
+type HandlerOption func(*handlerConfig)
+
+// StatusHandler records the status returned by a call on its transaction.
+// Use WithStatusHandler to choose the StatusHandler used for a status code.
+type StatusHandler func(ctx context.Context, txn *newrelic.Transaction, s *status.Status)
+
+// DefaultStatusHandler records the status code as the transaction's
+// response code.  Codes other than OK and NotFound are noticed as errors
+// unless they are listed in Config.ErrorCollector.IgnoreStatusCodes.
+func DefaultStatusHandler(ctx context.Context, txn *newrelic.Transaction, s *status.Status) {
+	txn.SetWebResponse(nil).WriteHeader(int(s.Code()))
+}
+
+// IgnoreStatusHandler records the call as successful, whatever its status
+// code.  Use it for codes which are expected outcomes of a service, eg.
+// codes.AlreadyExists.
+func IgnoreStatusHandler(ctx context.Context, txn *newrelic.Transaction, s *status.Status) {
+	txn.SetWebResponse(nil).WriteHeader(int(codes.OK))
+}
+
+type handlerConfig struct {
+	namer          func(fullMethod string) string
+	tracingDetail  newrelic.TracingDetail
+	statusHandlers map[codes.Code]StatusHandler
+	services       map[string][]HandlerOption
+	ignored        []string
+}
+
+// WithTransactionNamer sets the function which names the transaction of a
+// call from its full method name, eg. "/helloworld.Greeter/SayHello".  By
+// default the transaction is named after the full method without its
+// leading slash.
+func WithTransactionNamer(namer func(fullMethod string) string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.namer = namer
+	}
+}
+
+// WithTracingDetail sets the newrelic.TracingDetail of the transactions, see
+// Transaction.SetTracingDetail.  For example, use
+// newrelic.TracingDetailMetrics for a noisy health checking service.
+func WithTracingDetail(detail newrelic.TracingDetail) HandlerOption {
+	return func(cfg *handlerConfig) {
+		cfg.tracingDetail = detail
+	}
+}
+
+// WithStatusHandler sets the StatusHandler used for calls which return the
+// status code.  DefaultStatusHandler is used for codes without a handler.
+func WithStatusHandler(code codes.Code, handler StatusHandler) HandlerOption {
+	return func(cfg *handlerConfig) {
+		if nil == cfg.statusHandlers {
+			cfg.statusHandlers = make(map[codes.Code]StatusHandler)
+		}
+		cfg.statusHandlers[code] = handler
+	}
+}
+
+// WithIgnoredMethods excludes the calls whose full method name matches one
+// of the patterns from instrumentation: no transaction is created for them.
+// Use it for calls which would otherwise dominate throughput, eg. health
+// checks and reflection:
+//
+//	nrgrpc.WithIgnoredMethods(
+//		"/grpc.health.v1.Health/*",
+//		"/grpc.reflection.*/*",
+//	)
+//
+// Patterns use the syntax of path.Match and are matched against the full
+// method name, eg. "/grpc.health.v1.Health/Check".  A leading slash is added
+// to patterns which do not have one.  Malformed patterns match nothing.
+func WithIgnoredMethods(patterns ...string) HandlerOption {
+	return func(cfg *handlerConfig) {
+		for _, p := range patterns {
+			if !strings.HasPrefix(p, "/") {
+				p = "/" + p
+			}
+			cfg.ignored = append(cfg.ignored, p)
+		}
+	}
+}
+
+// WithService applies the options only to the calls of the named service,
+// eg. "helloworld.Greeter".  They are applied after the options which apply
+// to every call, so a server hosting several services can give each its
+// own policies:
+//
+//	opts := []nrgrpc.HandlerOption{
+//		nrgrpc.WithService("grpc.health.v1.Health",
+//			nrgrpc.WithTracingDetail(newrelic.TracingDetailMetrics)),
+//		nrgrpc.WithService("accounts.Accounts",
+//			nrgrpc.WithStatusHandler(codes.AlreadyExists, nrgrpc.IgnoreStatusHandler)),
+//	}
+//	server := grpc.NewServer(
+//		grpc.UnaryInterceptor(nrgrpc.UnaryServerInterceptor(app, opts...)),
+//		grpc.StreamInterceptor(nrgrpc.StreamServerInterceptor(app, opts...)),
+//	)
+//
+// WithService options nested within WithService are ignored.
+func WithService(service string, opts ...HandlerOption) HandlerOption {
+	return func(cfg *handlerConfig) {
+		if nil == cfg.services {
+			cfg.services = make(map[string][]HandlerOption)
+		}
+		cfg.services[service] = append(cfg.services[service], opts...)
+	}
+}
+
+// interceptorConfig holds the handlerConfig of every call and the
+// handlerConfigs of the services given their own options.
+type interceptorConfig struct {
+	all      *handlerConfig
+	services map[string]*handlerConfig
+}
+
+func newInterceptorConfig(opts []HandlerOption) *interceptorConfig {
+	all := &handlerConfig{}
+	for _, opt := range opts {
+		opt(all)
+	}
+	cfg := &interceptorConfig{all: all}
+	for service, serviceOpts := range all.services {
+		c := &handlerConfig{
+			namer:          all.namer,
+			tracingDetail:  all.tracingDetail,
+			statusHandlers: make(map[codes.Code]StatusHandler, len(all.statusHandlers)),
+			ignored:        append([]string(nil), all.ignored...),
+		}
+		for code, handler := range all.statusHandlers {
+			c.statusHandlers[code] = handler
+		}
+		for _, opt := range serviceOpts {
+			opt(c)
+		}
+		if nil == cfg.services {
+			cfg.services = make(map[string]*handlerConfig)
+		}
+		cfg.services[service] = c
+	}
+	return cfg
+}
+
+// forMethod returns the handlerConfig for the full method of a call.
+func (cfg *interceptorConfig) forMethod(fullMethod string) *handlerConfig {
+	if c, ok := cfg.services[serviceName(fullMethod)]; ok {
+		return c
+	}
+	return cfg.all
+}
+
+// serviceName returns the service of a full method name of the form
+// "/service/method".
+func serviceName(fullMethod string) string {
+	method := strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(method, "/"); i >= 0 {
+		return method[:i]
+	}
+	return ""
+}
+
+// ignores returns true if the call of the full method is not instrumented.
+func (cfg *handlerConfig) ignores(fullMethod string) bool {
+	if !strings.HasPrefix(fullMethod, "/") {
+		fullMethod = "/" + fullMethod
+	}
+	for _, pattern := range cfg.ignored {
+		if ok, _ := path.Match(pattern, fullMethod); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (cfg *handlerConfig) transactionName(fullMethod string) string {
+	if nil != cfg.namer {
+		return cfg.namer(fullMethod)
+	}
+	return strings.TrimPrefix(fullMethod, "/")
+}
+
+// recordStatus records the outcome of the call using the StatusHandler for
+// its status code.
+func (cfg *handlerConfig) recordStatus(ctx context.Context, txn *newrelic.Transaction, err error) {
+	s, _ := status.FromError(err)
+	if nil == s {
+		s = status.New(codes.OK, "")
+	}
+	handler, ok := cfg.statusHandlers[s.Code()]
+	if !ok || nil == handler {
+		handler = DefaultStatusHandler
+	}
+	handler(ctx, txn, s)
+}
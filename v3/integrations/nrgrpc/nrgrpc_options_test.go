@@ -0,0 +1,196 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrgrpc
+
+import (
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc/codes"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrgrpc/testapp"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func TestServiceName(t *testing.T) {
+	testcases := map[string]string{
+		"/helloworld.Greeter/SayHello": "helloworld.Greeter",
+		"helloworld.Greeter/SayHello":  "helloworld.Greeter",
+		"/a/b/c":                       "a/b",
+		"SayHello":                     "",
+		"":                             "",
+	}
+	for fullMethod, expect := range testcases {
+		if s := serviceName(fullMethod); s != expect {
+			t.Error(fullMethod, s, expect)
+		}
+	}
+}
+
+func TestInterceptorConfigForMethod(t *testing.T) {
+	cfg := newInterceptorConfig([]HandlerOption{
+		WithTracingDetail(newrelic.TracingDetailSpans),
+		WithStatusHandler(codes.NotFound, IgnoreStatusHandler),
+		WithService("accounts.Accounts",
+			WithTracingDetail(newrelic.TracingDetailMetrics),
+			WithStatusHandler(codes.AlreadyExists, IgnoreStatusHandler),
+		),
+		WithService("accounts.Accounts", WithTransactionNamer(strings.ToUpper)),
+	})
+
+	all := cfg.forMethod("/helloworld.Greeter/SayHello")
+	if all.tracingDetail != newrelic.TracingDetailSpans {
+		t.Error(all.tracingDetail)
+	}
+	if _, ok := all.statusHandlers[codes.AlreadyExists]; ok {
+		t.Error("service option applied to every call")
+	}
+	if name := all.transactionName("/helloworld.Greeter/SayHello"); name != "helloworld.Greeter/SayHello" {
+		t.Error(name)
+	}
+
+	accounts := cfg.forMethod("/accounts.Accounts/Create")
+	if accounts.tracingDetail != newrelic.TracingDetailMetrics {
+		t.Error(accounts.tracingDetail)
+	}
+	if _, ok := accounts.statusHandlers[codes.NotFound]; !ok {
+		t.Error("option applied to every call missing from service")
+	}
+	if _, ok := accounts.statusHandlers[codes.AlreadyExists]; !ok {
+		t.Error("service option missing")
+	}
+	if name := accounts.transactionName("/accounts.Accounts/Create"); name != "/ACCOUNTS.ACCOUNTS/CREATE" {
+		t.Error(name)
+	}
+}
+
+func TestUnaryServerInterceptorServiceOptions(t *testing.T) {
+	app := testApp()
+
+	s, conn := newTestServerAndConn(t, app.Application,
+		WithService("TestApplication",
+			WithTransactionNamer(func(fullMethod string) string {
+				return "TestApplication/Renamed"
+			}),
+			WithStatusHandler(codes.DataLoss, IgnoreStatusHandler),
+		),
+	)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	_, err := client.DoUnaryUnaryError(context.Background(), &testapp.Message{})
+	if nil == err {
+		t.Fatal("DoUnaryUnaryError should have returned an error")
+	}
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"guid":             internal.MatchAnything,
+			"name":             "WebTransaction/Go/TestApplication/Renamed",
+			"nr.apdexPerfZone": internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"sampled":          internal.MatchAnything,
+			"traceId":          internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: withMessageAttributes(1, 0, map[string]interface{}{
+			"httpResponseCode":            0,
+			"http.statusCode":             0,
+			"request.headers.contentType": "application/grpc",
+			"request.method":              "TestApplication/DoUnaryUnaryError",
+			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnaryError",
+		}),
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{})
+}
+
+func TestStreamServerInterceptorOtherServiceOptions(t *testing.T) {
+	app := testApp()
+
+	s, conn := newTestServerAndConn(t, app.Application,
+		WithService("OtherApplication", WithStatusHandler(codes.DataLoss, IgnoreStatusHandler)),
+	)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	stream, err := client.DoUnaryStreamError(context.Background(), &testapp.Message{})
+	if nil != err {
+		t.Fatal("client call to DoUnaryStreamError failed", err)
+	}
+	if _, err := stream.Recv(); nil == err || io.EOF == err {
+		t.Fatal("DoUnaryStreamError should have returned an error", err)
+	}
+
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "15",
+			"error.message":   "response code 15",
+			"guid":            internal.MatchAnything,
+			"priority":        internal.MatchAnything,
+			"sampled":         internal.MatchAnything,
+			"spanId":          internal.MatchAnything,
+			"traceId":         internal.MatchAnything,
+			"transactionName": "WebTransaction/Go/TestApplication/DoUnaryStreamError",
+		},
+	}})
+}
+
+func TestIgnoredMethods(t *testing.T) {
+	cfg := newInterceptorConfig([]HandlerOption{
+		WithIgnoredMethods("/grpc.health.v1.Health/*", "grpc.reflection.*/*", "[malformed"),
+		WithService("accounts.Accounts", WithIgnoredMethods("/accounts.Accounts/Ping")),
+	})
+	testcases := []struct {
+		fullMethod string
+		ignored    bool
+	}{
+		{fullMethod: "/grpc.health.v1.Health/Check", ignored: true},
+		{fullMethod: "/grpc.health.v1.Health/Watch", ignored: true},
+		{fullMethod: "/grpc.reflection.v1alpha.ServerReflection/ServerReflectionInfo", ignored: true},
+		{fullMethod: "grpc.health.v1.Health/Check", ignored: true},
+		{fullMethod: "/helloworld.Greeter/SayHello", ignored: false},
+		{fullMethod: "/accounts.Accounts/Ping", ignored: true},
+		{fullMethod: "/accounts.Accounts/Create", ignored: false},
+		{fullMethod: "/other.Other/Ping", ignored: false},
+	}
+	for _, tc := range testcases {
+		if ignored := cfg.forMethod(tc.fullMethod).ignores(tc.fullMethod); ignored != tc.ignored {
+			t.Error(tc.fullMethod, ignored)
+		}
+	}
+}
+
+func TestServerInterceptorsIgnoredMethods(t *testing.T) {
+	app := testApp()
+
+	s, conn := newTestServerAndConn(t, app.Application,
+		WithIgnoredMethods("/TestApplication/DoUnaryUnary", "/TestApplication/DoUnaryStream"),
+	)
+	defer s.Stop()
+	defer conn.Close()
+
+	client := testapp.NewTestApplicationClient(conn)
+	if _, err := client.DoUnaryUnary(context.Background(), &testapp.Message{}); nil != err {
+		t.Fatal("unable to call client DoUnaryUnary", err)
+	}
+	stream, err := client.DoUnaryStream(context.Background(), &testapp.Message{})
+	if nil != err {
+		t.Fatal("client call to DoUnaryStream failed", err)
+	}
+	for {
+		if _, err := stream.Recv(); io.EOF == err {
+			break
+		} else if nil != err {
+			t.Fatal("failure to Recv", err)
+		}
+	}
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+	app.ExpectMetrics(t, []internal.WantMetric{})
+}
@@ -7,14 +7,16 @@ import (
 	"context"
 	"net/http"
 	"strings"
+	"sync/atomic"
+	"time"
 
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
 	"github.com/newrelic/go-agent/v3/newrelic"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/metadata"
-	"google.golang.org/grpc/status"
 )
 
-func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod string) *newrelic.Transaction {
+func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod string, cfg *handlerConfig) *newrelic.Transaction {
 	method := strings.TrimPrefix(fullMethod, "/")
 
 	var hdrs http.Header
@@ -36,7 +38,10 @@ func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod
 		Method:    method,
 		Transport: newrelic.TransportHTTP,
 	}
-	txn := app.StartTransaction(method)
+	txn := app.StartTransaction(cfg.transactionName(fullMethod))
+	if newrelic.TracingDetailDefault != cfg.tracingDetail {
+		txn.SetTracingDetail(cfg.tracingDetail)
+	}
 	txn.SetWebRequest(webReq)
 
 	return txn
@@ -63,32 +68,58 @@ func startTransaction(ctx context.Context, app *newrelic.Application, fullMethod
 //	)
 //
 // These interceptors add the transaction to the call context so it may be
-// accessed in your method handlers using newrelic.FromContext.
+// accessed in your method handlers using newrelic.FromContext.  The size and
+// number of the request and response messages and the compression of the
+// request are added to the transaction as attributes.  The options configure
+// the transactions, see HandlerOption.
 //
 // Full example:
 // https://github.com/newrelic/go-agent/blob/master/v3/integrations/nrgrpc/example/server/server.go
 //
-func UnaryServerInterceptor(app *newrelic.Application) grpc.UnaryServerInterceptor {
+func UnaryServerInterceptor(app *newrelic.Application, opts ...HandlerOption) grpc.UnaryServerInterceptor {
 	if nil == app {
 		return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
 			return handler(ctx, req)
 		}
 	}
 
+	cfg := newInterceptorConfig(opts)
 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
-		txn := startTransaction(ctx, app, info.FullMethod)
+		hc := cfg.forMethod(info.FullMethod)
+		if hc.ignores(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		txn := startTransaction(ctx, app, info.FullMethod, hc)
 		defer txn.End()
 
+		stats := &messageStats{compression: serverCompression(ctx)}
+		stats.addRequest(req)
 		ctx = newrelic.NewContext(ctx, txn)
 		resp, err = handler(ctx, req)
-		txn.SetWebResponse(nil).WriteHeader(int(status.Code(err)))
+		if nil == err {
+			stats.addResponse(resp)
+		}
+		addTransactionAttributes(txn, stats)
+		hc.recordStatus(ctx, txn, err)
 		return
 	}
 }
 
+func addTransactionAttributes(txn *newrelic.Transaction, stats *messageStats) {
+	for key, val := range stats.attributes() {
+		if s, ok := val.(string); ok {
+			integrationsupport.AddAgentAttribute(txn, key, s, nil)
+		} else {
+			integrationsupport.AddAgentAttribute(txn, key, "", val)
+		}
+	}
+}
+
 type wrappedServerStream struct {
 	grpc.ServerStream
-	txn *newrelic.Transaction
+	txn     *newrelic.Transaction
+	stats   *messageStats
+	metrics *streamMetrics
 }
 
 func (s wrappedServerStream) Context() context.Context {
@@ -96,10 +127,32 @@ func (s wrappedServerStream) Context() context.Context {
 	return newrelic.NewContext(ctx, s.txn)
 }
 
-func newWrappedServerStream(stream grpc.ServerStream, txn *newrelic.Transaction) grpc.ServerStream {
+func (s wrappedServerStream) SendMsg(m interface{}) error {
+	start := time.Now()
+	err := s.ServerStream.SendMsg(m)
+	if nil == err {
+		s.stats.addResponse(m)
+		s.metrics.recordSend(time.Since(start))
+	}
+	return err
+}
+
+func (s wrappedServerStream) RecvMsg(m interface{}) error {
+	start := time.Now()
+	err := s.ServerStream.RecvMsg(m)
+	if nil == err {
+		s.stats.addRequest(m)
+		s.metrics.recordReceive(time.Since(start))
+	}
+	return err
+}
+
+func newWrappedServerStream(stream grpc.ServerStream, txn *newrelic.Transaction, stats *messageStats, metrics *streamMetrics) grpc.ServerStream {
 	return wrappedServerStream{
 		ServerStream: stream,
 		txn:          txn,
+		stats:        stats,
+		metrics:      metrics,
 	}
 }
 
@@ -124,24 +177,39 @@ func newWrappedServerStream(stream grpc.ServerStream, txn *newrelic.Transaction)
 //	)
 //
 // These interceptors add the transaction to the call context so it may be
-// accessed in your method handlers using newrelic.FromContext.
+// accessed in your method handlers using newrelic.FromContext.  The size and
+// number of the request and response messages and the compression of the
+// request are added to the transaction as attributes.  The options configure
+// the transactions, see HandlerOption.  The transaction ends when the handler
+// returns, and the messages of the stream are also recorded as custom
+// metrics, see the package documentation.
 //
 // Full example:
 // https://github.com/newrelic/go-agent/blob/master/v3/integrations/nrgrpc/example/server/server.go
 //
-func StreamServerInterceptor(app *newrelic.Application) grpc.StreamServerInterceptor {
+func StreamServerInterceptor(app *newrelic.Application, opts ...HandlerOption) grpc.StreamServerInterceptor {
 	if nil == app {
 		return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
 			return handler(srv, ss)
 		}
 	}
 
+	cfg := newInterceptorConfig(opts)
 	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
-		txn := startTransaction(ss.Context(), app, info.FullMethod)
+		hc := cfg.forMethod(info.FullMethod)
+		if hc.ignores(info.FullMethod) {
+			return handler(srv, ss)
+		}
+		txn := startTransaction(ss.Context(), app, info.FullMethod, hc)
 		defer txn.End()
 
-		err := handler(srv, newWrappedServerStream(ss, txn))
-		txn.SetWebResponse(nil).WriteHeader(int(status.Code(err)))
+		stats := &messageStats{compression: serverCompression(ss.Context())}
+		metrics := newStreamMetrics(app, "Server", info.FullMethod)
+		err := handler(srv, newWrappedServerStream(ss, txn, stats, metrics))
+		// The handler returning ends the stream, whether or not it failed.
+		metrics.record(atomic.LoadInt64(&stats.responseMessages), atomic.LoadInt64(&stats.requestMessages))
+		addTransactionAttributes(txn, stats)
+		hc.recordStatus(ss.Context(), txn, err)
 		return err
 	}
 }
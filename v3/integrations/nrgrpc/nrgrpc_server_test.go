@@ -20,12 +20,13 @@ import (
 
 // newTestServerAndConn creates a new *grpc.Server and *grpc.ClientConn for use
 // in testing. It adds instrumentation to both. If app is nil, then
-// instrumentation is not applied to the server. Be sure to Stop() the server
-// and Close() the connection when done with them.
-func newTestServerAndConn(t *testing.T, app *newrelic.Application) (*grpc.Server, *grpc.ClientConn) {
+// instrumentation is not applied to the server. The options are passed to the
+// server interceptors. Be sure to Stop() the server and Close() the connection
+// when done with them.
+func newTestServerAndConn(t *testing.T, app *newrelic.Application, opts ...HandlerOption) (*grpc.Server, *grpc.ClientConn) {
 	s := grpc.NewServer(
-		grpc.UnaryInterceptor(UnaryServerInterceptor(app)),
-		grpc.StreamInterceptor(StreamServerInterceptor(app)),
+		grpc.UnaryInterceptor(UnaryServerInterceptor(app, opts...)),
+		grpc.StreamInterceptor(StreamServerInterceptor(app, opts...)),
 	)
 	testapp.RegisterTestApplicationServer(s, &testapp.Server{})
 	lis := bufconn.Listen(1024 * 1024)
@@ -51,6 +52,28 @@ func newTestServerAndConn(t *testing.T, app *newrelic.Application) (*grpc.Server
 	return s, conn
 }
 
+// messageAttributes returns the attributes expected on a client segment for
+// a call with the given number of messages.
+func messageAttributes(requests, responses int) map[string]interface{} {
+	return map[string]interface{}{
+		AttributeRequestSize:      internal.MatchAnything,
+		AttributeResponseSize:     internal.MatchAnything,
+		AttributeRequestMessages:  requests,
+		AttributeResponseMessages: responses,
+		AttributeCompression:      "identity",
+	}
+}
+
+// withMessageAttributes adds the attributes expected on a server transaction
+// for a call with the given number of messages to its other agent
+// attributes.
+func withMessageAttributes(requests, responses int, attrs map[string]interface{}) map[string]interface{} {
+	for key, val := range messageAttributes(requests, responses) {
+		attrs[key] = val
+	}
+	return attrs
+}
+
 func TestUnaryServerInterceptor(t *testing.T) {
 	app := testApp()
 
@@ -99,13 +122,13 @@ func TestUnaryServerInterceptor(t *testing.T) {
 			"traceId":                  internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 1, map[string]interface{}{
 			"httpResponseCode":            0,
 			"http.statusCode":             0,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryUnary",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnary",
-		},
+		}),
 	}})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -127,7 +150,7 @@ func TestUnaryServerInterceptor(t *testing.T) {
 				"trustedParentId":  internal.MatchAnything,
 			},
 			UserAttributes: map[string]interface{}{},
-			AgentAttributes: map[string]interface{}{
+			AgentAttributes: withMessageAttributes(1, 1, map[string]interface{}{
 				"httpResponseCode":            0,
 				"http.statusCode":             0,
 				"parent.account":              "123",
@@ -138,7 +161,7 @@ func TestUnaryServerInterceptor(t *testing.T) {
 				"request.headers.contentType": "application/grpc",
 				"request.method":              "TestApplication/DoUnaryUnary",
 				"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnary",
-			},
+			}),
 		},
 	})
 }
@@ -182,13 +205,13 @@ func TestUnaryServerInterceptorError(t *testing.T) {
 			"traceId":          internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 0, map[string]interface{}{
 			"httpResponseCode":            15,
 			"http.statusCode":             15,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryUnaryError",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnaryError",
-		},
+		}),
 	}})
 	app.ExpectErrorEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -201,7 +224,7 @@ func TestUnaryServerInterceptorError(t *testing.T) {
 			"traceId":         internal.MatchAnything,
 			"transactionName": "WebTransaction/Go/TestApplication/DoUnaryUnaryError",
 		},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 0, map[string]interface{}{
 			"httpResponseCode":            15,
 			"http.statusCode":             15,
 			"request.headers.User-Agent":  internal.MatchAnything,
@@ -209,7 +232,7 @@ func TestUnaryServerInterceptorError(t *testing.T) {
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryUnaryError",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryUnaryError",
-		},
+		}),
 		UserAttributes: map[string]interface{}{},
 	}})
 }
@@ -258,6 +281,14 @@ func TestUnaryStreamServerInterceptor(t *testing.T) {
 		{Name: "WebTransaction/Go/TestApplication/DoUnaryStream", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime/Go/TestApplication/DoUnaryStream", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoUnaryStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectTxnEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -276,13 +307,13 @@ func TestUnaryStreamServerInterceptor(t *testing.T) {
 			"traceId":                  internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 3, map[string]interface{}{
 			"httpResponseCode":            0,
 			"http.statusCode":             0,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryStream",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryStream",
-		},
+		}),
 	}})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -304,7 +335,7 @@ func TestUnaryStreamServerInterceptor(t *testing.T) {
 				"trustedParentId":  internal.MatchAnything,
 			},
 			UserAttributes: map[string]interface{}{},
-			AgentAttributes: map[string]interface{}{
+			AgentAttributes: withMessageAttributes(1, 3, map[string]interface{}{
 				"httpResponseCode":            0,
 				"http.statusCode":             0,
 				"parent.account":              "123",
@@ -315,7 +346,7 @@ func TestUnaryStreamServerInterceptor(t *testing.T) {
 				"request.headers.contentType": "application/grpc",
 				"request.method":              "TestApplication/DoUnaryStream",
 				"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryStream",
-			},
+			}),
 		},
 	})
 }
@@ -362,6 +393,14 @@ func TestStreamUnaryServerInterceptor(t *testing.T) {
 		{Name: "WebTransaction/Go/TestApplication/DoStreamUnary", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime/Go/TestApplication/DoStreamUnary", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamUnary/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamUnary/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamUnary/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamUnary/MessagesReceived", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamUnary/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectTxnEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -380,13 +419,13 @@ func TestStreamUnaryServerInterceptor(t *testing.T) {
 			"traceId":                  internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(3, 1, map[string]interface{}{
 			"httpResponseCode":            0,
 			"http.statusCode":             0,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoStreamUnary",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoStreamUnary",
-		},
+		}),
 	}})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -408,7 +447,7 @@ func TestStreamUnaryServerInterceptor(t *testing.T) {
 				"trustedParentId":  internal.MatchAnything,
 			},
 			UserAttributes: map[string]interface{}{},
-			AgentAttributes: map[string]interface{}{
+			AgentAttributes: withMessageAttributes(3, 1, map[string]interface{}{
 				"httpResponseCode":            0,
 				"http.statusCode":             0,
 				"parent.account":              "123",
@@ -419,7 +458,7 @@ func TestStreamUnaryServerInterceptor(t *testing.T) {
 				"request.headers.contentType": "application/grpc",
 				"request.method":              "TestApplication/DoStreamUnary",
 				"request.uri":                 "grpc://bufnet/TestApplication/DoStreamUnary",
-			},
+			}),
 		},
 	})
 }
@@ -479,6 +518,14 @@ func TestStreamStreamServerInterceptor(t *testing.T) {
 		{Name: "WebTransaction/Go/TestApplication/DoStreamStream", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime/Go/TestApplication/DoStreamStream", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Send", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Client/Stream/TestApplication/DoStreamStream/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectTxnEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -497,13 +544,13 @@ func TestStreamStreamServerInterceptor(t *testing.T) {
 			"traceId":                  internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(3, 3, map[string]interface{}{
 			"httpResponseCode":            0,
 			"http.statusCode":             0,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoStreamStream",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoStreamStream",
-		},
+		}),
 	}})
 	app.ExpectSpanEvents(t, []internal.WantEvent{
 		{
@@ -525,7 +572,7 @@ func TestStreamStreamServerInterceptor(t *testing.T) {
 				"trustedParentId":  internal.MatchAnything,
 			},
 			UserAttributes: map[string]interface{}{},
-			AgentAttributes: map[string]interface{}{
+			AgentAttributes: withMessageAttributes(3, 3, map[string]interface{}{
 				"httpResponseCode":            0,
 				"http.statusCode":             0,
 				"parent.account":              "123",
@@ -536,7 +583,7 @@ func TestStreamStreamServerInterceptor(t *testing.T) {
 				"request.headers.contentType": "application/grpc",
 				"request.method":              "TestApplication/DoStreamStream",
 				"request.uri":                 "grpc://bufnet/TestApplication/DoStreamStream",
-			},
+			}),
 		},
 	})
 }
@@ -573,6 +620,9 @@ func TestStreamServerInterceptorError(t *testing.T) {
 		{Name: "WebTransaction/Go/TestApplication/DoUnaryStreamError", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime", Scope: "", Forced: true, Data: nil},
 		{Name: "WebTransactionTotalTime/Go/TestApplication/DoUnaryStreamError", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStreamError/Receive", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStreamError/MessagesSent", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/gRPC/Server/Stream/TestApplication/DoUnaryStreamError/MessagesReceived", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectTxnEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -584,13 +634,13 @@ func TestStreamServerInterceptorError(t *testing.T) {
 			"traceId":          internal.MatchAnything,
 		},
 		UserAttributes: map[string]interface{}{},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 0, map[string]interface{}{
 			"httpResponseCode":            15,
 			"http.statusCode":             15,
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryStreamError",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryStreamError",
-		},
+		}),
 	}})
 	app.ExpectErrorEvents(t, []internal.WantEvent{{
 		Intrinsics: map[string]interface{}{
@@ -603,7 +653,7 @@ func TestStreamServerInterceptorError(t *testing.T) {
 			"traceId":         internal.MatchAnything,
 			"transactionName": "WebTransaction/Go/TestApplication/DoUnaryStreamError",
 		},
-		AgentAttributes: map[string]interface{}{
+		AgentAttributes: withMessageAttributes(1, 0, map[string]interface{}{
 			"httpResponseCode":            15,
 			"http.statusCode":             15,
 			"request.headers.User-Agent":  internal.MatchAnything,
@@ -611,7 +661,7 @@ func TestStreamServerInterceptorError(t *testing.T) {
 			"request.headers.contentType": "application/grpc",
 			"request.method":              "TestApplication/DoUnaryStreamError",
 			"request.uri":                 "grpc://bufnet/TestApplication/DoUnaryStreamError",
-		},
+		}),
 		UserAttributes: map[string]interface{}{},
 	}})
 }
@@ -110,6 +110,7 @@ func TestHandle(t *testing.T) {
 				"httpResponseCode": 500,
 				"http.statusCode":  500,
 				"request.method":   "GET",
+				"http.flavor":      "1.1",
 				"request.uri":      "/hello/person",
 			},
 		},
@@ -154,6 +155,7 @@ func TestHandler(t *testing.T) {
 				"httpResponseCode": 500,
 				"http.statusCode":  500,
 				"request.method":   "GET",
+				"http.flavor":      "1.1",
 				"request.uri":      "/hello/",
 			},
 		},
@@ -241,6 +243,7 @@ func TestNotFound(t *testing.T) {
 				"httpResponseCode": 500,
 				"http.statusCode":  500,
 				"request.method":   "GET",
+				"http.flavor":      "1.1",
 				"request.uri":      "/hello/",
 			},
 		},
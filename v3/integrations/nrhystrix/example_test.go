@@ -0,0 +1,31 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrhystrix_test
+
+import (
+	"net/http"
+
+	"github.com/afex/hystrix-go/hystrix"
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/newrelic/go-agent/v3/integrations/nrhystrix"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+	metricCollector.Registry.Register(nrhystrix.NewMetricCollector(app))
+
+	// The command name is used as the name of the downstream service.
+	err := hystrix.Do("payments", func() error {
+		resp, err := http.Get("http://payments.example.com/status")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}, nil)
+	_ = err
+}
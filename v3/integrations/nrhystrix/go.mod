@@ -0,0 +1,9 @@
+module github.com/newrelic/go-agent/v3/integrations/nrhystrix
+
+// hystrix-go does not have a go.mod file.
+go 1.12
+
+require (
+	github.com/afex/hystrix-go v0.0.0-20180502004556-fa1af6a1f4f5
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
@@ -0,0 +1,81 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrhystrix reports the state of https://github.com/afex/hystrix-go
+// circuit breakers.
+//
+// Calls rejected by an open circuit are recorded using
+// Application.RecordCircuitBreakerShortCircuits
+// (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#Application.RecordCircuitBreakerShortCircuits)
+// and circuit state transitions are recorded using
+// Application.RecordCircuitBreakerTransition
+// (https://godoc.org/github.com/newrelic/go-agent/v3/newrelic#Application.RecordCircuitBreakerTransition).
+// The command name is used as the name of the downstream service.
+//
+// Register the metric collector once, before running any commands:
+//
+//	metricCollector.Registry.Register(nrhystrix.NewMetricCollector(app))
+//
+// hystrix-go does not report circuit state transitions, so they are
+// inferred from the results of commands: a circuit is reported as opened
+// when a command is short-circuited, and as closed when a command next
+// succeeds, since hystrix-go closes an open circuit when a test command
+// succeeds.
+package nrhystrix
+
+import (
+	"sync"
+
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "circuitbreaker", "hystrix") }
+
+// NewMetricCollector returns a function which creates a metric collector
+// for each hystrix command.  Register it using
+// metricCollector.Registry.Register.
+func NewMetricCollector(app *newrelic.Application) func(name string) metricCollector.MetricCollector {
+	return func(name string) metricCollector.MetricCollector {
+		return &collector{app: app, name: name}
+	}
+}
+
+type collector struct {
+	app  *newrelic.Application
+	name string
+
+	sync.Mutex
+	open bool
+}
+
+// Update implements metricCollector.MetricCollector.
+func (c *collector) Update(r metricCollector.MetricResult) {
+	if r.ShortCircuits > 0 {
+		c.app.RecordCircuitBreakerShortCircuits(c.name, int(r.ShortCircuits))
+	}
+
+	var from, to newrelic.CircuitBreakerState
+	c.Lock()
+	if r.ShortCircuits > 0 && !c.open {
+		c.open = true
+		from, to = newrelic.CircuitBreakerClosed, newrelic.CircuitBreakerOpen
+	} else if r.Successes > 0 && c.open {
+		c.open = false
+		from, to = newrelic.CircuitBreakerOpen, newrelic.CircuitBreakerClosed
+	}
+	c.Unlock()
+
+	if "" != to {
+		c.app.RecordCircuitBreakerTransition(c.name, from, to)
+	}
+}
+
+// Reset implements metricCollector.MetricCollector.  It is called when
+// hystrix-go flushes its circuits, which closes them.
+func (c *collector) Reset() {
+	c.Lock()
+	c.open = false
+	c.Unlock()
+}
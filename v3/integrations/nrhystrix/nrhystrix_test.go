@@ -0,0 +1,64 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrhystrix
+
+import (
+	"testing"
+
+	metricCollector "github.com/afex/hystrix-go/hystrix/metric_collector"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+)
+
+func TestCollector(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	c := NewMetricCollector(app.Application)("payments")
+
+	c.Update(metricCollector.MetricResult{Attempts: 1, Successes: 1})
+	c.Update(metricCollector.MetricResult{Attempts: 1, ShortCircuits: 1})
+	c.Update(metricCollector.MetricResult{Attempts: 1, ShortCircuits: 1})
+	c.Update(metricCollector.MetricResult{Attempts: 1, Successes: 1})
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments/State/open", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/payments/State/closed", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/payments/ShortCircuited", Scope: "", Forced: false, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/all/ShortCircuited", Scope: "", Forced: false, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "CircuitBreakerStateChange",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"service": "payments",
+			"from":    "closed",
+			"to":      "open",
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "CircuitBreakerStateChange",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"service": "payments",
+			"from":    "open",
+			"to":      "closed",
+		},
+	}})
+}
+
+func TestCollectorReset(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	c := NewMetricCollector(app.Application)("payments")
+	c.Update(metricCollector.MetricResult{Attempts: 1, ShortCircuits: 1})
+	c.Reset()
+	c.Update(metricCollector.MetricResult{Attempts: 1, Successes: 1})
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments/State/open", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/payments/ShortCircuited", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/all/ShortCircuited", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
@@ -28,6 +28,21 @@
 //
 //	ctx = newrelic.NewContext(context.Background(), txn)
 //	resp, err := collection.InsertOne(ctx, bson.M{"name": "pi", "value": 3.14159})
+//
+// Each command is timed by a DatastoreSegment whose operation is the command
+// name and whose collection is the collection the command acts on.  Beyond
+// individual commands:
+//
+//   - Opening a change stream, the aggregate command whose pipeline starts
+//     with a $changeStream stage, has the operation "changeStream".  The
+//     getMore commands which wait for its events have the collection of the
+//     stream.
+//   - The insert, update, and delete commands of bulk writes, including
+//     InsertMany and BulkWrite, have the number of documents or statements
+//     written in the AttributeBatchSize attribute.
+//   - The commands run in a session's transaction, including the
+//     commitTransaction and abortTransaction commands which end it, have the
+//     transaction's number in the AttributeTxnNumber attribute.
 package nrmongo
 
 import (
@@ -42,6 +57,20 @@ import (
 
 func init() { internal.TrackUsage("integration", "datastore", "mongo") }
 
+// These attributes are added to the segments of commands.
+const (
+	// AttributeBatchSize is the number of documents or statements written
+	// by an insert, update, or delete command which writes more than one.
+	AttributeBatchSize = "db.mongodb.batchSize"
+	// AttributeTxnNumber is the number of the session transaction which
+	// the command is part of.
+	AttributeTxnNumber = "db.mongodb.txnNumber"
+)
+
+// operationChangeStream is the operation of aggregate commands which open a
+// change stream.
+const operationChangeStream = "changeStream"
+
 type mongoMonitor struct {
 	segmentMap  map[int64]*newrelic.DatastoreSegment
 	origCommMon *event.CommandMonitor
@@ -100,20 +129,86 @@ func (m *mongoMonitor) started(ctx context.Context, e *event.CommandStartedEvent
 		StartTime:    txn.StartSegmentNow(),
 		Product:      newrelic.DatastoreMongoDB,
 		Collection:   collName(e),
-		Operation:    e.CommandName,
+		Operation:    operation(e),
 		Host:         host,
 		PortPathOrID: port,
 		DatabaseName: e.DatabaseName,
 	}
+	if n := batchSize(e); n > 1 {
+		sgmt.AddAttribute(AttributeBatchSize, n)
+	}
+	if txnNumber, ok := e.Command.Lookup("txnNumber").Int64OK(); ok {
+		sgmt.AddAttribute(AttributeTxnNumber, txnNumber)
+	}
 	m.addSgmt(e, &sgmt)
 }
 
 func collName(e *event.CommandStartedEvent) string {
-	coll := e.Command.Lookup(e.CommandName)
+	key := e.CommandName
+	if "getMore" == key {
+		// The value of getMore is the cursor id.
+		key = "collection"
+	}
+	coll := e.Command.Lookup(key)
 	collName, _ := coll.StringValueOK()
 	return collName
 }
 
+func operation(e *event.CommandStartedEvent) string {
+	if isChangeStream(e) {
+		return operationChangeStream
+	}
+	return e.CommandName
+}
+
+// isChangeStream returns whether the command is an aggregate whose pipeline
+// starts with a $changeStream stage.
+func isChangeStream(e *event.CommandStartedEvent) bool {
+	if "aggregate" != e.CommandName {
+		return false
+	}
+	pipeline, ok := e.Command.Lookup("pipeline").ArrayOK()
+	if !ok {
+		return false
+	}
+	stages, err := pipeline.Values()
+	if nil != err || 0 == len(stages) {
+		return false
+	}
+	stage, ok := stages[0].DocumentOK()
+	if !ok {
+		return false
+	}
+	_, err = stage.LookupErr("$changeStream")
+	return nil == err
+}
+
+// batchSizeFields maps each write command to the field containing the
+// documents or statements it writes.
+var batchSizeFields = map[string]string{
+	"insert": "documents",
+	"update": "updates",
+	"delete": "deletes",
+}
+
+// batchSize returns the number of documents or statements written by an
+// insert, update, or delete command, or 0 for other commands.
+func batchSize(e *event.CommandStartedEvent) int {
+	field, ok := batchSizeFields[e.CommandName]
+	if !ok {
+		return 0
+	}
+	writes, ok := e.Command.Lookup(field).ArrayOK()
+	if !ok {
+		return 0
+	}
+	values, err := writes.Values()
+	if nil != err {
+		return 0
+	}
+	return len(values)
+}
+
 func (m *mongoMonitor) addSgmt(e *event.CommandStartedEvent, sgmt *newrelic.DatastoreSegment) {
 	m.Lock()
 	defer m.Unlock()
@@ -234,6 +234,129 @@ func TestCollName(t *testing.T) {
 
 }
 
+func TestGetMoreCollName(t *testing.T) {
+	raw, _ := bson.Marshal(bson.D{{Key: "getMore", Value: int64(12345)}, {Key: "collection", Value: "orders"}})
+	e := event.CommandStartedEvent{Command: raw, CommandName: "getMore"}
+	if result := collName(&e); result != "orders" {
+		t.Errorf("Wrong collection name: %s", result)
+	}
+}
+
+func TestOperation(t *testing.T) {
+	changeStream, _ := bson.Marshal(bson.D{
+		{Key: "aggregate", Value: "orders"},
+		{Key: "pipeline", Value: bson.A{bson.D{{Key: "$changeStream", Value: bson.D{}}}, bson.D{{Key: "$match", Value: bson.D{}}}}},
+	})
+	aggregate, _ := bson.Marshal(bson.D{
+		{Key: "aggregate", Value: "orders"},
+		{Key: "pipeline", Value: bson.A{bson.D{{Key: "$match", Value: bson.D{}}}}},
+	})
+	empty, _ := bson.Marshal(bson.D{
+		{Key: "aggregate", Value: "orders"},
+		{Key: "pipeline", Value: bson.A{}},
+	})
+	testCases := []struct {
+		command string
+		raw     bson.Raw
+		expect  string
+	}{
+		{command: "aggregate", raw: changeStream, expect: "changeStream"},
+		{command: "aggregate", raw: aggregate, expect: "aggregate"},
+		{command: "aggregate", raw: empty, expect: "aggregate"},
+		{command: "find", raw: changeStream, expect: "find"},
+	}
+	for _, tc := range testCases {
+		e := event.CommandStartedEvent{Command: tc.raw, CommandName: tc.command}
+		if result := operation(&e); result != tc.expect {
+			t.Errorf("Wrong operation: expected %s got %s", tc.expect, result)
+		}
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	insert, _ := bson.Marshal(bson.D{
+		{Key: "insert", Value: "orders"},
+		{Key: "documents", Value: bson.A{bson.D{{Key: "a", Value: 1}}, bson.D{{Key: "a", Value: 2}}, bson.D{{Key: "a", Value: 3}}}},
+	})
+	update, _ := bson.Marshal(bson.D{
+		{Key: "update", Value: "orders"},
+		{Key: "updates", Value: bson.A{bson.D{{Key: "q", Value: bson.D{}}}}},
+	})
+	testCases := []struct {
+		command string
+		raw     bson.Raw
+		expect  int
+	}{
+		{command: "insert", raw: insert, expect: 3},
+		{command: "update", raw: update, expect: 1},
+		{command: "delete", raw: update, expect: 0},
+		{command: "find", raw: insert, expect: 0},
+	}
+	for _, tc := range testCases {
+		e := event.CommandStartedEvent{Command: tc.raw, CommandName: tc.command}
+		if result := batchSize(&e); result != tc.expect {
+			t.Errorf("Wrong batch size for %s: expected %d got %d", tc.command, tc.expect, result)
+		}
+	}
+}
+
+func TestBulkWriteInTransaction(t *testing.T) {
+	raw, _ := bson.Marshal(bson.D{
+		{Key: "insert", Value: "orders"},
+		{Key: "documents", Value: bson.A{bson.D{{Key: "a", Value: 1}}, bson.D{{Key: "a", Value: 2}}}},
+		{Key: "txnNumber", Value: int64(7)},
+		{Key: "$db", Value: "testing"},
+	})
+	nrMonitor := NewCommandMonitor(nil)
+	app := createTestApp()
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	nrMonitor.Started(ctx, &event.CommandStartedEvent{
+		Command:      raw,
+		DatabaseName: "testdb",
+		CommandName:  "insert",
+		RequestID:    reqID,
+		ConnectionID: connID,
+	})
+	nrMonitor.Succeeded(ctx, se)
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/MongoDB/orders/insert",
+				"sampled":   true,
+				"category":  "datastore",
+				"component": "MongoDB",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeBatchSize: 2,
+				AttributeTxnNumber: 7,
+			},
+			AgentAttributes: map[string]interface{}{
+				"peer.address":  thisHost + ":27017",
+				"peer.hostname": thisHost,
+				"db.statement":  "'insert' on 'orders' using 'MongoDB'",
+				"db.instance":   "testdb",
+				"db.collection": "orders",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/txnName",
+				"transaction.name": "OtherTransaction/Go/txnName",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
 func createTestApp() integrationsupport.ExpectApp {
 	return integrationsupport.NewTestApp(replyFn, integrationsupport.ConfigFullTraces)
 }
@@ -14,6 +14,8 @@ import (
 
 func currentTransaction() *newrelic.Transaction { return nil }
 
+func currentApplication() *newrelic.Application { return nil }
+
 func ExampleStartPublishSegment() {
 	nc, _ := nats.Connect(nats.DefaultURL)
 	txn := currentTransaction()
@@ -42,3 +44,41 @@ func ExampleStartPublishSegment_defer() {
 	}
 	fmt.Println("Received reply message:", string(m.Data))
 }
+
+func ExamplePublish() {
+	nc, _ := nats.Connect(nats.DefaultURL)
+	txn := currentTransaction()
+
+	// Publish the message in a segment, adding the distributed tracing
+	// headers if the server supports them
+	if err := nrnats.Publish(txn, nc, "testing.subject", []byte("Hello World")); nil != err {
+		panic(err)
+	}
+}
+
+func ExampleJetStreamPublish() {
+	nc, _ := nats.Connect(nats.DefaultURL)
+	js, _ := nc.JetStream()
+	txn := currentTransaction()
+
+	ack, err := nrnats.JetStreamPublish(txn, js, "orders.created", []byte("order 42"))
+	if nil != err {
+		panic(err)
+	}
+	fmt.Println("Stored message with sequence", ack.Sequence)
+}
+
+func ExampleStartConsumeTransaction() {
+	nc, _ := nats.Connect(nats.DefaultURL)
+	js, _ := nc.JetStream()
+	app := currentApplication()
+
+	sub, _ := js.PullSubscribe("orders.*", "worker")
+	msgs, _ := sub.Fetch(10)
+	for _, msg := range msgs {
+		txn := nrnats.StartConsumeTransaction(app, msg)
+		// ... process the message ...
+		msg.Ack()
+		txn.End()
+	}
+}
@@ -1,11 +1,12 @@
 module github.com/newrelic/go-agent/v3/integrations/nrnats
 
-// As of Dec 2019, 1.11 is the earliest version of Go tested by nats:
-// https://github.com/nats-io/nats.go/blob/master/.travis.yml
-go 1.11
+// As of Oct 2021, go 1.16 is in the nats go.mod file:
+// https://github.com/nats-io/nats.go/blob/main/go.mod
+go 1.16
 
 require (
-	// v1.8.0 is the first nats version with a go.mod.
-	github.com/nats-io/nats.go v1.8.0
-	github.com/newrelic/go-agent/v3 v3.4.0
+	// v1.13.0 is the first nats version with the current JetStream message
+	// metadata API.  Message headers require v1.11.0.
+	github.com/nats-io/nats.go v1.13.0
+	github.com/newrelic/go-agent/v3 v3.13.0
 )
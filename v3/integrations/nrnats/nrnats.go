@@ -4,6 +4,7 @@
 package nrnats
 
 import (
+	"net/http"
 	"strings"
 
 	nats "github.com/nats-io/nats.go"
@@ -12,6 +13,17 @@ import (
 	newrelic "github.com/newrelic/go-agent/v3/newrelic"
 )
 
+const library = "NATS"
+
+// These attributes are added to the transactions created for messages
+// consumed from a JetStream stream.
+const (
+	AttributeJetStreamStream    = "jetstream.stream"
+	AttributeJetStreamConsumer  = "jetstream.consumer"
+	AttributeJetStreamSequence  = "jetstream.sequence"
+	AttributeJetStreamDelivered = "jetstream.delivered"
+)
+
 // StartPublishSegment creates and starts a `newrelic.MessageProducerSegment`
 // (https://godoc.org/github.com/newrelic/go-agent#MessageProducerSegment) for NATS
 // publishers.  Call this function before calling any method that publishes or
@@ -22,45 +34,144 @@ import (
 // parameter is the subject of the publish call and is used in metric and span
 // names.
 func StartPublishSegment(txn *newrelic.Transaction, nc *nats.Conn, subject string) *newrelic.MessageProducerSegment {
-	if nil == txn {
+	if nil == nc {
 		return nil
 	}
-	if nil == nc {
+	return startSegment(txn, subject)
+}
+
+func startSegment(txn *newrelic.Transaction, subject string) *newrelic.MessageProducerSegment {
+	if nil == txn {
 		return nil
 	}
 	return &newrelic.MessageProducerSegment{
 		StartTime:            txn.StartSegmentNow(),
-		Library:              "NATS",
+		Library:              library,
 		DestinationType:      newrelic.MessageTopic,
 		DestinationName:      subject,
 		DestinationTemporary: strings.HasPrefix(subject, "_INBOX"),
 	}
 }
 
+// InsertDistributedTraceHeaders adds the distributed tracing headers of the
+// Transaction to the message's headers, replacing any added previously.
+// Message headers require NATS server 2.2 or later.
+func InsertDistributedTraceHeaders(txn *newrelic.Transaction, msg *nats.Msg) {
+	if nil == txn || nil == msg {
+		return
+	}
+	hdrs := http.Header{}
+	txn.InsertDistributedTraceHeaders(hdrs)
+	if 0 == len(hdrs) {
+		return
+	}
+	if nil == msg.Header {
+		msg.Header = nats.Header{}
+	}
+	for key, values := range hdrs {
+		for existing := range msg.Header {
+			if strings.EqualFold(key, existing) {
+				delete(msg.Header, existing)
+			}
+		}
+		msg.Header[strings.ToLower(key)] = values
+	}
+}
+
+// PublishMsg publishes the message using the connection.  The publish is
+// timed by a MessageProducerSegment, and the distributed tracing headers
+// of the Transaction are added to the message if the server supports
+// headers.
+func PublishMsg(txn *newrelic.Transaction, nc *nats.Conn, msg *nats.Msg) error {
+	if nil != nc && nc.HeadersSupported() {
+		InsertDistributedTraceHeaders(txn, msg)
+	}
+	seg := StartPublishSegment(txn, nc, msg.Subject)
+	err := nc.PublishMsg(msg)
+	seg.End()
+	return err
+}
+
+// Publish publishes the data to the subject using the connection in the
+// same way as PublishMsg.
+func Publish(txn *newrelic.Transaction, nc *nats.Conn, subject string, data []byte) error {
+	return PublishMsg(txn, nc, &nats.Msg{Subject: subject, Data: data})
+}
+
+// JetStreamPublishMsg publishes the message to a JetStream stream and waits
+// for its acknowledgement.  The publish is timed by a
+// MessageProducerSegment, and the distributed tracing headers of the
+// Transaction are added to the message.
+func JetStreamPublishMsg(txn *newrelic.Transaction, js nats.JetStream, msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	InsertDistributedTraceHeaders(txn, msg)
+	seg := startSegment(txn, msg.Subject)
+	ack, err := js.PublishMsg(msg, opts...)
+	seg.End()
+	return ack, err
+}
+
+// JetStreamPublish publishes the data to the subject of a JetStream stream
+// in the same way as JetStreamPublishMsg.
+func JetStreamPublish(txn *newrelic.Transaction, js nats.JetStream, subject string, data []byte, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	return JetStreamPublishMsg(txn, js, &nats.Msg{Subject: subject, Data: data}, opts...)
+}
+
+// StartConsumeTransaction starts a transaction for a message received by a
+// subscription.  The transaction accepts the distributed tracing headers
+// found in the message's headers.  If the message was consumed from a
+// JetStream stream, the stream, consumer, stream sequence and delivery
+// count are added as attributes.  The caller must end the transaction.
+func StartConsumeTransaction(app *newrelic.Application, msg *nats.Msg) *newrelic.Transaction {
+	if nil == app || nil == msg {
+		return nil
+	}
+	namer := internal.MessageMetricKey{
+		Library:         library,
+		DestinationType: string(newrelic.MessageTopic),
+		DestinationName: msg.Subject,
+		Consumer:        true,
+	}
+	txn := app.StartTransaction(namer.Name())
+
+	hdrs := http.Header{}
+	for key, values := range msg.Header {
+		for _, value := range values {
+			hdrs.Add(key, value)
+		}
+	}
+	txn.AcceptDistributedTraceHeaders(newrelic.TransportQueue, hdrs)
+
+	if nil != msg.Sub {
+		integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageRoutingKey, msg.Sub.Subject, nil)
+		integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageQueueName, msg.Sub.Queue, nil)
+	}
+	integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageReplyTo, msg.Reply, nil)
+
+	if meta, err := msg.Metadata(); nil == err {
+		txn.AddAttribute(AttributeJetStreamStream, meta.Stream)
+		txn.AddAttribute(AttributeJetStreamConsumer, meta.Consumer)
+		txn.AddAttribute(AttributeJetStreamSequence, meta.Sequence.Stream)
+		txn.AddAttribute(AttributeJetStreamDelivered, meta.NumDelivered)
+	}
+	return txn
+}
+
 // SubWrapper can be used to wrap the function for nats.Subscribe (https://godoc.org/github.com/nats-io/go-nats#Conn.Subscribe
 // or https://godoc.org/github.com/nats-io/go-nats#EncodedConn.Subscribe)
 // and nats.QueueSubscribe (https://godoc.org/github.com/nats-io/go-nats#Conn.QueueSubscribe or
 // https://godoc.org/github.com/nats-io/go-nats#EncodedConn.QueueSubscribe)
 // If the `newrelic.Application` parameter is non-nil, it will create a `newrelic.Transaction` and end the transaction
-// when the passed function is complete.
+// when the passed function is complete.  The transaction is created using
+// StartConsumeTransaction, so the function can also be used with
+// JetStream subscriptions (https://godoc.org/github.com/nats-io/nats.go#JetStreamContext).
 func SubWrapper(app *newrelic.Application, f func(msg *nats.Msg)) func(msg *nats.Msg) {
 	if app == nil {
 		return f
 	}
 	return func(msg *nats.Msg) {
-		namer := internal.MessageMetricKey{
-			Library:         "NATS",
-			DestinationType: string(newrelic.MessageTopic),
-			DestinationName: msg.Subject,
-			Consumer:        true,
-		}
-		txn := app.StartTransaction(namer.Name())
+		txn := StartConsumeTransaction(app, msg)
 		defer txn.End()
 
-		integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageRoutingKey, msg.Sub.Subject, nil)
-		integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageQueueName, msg.Sub.Queue, nil)
-		integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageReplyTo, msg.Reply, nil)
-
 		f(msg)
 	}
 }
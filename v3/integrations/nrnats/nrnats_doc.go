@@ -3,9 +3,19 @@
 
 // Package nrnats instruments https://github.com/nats-io/nats.go.
 //
-// This package can be used to simplify instrumenting NATS publishers and subscribers. Currently due to the nature of
-// the NATS framework we are limited to two integration points: `StartPublishSegment` for publishers, and `SubWrapper`
-// for subscribers.
+// This package can be used to simplify instrumenting NATS publishers and subscribers: `Publish`, `PublishMsg` and
+// `StartPublishSegment` for publishers, `JetStreamPublish` and `JetStreamPublishMsg` for JetStream publishers, and
+// `SubWrapper` for subscribers.
+//
+// Distributed tracing
+//
+// `Publish`, `PublishMsg`, `JetStreamPublish` and `JetStreamPublishMsg` add the distributed tracing headers of the
+// transaction to the message headers, and the transactions created by `SubWrapper` and `StartConsumeTransaction`
+// accept them.  Message headers require NATS server 2.2 or later:  `Publish` and `PublishMsg` only add them if the
+// server supports headers.
+//
+//	txn := currentTransaction()  // current newrelic.Transaction
+//	err := nrnats.Publish(txn, nc, "testing.subject", []byte("Hello World"))
 //
 // NATS publishers
 //
@@ -57,6 +67,18 @@
 //	subject := "testing.subject"
 //	nc.Subscribe(subject, nrnats.SubWrapper(app, myMessageHandler))
 //
+// NATS JetStream
+//
+// Use `JetStreamPublish` or `JetStreamPublishMsg` to publish messages to a JetStream stream, and `SubWrapper` to
+// consume them.  The stream, consumer, stream sequence and delivery count of each message consumed from a stream are
+// added to its transaction as attributes.  Example:
+//
+//	js, _ := nc.JetStream()
+//	ack, err := nrnats.JetStreamPublish(txn, js, "orders.created", []byte("order 42"))
+//	js.Subscribe("orders.*", nrnats.SubWrapper(app, myMessageHandler), nats.Durable("worker"))
+//
+// Use `StartConsumeTransaction` to instrument messages received in other ways, such as by a pull subscription.
+//
 // Full Publisher/Subscriber example:
 // https://github.com/newrelic/go-agent/blob/master/v3/integrations/nrnats/examples/main.go
 package nrnats
@@ -0,0 +1,176 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrnats
+
+import (
+	"testing"
+
+	nats "github.com/nats-io/nats.go"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func testApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn, func(cfg *newrelic.Config) {
+		cfg.Attributes.Include = append(cfg.Attributes.Include,
+			newrelic.AttributeMessageRoutingKey,
+			newrelic.AttributeMessageQueueName,
+			newrelic.AttributeMessageReplyTo,
+		)
+	})
+}
+
+type jetStream struct {
+	nats.JetStream
+	published []*nats.Msg
+}
+
+func (js *jetStream) PublishMsg(msg *nats.Msg, opts ...nats.PubOpt) (*nats.PubAck, error) {
+	js.published = append(js.published, msg)
+	return &nats.PubAck{Stream: "ORDERS", Sequence: uint64(len(js.published))}, nil
+}
+
+func TestJetStreamPublishMsg(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("produce")
+	msg := &nats.Msg{
+		Subject: "orders.created",
+		Header: nats.Header{
+			"Traceparent": []string{"stale"},
+			"User":        []string{"kept"},
+		},
+	}
+	js := &jetStream{}
+	if ack, err := JetStreamPublishMsg(txn, js, msg); nil != err || 1 != ack.Sequence {
+		t.Error(ack, err)
+	}
+	txn.End()
+
+	if 1 != len(js.published) || msg != js.published[0] {
+		t.Error(js.published)
+	}
+	if _, ok := msg.Header["Traceparent"]; ok {
+		t.Error(msg.Header)
+	}
+	if v := msg.Header["traceparent"]; 1 != len(v) || "stale" == v[0] {
+		t.Error(v)
+	}
+	if v := msg.Header["newrelic"]; 1 != len(v) {
+		t.Error(v)
+	}
+	if v := msg.Header["User"]; 1 != len(v) || "kept" != v[0] {
+		t.Error(v)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "MessageBroker/NATS/Topic/Produce/Named/orders.created", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/NATS/Topic/Produce/Named/orders.created", Scope: "OtherTransaction/Go/produce", Forced: false, Data: []float64{1}},
+	})
+}
+
+func TestJetStreamPublishNilTransaction(t *testing.T) {
+	js := &jetStream{}
+	if _, err := JetStreamPublish(nil, js, "orders.created", []byte("data")); nil != err {
+		t.Error(err)
+	}
+	if 1 != len(js.published) || 0 != len(js.published[0].Header) {
+		t.Error(js.published)
+	}
+}
+
+func TestSubWrapperJetStream(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("produce")
+	msg := &nats.Msg{Subject: "orders.created"}
+	InsertDistributedTraceHeaders(txn, msg)
+	txn.End()
+
+	msg.Sub = &nats.Subscription{Subject: "orders.*"}
+	msg.Reply = "$JS.ACK.ORDERS.worker.2.42.7.1634380000000000000.0"
+	called := false
+	SubWrapper(app.Application, func(m *nats.Msg) { called = msg == m })(msg)
+	if !called {
+		t.Error("handler not called")
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/produce",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":                     "OtherTransaction/Go/Message/NATS/Topic/Named/orders.created",
+				"guid":                     internal.MatchAnything,
+				"traceId":                  internal.MatchAnything,
+				"priority":                 internal.MatchAnything,
+				"sampled":                  internal.MatchAnything,
+				"parent.type":              "App",
+				"parent.account":           "123",
+				"parent.app":               "456",
+				"parent.transportType":     "Queue",
+				"parent.transportDuration": internal.MatchAnything,
+				"parentId":                 internal.MatchAnything,
+				"parentSpanId":             internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeJetStreamStream:    "ORDERS",
+				AttributeJetStreamConsumer:  "worker",
+				AttributeJetStreamSequence:  42,
+				AttributeJetStreamDelivered: 2,
+			},
+			AgentAttributes: map[string]interface{}{
+				"message.routingKey": "orders.*",
+				"message.replyTo":    msg.Reply,
+			},
+		},
+	})
+}
+
+func TestStartConsumeTransactionCore(t *testing.T) {
+	app := testApp()
+	msg := &nats.Msg{
+		Subject: "orders.created",
+		Reply:   "_INBOX.abc",
+		Sub:     &nats.Subscription{Subject: "orders.created", Queue: "workers"},
+	}
+	StartConsumeTransaction(app.Application, msg).End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/Message/NATS/Topic/Named/orders.created", Scope: "", Forced: true, Data: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Queue/all", Scope: "", Forced: false, Data: nil},
+	})
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/Message/NATS/Topic/Named/orders.created",
+			"guid":     internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+			"priority": internal.MatchAnything,
+			"sampled":  internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			"message.routingKey": "orders.created",
+			"message.queueName":  "workers",
+			"message.replyTo":    "_INBOX.abc",
+		},
+	}})
+}
+
+func TestStartConsumeTransactionNil(t *testing.T) {
+	if txn := StartConsumeTransaction(nil, &nats.Msg{}); nil != txn {
+		t.Error(txn)
+	}
+	if txn := StartConsumeTransaction(testApp().Application, nil); nil != txn {
+		t.Error(txn)
+	}
+}
@@ -2,12 +2,12 @@ module github.com/newrelic/go-agent/v3/integrations/test
 
 // This module exists to avoid having extra nrnats module dependencies.
 
-go 1.13
+go 1.16
 
 require (
 	github.com/nats-io/gnatsd v1.4.1 // indirect
 	github.com/nats-io/nats-server v1.4.1
-	github.com/nats-io/nats.go v1.8.0
+	github.com/nats-io/nats.go v1.13.0
 	github.com/newrelic/go-agent/v3 v3.4.0
 	github.com/newrelic/go-agent/v3/integrations/nrnats v0.0.0
 )
@@ -0,0 +1,37 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrpgx5_test
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/newrelic/go-agent/v3/integrations/nrpgx5"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+	txn := app.StartTransaction("createUser")
+	defer txn.End()
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	cfg, err := pgx.ParseConfig("postgres://user@localhost/mydb")
+	if err != nil {
+		panic(err)
+	}
+	cfg.Tracer = nrpgx5.NewTracer()
+	conn, err := pgx.ConnectConfig(ctx, cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "INSERT INTO users (name) VALUES ($1)", "gopher"); err != nil {
+		txn.NoticeError(err)
+	}
+}
@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrpgx5
+
+go 1.18
+
+require (
+	github.com/jackc/pgx/v5 v5.0.0
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
@@ -0,0 +1,210 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrpgx5 instruments PostgreSQL calls made using the native API of
+// https://github.com/jackc/pgx version 5.
+//
+// Use this package when calling pgx directly, or through pgxpool, rather
+// than through database/sql.  Set the Tracer of the connection config to a
+// Tracer created by NewTracer:
+//
+//	cfg, err := pgx.ParseConfig("postgres://user@localhost/mydb")
+//	if err != nil {
+//		panic(err)
+//	}
+//	cfg.Tracer = nrpgx5.NewTracer()
+//	conn, err := pgx.ConnectConfig(ctx, cfg)
+//
+// When using pgxpool, set the Tracer of the pool config's ConnConfig:
+//
+//	cfg, err := pgxpool.ParseConfig("postgres://user@localhost/mydb")
+//	if err != nil {
+//		panic(err)
+//	}
+//	cfg.ConnConfig.Tracer = nrpgx5.NewTracer()
+//	pool, err := pgxpool.NewWithConfig(ctx, cfg)
+//
+// Then provide a context containing a newrelic.Transaction to the calls
+// to be timed:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	row := conn.QueryRow(ctx, "SELECT count(*) FROM pg_catalog.pg_tables")
+//
+// Each query, including those run by Exec, is timed by a DatastoreSegment
+// whose operation and collection are parsed from the SQL.  Each batch sent
+// using SendBatch is timed by a single segment with the operation "batch",
+// and connecting is timed by a segment with the operation "connect".
+package nrpgx5
+
+import (
+	"context"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/newrelic/go-agent/v3/newrelic/sqlparse"
+)
+
+func init() { internal.TrackUsage("integration", "datastore", "pgx5") }
+
+// Tracer times the queries, batches and connections of pgx connections
+// using DatastoreSegments.  Create it using NewTracer.
+type Tracer struct {
+	// ParseQuery sets the Operation and Collection of the segment of each
+	// query.  By default it is sqlparse.ParseQuery.
+	ParseQuery func(segment *newrelic.DatastoreSegment, query string)
+}
+
+var (
+	_ pgx.QueryTracer   = (*Tracer)(nil)
+	_ pgx.BatchTracer   = (*Tracer)(nil)
+	_ pgx.ConnectTracer = (*Tracer)(nil)
+)
+
+// NewTracer creates a Tracer to be assigned to pgx.ConnConfig.Tracer.
+func NewTracer() *Tracer {
+	return &Tracer{ParseQuery: sqlparse.ParseQuery}
+}
+
+type querySegmentKey struct{}
+type batchSegmentKey struct{}
+type connectSegmentKey struct{}
+
+// batchSegment accumulates the queries of a batch as their results are
+// read.
+type batchSegment struct {
+	segment    *newrelic.DatastoreSegment
+	queries    []string
+	collection string
+	mixed      bool
+}
+
+// baseSegment returns a segment with the location of the database
+// described by the config.
+func baseSegment(cfg *pgx.ConnConfig) newrelic.DatastoreSegment {
+	s := newrelic.DatastoreSegment{Product: newrelic.DatastorePostgres}
+	if nil == cfg {
+		return s
+	}
+	host := cfg.Host
+	port := cfg.Port
+	if 0 == port {
+		port = 5432
+	}
+	ppoid := strconv.Itoa(int(port))
+	if "" == host {
+		host = "localhost"
+	} else if strings.HasPrefix(host, "/") {
+		// this is a unix socket
+		ppoid = path.Join(host, ".s.PGSQL."+ppoid)
+		host = "localhost"
+	}
+	s.Host = host
+	s.PortPathOrID = ppoid
+	s.DatabaseName = cfg.Database
+	return s
+}
+
+func connConfig(conn *pgx.Conn) *pgx.ConnConfig {
+	if nil == conn {
+		return nil
+	}
+	return conn.Config()
+}
+
+func (t *Tracer) parseQuery(s *newrelic.DatastoreSegment, query string) {
+	if nil != t.ParseQuery {
+		t.ParseQuery(s, query)
+	}
+}
+
+// TraceQueryStart implements pgx.QueryTracer.  It starts the segment of the
+// query if the context contains a Transaction.
+func (t *Tracer) TraceQueryStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryStartData) context.Context {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return ctx
+	}
+	s := baseSegment(connConfig(conn))
+	s.StartTime = txn.StartSegmentNow()
+	s.ParameterizedQuery = data.SQL
+	t.parseQuery(&s, data.SQL)
+	return context.WithValue(ctx, querySegmentKey{}, &s)
+}
+
+// TraceQueryEnd implements pgx.QueryTracer.  It ends the segment of the
+// query.
+func (t *Tracer) TraceQueryEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceQueryEndData) {
+	if s, ok := ctx.Value(querySegmentKey{}).(*newrelic.DatastoreSegment); ok {
+		s.End()
+	}
+}
+
+// TraceBatchStart implements pgx.BatchTracer.  It starts the segment of the
+// batch if the context contains a Transaction.
+func (t *Tracer) TraceBatchStart(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchStartData) context.Context {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return ctx
+	}
+	s := baseSegment(connConfig(conn))
+	s.StartTime = txn.StartSegmentNow()
+	s.Operation = "batch"
+	return context.WithValue(ctx, batchSegmentKey{}, &batchSegment{segment: &s})
+}
+
+// TraceBatchQuery implements pgx.BatchTracer.  It adds the query to the
+// segment of the batch.  The collection of the segment is set if every
+// query of the batch uses the same collection.
+func (t *Tracer) TraceBatchQuery(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchQueryData) {
+	b, ok := ctx.Value(batchSegmentKey{}).(*batchSegment)
+	if !ok {
+		return
+	}
+	var parsed newrelic.DatastoreSegment
+	t.parseQuery(&parsed, data.SQL)
+	if 0 == len(b.queries) {
+		b.collection = parsed.Collection
+	} else if b.collection != parsed.Collection {
+		b.mixed = true
+	}
+	b.queries = append(b.queries, data.SQL)
+}
+
+// TraceBatchEnd implements pgx.BatchTracer.  It ends the segment of the
+// batch.
+func (t *Tracer) TraceBatchEnd(ctx context.Context, conn *pgx.Conn, data pgx.TraceBatchEndData) {
+	b, ok := ctx.Value(batchSegmentKey{}).(*batchSegment)
+	if !ok {
+		return
+	}
+	if !b.mixed {
+		b.segment.Collection = b.collection
+	}
+	b.segment.ParameterizedQuery = strings.Join(b.queries, "; ")
+	b.segment.End()
+}
+
+// TraceConnectStart implements pgx.ConnectTracer.  It starts the segment of
+// the connection if the context contains a Transaction.
+func (t *Tracer) TraceConnectStart(ctx context.Context, data pgx.TraceConnectStartData) context.Context {
+	txn := newrelic.FromContext(ctx)
+	if nil == txn {
+		return ctx
+	}
+	s := baseSegment(data.ConnConfig)
+	s.StartTime = txn.StartSegmentNow()
+	s.Operation = "connect"
+	return context.WithValue(ctx, connectSegmentKey{}, &s)
+}
+
+// TraceConnectEnd implements pgx.ConnectTracer.  It ends the segment of the
+// connection.
+func (t *Tracer) TraceConnectEnd(ctx context.Context, data pgx.TraceConnectEndData) {
+	if s, ok := ctx.Value(connectSegmentKey{}).(*newrelic.DatastoreSegment); ok {
+		s.End()
+	}
+}
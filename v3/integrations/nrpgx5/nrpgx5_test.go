@@ -0,0 +1,147 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrpgx5
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func TestBaseSegment(t *testing.T) {
+	testcases := []struct {
+		cfg             *pgx.ConnConfig
+		expHost         string
+		expPortPathOrID string
+		expDatabaseName string
+	}{
+		{
+			cfg:             nil,
+			expHost:         "",
+			expPortPathOrID: "",
+			expDatabaseName: "",
+		},
+		{
+			cfg:             &pgx.ConnConfig{Config: pgconn.Config{Host: "db.example.com", Port: 5433, Database: "mydb"}},
+			expHost:         "db.example.com",
+			expPortPathOrID: "5433",
+			expDatabaseName: "mydb",
+		},
+		{
+			cfg:             &pgx.ConnConfig{Config: pgconn.Config{}},
+			expHost:         "localhost",
+			expPortPathOrID: "5432",
+			expDatabaseName: "",
+		},
+		{
+			cfg:             &pgx.ConnConfig{Config: pgconn.Config{Host: "/var/run/postgresql", Port: 5432, Database: "mydb"}},
+			expHost:         "localhost",
+			expPortPathOrID: "/var/run/postgresql/.s.PGSQL.5432",
+			expDatabaseName: "mydb",
+		},
+	}
+
+	for _, tc := range testcases {
+		s := baseSegment(tc.cfg)
+		if s.Product != newrelic.DatastorePostgres {
+			t.Errorf("incorrect product: %s", s.Product)
+		}
+		if s.Host != tc.expHost {
+			t.Errorf(`incorrect host, expected="%s", actual="%s"`, tc.expHost, s.Host)
+		}
+		if s.PortPathOrID != tc.expPortPathOrID {
+			t.Errorf(`incorrect port path or id, expected="%s", actual="%s"`, tc.expPortPathOrID, s.PortPathOrID)
+		}
+		if s.DatabaseName != tc.expDatabaseName {
+			t.Errorf(`incorrect database name, expected="%s", actual="%s"`, tc.expDatabaseName, s.DatabaseName)
+		}
+	}
+}
+
+func TestQuery(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	tracer := NewTracer()
+	txn := app.StartTransaction("query")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT * FROM users WHERE id = $1"})
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{})
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/Postgres/all", Scope: "", Forced: true, Data: nil},
+		{Name: "Datastore/operation/Postgres/select", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Postgres/users/select", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/statement/Postgres/users/select", Scope: "OtherTransaction/Go/query", Forced: false, Data: nil},
+	})
+}
+
+func TestQueryWithoutTransaction(t *testing.T) {
+	tracer := NewTracer()
+	ctx := context.Background()
+	qctx := tracer.TraceQueryStart(ctx, nil, pgx.TraceQueryStartData{SQL: "SELECT 1"})
+	if qctx != ctx {
+		t.Error("context changed without a transaction")
+	}
+	tracer.TraceQueryEnd(qctx, nil, pgx.TraceQueryEndData{})
+}
+
+func TestBatch(t *testing.T) {
+	testcases := []struct {
+		queries []string
+		metric  string
+	}{
+		{
+			queries: []string{"INSERT INTO users (id) VALUES ($1)", "UPDATE users SET name = $1"},
+			metric:  "Datastore/statement/Postgres/users/batch",
+		},
+		{
+			queries: []string{"INSERT INTO users (id) VALUES ($1)", "DELETE FROM orders"},
+			metric:  "Datastore/operation/Postgres/batch",
+		},
+	}
+
+	for _, tc := range testcases {
+		app := integrationsupport.NewBasicTestApp()
+		tracer := NewTracer()
+		txn := app.StartTransaction("batch")
+		ctx := newrelic.NewContext(context.Background(), txn)
+
+		bctx := tracer.TraceBatchStart(ctx, nil, pgx.TraceBatchStartData{Batch: &pgx.Batch{}})
+		for _, q := range tc.queries {
+			tracer.TraceBatchQuery(bctx, nil, pgx.TraceBatchQueryData{SQL: q})
+		}
+		tracer.TraceBatchEnd(bctx, nil, pgx.TraceBatchEndData{})
+		txn.End()
+
+		app.ExpectMetricsPresent(t, []internal.WantMetric{
+			{Name: "Datastore/operation/Postgres/batch", Scope: "", Forced: false, Data: nil},
+			{Name: tc.metric, Scope: "OtherTransaction/Go/batch", Forced: false, Data: nil},
+		})
+	}
+}
+
+func TestConnect(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	tracer := NewTracer()
+	txn := app.StartTransaction("connect")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	cfg := &pgx.ConnConfig{Config: pgconn.Config{Host: "db.example.com", Port: 5432, Database: "mydb"}}
+	cctx := tracer.TraceConnectStart(ctx, pgx.TraceConnectStartData{ConnConfig: cfg})
+	tracer.TraceConnectEnd(cctx, pgx.TraceConnectEndData{})
+	txn.End()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/operation/Postgres/connect", Scope: "", Forced: false, Data: nil},
+		{Name: "Datastore/operation/Postgres/connect", Scope: "OtherTransaction/Go/connect", Forced: false, Data: nil},
+		{Name: "Datastore/instance/Postgres/db.example.com/5432", Scope: "", Forced: false, Data: nil},
+	})
+}
@@ -0,0 +1,9 @@
+module github.com/newrelic/go-agent/v3/integrations/nrredis-v9
+
+// go-redis/v9 requires go 1.17
+go 1.17
+
+require (
+	github.com/newrelic/go-agent/v3 v3.13.0
+	github.com/redis/go-redis/v9 v9.0.5
+)
@@ -0,0 +1,156 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrredis instruments github.com/redis/go-redis/v9.
+//
+// Use this package to instrument your go-redis/v9 calls without having to
+// manually create DatastoreSegments.  Add the hook to your client, then
+// ensure that all calls contain a context which includes the transaction:
+//
+//	opts := &redis.Options{Addr: "localhost:6379"}
+//	client := redis.NewClient(opts)
+//	client.AddHook(nrredis.NewHook(opts))
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	client.Get(ctx, "key")
+//
+// Each command is timed by a DatastoreSegment whose operation is the
+// command name, eg. "get".  Each pipeline, including transactions using
+// MULTI and EXEC, is timed by a single segment whose operation lists the
+// commands, eg. "pipeline:get,set".  The arguments of commands are not
+// recorded unless the WithArguments option is provided.
+package nrredis
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/redis/go-redis/v9"
+)
+
+func init() { internal.TrackUsage("integration", "datastore", "redis") }
+
+type hook struct {
+	segment newrelic.DatastoreSegment
+	// arguments controls whether the arguments of commands are recorded
+	// as the statement of segments.
+	arguments bool
+	// obfuscate replaces the arguments recorded with "?".
+	obfuscate bool
+}
+
+var _ redis.Hook = hook{}
+
+// HookOption configures the hook created by NewHook.
+type HookOption func(*hook)
+
+// WithArguments records the arguments of each command as the statement of
+// its segment, the "db.statement" span attribute, eg. "set mykey ?" or
+// "set mykey myvalue".  The arguments of the commands of a pipeline are
+// separated by semicolons.  If obfuscate is true, every argument other than
+// the command name is replaced by "?", so that no keys or values are sent to
+// New Relic.
+func WithArguments(obfuscate bool) HookOption {
+	return func(h *hook) {
+		h.arguments = true
+		h.obfuscate = obfuscate
+	}
+}
+
+// NewHook creates a redis.Hook to instrument Redis calls.  Add it to your
+// client, then ensure that all calls contain a context which includes the
+// transaction.  The redis options are optional.  Provide them to get
+// instance metrics broken out by host and port.  The hook returned can be
+// used with redis.Client, redis.ClusterClient, and redis.Ring.
+func NewHook(opts *redis.Options, hookOpts ...HookOption) redis.Hook {
+	h := hook{}
+	h.segment.Product = newrelic.DatastoreRedis
+	if opts != nil {
+		// Per https://pkg.go.dev/github.com/redis/go-redis/v9#Options the
+		// network should either be tcp or unix, and the default is tcp.
+		if opts.Network == "unix" {
+			h.segment.Host = "localhost"
+			h.segment.PortPathOrID = opts.Addr
+		} else if host, port, err := net.SplitHostPort(opts.Addr); err == nil {
+			if "" == host {
+				host = "localhost"
+			}
+			h.segment.Host = host
+			h.segment.PortPathOrID = port
+		}
+	}
+	for _, opt := range hookOpts {
+		opt(&h)
+	}
+	return h
+}
+
+func (h hook) start(ctx context.Context, operation string, cmds []redis.Cmder) *newrelic.DatastoreSegment {
+	txn := newrelic.FromContext(ctx)
+	if txn == nil {
+		return nil
+	}
+	s := h.segment
+	s.StartTime = txn.StartSegmentNow()
+	s.Operation = operation
+	if h.arguments {
+		s.ParameterizedQuery = h.statement(cmds)
+	}
+	return &s
+}
+
+// statement returns the arguments of the commands, separated by
+// semicolons.
+func (h hook) statement(cmds []redis.Cmder) string {
+	statements := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		args := cmd.Args()
+		words := make([]string, 0, len(args))
+		for i, arg := range args {
+			if h.obfuscate && i > 0 {
+				words = append(words, "?")
+			} else {
+				words = append(words, fmt.Sprint(arg))
+			}
+		}
+		statements = append(statements, strings.Join(words, " "))
+	}
+	return strings.Join(statements, "; ")
+}
+
+// DialHook implements redis.Hook.  Dialing is not instrumented.
+func (h hook) DialHook(next redis.DialHook) redis.DialHook {
+	return next
+}
+
+// ProcessHook implements redis.Hook.  It times each command.
+func (h hook) ProcessHook(next redis.ProcessHook) redis.ProcessHook {
+	return func(ctx context.Context, cmd redis.Cmder) error {
+		s := h.start(ctx, cmd.Name(), []redis.Cmder{cmd})
+		err := next(ctx, cmd)
+		s.End()
+		return err
+	}
+}
+
+func pipelineOperation(cmds []redis.Cmder) string {
+	operations := make([]string, 0, len(cmds))
+	for _, cmd := range cmds {
+		operations = append(operations, cmd.Name())
+	}
+	return "pipeline:" + strings.Join(operations, ",")
+}
+
+// ProcessPipelineHook implements redis.Hook.  It times each pipeline.
+func (h hook) ProcessPipelineHook(next redis.ProcessPipelineHook) redis.ProcessPipelineHook {
+	return func(ctx context.Context, cmds []redis.Cmder) error {
+		s := h.start(ctx, pipelineOperation(cmds), cmds)
+		err := next(ctx, cmds)
+		s.End()
+		return err
+	}
+}
@@ -0,0 +1,229 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrredis
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+	"github.com/redis/go-redis/v9"
+)
+
+func noopProcess(context.Context, redis.Cmder) error { return nil }
+
+func noopPipeline(context.Context, []redis.Cmder) error { return nil }
+
+func TestProcessHook(t *testing.T) {
+	app := integrationsupport.NewTestApp(nil, nil)
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	hk := NewHook(&redis.Options{Addr: "myhost:myport"})
+	if err := hk.ProcessHook(noopProcess)(ctx, redis.NewCmd(ctx, "GET", "mykey")); nil != err {
+		t.Error(err)
+	}
+	txn.End()
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/txnName", Forced: nil},
+		{Name: "OtherTransactionTotalTime/Go/txnName", Forced: nil},
+		{Name: "OtherTransaction/all", Forced: nil},
+		{Name: "OtherTransactionTotalTime", Forced: nil},
+		{Name: "Datastore/all", Forced: nil},
+		{Name: "Datastore/allOther", Forced: nil},
+		{Name: "Datastore/Redis/all", Forced: nil},
+		{Name: "Datastore/Redis/allOther", Forced: nil},
+		{Name: "Datastore/instance/Redis/myhost/myport", Forced: nil},
+		{Name: "Datastore/operation/Redis/get", Forced: nil},
+		{Name: "Datastore/operation/Redis/get", Scope: "OtherTransaction/Go/txnName", Forced: nil},
+	})
+}
+
+func TestProcessHookError(t *testing.T) {
+	errRedis := errors.New("redis: nil")
+	hk := NewHook(nil)
+	err := hk.ProcessHook(func(context.Context, redis.Cmder) error {
+		return errRedis
+	})(context.Background(), redis.NewCmd(context.Background(), "GET", "mykey"))
+	if err != errRedis {
+		t.Error(err)
+	}
+}
+
+func TestProcessPipelineHook(t *testing.T) {
+	app := integrationsupport.NewTestApp(nil, nil)
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	hk := NewHook(nil)
+	cmds := []redis.Cmder{redis.NewCmd(ctx, "GET", "a"), redis.NewCmd(ctx, "SET", "b", 1)}
+	if err := hk.ProcessPipelineHook(noopPipeline)(ctx, cmds); nil != err {
+		t.Error(err)
+	}
+	txn.End()
+
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/txnName", Forced: nil},
+		{Name: "OtherTransactionTotalTime/Go/txnName", Forced: nil},
+		{Name: "OtherTransaction/all", Forced: nil},
+		{Name: "OtherTransactionTotalTime", Forced: nil},
+		{Name: "Datastore/all", Forced: nil},
+		{Name: "Datastore/allOther", Forced: nil},
+		{Name: "Datastore/Redis/all", Forced: nil},
+		{Name: "Datastore/Redis/allOther", Forced: nil},
+		{Name: "Datastore/operation/Redis/pipeline:get,set", Forced: nil},
+		{Name: "Datastore/operation/Redis/pipeline:get,set", Scope: "OtherTransaction/Go/txnName", Forced: nil},
+	})
+}
+
+func TestPipelineOperation(t *testing.T) {
+	if op := pipelineOperation(nil); op != "pipeline:" {
+		t.Error(op)
+	}
+	ctx := context.Background()
+	cmds := []redis.Cmder{redis.NewCmd(ctx, "GET"), redis.NewCmd(ctx, "SET")}
+	if op := pipelineOperation(cmds); op != "pipeline:get,set" {
+		t.Error(op)
+	}
+}
+
+func TestStatement(t *testing.T) {
+	ctx := context.Background()
+	cmds := []redis.Cmder{redis.NewCmd(ctx, "set", "mykey", "myvalue"), redis.NewCmd(ctx, "incrby", "counter", 5)}
+	testcases := []struct {
+		opts   []HookOption
+		expect string
+	}{
+		{opts: nil, expect: ""},
+		{opts: []HookOption{WithArguments(true)}, expect: "set ? ?; incrby ? ?"},
+		{opts: []HookOption{WithArguments(false)}, expect: "set mykey myvalue; incrby counter 5"},
+	}
+	for _, tc := range testcases {
+		hk := NewHook(nil, tc.opts...).(hook)
+		var statement string
+		if hk.arguments {
+			statement = hk.statement(cmds)
+		}
+		if statement != tc.expect {
+			t.Errorf("expected %q got %q", tc.expect, statement)
+		}
+	}
+}
+
+func TestWithArgumentsSpanAttribute(t *testing.T) {
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("txnName")
+	ctx := newrelic.NewContext(context.Background(), txn)
+
+	hk := NewHook(nil, WithArguments(true))
+	hk.ProcessHook(noopProcess)(ctx, redis.NewCmd(ctx, "set", "mykey", "myvalue"))
+	txn.End()
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/operation/Redis/set",
+				"category":  "datastore",
+				"component": "Redis",
+				"span.kind": "client",
+				"parentId":  internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.statement": "set ? ?",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/txnName",
+				"transaction.name": "OtherTransaction/Go/txnName",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestNewHookAddress(t *testing.T) {
+	testcases := []struct {
+		network string
+		address string
+		expHost string
+		expPort string
+	}{
+		// examples from net.Dial https://godoc.org/net#Dial
+		{
+			network: "tcp",
+			address: "golang.org:http",
+			expHost: "golang.org",
+			expPort: "http",
+		},
+		{
+			network: "", // tcp is assumed if missing
+			address: "golang.org:http",
+			expHost: "golang.org",
+			expPort: "http",
+		},
+		{
+			network: "tcp",
+			address: "192.0.2.1:http",
+			expHost: "192.0.2.1",
+			expPort: "http",
+		},
+		{
+			network: "tcp",
+			address: "198.51.100.1:80",
+			expHost: "198.51.100.1",
+			expPort: "80",
+		},
+		{
+			network: "tcp",
+			address: ":80",
+			expHost: "localhost",
+			expPort: "80",
+		},
+		{
+			network: "tcp",
+			address: "0.0.0.0:80",
+			expHost: "0.0.0.0",
+			expPort: "80",
+		},
+		{
+			network: "tcp",
+			address: "[::]:80",
+			expHost: "::",
+			expPort: "80",
+		},
+		{
+			network: "unix",
+			address: "path/to/socket",
+			expHost: "localhost",
+			expPort: "path/to/socket",
+		},
+	}
+
+	for _, tc := range testcases {
+		t.Run(tc.network+","+tc.address, func(t *testing.T) {
+			hk := NewHook(&redis.Options{
+				Network: tc.network,
+				Addr:    tc.address,
+			}).(hook)
+
+			if hk.segment.Host != tc.expHost {
+				t.Errorf("incorrect host: expect=%s actual=%s",
+					tc.expHost, hk.segment.Host)
+			}
+			if hk.segment.PortPathOrID != tc.expPort {
+				t.Errorf("incorrect port: expect=%s actual=%s",
+					tc.expPort, hk.segment.PortPathOrID)
+			}
+		})
+	}
+}
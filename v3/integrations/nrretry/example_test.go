@@ -0,0 +1,32 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrretry_test
+
+import (
+	"net/http"
+
+	retry "github.com/avast/retry-go"
+	"github.com/newrelic/go-agent/v3/integrations/nrretry"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+	)
+	txn := app.StartTransaction("fetchConfig")
+	defer txn.End()
+
+	err := nrretry.Do(txn, "fetchConfig", func() error {
+		resp, err := http.Get("http://config.example.com/")
+		if err != nil {
+			return err
+		}
+		return resp.Body.Close()
+	}, retry.Attempts(5))
+	if err != nil {
+		txn.NoticeError(err)
+	}
+}
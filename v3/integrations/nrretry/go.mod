@@ -0,0 +1,8 @@
+module github.com/newrelic/go-agent/v3/integrations/nrretry
+
+go 1.13
+
+require (
+	github.com/avast/retry-go v3.0.0+incompatible
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
@@ -0,0 +1,98 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrretry instruments retries made using
+// https://github.com/avast/retry-go.
+//
+// Use Do in place of retry.Do.  Instead of:
+//
+//	err := retry.Do(fetchConfig, retry.Attempts(5))
+//
+// Use:
+//
+//	err := nrretry.Do(txn, "fetchConfig", fetchConfig, retry.Attempts(5))
+//
+// Each call creates a segment with the given name which covers every
+// attempt, including the time spent waiting between attempts.  It has the
+// following attributes:
+//
+//	retry.attempts  the number of attempts made
+//	retry.outcome   "success" if the last attempt succeeded, otherwise
+//	                "failure"
+//
+// Each attempt creates a child segment named "{name}/attempt" with the
+// following attributes:
+//
+//	retry.attempt   the attempt number, starting at 1
+//	retry.delay     the time in seconds waited since the previous attempt
+//	retry.outcome   "success" or "error"
+//	retry.error     the error returned by the attempt, if any
+package nrretry
+
+import (
+	"time"
+
+	retry "github.com/avast/retry-go"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "retry", "retry-go") }
+
+// These attributes are added to the segments created by Do.
+const (
+	AttributeAttempts = "retry.attempts"
+	AttributeAttempt  = "retry.attempt"
+	AttributeDelay    = "retry.delay"
+	AttributeOutcome  = "retry.outcome"
+	AttributeError    = "retry.error"
+)
+
+const (
+	outcomeSuccess = "success"
+	outcomeError   = "error"
+	outcomeFailure = "failure"
+)
+
+// Do calls retry.Do, recording the operation and each attempt as segments.
+// The Transaction may be nil, in which case no segments are created.
+//
+// retry-go does not report the delay before each attempt, so the delay is
+// measured as the time between the end of one attempt and the start of the
+// next.
+func Do(txn *newrelic.Transaction, name string, fn retry.RetryableFunc, opts ...retry.Option) error {
+	if nil == txn {
+		return retry.Do(fn, opts...)
+	}
+	seg := txn.StartSegment(name)
+	attempts := 0
+	var lastEnd time.Time
+	err := retry.Do(func() error {
+		attempts++
+		var delay time.Duration
+		if !lastEnd.IsZero() {
+			delay = time.Since(lastEnd)
+		}
+		s := txn.StartSegment(name + "/attempt")
+		s.AddAttribute(AttributeAttempt, attempts)
+		s.AddAttribute(AttributeDelay, delay.Seconds())
+		err := fn()
+		if nil != err {
+			s.AddAttribute(AttributeOutcome, outcomeError)
+			s.AddAttribute(AttributeError, err.Error())
+		} else {
+			s.AddAttribute(AttributeOutcome, outcomeSuccess)
+		}
+		s.End()
+		lastEnd = time.Now()
+		return err
+	}, opts...)
+	seg.AddAttribute(AttributeAttempts, attempts)
+	if nil != err {
+		seg.AddAttribute(AttributeOutcome, outcomeFailure)
+	} else {
+		seg.AddAttribute(AttributeOutcome, outcomeSuccess)
+	}
+	seg.End()
+	return err
+}
@@ -0,0 +1,67 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrsarama_test
+
+import (
+	"context"
+
+	"github.com/Shopify/sarama"
+	"github.com/newrelic/go-agent/v3/integrations/nrsarama"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example_producer() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigDistributedTracerEnabled(true),
+	)
+	cfg := sarama.NewConfig()
+	// Record headers require Kafka 0.11 or later.
+	cfg.Version = sarama.V0_11_0_0
+	cfg.Producer.Return.Successes = true
+	producer, err := sarama.NewSyncProducer([]string{"localhost:9092"}, cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer producer.Close()
+
+	txn := app.StartTransaction("placeOrder")
+	defer txn.End()
+	_, _, err = nrsarama.SendMessage(txn, producer, &sarama.ProducerMessage{
+		Topic: "orders",
+		Value: sarama.StringEncoder("order 42"),
+	})
+	if err != nil {
+		txn.NoticeError(err)
+	}
+}
+
+func Example_consumer() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigDistributedTracerEnabled(true),
+	)
+	cfg := sarama.NewConfig()
+	cfg.Version = sarama.V0_11_0_0
+	group, err := sarama.NewConsumerGroup([]string{"localhost:9092"}, "fulfillment", cfg)
+	if err != nil {
+		panic(err)
+	}
+	defer group.Close()
+
+	handler := nrsarama.NewConsumerGroupHandler(app, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		txn := newrelic.FromContext(ctx)
+		defer txn.StartSegment("fulfill").End()
+		// ... process the order ...
+		return nil
+	})
+	ctx := context.Background()
+	for {
+		if err := group.Consume(ctx, []string{"orders"}, handler); err != nil {
+			return
+		}
+	}
+}
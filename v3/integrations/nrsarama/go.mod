@@ -0,0 +1,10 @@
+module github.com/newrelic/go-agent/v3/integrations/nrsarama
+
+// As of Nov 2020, go 1.13 is in the sarama go.mod file:
+// https://github.com/Shopify/sarama/blob/master/go.mod
+go 1.13
+
+require (
+	github.com/Shopify/sarama v1.27.2
+	github.com/newrelic/go-agent/v3 v3.13.0
+)
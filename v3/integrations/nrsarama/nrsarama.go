@@ -0,0 +1,192 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrsarama instruments Kafka producers and consumers using
+// https://github.com/Shopify/sarama.
+//
+// Use SendMessage and SendMessages in place of the methods of
+// sarama.SyncProducer.  Instead of:
+//
+//	partition, offset, err := producer.SendMessage(msg)
+//
+// Use:
+//
+//	partition, offset, err := nrsarama.SendMessage(txn, producer, msg)
+//
+// Each message is timed by a MessageProducerSegment, and the distributed
+// tracing headers are added to the message's record headers.  Use
+// StartProducerSegment to instrument messages sent using a
+// sarama.AsyncProducer.
+//
+// Use NewConsumerGroupHandler to consume messages using a
+// sarama.ConsumerGroup:
+//
+//	handler := nrsarama.NewConsumerGroupHandler(app, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+//		txn := newrelic.FromContext(ctx)
+//		// ... process the message ...
+//		return nil
+//	})
+//	err := group.Consume(ctx, []string{"orders"}, handler)
+//
+// Each message is consumed in its own transaction, which accepts the
+// distributed tracing headers found in the message's record headers.  Use
+// StartConsumeTransaction to instrument messages consumed in other ways.
+//
+// Record headers require Kafka 0.11 or later, so set the Version of the
+// sarama.Config accordingly.
+package nrsarama
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Shopify/sarama"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "messagebroker", "sarama") }
+
+const library = integrationsupport.KafkaLibrary
+
+// These attributes are added to the transactions created by
+// StartConsumeTransaction.
+const (
+	AttributePartition = integrationsupport.AttributeKafkaPartition
+	AttributeOffset    = integrationsupport.AttributeKafkaOffset
+)
+
+// InsertDistributedTraceHeaders adds the distributed tracing headers of the
+// Transaction to the message's record headers, replacing any added
+// previously.
+func InsertDistributedTraceHeaders(txn *newrelic.Transaction, msg *sarama.ProducerMessage) {
+	if nil == txn || nil == msg {
+		return
+	}
+	hdrs := integrationsupport.KafkaDistributedTraceHeaders(txn)
+	headers := msg.Headers[:0]
+	for _, h := range msg.Headers {
+		if !integrationsupport.IsKafkaDistributedTraceHeader(hdrs, string(h.Key)) {
+			headers = append(headers, h)
+		}
+	}
+	for key, values := range hdrs {
+		for _, value := range values {
+			headers = append(headers, sarama.RecordHeader{
+				Key:   []byte(key),
+				Value: []byte(value),
+			})
+		}
+	}
+	msg.Headers = headers
+}
+
+// StartProducerSegment starts a MessageProducerSegment for the message and
+// adds the distributed tracing headers to its record headers.  Call End on
+// the segment once the message has been sent.  The Transaction may be nil,
+// in which case nil is returned.
+func StartProducerSegment(txn *newrelic.Transaction, msg *sarama.ProducerMessage) *newrelic.MessageProducerSegment {
+	if nil == txn || nil == msg {
+		return nil
+	}
+	s := &newrelic.MessageProducerSegment{
+		StartTime:       txn.StartSegmentNow(),
+		Library:         library,
+		DestinationType: newrelic.MessageTopic,
+		DestinationName: msg.Topic,
+	}
+	InsertDistributedTraceHeaders(txn, msg)
+	return s
+}
+
+// SendMessage calls producer.SendMessage, timing the call using a
+// MessageProducerSegment.
+func SendMessage(txn *newrelic.Transaction, producer sarama.SyncProducer, msg *sarama.ProducerMessage) (int32, int64, error) {
+	s := StartProducerSegment(txn, msg)
+	partition, offset, err := producer.SendMessage(msg)
+	s.End()
+	return partition, offset, err
+}
+
+// SendMessages calls producer.SendMessages, timing the call using a
+// MessageProducerSegment for each message.
+func SendMessages(txn *newrelic.Transaction, producer sarama.SyncProducer, msgs []*sarama.ProducerMessage) error {
+	segments := make([]*newrelic.MessageProducerSegment, len(msgs))
+	for i, msg := range msgs {
+		segments[i] = StartProducerSegment(txn, msg)
+	}
+	err := producer.SendMessages(msgs)
+	// Segments must be ended in the reverse order of starting them.
+	for i := len(segments) - 1; i >= 0; i-- {
+		segments[i].End()
+	}
+	return err
+}
+
+// StartConsumeTransaction starts a transaction for consuming the message.
+// The transaction is named after the message's topic and accepts the
+// distributed tracing headers found in its record headers.  The
+// Application may be nil, in which case nil is returned.
+func StartConsumeTransaction(app *newrelic.Application, msg *sarama.ConsumerMessage) *newrelic.Transaction {
+	if nil == app || nil == msg {
+		return nil
+	}
+	hdrs := http.Header{}
+	for _, h := range msg.Headers {
+		if nil != h {
+			hdrs.Add(string(h.Key), string(h.Value))
+		}
+	}
+	return integrationsupport.StartKafkaConsumeTransaction(app, integrationsupport.KafkaRecord{
+		Topic:     msg.Topic,
+		Key:       msg.Key,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Headers:   hdrs,
+	})
+}
+
+type consumerGroupHandler struct {
+	app    *newrelic.Application
+	handle func(ctx context.Context, msg *sarama.ConsumerMessage) error
+}
+
+// NewConsumerGroupHandler returns a sarama.ConsumerGroupHandler which calls
+// handle for each message consumed, in a transaction created using
+// StartConsumeTransaction.  The transaction is added to the context passed
+// to handle, which is the context of the consumer group session.  Messages
+// for which handle returns nil are marked as consumed.  If handle returns
+// an error, it is noticed by the transaction, the message is not marked,
+// and consuming the claim stops with the error.
+func NewConsumerGroupHandler(app *newrelic.Application, handle func(ctx context.Context, msg *sarama.ConsumerMessage) error) sarama.ConsumerGroupHandler {
+	return consumerGroupHandler{app: app, handle: handle}
+}
+
+// Setup implements sarama.ConsumerGroupHandler.
+func (h consumerGroupHandler) Setup(sarama.ConsumerGroupSession) error { return nil }
+
+// Cleanup implements sarama.ConsumerGroupHandler.
+func (h consumerGroupHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+// ConsumeClaim implements sarama.ConsumerGroupHandler.
+func (h consumerGroupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := h.consume(session, msg); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+func (h consumerGroupHandler) consume(session sarama.ConsumerGroupSession, msg *sarama.ConsumerMessage) error {
+	txn := StartConsumeTransaction(h.app, msg)
+	defer txn.End()
+
+	if err := h.handle(newrelic.NewContext(session.Context(), txn), msg); nil != err {
+		txn.NoticeError(err)
+		return err
+	}
+	session.MarkMessage(msg, "")
+	return nil
+}
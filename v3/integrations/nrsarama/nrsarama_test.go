@@ -0,0 +1,238 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrsarama
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+var errProcess = errors.New("unable to process")
+
+func testApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+}
+
+type syncProducer struct {
+	sarama.SyncProducer
+	sent []*sarama.ProducerMessage
+}
+
+func (p *syncProducer) SendMessage(msg *sarama.ProducerMessage) (int32, int64, error) {
+	p.sent = append(p.sent, msg)
+	return 1, int64(len(p.sent)), nil
+}
+
+func (p *syncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
+	p.sent = append(p.sent, msgs...)
+	return nil
+}
+
+func header(headers []sarama.RecordHeader, key string) (string, int) {
+	var value string
+	count := 0
+	for _, h := range headers {
+		if key == string(h.Key) {
+			value = string(h.Value)
+			count++
+		}
+	}
+	return value, count
+}
+
+func TestSendMessage(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("produce")
+	msg := &sarama.ProducerMessage{
+		Topic: "orders",
+		Headers: []sarama.RecordHeader{
+			{Key: []byte("traceparent"), Value: []byte("stale")},
+			{Key: []byte("user"), Value: []byte("kept")},
+		},
+	}
+	p := &syncProducer{}
+	if partition, offset, err := SendMessage(txn, p, msg); nil != err || 1 != partition || 1 != offset {
+		t.Error(partition, offset, err)
+	}
+	txn.End()
+
+	if v, n := header(msg.Headers, "traceparent"); 1 != n || "stale" == v {
+		t.Error(v, n)
+	}
+	if _, n := header(msg.Headers, "newrelic"); 1 != n {
+		t.Error(n)
+	}
+	if v, n := header(msg.Headers, "user"); 1 != n || "kept" != v {
+		t.Error(v, n)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/orders", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/orders", Scope: "OtherTransaction/Go/produce", Forced: false, Data: []float64{1}},
+	})
+}
+
+func TestSendMessages(t *testing.T) {
+	app := testApp()
+	txn := app.StartTransaction("produce")
+	msgs := []*sarama.ProducerMessage{{Topic: "orders"}, {Topic: "payments"}}
+	if err := SendMessages(txn, &syncProducer{}, msgs); nil != err {
+		t.Error(err)
+	}
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/orders", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "MessageBroker/Kafka/Topic/Produce/Named/payments", Scope: "", Forced: false, Data: []float64{1}},
+	})
+	for _, msg := range msgs {
+		if _, n := header(msg.Headers, "traceparent"); 1 != n {
+			t.Error(msg.Topic, n)
+		}
+	}
+}
+
+func TestSendMessageNilTransaction(t *testing.T) {
+	msg := &sarama.ProducerMessage{Topic: "orders"}
+	if _, _, err := SendMessage(nil, &syncProducer{}, msg); nil != err {
+		t.Error(err)
+	}
+	if 0 != len(msg.Headers) {
+		t.Error(msg.Headers)
+	}
+}
+
+type session struct {
+	sarama.ConsumerGroupSession
+	marked []*sarama.ConsumerMessage
+}
+
+func (s *session) Context() context.Context { return context.Background() }
+
+func (s *session) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.marked = append(s.marked, msg)
+}
+
+type claim struct {
+	sarama.ConsumerGroupClaim
+	messages chan *sarama.ConsumerMessage
+}
+
+func (c claim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
+
+func consumerMessage(app integrationsupport.ExpectApp) *sarama.ConsumerMessage {
+	txn := app.StartTransaction("produce")
+	produced := &sarama.ProducerMessage{Topic: "orders"}
+	InsertDistributedTraceHeaders(txn, produced)
+	txn.End()
+
+	msg := &sarama.ConsumerMessage{
+		Topic:     "orders",
+		Key:       []byte("order-42"),
+		Partition: 3,
+		Offset:    7,
+	}
+	for i := range produced.Headers {
+		msg.Headers = append(msg.Headers, &produced.Headers[i])
+	}
+	return msg
+}
+
+func TestConsumerGroupHandler(t *testing.T) {
+	app := testApp()
+	msg := consumerMessage(app)
+	c := claim{messages: make(chan *sarama.ConsumerMessage, 1)}
+	c.messages <- msg
+	close(c.messages)
+	s := &session{}
+	var found bool
+	handler := NewConsumerGroupHandler(app.Application, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		found = nil != newrelic.FromContext(ctx)
+		return nil
+	})
+	if err := handler.ConsumeClaim(s, c); nil != err {
+		t.Error(err)
+	}
+	if !found {
+		t.Error("transaction not added to context")
+	}
+	if 1 != len(s.marked) || msg != s.marked[0] {
+		t.Error(s.marked)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "OtherTransaction/Go/produce",
+				"guid":     internal.MatchAnything,
+				"traceId":  internal.MatchAnything,
+				"priority": internal.MatchAnything,
+				"sampled":  internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":                     "OtherTransaction/Go/Message/Kafka/Topic/Named/orders",
+				"guid":                     internal.MatchAnything,
+				"traceId":                  internal.MatchAnything,
+				"priority":                 internal.MatchAnything,
+				"sampled":                  internal.MatchAnything,
+				"parent.type":              "App",
+				"parent.account":           "123",
+				"parent.app":               "456",
+				"parent.transportType":     "Kafka",
+				"parent.transportDuration": internal.MatchAnything,
+				"parentId":                 internal.MatchAnything,
+				"parentSpanId":             internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributePartition: 3,
+				AttributeOffset:    7,
+			},
+			AgentAttributes: map[string]interface{}{
+				"message.routingKey": "order-42",
+			},
+		},
+	})
+}
+
+func TestConsumerGroupHandlerError(t *testing.T) {
+	app := testApp()
+	c := claim{messages: make(chan *sarama.ConsumerMessage, 2)}
+	c.messages <- &sarama.ConsumerMessage{Topic: "orders"}
+	c.messages <- &sarama.ConsumerMessage{Topic: "orders"}
+	close(c.messages)
+	s := &session{}
+	calls := 0
+	handler := NewConsumerGroupHandler(app.Application, func(ctx context.Context, msg *sarama.ConsumerMessage) error {
+		calls++
+		return errProcess
+	})
+	if err := handler.ConsumeClaim(s, c); errProcess != err {
+		t.Error(err)
+	}
+	if 1 != calls || 0 != len(s.marked) {
+		t.Error(calls, s.marked)
+	}
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "*errors.errorString",
+			"error.message":   errProcess.Error(),
+			"transactionName": "OtherTransaction/Go/Message/Kafka/Topic/Named/orders",
+			"guid":            internal.MatchAnything,
+			"traceId":         internal.MatchAnything,
+			"priority":        internal.MatchAnything,
+			"sampled":         internal.MatchAnything,
+			"spanId":          internal.MatchAnything,
+		},
+	}})
+}
@@ -0,0 +1,32 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrslog_test
+
+import (
+	"context"
+	"log/slog"
+	"os"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrslog"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func Example() {
+	app, _ := newrelic.NewApplication(
+		newrelic.ConfigAppName("Example App"),
+		newrelic.ConfigLicense("__YOUR_NEWRELIC_LICENSE_KEY__"),
+		newrelic.ConfigDistributedTracerEnabled(true),
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+
+	// Wrap your handler using nrslog:
+	logger := nrslog.New(app, slog.NewJSONHandler(os.Stdout, nil))
+
+	txn := app.StartTransaction("example")
+	defer txn.End()
+
+	// Log with the transaction's context to add trace.id and span.id:
+	ctx := newrelic.NewContext(context.Background(), txn)
+	logger.InfoContext(ctx, "Hello New Relic!")
+}
@@ -0,0 +1,7 @@
+module github.com/newrelic/go-agent/v3/integrations/nrslog
+
+// log/slog was added in Go 1.21.
+go 1.21
+
+// v3.13.0 is required for Application.RecordLog.
+require github.com/newrelic/go-agent/v3 v3.13.0
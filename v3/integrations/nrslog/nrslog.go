@@ -0,0 +1,145 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrslog supports https://pkg.go.dev/log/slog
+//
+// Wrap your slog.Handler using nrslog.WrapHandler to decorate its records
+// with the trace.id and span.id of the transaction found in the record's
+// context, and to forward the records to New Relic:
+//
+//	logger := slog.New(nrslog.WrapHandler(app, slog.NewJSONHandler(os.Stdout, nil)))
+//
+// Records are decorated only when they are logged with a context holding a
+// transaction and Distributed Tracing is enabled:
+//
+//	ctx := newrelic.NewContext(context.Background(), txn)
+//	logger.InfoContext(ctx, "Hello New Relic!")
+//
+// Records are forwarded using Application.RecordLog, which only sends them
+// when Config.ApplicationLogging.Forwarding.Enabled is set.  Pass a nil
+// Application to decorate records without forwarding them.  Records which
+// are not enabled by the wrapped handler are neither written nor forwarded.
+package nrslog
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/newrelic/go-agent/v3/integrations/logcontext"
+	"github.com/newrelic/go-agent/v3/internal"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "logcontext", "slog") }
+
+// groupOrAttrs is a group name or attributes added to the Handler using
+// WithGroup or WithAttrs.
+type groupOrAttrs struct {
+	group string
+	attrs []slog.Attr
+}
+
+// Handler is a slog.Handler which decorates records with the trace.id and
+// span.id of the current transaction and forwards them to New Relic.
+// Create a Handler using WrapHandler.
+type Handler struct {
+	app *newrelic.Application
+	// base is the wrapped handler and handler is base with goas applied.
+	// base is kept so that the trace.id and span.id attributes may be
+	// added outside of any groups.
+	base     slog.Handler
+	handler  slog.Handler
+	goas     []groupOrAttrs
+	hasGroup bool
+}
+
+// WrapHandler returns a Handler which writes records using handler.  The
+// Application may be nil, in which case records are decorated but not
+// forwarded.
+func WrapHandler(app *newrelic.Application, handler slog.Handler) *Handler {
+	return &Handler{
+		app:     app,
+		base:    handler,
+		handler: handler,
+	}
+}
+
+// New returns a slog.Logger which writes records using the handler wrapped
+// by WrapHandler.
+func New(app *newrelic.Application, handler slog.Handler) *slog.Logger {
+	return slog.New(WrapHandler(app, handler))
+}
+
+// Enabled implements slog.Handler.
+func (h *Handler) Enabled(ctx context.Context, level slog.Level) bool {
+	return h.handler.Enabled(ctx, level)
+}
+
+// Handle implements slog.Handler.
+func (h *Handler) Handle(ctx context.Context, r slog.Record) error {
+	handler := h.handler
+	if txn := newrelic.FromContext(ctx); nil != txn {
+		if attrs := linkingAttrs(txn.GetTraceMetadata()); nil != attrs {
+			if h.hasGroup {
+				handler = h.base.WithAttrs(attrs)
+				for _, goa := range h.goas {
+					handler = goa.apply(handler)
+				}
+			} else {
+				r = r.Clone()
+				r.AddAttrs(attrs...)
+			}
+		}
+	}
+	h.app.RecordLog(newrelic.LogData{
+		Timestamp: r.Time.UnixNano() / int64(1000*1000),
+		Severity:  r.Level.String(),
+		Message:   r.Message,
+		Context:   ctx,
+	})
+	return handler.Handle(ctx, r)
+}
+
+// WithAttrs implements slog.Handler.
+func (h *Handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	if 0 == len(attrs) {
+		return h
+	}
+	return h.with(groupOrAttrs{attrs: attrs})
+}
+
+// WithGroup implements slog.Handler.
+func (h *Handler) WithGroup(name string) slog.Handler {
+	if "" == name {
+		return h
+	}
+	return h.with(groupOrAttrs{group: name})
+}
+
+func (h *Handler) with(goa groupOrAttrs) *Handler {
+	h2 := *h
+	h2.goas = make([]groupOrAttrs, len(h.goas), len(h.goas)+1)
+	copy(h2.goas, h.goas)
+	h2.goas = append(h2.goas, goa)
+	h2.handler = goa.apply(h.handler)
+	h2.hasGroup = h.hasGroup || "" != goa.group
+	return &h2
+}
+
+func (goa groupOrAttrs) apply(handler slog.Handler) slog.Handler {
+	if "" != goa.group {
+		return handler.WithGroup(goa.group)
+	}
+	return handler.WithAttrs(goa.attrs)
+}
+
+func linkingAttrs(md newrelic.TraceMetadata) []slog.Attr {
+	if "" == md.TraceID {
+		return nil
+	}
+	attrs := []slog.Attr{slog.String(logcontext.KeyTraceID, md.TraceID)}
+	if "" != md.SpanID {
+		attrs = append(attrs, slog.String(logcontext.KeySpanID, md.SpanID))
+	}
+	return attrs
+}
@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrslog
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+var replyFn = func(reply *internal.ConnectReply) {
+	reply.SetSampleEverything()
+	reply.AccountID = "123"
+	reply.TrustedAccountKey = "123"
+	reply.PrimaryAppID = "456"
+}
+
+func newTestApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(replyFn,
+		integrationsupport.DTEnabledCfgFn,
+		newrelic.ConfigAppLogForwardingEnabled(true),
+	)
+}
+
+func decodeLines(t *testing.T, buf *bytes.Buffer) []map[string]interface{} {
+	var lines []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	for dec.More() {
+		var line map[string]interface{}
+		if err := dec.Decode(&line); nil != err {
+			t.Fatal(err)
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func TestHandlerDecoration(t *testing.T) {
+	app := newTestApp()
+	buf := &bytes.Buffer{}
+	logger := New(nil, slog.NewJSONHandler(buf, nil))
+
+	txn := app.StartTransaction("hello")
+	ctx := newrelic.NewContext(context.Background(), txn)
+	md := txn.GetTraceMetadata()
+
+	logger.Info("no context")
+	logger.InfoContext(ctx, "context")
+	logger.With("user", "me").WithGroup("request").InfoContext(ctx, "group", "path", "/")
+	txn.End()
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 3 {
+		t.Fatal(len(lines))
+	}
+	if _, ok := lines[0]["trace.id"]; ok {
+		t.Error(lines[0])
+	}
+	for _, line := range lines[1:] {
+		if line["trace.id"] != md.TraceID || line["span.id"] != md.SpanID {
+			t.Error(line)
+		}
+	}
+	if lines[2]["user"] != "me" {
+		t.Error(lines[2])
+	}
+	if group, ok := lines[2]["request"].(map[string]interface{}); !ok || group["path"] != "/" {
+		t.Error(lines[2])
+	}
+}
+
+func TestHandlerDistributedTracingDisabled(t *testing.T) {
+	app := integrationsupport.NewBasicTestApp()
+	buf := &bytes.Buffer{}
+	logger := New(nil, slog.NewJSONHandler(buf, nil))
+
+	txn := app.StartTransaction("hello")
+	logger.InfoContext(newrelic.NewContext(context.Background(), txn), "hello")
+	txn.End()
+
+	lines := decodeLines(t, buf)
+	if len(lines) != 1 {
+		t.Fatal(len(lines))
+	}
+	if _, ok := lines[0]["trace.id"]; ok {
+		t.Error(lines[0])
+	}
+}
+
+func TestHandlerForwarding(t *testing.T) {
+	app := newTestApp()
+	buf := &bytes.Buffer{}
+	logger := New(app.Application, slog.NewJSONHandler(buf, nil))
+
+	txn := app.StartTransaction("hello")
+	logger.InfoContext(newrelic.NewContext(context.Background(), txn), "inside")
+	txn.End()
+	logger.Warn("outside")
+	logger.Debug("disabled")
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Logging/lines", Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "Logging/lines/INFO", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "Logging/lines/WARN", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
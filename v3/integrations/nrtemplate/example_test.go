@@ -0,0 +1,25 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrtemplate_test
+
+import (
+	"html/template"
+	"net/http"
+
+	"github.com/newrelic/go-agent/v3/integrations/nrtemplate"
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+var usersTemplate = template.Must(template.New("users").Parse(
+	`<ul>{{range .}}<li>{{.}}</li>{{end}}</ul>`))
+
+func ExampleExecute() {
+	http.HandleFunc("/users", func(w http.ResponseWriter, r *http.Request) {
+		txn := newrelic.FromContext(r.Context())
+		users := []string{"alice", "bob"}
+		if err := nrtemplate.Execute(txn, usersTemplate, w, users); nil != err {
+			txn.NoticeError(err)
+		}
+	})
+}
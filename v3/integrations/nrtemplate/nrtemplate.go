@@ -0,0 +1,155 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package nrtemplate instruments template rendering with segments.
+//
+// Use this package to time calls to Execute and ExecuteTemplate on
+// html/template (https://golang.org/pkg/html/template/) and text/template
+// (https://golang.org/pkg/text/template/) templates.  Instead of:
+//
+//	err := tmpl.Execute(w, data)
+//
+// Use:
+//
+//	err := nrtemplate.Execute(txn, tmpl, w, data)
+//
+// Each call creates a segment named "template/<name>" which has the
+// following attributes:
+//
+//	template.name    the name of the template executed
+//	template.size    the number of bytes written
+//	template.nested  the comma separated names of the templates invoked by
+//	                 the template using {{template}} or {{block}} actions
+//
+// The time spent rendering nested templates is included in the segment of
+// the template which invokes them.  Use ExecuteTemplate within your own
+// template functions to time nested templates separately.
+package nrtemplate
+
+import (
+	htmltemplate "html/template"
+	"io"
+	"sort"
+	"strings"
+	texttemplate "text/template"
+	"text/template/parse"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func init() { internal.TrackUsage("integration", "template") }
+
+const (
+	// AttributeTemplateName is the name of the template executed.
+	AttributeTemplateName = "template.name"
+	// AttributeTemplateSize is the number of bytes written by the
+	// template.
+	AttributeTemplateSize = "template.size"
+	// AttributeTemplateNested is the comma separated names of the templates
+	// invoked by the template executed.
+	AttributeTemplateNested = "template.nested"
+)
+
+// Template is implemented by *html/template.Template and
+// *text/template.Template.
+type Template interface {
+	Name() string
+	ExecuteTemplate(wr io.Writer, name string, data interface{}) error
+}
+
+// Execute applies the template to the data object, writing the output to
+// wr, inside a segment.  The Transaction may be nil, in which case the
+// template is executed without creating a segment.
+func Execute(txn *newrelic.Transaction, t Template, wr io.Writer, data interface{}) error {
+	return ExecuteTemplate(txn, t, wr, t.Name(), data)
+}
+
+// ExecuteTemplate applies the template associated with t that has the given
+// name to the data object, writing the output to wr, inside a segment.  The
+// Transaction may be nil, in which case the template is executed without
+// creating a segment.
+func ExecuteTemplate(txn *newrelic.Transaction, t Template, wr io.Writer, name string, data interface{}) error {
+	if nil == txn {
+		return t.ExecuteTemplate(wr, name, data)
+	}
+	seg := txn.StartSegment("template/" + name)
+	cw := &countingWriter{w: wr}
+	err := t.ExecuteTemplate(cw, name, data)
+	seg.AddAttribute(AttributeTemplateName, name)
+	seg.AddAttribute(AttributeTemplateSize, cw.n)
+	if nested := nestedTemplates(t, name); len(nested) > 0 {
+		seg.AddAttribute(AttributeTemplateNested, strings.Join(nested, ","))
+	}
+	seg.End()
+	return err
+}
+
+type countingWriter struct {
+	w io.Writer
+	n int
+}
+
+func (cw *countingWriter) Write(p []byte) (int, error) {
+	n, err := cw.w.Write(p)
+	cw.n += n
+	return n, err
+}
+
+// lookupTree returns the parse tree of the template associated with t that
+// has the given name, or nil if it cannot be found.
+func lookupTree(t Template, name string) *parse.Tree {
+	switch tmpl := t.(type) {
+	case *htmltemplate.Template:
+		if found := tmpl.Lookup(name); nil != found {
+			return found.Tree
+		}
+	case *texttemplate.Template:
+		if found := tmpl.Lookup(name); nil != found {
+			return found.Tree
+		}
+	}
+	return nil
+}
+
+// nestedTemplates returns the sorted names of the templates invoked, directly
+// or indirectly, by the template with the given name.
+func nestedTemplates(t Template, name string) []string {
+	seen := map[string]bool{name: true}
+	var names []string
+	var walk func(node parse.Node)
+	walk = func(node parse.Node) {
+		switch n := node.(type) {
+		case *parse.ListNode:
+			if nil == n {
+				return
+			}
+			for _, child := range n.Nodes {
+				walk(child)
+			}
+		case *parse.IfNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.RangeNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.WithNode:
+			walk(n.List)
+			walk(n.ElseList)
+		case *parse.TemplateNode:
+			if seen[n.Name] {
+				return
+			}
+			seen[n.Name] = true
+			names = append(names, n.Name)
+			if tree := lookupTree(t, n.Name); nil != tree {
+				walk(tree.Root)
+			}
+		}
+	}
+	if tree := lookupTree(t, name); nil != tree {
+		walk(tree.Root)
+	}
+	sort.Strings(names)
+	return names
+}
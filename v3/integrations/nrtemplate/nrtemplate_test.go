@@ -0,0 +1,111 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package nrtemplate
+
+import (
+	"bytes"
+	htmltemplate "html/template"
+	"reflect"
+	"testing"
+	texttemplate "text/template"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+)
+
+const page = `{{define "page"}}<h1>{{template "header" .Title}}</h1>{{template "body" .Items}}{{end}}` +
+	`{{define "header"}}{{.}}{{end}}` +
+	`{{define "body"}}{{if .}}{{range .}}{{template "item" .}}{{end}}{{end}}{{end}}` +
+	`{{define "item"}}{{.}}{{end}}`
+
+type pageData struct {
+	Title string
+	Items []string
+}
+
+func TestExecuteTemplateHTML(t *testing.T) {
+	tmpl := htmltemplate.Must(htmltemplate.New("root").Parse(page))
+	app := integrationsupport.NewTestApp(integrationsupport.SampleEverythingReplyFn, integrationsupport.DTEnabledCfgFn)
+	txn := app.StartTransaction("hello")
+	var buf bytes.Buffer
+	if err := ExecuteTemplate(txn, tmpl, &buf, "page", pageData{Title: "hi", Items: []string{"a", "b"}}); nil != err {
+		t.Fatal(err)
+	}
+	txn.End()
+	if out := buf.String(); out != "<h1>hi</h1>ab" {
+		t.Error(out)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/template/page", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/template/page",
+				"category": "generic",
+				"parentId": internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				AttributeTemplateName:   "page",
+				AttributeTemplateSize:   13,
+				AttributeTemplateNested: "body,header,item",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+				"transaction.name": "OtherTransaction/Go/hello",
+			},
+		},
+	})
+}
+
+func TestExecuteText(t *testing.T) {
+	tmpl := texttemplate.Must(texttemplate.New("greeting").Parse("hello {{.}}"))
+	app := integrationsupport.NewBasicTestApp()
+	txn := app.StartTransaction("hello")
+	var buf bytes.Buffer
+	if err := Execute(txn, tmpl, &buf, "world"); nil != err {
+		t.Fatal(err)
+	}
+	txn.End()
+	if out := buf.String(); out != "hello world" {
+		t.Error(out)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/template/greeting", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
+
+func TestExecuteNilTransaction(t *testing.T) {
+	tmpl := texttemplate.Must(texttemplate.New("greeting").Parse("hello {{.}}"))
+	var buf bytes.Buffer
+	if err := Execute(nil, tmpl, &buf, "world"); nil != err {
+		t.Fatal(err)
+	}
+	if out := buf.String(); out != "hello world" {
+		t.Error(out)
+	}
+}
+
+func TestNestedTemplates(t *testing.T) {
+	tmpl := texttemplate.Must(texttemplate.New("root").Parse(page +
+		`{{define "recursive"}}{{template "recursive" .}}{{end}}` +
+		`{{define "missing"}}{{template "undefined" .}}{{end}}`))
+	testcases := map[string][]string{
+		"page":      {"body", "header", "item"},
+		"body":      {"item"},
+		"item":      nil,
+		"recursive": nil,
+		"missing":   {"undefined"},
+		"undefined": nil,
+	}
+	for name, expect := range testcases {
+		if nested := nestedTemplates(tmpl, name); !reflect.DeepEqual(nested, expect) {
+			t.Error(name, nested, expect)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package awssupport
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// maxMessageAttributes is the number of message attributes SQS and SNS
+// allow on each message.
+const maxMessageAttributes = 10
+
+// DistributedTraceAttributes returns the SQS or SNS message attributes, by
+// name, which carry the distributed tracing payload of the transaction.
+// The names are the lowercase distributed tracing header names.  Nil is
+// returned if there is no payload, or if the attributes would not fit
+// alongside the existing attributes of the message.
+func DistributedTraceAttributes(txn *newrelic.Transaction, existing []string) map[string]string {
+	if nil == txn {
+		return nil
+	}
+	hdrs := http.Header{}
+	txn.InsertDistributedTraceHeaders(hdrs)
+	if 0 == len(hdrs) {
+		return nil
+	}
+	attrs := make(map[string]string, len(hdrs))
+	for key := range hdrs {
+		attrs[strings.ToLower(key)] = hdrs.Get(key)
+	}
+	count := len(attrs)
+	for _, name := range existing {
+		if _, ok := attrs[name]; !ok {
+			count++
+		}
+	}
+	if count > maxMessageAttributes {
+		return nil
+	}
+	return attrs
+}
+
+// snsNotification is the body of an SQS message delivered by an SNS
+// subscription without raw message delivery.
+type snsNotification struct {
+	Type              string `json:"Type"`
+	MessageAttributes map[string]struct {
+		Type  string `json:"Type"`
+		Value string `json:"Value"`
+	} `json:"MessageAttributes"`
+}
+
+// snsNotificationAttributes returns the string message attributes of the
+// SNS notification in the body, or nil if the body is not a notification.
+func snsNotificationAttributes(body string) map[string]string {
+	if !strings.HasPrefix(strings.TrimSpace(body), "{") {
+		return nil
+	}
+	var n snsNotification
+	if err := json.Unmarshal([]byte(body), &n); nil != err || "Notification" != n.Type {
+		return nil
+	}
+	attrs := make(map[string]string, len(n.MessageAttributes))
+	for name, attr := range n.MessageAttributes {
+		if "String" == attr.Type {
+			attrs[name] = attr.Value
+		}
+	}
+	return attrs
+}
+
+// queueName returns the name of the SQS queue from its URL, eg.
+// https://sqs.us-east-1.amazonaws.com/123456789012/my-queue.
+func queueName(queueURL string) string {
+	queueURL = strings.TrimSuffix(queueURL, "/")
+	return queueURL[strings.LastIndex(queueURL, "/")+1:]
+}
+
+// StartSQSTransaction starts a transaction for a message received from the
+// SQS queue.  The transaction accepts the distributed tracing payload
+// found in the string message attributes, or if there is none, in the
+// message attributes of the SNS notification in the body.
+func StartSQSTransaction(app *newrelic.Application, queueURL string, attrs map[string]string, body string) *newrelic.Transaction {
+	if nil == app {
+		return nil
+	}
+	name := queueName(queueURL)
+	namer := internal.MessageMetricKey{
+		Library:         "SQS",
+		DestinationType: string(newrelic.MessageQueue),
+		DestinationName: name,
+		Consumer:        true,
+	}
+	txn := app.StartTransaction(namer.Name())
+
+	hdrs := http.Header{}
+	for key, value := range attrs {
+		hdrs.Set(key, value)
+	}
+	if "" == hdrs.Get(newrelic.DistributedTraceNewRelicHeader) &&
+		"" == hdrs.Get(newrelic.DistributedTraceW3CTraceParentHeader) {
+		for key, value := range snsNotificationAttributes(body) {
+			hdrs.Set(key, value)
+		}
+	}
+	txn.AcceptDistributedTraceHeaders(newrelic.TransportQueue, hdrs)
+	integrationsupport.AddAgentAttribute(txn, newrelic.AttributeMessageQueueName, name, nil)
+	return txn
+}
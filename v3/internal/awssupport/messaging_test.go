@@ -0,0 +1,162 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package awssupport
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/integrationsupport"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+func dtTestApp() integrationsupport.ExpectApp {
+	return integrationsupport.NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, integrationsupport.DTEnabledCfgFn)
+}
+
+func TestDistributedTraceAttributes(t *testing.T) {
+	app := dtTestApp()
+	txn := app.StartTransaction("produce")
+	defer txn.End()
+
+	attrs := DistributedTraceAttributes(txn, []string{"user", "traceparent"})
+	if "" == attrs["newrelic"] || "" == attrs["traceparent"] || "" == attrs["tracestate"] {
+		t.Error(attrs)
+	}
+	full := []string{"a", "b", "c", "d", "e", "f", "g", "h"}
+	if attrs := DistributedTraceAttributes(txn, full); nil != attrs {
+		t.Error(attrs)
+	}
+	if attrs := DistributedTraceAttributes(nil, nil); nil != attrs {
+		t.Error(attrs)
+	}
+}
+
+func TestSNSNotificationAttributes(t *testing.T) {
+	testcases := []struct {
+		body   string
+		expect map[string]string
+	}{
+		{body: "plain text"},
+		{body: `{"order": 42}`},
+		{body: `{"Type":"Notification"`},
+		{
+			body: `{"Type":"Notification","Message":"hi","MessageAttributes":{` +
+				`"traceparent":{"Type":"String","Value":"00-abc"},` +
+				`"image":{"Type":"Binary","Value":"aGk="}}}`,
+			expect: map[string]string{"traceparent": "00-abc"},
+		},
+	}
+	for _, tc := range testcases {
+		attrs := snsNotificationAttributes(tc.body)
+		if len(attrs) != len(tc.expect) {
+			t.Error(tc.body, attrs)
+		}
+		for name, value := range tc.expect {
+			if attrs[name] != value {
+				t.Error(tc.body, attrs)
+			}
+		}
+	}
+}
+
+func TestQueueName(t *testing.T) {
+	for url, name := range map[string]string{
+		"https://sqs.us-east-1.amazonaws.com/123456789012/my-queue":  "my-queue",
+		"https://sqs.us-east-1.amazonaws.com/123456789012/my-queue/": "my-queue",
+		"my-queue": "my-queue",
+	} {
+		if n := queueName(url); n != name {
+			t.Error(url, n)
+		}
+	}
+}
+
+func sqsConsumeEvent(parent bool) internal.WantEvent {
+	intrinsics := map[string]interface{}{
+		"name":     "OtherTransaction/Go/Message/SQS/Queue/Named/my-queue",
+		"guid":     internal.MatchAnything,
+		"traceId":  internal.MatchAnything,
+		"priority": internal.MatchAnything,
+		"sampled":  internal.MatchAnything,
+	}
+	if parent {
+		intrinsics["parent.type"] = "App"
+		intrinsics["parent.account"] = "123"
+		intrinsics["parent.app"] = "456"
+		intrinsics["parent.transportType"] = "Queue"
+		intrinsics["parent.transportDuration"] = internal.MatchAnything
+		intrinsics["parentId"] = internal.MatchAnything
+		intrinsics["parentSpanId"] = internal.MatchAnything
+	}
+	return internal.WantEvent{Intrinsics: intrinsics}
+}
+
+func produceEvent() internal.WantEvent {
+	return internal.WantEvent{Intrinsics: map[string]interface{}{
+		"name":     "OtherTransaction/Go/produce",
+		"guid":     internal.MatchAnything,
+		"traceId":  internal.MatchAnything,
+		"priority": internal.MatchAnything,
+		"sampled":  internal.MatchAnything,
+	}}
+}
+
+const testQueueURL = "https://sqs.us-east-1.amazonaws.com/123456789012/my-queue"
+
+func TestStartSQSTransaction(t *testing.T) {
+	app := dtTestApp()
+	txn := app.StartTransaction("produce")
+	attrs := DistributedTraceAttributes(txn, nil)
+	txn.End()
+
+	StartSQSTransaction(app.Application, testQueueURL, attrs, "body").End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{produceEvent(), sqsConsumeEvent(true)})
+}
+
+func TestStartSQSTransactionFromSNS(t *testing.T) {
+	app := dtTestApp()
+	txn := app.StartTransaction("produce")
+	attrs := DistributedTraceAttributes(txn, nil)
+	txn.End()
+
+	type attr struct {
+		Type  string
+		Value string
+	}
+	notification := map[string]interface{}{
+		"Type":              "Notification",
+		"Message":           "order 42",
+		"MessageAttributes": map[string]attr{},
+	}
+	for name, value := range attrs {
+		notification["MessageAttributes"].(map[string]attr)[name] = attr{Type: "String", Value: value}
+	}
+	body, _ := json.Marshal(notification)
+
+	StartSQSTransaction(app.Application, testQueueURL, nil, string(body)).End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{produceEvent(), sqsConsumeEvent(true)})
+}
+
+func TestStartSQSTransactionNoPayload(t *testing.T) {
+	app := dtTestApp()
+	StartSQSTransaction(app.Application, testQueueURL, map[string]string{"user": "1"}, "body").End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{sqsConsumeEvent(false)})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/Message/SQS/Queue/Named/my-queue", Scope: "", Forced: true, Data: nil},
+	})
+
+	var nilApp *newrelic.Application
+	if txn := StartSQSTransaction(nilApp, testQueueURL, nil, ""); nil != txn {
+		t.Error(txn)
+	}
+}
@@ -120,6 +120,7 @@ type EventHarvestConfig struct {
 		CustomEvents *uint `json:"custom_event_data,omitempty"`
 		ErrorEvents  *uint `json:"error_event_data,omitempty"`
 		SpanEvents   *uint `json:"span_event_data,omitempty"`
+		LogEvents    *uint `json:"log_event_data,omitempty"`
 	} `json:"harvest_limits"`
 }
 
@@ -100,3 +100,25 @@ func TestConcurrentCalls(t *testing.T) {
 	go addAttr()
 	wg.Wait()
 }
+
+func TestKafkaDistributedTraceHeaders(t *testing.T) {
+	app := NewTestApp(func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+		reply.AccountID = "123"
+		reply.TrustedAccountKey = "123"
+		reply.PrimaryAppID = "456"
+	}, DTEnabledCfgFn)
+	txn := app.StartTransaction("produce")
+	defer txn.End()
+
+	hdrs := KafkaDistributedTraceHeaders(txn)
+	if 0 == len(hdrs["traceparent"]) || 0 == len(hdrs["newrelic"]) {
+		t.Error(hdrs)
+	}
+	if !IsKafkaDistributedTraceHeader(hdrs, "Traceparent") || IsKafkaDistributedTraceHeader(hdrs, "orderId") {
+		t.Error(hdrs)
+	}
+	if hdrs := KafkaDistributedTraceHeaders(nil); 0 != len(hdrs) {
+		t.Error(hdrs)
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// KafkaLibrary is the library of the segments and transactions of Kafka
+// integrations.
+const KafkaLibrary = "Kafka"
+
+// These attributes are added to the transactions created by
+// StartKafkaConsumeTransaction.
+const (
+	AttributeKafkaPartition = "kafka.partition"
+	AttributeKafkaOffset    = "kafka.offset"
+)
+
+// KafkaRecord is a Kafka record consumed by a transaction started using
+// StartKafkaConsumeTransaction.
+type KafkaRecord struct {
+	Topic     string
+	Key       []byte
+	Partition int32
+	Offset    int64
+	Headers   http.Header
+}
+
+// StartKafkaConsumeTransaction starts a transaction for consuming the
+// record.  The transaction is named after the record's topic and accepts the
+// distributed tracing headers found in its headers.  The Application may be
+// nil, in which case nil is returned.
+func StartKafkaConsumeTransaction(app *newrelic.Application, r KafkaRecord) *newrelic.Transaction {
+	if nil == app {
+		return nil
+	}
+	namer := internal.MessageMetricKey{
+		Library:         KafkaLibrary,
+		DestinationType: string(newrelic.MessageTopic),
+		DestinationName: r.Topic,
+		Consumer:        true,
+	}
+	txn := app.StartTransaction(namer.Name())
+	txn.AcceptDistributedTraceHeaders(newrelic.TransportKafka, r.Headers)
+
+	if len(r.Key) > 0 {
+		AddAgentAttribute(txn, newrelic.AttributeMessageRoutingKey, string(r.Key), nil)
+	}
+	txn.AddAttribute(AttributeKafkaPartition, r.Partition)
+	txn.AddAttribute(AttributeKafkaOffset, r.Offset)
+	return txn
+}
+
+// KafkaDistributedTraceHeaders returns the distributed tracing headers of
+// the Transaction to add to a record.  Their keys are in lower case, as
+// record headers are conventionally named; use IsKafkaDistributedTraceHeader
+// to find the headers of the record which they replace.
+func KafkaDistributedTraceHeaders(txn *newrelic.Transaction) http.Header {
+	hdrs := http.Header{}
+	txn.InsertDistributedTraceHeaders(hdrs)
+	lower := make(http.Header, len(hdrs))
+	for key, values := range hdrs {
+		lower[strings.ToLower(key)] = values
+	}
+	return lower
+}
+
+// IsKafkaDistributedTraceHeader returns whether the record header key is one
+// of the headers returned by KafkaDistributedTraceHeaders.
+func IsKafkaDistributedTraceHeader(hdrs http.Header, key string) bool {
+	_, ok := hdrs[strings.ToLower(key)]
+	return ok
+}
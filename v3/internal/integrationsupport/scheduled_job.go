@@ -0,0 +1,46 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package integrationsupport
+
+import (
+	"context"
+	"time"
+
+	newrelic "github.com/newrelic/go-agent/v3/newrelic"
+)
+
+// These attributes are added to the transactions of scheduled jobs.
+const (
+	AttributeJobSchedule = "job.schedule"
+	AttributeJobDuration = "job.duration"
+	AttributeJobOverlap  = "job.overlap"
+	AttributeJobSkipped  = "job.skipped"
+)
+
+// RunScheduledJob records a run of a scheduled job as a background
+// transaction with the job.schedule, job.overlap, and job.duration
+// attributes.  The transaction is added to the context passed to fn.  The
+// Application may be nil, in which case fn is still called.
+func RunScheduledJob(app *newrelic.Application, name string, schedule string, overlap bool, fn func(ctx context.Context)) {
+	txn := app.StartTransaction(name)
+	defer txn.End()
+	txn.AddAttribute(AttributeJobSchedule, schedule)
+	txn.AddAttribute(AttributeJobOverlap, overlap)
+
+	start := time.Now()
+	defer func() {
+		txn.AddAttribute(AttributeJobDuration, time.Since(start).Seconds())
+	}()
+	fn(newrelic.NewContext(context.Background(), txn))
+}
+
+// SkipScheduledJob records a run of a scheduled job which was skipped
+// because the previous run was still in progress.
+func SkipScheduledJob(app *newrelic.Application, name string, schedule string) {
+	txn := app.StartTransaction(name)
+	defer txn.End()
+	txn.AddAttribute(AttributeJobSchedule, schedule)
+	txn.AddAttribute(AttributeJobOverlap, true)
+	txn.AddAttribute(AttributeJobSkipped, true)
+}
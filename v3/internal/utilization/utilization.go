@@ -10,7 +10,9 @@ import (
 	"net/http"
 	"os"
 	"runtime"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/newrelic/go-agent/v3/internal/logger"
 	"github.com/newrelic/go-agent/v3/internal/sysinfo"
@@ -32,6 +34,16 @@ type Config struct {
 	TotalRAMMIB       int
 	BillingHostname   string
 	Hostname          string
+
+	// ProviderTimeout is the maximum time each request to a cloud
+	// provider's metadata endpoint may take.  If zero, the spec's 500
+	// millisecond timeout is used.
+	ProviderTimeout time.Duration
+	// ProviderRetries is the number of times the detection of a cloud
+	// provider is retried after an unexpected response, such as an error
+	// status code.  Requests which time out or are refused, as happens
+	// when not running on the provider, are not retried.
+	ProviderRetries int
 }
 
 type override struct {
@@ -111,36 +123,103 @@ func overrideFromConfig(config Config) *override {
 
 // Gather gathers system utilization data.
 func Gather(config Config, lg logger.Logger) *Data {
+	timeout := config.ProviderTimeout
+	if timeout <= 0 {
+		timeout = providerTimeout
+	}
 	client := &http.Client{
-		Timeout: providerTimeout,
+		Timeout: timeout,
 	}
 	return gatherWithClient(config, lg, client)
 }
 
-func gatherWithClient(config Config, lg logger.Logger, client *http.Client) *Data {
-	var wg sync.WaitGroup
+// GatherLocal gathers the system utilization data which does not require
+// network requests.  The cloud provider vendors and the full hostname are
+// omitted.
+func GatherLocal(config Config, lg logger.Logger) *Data {
+	uDat := newData(lg)
+	gatherLocal(config, lg, uDat)
+	return finishData(config, uDat)
+}
+
+func warnGatherError(lg logger.Logger, datatype string, err error) {
+	lg.Debug("error gathering utilization data", map[string]interface{}{
+		"error":    err.Error(),
+		"datatype": datatype,
+	})
+}
 
+// newData creates the data with the number of processors and the IPs.
+func newData(lg logger.Logger) *Data {
 	cpu := runtime.NumCPU()
 	uDat := &Data{
 		MetadataVersion:   metadataVersion,
 		LogicalProcessors: &cpu,
 		Vendors:           &vendors{},
 	}
+	if ips, err := utilizationIPs(); nil == err {
+		uDat.Addresses = ips
+	} else {
+		warnGatherError(lg, "addresses", err)
+	}
+	return uDat
+}
+
+// gatherLocal does the non-network gathering, sequentially since it is
+// fast.
+func gatherLocal(config Config, lg logger.Logger, uDat *Data) {
+	if id, err := sysinfo.BootID(); err != nil {
+		if err != sysinfo.ErrFeatureUnsupported {
+			warnGatherError(lg, "bootid", err)
+		}
+	} else {
+		uDat.BootID = id
+	}
 
-	warnGatherError := func(datatype string, err error) {
-		lg.Debug("error gathering utilization data", map[string]interface{}{
-			"error":    err.Error(),
-			"datatype": datatype,
-		})
+	if config.DetectKubernetes {
+		gatherKubernetes(uDat.Vendors, os.Getenv)
 	}
 
-	// Gather IPs before spawning goroutines since the IPs are used in
-	// gathering full hostname.
-	if ips, err := utilizationIPs(); nil == err {
-		uDat.Addresses = ips
+	if config.DetectDocker {
+		if id, err := sysinfo.DockerID(); err != nil {
+			if err != sysinfo.ErrFeatureUnsupported &&
+				err != sysinfo.ErrDockerNotFound {
+				warnGatherError(lg, "docker", err)
+			}
+		} else {
+			uDat.Vendors.Docker = &docker{ID: id}
+		}
+	}
+
+	uDat.Hostname = config.Hostname
+
+	if bts, err := sysinfo.PhysicalMemoryBytes(); nil == err {
+		mib := sysinfo.BytesToMebibytes(bts)
+		uDat.RAMMiB = &mib
 	} else {
-		warnGatherError("addresses", err)
+		warnGatherError(lg, "memory", err)
 	}
+}
+
+// finishData applies the overrides of the config once gathering is done.
+func finishData(config Config, uDat *Data) *Data {
+	// Override whatever needs to be overridden.
+	uDat.Config = overrideFromConfig(config)
+
+	if uDat.Vendors.isEmpty() {
+		// Per spec, we MUST NOT send any vendors hash if it's empty.
+		uDat.Vendors = nil
+	}
+
+	return uDat
+}
+
+func gatherWithClient(config Config, lg logger.Logger, client *http.Client) *Data {
+	var wg sync.WaitGroup
+
+	// Gather IPs before spawning goroutines since the IPs are used in
+	// gathering full hostname.
+	uDat := newData(lg)
 
 	// This closure allows us to run each gather function in a separate goroutine
 	// and wait for them at the end by closing over the wg WaitGroup we
@@ -154,8 +233,12 @@ func gatherWithClient(config Config, lg logger.Logger, client *http.Client) *Dat
 			// Thus this code is fine as long as each routine is
 			// modifying a different field of util.
 			defer wg.Done()
-			if err := gather(uDat, client); err != nil {
-				warnGatherError(datatype, err)
+			err := gather(uDat, client)
+			for retry := 0; retry < config.ProviderRetries && nil != err; retry++ {
+				err = gather(uDat, client)
+			}
+			if err != nil {
+				warnGatherError(lg, datatype, err)
 			}
 		}()
 	}
@@ -184,52 +267,12 @@ func gatherWithClient(config Config, lg logger.Logger, client *http.Client) *Dat
 		uDat.FullHostname = getFQDN(uDat.Addresses)
 	}()
 
-	// Do non-network gathering sequentially since it is fast.
-
-	if id, err := sysinfo.BootID(); err != nil {
-		if err != sysinfo.ErrFeatureUnsupported {
-			warnGatherError("bootid", err)
-		}
-	} else {
-		uDat.BootID = id
-	}
-
-	if config.DetectKubernetes {
-		gatherKubernetes(uDat.Vendors, os.Getenv)
-	}
-
-	if config.DetectDocker {
-		if id, err := sysinfo.DockerID(); err != nil {
-			if err != sysinfo.ErrFeatureUnsupported &&
-				err != sysinfo.ErrDockerNotFound {
-				warnGatherError("docker", err)
-			}
-		} else {
-			uDat.Vendors.Docker = &docker{ID: id}
-		}
-	}
-
-	uDat.Hostname = config.Hostname
-
-	if bts, err := sysinfo.PhysicalMemoryBytes(); nil == err {
-		mib := sysinfo.BytesToMebibytes(bts)
-		uDat.RAMMiB = &mib
-	} else {
-		warnGatherError("memory", err)
-	}
+	gatherLocal(config, lg, uDat)
 
 	// Now we wait for everything!
 	wg.Wait()
 
-	// Override whatever needs to be overridden.
-	uDat.Config = overrideFromConfig(config)
-
-	if uDat.Vendors.isEmpty() {
-		// Per spec, we MUST NOT send any vendors hash if it's empty.
-		uDat.Vendors = nil
-	}
-
-	return uDat
+	return finishData(config, uDat)
 }
 
 func gatherKubernetes(v *vendors, getenv func(string) string) {
@@ -237,3 +280,29 @@ func gatherKubernetes(v *vendors, getenv func(string) string) {
 		v.Kubernetes = &kubernetes{Host: host}
 	}
 }
+
+// Region returns the cloud region in which the process is running, or the
+// empty string if no cloud provider was detected.
+func (d *Data) Region() string {
+	if nil == d || nil == d.Vendors {
+		return ""
+	}
+	switch {
+	case nil != d.Vendors.AWS && "" != d.Vendors.AWS.AvailabilityZone:
+		// AWS availability zones are the region followed by a
+		// single letter, eg. "us-east-1a".
+		az := d.Vendors.AWS.AvailabilityZone
+		return az[:len(az)-1]
+	case nil != d.Vendors.Azure:
+		return d.Vendors.Azure.Location
+	case nil != d.Vendors.GCP && "" != d.Vendors.GCP.Zone:
+		// GCP zones are the region followed by a suffix, eg.
+		// "us-central1-a".
+		zone := d.Vendors.GCP.Zone
+		if idx := strings.LastIndex(zone, "-"); idx > 0 {
+			return zone[:idx]
+		}
+		return zone
+	}
+	return ""
+}
@@ -7,7 +7,10 @@ import (
 	"bytes"
 	"encoding/json"
 	"errors"
+	"io/ioutil"
 	"net/http"
+	"strings"
+	"sync/atomic"
 	"testing"
 
 	"github.com/newrelic/go-agent/v3/internal/crossagent"
@@ -127,6 +130,44 @@ func TestUtilizationHash(t *testing.T) {
 	}
 }
 
+type countingRoundTripper struct{ count *int32 }
+
+func (c countingRoundTripper) RoundTrip(*http.Request) (*http.Response, error) {
+	atomic.AddInt32(c.count, 1)
+	return &http.Response{
+		StatusCode: http.StatusInternalServerError,
+		Body:       ioutil.NopCloser(strings.NewReader("")),
+	}, nil
+}
+
+func TestGatherProviderRetries(t *testing.T) {
+	var requests int32
+	client := &http.Client{
+		Transport: countingRoundTripper{count: &requests},
+	}
+	gatherWithClient(Config{DetectAzure: true, ProviderRetries: 2}, logger.ShimLogger{}, client)
+	if n := atomic.LoadInt32(&requests); 3 != n {
+		t.Error(n)
+	}
+}
+
+func TestGatherLocal(t *testing.T) {
+	data := GatherLocal(Config{
+		DetectAWS:         true,
+		Hostname:          "my-hostname",
+		LogicalProcessors: 4,
+	}, logger.ShimLogger{})
+	if "my-hostname" != data.Hostname || nil == data.LogicalProcessors || "" != data.FullHostname {
+		t.Error(data)
+	}
+	if nil != data.Vendors && nil != data.Vendors.AWS {
+		t.Error(data.Vendors)
+	}
+	if nil == data.Config || 4 != *data.Config.LogicalProcessors {
+		t.Error(data.Config)
+	}
+}
+
 func TestOverrideFromConfig(t *testing.T) {
 	testcases := []struct {
 		config Config
@@ -325,3 +366,27 @@ func TestVendorsIsEmpty(t *testing.T) {
 		t.Fatal("nil vendors should be empty")
 	}
 }
+
+func TestDataRegion(t *testing.T) {
+	testcases := []struct {
+		vendors *vendors
+		expect  string
+	}{
+		{vendors: nil, expect: ""},
+		{vendors: &vendors{}, expect: ""},
+		{vendors: &vendors{AWS: &aws{AvailabilityZone: "us-east-1a"}}, expect: "us-east-1"},
+		{vendors: &vendors{Azure: &azure{Location: "eastus"}}, expect: "eastus"},
+		{vendors: &vendors{GCP: &gcp{Zone: "us-central1-a"}}, expect: "us-central1"},
+		{vendors: &vendors{Kubernetes: &kubernetes{Host: "10.0.0.1"}}, expect: ""},
+	}
+	for _, tc := range testcases {
+		d := &Data{Vendors: tc.vendors}
+		if region := d.Region(); region != tc.expect {
+			t.Error(tc.vendors, region, tc.expect)
+		}
+	}
+	var nilData *Data
+	if region := nilData.Region(); region != "" {
+		t.Error(region)
+	}
+}
@@ -14,14 +14,19 @@ type adaptiveSampler struct {
 	period time.Duration
 	target uint64
 
+	// errorBudget is the number of transactions per period which may be
+	// sampled because they noticed an error.
+	errorBudget uint64
+
 	// Transactions with priority higher than this are sampled.
 	// This is 1 - sampleRatio.
 	priorityMin float32
 
 	currentPeriod struct {
-		numSampled uint64
-		numSeen    uint64
-		end        time.Time
+		numSampled      uint64
+		numSeen         uint64
+		numErrorSampled uint64
+		end             time.Time
 	}
 }
 
@@ -49,21 +54,7 @@ func (as *adaptiveSampler) computeSampled(priority float32, now time.Time) bool
 		return false
 	}
 
-	// If the current time is after the end of the "currentPeriod".  This is in
-	// a `for`/`while` loop in case there's a harvest where no sampling happened.
-	// i.e. for situations where a single call to
-	//    as.currentPeriod.end = as.currentPeriod.end.Add(as.period)
-	// might not catch us up to the current period
-	for now.After(as.currentPeriod.end) {
-		as.priorityMin = 0.0
-		if as.currentPeriod.numSeen > 0 {
-			sampledRatio := float32(as.target) / float32(as.currentPeriod.numSeen)
-			as.priorityMin = 1.0 - sampledRatio
-		}
-		as.currentPeriod.numSampled = 0
-		as.currentPeriod.numSeen = 0
-		as.currentPeriod.end = as.currentPeriod.end.Add(as.period)
-	}
+	as.advancePeriod(now)
 
 	as.currentPeriod.numSeen++
 
@@ -85,6 +76,56 @@ func (as *adaptiveSampler) computeSampled(priority float32, now time.Time) bool
 	return false
 }
 
+// advancePeriod resets the period counters if the current time is after the
+// end of the "currentPeriod".  This is in a `for`/`while` loop in case there's
+// a harvest where no sampling happened.  i.e. for situations where a single
+// call to
+//
+//	as.currentPeriod.end = as.currentPeriod.end.Add(as.period)
+//
+// might not catch us up to the current period
+func (as *adaptiveSampler) advancePeriod(now time.Time) {
+	for now.After(as.currentPeriod.end) {
+		as.priorityMin = 0.0
+		if as.currentPeriod.numSeen > 0 {
+			sampledRatio := float32(as.target) / float32(as.currentPeriod.numSeen)
+			as.priorityMin = 1.0 - sampledRatio
+		}
+		as.currentPeriod.numSampled = 0
+		as.currentPeriod.numSeen = 0
+		as.currentPeriod.numErrorSampled = 0
+		as.currentPeriod.end = as.currentPeriod.end.Add(as.period)
+	}
+}
+
+// computeErrorSampled calculates if a transaction which was not sampled but
+// has noticed an error should be sampled anyway.  Transactions sampled this
+// way do not count towards the sampling target.
+func (as *adaptiveSampler) computeErrorSampled(now time.Time) bool {
+	as.Lock()
+	defer as.Unlock()
+
+	as.advancePeriod(now)
+
+	if as.currentPeriod.numErrorSampled >= as.errorBudget {
+		return false
+	}
+	as.currentPeriod.numErrorSampled++
+	return true
+}
+
+// setErrorBudget changes the error budget.  A budget of zero or less
+// disables error sampling.
+func (as *adaptiveSampler) setErrorBudget(budget int) {
+	as.Lock()
+	defer as.Unlock()
+
+	if budget < 0 {
+		budget = 0
+	}
+	as.errorBudget = uint64(budget)
+}
+
 func (as *adaptiveSampler) computeSampledBackoff(target uint64, decidedCount uint64, sampledTrueCount uint64) bool {
 	return float64(randUint64N(decidedCount)) <
 		math.Pow(float64(target), (float64(target)/float64(sampledTrueCount)))-math.Pow(float64(target), 0.5)
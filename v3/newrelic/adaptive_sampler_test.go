@@ -100,3 +100,18 @@ func TestAdaptiveSamplerTargetZero(t *testing.T) {
 		assert(t, !sampler.computeSampled(0.0, start))
 	}
 }
+
+func TestAdaptiveSamplerErrorBudget(t *testing.T) {
+	start := time.Now()
+	sampler := newAdaptiveSampler(60*time.Second, 2, start)
+	assert(t, !sampler.computeErrorSampled(start))
+
+	sampler.errorBudget = 2
+	assert(t, sampler.computeErrorSampled(start))
+	assert(t, sampler.computeErrorSampled(start))
+	assert(t, !sampler.computeErrorSampled(start))
+
+	// The budget is restored in the next period.
+	now := start.Add(61 * time.Second)
+	assert(t, sampler.computeErrorSampled(now))
+}
@@ -46,12 +46,20 @@ func (events *analyticsEvents) capacity() int {
 	return cap(events.events)
 }
 
-func (events *analyticsEvents) addEvent(e analyticsEvent) {
+// setCapacity changes the number of events kept.  It must only be used
+// before any events have been added.
+func (events *analyticsEvents) setCapacity(max int) {
+	events.events = make(analyticsEventHeap, 0, max)
+}
+
+// addEvent adds the event, replacing the lowest priority event if the
+// reservoir is full.  It returns the event which was not kept, if any.
+func (events *analyticsEvents) addEvent(e analyticsEvent) (analyticsEvent, bool) {
 	events.numSeen++
 
 	if events.capacity() == 0 {
 		// Configurable event harvest limits may be zero.
-		return
+		return e, true
 	}
 
 	if len(events.events) < cap(events.events) {
@@ -62,15 +70,17 @@ func (events *analyticsEvents) addEvent(e analyticsEvent) {
 			// is not being reached).
 			heap.Init(events.events)
 		}
-		return
+		return analyticsEvent{}, false
 	}
 
 	if e.priority.isLowerPriority((events.events)[0].priority) {
-		return
+		return e, true
 	}
 
+	dropped := events.events[0]
 	events.events[0] = e
 	heap.Fix(events.events, 0)
+	return dropped, true
 }
 
 func (events *analyticsEvents) mergeFailed(other *analyticsEvents) {
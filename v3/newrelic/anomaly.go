@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "sync"
+
+// anomalyMaxBaselines limits the number of transaction names whose average
+// duration is kept, so that names of unbounded cardinality do not use
+// unbounded memory.
+const anomalyMaxBaselines = 2000
+
+type baseline struct {
+	// average is the moving average of the duration in seconds.
+	average float64
+	count   int
+}
+
+// baselines contains the average duration of the transactions with each
+// name.
+type baselines struct {
+	sync.Mutex
+	names map[string]*baseline
+}
+
+// observe adds the duration of a transaction to the average of its name.
+// It returns the average before the duration was added, and whether enough
+// transactions with the name had ended for the average to be used.
+func (b *baselines) observe(name string, duration float64, weight float64, minSamples int) (float64, bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	bl, ok := b.names[name]
+	if !ok {
+		if len(b.names) >= anomalyMaxBaselines {
+			return 0, false
+		}
+		if nil == b.names {
+			b.names = make(map[string]*baseline)
+		}
+		b.names[name] = &baseline{average: duration, count: 1}
+		return 0, false
+	}
+	average := bl.average
+	ready := bl.count >= minSamples
+	bl.average += weight * (duration - bl.average)
+	bl.count++
+	return average, ready
+}
+
+// recordAnomaly adds the duration of the transaction to the average of its
+// name, and flags the transaction if it took more than
+// Config.AnomalyDetection.Factor times the average.  It must be called
+// while the transaction is locked, after its name has been frozen.
+func (txn *txn) recordAnomaly() {
+	cfg := txn.Config.AnomalyDetection
+	if !cfg.Enabled || txn.ignore {
+		return
+	}
+	duration := txn.Duration.Seconds()
+	average, ready := txn.app.baselines.observe(txn.FinalName, duration, cfg.Weight, cfg.MinSamples)
+	if ready && duration > cfg.Factor*average {
+		txn.Attrs.Agent.Add(AttributeAnomaly, "", true)
+		txn.Attrs.Agent.Add(AttributeAnomalyBaseline, "", average)
+	}
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strconv"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestBaselinesObserve(t *testing.T) {
+	var b baselines
+	if _, ready := b.observe("hello", 1, 0.5, 2); ready {
+		t.Error("ready after one transaction")
+	}
+	if average, ready := b.observe("hello", 3, 0.5, 2); ready || 1 != average {
+		t.Error(average, ready)
+	}
+	if average, ready := b.observe("hello", 10, 0.5, 2); !ready || 2 != average {
+		t.Error(average, ready)
+	}
+	if average := b.names["hello"].average; 6 != average {
+		t.Error(average)
+	}
+}
+
+func TestBaselinesMaxNames(t *testing.T) {
+	var b baselines
+	for i := 0; i < anomalyMaxBaselines+1; i++ {
+		b.observe(strconv.Itoa(i), 1, 0.1, 0)
+	}
+	if n := len(b.names); anomalyMaxBaselines != n {
+		t.Error(n)
+	}
+	if _, ready := b.observe(strconv.Itoa(anomalyMaxBaselines), 1, 0.1, 0); ready {
+		t.Error("name beyond the limit was tracked")
+	}
+}
+
+func TestAnomalyDetection(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.AnomalyDetection.Enabled = true
+	}, t)
+	baselines := &internalApp(app).baselines
+	baselines.names = map[string]*baseline{
+		"OtherTransaction/Go/slow": {average: 1e-12, count: 100},
+		"OtherTransaction/Go/fast": {average: 1000, count: 100},
+		"OtherTransaction/Go/new":  {average: 1e-12, count: 1},
+	}
+	app.StartTransaction("slow").End()
+	app.StartTransaction("fast").End()
+	app.StartTransaction("new").End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/slow",
+			},
+			AgentAttributes: map[string]interface{}{
+				AttributeAnomaly:         true,
+				AttributeAnomalyBaseline: 1e-12,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/fast",
+			},
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/new",
+			},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+	if count := baselines.names["OtherTransaction/Go/slow"].count; 101 != count {
+		t.Error(count)
+	}
+}
+
+func TestAnomalyDetectionDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.StartTransaction("hello").End()
+	if nil != internalApp(app).baselines.names {
+		t.Error(internalApp(app).baselines.names)
+	}
+}
+
+func TestAnomalyDetectionValidate(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.License = testLicenseKey
+	cfg.AppName = "my app"
+	cfg.AnomalyDetection.Enabled = true
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+	for _, tc := range []struct {
+		factor float64
+		weight float64
+	}{
+		{factor: 1, weight: 0.1},
+		{factor: 3, weight: 0},
+		{factor: 3, weight: 1.5},
+	} {
+		cfg.AnomalyDetection.Factor = tc.factor
+		cfg.AnomalyDetection.Weight = tc.weight
+		if err := cfg.validate(); errAnomalyDetection != err {
+			t.Error(tc.factor, tc.weight, err)
+		}
+	}
+	cfg.AnomalyDetection.Enabled = false
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+}
@@ -24,6 +24,9 @@ type appRun struct {
 	// firstAppName is the value of Config.AppName up to the first semicolon.
 	firstAppName string
 
+	// preconnectHost is the collector host used to establish this run.
+	preconnectHost string
+
 	adaptiveSampler *adaptiveSampler
 
 	// rulesCache caches the results of creating transaction names.  It
@@ -35,6 +38,10 @@ type appRun struct {
 	// flexible harvest periods.  This field is created once at appRun
 	// creation.
 	harvestConfig harvestConfig
+
+	// ended is set atomically once a harvest has reported the collector
+	// response which ends this run.
+	ended int32
 }
 
 const (
@@ -49,6 +56,18 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 		rulesCache:      newRulesCache(txnNameCacheLimit),
 	}
 
+	// Distributed tracing uses the configured account until the
+	// connect reply provides one.
+	if "" == run.Reply.AccountID {
+		run.Reply.AccountID = config.DistributedTracer.AccountID
+	}
+	if "" == run.Reply.TrustedAccountKey {
+		run.Reply.TrustedAccountKey = config.DistributedTracer.TrustedAccountKey
+		if "" == run.Reply.TrustedAccountKey {
+			run.Reply.TrustedAccountKey = config.DistributedTracer.AccountID
+		}
+	}
+
 	// Overwrite local settings with any server-side-config settings
 	// present. NOTE!  This requires that the Config provided to this
 	// function is a value and not a pointer: We do not want to change the
@@ -108,6 +127,9 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 		time.Duration(reply.SamplingTargetPeriodInSeconds)*time.Second,
 		reply.SamplingTarget,
 		time.Now())
+	if budget := run.Config.DistributedTracer.ErrorSamplingBudget; budget > 0 {
+		run.adaptiveSampler.errorBudget = uint64(budget)
+	}
 
 	if "" != run.Reply.RunID {
 		js, _ := json.Marshal(settings(run.Config.Config))
@@ -122,6 +144,17 @@ func newAppRun(config config, reply *internal.ConnectReply) *appRun {
 		MaxCustomEvents: run.MaxCustomEvents(),
 		MaxErrorEvents:  run.MaxErrorEvents(),
 		MaxSpanEvents:   run.MaxSpanEvents(),
+		MaxLogEvents:    run.MaxLogEvents(),
+		LogCommon: logCommon{
+			entityGUID: run.Reply.EntityGUID,
+			entityName: run.firstAppName,
+			hostname:   run.Config.hostname,
+		},
+
+		StratifiedErrorEvents: run.Config.ErrorCollector.StratifyEvents,
+	}
+	if run.Config.EventHarvest.Adaptive.Enabled {
+		run.harvestConfig.AdaptiveMinPeriod = run.Config.EventHarvest.Adaptive.MinPeriod
 	}
 
 	return run
@@ -189,6 +222,7 @@ func (run *appRun) ptrTxnEvents() *uint    { return run.Reply.EventData.Limits.T
 func (run *appRun) ptrCustomEvents() *uint { return run.Reply.EventData.Limits.CustomEvents }
 func (run *appRun) ptrErrorEvents() *uint  { return run.Reply.EventData.Limits.ErrorEvents }
 func (run *appRun) ptrSpanEvents() *uint   { return run.Reply.EventData.Limits.SpanEvents }
+func (run *appRun) ptrLogEvents() *uint    { return run.Reply.EventData.Limits.LogEvents }
 
 func (run *appRun) MaxTxnEvents() int { return run.limit(run.Config.maxTxnEvents(), run.ptrTxnEvents) }
 func (run *appRun) MaxCustomEvents() int {
@@ -199,6 +233,15 @@ func (run *appRun) MaxErrorEvents() int {
 }
 func (run *appRun) MaxSpanEvents() int { return run.limit(maxSpanEvents, run.ptrSpanEvents) }
 
+// MaxLogEvents returns zero unless log forwarding is enabled.
+func (run *appRun) MaxLogEvents() int {
+	logging := run.Config.ApplicationLogging
+	if !logging.Enabled || !logging.Forwarding.Enabled {
+		return 0
+	}
+	return run.limit(run.Config.maxLogEvents(), run.ptrLogEvents)
+}
+
 func (run *appRun) limit(dflt int, field func() *uint) int {
 	if nil != field() {
 		return int(*field())
@@ -215,6 +258,7 @@ func (run *appRun) ReportPeriods() map[harvestTypes]time.Duration {
 		harvestCustomEvents: run.ptrCustomEvents,
 		harvestErrorEvents:  run.ptrErrorEvents,
 		harvestSpanEvents:   run.ptrSpanEvents,
+		harvestLogEvents:    run.ptrLogEvents,
 	} {
 		if nil != run && fn() != nil {
 			configurable |= tp
@@ -145,7 +145,8 @@ func TestEventHarvestFieldsAllPopulated(t *testing.T) {
 					"analytic_event_data": 1,
 					"custom_event_data": 2,
 					"span_event_data": 3,
-					"error_event_data": 4
+					"error_event_data": 4,
+					"log_event_data": 5
 				}
 			}
 		}}`), internal.PreconnectReply{})
@@ -401,6 +402,31 @@ func TestPlaceholderAppRunSampler(t *testing.T) {
 	}
 }
 
+func TestAppRunDistributedTracerAccount(t *testing.T) {
+	testcases := []struct {
+		accountID, trustKey        string
+		replyAccount, replyTrust   string
+		expectAccount, expectTrust string
+	}{
+		{},
+		{accountID: "123", expectAccount: "123", expectTrust: "123"},
+		{accountID: "123", trustKey: "789", expectAccount: "123", expectTrust: "789"},
+		{accountID: "123", trustKey: "789", replyAccount: "456", replyTrust: "456", expectAccount: "456", expectTrust: "456"},
+	}
+	for i, tc := range testcases {
+		cfg := config{Config: defaultConfig()}
+		cfg.DistributedTracer.AccountID = tc.accountID
+		cfg.DistributedTracer.TrustedAccountKey = tc.trustKey
+		reply := internal.ConnectReplyDefaults()
+		reply.AccountID = tc.replyAccount
+		reply.TrustedAccountKey = tc.replyTrust
+		run := newAppRun(cfg, reply)
+		if run.Reply.AccountID != tc.expectAccount || run.Reply.TrustedAccountKey != tc.expectTrust {
+			t.Error(i, run.Reply.AccountID, run.Reply.TrustedAccountKey)
+		}
+	}
+}
+
 func TestAppRunSampler(t *testing.T) {
 	// Test that a default app run samples transactions.
 	// Test that the default txn trace threshold is the failing apdex.
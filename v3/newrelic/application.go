@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"os"
 	"time"
 )
@@ -51,6 +52,28 @@ func (app *Application) RecordCustomEvent(eventType string, params map[string]in
 	}
 }
 
+// RecordLog records a log, eg. from the hook of a logging framework.  The
+// log is counted by the Logging/lines metrics and, when
+// Config.ApplicationLogging.Forwarding is enabled, sent to New Relic.  If
+// LogData.Context contains a Transaction, the log is linked to it, as it is by
+// Transaction.RecordLog.
+//
+// An error is logged if the log message is empty.
+func (app *Application) RecordLog(log LogData) {
+	if nil == app {
+		return
+	}
+	if nil == app.app {
+		return
+	}
+	err := app.app.RecordLog(log)
+	if err != nil {
+		app.app.Error("unable to record log", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+}
+
 // RecordCustomMetric records a custom metric.  The metric name you
 // provide will be prefixed by "Custom/".  Custom metrics are not
 // currently supported in serverless mode.
@@ -74,6 +97,113 @@ func (app *Application) RecordCustomMetric(name string, value float64) {
 	}
 }
 
+// RecordCustomMetricSummary records the values added to the summary as a
+// custom metric, as though RecordCustomMetric had been called with each.
+// The metric name you provide will be prefixed by "Custom/".  Nothing is
+// recorded for an empty summary.  Custom metrics are not currently supported
+// in serverless mode.
+func (app *Application) RecordCustomMetricSummary(name string, summary CustomMetricSummary) {
+	if nil == app {
+		return
+	}
+	if nil == app.app {
+		return
+	}
+	err := app.app.RecordCustomMetricSummary(name, summary)
+	if err != nil {
+		app.app.Error("unable to record custom metric", map[string]interface{}{
+			"metric-name": name,
+			"reason":      err.Error(),
+		})
+	}
+}
+
+// NewCounter returns the Counter with the given name, creating it if
+// necessary.  Counters are custom metrics, like those recorded by
+// RecordCustomMetric, whose names are prefixed by "Custom/", but adding to a
+// Counter is a single atomic operation suitable for code run thousands of
+// times a second.  Keep the Counter rather than calling NewCounter on each
+// use.  Counters are not supported in serverless mode: nil, whose methods do
+// nothing, is returned.
+func (app *Application) NewCounter(name string) *Counter {
+	if nil == app || nil == app.app {
+		return nil
+	}
+	c, err := app.app.NewCounter(name)
+	if nil != err {
+		app.app.Error("unable to create counter", map[string]interface{}{
+			"metric-name": name,
+			"reason":      err.Error(),
+		})
+	}
+	return c
+}
+
+// NewGauge returns the Gauge with the given name, creating it if necessary.
+// Gauges are custom metrics, like those recorded by RecordCustomMetric, whose
+// names are prefixed by "Custom/", but setting a Gauge is a single atomic
+// operation suitable for code run thousands of times a second.  Keep the
+// Gauge rather than calling NewGauge on each use.  Gauges are not supported
+// in serverless mode: nil, whose methods do nothing, is returned.
+func (app *Application) NewGauge(name string) *Gauge {
+	if nil == app || nil == app.app {
+		return nil
+	}
+	g, err := app.app.NewGauge(name)
+	if nil != err {
+		app.app.Error("unable to create gauge", map[string]interface{}{
+			"metric-name": name,
+			"reason":      err.Error(),
+		})
+	}
+	return g
+}
+
+// RecordCircuitBreakerTransition records a change in the state of a
+// client-side circuit breaker protecting calls to the named downstream
+// service.  Each transition increments the metric
+// "CircuitBreaker/{service}/State/{to}" and records a
+// "CircuitBreakerStateChange" custom event with the service, from, and to
+// attributes.  The event is not recorded if custom events are disabled.
+// Use the nrgobreaker and nrhystrix integrations to report transitions
+// automatically.
+func (app *Application) RecordCircuitBreakerTransition(service string, from, to CircuitBreakerState) {
+	if nil == app {
+		return
+	}
+	if nil == app.app {
+		return
+	}
+	err := app.app.RecordCircuitBreakerTransition(service, from, to)
+	if err != nil {
+		app.app.Error("unable to record circuit breaker transition", map[string]interface{}{
+			"service": service,
+			"reason":  err.Error(),
+		})
+	}
+}
+
+// RecordCircuitBreakerShortCircuits records calls to the named downstream
+// service which were rejected by a client-side circuit breaker without
+// being attempted.  The count is added to the metrics
+// "CircuitBreaker/{service}/ShortCircuited" and
+// "CircuitBreaker/all/ShortCircuited".
+func (app *Application) RecordCircuitBreakerShortCircuits(service string, count int) {
+	if nil == app {
+		return
+	}
+	if nil == app.app {
+		return
+	}
+	err := app.app.RecordCircuitBreakerShortCircuits(service, count)
+	if err != nil {
+		app.app.Error("unable to record circuit breaker short circuits", map[string]interface{}{
+			"service": service,
+			"reason":  err.Error(),
+		})
+	}
+}
+
 // WaitForConnection blocks until the application is connected, is
 // incapable of being connected, or the timeout has been reached.  This
 // method is useful for short-lived processes since the application will
@@ -90,6 +220,123 @@ func (app *Application) WaitForConnection(timeout time.Duration) error {
 	return app.app.WaitForConnection(timeout)
 }
 
+// SecurityPolicyEffects returns the configured agent behavior which has been
+// restricted by Config.HighSecurity or by the security policies set for your
+// account.  Security policies are only known once the application has
+// connected.  A summary of these effects is logged when the application
+// connects.
+func (app *Application) SecurityPolicyEffects() []SecurityPolicyEffect {
+	if nil == app {
+		return nil
+	}
+	return app.app.SecurityPolicyEffects()
+}
+
+// ConfigFingerprint returns a hash of the Application's effective
+// configuration.  Instances of an application with identical agent settings
+// have identical fingerprints, so comparing the fingerprints of a fleet
+// detects instances running divergent settings.  The license and the
+// settings which identify the host, HostDisplayName and
+// Utilization.BillingHostname, are not included.  The fingerprint is also
+// sent to New Relic as the NEW_RELIC_METADATA_CONFIG_FINGERPRINT connect
+// metadata and logged in the startup summary.
+func (app *Application) ConfigFingerprint() string {
+	if nil == app {
+		return ""
+	}
+	return app.app.ConfigFingerprint()
+}
+
+// LoadStats returns the number of transactions in progress and the 99th
+// percentile duration of recent web transactions.  It is cheap enough to
+// call for every request, so that admission control middleware can shed
+// load based on the same measurements as the agent:
+//
+//	if stats := app.LoadStats(); stats.P99 > time.Second && stats.InFlight > 100 {
+//		w.WriteHeader(http.StatusServiceUnavailable)
+//		return
+//	}
+//
+// The statistics are measured even if the Application is not connected.
+func (app *Application) LoadStats() LoadStats {
+	if nil == app || nil == app.app {
+		return LoadStats{}
+	}
+	return app.app.LoadStats()
+}
+
+// UpdateConfig changes the configuration of a running Application without
+// restarting it or reconnecting to New Relic.  The ConfigOptions are applied
+// to the current configuration in order.  Only the following settings may be
+// changed:
+//
+//	Config.Logger
+//	Config.Attributes and the Attributes of each destination
+//	Config.TransactionEvents.Enabled
+//	Config.ErrorCollector.Enabled
+//	Config.TransactionTracer.Enabled
+//	Config.DatastoreTracer.SlowQuery.Enabled
+//	Config.SpanEvents.Enabled
+//	Config.CustomInsightsEvents.Enabled
+//	Config.BrowserMonitoring.Enabled
+//	Config.DistributedTracer.ErrorSamplingBudget
+//	Config.DistributedTracer.InboundSampled
+//	Config.DistributedTracer.SamplingRules
+//	Config.DistributedTracer.TailSampling
+//
+// An error is returned, and nothing is changed, if the options change any
+// other setting or if the resulting configuration is invalid.  Settings
+// controlled by New Relic, such as a connect reply disabling span events,
+// continue to take priority.  Transactions which have already started keep
+// the configuration they started with.
+//
+//	err := app.UpdateConfig(
+//		newrelic.ConfigDebugLogger(os.Stdout),
+//		func(cfg *newrelic.Config) {
+//			cfg.Attributes.Exclude = append(cfg.Attributes.Exclude, "request.headers.*")
+//		},
+//	)
+func (app *Application) UpdateConfig(opts ...ConfigOption) error {
+	if nil == app || nil == app.app {
+		return nil
+	}
+	return app.app.updateConfig(opts)
+}
+
+// Tick harvests the data which is ready to be sent to New Relic, and samples
+// the runtime, records the scaling signal and transaction checkpoints, and
+// refreshes the license when due.  It has no effect unless Config.ManualHarvest is
+// enabled, in which case the host application must call Tick about once per
+// second.  The harvest is sent using the calling goroutine, so Tick blocks
+// until the data has been sent or the request has failed.
+func (app *Application) Tick() {
+	if nil == app {
+		return
+	}
+	app.app.tick()
+}
+
+// RunHarvestLoop calls Tick once per second until the context is done or the
+// Application is shut down.  It allows the host application to choose the
+// goroutine which harvests data when Config.ManualHarvest is enabled.
+func (app *Application) RunHarvestLoop(ctx context.Context) {
+	if nil == app || nil == app.app {
+		return
+	}
+	t := time.NewTicker(time.Second)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+			app.Tick()
+		case <-ctx.Done():
+			return
+		case <-app.app.shutdownStarted:
+			return
+		}
+	}
+}
+
 // Shutdown flushes data to New Relic's servers and stops all
 // agent-related goroutines managing this application.  After Shutdown
 // is called, the Application is disabled and will never collect data
@@ -97,6 +344,11 @@ func (app *Application) WaitForConnection(timeout time.Duration) error {
 // or the timeout has elapsed.  Increase the timeout and check debug
 // logs if you aren't seeing data.
 //
+// Transactions which are still in progress when Shutdown is called are
+// ended and included in the final harvest.  They are given the
+// AttributeTruncated attribute.  Transactions which were never ended and
+// whose Transaction values are no longer referenced are not included.
+//
 // If Infinite Tracing is enabled, Shutdown will block until all queued span
 // events have been sent to the Trace Observer or the timeout has been reached.
 func (app *Application) Shutdown(timeout time.Duration) {
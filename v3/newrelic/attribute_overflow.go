@@ -0,0 +1,76 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// attributeOverflowSeverity is the severity of the log events which record
+// the complete values of truncated attributes.
+const attributeOverflowSeverity = "INFO"
+
+// AttributeOverflow is provided to Config.AttributeLimits.OverflowHandler
+// when the string value of a custom transaction or span attribute is
+// truncated.
+type AttributeOverflow struct {
+	// Key is the attribute key.
+	Key string
+	// Value is the complete attribute value.
+	Value string
+	// Metadata links the value to the transaction and span which recorded
+	// the attribute.  It may be used to decorate a log message, see
+	// https://github.com/newrelic/go-agent/tree/master/v3/integrations/logcontext.
+	Metadata LinkingMetadata
+}
+
+// reportAttributeOverflow records the complete value of a custom attribute
+// which was truncated as a log event linked to the trace and span, if
+// application log forwarding is enabled, and provides it to the overflow
+// handler, if one is configured.  It must be called after the attribute has
+// been successfully added.  It must not be called with the transaction
+// locked, since recording the log event and the linking metadata lock it.
+func (thd *thread) reportAttributeOverflow(key string, val interface{}, d destinationSet) {
+	s, ok := val.(string)
+	if !ok || len(s) <= thd.Attrs.config.attributeLimits().valueLength {
+		return
+	}
+	// Do not report values which have been excluded by the attributes
+	// configuration.
+	if destNone == applyAttributeConfig(thd.Attrs.config, key, d) {
+		return
+	}
+	thd.recordAttributeOverflowLog(key, s, time.Now())
+	if fn := thd.Config.AttributeLimits.OverflowHandler; nil != fn {
+		fn(AttributeOverflow{
+			Key:      key,
+			Value:    s,
+			Metadata: thd.GetLinkingMetadata(),
+		})
+	}
+}
+
+// recordAttributeOverflowLog records the complete value of a truncated
+// attribute as a forwarded log event.  Unlike the logs recorded using
+// RecordLog, it is not counted by the logging metrics.
+func (thd *thread) recordAttributeOverflowLog(key string, value string, now time.Time) {
+	txn := thd.txn
+	txn.Lock()
+	if txn.finished {
+		txn.Unlock()
+		return
+	}
+	e, _ := createLogEvent(txn.Config, LogData{
+		Severity: attributeOverflowSeverity,
+		Message:  key + ": " + value,
+	}, now)
+	if nil == e || !e.forward {
+		txn.Unlock()
+		return
+	}
+	e.lineMetrics = false
+	thd.linkLogEvent(e)
+	app, id := txn.consumer()
+	txn.Unlock()
+
+	app.Consume(id, e)
+}
@@ -0,0 +1,145 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestAttributeOverflowHandler(t *testing.T) {
+	var overflows []AttributeOverflow
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.Attributes.Exclude = []string{"excluded"}
+		cfg.AttributeLimits.OverflowHandler = func(o AttributeOverflow) {
+			overflows = append(overflows, o)
+		}
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	long := strings.Repeat("a", attributeValueLengthLimit+1)
+
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("short", "a")
+	txn.AddAttribute("number", 123)
+	txn.AddAttribute("excluded", long)
+	txn.AddAttribute("txnAttr", long)
+	seg := txn.StartSegment("segment")
+	seg.AddAttribute("spanAttr", long)
+	segMetadata := txn.GetLinkingMetadata()
+	seg.End()
+	txnMetadata := txn.GetLinkingMetadata()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	if len(overflows) != 2 {
+		t.Fatal(overflows)
+	}
+	if o := overflows[0]; o.Key != "txnAttr" || o.Value != long || o.Metadata != txnMetadata {
+		t.Error(o.Key, o.Metadata)
+	}
+	if o := overflows[1]; o.Key != "spanAttr" || o.Value != long || o.Metadata != segMetadata {
+		t.Error(o.Key, o.Metadata)
+	}
+	if "" == segMetadata.SpanID || segMetadata.SpanID == txnMetadata.SpanID {
+		t.Error(segMetadata.SpanID, txnMetadata.SpanID)
+	}
+}
+
+func TestAttributeOverflowLogEvent(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		enableLogForwarding(cfg)
+	}
+	app := testApp(replyfn, cfgfn, t)
+	long := strings.Repeat("a", attributeValueLengthLimit+1)
+
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("short", "a")
+	txn.AddAttribute("txnAttr", long)
+	seg := txn.StartSegment("segment")
+	seg.AddAttribute("spanAttr", long)
+	segMetadata := txn.GetLinkingMetadata()
+	seg.End()
+	txnMetadata := txn.GetLinkingMetadata()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	events := internalApp(app).testHarvest.LogEvents.events
+	if len(events) != 2 {
+		t.Fatal(len(events))
+	}
+	for i, want := range []struct {
+		message  string
+		metadata LinkingMetadata
+	}{
+		{message: "txnAttr: " + long, metadata: txnMetadata},
+		{message: "spanAttr: " + long, metadata: segMetadata},
+	} {
+		e := events[i].jsonWriter.(*logEvent)
+		if e.message != want.message || e.severity != attributeOverflowSeverity {
+			t.Error(i, e.severity, e.message)
+		}
+		if e.traceID != want.metadata.TraceID || e.spanID != want.metadata.SpanID {
+			t.Error(i, e.traceID, e.spanID)
+		}
+	}
+	// The overflow logs are not counted as logs of the application.
+	if _, ok := internalApp(app).testHarvest.Metrics.metrics[metricID{Name: "Logging/lines"}]; ok {
+		t.Error("overflow logs counted")
+	}
+}
+
+func TestAttributeOverflowLogForwardingDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("txnAttr", strings.Repeat("a", attributeValueLengthLimit+1))
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	if n := internalApp(app).testHarvest.LogEvents.NumSeen(); 0 != n {
+		t.Error(n)
+	}
+}
+
+func TestAttributeOverflowTransactionUnlocked(t *testing.T) {
+	// The handler may call Transaction methods, which lock the
+	// transaction: the overflow must be reported without the transaction
+	// locked or adding the attribute deadlocks.
+	var txn *Transaction
+	var sampled []bool
+	cfgfn := func(cfg *Config) {
+		enableLogForwarding(cfg)
+		cfg.AttributeLimits.OverflowHandler = func(o AttributeOverflow) {
+			sampled = append(sampled, txn.IsSampled())
+		}
+	}
+	app := testApp(nil, cfgfn, t)
+	long := strings.Repeat("a", attributeValueLengthLimit+1)
+	txn = app.StartTransaction("hello")
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		txn.AddAttribute("txnAttr", long)
+		seg := txn.StartSegment("segment")
+		seg.AddAttribute("spanAttr", long)
+		seg.End()
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("adding an overflowing attribute deadlocked")
+	}
+	txn.End()
+	if len(sampled) != 2 {
+		t.Error(sampled)
+	}
+}
@@ -44,6 +44,83 @@ const (
 	AttributeResponseContentLength = "response.headers.contentLength"
 	// AttributeHostDisplayName contains the value of Config.HostDisplayName.
 	AttributeHostDisplayName = "host.displayName"
+	// AttributeTruncated is true for transactions which were still in
+	// progress when Application.Shutdown was called.
+	AttributeTruncated = "truncated"
+	// AttributeRequestReadDuration is the time in seconds spent reading the
+	// request body.  It is recorded for requests handled by a server
+	// instrumented using WrapServer.
+	AttributeRequestReadDuration = "request.readDuration"
+	// AttributeResponseWriteDuration is the time in seconds spent writing the
+	// response.  It is recorded for requests handled by a server
+	// instrumented using WrapServer.
+	AttributeResponseWriteDuration = "response.writeDuration"
+	// AttributeHTTPFlavor is the HTTP protocol version of the request, eg.
+	// "1.1", "2", or "3".
+	AttributeHTTPFlavor = "http.flavor"
+	// AttributeRequestClientIP is the IP address of the client.  It is
+	// recorded when Config.ClientIP.Enabled is true.
+	AttributeRequestClientIP = "request.clientIp"
+	// AttributeTLSVersion is the TLS version negotiated for the request's
+	// connection, eg. "1.2" or "1.3".
+	AttributeTLSVersion = "tls.version"
+	// AttributeTLSALPN is the application protocol negotiated using ALPN
+	// for the request's connection, eg. "h2".
+	AttributeTLSALPN = "tls.alpn"
+	// AttributeProcessCPUTime is the CPU time in seconds, user and
+	// system, used by the whole process, not only by the transaction,
+	// between the start and end of the transaction.  It is recorded when
+	// Config.TransactionProcessCPUTime.Enabled is true.
+	AttributeProcessCPUTime = "process.cpu.time"
+	// AttributeProcessCPUUserTime is the user CPU time in seconds used by
+	// the process during the transaction.
+	AttributeProcessCPUUserTime = "process.cpu.time.user"
+	// AttributeProcessCPUSystemTime is the system CPU time in seconds used
+	// by the process during the transaction.
+	AttributeProcessCPUSystemTime = "process.cpu.time.system"
+	// AttributeContentionBlockDelay is the time in seconds goroutines spent
+	// blocked, according to the block profile, during the transaction.  It
+	// is recorded when Config.ContentionProfiling.Enabled is true.
+	AttributeContentionBlockDelay = "contention.blockDelay"
+	// AttributeContentionMutexDelay is the time in seconds goroutines spent
+	// waiting for mutexes, according to the mutex profile, during the
+	// transaction.
+	AttributeContentionMutexDelay = "contention.mutexDelay"
+	// AttributeContentionSites lists the code locations with the most
+	// contention during the transaction, most delayed first, in the form
+	// "block main.(*cache).get:42 0.012s; mutex ...".
+	AttributeContentionSites = "contention.sites"
+	// AttributeTransactionOutcome is the outcome of the transaction set
+	// using Transaction.SetOutcome, eg. "Success" or "Timeout".
+	AttributeTransactionOutcome = "transaction.outcome"
+	// AttributeContextCancelled is true for transactions whose context was
+	// cancelled before the transaction ended, see
+	// Config.ContextCancellation.
+	AttributeContextCancelled = "context.cancelled"
+	// AttributeContextDeadlineExceeded is true for transactions whose
+	// context's deadline passed before the transaction ended.
+	AttributeContextDeadlineExceeded = "context.deadline_exceeded"
+	// AttributeRetentionHint is the reason given to
+	// Transaction.MarkImportant.
+	AttributeRetentionHint = "retention.hint"
+	// AttributeWorkflowID and AttributeWorkflowStep are the workflow and
+	// the index of the transaction's step within it, set using
+	// Transaction.SetWorkflow or propagated in the distributed tracing
+	// baggage header.
+	AttributeWorkflowID   = "workflow.id"
+	AttributeWorkflowStep = "workflow.step"
+	// AttributeRequestTooLarge is true for web requests which were rejected
+	// because their body was too large: the response status code was 413
+	// or Transaction.NoticeError was called with the error returned by an
+	// http.MaxBytesReader.  The transaction's outcome is also set to
+	// OutcomeRequestTooLarge, unless Transaction.SetOutcome was called.
+	AttributeRequestTooLarge = "request.too_large"
+	// AttributeAnomaly is true for transactions which took longer than
+	// expected for their name, and AttributeAnomalyBaseline is the
+	// expected duration in seconds.  They are recorded when
+	// Config.AnomalyDetection.Enabled is true.
+	AttributeAnomaly         = "anomaly"
+	AttributeAnomalyBaseline = "anomaly.baseline"
 )
 
 // Attributes destined for Errors and Transaction Traces:
@@ -117,6 +194,26 @@ const (
 	AttributeMessageCorrelationID = "message.correlationId"
 )
 
+// Attributes for gRPC server transactions:
+//
+// The nrgrpc integration
+// (https://godoc.org/github.com/newrelic/go-agent/v3/integrations/nrgrpc)
+// adds these attributes describing the messages of each call.  Sizes are the
+// serialized size in bytes of the protobuf messages before compression.
+const (
+	// The total size in bytes of the request messages.
+	AttributeGRPCRequestSize = "grpc.request.size"
+	// The total size in bytes of the response messages.
+	AttributeGRPCResponseSize = "grpc.response.size"
+	// The number of request messages.
+	AttributeGRPCRequestMessages = "grpc.request.messages"
+	// The number of response messages.
+	AttributeGRPCResponseMessages = "grpc.response.messages"
+	// The compression codec applied to the request messages, eg. "gzip",
+	// or "identity" if they were not compressed.
+	AttributeGRPCCompression = "grpc.compression"
+)
+
 // Attributes destined for Span Events. These attributes appear only on Span
 // Events and are not available to transaction events, error events, or traced
 // errors.
@@ -138,11 +235,29 @@ const (
 	SpanAttributeAWSRegion               = "aws.region"
 	SpanAttributeErrorClass              = "error.class"
 	SpanAttributeErrorMessage            = "error.message"
+	SpanAttributeErrorCode               = "error.code"
 	SpanAttributeParentType              = "parent.type"
 	SpanAttributeParentApp               = "parent.app"
 	SpanAttributeParentAccount           = "parent.account"
 	SpanAttributeParentTransportDuration = "parent.transportDuration"
 	SpanAttributeParentTransportType     = "parent.transportType"
+	SpanAttributeIOLocation              = "io.location"
+	SpanAttributeIOBytes                 = "io.bytes"
+	SpanAttributeIOThroughput            = "io.throughput"
+	SpanAttributeIOStorageClass          = "io.storageClass"
+	SpanAttributeDeadlineRemaining       = "deadline.remaining"
+	SpanAttributeDeadlineConsumed        = "deadline.consumed"
+	SpanAttributeDeadlineOverBudget      = "deadline.overBudget"
+	// SpanAttributeAsync is true for the spans of segments started using
+	// Transaction.StartBackgroundSegment.
+	SpanAttributeAsync = "async"
+	// SpanAttributeCodeFunction, SpanAttributeCodeFilepath, and
+	// SpanAttributeCodeLineno are the location of the code which started
+	// the segment.  They are recorded when Config.CodeLevelMetrics is
+	// enabled.
+	SpanAttributeCodeFunction = "code.function"
+	SpanAttributeCodeFilepath = "code.filepath"
+	SpanAttributeCodeLineno   = "code.lineno"
 
 	// Deprecated: This attribute is a duplicate of AttributeResponseCode and
 	// will be removed in a later release.
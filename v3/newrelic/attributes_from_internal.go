@@ -5,8 +5,10 @@ package newrelic
 
 import (
 	"bytes"
+	"crypto/tls"
 	"fmt"
 	"math"
+	"net"
 	"net/http"
 	"net/url"
 	"sort"
@@ -30,6 +32,28 @@ var (
 	//
 	agentAttributeDefaultDests = map[string]destinationSet{
 		AttributeHostDisplayName:            usualDests,
+		AttributeTruncated:                  usualDests,
+		AttributeRequestReadDuration:        usualDests,
+		AttributeResponseWriteDuration:      usualDests,
+		AttributeHTTPFlavor:                 usualDests,
+		AttributeRequestClientIP:            usualDests,
+		AttributeTLSVersion:                 usualDests,
+		AttributeTLSALPN:                    usualDests,
+		AttributeProcessCPUTime:             usualDests,
+		AttributeProcessCPUUserTime:         usualDests,
+		AttributeProcessCPUSystemTime:       usualDests,
+		AttributeContentionBlockDelay:       usualDests,
+		AttributeContentionMutexDelay:       usualDests,
+		AttributeContentionSites:            usualDests,
+		AttributeTransactionOutcome:         usualDests,
+		AttributeContextCancelled:           usualDests,
+		AttributeContextDeadlineExceeded:    usualDests,
+		AttributeRetentionHint:              usualDests,
+		AttributeWorkflowID:                 usualDests,
+		AttributeWorkflowStep:               usualDests,
+		AttributeRequestTooLarge:            usualDests,
+		AttributeAnomaly:                    usualDests,
+		AttributeAnomalyBaseline:            usualDests,
 		AttributeRequestMethod:              usualDests,
 		AttributeRequestAccept:              usualDests,
 		AttributeRequestContentType:         usualDests,
@@ -52,6 +76,11 @@ var (
 		AttributeMessageExchangeType:        destNone,
 		AttributeMessageReplyTo:             destNone,
 		AttributeMessageCorrelationID:       destNone,
+		AttributeGRPCRequestSize:            usualDests,
+		AttributeGRPCResponseSize:           usualDests,
+		AttributeGRPCRequestMessages:        usualDests,
+		AttributeGRPCResponseMessages:       usualDests,
+		AttributeGRPCCompression:            usualDests,
 
 		// Span specific attributes
 		SpanAttributeDBStatement:             usualDests,
@@ -66,11 +95,23 @@ var (
 		SpanAttributeAWSRegion:               usualDests,
 		SpanAttributeErrorClass:              usualDests,
 		SpanAttributeErrorMessage:            usualDests,
+		SpanAttributeErrorCode:               usualDests,
 		SpanAttributeParentType:              usualDests,
 		SpanAttributeParentApp:               usualDests,
 		SpanAttributeParentAccount:           usualDests,
 		SpanAttributeParentTransportDuration: usualDests,
 		SpanAttributeParentTransportType:     usualDests,
+		SpanAttributeIOLocation:              usualDests,
+		SpanAttributeIOBytes:                 usualDests,
+		SpanAttributeIOThroughput:            usualDests,
+		SpanAttributeIOStorageClass:          usualDests,
+		SpanAttributeDeadlineRemaining:       usualDests,
+		SpanAttributeDeadlineConsumed:        usualDests,
+		SpanAttributeDeadlineOverBudget:      usualDests,
+		SpanAttributeAsync:                   usualDests,
+		SpanAttributeCodeFunction:            usualDests,
+		SpanAttributeCodeFilepath:            usualDests,
+		SpanAttributeCodeLineno:              usualDests,
 	}
 )
 
@@ -117,6 +158,53 @@ type attributeConfig struct {
 	// over modifiers appearing earlier.
 	wildcardModifiers []*attributeModifier
 	agentDests        map[string]destinationSet
+	limits            attributeLimits
+	// integrationExcludes contains the lower case names of the
+	// attributes excluded from the segments of each integration, keyed by
+	// the lower case integration name.
+	integrationExcludes map[string]map[string]bool
+}
+
+// attributeLimits are the limits applied to user attributes.  They are
+// configured using Config.AttributeLimits.
+type attributeLimits struct {
+	count         int
+	keyLength     int
+	valueLength   int
+	rejectInvalid bool
+	// errorDepth is the number of levels of nested maps flattened in the
+	// extra attributes of errors.
+	errorDepth int
+}
+
+var defaultAttributeLimits = attributeLimits{
+	count:       attributeUserLimit,
+	keyLength:   attributeKeyLengthLimit,
+	valueLength: attributeValueLengthLimit,
+	errorDepth:  errorAttributeDepthDefault,
+}
+
+func attributeLimitsFromConfig(c Config) attributeLimits {
+	limits := defaultAttributeLimits
+	if n := c.AttributeLimits.MaxCount; n > 0 {
+		limits.count = n
+	}
+	if n := c.AttributeLimits.MaxKeyLength; n > 0 {
+		limits.keyLength = n
+	}
+	if n := c.AttributeLimits.MaxValueLength; n > 0 {
+		limits.valueLength = n
+	}
+	limits.rejectInvalid = c.AttributeLimits.RejectInvalidStrings
+	limits.errorDepth = c.ErrorCollector.MaxAttributeDepth
+	return limits
+}
+
+func (c *attributeConfig) attributeLimits() attributeLimits {
+	if nil == c || (attributeLimits{}) == c.limits {
+		return defaultAttributeLimits
+	}
+	return c.limits
 }
 
 type includeExclude struct {
@@ -217,6 +305,9 @@ func createAttributeConfig(input config, includeEnabled bool) *attributeConfig {
 
 	sort.Sort(byMatch(c.wildcardModifiers))
 
+	c.limits = attributeLimitsFromConfig(input.Config)
+	c.integrationExcludes = integrationExcludesFromConfig(input.IntegrationAttributes.Exclude)
+
 	c.agentDests = make(map[string]destinationSet)
 	for name, dest := range agentAttributeDefaultDests {
 		c.agentDests[name] = applyAttributeConfig(c, name, dest)
@@ -225,6 +316,23 @@ func createAttributeConfig(input config, includeEnabled bool) *attributeConfig {
 	return c
 }
 
+func integrationExcludesFromConfig(exclude map[string][]string) map[string]map[string]bool {
+	if 0 == len(exclude) {
+		return nil
+	}
+	excludes := make(map[string]map[string]bool, len(exclude))
+	for integration, names := range exclude {
+		integration = strings.ToLower(integration)
+		if nil == excludes[integration] {
+			excludes[integration] = make(map[string]bool, len(names))
+		}
+		for _, name := range names {
+			excludes[integration][strings.ToLower(name)] = true
+		}
+	}
+	return excludes
+}
+
 type userAttribute struct {
 	value interface{}
 	dests destinationSet
@@ -248,6 +356,29 @@ func (a *attributes) filterSpanAttributes(s map[string]jsonWriter, d destination
 	return s
 }
 
+// excludeIntegrationAttributes removes the attributes excluded from the
+// segments of the integration using Config.IntegrationAttributes.
+func (a *attributes) excludeIntegrationAttributes(integration string, s spanAttributeMap) {
+	excluded := a.integrationExcludes(integration)
+	if 0 == len(excluded) {
+		return
+	}
+	for key := range s {
+		if excluded[strings.ToLower(key)] {
+			delete(s, key)
+		}
+	}
+}
+
+// integrationExcludes returns the lower case names of the attributes excluded
+// from the segments of the integration, or nil if there are none.
+func (a *attributes) integrationExcludes(integration string) map[string]bool {
+	if nil == a || 0 == len(a.config.integrationExcludes) {
+		return nil
+	}
+	return a.config.integrationExcludes[strings.ToLower(integration)]
+}
+
 // GetAgentValue is used to access agent attributes.  This function returns ("",
 // nil) if the attribute doesn't exist or it doesn't match the destinations
 // provided.
@@ -264,6 +395,9 @@ func (a *attributes) GetAgentValue(id string, d destinationSet) (string, interfa
 // stringVal exists to avoid allocations.
 func (attr agentAttributes) Add(id string, stringVal string, otherVal interface{}) {
 	if "" != stringVal || otherVal != nil {
+		if !isValidString(stringVal) {
+			stringVal = coerceString(stringVal)
+		}
 		attr[id] = agentAttributeValue{
 			stringVal: truncateStringValueIfLong(stringVal),
 			otherVal:  otherVal,
@@ -276,6 +410,14 @@ type attributes struct {
 	config *attributeConfig
 	user   map[string]userAttribute
 	Agent  agentAttributes
+
+	// truncated and dropped count the user attributes whose values were
+	// truncated, or which were discarded, because of the attribute limits.
+	// coerced counts the user attributes whose string values contained
+	// invalid UTF-8 or control characters which were replaced.
+	truncated int
+	dropped   int
+	coerced   int
 }
 
 // newAttributes creates a new Attributes.
@@ -296,18 +438,32 @@ func (e errInvalidAttributeType) Error() string {
 	return fmt.Sprintf("attribute '%s' value of type %T is invalid", e.key, e.val)
 }
 
-type invalidAttributeKeyErr struct{ key string }
+type invalidAttributeKeyErr struct {
+	key   string
+	limit int
+}
 
 func (e invalidAttributeKeyErr) Error() string {
 	return fmt.Sprintf("attribute key '%.32s...' exceeds length limit %d",
-		e.key, attributeKeyLengthLimit)
+		e.key, e.limit)
 }
 
-type userAttributeLimitErr struct{ key string }
+type userAttributeLimitErr struct {
+	key   string
+	limit int
+}
 
 func (e userAttributeLimitErr) Error() string {
 	return fmt.Sprintf("attribute '%s' discarded: limit of %d reached", e.key,
-		attributeUserLimit)
+		e.limit)
+}
+
+type invalidStringAttrValue struct {
+	key string
+}
+
+func (e invalidStringAttrValue) Error() string {
+	return fmt.Sprintf("attribute '%s' of type string contains invalid UTF-8 or control characters", e.key)
 }
 
 type invalidFloatAttrValue struct {
@@ -327,10 +483,34 @@ func truncateStringValueIfLong(val string) string {
 	return val
 }
 
-// validateUserAttribute validates a user attribute.
+// validateUserAttribute validates a user attribute using the default limits.
 func validateUserAttribute(key string, val interface{}) (interface{}, error) {
-	if str, ok := val.(string); ok {
-		val = interface{}(truncateStringValueIfLong(str))
+	val, _, err := defaultAttributeLimits.validate(key, val)
+	return val, err
+}
+
+// attributeFixes records the changes made to a user attribute value by
+// attributeLimits.validate.
+type attributeFixes struct {
+	truncated bool
+	coerced   bool
+}
+
+// validate validates a user attribute.  String values containing invalid
+// UTF-8 or control characters are rejected or coerced, and string values
+// which exceed the value length limit are truncated.
+func (l attributeLimits) validate(key string, val interface{}) (v interface{}, fixes attributeFixes, err error) {
+	if str, ok := val.(string); ok && !isValidString(str) {
+		if l.rejectInvalid {
+			return nil, fixes, invalidStringAttrValue{key: key}
+		}
+		str = coerceString(str)
+		val = interface{}(str)
+		fixes.coerced = true
+	}
+	if str, ok := val.(string); ok && len(str) > l.valueLength {
+		val = interface{}(stringLengthByteLimit(str, l.valueLength))
+		fixes.truncated = true
 	}
 
 	switch v := val.(type) {
@@ -339,14 +519,14 @@ func validateUserAttribute(key string, val interface{}) (interface{}, error) {
 		uint, int, uintptr:
 	case float32:
 		if err := validateFloat(float64(v), key); err != nil {
-			return nil, err
+			return nil, fixes, err
 		}
 	case float64:
 		if err := validateFloat(v, key); err != nil {
-			return nil, err
+			return nil, fixes, err
 		}
 	default:
-		return nil, errInvalidAttributeType{
+		return nil, fixes, errInvalidAttributeType{
 			key: key,
 			val: val,
 		}
@@ -355,10 +535,42 @@ func validateUserAttribute(key string, val interface{}) (interface{}, error) {
 	// Attributes whose keys are excessively long are dropped rather than
 	// truncated to avoid worrying about the application of configuration to
 	// truncated values or performing the truncation after configuration.
-	if len(key) > attributeKeyLengthLimit {
-		return nil, invalidAttributeKeyErr{key: key}
+	if len(key) > l.keyLength {
+		return nil, fixes, invalidAttributeKeyErr{key: key, limit: l.keyLength}
+	}
+	return val, fixes, nil
+}
+
+// validateUserAttribute validates a user attribute using the configured
+// limits and counts the attributes which are truncated, coerced, or
+// dropped.
+func (a *attributes) validateUserAttribute(key string, val interface{}) (interface{}, error) {
+	val, fixes, err := a.config.attributeLimits().validate(key, val)
+	if fixes.truncated {
+		a.truncated++
+	}
+	if fixes.coerced {
+		a.coerced++
+	}
+	switch err.(type) {
+	case invalidAttributeKeyErr, invalidStringAttrValue:
+		a.dropped++
+	}
+	return val, err
+}
+
+// createLimitMetrics records the number of attributes truncated, coerced,
+// or dropped because of the attribute limits.
+func (a *attributes) createLimitMetrics(mt *metricTable) {
+	if a.truncated > 0 {
+		mt.addCount(supportAttributesTruncated, float64(a.truncated), forced)
+	}
+	if a.coerced > 0 {
+		mt.addCount(supportAttributesCoerced, float64(a.coerced), forced)
+	}
+	if a.dropped > 0 {
+		mt.addCount(supportAttributesDropped, float64(a.dropped), forced)
 	}
-	return val, nil
 }
 
 func validateFloat(v float64, key string) error {
@@ -373,7 +585,7 @@ func validateFloat(v float64, key string) error {
 
 // addUserAttribute adds a user attribute.
 func addUserAttribute(a *attributes, key string, val interface{}, d destinationSet) error {
-	val, err := validateUserAttribute(key, val)
+	val, err := a.validateUserAttribute(key, val)
 	if nil != err {
 		return err
 	}
@@ -385,8 +597,11 @@ func addUserAttribute(a *attributes, key string, val interface{}, d destinationS
 		a.user = make(map[string]userAttribute)
 	}
 
-	if _, exists := a.user[key]; !exists && len(a.user) >= attributeUserLimit {
-		return userAttributeLimitErr{key}
+	if limit := a.config.attributeLimits().count; len(a.user) >= limit {
+		if _, exists := a.user[key]; !exists {
+			a.dropped++
+			return userAttributeLimitErr{key: key, limit: limit}
+		}
 	}
 
 	// Note: Duplicates are overridden: last attribute in wins.
@@ -511,6 +726,77 @@ func requestAgentAttributes(a *attributes, method string, hdrs http.Header, u *u
 	}
 }
 
+// requestProtocolAgentAttributes gathers agent attributes describing the
+// protocol and connection used by the request.
+func requestProtocolAgentAttributes(a *attributes, proto string, state *tls.ConnectionState) {
+	a.Agent.Add(AttributeHTTPFlavor, httpFlavor(proto), nil)
+	if nil == state {
+		return
+	}
+	a.Agent.Add(AttributeTLSVersion, tlsVersionName(state.Version), nil)
+	a.Agent.Add(AttributeTLSALPN, state.NegotiatedProtocol, nil)
+}
+
+// httpFlavor returns the version from a request protocol, eg. "1.1" for
+// "HTTP/1.1" and "2" for "HTTP/2.0".  An empty string is returned if the
+// protocol is not HTTP.
+func httpFlavor(proto string) string {
+	if !strings.HasPrefix(proto, "HTTP/") {
+		return ""
+	}
+	version := strings.TrimPrefix(proto, "HTTP/")
+	if strings.HasSuffix(version, ".0") && !strings.HasPrefix(version, "1.") {
+		version = strings.TrimSuffix(version, ".0")
+	}
+	return version
+}
+
+func tlsVersionName(version uint16) string {
+	switch version {
+	case tls.VersionSSL30:
+		return "SSL 3.0"
+	case tls.VersionTLS10:
+		return "1.0"
+	case tls.VersionTLS11:
+		return "1.1"
+	case tls.VersionTLS12:
+		return "1.2"
+	case 0x0304:
+		// tls.VersionTLS13 is not defined before Go 1.12.
+		return "1.3"
+	}
+	return ""
+}
+
+// requestClientIP returns the IP address of the client which made the
+// request.  The first address in the X-Forwarded-For header is used if
+// trustForwardedFor is true, otherwise the address is taken from
+// remoteAddr.  Nil is returned if no address is found.
+func requestClientIP(remoteAddr string, hdrs http.Header, trustForwardedFor bool) net.IP {
+	if trustForwardedFor && nil != hdrs {
+		if forwarded := hdrs.Get("X-Forwarded-For"); "" != forwarded {
+			first := strings.TrimSpace(strings.Split(forwarded, ",")[0])
+			if ip := net.ParseIP(first); nil != ip {
+				return ip
+			}
+		}
+	}
+	host, _, err := net.SplitHostPort(remoteAddr)
+	if nil != err {
+		host = remoteAddr
+	}
+	return net.ParseIP(host)
+}
+
+// anonymizeIP zeroes the host portion of the address: the last octet of
+// IPv4 addresses and the last 80 bits of IPv6 addresses.
+func anonymizeIP(ip net.IP) net.IP {
+	if v4 := ip.To4(); nil != v4 {
+		return v4.Mask(net.CIDRMask(24, 32))
+	}
+	return ip.Mask(net.CIDRMask(48, 128))
+}
+
 // responseHeaderAttributes gather agent attributes from the response headers.
 func responseHeaderAttributes(a *attributes, h http.Header) {
 	if nil == h {
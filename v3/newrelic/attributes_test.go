@@ -5,7 +5,9 @@ package newrelic
 
 import (
 	"bytes"
+	"crypto/tls"
 	"encoding/json"
+	"net"
 	"net/http"
 	"strconv"
 	"strings"
@@ -399,6 +401,71 @@ func TestRequestAgentAttributesPresent(t *testing.T) {
 	})
 }
 
+func TestRequestProtocolAgentAttributes(t *testing.T) {
+	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
+	attrs := newAttributes(cfg)
+	requestProtocolAgentAttributes(attrs, "HTTP/2.0", &tls.ConnectionState{
+		Version:            tls.VersionTLS12,
+		NegotiatedProtocol: "h2",
+	})
+	got := agentAttributesMap(attrs, destAll)
+	expectAttributes(t, got, map[string]interface{}{
+		"http.flavor": "2",
+		"tls.version": "1.2",
+		"tls.alpn":    "h2",
+	})
+}
+
+func TestHTTPFlavor(t *testing.T) {
+	for proto, expect := range map[string]string{
+		"HTTP/1.0": "1.0",
+		"HTTP/1.1": "1.1",
+		"HTTP/2.0": "2",
+		"HTTP/3":   "3",
+		"":         "",
+		"SPDY/3":   "",
+	} {
+		if got := httpFlavor(proto); got != expect {
+			t.Errorf("proto=%q got=%q expect=%q", proto, got, expect)
+		}
+	}
+}
+
+func TestRequestClientIP(t *testing.T) {
+	hdrs := http.Header{"X-Forwarded-For": []string{"203.0.113.7, 10.0.0.1"}}
+	testcases := []struct {
+		remoteAddr        string
+		hdrs              http.Header
+		trustForwardedFor bool
+		expect            string
+	}{
+		{remoteAddr: "192.0.2.1:1234", expect: "192.0.2.1"},
+		{remoteAddr: "[2001:db8::1]:443", expect: "2001:db8::1"},
+		{remoteAddr: "192.0.2.1", expect: "192.0.2.1"},
+		{remoteAddr: "192.0.2.1:1234", hdrs: hdrs, expect: "192.0.2.1"},
+		{remoteAddr: "192.0.2.1:1234", hdrs: hdrs, trustForwardedFor: true, expect: "203.0.113.7"},
+		{remoteAddr: "192.0.2.1:1234", trustForwardedFor: true, expect: "192.0.2.1"},
+		{remoteAddr: "pipe", expect: "<nil>"},
+	}
+	for _, tc := range testcases {
+		ip := requestClientIP(tc.remoteAddr, tc.hdrs, tc.trustForwardedFor)
+		if got := ip.String(); got != tc.expect {
+			t.Errorf("remoteAddr=%q got=%q expect=%q", tc.remoteAddr, got, tc.expect)
+		}
+	}
+}
+
+func TestAnonymizeIP(t *testing.T) {
+	for addr, expect := range map[string]string{
+		"192.0.2.123":               "192.0.2.0",
+		"2001:db8:85a3:8d3:1319::1": "2001:db8:85a3::",
+	} {
+		if got := anonymizeIP(net.ParseIP(addr)).String(); got != expect {
+			t.Errorf("addr=%q got=%q expect=%q", addr, got, expect)
+		}
+	}
+}
+
 func BenchmarkAgentAttributes(b *testing.B) {
 	cfg := createAttributeConfig(config{Config: defaultConfig()}, true)
 
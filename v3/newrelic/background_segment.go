@@ -0,0 +1,106 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// BackgroundSegment times work done in a goroutine which may continue after
+// its Transaction has ended.  Create it using
+// Transaction.StartBackgroundSegment.
+type BackgroundSegment struct {
+	Name string
+
+	thread   *thread
+	start    time.Time
+	spanID   string
+	parentID string
+	ended    bool
+}
+
+// End finishes the segment.  End may be called from any goroutine, before
+// or after the Transaction has ended.  Calls after the first have no effect.
+func (s *BackgroundSegment) End() {
+	if nil == s || nil == s.thread {
+		return
+	}
+	s.thread.logAPIError(s.thread.endBackgroundSegment(s, time.Now()), "end background segment", nil)
+}
+
+func (thd *thread) startBackgroundSegment(s *BackgroundSegment) error {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if !txn.shouldCollectSpanEvents() {
+		return nil
+	}
+	s.parentID = txn.CurrentSpanIdentifier(thd.thread)
+	s.spanID = txn.TraceIDGenerator.GenerateSpanID()
+	return nil
+}
+
+// endBackgroundSegment records the span event of the segment.  The span of a
+// segment which ends before the transaction is added to the transaction's
+// span events.  Otherwise it is sent on its own, provided the transaction's
+// span events were kept.
+func (thd *thread) endBackgroundSegment(s *BackgroundSegment, now time.Time) error {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+
+	if s.ended {
+		return nil
+	}
+	s.ended = true
+	if "" == s.spanID {
+		return nil
+	}
+
+	evt := &spanEvent{
+		GUID:      s.spanID,
+		ParentID:  s.parentID,
+		Timestamp: s.start,
+		Duration:  now.Sub(s.start),
+		Name:      customSegmentMetric(s.Name),
+		Category:  spanCategoryGeneric,
+	}
+	evt.AgentAttributes.addBool(SpanAttributeAsync, true)
+
+	if !txn.finished {
+		txn.saveSpanEvent(evt)
+		return nil
+	}
+	if txn.ignore || !txn.shouldCollectSpanEvents() {
+		return nil
+	}
+	evt.AgentAttributes = txn.Attrs.filterSpanAttributes(evt.AgentAttributes, destSpan)
+	evt.TraceID = txn.BetterCAT.TraceID
+	evt.TransactionID = txn.BetterCAT.TxnID
+	evt.Sampled = txn.BetterCAT.Sampled
+	evt.Priority = txn.BetterCAT.Priority
+	evt.OwnerTxnName = txn.FinalName
+
+	consumer, runID := txn.consumer()
+	if observer := consumer.getObserver(); nil != observer {
+		observer.consumeSpan(evt)
+	}
+	if !shouldUseTraceObserver(txn.Config) {
+		consumer.Consume(runID, backgroundSpan{evt})
+	}
+	return nil
+}
+
+// backgroundSpan is the span event of a BackgroundSegment which ended after
+// its transaction.
+type backgroundSpan struct {
+	*spanEvent
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (s backgroundSpan) MergeIntoHarvest(h *harvest) {
+	h.SpanEvents.addEventPopulated(s.spanEvent)
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func backgroundSegmentTestApp(replyfn func(*internal.ConnectReply), t *testing.T) expectApp {
+	return testApp(func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		replyfn(reply)
+	}, enableBetterCAT, t)
+}
+
+func TestBackgroundSegmentEndedBeforeTransaction(t *testing.T) {
+	app := backgroundSegmentTestApp(func(reply *internal.ConnectReply) { reply.SetSampleEverything() }, t)
+	txn := app.StartTransaction("hello")
+	segment := txn.StartBackgroundSegment("work")
+	segment.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/work",
+				"sampled":  true,
+				"category": "generic",
+				"priority": internal.MatchAnything,
+				"guid":     internal.MatchAnything,
+				"parentId": internal.MatchAnything,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{"async": true},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestBackgroundSegmentEndedAfterTransaction(t *testing.T) {
+	app := backgroundSegmentTestApp(func(reply *internal.ConnectReply) { reply.SetSampleEverything() }, t)
+	txn := app.StartTransaction("hello")
+	parent := txn.StartSegment("parent")
+	segment := txn.StartBackgroundSegment("work")
+	parent.End()
+	txn.End()
+	segment.End()
+	// Later calls to End have no effect.
+	segment.End()
+	app.expectNoLoggedErrors(t)
+
+	var parentID, traceID string
+	for _, evt := range internalApp(app).testHarvest.SpanEvents.events {
+		span := evt.jsonWriter.(*spanEvent)
+		if "Custom/parent" == span.Name {
+			parentID = span.GUID
+			traceID = span.TraceID
+		}
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/parent",
+				"sampled":  true,
+				"category": "generic",
+				"priority": internal.MatchAnything,
+				"guid":     parentID,
+				"parentId": internal.MatchAnything,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/work",
+				"sampled":  true,
+				"category": "generic",
+				"priority": internal.MatchAnything,
+				"guid":     internal.MatchAnything,
+				"parentId": parentID,
+				"traceId":  traceID,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{"async": true},
+		},
+	})
+}
+
+func TestBackgroundSegmentNotSampled(t *testing.T) {
+	app := backgroundSegmentTestApp(func(reply *internal.ConnectReply) { reply.SetSampleNothing() }, t)
+	txn := app.StartTransaction("hello")
+	segment := txn.StartBackgroundSegment("work")
+	txn.End()
+	segment.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectSpanEvents(t, []internal.WantEvent{})
+}
+
+func TestBackgroundSegmentNilTransaction(t *testing.T) {
+	var txn *Transaction
+	segment := txn.StartBackgroundSegment("work")
+	segment.End()
+	var nilSegment *BackgroundSegment
+	nilSegment.End()
+}
@@ -0,0 +1,204 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// These segment attributes are added to the segments created by Bulkhead.
+const (
+	// BulkheadAttributeWait is the time in seconds spent waiting for a
+	// slot.
+	BulkheadAttributeWait = "bulkhead.wait"
+	// BulkheadAttributeInUse is the number of slots in use once the slot
+	// was acquired.
+	BulkheadAttributeInUse = "bulkhead.inUse"
+	// BulkheadAttributeCapacity is the number of slots.
+	BulkheadAttributeCapacity = "bulkhead.capacity"
+	// BulkheadAttributeRejected is true if no slot was acquired.
+	BulkheadAttributeRejected = "bulkhead.rejected"
+)
+
+var errBulkheadOverReleased = errors.New("released more times than acquired")
+
+// Bulkhead is a counting semaphore which limits the number of concurrent
+// operations, such as calls to a downstream service or jobs run by a worker
+// pool, and records how long callers queue for it.  It records the
+// following metrics:
+//
+//	Bulkhead/{name}/Wait         time spent waiting to acquire a slot
+//	Bulkhead/{name}/Utilization  fraction of slots in use once a slot is
+//	                             acquired
+//	Bulkhead/{name}/Rejected     attempts which did not acquire a slot
+//
+// When the context passed to Acquire or TryAcquire contains a Transaction
+// (see NewContext), the attempt is recorded as a segment named
+// "Bulkhead/{name}/Acquire" with the BulkheadAttributeWait,
+// BulkheadAttributeInUse, BulkheadAttributeCapacity, and
+// BulkheadAttributeRejected attributes.
+//
+// To bound a worker pool, acquire a slot before starting each job:
+//
+//	pool := newrelic.NewBulkhead(app, "thumbnails", 8)
+//	if err := pool.Acquire(ctx); err != nil {
+//		return err
+//	}
+//	go func() {
+//		defer pool.Release()
+//		makeThumbnail(img)
+//	}()
+//
+// All methods on Bulkhead are safe for concurrent use.
+type Bulkhead struct {
+	app   *Application
+	name  string
+	slots chan struct{}
+	// overReleased ensures that calls to Release which do not match an
+	// acquired slot are logged once.
+	overReleased sync.Once
+}
+
+// NewBulkhead creates a Bulkhead with the given number of slots.  The name
+// identifies the bulkhead in metrics.  If capacity is less than 1 then the
+// Bulkhead has a single slot.  The Application may be nil, in which case
+// no metrics are recorded.
+func NewBulkhead(app *Application, name string, capacity int) *Bulkhead {
+	if capacity < 1 {
+		capacity = 1
+	}
+	return &Bulkhead{
+		app:   app,
+		name:  name,
+		slots: make(chan struct{}, capacity),
+	}
+}
+
+// Capacity returns the number of slots.
+func (b *Bulkhead) Capacity() int { return cap(b.slots) }
+
+// InUse returns the number of slots currently acquired.
+func (b *Bulkhead) InUse() int { return len(b.slots) }
+
+// Acquire waits until a slot is available or the context is done.  If
+// the context is done first then the context's error is returned and the
+// attempt is counted as rejected.  Each successful Acquire must be
+// followed by a call to Release.
+func (b *Bulkhead) Acquire(ctx context.Context) error {
+	start := time.Now()
+	seg := b.startSegment(ctx)
+	select {
+	case b.slots <- struct{}{}:
+		b.acquired(seg, start)
+		return nil
+	default:
+	}
+	select {
+	case b.slots <- struct{}{}:
+		b.acquired(seg, start)
+		return nil
+	case <-ctx.Done():
+		b.rejected(seg, start)
+		return ctx.Err()
+	}
+}
+
+// TryAcquire acquires a slot if one is available without waiting.  If no
+// slot is available then false is returned and the attempt is counted as
+// rejected.
+func (b *Bulkhead) TryAcquire(ctx context.Context) bool {
+	start := time.Now()
+	seg := b.startSegment(ctx)
+	select {
+	case b.slots <- struct{}{}:
+		b.acquired(seg, start)
+		return true
+	default:
+		b.rejected(seg, start)
+		return false
+	}
+}
+
+// Release releases a slot acquired using Acquire or TryAcquire.  Calling
+// Release when no slot is acquired has no effect, and is logged as an error
+// the first time it happens.
+func (b *Bulkhead) Release() {
+	select {
+	case <-b.slots:
+	default:
+		b.overReleased.Do(func() {
+			if nil == b.app || nil == b.app.app {
+				return
+			}
+			b.app.app.Error("unable to release bulkhead slot", map[string]interface{}{
+				"name":   b.name,
+				"reason": errBulkheadOverReleased.Error(),
+			})
+		})
+	}
+}
+
+func (b *Bulkhead) startSegment(ctx context.Context) *Segment {
+	txn := FromContext(ctx)
+	if nil == txn {
+		return nil
+	}
+	return txn.StartSegment("Bulkhead/" + b.name + "/Acquire")
+}
+
+func (b *Bulkhead) acquired(seg *Segment, start time.Time) {
+	wait := time.Since(start)
+	inUse := len(b.slots)
+	if nil != seg {
+		seg.AddAttribute(BulkheadAttributeWait, wait.Seconds())
+		seg.AddAttribute(BulkheadAttributeInUse, inUse)
+		seg.AddAttribute(BulkheadAttributeCapacity, cap(b.slots))
+		seg.End()
+	}
+	b.consume(bulkheadMetrics{
+		name:        b.name,
+		wait:        wait,
+		utilization: float64(inUse) / float64(cap(b.slots)),
+	})
+}
+
+func (b *Bulkhead) rejected(seg *Segment, start time.Time) {
+	if nil != seg {
+		seg.AddAttribute(BulkheadAttributeWait, time.Since(start).Seconds())
+		seg.AddAttribute(BulkheadAttributeCapacity, cap(b.slots))
+		seg.AddAttribute(BulkheadAttributeRejected, true)
+		seg.End()
+	}
+	b.consume(bulkheadMetrics{name: b.name, rejected: true})
+}
+
+func (b *Bulkhead) consume(m bulkheadMetrics) {
+	if nil == b.app || nil == b.app.app {
+		return
+	}
+	run, _ := b.app.app.getState()
+	b.app.app.Consume(run.Reply.RunID, m)
+}
+
+// bulkheadMetrics records an attempt to acquire a slot of a Bulkhead.
+type bulkheadMetrics struct {
+	name        string
+	wait        time.Duration
+	utilization float64
+	rejected    bool
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (m bulkheadMetrics) MergeIntoHarvest(h *harvest) {
+	prefix := "Bulkhead/" + metricNameSegment(m.name)
+	if m.rejected {
+		h.Metrics.addSingleCount(prefix+"/Rejected", unforced)
+		return
+	}
+	h.Metrics.addDuration(prefix+"/Wait", "", m.wait, m.wait, unforced)
+	h.Metrics.addValueExclusive(prefix+"/Utilization", "", m.utilization, 0, unforced)
+}
@@ -0,0 +1,141 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestBulkheadAcquireRelease(t *testing.T) {
+	app := testApp(nil, nil, t)
+	b := NewBulkhead(app.Application, "db", 2)
+	if err := b.Acquire(context.Background()); nil != err {
+		t.Fatal(err)
+	}
+	if err := b.Acquire(context.Background()); nil != err {
+		t.Fatal(err)
+	}
+	if b.InUse() != 2 || b.Capacity() != 2 {
+		t.Error(b.InUse(), b.Capacity())
+	}
+	if b.TryAcquire(context.Background()) {
+		t.Error("bulkhead should be full")
+	}
+	b.Release()
+	b.Release()
+	if b.InUse() != 0 {
+		t.Error(b.InUse())
+	}
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Bulkhead/db/Wait", Scope: "", Forced: false, Data: nil},
+		{Name: "Bulkhead/db/Utilization", Scope: "", Forced: false, Data: []float64{2, 1.5, 0, 0.5, 1, 1.25}},
+		{Name: "Bulkhead/db/Rejected", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestBulkheadAcquireContextDone(t *testing.T) {
+	app := testApp(nil, nil, t)
+	b := NewBulkhead(app.Application, "db", 1)
+	if !b.TryAcquire(context.Background()) {
+		t.Fatal("bulkhead should be empty")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := b.Acquire(ctx); err != context.DeadlineExceeded {
+		t.Error(err)
+	}
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Bulkhead/db/Wait", Scope: "", Forced: false, Data: nil},
+		{Name: "Bulkhead/db/Utilization", Scope: "", Forced: false, Data: []float64{1, 1, 0, 1, 1, 1}},
+		{Name: "Bulkhead/db/Rejected", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestBulkheadAcquireWaitsForRelease(t *testing.T) {
+	b := NewBulkhead(nil, "db", 1)
+	b.Acquire(context.Background())
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		b.Release()
+	}()
+	if err := b.Acquire(context.Background()); nil != err {
+		t.Error(err)
+	}
+	if b.InUse() != 1 {
+		t.Error(b.InUse())
+	}
+}
+
+func TestBulkheadSegments(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	b := NewBulkhead(app.Application, "payments/api", 1)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+	b.Acquire(ctx)
+	b.TryAcquire(ctx)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/Bulkhead/payments/api/Acquire",
+				"parentId": internal.MatchAnything,
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{
+				BulkheadAttributeWait:     internal.MatchAnything,
+				BulkheadAttributeInUse:    1,
+				BulkheadAttributeCapacity: 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/Bulkhead/payments/api/Acquire",
+				"parentId": internal.MatchAnything,
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{
+				BulkheadAttributeWait:     internal.MatchAnything,
+				BulkheadAttributeCapacity: 1,
+				BulkheadAttributeRejected: true,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Bulkhead/payments_api/Wait", Scope: "", Forced: false, Data: nil},
+		{Name: "Bulkhead/payments_api/Rejected", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestBulkheadReleaseWithoutAcquire(t *testing.T) {
+	NewBulkhead(nil, "db", 0).Release()
+
+	app := testApp(nil, nil, t)
+	b := NewBulkhead(app.Application, "db", 1)
+	b.Release()
+	b.Release()
+	if b.InUse() != 0 {
+		t.Error(b.InUse())
+	}
+	if !b.TryAcquire(context.Background()) || b.TryAcquire(context.Background()) {
+		t.Error("over release changed the capacity")
+	}
+	app.expectSingleLoggedError(t, "unable to release bulkhead slot", map[string]interface{}{
+		"name":   "db",
+		"reason": errBulkheadOverReleased.Error(),
+	})
+}
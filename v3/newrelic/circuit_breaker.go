@@ -0,0 +1,107 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+)
+
+// CircuitBreakerState is the state of a client-side circuit breaker,
+// reported using Application.RecordCircuitBreakerTransition.
+type CircuitBreakerState string
+
+// These states are used by the circuit breaker integrations.  Other states
+// may be reported if a circuit breaker library uses them.
+const (
+	// CircuitBreakerClosed is the state of a circuit breaker which allows
+	// calls to the downstream service.
+	CircuitBreakerClosed CircuitBreakerState = "closed"
+	// CircuitBreakerOpen is the state of a circuit breaker which rejects
+	// calls to the downstream service.
+	CircuitBreakerOpen CircuitBreakerState = "open"
+	// CircuitBreakerHalfOpen is the state of a circuit breaker which allows
+	// a limited number of calls to test whether the downstream service has
+	// recovered.
+	CircuitBreakerHalfOpen CircuitBreakerState = "half-open"
+)
+
+const (
+	// circuitBreakerEventType is the type of the custom event recorded for
+	// each circuit breaker state transition.
+	circuitBreakerEventType = "CircuitBreakerStateChange"
+)
+
+var (
+	errCircuitBreakerServiceEmpty = errors.New("missing circuit breaker service name")
+	errCircuitBreakerStateEmpty   = errors.New("missing circuit breaker state")
+)
+
+func circuitBreakerMetricPrefix(service string) string {
+	return "CircuitBreaker/" + metricNameSegment(service)
+}
+
+// circuitBreakerMetrics records a circuit breaker state transition or
+// short-circuited calls.
+type circuitBreakerMetrics struct {
+	service        string
+	to             CircuitBreakerState
+	shortCircuited int
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (m circuitBreakerMetrics) MergeIntoHarvest(h *harvest) {
+	prefix := circuitBreakerMetricPrefix(m.service)
+	if "" != m.to {
+		h.Metrics.addSingleCount(prefix+"/State/"+string(m.to), unforced)
+	}
+	if m.shortCircuited > 0 {
+		h.Metrics.addCount(prefix+"/ShortCircuited", float64(m.shortCircuited), unforced)
+		h.Metrics.addCount(circuitBreakerShortCircuitedAll, float64(m.shortCircuited), unforced)
+	}
+}
+
+func (app *app) RecordCircuitBreakerTransition(service string, from, to CircuitBreakerState) error {
+	if nil == app {
+		return nil
+	}
+	if "" == service {
+		return errCircuitBreakerServiceEmpty
+	}
+	if "" == to {
+		return errCircuitBreakerStateEmpty
+	}
+	run, _ := app.getState()
+	app.Consume(run.Reply.RunID, circuitBreakerMetrics{service: service, to: to})
+
+	// The metrics are recorded even if custom events are disabled.
+	params := map[string]interface{}{
+		"service": service,
+		"to":      string(to),
+	}
+	if "" != from {
+		params["from"] = string(from)
+	}
+	if err := app.RecordCustomEvent(circuitBreakerEventType, params); nil != err {
+		app.Debug("unable to record circuit breaker event", map[string]interface{}{
+			"service": service,
+			"reason":  err.Error(),
+		})
+	}
+	return nil
+}
+
+func (app *app) RecordCircuitBreakerShortCircuits(service string, count int) error {
+	if nil == app {
+		return nil
+	}
+	if "" == service {
+		return errCircuitBreakerServiceEmpty
+	}
+	if count <= 0 {
+		return nil
+	}
+	run, _ := app.getState()
+	app.Consume(run.Reply.RunID, circuitBreakerMetrics{service: service, shortCircuited: count})
+	return nil
+}
@@ -0,0 +1,86 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestRecordCircuitBreakerTransition(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.RecordCircuitBreakerTransition("payments", CircuitBreakerClosed, CircuitBreakerOpen)
+	app.RecordCircuitBreakerTransition("payments", CircuitBreakerOpen, CircuitBreakerHalfOpen)
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments/State/open", Scope: "", Forced: false, Data: singleCount},
+		{Name: "CircuitBreaker/payments/State/half-open", Scope: "", Forced: false, Data: singleCount},
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "CircuitBreakerStateChange",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"service": "payments",
+			"from":    "closed",
+			"to":      "open",
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "CircuitBreakerStateChange",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"service": "payments",
+			"from":    "open",
+			"to":      "half-open",
+		},
+	}})
+}
+
+func TestRecordCircuitBreakerTransitionCustomEventsDisabled(t *testing.T) {
+	cfgfn := func(cfg *Config) { cfg.CustomInsightsEvents.Enabled = false }
+	app := testApp(nil, cfgfn, t)
+	app.RecordCircuitBreakerTransition("payments/v1", "", CircuitBreakerOpen)
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments_v1/State/open", Scope: "", Forced: false, Data: singleCount},
+	})
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+func TestRecordCircuitBreakerTransitionInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.RecordCircuitBreakerTransition("", CircuitBreakerClosed, CircuitBreakerOpen)
+	app.expectSingleLoggedError(t, "unable to record circuit breaker transition", map[string]interface{}{
+		"service": "",
+		"reason":  errCircuitBreakerServiceEmpty.Error(),
+	})
+
+	app = testApp(nil, nil, t)
+	app.RecordCircuitBreakerTransition("payments", CircuitBreakerClosed, "")
+	app.expectSingleLoggedError(t, "unable to record circuit breaker transition", map[string]interface{}{
+		"service": "payments",
+		"reason":  errCircuitBreakerStateEmpty.Error(),
+	})
+}
+
+func TestRecordCircuitBreakerShortCircuits(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.RecordCircuitBreakerShortCircuits("payments", 3)
+	app.RecordCircuitBreakerShortCircuits("payments", 0)
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "CircuitBreaker/payments/ShortCircuited", Scope: "", Forced: false, Data: []float64{3, 0, 0, 0, 0, 0}},
+		{Name: "CircuitBreaker/all/ShortCircuited", Scope: "", Forced: false, Data: []float64{3, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestRecordCircuitBreakerNilApplication(t *testing.T) {
+	var app *Application
+	app.RecordCircuitBreakerTransition("payments", CircuitBreakerClosed, CircuitBreakerOpen)
+	app.RecordCircuitBreakerShortCircuits("payments", 1)
+}
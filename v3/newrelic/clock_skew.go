@@ -0,0 +1,37 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// clockSkewTolerance is the largest difference between the wall clock and
+// the monotonic clock elapsed during a transaction which is not reported as
+// clock skew.  NTP slews the wall clock by at most 500 parts per million, so
+// larger differences are steps of the wall clock, eg. by NTP or after the
+// live migration of a virtual machine.
+const clockSkewTolerance = 100 * time.Millisecond
+
+// measureClockSkew returns how far the wall clock jumped relative to the
+// monotonic clock, given the time elapsed according to each: positive if it
+// jumped forwards and negative if it jumped backwards.  It returns zero when
+// the difference is within clockSkewTolerance.
+func measureClockSkew(wall, monotonic time.Duration) time.Duration {
+	skew := wall - monotonic
+	if skew > -clockSkewTolerance && skew < clockSkewTolerance {
+		return 0
+	}
+	return skew
+}
+
+// correctTime returns the wall clock time at which now happened as seen from
+// the start of the transaction: the start plus the monotonic clock elapsed
+// since.  Every time recorded by the transaction is corrected so that its
+// timestamps, eg. those of span events, remain consistent with its start and
+// its duration if the wall clock jumps during the transaction.
+func (t *txnData) correctTime(now time.Time) time.Time {
+	if t.Start.IsZero() {
+		return now
+	}
+	return t.Start.Add(now.Sub(t.Start))
+}
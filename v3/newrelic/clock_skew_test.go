@@ -0,0 +1,94 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestMeasureClockSkew(t *testing.T) {
+	testcases := []struct {
+		wall, monotonic, skew time.Duration
+	}{
+		{wall: 5 * time.Second, monotonic: 5 * time.Second, skew: 0},
+		{wall: 5*time.Second + 50*time.Millisecond, monotonic: 5 * time.Second, skew: 0},
+		{wall: 4*time.Second + 950*time.Millisecond, monotonic: 5 * time.Second, skew: 0},
+		{wall: 65 * time.Second, monotonic: 5 * time.Second, skew: 60 * time.Second},
+		{wall: -55 * time.Second, monotonic: 5 * time.Second, skew: -60 * time.Second},
+	}
+	for _, tc := range testcases {
+		if skew := measureClockSkew(tc.wall, tc.monotonic); skew != tc.skew {
+			t.Errorf("wall=%v monotonic=%v: got %v want %v", tc.wall, tc.monotonic, skew, tc.skew)
+		}
+	}
+}
+
+func TestCorrectTime(t *testing.T) {
+	var data txnData
+	now := time.Now()
+	if got := data.correctTime(now); !got.Equal(now) {
+		t.Error("time changed before the transaction started", got, now)
+	}
+	data.Start = now
+	later := now.Add(2 * time.Second)
+	if got := data.correctTime(later); !got.Equal(later) {
+		t.Error(got, later)
+	}
+}
+
+func TestMarkEndWallClockBackwards(t *testing.T) {
+	// Times without monotonic clock readings are compared using the wall
+	// clock, which here jumps backwards during the transaction.
+	start := time.Unix(1000, 0)
+	txn := &txn{}
+	txn.markStart(start)
+	txn.markEnd(start.Add(-10*time.Second), &txn.mainThread)
+	if txn.Duration != 0 {
+		t.Error("negative duration not corrected", txn.Duration)
+	}
+	if !txn.Stop.Equal(start) {
+		t.Error(txn.Stop)
+	}
+	if txn.ClockSkew != -10*time.Second {
+		t.Error(txn.ClockSkew)
+	}
+}
+
+func TestMarkEndNoClockSkew(t *testing.T) {
+	start := time.Now()
+	txn := &txn{}
+	txn.markStart(start)
+	txn.markEnd(start.Add(3*time.Second), &txn.mainThread)
+	if txn.Duration != 3*time.Second || txn.ClockSkew != 0 {
+		t.Error(txn.Duration, txn.ClockSkew)
+	}
+}
+
+func TestCreateTxnMetricsClockSkew(t *testing.T) {
+	args := &txnData{}
+	args.FinalName = "OtherTransaction/Go/hello"
+	args.Duration = 3 * time.Second
+
+	args.ClockSkew = -2 * time.Second
+	metrics := newMetricTable(100, time.Now())
+	createTxnMetrics(args, metrics)
+	expectMetricsPresent(t, metrics, []internal.WantMetric{
+		{Name: supportClockSkewBackward, Scope: "", Forced: true, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+	if _, ok := metrics.metrics[metricID{Name: supportClockSkewForward}]; ok {
+		t.Error("forward clock skew recorded")
+	}
+
+	args.ClockSkew = 0
+	metrics = newMetricTable(100, time.Now())
+	createTxnMetrics(args, metrics)
+	for _, name := range []string{supportClockSkewForward, supportClockSkewBackward} {
+		if _, ok := metrics.metrics[metricID{Name: name}]; ok {
+			t.Error("clock skew recorded", name)
+		}
+	}
+}
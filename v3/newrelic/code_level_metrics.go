@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"strings"
+)
+
+// codeLevelAgentPrefix is the prefix of the functions of the agent and its
+// integrations, which are never reported as the code location of a segment.
+const codeLevelAgentPrefix = "github.com/newrelic/go-agent/"
+
+// codeLevelMetrics holds the Config.CodeLevelMetrics settings of a
+// transaction.
+type codeLevelMetrics struct {
+	enabled         bool
+	depth           int
+	ignoredPrefixes []string
+}
+
+func newCodeLevelMetrics(c Config) codeLevelMetrics {
+	return codeLevelMetrics{
+		enabled:         c.CodeLevelMetrics.Enabled,
+		depth:           c.CodeLevelMetrics.Depth,
+		ignoredPrefixes: c.CodeLevelMetrics.IgnoredPrefixes,
+	}
+}
+
+// ignored returns true if the frame belongs to the agent or to one of the
+// ignored packages.  The agent's tests are treated as application code.
+func (c codeLevelMetrics) ignored(frame runtime.Frame) bool {
+	if strings.HasPrefix(frame.Function, codeLevelAgentPrefix) {
+		return !strings.HasSuffix(frame.File, "_test.go")
+	}
+	for _, prefix := range c.ignoredPrefixes {
+		if strings.HasPrefix(frame.Function, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// addAttributes adds the location of the code which started the segment to
+// the segment's attributes.  It must be called by startSegment, since the
+// frames of addAttributes and its caller are skipped.
+func (c codeLevelMetrics) addAttributes(attrs *spanAttributeMap) {
+	if c.depth <= 0 {
+		return
+	}
+	pcs := make([]uintptr, c.depth)
+	// Skip runtime.Callers, addAttributes, and startSegment.
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for n > 0 {
+		frame, more := frames.Next()
+		if !c.ignored(frame) {
+			attrs.addString(SpanAttributeCodeFunction, frame.Function)
+			attrs.addString(SpanAttributeCodeFilepath, frame.File)
+			attrs.addInt(SpanAttributeCodeLineno, frame.Line)
+			return
+		}
+		if !more {
+			return
+		}
+	}
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestCodeLevelMetricsAttributes(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	app := testApp(replyfn, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.CodeLevelMetrics.Enabled = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	_, file, line, _ := runtime.Caller(0)
+	txn.StartSegment("basic").End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":     "Custom/basic",
+				"parentId": internal.MatchAnything,
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"code.function": "github.com/newrelic/go-agent/v3/newrelic.TestCodeLevelMetricsAttributes",
+				"code.filepath": file,
+				"code.lineno":   line + 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestCodeLevelMetricsDisabled(t *testing.T) {
+	thd := &thread{txn: &txn{}, thread: &tracingThread{}}
+	startSegment(&thd.txnData, thd.thread, thd.Start)
+	if attrs := thd.thread.stack[0].agentAttributes; 0 != len(attrs) {
+		t.Error(attrs)
+	}
+}
+
+func TestCodeLevelMetricsNotSampled(t *testing.T) {
+	thd := &thread{txn: &txn{}, thread: &tracingThread{}}
+	thd.codeLevelMetrics = newCodeLevelMetrics(defaultConfig())
+	thd.codeLevelMetrics.enabled = true
+	thd.ShouldCollectSpanEvents = func() bool { return false }
+	startSegment(&thd.txnData, thd.thread, thd.Start)
+	if attrs := thd.thread.stack[0].agentAttributes; 0 != len(attrs) {
+		t.Error(attrs)
+	}
+}
+
+func TestCodeLevelMetricsIgnored(t *testing.T) {
+	c := newCodeLevelMetrics(defaultConfig())
+	for _, tc := range []struct {
+		frame   runtime.Frame
+		ignored bool
+	}{
+		{frame: runtime.Frame{Function: "github.com/newrelic/go-agent/v3/newrelic.startSegment", File: "tracing.go"}, ignored: true},
+		{frame: runtime.Frame{Function: "github.com/newrelic/go-agent/v3/integrations/nrmysql.wrap", File: "nrmysql.go"}, ignored: true},
+		{frame: runtime.Frame{Function: "github.com/newrelic/go-agent/v3/newrelic.TestX", File: "x_test.go"}, ignored: false},
+		{frame: runtime.Frame{Function: "database/sql.(*DB).QueryContext", File: "sql.go"}, ignored: true},
+		{frame: runtime.Frame{Function: "net/http.(*Client).Do", File: "client.go"}, ignored: true},
+		{frame: runtime.Frame{Function: "main.handler", File: "main.go"}, ignored: false},
+	} {
+		if ignored := c.ignored(tc.frame); ignored != tc.ignored {
+			t.Error(tc.frame.Function, ignored)
+		}
+	}
+}
@@ -13,16 +13,13 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"time"
 
 	"github.com/newrelic/go-agent/v3/internal"
 	"github.com/newrelic/go-agent/v3/internal/logger"
 )
 
 const (
-	// procotolVersion is the protocol version used to communicate with NR
-	// backend.
-	procotolVersion = 17
-
 	userAgentPrefix = "NewRelic-Go-Agent/"
 
 	// Methods used in collector communication.
@@ -36,6 +33,7 @@ const (
 	cmdTxnTraces    = "transaction_sample_data"
 	cmdSlowSQLs     = "sql_trace_data"
 	cmdSpanEvents   = "span_event_data"
+	cmdLogEvents    = "log_event_data"
 )
 
 // rpmCmd contains fields specific to an individual call made to RPM.
@@ -54,6 +52,15 @@ type rpmControls struct {
 	License string
 	Client  *http.Client
 	Logger  logger.Logger
+	// Sender, if non-nil, is used in place of the Client.
+	Sender HarvestSender
+	// Gateway is true when requests are sent to Config.Gateway.Address,
+	// which uses plain HTTP and does not require the License.
+	Gateway      bool
+	GatewayToken string
+	// ResponseHandler, if non-nil, is called with the outcome of each
+	// request.
+	ResponseHandler func(CollectorResponse)
 }
 
 // rpmResponse contains a NR endpoint response.
@@ -79,6 +86,9 @@ type rpmResponse struct {
 	disconnectSecurityPolicy bool
 	// forceSaveHarvestData overrides the status code and forces a save of data
 	forceSaveHarvestData bool
+	// failover indicates that the application should reconnect using a
+	// different collector host.
+	failover bool
 }
 
 func newRPMResponse(statusCode int) rpmResponse {
@@ -100,6 +110,23 @@ func (resp rpmResponse) IsRestartException() bool {
 		resp.statusCode == 409
 }
 
+// IsFailover indicates that the agent should reconnect to a different
+// collector host.
+func (resp rpmResponse) IsFailover() bool {
+	return resp.failover
+}
+
+// isCollectorUnavailable indicates that the request failed because the
+// collector could not be reached or had a server error.
+func (resp rpmResponse) isCollectorUnavailable() bool {
+	if nil == resp.Err {
+		return false
+	}
+	// forceSaveHarvestData is only set when the request could not be
+	// completed.
+	return resp.forceSaveHarvestData || resp.statusCode >= 500
+}
+
 // ShouldSaveHarvestData indicates that the agent should save the data and try
 // to send it in the next harvest.
 func (resp rpmResponse) ShouldSaveHarvestData() bool {
@@ -120,12 +147,17 @@ func rpmURL(cmd rpmCmd, cs rpmControls) string {
 	u.Host = cmd.Collector
 	u.Path = "agent_listener/invoke_raw_method"
 	u.Scheme = "https"
+	if cs.Gateway {
+		u.Scheme = "http"
+	}
 
 	query := url.Values{}
 	query.Set("marshal_format", "json")
-	query.Set("protocol_version", strconv.Itoa(procotolVersion))
+	query.Set("protocol_version", strconv.Itoa(CollectorProtocolVersion))
 	query.Set("method", cmd.Name)
-	query.Set("license_key", cs.License)
+	if !cs.Gateway || "" != cs.License {
+		query.Set("license_key", cs.License)
+	}
 
 	if len(cmd.RunID) > 0 {
 		query.Set("run_id", cmd.RunID)
@@ -158,6 +190,7 @@ func collectorRequestInternal(url string, cmd rpmCmd, cs rpmControls) rpmRespons
 		return rpmResponse{Err: fmt.Errorf("Payload size for %s too large: %d greater than %d", cmd.Name, l, cmd.MaxPayloadSize)}
 	}
 
+	payload := compressed.Bytes()
 	req, err := http.NewRequest("POST", url, compressed)
 	if nil != err {
 		return rpmResponse{Err: err}
@@ -170,6 +203,13 @@ func collectorRequestInternal(url string, cmd rpmCmd, cs rpmControls) rpmRespons
 	for k, v := range cmd.RequestHeadersMap {
 		req.Header.Add(k, v)
 	}
+	if "" != cs.GatewayToken {
+		req.Header.Set(gatewayTokenHeader, cs.GatewayToken)
+	}
+
+	if nil != cs.Sender {
+		return harvestSenderRequest(cs.Sender, cmd, req, payload)
+	}
 
 	resp, err := cs.Client.Do(req)
 	if err != nil {
@@ -209,7 +249,11 @@ func collectorRequest(cmd rpmCmd, cs rpmControls) rpmResponse {
 		})
 	}
 
+	start := time.Now()
 	resp := collectorRequestInternal(url, cmd, cs)
+	if nil != cs.ResponseHandler {
+		cs.ResponseHandler(newCollectorResponse(cmd, resp, time.Since(start)))
+	}
 
 	if cs.Logger.DebugEnabled() {
 		if err := resp.Err; err != nil {
@@ -240,8 +284,10 @@ var (
 	errMissingAgentRunID = errors.New("connect reply missing agent run id")
 )
 
-// connectAttempt tries to connect an application.
-func connectAttempt(config config, cs rpmControls) (*internal.ConnectReply, rpmResponse) {
+// connectAttempt tries to connect an application using the preconnect host
+// provided.  Settings resolved while connecting, such as the host display
+// name, are stored in config.
+func connectAttempt(config *config, host string, cs rpmControls) (*internal.ConnectReply, rpmResponse) {
 	preconnectData, err := json.Marshal([]preconnectRequest{{
 		SecurityPoliciesToken: config.SecurityPoliciesToken,
 		HighSecurity:          config.HighSecurity,
@@ -252,7 +298,7 @@ func connectAttempt(config config, cs rpmControls) (*internal.ConnectReply, rpmR
 
 	call := rpmCmd{
 		Name:           cmdPreconnect,
-		Collector:      config.preconnectHost(),
+		Collector:      host,
 		Data:           preconnectData,
 		MaxPayloadSize: internal.MaxPayloadSizeInBytes,
 	}
@@ -274,6 +320,12 @@ func connectAttempt(config config, cs rpmControls) (*internal.ConnectReply, rpmR
 		}
 	}
 
+	// The collector may redirect the application to a different host,
+	// which must also be in an allowed region.
+	if err := config.checkCollectorRegion(preconnect.Preconnect.Collector); nil != err {
+		return nil, rpmResponse{Err: err}
+	}
+
 	js, err := config.createConnectJSON(preconnect.Preconnect.SecurityPolicies.PointerIfPopulated())
 	if nil != err {
 		return nil, rpmResponse{Err: fmt.Errorf("unable to create connect data: %v", err)}
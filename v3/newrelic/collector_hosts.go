@@ -0,0 +1,123 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sync"
+	"time"
+)
+
+// collectorHosts tracks the health of the ordered list of preconnect hosts.
+// The first host is the primary.  A host which fails is skipped until
+// failoverCooldown has passed, after which it is preferred again.
+type collectorHosts struct {
+	sync.Mutex
+	hosts           []string
+	downUntil       []time.Time
+	harvestFailures int
+}
+
+func newCollectorHosts(hosts []string) *collectorHosts {
+	return &collectorHosts{
+		hosts:     hosts,
+		downUntil: make([]time.Time, len(hosts)),
+	}
+}
+
+// reset replaces the hosts, forgetting their health.
+func (ch *collectorHosts) reset(hosts []string) {
+	ch.Lock()
+	defer ch.Unlock()
+
+	ch.hosts = hosts
+	ch.downUntil = make([]time.Time, len(hosts))
+	ch.harvestFailures = 0
+}
+
+func (ch *collectorHosts) index(host string) int {
+	for i, h := range ch.hosts {
+		if h == host {
+			return i
+		}
+	}
+	return -1
+}
+
+// best returns the index of the highest priority healthy host.  If every host
+// is down, the host which will recover soonest is returned.
+func (ch *collectorHosts) best(now time.Time) int {
+	soonest := 0
+	for i, until := range ch.downUntil {
+		if !now.Before(until) {
+			return i
+		}
+		if until.Before(ch.downUntil[soonest]) {
+			soonest = i
+		}
+	}
+	return soonest
+}
+
+// next returns the host that the next connect attempt should use.
+func (ch *collectorHosts) next(now time.Time) string {
+	ch.Lock()
+	defer ch.Unlock()
+
+	return ch.hosts[ch.best(now)]
+}
+
+// markFailed takes the host out of rotation.  It returns true if a different
+// healthy host is available to fail over to.
+func (ch *collectorHosts) markFailed(host string, now time.Time) bool {
+	ch.Lock()
+	defer ch.Unlock()
+
+	idx := ch.index(host)
+	if idx < 0 || len(ch.hosts) < 2 {
+		return false
+	}
+	ch.downUntil[idx] = now.Add(failoverCooldown)
+	return !now.Before(ch.downUntil[ch.best(now)])
+}
+
+// markHealthy records a successful connect to the host.
+func (ch *collectorHosts) markHealthy(host string) {
+	ch.Lock()
+	defer ch.Unlock()
+
+	if idx := ch.index(host); idx >= 0 {
+		ch.downUntil[idx] = time.Time{}
+	}
+	ch.harvestFailures = 0
+}
+
+// harvestResult records the outcome of a harvest request sent through the
+// connection established with host.  It returns true if the application
+// should reconnect using a different host.
+func (ch *collectorHosts) harvestResult(host string, available bool, now time.Time) bool {
+	ch.Lock()
+	if available {
+		ch.harvestFailures = 0
+		ch.Unlock()
+		return false
+	}
+	ch.harvestFailures++
+	failures := ch.harvestFailures
+	ch.Unlock()
+
+	if failures < failoverHarvestFailures {
+		return false
+	}
+	return ch.markFailed(host, now)
+}
+
+// shouldFailBack returns true if a higher priority host than the one
+// currently connected has come back into rotation.
+func (ch *collectorHosts) shouldFailBack(host string, now time.Time) bool {
+	ch.Lock()
+	defer ch.Unlock()
+
+	idx := ch.index(host)
+	return idx > 0 && ch.best(now) < idx
+}
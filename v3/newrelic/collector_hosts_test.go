@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCollectorHostsSingleHost(t *testing.T) {
+	now := time.Now()
+	ch := newCollectorHosts([]string{"primary"})
+	if ch.markFailed("primary", now) {
+		t.Error("single host should never fail over")
+	}
+	if h := ch.next(now); h != "primary" {
+		t.Error(h)
+	}
+	for i := 0; i < 2*failoverHarvestFailures; i++ {
+		if ch.harvestResult("primary", false, now) {
+			t.Error("single host should never fail over")
+		}
+	}
+}
+
+func TestCollectorHostsFailoverAndFailBack(t *testing.T) {
+	now := time.Now()
+	ch := newCollectorHosts([]string{"primary", "secondary", "tertiary"})
+	if h := ch.next(now); h != "primary" {
+		t.Error(h)
+	}
+	if !ch.markFailed("primary", now) {
+		t.Error("expected failover to be possible")
+	}
+	if h := ch.next(now); h != "secondary" {
+		t.Error(h)
+	}
+	if ch.shouldFailBack("secondary", now) {
+		t.Error("primary should still be out of rotation")
+	}
+	later := now.Add(failoverCooldown)
+	if !ch.shouldFailBack("secondary", later) {
+		t.Error("primary should be back in rotation")
+	}
+	if h := ch.next(later); h != "primary" {
+		t.Error(h)
+	}
+	if ch.shouldFailBack("primary", later) {
+		t.Error("primary should never fail back")
+	}
+}
+
+func TestCollectorHostsAllDown(t *testing.T) {
+	now := time.Now()
+	ch := newCollectorHosts([]string{"primary", "secondary"})
+	if !ch.markFailed("primary", now) {
+		t.Error("expected failover to be possible")
+	}
+	if ch.markFailed("secondary", now.Add(time.Second)) {
+		t.Error("no healthy hosts should remain")
+	}
+	// The primary recovers first.
+	if h := ch.next(now.Add(2 * time.Second)); h != "primary" {
+		t.Error(h)
+	}
+	ch.markHealthy("secondary")
+	if h := ch.next(now.Add(2 * time.Second)); h != "secondary" {
+		t.Error(h)
+	}
+}
+
+func TestCollectorHostsHarvestFailures(t *testing.T) {
+	now := time.Now()
+	ch := newCollectorHosts([]string{"primary", "secondary"})
+	for i := 1; i < failoverHarvestFailures; i++ {
+		if ch.harvestResult("primary", false, now) {
+			t.Fatal("failover too early", i)
+		}
+	}
+	// A success resets the consecutive failure count.
+	ch.harvestResult("primary", true, now)
+	for i := 1; i < failoverHarvestFailures; i++ {
+		if ch.harvestResult("primary", false, now) {
+			t.Fatal("failover too early", i)
+		}
+	}
+	if !ch.harvestResult("primary", false, now) {
+		t.Error("expected failover")
+	}
+	if h := ch.next(now); h != "secondary" {
+		t.Error(h)
+	}
+}
+
+func TestCollectorUnavailable(t *testing.T) {
+	if newRPMResponse(200).isCollectorUnavailable() {
+		t.Error("200 is available")
+	}
+	if newRPMResponse(400).isCollectorUnavailable() {
+		t.Error("400 is available")
+	}
+	if !newRPMResponse(503).isCollectorUnavailable() {
+		t.Error("503 is unavailable")
+	}
+	if !(rpmResponse{forceSaveHarvestData: true, Err: errMissingAgentRunID}).isCollectorUnavailable() {
+		t.Error("transport errors are unavailable")
+	}
+}
@@ -0,0 +1,76 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// collectorRegionUS is the region of collector hosts in the
+	// newrelic.com domain.
+	collectorRegionUS = "us01"
+	regionHostSuffix  = ".nr-data.net"
+)
+
+// collectorRegion returns the region of a collector host: the label which
+// precedes the nr-data.net domain, eg. "eu01" for
+// "collector.eu01.nr-data.net", or "us01" for hosts in the newrelic.com
+// domain.  An empty string is returned if the region is unknown.
+func collectorRegion(host string) string {
+	host = strings.ToLower(host)
+	if idx := strings.LastIndex(host, ":"); idx >= 0 {
+		host = host[:idx]
+	}
+	if "newrelic.com" == host || strings.HasSuffix(host, ".newrelic.com") {
+		return collectorRegionUS
+	}
+	if !strings.HasSuffix(host, regionHostSuffix) {
+		return ""
+	}
+	labels := strings.Split(strings.TrimSuffix(host, regionHostSuffix), ".")
+	if len(labels) < 2 {
+		// Hosts such as "collector.nr-data.net" do not contain a
+		// region.
+		return ""
+	}
+	return labels[len(labels)-1]
+}
+
+// checkCollectorRegion returns an error if AllowedRegions is configured and
+// the host is not in one of the allowed regions.
+func (c Config) checkCollectorRegion(host string) error {
+	if 0 == len(c.AllowedRegions) {
+		return nil
+	}
+	region := collectorRegion(host)
+	for _, allowed := range c.AllowedRegions {
+		if "" != region && strings.EqualFold(region, strings.TrimSpace(allowed)) {
+			return nil
+		}
+	}
+	if "" == region {
+		region = "unknown"
+	}
+	return fmt.Errorf("collector host %q is in region %s which is not one of the AllowedRegions %v",
+		host, region, c.AllowedRegions)
+}
+
+// validateAllowedRegions checks that the collector host and the failover
+// hosts are in the allowed regions.
+func (c Config) validateAllowedRegions() error {
+	hosts := (config{Config: c}).preconnectHosts()
+	if nil != c.LicenseProvider && "" == c.Host {
+		// The collector host is derived from the license, and is
+		// checked once the license has been fetched.
+		hosts = hosts[1:]
+	}
+	for _, host := range hosts {
+		if err := c.checkCollectorRegion(host); nil != err {
+			return err
+		}
+	}
+	return nil
+}
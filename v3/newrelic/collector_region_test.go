@@ -0,0 +1,98 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestCollectorRegion(t *testing.T) {
+	for host, expect := range map[string]string{
+		"collector.eu01.nr-data.net":         "eu01",
+		"collector-001.eu01.nr-data.net":     "eu01",
+		"Collector.EU01.nr-data.net:443":     "eu01",
+		"collector.gov01.nr-data.net":        "gov01",
+		"collector.newrelic.com":             "us01",
+		"collector-024.newrelic.com":         "us01",
+		"collector.nr-data.net":              "",
+		"collector.example.com":              "",
+		"collector.eu01.nr-data.net.evil.io": "",
+		"":                                   "",
+	} {
+		if region := collectorRegion(host); region != expect {
+			t.Errorf("host=%q region=%q expect=%q", host, region, expect)
+		}
+	}
+}
+
+func TestValidateAllowedRegions(t *testing.T) {
+	euLicense := "eu01xx6789012345678901234567890123456789"
+	usLicense := "0123456789012345678901234567890123456789"
+	testcases := []struct {
+		license  string
+		provider bool
+		host     string
+		backups  []string
+		allowed  []string
+		valid    bool
+	}{
+		{license: usLicense, valid: true},
+		{license: usLicense, allowed: []string{"eu01"}, valid: false},
+		{license: euLicense, allowed: []string{"eu01"}, valid: true},
+		{license: euLicense, allowed: []string{" EU01"}, valid: true},
+		{license: euLicense, allowed: []string{"us01", "eu01"}, valid: true},
+		{license: euLicense, host: "collector.example.com", allowed: []string{"eu01"}, valid: false},
+		{license: euLicense, backups: []string{"collector.newrelic.com"}, allowed: []string{"eu01"}, valid: false},
+		{license: euLicense, backups: []string{"collector-002.eu01.nr-data.net"}, allowed: []string{"eu01"}, valid: true},
+		// The host of a license from a LicenseProvider is checked once
+		// it has been fetched.
+		{provider: true, allowed: []string{"eu01"}, valid: true},
+		{provider: true, host: "collector.newrelic.com", allowed: []string{"eu01"}, valid: false},
+	}
+	for i, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.AppName = "my app"
+		cfg.License = tc.license
+		cfg.Host = tc.host
+		cfg.FailoverHosts = tc.backups
+		cfg.AllowedRegions = tc.allowed
+		if tc.provider {
+			cfg.LicenseProvider = func(context.Context) (string, error) { return euLicense, nil }
+		}
+		err := cfg.validate()
+		if tc.valid != (nil == err) {
+			t.Errorf("testcase %d: %v", i, err)
+		}
+	}
+}
+
+func TestValidateAllowedRegionsError(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AppName = "my app"
+	cfg.License = "0123456789012345678901234567890123456789"
+	cfg.AllowedRegions = []string{"eu01"}
+	err := cfg.validate()
+	if nil == err || !strings.Contains(err.Error(), `collector host "collector.newrelic.com" is in region us01`) {
+		t.Error(err)
+	}
+}
+
+func TestConnectAttemptRedirectOutsideAllowedRegions(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.License = "eu01xx6789012345678901234567890123456789"
+	cfg.AllowedRegions = []string{"eu01"}
+	run, resp := testConnectHelper(connectMock{
+		redirect: endpointResult{response: makeResponse(200, `{"return_value":{"redirect_host":"collector-001.newrelic.com"}}`)},
+		connect:  endpointResult{response: makeResponse(200, connectBody)},
+		config:   cfg,
+	})
+	if nil != run || nil == resp.Err {
+		t.Fatal(run, resp.Err)
+	}
+	if !strings.Contains(resp.Err.Error(), "collector-001.newrelic.com") {
+		t.Error(resp.Err)
+	}
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// CollectorResponse describes the outcome of a request the application made
+// to New Relic.  It is provided to Config.CollectorResponseHandler, which
+// can be used to report the health of a fleet of agents centrally, rather
+// than by aggregating their debug logs.
+type CollectorResponse struct {
+	// Method is the collector method called, eg. "preconnect", "connect",
+	// "metric_data", or "span_event_data".
+	Method string
+	// StatusCode is the HTTP status code of the response.  It is 0 if no
+	// response was received, for example because of a network error.
+	StatusCode int
+	// Duration is the time taken to make the request and read the
+	// response.
+	Duration time.Duration
+	// Err is the error of an unsuccessful request.
+	Err error
+	// Disconnect indicates that New Relic told the application to
+	// disconnect.  The application stops sending data.
+	Disconnect bool
+	// Restart indicates that New Relic told the application to restart.
+	// The application connects again, starting a new run.
+	Restart bool
+	// DataSaved indicates that the data of an unsuccessful harvest request
+	// is kept and sent again in the next harvest.  It is always false for
+	// the preconnect and connect methods.
+	DataSaved bool
+}
+
+func newCollectorResponse(cmd rpmCmd, resp rpmResponse, duration time.Duration) CollectorResponse {
+	harvest := cmdPreconnect != cmd.Name && cmdConnect != cmd.Name
+	return CollectorResponse{
+		Method:     cmd.Name,
+		StatusCode: resp.statusCode,
+		Duration:   duration,
+		Err:        resp.Err,
+		Disconnect: resp.IsDisconnect(),
+		Restart:    resp.IsRestartException(),
+		DataSaved:  harvest && nil != resp.Err && resp.ShouldSaveHarvestData(),
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal/logger"
+)
+
+func TestCollectorResponseHandler(t *testing.T) {
+	testcases := []struct {
+		method     string
+		statusCode int
+		err        bool
+		disconnect bool
+		restart    bool
+		dataSaved  bool
+	}{
+		{method: cmdMetrics, statusCode: 202},
+		{method: cmdMetrics, statusCode: 410, err: true, disconnect: true},
+		{method: cmdMetrics, statusCode: 409, err: true, restart: true},
+		{method: cmdMetrics, statusCode: 503, err: true, dataSaved: true},
+		{method: cmdMetrics, statusCode: 400, err: true},
+		{method: cmdConnect, statusCode: 503, err: true},
+	}
+
+	for _, tc := range testcases {
+		var got []CollectorResponse
+		cs := rpmControls{
+			License: "the_license",
+			Client: &http.Client{
+				Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					return &http.Response{
+						StatusCode: tc.statusCode,
+						Body:       ioutil.NopCloser(strings.NewReader("body")),
+					}, nil
+				}),
+			},
+			Logger: logger.ShimLogger{},
+			ResponseHandler: func(r CollectorResponse) {
+				got = append(got, r)
+			},
+		}
+		collectorRequest(rpmCmd{
+			Name:           tc.method,
+			Collector:      "collector.com",
+			MaxPayloadSize: 1000 * 1000,
+		}, cs)
+		if len(got) != 1 {
+			t.Fatal(tc.method, tc.statusCode, got)
+		}
+		r := got[0]
+		if r.Method != tc.method || r.StatusCode != tc.statusCode {
+			t.Error(tc.method, tc.statusCode, r)
+		}
+		if (nil != r.Err) != tc.err {
+			t.Error(tc.method, tc.statusCode, r.Err)
+		}
+		if r.Disconnect != tc.disconnect || r.Restart != tc.restart || r.DataSaved != tc.dataSaved {
+			t.Error(tc.method, tc.statusCode, r)
+		}
+		if r.Duration < 0 {
+			t.Error(tc.method, tc.statusCode, r.Duration)
+		}
+	}
+}
+
+func TestCollectorResponseHandlerNetworkError(t *testing.T) {
+	var got []CollectorResponse
+	cs := rpmControls{
+		License: "the_license",
+		Client: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				return nil, errors.New("connection refused")
+			}),
+		},
+		Logger: logger.ShimLogger{},
+		ResponseHandler: func(r CollectorResponse) {
+			got = append(got, r)
+		},
+	}
+	collectorRequest(rpmCmd{
+		Name:           cmdSpanEvents,
+		Collector:      "collector.com",
+		MaxPayloadSize: 1000 * 1000,
+	}, cs)
+	if len(got) != 1 {
+		t.Fatal(got)
+	}
+	if r := got[0]; 0 != r.StatusCode || nil == r.Err || !r.DataSaved {
+		t.Error(r)
+	}
+}
+
+func TestConfigCollectorResponseHandler(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	app, err := NewApplication(
+		ConfigAppName("my app"),
+		ConfigLicense(testLicenseKey),
+		func(cfg *Config) {
+			cfg.HarvestSender = &collectorSender{}
+			cfg.RuntimeSampler.Enabled = false
+			cfg.CollectorResponseHandler = func(r CollectorResponse) {
+				mu.Lock()
+				defer mu.Unlock()
+				if nil != r.Err {
+					t.Error(r)
+				}
+				methods = append(methods, r.Method)
+			}
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(10 * time.Second); nil != err {
+		t.Fatal(err)
+	}
+	app.Shutdown(10 * time.Second)
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(methods) < 2 || cmdPreconnect != methods[0] || cmdConnect != methods[1] {
+		t.Error(methods)
+	}
+}
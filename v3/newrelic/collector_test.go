@@ -237,7 +237,7 @@ func testConnectHelper(cm connectMock) (*internal.ConnectReply, rpmResponse) {
 		Logger:  logger.ShimLogger{IsDebugEnabled: true},
 	}
 
-	return connectAttempt(cm.config, cs)
+	return connectAttempt(&cm.config, cm.config.preconnectHost(), cs)
 }
 
 func TestConnectAttemptSuccess(t *testing.T) {
@@ -443,7 +443,7 @@ func TestConnectReplyMaxPayloadSize(t *testing.T) {
 	}
 
 	for _, test := range testcases {
-		reply, resp := connectAttempt(config{}, controls(test.replyBody))
+		reply, resp := connectAttempt(&config{}, preconnectHostDefault, controls(test.replyBody))
 		if nil != resp.Err {
 			t.Error("resp returned unexpected error:", resp.Err)
 		}
@@ -0,0 +1,40 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package collectortest
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock is a clock which only moves when it is advanced.  Use its Now method
+// as the Config.HarvestClock of an application to control when the
+// application harvests.  It is safe for concurrent use.
+type Clock struct {
+	sync.Mutex
+	now time.Time
+}
+
+// NewClock creates a Clock set to the time given.
+func NewClock(now time.Time) *Clock {
+	return &Clock{now: now}
+}
+
+// Now returns the time of the clock.
+func (c *Clock) Now() time.Time {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.now
+}
+
+// Advance moves the clock forward.  Advancing the clock of an application by
+// more than a minute causes all of its data to be harvested shortly
+// afterwards.
+func (c *Clock) Advance(d time.Duration) {
+	c.Lock()
+	defer c.Unlock()
+
+	c.now = c.now.Add(d)
+}
@@ -0,0 +1,302 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// Package collectortest provides a fake New Relic collector and a clock for
+// integration tests of instrumented applications.
+//
+// The Collector answers the preconnect and connect requests made by an
+// application and records the payloads of every harvest, so that tests can
+// assert on exactly what the agent sends.  The Clock controls when the
+// application harvests:
+//
+//	collector := collectortest.New()
+//	app, err := newrelic.NewApplication(
+//		newrelic.ConfigAppName("my app"),
+//		collector.ConfigOption(),
+//	)
+//	if nil != err {
+//		t.Fatal(err)
+//	}
+//	if err := app.WaitForConnection(5 * time.Second); nil != err {
+//		t.Fatal(err)
+//	}
+//	app.StartTransaction("hello").End()
+//
+//	collector.Clock.Advance(61 * time.Second)
+//	reqs, err := collector.WaitForRequests("analytic_event_data", 1, 5*time.Second)
+//	if nil != err {
+//		t.Fatal(err)
+//	}
+//	var payload []interface{}
+//	reqs[0].Decode(&payload)
+//
+// Application.Shutdown also harvests all remaining data.
+package collectortest
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+const (
+	// License is the license used by ConfigOption when the configuration
+	// does not already contain one.
+	License = "0123456789012345678901234567890123456789"
+	// AccountID, AppID, and TrustedAccountKey are returned in the connect
+	// reply so that distributed tracing headers can be created and
+	// accepted.
+	AccountID         = "123"
+	AppID             = "456"
+	TrustedAccountKey = "123"
+)
+
+// Request is a request the application made to the Collector.
+type Request struct {
+	// Method is the collector method, eg. "connect", "metric_data", or
+	// "span_event_data".
+	Method string
+	// RunID is the agent run id of the request.  It is empty for the
+	// preconnect and connect methods.
+	RunID string
+	// License is the license key of the request.
+	License string
+	// Header contains the request headers.
+	Header http.Header
+	// Body is the uncompressed payload, which is JSON.
+	Body []byte
+}
+
+// Decode unmarshals the JSON payload of the request into v.
+func (r Request) Decode(v interface{}) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Collector is an in-memory fake of the New Relic collector.  It is safe for
+// concurrent use.  Use ConfigOption to send an application's requests to it
+// without using the network, or serve it using httptest.NewTLSServer to test
+// the application's HTTP client as well.
+type Collector struct {
+	// Clock is used by ConfigOption as the application's
+	// Config.HarvestClock.
+	Clock *Clock
+
+	sync.Mutex
+	// connectReply is the return value of the connect method.
+	connectReply map[string]interface{}
+	statusCodes  map[string]int
+	requests     []Request
+	runs         int
+	// changed is closed and replaced when a request is recorded.
+	changed chan struct{}
+}
+
+// New creates a Collector whose connect reply samples every transaction and
+// contains the settings needed for distributed tracing.
+func New() *Collector {
+	return &Collector{
+		Clock: NewClock(time.Now()),
+		connectReply: map[string]interface{}{
+			"account_id":                        AccountID,
+			"trusted_account_key":               TrustedAccountKey,
+			"primary_application_id":            AppID,
+			"application_id":                    AppID,
+			"entity_guid":                       "collectortest-entity-guid",
+			"sampling_target":                   1000000,
+			"sampling_target_period_in_seconds": 60,
+		},
+		statusCodes: make(map[string]int),
+		changed:     make(chan struct{}),
+	}
+}
+
+// ConfigOption returns a ConfigOption which sends the application's requests
+// to the Collector and uses its Clock to decide when to harvest.  A license
+// is added if the configuration does not contain one.
+func (c *Collector) ConfigOption() newrelic.ConfigOption {
+	return func(cfg *newrelic.Config) {
+		if "" == cfg.License {
+			cfg.License = License
+		}
+		cfg.HarvestSender = c
+		cfg.HarvestClock = c.Clock.Now
+	}
+}
+
+// SetConnectReply sets a field of the connect reply, eg.
+// "collect_span_events" or "event_harvest_config".  Replies to connect
+// requests made afterwards contain the value.  Setting a field to nil
+// removes it.
+func (c *Collector) SetConnectReply(field string, value interface{}) {
+	c.Lock()
+	defer c.Unlock()
+
+	if nil == value {
+		delete(c.connectReply, field)
+		return
+	}
+	c.connectReply[field] = value
+}
+
+// SetStatusCode makes the Collector respond to requests for the method with
+// the status code, eg. 503 to simulate an outage or 409 to force the
+// application to reconnect.  Set a status code of 0 to restore successful
+// responses.
+func (c *Collector) SetStatusCode(method string, code int) {
+	c.Lock()
+	defer c.Unlock()
+
+	if 0 == code {
+		delete(c.statusCodes, method)
+		return
+	}
+	c.statusCodes[method] = code
+}
+
+// Requests returns the requests made for the method, or every request if the
+// method is empty, in the order they were received.
+func (c *Collector) Requests(method string) []Request {
+	c.Lock()
+	defer c.Unlock()
+
+	return c.requestsLocked(method)
+}
+
+func (c *Collector) requestsLocked(method string) []Request {
+	var reqs []Request
+	for _, r := range c.requests {
+		if "" == method || r.Method == method {
+			reqs = append(reqs, r)
+		}
+	}
+	return reqs
+}
+
+// WaitForRequests waits until at least n requests have been made for the
+// method and returns them.  An error is returned if the timeout elapses
+// first.
+func (c *Collector) WaitForRequests(method string, n int, timeout time.Duration) ([]Request, error) {
+	deadline := time.NewTimer(timeout)
+	defer deadline.Stop()
+
+	for {
+		c.Lock()
+		reqs := c.requestsLocked(method)
+		changed := c.changed
+		c.Unlock()
+
+		if len(reqs) >= n {
+			return reqs, nil
+		}
+		select {
+		case <-changed:
+		case <-deadline.C:
+			return reqs, fmt.Errorf("timed out waiting for %d %s requests, received %d", n, method, len(reqs))
+		}
+	}
+}
+
+// Reset removes the recorded requests.
+func (c *Collector) Reset() {
+	c.Lock()
+	defer c.Unlock()
+
+	c.requests = nil
+}
+
+// Send implements newrelic.HarvestSender.
+func (c *Collector) Send(req newrelic.HarvestRequest) (newrelic.HarvestResponse, error) {
+	r, err := http.NewRequest("POST", req.URL, bytes.NewReader(req.Body))
+	if nil != err {
+		return newrelic.HarvestResponse{}, err
+	}
+	r.Header = req.Header
+	w := httptest.NewRecorder()
+	c.ServeHTTP(w, r)
+	return newrelic.HarvestResponse{
+		StatusCode: w.Code,
+		Body:       w.Body.Bytes(),
+	}, nil
+}
+
+// ServeHTTP implements http.Handler.
+func (c *Collector) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	method := query.Get("method")
+	body, err := readBody(r)
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	c.Lock()
+	defer c.Unlock()
+
+	c.requests = append(c.requests, Request{
+		Method:  method,
+		RunID:   query.Get("run_id"),
+		License: query.Get("license_key"),
+		Header:  r.Header,
+		Body:    body,
+	})
+	close(c.changed)
+	c.changed = make(chan struct{})
+
+	if code, ok := c.statusCodes[method]; ok {
+		w.WriteHeader(code)
+		return
+	}
+
+	var reply interface{}
+	switch method {
+	case "preconnect":
+		reply = map[string]interface{}{"redirect_host": r.Host}
+	case "connect":
+		c.runs++
+		connect := make(map[string]interface{}, len(c.connectReply)+1)
+		for k, v := range c.connectReply {
+			connect[k] = v
+		}
+		connect["agent_run_id"] = fmt.Sprintf("run-%d", c.runs)
+		reply = connect
+	}
+	js, err := json.Marshal(map[string]interface{}{"return_value": reply})
+	if nil != err {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	w.Write(js)
+}
+
+func readBody(r *http.Request) ([]byte, error) {
+	if "gzip" != r.Header.Get("Content-Encoding") {
+		return ioutil.ReadAll(r.Body)
+	}
+	gz, err := gzip.NewReader(r.Body)
+	if nil != err {
+		return nil, err
+	}
+	defer gz.Close()
+	return ioutil.ReadAll(gz)
+}
+
+// Host returns the value of Config.Host which directs an application to the
+// Collector when it is served by the httptest.Server.  The Config.Transport
+// must also trust the server's certificate, eg. by using
+// server.Client().Transport.
+func Host(server *httptest.Server) string {
+	u, err := url.Parse(server.URL)
+	if nil != err {
+		return ""
+	}
+	return u.Host
+}
@@ -0,0 +1,255 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package collectortest
+
+import (
+	"context"
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/newrelic"
+)
+
+const (
+	waitTimeout = 5 * time.Second
+	// harvestPeriod is longer than the period of every harvest.
+	harvestPeriod = time.Minute + time.Second
+)
+
+func TestClock(t *testing.T) {
+	start := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+	c := NewClock(start)
+	if now := c.Now(); !now.Equal(start) {
+		t.Error(now)
+	}
+	c.Advance(time.Minute)
+	if now := c.Now(); !now.Equal(start.Add(time.Minute)) {
+		t.Error(now)
+	}
+}
+
+func TestCollectorHarvest(t *testing.T) {
+	collector := New()
+	app, err := newrelic.NewApplication(
+		newrelic.ConfigAppName("my app"),
+		newrelic.ConfigDistributedTracerEnabled(true),
+		collector.ConfigOption(),
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(waitTimeout)
+	if err := app.WaitForConnection(waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+
+	connect := collector.Requests("connect")
+	if len(connect) != 1 || License != connect[0].License {
+		t.Fatal(connect)
+	}
+	var settings []map[string]interface{}
+	if err := connect[0].Decode(&settings); nil != err || "my app" != settings[0]["app_name"].([]interface{})[0] {
+		t.Error(err, settings)
+	}
+
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("child").End()
+	txn.End()
+
+	// Nothing is harvested until the clock advances.
+	time.Sleep(50 * time.Millisecond)
+	if reqs := collector.Requests("analytic_event_data"); len(reqs) != 0 {
+		t.Fatal(reqs)
+	}
+
+	collector.Clock.Advance(harvestPeriod)
+	reqs, err := collector.WaitForRequests("analytic_event_data", 1, waitTimeout)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if "run-1" != reqs[0].RunID {
+		t.Error(reqs[0].RunID)
+	}
+	var txnEvents []interface{}
+	if err := reqs[0].Decode(&txnEvents); nil != err {
+		t.Fatal(err)
+	}
+	events := txnEvents[2].([]interface{})
+	intrinsics := events[0].([]interface{})[0].(map[string]interface{})
+	if "OtherTransaction/Go/hello" != intrinsics["name"] {
+		t.Error(intrinsics)
+	}
+
+	spans, err := collector.WaitForRequests("span_event_data", 1, waitTimeout)
+	if nil != err {
+		t.Fatal(err)
+	}
+	var spanEvents []interface{}
+	if err := spans[0].Decode(&spanEvents); nil != err || len(spanEvents[2].([]interface{})) != 2 {
+		t.Error(err, spanEvents)
+	}
+	if _, err := collector.WaitForRequests("metric_data", 1, waitTimeout); nil != err {
+		t.Error(err)
+	}
+}
+
+func TestCollectorManualHarvest(t *testing.T) {
+	collector := New()
+	app, err := newrelic.NewApplication(
+		newrelic.ConfigAppName("my app"),
+		collector.ConfigOption(),
+		func(cfg *newrelic.Config) {
+			cfg.ManualHarvest.Enabled = true
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(waitTimeout)
+	if err := app.WaitForConnection(waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+
+	app.RecordCustomEvent("MyEvent", map[string]interface{}{"zip": 1})
+	collector.Clock.Advance(harvestPeriod)
+
+	// Nothing is harvested until the application ticks.
+	time.Sleep(50 * time.Millisecond)
+	if reqs := collector.Requests("custom_event_data"); len(reqs) != 0 {
+		t.Fatal(reqs)
+	}
+
+	// Tick sends the harvest before returning.
+	app.Tick()
+	if reqs := collector.Requests("custom_event_data"); len(reqs) != 1 {
+		t.Error(reqs)
+	}
+	if reqs := collector.Requests("metric_data"); len(reqs) != 1 {
+		t.Error(reqs)
+	}
+
+	// Nothing more is ready until the clock advances again.
+	collector.Reset()
+	app.Tick()
+	if reqs := collector.Requests(""); len(reqs) != 0 {
+		t.Error(reqs)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		app.RunHarvestLoop(ctx)
+		close(done)
+	}()
+	app.RecordCustomEvent("MyEvent", map[string]interface{}{"zip": 2})
+	collector.Clock.Advance(harvestPeriod)
+	if _, err := collector.WaitForRequests("custom_event_data", 1, waitTimeout); nil != err {
+		t.Error(err)
+	}
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(waitTimeout):
+		t.Error("harvest loop did not stop")
+	}
+}
+
+func TestCollectorStatusCode(t *testing.T) {
+	collector := New()
+	collector.SetStatusCode("custom_event_data", http.StatusServiceUnavailable)
+	app, err := newrelic.NewApplication(
+		newrelic.ConfigAppName("my app"),
+		collector.ConfigOption(),
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(waitTimeout)
+	if err := app.WaitForConnection(waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+
+	app.RecordCustomEvent("MyEvent", map[string]interface{}{"zip": 1})
+	collector.Clock.Advance(harvestPeriod)
+	if _, err := collector.WaitForRequests("custom_event_data", 1, waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+
+	// The event is kept and sent again in the next harvest.
+	collector.SetStatusCode("custom_event_data", 0)
+	collector.Reset()
+	collector.Clock.Advance(harvestPeriod)
+	reqs, err := collector.WaitForRequests("custom_event_data", 1, waitTimeout)
+	if nil != err {
+		t.Fatal(err)
+	}
+	var payload []interface{}
+	if err := reqs[0].Decode(&payload); nil != err || len(payload[2].([]interface{})) != 1 {
+		t.Error(err, payload)
+	}
+}
+
+func TestCollectorConnectReply(t *testing.T) {
+	collector := New()
+	collector.SetConnectReply("collect_custom_events", false)
+	collector.SetConnectReply("entity_guid", nil)
+	app, err := newrelic.NewApplication(
+		newrelic.ConfigAppName("my app"),
+		collector.ConfigOption(),
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+	app.RecordCustomEvent("MyEvent", map[string]interface{}{"zip": 1})
+	app.Shutdown(waitTimeout)
+
+	if reqs := collector.Requests("custom_event_data"); len(reqs) != 0 {
+		t.Error(reqs)
+	}
+	if reqs := collector.Requests("metric_data"); len(reqs) != 1 {
+		t.Error(reqs)
+	}
+}
+
+func TestCollectorHTTP(t *testing.T) {
+	collector := New()
+	server := httptest.NewTLSServer(collector)
+	defer server.Close()
+
+	app, err := newrelic.NewApplication(
+		newrelic.ConfigAppName("my app"),
+		newrelic.ConfigLicense(License),
+		func(cfg *newrelic.Config) {
+			cfg.Host = Host(server)
+			cfg.Transport = &http.Transport{
+				TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+			}
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(waitTimeout); nil != err {
+		t.Fatal(err)
+	}
+	app.StartTransaction("hello").End()
+	app.Shutdown(waitTimeout)
+
+	if reqs := collector.Requests("analytic_event_data"); len(reqs) != 1 {
+		t.Error(reqs)
+	}
+	methods := map[string]bool{}
+	for _, r := range collector.Requests("") {
+		methods[r.Method] = true
+	}
+	if !methods["preconnect"] || !methods["connect"] || !methods["metric_data"] {
+		t.Error(methods)
+	}
+}
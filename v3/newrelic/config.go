@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,17 +21,50 @@ import (
 )
 
 // Config contains Application and Transaction behavior settings.
+//
+// References of the form ${VAR} in AppName, Labels, HostDisplayName, Host,
+// FailoverHosts, and InfiniteTracing.TraceObserver.Host are replaced with the
+// value of the environment variable VAR when the Application is created.
+// References to unset variables are replaced with the empty string.
 type Config struct {
 	// AppName is used by New Relic to link data across servers.
 	//
 	// https://docs.newrelic.com/docs/apm/new-relic-apm/installation-configuration/naming-your-application
 	AppName string
 
+	// ApplicationSubsets lists the subsets of the rollup application names
+	// in AppName to which Transaction.SetApplications may direct a
+	// transaction's data.  Each subset is written like AppName, eg. "a;c"
+	// when AppName is "a;b;c".  Each subset uses its own connection to New
+	// Relic, which is established when the Application is created.
+	// Application subsets are not supported in serverless mode.
+	ApplicationSubsets []string
+
 	// License is your New Relic license key.
 	//
 	// https://docs.newrelic.com/docs/accounts/install-new-relic/account-setup/license-key
 	License string
 
+	// LicenseProvider, when set, is called to fetch the license instead of
+	// using the License field, so that the license need not be placed in
+	// the environment or configuration files.  It is called in the
+	// background when the Application connects, so that NewApplication
+	// does not wait for it, and every LicenseRefreshPeriod afterwards.  If
+	// the initial call fails, an error is logged and the call is retried
+	// with the connect backoff until it succeeds.  If a later call fails,
+	// a warning is logged and the previous license continues to be used.
+	// A refreshed license is also used for the browser timing headers of
+	// transactions started afterwards.  See EnvironmentLicenseProvider,
+	// FileLicenseProvider, and VaultLicenseProvider; an AWS Secrets
+	// Manager provider is available in the nrawssdk-v1 integration.
+	LicenseProvider func(ctx context.Context) (string, error) `json:"-"`
+
+	// LicenseRefreshPeriod controls how often the LicenseProvider is
+	// called.  A value of zero disables refreshing.  A refreshed license
+	// is used by the next request made to New Relic; if the previous
+	// license was revoked, the application reconnects using the new one.
+	LicenseRefreshPeriod time.Duration
+
 	// Logger controls Go Agent logging.
 	//
 	// See https://github.com/newrelic/go-agent/blob/master/GUIDE.md#logging
@@ -48,6 +82,31 @@ type Config struct {
 	// https://docs.newrelic.com/docs/using-new-relic/user-interface-functions/organize-your-data/labels-categories-organize-apps-monitors
 	Labels map[string]string
 
+	// LabelHierarchies are labels made up of ordered levels, such as a
+	// region and an availability zone.  Each hierarchy is reported as a
+	// single label whose key and value join the levels with "/", for
+	// example "Region/AZ" with the value "us-east-1/us-east-1a".
+	//
+	// Unlike Labels, which the collector truncates when they exceed its
+	// limits, NewApplication returns an error if a hierarchy is empty, if
+	// its keys or values are empty or contain "/", ":", or ";", if its
+	// key or value is longer than 255 characters, or if there would be
+	// more than 64 labels.
+	LabelHierarchies []LabelHierarchy
+
+	// LabelAttributes controls whether Labels, including those created
+	// from LabelHierarchies, are also added to every transaction as user
+	// attributes named with the Prefix, eg. "label.Server", and copied
+	// onto every span of the transaction.  This allows events and spans to
+	// be faceted by label in NRQL queries.  Label attributes are subject
+	// to the attribute configuration and limits like other user
+	// attributes, and are not added when HighSecurity is enabled or the
+	// custom parameters security policy is disabled.
+	LabelAttributes struct {
+		Enabled bool
+		Prefix  string
+	}
+
 	// HighSecurity guarantees that certain agent settings can not be made
 	// more permissive.  This setting must match the corresponding account
 	// setting in the New Relic UI.
@@ -71,6 +130,45 @@ type Config struct {
 		// custom analytics events.  High security mode overrides this
 		// setting.
 		Enabled bool
+		// Derivations record additional custom events derived from the
+		// custom events recorded by RecordCustomEvent, so that call
+		// sites need not record the same data once for each event type.
+		// For example:
+		//
+		//	cfg.CustomInsightsEvents.Derivations = []newrelic.CustomEventDerivation{{
+		//		SourceType: "CheckoutDebug",
+		//		EventType:  "Billing",
+		//		Attributes: []string{"customer", "amount"},
+		//	}}
+		//
+		// Derived events are subject to the same limits as the events
+		// they are derived from.  Events are not derived from derived
+		// events.
+		Derivations []CustomEventDerivation
+	}
+
+	// ApplicationLogging controls the logs recorded with
+	// Application.RecordLog and Transaction.RecordLog.
+	ApplicationLogging struct {
+		// Enabled controls whether RecordLog records anything.
+		Enabled bool
+		// Forwarding controls whether logs are sent to New Relic, with
+		// the trace.id and span.id of the transaction they were
+		// recorded within.
+		Forwarding struct {
+			// Enabled controls whether logs are sent.  It is
+			// disabled by default.
+			Enabled bool
+			// MaxSamplesStored limits the number of logs sent in a
+			// 60-second period.  When there are more, the logs of
+			// the transactions with the highest priority are kept.
+			MaxSamplesStored int
+		}
+		// Metrics controls whether the Logging/lines metrics, which
+		// count the logs recorded by severity, are recorded.
+		Metrics struct {
+			Enabled bool
+		}
 	}
 
 	// TransactionEvents controls the behavior of transaction analytics
@@ -84,6 +182,14 @@ type Config struct {
 		// MaxSamplesStored allows you to limit the number of Transaction
 		// Events stored/reported in a given 60-second period
 		MaxSamplesStored int
+		// DistributedTracingIntrinsics controls which distributed
+		// tracing intrinsics are added to transaction events when
+		// DistributedTracer is enabled.  Accounts which never query
+		// them can use DistributedTracingIntrinsicsTrace or
+		// DistributedTracingIntrinsicsNone to reduce the size of each
+		// event.  Span events, error events, and traces are not
+		// affected.  The default is DistributedTracingIntrinsicsAll.
+		DistributedTracingIntrinsics DistributedTracingIntrinsics
 	}
 
 	// ErrorCollector controls the capture of errors.
@@ -106,6 +212,45 @@ type Config struct {
 		// as errors, and then re-panic them.  By default, this is
 		// set to false.
 		RecordPanics bool
+		// StratifyEvents controls how error events are kept when more
+		// are recorded than the reservoir holds.  When true, the
+		// default, the events kept are stratified by error class, so
+		// that a flood of one class, eg. timeouts, does not crowd out
+		// rarer classes.  The kept events of a class with events
+		// dropped have the error.classSeen attribute, the number of
+		// events of the class seen.  When false, the events with the
+		// highest priority are kept.
+		StratifyEvents bool
+		// MaxAttributeDepth is the number of levels of nested maps
+		// flattened in the attributes of errors implementing
+		// ErrorAttributer.  A map value is recorded as one attribute
+		// per entry, named by joining the keys with dots, eg.
+		// "details.db.host".  Values nested more deeply, and nested
+		// values of unsupported types, are dropped.  The default is 3.
+		// Set to 0 to drop all map values.
+		MaxAttributeDepth int
+	}
+
+	// EventHarvest controls how often transaction, custom, error, and span
+	// events are sent.
+	EventHarvest struct {
+		// Adaptive controls whether the event report period adapts to
+		// load.  New Relic grants a report period and a limit on the
+		// number of events of each type sent in that period.  When
+		// Adaptive is enabled, the period is halved, down to MinPeriod,
+		// after a cycle whose reservoirs filled in less than half of the
+		// period, and doubled, up to the granted period, after a cycle
+		// whose reservoirs were less than a quarter full.  The number of
+		// events kept in each cycle is scaled with the period so that the
+		// granted limits are never exceeded, but the events kept are
+		// spread across a traffic spike rather than being drawn from a
+		// single long cycle.
+		Adaptive struct {
+			Enabled bool
+			// MinPeriod is the shortest event report period used.
+			// The default is 5 seconds.
+			MinPeriod time.Duration
+		}
 	}
 
 	// TransactionTracer controls the capture of transaction traces.
@@ -143,6 +288,23 @@ type Config struct {
 			// trace segment.
 			Attributes AttributeDestinationConfig
 		}
+		// Sanitize, if set, is called with each segment of every
+		// transaction trace captured, when the transaction ends.  It may
+		// rename segments, redact or remove their attributes, or drop
+		// them along with the segments beneath them.  Use it to remove
+		// sensitive information from traces, such as the segment names
+		// of a library which cannot be modified:
+		//
+		//	cfg.TransactionTracer.Sanitize = func(s *newrelic.TraceSegment) {
+		//		if strings.HasPrefix(s.Name(), "Custom/vendor.") {
+		//			s.SetName("Custom/vendor")
+		//		}
+		//		s.RemoveAttribute(newrelic.SpanAttributeHTTPURL)
+		//	}
+		//
+		// Sanitize is called synchronously by Transaction.End.  Span
+		// events, slow queries, and metrics are not affected.
+		Sanitize func(*TraceSegment) `json:"-"`
 	}
 
 	// BrowserMonitoring contains settings which control the behavior of
@@ -160,13 +322,61 @@ type Config struct {
 	}
 
 	// HostDisplayName gives this server a recognizable name in the New
-	// Relic UI.  This is an optional setting.
+	// Relic UI.  This is an optional setting.  The tokens {hostname},
+	// {pod}, and {region} are replaced with the values detected when the
+	// application connects, for example "web-{region}-{pod}".  {pod} uses
+	// the POD_NAME or HOSTNAME environment variables, and {region} requires
+	// the cloud provider to be detected by Utilization.
 	HostDisplayName string
 
 	// Transport customizes communication with the New Relic servers.  This may
 	// be used to configure a proxy.
 	Transport http.RoundTripper
 
+	// HarvestSender, if set, sends the requests made to New Relic in place
+	// of the HTTP client, which allows the payloads of each harvest to be
+	// intercepted, buffered, or rerouted.  Transport is not used when
+	// HarvestSender is set.
+	HarvestSender HarvestSender
+
+	// CollectorResponseHandler, if set, is called with the status,
+	// duration, and outcome of each request made to New Relic, including
+	// whether New Relic told the application to disconnect or restart.  It
+	// can be used to feed a central view of the health of many agents.
+	// CollectorResponseHandler is called synchronously from the
+	// application's background goroutines, so it must be quick and safe for
+	// concurrent use.
+	CollectorResponseHandler func(CollectorResponse) `json:"-"`
+
+	// HarvestClock, if set, is used in place of time.Now to decide when
+	// data is harvested.  It is intended for tests, which can advance the
+	// clock to trigger a harvest without waiting for the harvest period.
+	// The timing of transactions and segments is not affected.  See the
+	// collectortest package.
+	HarvestClock func() time.Time `json:"-"`
+
+	// ManualHarvest controls whether the host application, rather than the
+	// agent, schedules harvests.  When enabled, the agent does not start
+	// goroutines to harvest data, sample the runtime, record the
+	// ScalingSignal and TransactionCheckpoints, or refresh the license
+	// using the LicenseProvider.  Instead the host application must call
+	// Application.Tick about once per second, or run
+	// Application.RunHarvestLoop, and these run during Tick.  This is
+	// intended for runtimes with strict goroutine budgets or custom
+	// schedulers.  The goroutines which remain are:
+	//
+	//   - one which aggregates recorded data, and one which connects to New
+	//     Relic until connected, for the application and for each of its
+	//     ApplicationSubsets, whose harvests are also sent during Tick
+	//   - one which gathers the utilization data, until it is gathered
+	//   - those which stream spans to the trace observer, when
+	//     InfiniteTracing is configured
+	//   - those which wait for the harvests of the ApplicationSubsets
+	//     during Application.Shutdown
+	ManualHarvest struct {
+		Enabled bool
+	}
+
 	// Utilization controls the detection and gathering of system
 	// information.
 	Utilization struct {
@@ -194,6 +404,27 @@ type Config struct {
 		LogicalProcessors int
 		TotalRAMMIB       int
 		BillingHostname   string
+
+		// Timeout is the maximum time each request to a cloud provider's
+		// metadata endpoint may take while detecting AWS, Azure, and
+		// GCP.  The default is 500 milliseconds.
+		Timeout time.Duration
+		// Retries is the number of times the detection of a cloud
+		// provider is retried after an unexpected response, such as an
+		// error status code.  The default is 0.
+		Retries int
+		// CacheTTL controls how long the utilization data is reused.
+		// Utilization data is gathered in the background from when the
+		// Application is created.  Connecting waits for it for at most
+		// twice the Timeout for each attempt allowed by Retries, after
+		// which the application connects using the data which does not
+		// require network requests, and the complete data is sent by the
+		// next reconnect.  When CacheTTL is positive, each
+		// reconnect within the CacheTTL reuses the data, and a reconnect
+		// after it uses the existing data while fresh data is gathered
+		// in the background.  When CacheTTL is zero, the default, the
+		// data is gathered again for each reconnect.
+		CacheTTL time.Duration
 	}
 
 	// Heroku controls the behavior of Heroku specific features.
@@ -235,6 +466,80 @@ type Config struct {
 		// Disabling the New Relic header here does not prevent the agent from
 		// accepting *inbound* New Relic headers.
 		ExcludeNewRelicHeader bool
+		// ErrorSamplingBudget is the maximum number of transactions per
+		// sampling period (one minute) that are sampled because they
+		// noticed an error, even though the adaptive sampler did not
+		// sample them.  All spans of such a transaction are kept.  The
+		// default is zero, which disables this behavior.
+		ErrorSamplingBudget int
+		// AccountID and TrustedAccountKey are used to create and accept
+		// distributed tracing payloads until the application connects,
+		// at which point the values provided by New Relic are used
+		// instead.  They are useful in tests and in environments where
+		// connecting is stubbed or delayed.  TrustedAccountKey defaults
+		// to AccountID.
+		AccountID         string
+		TrustedAccountKey string
+		// InboundSampled controls whether the sampled decision carried by
+		// inbound distributed tracing headers is used.  By default
+		// (InboundSampledHonor) a transaction is sampled whenever its
+		// caller was, which means an upstream service sampling every
+		// request causes every downstream transaction to be sampled.
+		// Mode applies to all transactions except web transactions whose
+		// request path matches the RoutePattern of one of the Rules, in
+		// which case the Mode of the first matching rule is used.  For
+		// example:
+		//
+		//	cfg.DistributedTracer.InboundSampled.Mode = newrelic.InboundSampledHint
+		//	cfg.DistributedTracer.InboundSampled.Rules = []newrelic.InboundSampledRule{
+		//		{RoutePattern: "/checkout/*", Mode: newrelic.InboundSampledHonor},
+		//		{RoutePattern: "/assets/*", Mode: newrelic.InboundSampledIgnore},
+		//	}
+		InboundSampled struct {
+			Mode  InboundSampledMode
+			Rules []InboundSampledRule
+		}
+		// SamplingRules set the fraction of transactions sampled for
+		// the transaction names they match.  Transactions whose name
+		// matches the NamePattern of a rule are sampled using the Rate
+		// of the first matching rule instead of the adaptive sampler,
+		// and do not count towards its sampling target.  This keeps
+		// important low volume transactions from being crowded out by
+		// high volume ones.  For example:
+		//
+		//	cfg.DistributedTracer.SamplingRules = []newrelic.SamplingRule{
+		//		{NamePattern: "* /checkout", Rate: 1},
+		//		{NamePattern: "GET /healthz", Rate: 0.01},
+		//	}
+		//
+		// The rules are only used when the transaction decides whether
+		// it is sampled, rather than using the decision of inbound
+		// distributed tracing headers.  The decision is made using the
+		// name of the transaction when it first creates a span or
+		// outbound headers, so a later call of Transaction.SetName does
+		// not change it.
+		SamplingRules []SamplingRule
+		// TailSampling decides which transactions' span events are
+		// sent when each transaction ends, rather than when it starts.
+		// Span events are buffered by every transaction, and sent only
+		// if the transaction noticed an error or its duration is at
+		// least LatencyThreshold, regardless of whether it was sampled.
+		// This keeps the span events which explain failures and
+		// latency while dropping those of fast, successful
+		// transactions.  Each service decides independently: the
+		// sampled flag of outbound distributed tracing headers is still
+		// the decision of the adaptive sampler.  TailSampling is not
+		// used with Infinite Tracing, where the trace observer samples
+		// traces.
+		TailSampling struct {
+			// Enabled controls whether tail sampling is used.
+			// Defaults to false.
+			Enabled bool
+			// LatencyThreshold is the duration at or above which
+			// a transaction's span events are sent.  Defaults to
+			// one second.
+			LatencyThreshold time.Duration
+		}
 	}
 
 	// SpanEvents controls behavior relating to Span Events.  Span Events
@@ -243,6 +548,58 @@ type Config struct {
 		Enabled bool
 		// Attributes controls the attributes included on Spans.
 		Attributes AttributeDestinationConfig
+		// PropagateAttributes lists the names of transaction attributes,
+		// such as a tenant or user identifier added using
+		// Transaction.AddAttribute, which are copied onto every span of
+		// the transaction when it ends, so that spans can be queried by
+		// them.  Both user attributes and agent attributes, such as
+		// "request.uri", may be listed.  Attributes already present on a
+		// span are not replaced, and attributes excluded from spans by the
+		// attribute configuration are not copied.  By default no
+		// attributes are copied and only the root span of each transaction
+		// carries the transaction's attributes.
+		PropagateAttributes []string
+		// AttributeNaming controls whether the agent attributes of span
+		// events use the New Relic names, such as "http.method",
+		// "db.instance", and "peer.hostname", the OpenTelemetry semantic
+		// convention names, such as "http.request.method", "db.namespace",
+		// and "server.address", or both.  With SpanAttributeNamingOTel or
+		// SpanAttributeNamingBoth, datastore spans also get "db.system",
+		// and spans with a known server port get "server.port".  This
+		// gives estates which mix OpenTelemetry and New Relic
+		// instrumentation one vocabulary for queries across services.
+		// Attributes are renamed after the attribute configuration is
+		// applied, so Attributes.Include and Attributes.Exclude use the New
+		// Relic names.  The default is SpanAttributeNamingNewRelic.
+		AttributeNaming SpanAttributeNaming
+	}
+
+	// CodeLevelMetrics controls the recording of the source code location
+	// which started each segment as the SpanAttributeCodeFunction,
+	// SpanAttributeCodeFilepath, and SpanAttributeCodeLineno span
+	// attributes.  These allow spans to be linked to source code.  The
+	// location is the first caller of the segment's start which is
+	// outside of the agent and the packages listed in IgnoredPrefixes.
+	// Finding it uses runtime.Callers, which adds overhead to every
+	// segment of the transactions whose span events are collected.  The
+	// attributes are not recorded for the segments of transactions which
+	// are not sampled.
+	CodeLevelMetrics struct {
+		// Enabled controls whether the code location is recorded.
+		// Defaults to false.
+		Enabled bool
+		// Depth is the maximum number of stack frames searched for
+		// the caller.  The attributes are not recorded if the caller
+		// is not found.  Defaults to 20.
+		Depth int
+		// IgnoredPrefixes are skipped when searching for the caller,
+		// in addition to the agent's own packages.  Each is compared
+		// with the start of the function's fully qualified name, eg.
+		// "github.com/my/app/internal/tracing.".  Defaults to
+		// "net/http." and "database/sql.", so that external and
+		// datastore segments started by the standard library are
+		// attributed to the code which made the request or query.
+		IgnoredPrefixes []string
 	}
 
 	// InfiniteTracing controls behavior related to Infinite Tracing tail based
@@ -297,19 +654,402 @@ type Config struct {
 		}
 	}
 
+	// ExternalProcedure, if set, is called with the request of each
+	// external segment started using StartExternalSegment or
+	// NewRoundTripper.  A non-empty result is used as the segment's
+	// Procedure in place of the request method, creating metrics such as
+	// "External/{host}/http/{procedure}".  Use this to split calls to a
+	// single URL, such as a SOAP or XML-RPC endpoint, by operation:
+	//
+	//	cfg.ExternalProcedure = newrelic.SOAPProcedure
+	//
+	// The function is called synchronously before the request is sent.
+	// If it reads the request body, it must replace the body so that the
+	// complete body is still sent.  Keep the number of distinct values
+	// small, since each creates its own metrics.
+	ExternalProcedure func(*http.Request) string `json:"-"`
+
+	// ExternalEntities attributes external segments to named entities by
+	// host.  The EntityName of the first rule whose HostPattern matches the
+	// host of an external segment replaces the host in the segment's
+	// metrics and span name, so that calls to several services behind a
+	// shared internal gateway appear as separate entities in service
+	// maps, or calls to several hosts of one service appear as one:
+	//
+	//	cfg.ExternalEntities = []newrelic.ExternalEntityRule{
+	//		{HostPattern: "billing.internal-alb.example.com", EntityName: "billing"},
+	//		{HostPattern: "*.search.example.com", EntityName: "search"},
+	//	}
+	//
+	// The SpanAttributeHTTPURL attribute keeps the actual host.
+	ExternalEntities []ExternalEntityRule
+
+	// ExternalErrors controls the noticing of errors for external segments
+	// whose responses have error status codes, so that failures of
+	// downstream services are attributed to the calling transaction
+	// without calls to Transaction.NoticeError.  The error is added to the
+	// external segment's span and has the attributes
+	// ExternalErrorAttributeURL, ExternalErrorAttributeHost, and
+	// ExternalErrorAttributeStatusCode.
+	//
+	// Rules override ServerErrors and ClientErrors for matching hosts:
+	//
+	//	cfg.ExternalErrors.ServerErrors = true
+	//	cfg.ExternalErrors.Rules = []newrelic.ExternalErrorRule{
+	//		// 404s from the users service mean the user is missing.
+	//		{HostPattern: "users.example.com", ServerErrors: true, ClientErrors: false},
+	//		// The search service is allowed to fail.
+	//		{HostPattern: "*.search.example.com"},
+	//	}
+	ExternalErrors struct {
+		// ServerErrors controls whether errors are noticed for
+		// responses with 5xx status codes.  Defaults to false.
+		ServerErrors bool
+		// ClientErrors controls whether errors are noticed for
+		// responses with 4xx status codes.  Defaults to false.
+		ClientErrors bool
+		// Rules are matched against the host of each external segment.
+		// The first matching rule is used in place of ServerErrors and
+		// ClientErrors.
+		Rules []ExternalErrorRule
+	}
+
+	// DeadlineBudget controls the attributes added to external and
+	// datastore segments when the transaction has a deadline.  The
+	// deadline is taken from the context passed to NewContext, the
+	// request passed to SetWebRequestHTTP, or Transaction.SetDeadline,
+	// whichever is earliest.  Each segment records the time remaining
+	// until the deadline when it started as SpanAttributeDeadlineRemaining
+	// and the fraction of that time it used as
+	// SpanAttributeDeadlineConsumed.
+	DeadlineBudget struct {
+		// Enabled controls whether deadline attributes are added.
+		Enabled bool
+		// Fraction is the fraction of the remaining time above which
+		// a segment is flagged with SpanAttributeDeadlineOverBudget.
+		// Segments which start after the deadline has passed are
+		// always flagged.  Defaults to 0.5.
+		Fraction float64
+	}
+
+	// ContextCancellation controls what is recorded when the context
+	// passed to NewContext, or the context of the request passed to
+	// SetWebRequestHTTP, is done before the transaction ends.  Only the
+	// first such context is watched, so that contexts derived for
+	// individual calls do not affect the transaction.
+	ContextCancellation struct {
+		// Enabled controls whether AttributeContextCancelled or
+		// AttributeContextDeadlineExceeded is added to the
+		// transaction.  Defaults to true.
+		Enabled bool
+		// NoticeErrors controls whether the context's error is also
+		// noticed as an error of the transaction.  Defaults to false.
+		NoticeErrors bool
+	}
+
+	// ClientIP controls the recording of the client's IP address on web
+	// transactions as AttributeRequestClientIP.  The address is never
+	// recorded when HighSecurity is enabled.
+	ClientIP struct {
+		// Enabled controls whether the client IP address is recorded.
+		// Defaults to false.
+		Enabled bool
+		// Anonymize zeroes the last octet of IPv4 addresses and the
+		// last 80 bits of IPv6 addresses before they are recorded.
+		// Defaults to true.
+		Anonymize bool
+		// TrustForwardedFor uses the first address in the
+		// X-Forwarded-For request header, when present, instead of the
+		// address of the connection.  Only enable this when the
+		// application is behind a proxy which sets the header, since
+		// clients can set it to any value.  Defaults to false.
+		TrustForwardedFor bool
+	}
+
+	// HealthChecks controls the ignoring of web transactions created by
+	// load balancer and orchestrator health checks, which would otherwise
+	// dominate the throughput and response time of many services.
+	HealthChecks struct {
+		// Enabled controls whether web transactions with a User-Agent
+		// request header matching UserAgents are ignored, as if
+		// Transaction.Ignore were called.  Defaults to true.
+		Enabled bool
+		// UserAgents is the list of User-Agent prefixes, matched
+		// case-insensitively, which identify health checks.  Defaults to
+		// the health checks of Kubernetes, AWS and Google Cloud load
+		// balancers, Consul, and Envoy.  Append to the defaults to add
+		// your own.
+		UserAgents []string
+	}
+
 	// Attributes controls which attributes are enabled and disabled globally.
 	// This setting affects all attribute destinations: Transaction Events,
 	// Error Events, Transaction Traces and segments, Traced Errors, Span
 	// Events, and Browser timing header.
 	Attributes AttributeDestinationConfig
 
+	// AttributeLimits controls the limits applied to custom attributes
+	// added to transactions, spans, errors, and custom events.  Values
+	// longer than MaxValueLength are truncated, and attributes which exceed
+	// the other limits are dropped.  The number of attributes truncated and
+	// dropped is reported with the "Supportability/Attributes/Truncated"
+	// and "Supportability/Attributes/Dropped" metrics.
+	//
+	// String values containing invalid UTF-8 or control characters other
+	// than tab, newline, and carriage return are coerced by replacing the
+	// invalid bytes and characters with the Unicode replacement character
+	// U+FFFD, unless RejectInvalidStrings is set.  The number of values
+	// coerced is reported with the "Supportability/Attributes/Coerced"
+	// metric.  The values of agent attributes, such as request headers,
+	// are always coerced.
+	AttributeLimits struct {
+		// MaxCount is the maximum number of custom attributes on a
+		// transaction or custom event.  The default and maximum is 64.
+		MaxCount int
+		// MaxKeyLength is the maximum length in bytes of attribute
+		// keys.  The default and maximum is 255.
+		MaxKeyLength int
+		// MaxValueLength is the maximum length in bytes of string
+		// attribute values.  The default is 255 and the maximum is 4095.
+		//
+		// When ApplicationLogging.Forwarding is enabled, the complete
+		// value of each custom transaction or span attribute which is
+		// truncated, such as those added using Transaction.AddAttribute
+		// and Segment.AddAttribute, is recorded as an INFO log event
+		// linked to the trace and span.  Its message is the attribute
+		// key followed by ": " and the value, truncated to 32KB.  These
+		// log events are not counted by the logging metrics.  The
+		// attributes of errors and custom events are not recorded.
+		MaxValueLength int
+		// OverflowHandler, if set, is also called with the complete value
+		// of each of these truncated attributes, whether or not log
+		// forwarding is enabled.  The AttributeOverflow provided
+		// contains the linking metadata of the trace and span.
+		// OverflowHandler is called synchronously on the goroutine which
+		// added the attribute, without the transaction locked, so it may
+		// call the methods of the Transaction.
+		OverflowHandler func(AttributeOverflow) `json:"-"`
+		// RejectInvalidStrings causes custom attributes whose string
+		// values contain invalid UTF-8 or control characters to be
+		// dropped, and an error to be returned, rather than coerced.
+		// Defaults to false.
+		RejectInvalidStrings bool
+	}
+
+	// IntegrationAttributes controls the agent attributes added to the
+	// segments and span events of individual integrations.  Unlike the
+	// include and exclude lists of Attributes, which apply to every
+	// attribute with a matching name, these settings only affect the
+	// segments of the named integration.
+	IntegrationAttributes struct {
+		// Exclude maps an integration to the names of the attributes
+		// which are not recorded on its segments.  Integrations are
+		// named by the Product of datastore segments, eg. "MongoDB",
+		// and the Library of external and message segments, eg. "http"
+		// for external segments using the default library.  The
+		// statement, query parameters, instance, and peer attributes
+		// excluded from a datastore product are also removed from its
+		// slow queries, whose query is then described by its operation
+		// and collection.  Names are matched case insensitively:
+		//
+		//	cfg.IntegrationAttributes.Exclude = map[string][]string{
+		//		"MongoDB": {newrelic.SpanAttributeDBStatement},
+		//		"http":    {newrelic.SpanAttributeHTTPURL},
+		//	}
+		Exclude map[string][]string
+	}
+
 	// RuntimeSampler controls the collection of runtime statistics like
-	// CPU/Memory usage, goroutine count, and GC pauses.
+	// CPU/Memory usage, goroutine count, and GC pauses.  On Go 1.20 and
+	// above, scheduler latency, GC CPU fraction, and mutex wait time are
+	// also collected using the runtime/metrics package.
 	RuntimeSampler struct {
 		// Enabled controls whether runtime statistics are captured.
 		Enabled bool
 	}
 
+	// ScalingSignal controls the ScalingSignal metrics, which are designed
+	// to be used by autoscalers, such as a Kubernetes
+	// HorizontalPodAutoscaler or a KEDA scaler, to scale out before the
+	// CPU is saturated.  Every five seconds the following are recorded:
+	//
+	//	ScalingSignal/QueueTime       the average queue time in seconds of
+	//	                              the web requests which arrived
+	//	ScalingSignal/InFlight        the transactions in progress per
+	//	                              GOMAXPROCS
+	//	ScalingSignal/CPU Utilization the CPU time used as a fraction of
+	//	                              GOMAXPROCS
+	//	ScalingSignal/Pressure        the largest of these divided by its
+	//	                              target
+	//
+	// A Pressure above 1 means the application is busier than its
+	// targets.  Scale on its average or maximum over each minute.  Queue
+	// time is measured using the X-Request-Start or X-Queue-Start header
+	// added by a load balancer.
+	ScalingSignal struct {
+		// Enabled controls whether the metrics are recorded.  Defaults
+		// to false.
+		Enabled bool
+		// TargetQueueTime, TargetInFlightPerProc, and
+		// TargetCPUUtilization are the values of the components at
+		// which the Pressure is 1.  They default to 50 milliseconds, 8
+		// transactions, and 0.7.
+		TargetQueueTime       time.Duration
+		TargetInFlightPerProc float64
+		TargetCPUUtilization  float64
+	}
+
+	// StartupSummary controls the single log line, written at info level
+	// when the Application is created, which summarizes the effective
+	// configuration: the application name, the collector host and its
+	// region, the enabled features, whether high security is enabled, and
+	// the integrations in use.  Comparing this line across a fleet is an
+	// easy way to verify that every instance is configured alike.
+	StartupSummary struct {
+		// Enabled controls whether the summary is logged.
+		Enabled bool
+	}
+
+	// TransactionProcessCPUTime controls the recording of the CPU time
+	// used by the process during each transaction as the
+	// AttributeProcessCPUTime, AttributeProcessCPUUserTime, and
+	// AttributeProcessCPUSystemTime attributes.  This is not the CPU time
+	// of the transaction itself: it is measured for the whole process
+	// between the start and end of the transaction, since Go offers no
+	// measure of the CPU time of a goroutine, and so includes the CPU time
+	// of concurrent transactions and background work.  It is most useful
+	// for distinguishing CPU-bound from wait-bound transactions when the
+	// ratio of CPU time to duration is compared across transactions.
+	TransactionProcessCPUTime struct {
+		// Enabled controls whether process CPU time is recorded.
+		// Defaults to false.
+		Enabled bool
+	}
+
+	// TransactionCheckpoints controls the checkpoint events recorded for
+	// long-running background transactions, such as ETL jobs, while they
+	// are still in progress.  Once a background transaction has been
+	// running for Threshold, a TransactionCheckpoint custom event is
+	// recorded every Interval until it ends.  Each event holds the
+	// transaction's name, elapsed time in seconds, number of segments
+	// started so far, and the checkpoint's sequence number, along with the
+	// custom attributes added to the transaction.  Progress can therefore
+	// be reported by updating an attribute with Transaction.AddAttribute.
+	// Checkpoint events are recorded in the custom event reservoir and
+	// require CustomInsightsEvents to be enabled.
+	TransactionCheckpoints struct {
+		// Enabled controls whether checkpoint events are recorded.
+		// Defaults to false.
+		Enabled bool
+		// Threshold is how long a background transaction runs before
+		// its first checkpoint.  Defaults to 5 minutes.
+		Threshold time.Duration
+		// Interval is the time between checkpoints.  Defaults to 1
+		// minute.
+		Interval time.Duration
+	}
+
+	// AnomalyDetection controls the flagging of unusually slow
+	// transactions.  When enabled, the agent keeps an exponentially
+	// weighted moving average of the duration of the transactions with
+	// each name.  Transactions which take longer than Factor times the
+	// average of their name are given the AttributeAnomaly attribute, and
+	// the average as the AttributeAnomalyBaseline attribute, so that they
+	// can be found by filtering rather than by computing percentiles.
+	AnomalyDetection struct {
+		// Enabled controls whether transactions are flagged.  Defaults
+		// to false.
+		Enabled bool
+		// Factor is the multiple of the average duration above which a
+		// transaction is flagged.  Defaults to 3.
+		Factor float64
+		// Weight is the weight, between 0 and 1, given to the duration
+		// of each transaction in the moving average.  Larger weights
+		// adapt to changes in duration more quickly.  Defaults to 0.1.
+		Weight float64
+		// MinSamples is the number of transactions with a name which
+		// must end before transactions with that name are flagged.
+		// Defaults to 20.
+		MinSamples int
+	}
+
+	// ContentionProfiling controls the recording of lock contention during
+	// sampled transactions.  When enabled, the runtime's block and mutex
+	// profiles are turned on when the application is created.  The
+	// profiles are read when transactions start and when sampled
+	// transactions end, and the delay added to the profiles and the sites
+	// with the most delay are recorded as the AttributeContentionBlockDelay,
+	// AttributeContentionMutexDelay, and AttributeContentionSites
+	// attributes.  Like CPU time, the profiles cover the whole process and
+	// so include contention in concurrent transactions.  Reading the
+	// profiles adds overhead which grows with the number of distinct
+	// contention sites, so they are read at most every 100 milliseconds
+	// and each read is shared by the transactions which start or end in
+	// that time.  The contention of short transactions is therefore
+	// approximate.
+	ContentionProfiling struct {
+		// Enabled controls whether contention is recorded.  Defaults
+		// to false.
+		Enabled bool
+		// BlockProfileRate is passed to runtime.SetBlockProfileRate
+		// when the application is created.  Defaults to 10000, which
+		// samples one blocking event per 10 microseconds spent blocked.
+		BlockProfileRate int
+		// MutexProfileFraction is passed to
+		// runtime.SetMutexProfileFraction when the application is
+		// created.  Defaults to 10, which samples one in ten contention
+		// events.
+		MutexProfileFraction int
+		// MaxSites is the maximum number of contention sites recorded
+		// for each transaction.  Defaults to 5.
+		MaxSites int
+	}
+
+	// OTLP controls the export of data using the OpenTelemetry Protocol
+	// instead of the New Relic collector protocol.  When Endpoint is set
+	// the application does not connect to New Relic: each harvest of span
+	// events, metrics, logs, error events, and custom events is sent to the
+	// endpoint using OTLP/HTTP with JSON encoding.  Span events are
+	// exported as spans, and logs forwarded using
+	// ApplicationLogging.Forwarding, error events, and custom events as log
+	// records.  Metrics are exported as sums with delta temporality of
+	// their totals and counts, the latter named with the ".count" suffix,
+	// except for sampled values such as the memory in use, which are
+	// exported as gauges, and apdex metrics, which are exported as sums of
+	// the transactions in each "apdex.zone".  Transaction traces, slow
+	// queries, traced errors, and transaction events are not exported.
+	// The License is optional when Endpoint is set.
+	//
+	// See ConfigOTLPEndpoint.
+	OTLP struct {
+		// Endpoint is the base URL of the OTLP/HTTP receiver, eg.
+		// "http://localhost:4318".  Data is sent to the "/v1/traces",
+		// "/v1/metrics", and "/v1/logs" paths beneath it.
+		Endpoint string
+		// Headers are added to each request sent to the Endpoint, eg.
+		// to provide an API key.
+		Headers map[string]string
+	}
+
+	// Spool controls the persistence of harvest payloads to disk when the
+	// collector cannot be reached.  Ordinarily the data of a failed
+	// harvest is kept in memory and discarded after a few more failures,
+	// and is lost if the process exits.  When Directory is set, payloads
+	// which could not be sent because the collector was unreachable or
+	// responded with a server error are written to the directory instead,
+	// and are sent after the next successful harvest, including by a later
+	// run of the process.
+	Spool struct {
+		// Directory is the directory, created if needed, in which
+		// payloads are written.  Spooling is disabled if empty.  Each
+		// process should use its own directory.
+		Directory string
+		// MaxBytes limits the total size of the spooled payloads.  The
+		// oldest payloads are removed to make space for new ones.
+		// Defaults to 100 MiB.
+		MaxBytes int64
+	}
+
 	// ServerlessMode contains fields which control behavior when running in
 	// AWS Lambda.
 	//
@@ -337,9 +1077,75 @@ type Config struct {
 		PrimaryAppID      string
 	}
 
+	// ShortLived optimizes the Application for processes which run for
+	// less than a minute, such as command line tools and cron jobs, which
+	// otherwise exit before the Application connects and so report
+	// nothing.  In short-lived mode the Application connects when data is
+	// first recorded, or when WaitForConnection or Shutdown is called,
+	// rather than when it is created.  Data recorded before the connect
+	// completes is kept rather than dropped, and Shutdown waits for the
+	// connect and then harvests all data before returning.  Shutdown waits
+	// for the connect for at most half of its timeout, leaving the rest for
+	// the harvest, and gives up once its timeout has elapsed.  The cloud
+	// provider, Docker, and Kubernetes detection usually performed before
+	// the connect is skipped.
+	//
+	// Transactions which start before the connect completes are not
+	// sampled for distributed tracing.  Use ConfigShortLived to enable
+	// short-lived mode.
+	ShortLived struct {
+		// Enabled controls whether short-lived mode is used.  Defaults
+		// to false.
+		Enabled bool
+	}
+
 	// Host can be used to override the New Relic endpoint.
 	Host string
 
+	// FailoverHosts is an ordered list of additional New Relic endpoints
+	// used when Host (or the endpoint derived from the License) is
+	// unavailable.  The application fails over to the next host after a
+	// connect failure or repeated harvest failures, and fails back to a
+	// higher priority host once it has been out of rotation for five
+	// minutes.
+	FailoverHosts []string
+
+	// Gateway sends the requests made to New Relic through a local
+	// telemetry gateway, such as a node-local proxy, instead of directly
+	// to Host.  Requests are sent to the gateway using plain HTTP with the
+	// New Relic endpoint as their Host header, and the gateway is
+	// responsible for forwarding them.  Transport is not used when
+	// Gateway.Address is set.  InfiniteTracing and OTLP export are not
+	// sent through the gateway.
+	Gateway struct {
+		// Address is the address of the gateway: either "unix:"
+		// followed by the path of a Unix domain socket, eg.
+		// "unix:/var/run/nr-gateway.sock", or a loopback host and port,
+		// eg. "localhost:8080" or "127.0.0.1:8080".  Since requests are
+		// sent to the gateway using plain HTTP, remote gateways are not
+		// allowed.
+		Address string
+		// Token, if set, is sent to the gateway in the
+		// X-NewRelic-Gateway-Token header of each request.  The License
+		// is optional when Address is set, so that the gateway may add
+		// the license itself and authenticate the agent using the Token
+		// instead.  Token is not included in the settings reported to
+		// New Relic.
+		Token string
+	}
+
+	// AllowedRegions restricts the New Relic regions to which data may be
+	// sent, eg. []string{"eu01"}.  The region of a collector host is the
+	// label preceding the nr-data.net domain, eg. "eu01" for
+	// "collector.eu01.nr-data.net", and "us01" for hosts in the
+	// newrelic.com domain.  NewApplication returns an error if Host (or
+	// the host derived from the License) or any of the FailoverHosts is
+	// not in an allowed region, and the application refuses to connect if
+	// the collector redirects it to a host outside the allowed regions.
+	// An empty list allows every region.  AllowedRegions does not apply
+	// to InfiniteTracing.TraceObserver.Host.
+	AllowedRegions []string
+
 	// Error may be populated by the ConfigOptions provided to NewApplication
 	// to indicate that setup has failed.  NewApplication will return this
 	// error if it is set.
@@ -378,13 +1184,21 @@ func defaultConfig() Config {
 
 	c.Enabled = true
 	c.Labels = make(map[string]string)
+	c.LabelAttributes.Prefix = "label."
+	c.LicenseRefreshPeriod = 10 * time.Minute
 	c.CustomInsightsEvents.Enabled = true
+	c.ApplicationLogging.Enabled = true
+	c.ApplicationLogging.Forwarding.MaxSamplesStored = internal.MaxLogEvents
+	c.ApplicationLogging.Metrics.Enabled = true
 	c.TransactionEvents.Enabled = true
 	c.TransactionEvents.Attributes.Enabled = true
 	c.TransactionEvents.MaxSamplesStored = internal.MaxTxnEvents
+	c.TransactionEvents.DistributedTracingIntrinsics = DistributedTracingIntrinsicsAll
 	c.HighSecurity = false
 	c.ErrorCollector.Enabled = true
 	c.ErrorCollector.CaptureEvents = true
+	c.ErrorCollector.StratifyEvents = true
+	c.ErrorCollector.MaxAttributeDepth = errorAttributeDepthDefault
 	c.ErrorCollector.IgnoreStatusCodes = []int{
 		// https://github.com/grpc/grpc/blob/master/doc/statuscodes.md
 		0,                   // gRPC OK
@@ -398,8 +1212,22 @@ func defaultConfig() Config {
 	c.Utilization.DetectGCP = true
 	c.Utilization.DetectDocker = true
 	c.Utilization.DetectKubernetes = true
+	c.Utilization.Timeout = 500 * time.Millisecond
 	c.Attributes.Enabled = true
 	c.RuntimeSampler.Enabled = true
+	c.ScalingSignal.TargetQueueTime = 50 * time.Millisecond
+	c.ScalingSignal.TargetInFlightPerProc = 8
+	c.ScalingSignal.TargetCPUUtilization = 0.7
+	c.StartupSummary.Enabled = true
+	c.EventHarvest.Adaptive.MinPeriod = 5 * time.Second
+	c.ContentionProfiling.BlockProfileRate = 10000
+	c.ContentionProfiling.MutexProfileFraction = 10
+	c.ContentionProfiling.MaxSites = 5
+	c.TransactionCheckpoints.Threshold = 5 * time.Minute
+	c.TransactionCheckpoints.Interval = time.Minute
+	c.AnomalyDetection.Factor = 3
+	c.AnomalyDetection.Weight = 0.1
+	c.AnomalyDetection.MinSamples = 20
 
 	c.TransactionTracer.Enabled = true
 	c.TransactionTracer.Threshold.IsApdexFailing = true
@@ -415,8 +1243,13 @@ func defaultConfig() Config {
 
 	c.CrossApplicationTracer.Enabled = true
 	c.DistributedTracer.Enabled = false
+	c.DistributedTracer.InboundSampled.Mode = InboundSampledHonor
+	c.DistributedTracer.TailSampling.LatencyThreshold = time.Second
 	c.SpanEvents.Enabled = true
 	c.SpanEvents.Attributes.Enabled = true
+	c.SpanEvents.AttributeNaming = SpanAttributeNamingNewRelic
+	c.CodeLevelMetrics.Depth = 20
+	c.CodeLevelMetrics.IgnoredPrefixes = []string{"net/http.", "database/sql."}
 
 	c.DatastoreTracer.InstanceReporting.Enabled = true
 	c.DatastoreTracer.DatabaseNameReporting.Enabled = true
@@ -424,12 +1257,30 @@ func defaultConfig() Config {
 	c.DatastoreTracer.SlowQuery.Enabled = true
 	c.DatastoreTracer.SlowQuery.Threshold = 10 * time.Millisecond
 
+	c.DeadlineBudget.Enabled = true
+	c.DeadlineBudget.Fraction = 0.5
+	c.ContextCancellation.Enabled = true
+	c.ClientIP.Anonymize = true
+	c.HealthChecks.Enabled = true
+	c.HealthChecks.UserAgents = []string{
+		"kube-probe/",
+		"ELB-HealthChecker/",
+		"GoogleHC/",
+		"Consul Health Check",
+		"Envoy/HC",
+	}
+
 	c.ServerlessMode.ApdexThreshold = 500 * time.Millisecond
 	c.ServerlessMode.Enabled = false
 
+	c.Spool.MaxBytes = spoolDefaultMaxBytes
+
 	c.Heroku.UseDynoNames = true
 	c.Heroku.DynoNamePrefixesToShorten = []string{"scheduler", "run"}
 
+	c.AttributeLimits.MaxCount = attributeUserLimit
+	c.AttributeLimits.MaxKeyLength = attributeKeyLengthLimit
+	c.AttributeLimits.MaxValueLength = attributeValueLengthLimit
 	c.InfiniteTracing.TraceObserver.Port = 443
 	c.InfiniteTracing.SpanEvents.QueueSize = 10000
 
@@ -448,12 +1299,26 @@ var (
 	errAppNameLimit                     = fmt.Errorf("max of %d rollup application names", appNameLimit)
 	errHighSecurityWithSecurityPolicies = errors.New("SecurityPoliciesToken and HighSecurity are incompatible; please ensure HighSecurity is set to false if SecurityPoliciesToken is a non-empty string and a security policy has been set for your account")
 	errInfTracingServerless             = errors.New("ServerlessMode cannot be used with Infinite Tracing")
+	errOTLPServerless                   = errors.New("ServerlessMode cannot be used with OTLP export")
+	errOTLPEndpoint                     = errors.New("OTLP.Endpoint must be an absolute http or https URL")
+	errGatewayAddress                   = errors.New("Gateway.Address must be \"unix:\" followed by a socket path, or a loopback host and port")
+	errAttributeLimits                  = fmt.Errorf("AttributeLimits must not exceed MaxCount %d, MaxKeyLength %d, and MaxValueLength %d",
+		attributeUserLimit, attributeKeyLengthLimit, attributeValueLengthMax)
+	errScalingSignalTargets  = errors.New("ScalingSignal targets must be positive and TargetCPUUtilization must not exceed 1")
+	errTailSamplingThreshold = errors.New("DistributedTracer.TailSampling.LatencyThreshold must be positive")
+	errTxnEventIntrinsics    = errors.New("TransactionEvents.DistributedTracingIntrinsics must be \"all\", \"trace\", or \"none\"")
+	errSpanAttributeNaming   = errors.New("SpanEvents.AttributeNaming must be \"newrelic\", \"otel\", or \"both\"")
+	errTxnCheckpoints        = errors.New("TransactionCheckpoints.Threshold and Interval must be positive")
+	errAnomalyDetection      = errors.New("AnomalyDetection.Factor must be greater than 1 and Weight must be greater than 0 and at most 1")
 )
 
 // validate checks the config for improper fields.  If the config is invalid,
 // newrelic.NewApplication returns an error.
 func (c Config) validate() error {
-	if c.Enabled && !c.ServerlessMode.Enabled {
+	if nil != c.LicenseProvider {
+		// The license returned by the LicenseProvider is validated
+		// when it is fetched.
+	} else if c.Enabled && !c.ServerlessMode.Enabled && "" == c.OTLP.Endpoint && "" == c.Gateway.Address {
 		if len(c.License) != licenseLength {
 			return errLicenseLen
 		}
@@ -472,9 +1337,64 @@ func (c Config) validate() error {
 	if strings.Count(c.AppName, ";") >= appNameLimit {
 		return errAppNameLimit
 	}
+	if err := c.validateApplicationSubsets(); nil != err {
+		return err
+	}
 	if "" != c.InfiniteTracing.TraceObserver.Host && c.ServerlessMode.Enabled {
 		return errInfTracingServerless
 	}
+	if err := c.validateOTLP(); nil != err {
+		return err
+	}
+	if err := c.validateGateway(); nil != err {
+		return err
+	}
+	if err := c.validateTxnEventIntrinsics(); nil != err {
+		return err
+	}
+	if err := c.validateSpanAttributeNaming(); nil != err {
+		return err
+	}
+	if err := c.validateLabels(); nil != err {
+		return err
+	}
+	if err := c.validateAllowedRegions(); nil != err {
+		return err
+	}
+	if err := c.validateExternalEntities(); nil != err {
+		return err
+	}
+	if err := c.validateExternalErrors(); nil != err {
+		return err
+	}
+	if err := c.validateInboundSampled(); nil != err {
+		return err
+	}
+	if err := c.validateSamplingRules(); nil != err {
+		return err
+	}
+	if err := c.validateCustomEventDerivations(); nil != err {
+		return err
+	}
+	if err := c.validateScalingSignal(); nil != err {
+		return err
+	}
+	if c.TransactionCheckpoints.Enabled &&
+		(c.TransactionCheckpoints.Threshold <= 0 || c.TransactionCheckpoints.Interval <= 0) {
+		return errTxnCheckpoints
+	}
+	if c.AnomalyDetection.Enabled &&
+		(c.AnomalyDetection.Factor <= 1 || c.AnomalyDetection.Weight <= 0 || c.AnomalyDetection.Weight > 1) {
+		return errAnomalyDetection
+	}
+	if c.DistributedTracer.TailSampling.Enabled && c.DistributedTracer.TailSampling.LatencyThreshold <= 0 {
+		return errTailSamplingThreshold
+	}
+	if c.AttributeLimits.MaxCount < 0 || c.AttributeLimits.MaxCount > attributeUserLimit ||
+		c.AttributeLimits.MaxKeyLength < 0 || c.AttributeLimits.MaxKeyLength > attributeKeyLengthLimit ||
+		c.AttributeLimits.MaxValueLength < 0 || c.AttributeLimits.MaxValueLength > attributeValueLengthMax {
+		return errAttributeLimits
+	}
 
 	return nil
 }
@@ -509,6 +1429,28 @@ func (c Config) maxTxnEvents() int {
 	return configured
 }
 
+// maxLogEvents returns the configured maximum number of Log Events if it has
+// been configured and is less than the default maximum; otherwise it returns
+// the default max.
+func (c Config) maxLogEvents() int {
+	configured := c.ApplicationLogging.Forwarding.MaxSamplesStored
+	if configured < 0 || configured > internal.MaxLogEvents {
+		return internal.MaxLogEvents
+	}
+	return configured
+}
+
+// eventHarvestConfig returns the event limits sent on connect.  The limit of
+// log events is only sent when log forwarding is enabled.
+func (c Config) eventHarvestConfig() internal.EventHarvestConfig {
+	cfg := internal.DefaultEventHarvestConfig(c.maxTxnEvents())
+	if c.ApplicationLogging.Enabled && c.ApplicationLogging.Forwarding.Enabled {
+		max := uint(c.maxLogEvents())
+		cfg.Limits.LogEvents = &max
+	}
+	return cfg
+}
+
 func copyDestConfig(c AttributeDestinationConfig) AttributeDestinationConfig {
 	cp := c
 	if nil != c.Include {
@@ -530,6 +1472,68 @@ func copyConfigReferenceFields(cfg Config) Config {
 			cp.Labels[key] = val
 		}
 	}
+	if nil != cfg.LabelHierarchies {
+		cp.LabelHierarchies = make([]LabelHierarchy, len(cfg.LabelHierarchies))
+		for i, h := range cfg.LabelHierarchies {
+			cp.LabelHierarchies[i] = append(LabelHierarchy(nil), h...)
+		}
+	}
+	if nil != cfg.ApplicationSubsets {
+		cp.ApplicationSubsets = make([]string, len(cfg.ApplicationSubsets))
+		copy(cp.ApplicationSubsets, cfg.ApplicationSubsets)
+	}
+	if nil != cfg.FailoverHosts {
+		cp.FailoverHosts = make([]string, len(cfg.FailoverHosts))
+		copy(cp.FailoverHosts, cfg.FailoverHosts)
+	}
+	if nil != cfg.AllowedRegions {
+		cp.AllowedRegions = make([]string, len(cfg.AllowedRegions))
+		copy(cp.AllowedRegions, cfg.AllowedRegions)
+	}
+	if nil != cfg.SpanEvents.PropagateAttributes {
+		cp.SpanEvents.PropagateAttributes = make([]string, len(cfg.SpanEvents.PropagateAttributes))
+		copy(cp.SpanEvents.PropagateAttributes, cfg.SpanEvents.PropagateAttributes)
+	}
+	if nil != cfg.CodeLevelMetrics.IgnoredPrefixes {
+		cp.CodeLevelMetrics.IgnoredPrefixes = make([]string, len(cfg.CodeLevelMetrics.IgnoredPrefixes))
+		copy(cp.CodeLevelMetrics.IgnoredPrefixes, cfg.CodeLevelMetrics.IgnoredPrefixes)
+	}
+	if nil != cfg.IntegrationAttributes.Exclude {
+		cp.IntegrationAttributes.Exclude = make(map[string][]string, len(cfg.IntegrationAttributes.Exclude))
+		for integration, names := range cfg.IntegrationAttributes.Exclude {
+			cp.IntegrationAttributes.Exclude[integration] = append([]string(nil), names...)
+		}
+	}
+	if nil != cfg.ExternalEntities {
+		cp.ExternalEntities = make([]ExternalEntityRule, len(cfg.ExternalEntities))
+		copy(cp.ExternalEntities, cfg.ExternalEntities)
+	}
+	if nil != cfg.DistributedTracer.InboundSampled.Rules {
+		cp.DistributedTracer.InboundSampled.Rules = make([]InboundSampledRule, len(cfg.DistributedTracer.InboundSampled.Rules))
+		copy(cp.DistributedTracer.InboundSampled.Rules, cfg.DistributedTracer.InboundSampled.Rules)
+	}
+	if nil != cfg.DistributedTracer.SamplingRules {
+		cp.DistributedTracer.SamplingRules = make([]SamplingRule, len(cfg.DistributedTracer.SamplingRules))
+		copy(cp.DistributedTracer.SamplingRules, cfg.DistributedTracer.SamplingRules)
+	}
+	if nil != cfg.CustomInsightsEvents.Derivations {
+		cp.CustomInsightsEvents.Derivations = make([]CustomEventDerivation, len(cfg.CustomInsightsEvents.Derivations))
+		copy(cp.CustomInsightsEvents.Derivations, cfg.CustomInsightsEvents.Derivations)
+	}
+	if nil != cfg.ExternalErrors.Rules {
+		cp.ExternalErrors.Rules = make([]ExternalErrorRule, len(cfg.ExternalErrors.Rules))
+		copy(cp.ExternalErrors.Rules, cfg.ExternalErrors.Rules)
+	}
+	if nil != cfg.OTLP.Headers {
+		cp.OTLP.Headers = make(map[string]string, len(cfg.OTLP.Headers))
+		for key, val := range cfg.OTLP.Headers {
+			cp.OTLP.Headers[key] = val
+		}
+	}
+	if nil != cfg.HealthChecks.UserAgents {
+		cp.HealthChecks.UserAgents = make([]string, len(cfg.HealthChecks.UserAgents))
+		copy(cp.HealthChecks.UserAgents, cfg.HealthChecks.UserAgents)
+	}
 	if nil != cfg.ErrorCollector.IgnoreStatusCodes {
 		ignored := make([]int, len(cfg.ErrorCollector.IgnoreStatusCodes))
 		copy(ignored, cfg.ErrorCollector.IgnoreStatusCodes)
@@ -555,6 +1559,9 @@ func transportSetting(t http.RoundTripper) interface{} {
 }
 
 func loggerSetting(lg Logger) interface{} {
+	if r, ok := lg.(*reloadableLogger); ok {
+		lg = r.get()
+	}
 	if nil == lg {
 		return nil
 	}
@@ -575,6 +1582,8 @@ func (s settings) MarshalJSON() ([]byte, error) {
 	c := Config(s)
 	transport := c.Transport
 	c.Transport = nil
+	sender := c.HarvestSender
+	c.HarvestSender = nil
 	l := c.Logger
 	c.Logger = nil
 
@@ -590,7 +1599,11 @@ func (s settings) MarshalJSON() ([]byte, error) {
 	// The License field is not simply ignored by adding the `json:"-"` tag
 	// to it since we want to allow consumers to populate Config from JSON.
 	delete(fields, `License`)
+	if gateway, ok := fields[`Gateway`].(map[string]interface{}); ok {
+		delete(gateway, `Token`)
+	}
 	fields[`Transport`] = transportSetting(transport)
+	fields[`HarvestSender`] = harvestSenderSetting(sender)
 	fields[`Logger`] = loggerSetting(l)
 
 	// Browser monitoring support.
@@ -663,7 +1676,7 @@ func configConnectJSONInternal(c Config, pid int, util *utilization.Data, e envi
 		Util:             util,
 		SecurityPolicies: securityPolicies,
 		Metadata:         metadata,
-		EventData:        internal.DefaultEventHarvestConfig(c.maxTxnEvents()),
+		EventData:        c.eventHarvestConfig(),
 	}})
 }
 
@@ -696,6 +1709,12 @@ type config struct {
 	metadata         map[string]string
 	hostname         string
 	traceObserverURL *observerURL
+	// fingerprint is the hash of the effective configuration returned by
+	// Application.ConfigFingerprint.
+	fingerprint string
+	// utilization caches the utilization data sent on connect.  It is
+	// created by newApp.
+	utilization *utilizationCache
 }
 
 func (c Config) computeDynoHostname(getenv func(string) string) string {
@@ -718,13 +1737,50 @@ func (c Config) computeDynoHostname(getenv func(string) string) string {
 	return dyno
 }
 
+var envReference = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+
+func expandEnvironment(s string, getenv func(string) string) string {
+	if !strings.Contains(s, "${") {
+		return s
+	}
+	return envReference.ReplaceAllStringFunc(s, func(ref string) string {
+		return getenv(ref[2 : len(ref)-1])
+	})
+}
+
+// expandEnvironment replaces environment variable references in the string
+// fields that support them.  It must be called on a copy of the Config
+// created by copyConfigReferenceFields.
+func (c *Config) expandEnvironment(getenv func(string) string) {
+	c.AppName = expandEnvironment(c.AppName, getenv)
+	c.HostDisplayName = expandEnvironment(c.HostDisplayName, getenv)
+	c.Host = expandEnvironment(c.Host, getenv)
+	c.InfiniteTracing.TraceObserver.Host = expandEnvironment(c.InfiniteTracing.TraceObserver.Host, getenv)
+	for i, host := range c.FailoverHosts {
+		c.FailoverHosts[i] = expandEnvironment(host, getenv)
+	}
+	if nil != c.Labels {
+		labels := make(map[string]string, len(c.Labels))
+		for key, val := range c.Labels {
+			labels[expandEnvironment(key, getenv)] = expandEnvironment(val, getenv)
+		}
+		c.Labels = labels
+	}
+}
+
 func newInternalConfig(cfg Config, getenv func(string) string, environ []string) (config, error) {
 	// Copy maps and slices to prevent race conditions if a consumer changes
 	// them after calling NewApplication.
 	cfg = copyConfigReferenceFields(cfg)
+	cfg.expandEnvironment(getenv)
+	if nil != cfg.LicenseProvider {
+		// The license is fetched when the application connects.
+		cfg.License = ""
+	}
 	if err := cfg.validate(); nil != err {
 		return config{}, err
 	}
+	cfg.Labels = cfg.labels()
 	obsURL, err := cfg.validateTraceObserverConfig()
 	if err != nil {
 		return config{}, err
@@ -741,29 +1797,106 @@ func newInternalConfig(cfg Config, getenv func(string) string, environ []string)
 	} else {
 		hostname = "unknown"
 	}
+	fingerprint, err := configFingerprint(cfg)
+	if nil != err {
+		return config{}, err
+	}
 	return config{
 		Config:           cfg,
 		metadata:         gatherMetadata(environ),
 		hostname:         hostname,
 		traceObserverURL: obsURL,
+		fingerprint:      fingerprint,
 	}, nil
 }
 
-func (c config) createConnectJSON(securityPolicies *internal.SecurityPolicies) ([]byte, error) {
-	env := newEnvironment()
-	util := utilization.Gather(utilization.Config{
-		DetectAWS:         c.Utilization.DetectAWS,
-		DetectAzure:       c.Utilization.DetectAzure,
-		DetectPCF:         c.Utilization.DetectPCF,
-		DetectGCP:         c.Utilization.DetectGCP,
-		DetectDocker:      c.Utilization.DetectDocker,
-		DetectKubernetes:  c.Utilization.DetectKubernetes,
+var hostDisplayNameToken = regexp.MustCompile(`\{(hostname|pod|region)\}`)
+
+// hostDisplayName returns HostDisplayName with its {hostname}, {pod}, and
+// {region} tokens replaced.  Tokens which cannot be resolved are replaced
+// with "unknown".
+func (c config) hostDisplayName(util *utilization.Data, getenv func(string) string) string {
+	if !strings.Contains(c.HostDisplayName, "{") {
+		return c.HostDisplayName
+	}
+	return hostDisplayNameToken.ReplaceAllStringFunc(c.HostDisplayName, func(token string) string {
+		var val string
+		switch token {
+		case "{hostname}":
+			val = util.Hostname
+		case "{pod}":
+			// Kubernetes sets the container hostname to the
+			// pod name.  POD_NAME is commonly populated using
+			// the downward API.
+			if val = getenv("POD_NAME"); "" == val {
+				val = getenv("HOSTNAME")
+			}
+		case "{region}":
+			val = util.Region()
+		}
+		if "" == val {
+			return "unknown"
+		}
+		return val
+	})
+}
+
+// utilizationConfig returns the settings used to gather utilization data.
+// Detection is skipped in short-lived mode, since querying the cloud
+// provider metadata endpoints can take longer than the process runs.
+func (c *config) utilizationConfig() utilization.Config {
+	detect := !c.ShortLived.Enabled
+	return utilization.Config{
+		DetectAWS:         detect && c.Utilization.DetectAWS,
+		DetectAzure:       detect && c.Utilization.DetectAzure,
+		DetectPCF:         detect && c.Utilization.DetectPCF,
+		DetectGCP:         detect && c.Utilization.DetectGCP,
+		DetectDocker:      detect && c.Utilization.DetectDocker,
+		DetectKubernetes:  detect && c.Utilization.DetectKubernetes,
 		LogicalProcessors: c.Utilization.LogicalProcessors,
 		TotalRAMMIB:       c.Utilization.TotalRAMMIB,
 		BillingHostname:   c.Utilization.BillingHostname,
 		Hostname:          c.hostname,
-	}, c.Logger)
-	return configConnectJSONInternal(c.Config, os.Getpid(), util, env, Version, securityPolicies, c.metadata)
+		ProviderTimeout:   c.Utilization.Timeout,
+		ProviderRetries:   c.Utilization.Retries,
+	}
+}
+
+// utilizationWait returns how long connecting waits for the utilization
+// data to be gathered before connecting using the data which does not
+// require network requests: long enough for the requests to each cloud
+// provider's metadata endpoints, made concurrently, to time out.  AWS
+// detection makes two requests per attempt.
+func (c *config) utilizationWait() time.Duration {
+	timeout := c.Utilization.Timeout
+	if timeout <= 0 {
+		// The utilization package uses the spec's timeout.
+		timeout = 500 * time.Millisecond
+	}
+	attempts := 1
+	if c.Utilization.Retries > 0 {
+		attempts += c.Utilization.Retries
+	}
+	return 2 * timeout * time.Duration(attempts)
+}
+
+// gatherUtilization returns the utilization data from the application's
+// cache, or gathers it if there is no cache.
+func (c *config) gatherUtilization() *utilization.Data {
+	if nil != c.utilization {
+		return c.utilization.get()
+	}
+	return utilization.Gather(c.utilizationConfig(), c.Logger)
+}
+
+// createConnectJSON creates the connect payload.  HostDisplayName tokens are
+// resolved using the utilization data gathered, and the resolved name is
+// stored in the config so that it is also used for AttributeHostDisplayName.
+func (c *config) createConnectJSON(securityPolicies *internal.SecurityPolicies) ([]byte, error) {
+	env := newEnvironment()
+	util := c.gatherUtilization()
+	c.HostDisplayName = c.hostDisplayName(util, os.Getenv)
+	return configConnectJSONInternal(c.Config, os.Getpid(), util, env, Version, securityPolicies, c.connectMetadata())
 }
 
 var (
@@ -781,3 +1914,18 @@ func (c config) preconnectHost() string {
 	}
 	return preconnectHostDefault
 }
+
+// preconnectHosts returns the preconnect host followed by the configured
+// failover hosts in priority order.  Duplicates and empty hosts are removed.
+func (c config) preconnectHosts() []string {
+	hosts := []string{c.preconnectHost()}
+	seen := map[string]bool{hosts[0]: true}
+	for _, h := range c.FailoverHosts {
+		if "" == h || seen[h] {
+			continue
+		}
+		seen[h] = true
+		hosts = append(hosts, h)
+	}
+	return hosts
+}
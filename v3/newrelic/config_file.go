@@ -0,0 +1,287 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// configFileAliases maps the names used in the configuration files of other
+// New Relic agents to Config fields.  Keys are normalized by
+// configFileKey.
+var configFileAliases = map[string]string{
+	"licensekey":         "License",
+	"distributedtracing": "DistributedTracer",
+}
+
+var (
+	configFileEnvRegex = regexp.MustCompile(`\$\{([A-Za-z_][A-Za-z0-9_]*)(:-([^}]*))?\}`)
+	durationType       = reflect.TypeOf(time.Duration(0))
+)
+
+// ConfigFromJSON populates the config from the JSON file at path.  The file
+// contains an object whose keys are the names of Config fields, with nested
+// objects for nested fields, eg.
+//
+//	{
+//		"app_name": "my app",
+//		"license": "${NEW_RELIC_LICENSE_KEY}",
+//		"distributed_tracer": {"enabled": true},
+//		"labels": {"team": "payments"},
+//		"transaction_tracer": {"threshold": {"duration": "500ms"}}
+//	}
+//
+// Keys are matched against field names ignoring case, underscores, and
+// hyphens, so "app_name", "appName", and "AppName" are equivalent.
+// "license_key" and "distributed_tracing" are accepted for License and
+// DistributedTracer.  Durations may be given as strings parsed by
+// time.ParseDuration.  Fields which are not present in the file are left
+// unchanged, so options applied before ConfigFromJSON provide defaults and
+// options applied after it override the file.
+//
+// Once the file is parsed, "${NAME}" within string values is replaced with
+// the value of the environment variable NAME, and "${NAME:-default}" with the
+// value of NAME or "default" if NAME is unset or empty.  The values of
+// environment variables are never parsed, so they cannot change the structure
+// of the file.  Strings are accepted for boolean and numeric fields so that
+// they may be set using environment variables, eg. "${NR_ENABLED:-true}".
+//
+// This function is strict and will assign Config.Error if the file cannot be
+// read or parsed, or if it contains a key which does not match a field.
+// Fields such as Logger and Transport cannot be set from a file.
+func ConfigFromJSON(path string) ConfigOption {
+	return configFromFile(path, func(data []byte) (interface{}, error) {
+		var v interface{}
+		d := json.NewDecoder(bytes.NewReader(data))
+		d.UseNumber()
+		if err := d.Decode(&v); nil != err {
+			return nil, err
+		}
+		return v, nil
+	})
+}
+
+// ConfigFromYAML populates the config from the YAML file at path.  The file
+// is interpreted the same way as the files read by ConfigFromJSON, eg.
+//
+//	app_name: my app
+//	license_key: ${NEW_RELIC_LICENSE_KEY}
+//	distributed_tracer:
+//	  enabled: true
+//	labels:
+//	  team: payments
+//	transaction_tracer:
+//	  threshold:
+//	    duration: 500ms
+//
+// The subset of YAML used by configuration files is supported:  mappings,
+// sequences, quoted and plain scalars, and comments.  Anchors, tags, and
+// multi-line scalars are not.
+func ConfigFromYAML(path string) ConfigOption {
+	return configFromFile(path, parseYAML)
+}
+
+func configFromFile(path string, parse func([]byte) (interface{}, error)) ConfigOption {
+	return func(cfg *Config) {
+		if err := cfg.loadFile(path, parse); nil != err {
+			cfg.Error = fmt.Errorf("unable to load config file %s: %v", path, err)
+		}
+	}
+}
+
+func (cfg *Config) loadFile(path string, parse func([]byte) (interface{}, error)) error {
+	data, err := ioutil.ReadFile(path)
+	if nil != err {
+		return err
+	}
+	v, err := parse(data)
+	if nil != err {
+		return err
+	}
+	if nil == v {
+		return nil
+	}
+	v = interpolateConfigEnv(v, os.Getenv)
+	v, err = configFileValue(v, reflect.TypeOf(*cfg), "")
+	if nil != err {
+		return err
+	}
+	js, err := json.Marshal(v)
+	if nil != err {
+		return err
+	}
+	// The file is decoded into a copy so that the config is unchanged if
+	// it contains a value of the wrong type.  Maps are copied since
+	// decoding adds to them.
+	c := copyConfigReferenceFields(*cfg)
+	if err := json.Unmarshal(js, &c); nil != err {
+		return err
+	}
+	*cfg = c
+	return nil
+}
+
+// interpolateConfigEnv replaces the references to environment variables in
+// the string values of the parsed file.
+func interpolateConfigEnv(v interface{}, getenv func(string) string) interface{} {
+	switch x := v.(type) {
+	case string:
+		return interpolateConfigString(x, getenv)
+	case map[string]interface{}:
+		for key, val := range x {
+			x[key] = interpolateConfigEnv(val, getenv)
+		}
+	case []interface{}:
+		for i, val := range x {
+			x[i] = interpolateConfigEnv(val, getenv)
+		}
+	}
+	return v
+}
+
+func interpolateConfigString(s string, getenv func(string) string) string {
+	return configFileEnvRegex.ReplaceAllStringFunc(s, func(m string) string {
+		sub := configFileEnvRegex.FindStringSubmatch(m)
+		if v := getenv(sub[1]); "" != v {
+			return v
+		}
+		return sub[3]
+	})
+}
+
+func configFileKey(key string) string {
+	key = strings.Replace(key, "_", "", -1)
+	key = strings.Replace(key, "-", "", -1)
+	return strings.ToLower(key)
+}
+
+// configFileField returns the field of the struct type matching the key.
+func configFileField(t reflect.Type, key string) (reflect.StructField, bool) {
+	k := configFileKey(key)
+	if alias, ok := configFileAliases[k]; ok {
+		if f, ok := t.FieldByName(alias); ok {
+			return f, true
+		}
+	}
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if "" != f.PkgPath || "-" == f.Tag.Get("json") {
+			continue
+		}
+		if k == strings.ToLower(f.Name) {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
+
+// configFileValue converts the value decoded from a config file into a value
+// which encoding/json will decode into a field of type t:  struct keys are
+// replaced with field names and duration strings are parsed.
+func configFileValue(v interface{}, t reflect.Type, name string) (interface{}, error) {
+	if nil == v {
+		return nil, nil
+	}
+	if durationType == t {
+		if s, ok := v.(string); ok {
+			d, err := time.ParseDuration(s)
+			if nil != err {
+				return nil, fmt.Errorf("invalid duration for %s: %q", name, s)
+			}
+			return int64(d), nil
+		}
+		return v, nil
+	}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return configFileValue(v, t.Elem(), name)
+	case reflect.Struct:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a mapping", name)
+		}
+		out := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			fieldName := key
+			if "" != name {
+				fieldName = name + "." + key
+			}
+			f, ok := configFileField(t, key)
+			if !ok {
+				return nil, fmt.Errorf("unknown field %s", fieldName)
+			}
+			if k := f.Type.Kind(); reflect.Interface == k || reflect.Func == k || reflect.Chan == k {
+				return nil, fmt.Errorf("field %s cannot be set from a config file", fieldName)
+			}
+			conv, err := configFileValue(val, f.Type, fieldName)
+			if nil != err {
+				return nil, err
+			}
+			out[f.Name] = conv
+		}
+		return out, nil
+	case reflect.Map:
+		m, ok := v.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a mapping", name)
+		}
+		out := make(map[string]interface{}, len(m))
+		for key, val := range m {
+			conv, err := configFileValue(val, t.Elem(), name+"."+key)
+			if nil != err {
+				return nil, err
+			}
+			out[key] = conv
+		}
+		return out, nil
+	case reflect.Slice:
+		s, ok := v.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%s must be a sequence", name)
+		}
+		out := make([]interface{}, len(s))
+		for i, val := range s {
+			conv, err := configFileValue(val, t.Elem(), fmt.Sprintf("%s[%d]", name, i))
+			if nil != err {
+				return nil, err
+			}
+			out[i] = conv
+		}
+		return out, nil
+	case reflect.String:
+		// Plain YAML scalars such as 2 or true are accepted for string
+		// fields, eg. labels.
+		switch val := v.(type) {
+		case bool, json.Number:
+			return fmt.Sprint(val), nil
+		}
+	case reflect.Bool:
+		if s, ok := v.(string); ok {
+			b, err := strconv.ParseBool(s)
+			if nil != err {
+				return nil, fmt.Errorf("invalid boolean for %s: %q", name, s)
+			}
+			return b, nil
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		if s, ok := v.(string); ok {
+			if _, err := strconv.ParseFloat(s, 64); nil != err {
+				return nil, fmt.Errorf("invalid number for %s: %q", name, s)
+			}
+			return json.Number(s), nil
+		}
+	}
+	return v, nil
+}
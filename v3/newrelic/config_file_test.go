@@ -0,0 +1,246 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, contents string) (string, func()) {
+	dir, err := ioutil.TempDir("", "config")
+	if nil != err {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, name)
+	if err := ioutil.WriteFile(path, []byte(contents), 0600); nil != err {
+		t.Fatal(err)
+	}
+	return path, func() { os.RemoveAll(dir) }
+}
+
+const testConfigYAML = `
+# Shared settings for the payments services.
+app_name: payments;all-services
+license_key: ${TEST_CONFIG_FILE_LICENSE}
+host: ${TEST_CONFIG_FILE_UNSET:-collector.example.com}
+distributed_tracing:
+  enabled: true
+labels:
+  team: payments
+  tier: 1
+attributes:
+  exclude: [request.headers.cookie, "request.headers.authorization"]
+transaction_tracer:
+  threshold:
+    is_apdex_failing: false
+    duration: 500ms
+error_collector:
+  ignore_status_codes:
+    - 404
+    - 429
+external_errors:
+  rules:
+    - host_pattern: "*.internal"
+      server_errors: true
+`
+
+const testConfigJSON = `{
+	"AppName": "payments;all-services",
+	"license_key": "${TEST_CONFIG_FILE_LICENSE}",
+	"host": "${TEST_CONFIG_FILE_UNSET:-collector.example.com}",
+	"DistributedTracer": {"Enabled": true},
+	"labels": {"team": "payments", "tier": "1"},
+	"attributes": {"exclude": ["request.headers.cookie", "request.headers.authorization"]},
+	"transaction-tracer": {"threshold": {"isApdexFailing": false, "duration": "500ms"}},
+	"error_collector": {"ignore_status_codes": [404, 429]},
+	"external_errors": {"rules": [{"host_pattern": "*.internal", "server_errors": true}]}
+}`
+
+func testConfigFileContents(t *testing.T, cfg Config) {
+	if nil != cfg.Error {
+		t.Fatal(cfg.Error)
+	}
+	if cfg.AppName != "payments;all-services" {
+		t.Error(cfg.AppName)
+	}
+	if cfg.License != "0123456789012345678901234567890123456789" {
+		t.Error(cfg.License)
+	}
+	if cfg.Host != "collector.example.com" {
+		t.Error(cfg.Host)
+	}
+	if !cfg.DistributedTracer.Enabled {
+		t.Error("distributed tracing not enabled")
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "payments", "tier": "1"}) {
+		t.Error(cfg.Labels)
+	}
+	if !reflect.DeepEqual(cfg.Attributes.Exclude, []string{"request.headers.cookie", "request.headers.authorization"}) {
+		t.Error(cfg.Attributes.Exclude)
+	}
+	if cfg.TransactionTracer.Threshold.IsApdexFailing || cfg.TransactionTracer.Threshold.Duration != 500*time.Millisecond {
+		t.Error(cfg.TransactionTracer.Threshold)
+	}
+	if !reflect.DeepEqual(cfg.ErrorCollector.IgnoreStatusCodes, []int{404, 429}) {
+		t.Error(cfg.ErrorCollector.IgnoreStatusCodes)
+	}
+	if !reflect.DeepEqual(cfg.ExternalErrors.Rules, []ExternalErrorRule{{HostPattern: "*.internal", ServerErrors: true}}) {
+		t.Error(cfg.ExternalErrors.Rules)
+	}
+	// Fields absent from the file keep their defaults.
+	if !cfg.Enabled || !cfg.TransactionTracer.Enabled {
+		t.Error(cfg.Enabled, cfg.TransactionTracer.Enabled)
+	}
+}
+
+func TestConfigFromYAML(t *testing.T) {
+	os.Setenv("TEST_CONFIG_FILE_LICENSE", "0123456789012345678901234567890123456789")
+	defer os.Unsetenv("TEST_CONFIG_FILE_LICENSE")
+
+	path, cleanup := writeConfigFile(t, "newrelic.yml", testConfigYAML)
+	defer cleanup()
+
+	cfg := defaultConfig()
+	ConfigFromYAML(path)(&cfg)
+	testConfigFileContents(t, cfg)
+}
+
+func TestConfigFromJSON(t *testing.T) {
+	os.Setenv("TEST_CONFIG_FILE_LICENSE", "0123456789012345678901234567890123456789")
+	defer os.Unsetenv("TEST_CONFIG_FILE_LICENSE")
+
+	path, cleanup := writeConfigFile(t, "newrelic.json", testConfigJSON)
+	defer cleanup()
+
+	cfg := defaultConfig()
+	ConfigFromJSON(path)(&cfg)
+	testConfigFileContents(t, cfg)
+}
+
+func TestConfigFromFileErrors(t *testing.T) {
+	testcases := []struct {
+		contents string
+		expect   string
+	}{
+		{contents: "app_nam: my app", expect: "unknown field app_nam"},
+		{contents: "transaction_tracer:\n  treshold: 1", expect: "unknown field transaction_tracer.treshold"},
+		{contents: "logger: stdout", expect: "field logger cannot be set from a config file"},
+		{contents: "transaction_tracer:\n  threshold:\n    duration: soon", expect: `invalid duration for transaction_tracer.threshold.duration: "soon"`},
+		{contents: "labels: [a, b]", expect: "labels must be a mapping"},
+		{contents: "attributes:\n  exclude: cookie", expect: "attributes.exclude must be a sequence"},
+		{contents: "enabled: maybe", expect: `invalid boolean for enabled: "maybe"`},
+		{contents: "labels:\n  team: [a]", expect: "cannot unmarshal"},
+		{contents: "a: [", expect: "unterminated flow collection"},
+	}
+	for _, tc := range testcases {
+		path, cleanup := writeConfigFile(t, "newrelic.yml", tc.contents)
+		cfg := defaultConfig()
+		ConfigFromYAML(path)(&cfg)
+		cleanup()
+		if nil == cfg.Error || !strings.Contains(cfg.Error.Error(), tc.expect) {
+			t.Error(tc.contents, cfg.Error)
+		}
+		if !cfg.Enabled {
+			t.Error("config changed despite error", tc.contents)
+		}
+	}
+
+	cfg := defaultConfig()
+	ConfigFromJSON("/does/not/exist.json")(&cfg)
+	if nil == cfg.Error {
+		t.Error("missing file should be an error")
+	}
+}
+
+func TestConfigFromFileEnvNotParsed(t *testing.T) {
+	// The values of environment variables cannot change the structure of
+	// the file.
+	os.Setenv("TEST_CONFIG_FILE_NAME", "my app # comment\nhigh_security: true")
+	os.Setenv("TEST_CONFIG_FILE_ENABLED", "false")
+	defer os.Unsetenv("TEST_CONFIG_FILE_NAME")
+	defer os.Unsetenv("TEST_CONFIG_FILE_ENABLED")
+
+	path, cleanup := writeConfigFile(t, "newrelic.yml", "app_name: ${TEST_CONFIG_FILE_NAME}\nenabled: ${TEST_CONFIG_FILE_ENABLED}\nlabels:\n  team: ${TEST_CONFIG_FILE_UNSET:-payments}")
+	defer cleanup()
+
+	cfg := defaultConfig()
+	ConfigFromYAML(path)(&cfg)
+	if nil != cfg.Error {
+		t.Fatal(cfg.Error)
+	}
+	if "my app # comment\nhigh_security: true" != cfg.AppName || cfg.HighSecurity {
+		t.Error(cfg.AppName, cfg.HighSecurity)
+	}
+	if cfg.Enabled {
+		t.Error("enabled not set from the environment")
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"team": "payments"}) {
+		t.Error(cfg.Labels)
+	}
+}
+
+func TestConfigFromFileErrorLeavesMapsUnchanged(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "newrelic.yml", "labels:\n  team: payments\ntransaction_tracer:\n  enabled: [a]")
+	defer cleanup()
+
+	cfg := defaultConfig()
+	cfg.Labels = map[string]string{"tier": "1"}
+	ConfigFromYAML(path)(&cfg)
+	if nil == cfg.Error {
+		t.Fatal("invalid file accepted")
+	}
+	if !reflect.DeepEqual(cfg.Labels, map[string]string{"tier": "1"}) {
+		t.Error(cfg.Labels)
+	}
+}
+
+func TestConfigFromFileOptionOrder(t *testing.T) {
+	path, cleanup := writeConfigFile(t, "newrelic.yml", "app_name: from file\nhigh_security: true")
+	defer cleanup()
+
+	app, err := NewApplication(
+		ConfigAppName("before"),
+		ConfigFromYAML(path),
+		ConfigEnabled(false),
+		func(cfg *Config) {
+			if "from file" != cfg.AppName || !cfg.HighSecurity {
+				t.Error(cfg.AppName, cfg.HighSecurity)
+			}
+		},
+	)
+	if nil != err || nil == app {
+		t.Fatal(err)
+	}
+}
+
+func TestInterpolateConfigEnv(t *testing.T) {
+	getenv := func(name string) string {
+		if "SET" == name {
+			return "value"
+		}
+		return ""
+	}
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{input: "${SET}", expect: "value"},
+		{input: "a-${SET}-b", expect: "a-value-b"},
+		{input: "${UNSET}", expect: ""},
+		{input: "${UNSET:-fallback}", expect: "fallback"},
+		{input: "${SET:-fallback}", expect: "value"},
+		{input: "$SET and ${not valid}", expect: "$SET and ${not valid}"},
+	}
+	for _, tc := range testcases {
+		if out := interpolateConfigEnv(tc.input, getenv); out != tc.expect {
+			t.Error(tc.input, out)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+)
+
+// configFingerprintMetadataKey is the connect metadata key of the
+// configuration fingerprint.
+const configFingerprintMetadataKey = metadataPrefix + "CONFIG_FINGERPRINT"
+
+// configFingerprint returns the hex encoded SHA-256 hash of the settings sent
+// to New Relic when connecting.  Settings which identify the host rather than
+// configure the agent are excluded so that identically configured instances
+// have the same fingerprint.
+func configFingerprint(c Config) (string, error) {
+	c.HostDisplayName = ""
+	c.Utilization.BillingHostname = ""
+	js, err := json.Marshal(settings(c))
+	if nil != err {
+		return "", err
+	}
+	sum := sha256.Sum256(js)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// connectMetadata returns the metadata sent to New Relic when connecting: the
+// NEW_RELIC_METADATA_ environment variables and the configuration
+// fingerprint.
+func (c config) connectMetadata() map[string]string {
+	metadata := make(map[string]string, len(c.metadata)+1)
+	for k, v := range c.metadata {
+		metadata[k] = v
+	}
+	if "" != c.fingerprint {
+		metadata[configFingerprintMetadataKey] = c.fingerprint
+	}
+	return metadata
+}
+
+// ConfigFingerprint implements newrelic.Application's ConfigFingerprint.
+func (app *app) ConfigFingerprint() string {
+	if nil == app {
+		return ""
+	}
+	return app.config.fingerprint
+}
@@ -0,0 +1,96 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal/utilization"
+)
+
+func fingerprintConfig(t *testing.T, cfgfn func(*Config)) string {
+	cfg := defaultConfig()
+	cfg.AppName = "my app"
+	cfg.License = testLicenseKey
+	if nil != cfgfn {
+		cfgfn(&cfg)
+	}
+	c, err := newInternalConfig(cfg, func(string) string { return "" }, nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return c.fingerprint
+}
+
+func TestConfigFingerprint(t *testing.T) {
+	base := fingerprintConfig(t, nil)
+	if len(base) != 64 {
+		t.Fatal(base)
+	}
+	same := map[string]func(*Config){
+		"license":          func(cfg *Config) { cfg.License = "9876543210987654321098765432109876543210" },
+		"display name":     func(cfg *Config) { cfg.HostDisplayName = "pod-1234" },
+		"billing hostname": func(cfg *Config) { cfg.Utilization.BillingHostname = "node-7" },
+	}
+	for name, fn := range same {
+		if fp := fingerprintConfig(t, fn); fp != base {
+			t.Error(name, "should not change the fingerprint")
+		}
+	}
+	different := map[string]func(*Config){
+		"app name":            func(cfg *Config) { cfg.AppName = "other app" },
+		"distributed tracing": func(cfg *Config) { cfg.DistributedTracer.Enabled = true },
+		"labels":              func(cfg *Config) { cfg.Labels = map[string]string{"zip": "zap"} },
+		"attributes":          func(cfg *Config) { cfg.Attributes.Exclude = []string{"request.uri"} },
+	}
+	for name, fn := range different {
+		if fp := fingerprintConfig(t, fn); fp == base {
+			t.Error(name, "should change the fingerprint")
+		}
+	}
+}
+
+func TestConfigFingerprintConnectMetadata(t *testing.T) {
+	c := config{
+		Config:      defaultConfig(),
+		metadata:    map[string]string{"NEW_RELIC_METADATA_ZIP": "ZAP"},
+		fingerprint: "abc",
+	}
+	if md := c.connectMetadata(); !reflect.DeepEqual(md, map[string]string{
+		"NEW_RELIC_METADATA_ZIP":                "ZAP",
+		"NEW_RELIC_METADATA_CONFIG_FINGERPRINT": "abc",
+	}) {
+		t.Error(md)
+	}
+	if len(c.metadata) != 1 {
+		t.Error("metadata should not be modified", c.metadata)
+	}
+
+	js, err := configConnectJSONInternal(c.Config, 123, &utilization.SampleData, sampleEnvironment, "0.2.2", nil, c.connectMetadata())
+	if nil != err {
+		t.Fatal(err)
+	}
+	var connect []struct {
+		Metadata map[string]string `json:"metadata"`
+	}
+	if err := json.Unmarshal(js, &connect); nil != err {
+		t.Fatal(err)
+	}
+	if fp := connect[0].Metadata[configFingerprintMetadataKey]; fp != "abc" {
+		t.Error(string(js))
+	}
+}
+
+func TestApplicationConfigFingerprint(t *testing.T) {
+	app := testApp(nil, nil, t)
+	if fp := app.ConfigFingerprint(); fp != app.app.config.fingerprint || "" == fp {
+		t.Error(fp)
+	}
+	var nilApp *Application
+	if fp := nilApp.ConfigFingerprint(); "" != fp {
+		t.Error(fp)
+	}
+}
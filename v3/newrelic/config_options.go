@@ -4,12 +4,16 @@
 package newrelic
 
 import (
+	"context"
 	"fmt"
 	"io"
 	"os"
 	"strconv"
 	"strings"
+	"time"
 	"unicode/utf8"
+
+	"github.com/newrelic/go-agent/v3/internal"
 )
 
 // ConfigOption configures the Config when provided to NewApplication.
@@ -30,12 +34,201 @@ func ConfigLicense(license string) ConfigOption {
 	return func(cfg *Config) { cfg.License = license }
 }
 
+// ConfigLicenseProvider sets the LicenseProvider, which is used to fetch the
+// license from a secrets manager.
+func ConfigLicenseProvider(provider func(context.Context) (string, error)) ConfigOption {
+	return func(cfg *Config) { cfg.LicenseProvider = provider }
+}
+
 // ConfigDistributedTracerEnabled populates the Config's
 // DistributedTracer.Enabled setting.
 func ConfigDistributedTracerEnabled(enabled bool) ConfigOption {
 	return func(cfg *Config) { cfg.DistributedTracer.Enabled = enabled }
 }
 
+// ConfigCodeLevelMetricsEnabled populates the Config's
+// CodeLevelMetrics.Enabled setting.
+func ConfigCodeLevelMetricsEnabled(enabled bool) ConfigOption {
+	return func(cfg *Config) { cfg.CodeLevelMetrics.Enabled = enabled }
+}
+
+// ConfigSamplingRules populates the Config's DistributedTracer.SamplingRules
+// setting, which sets the fraction of transactions sampled by transaction
+// name:
+//
+//	newrelic.ConfigSamplingRules([]newrelic.SamplingRule{
+//		{NamePattern: "* /checkout", Rate: 1},
+//		{NamePattern: "GET /healthz", Rate: 0.01},
+//	})
+func ConfigSamplingRules(rules []SamplingRule) ConfigOption {
+	return func(cfg *Config) {
+		cfg.DistributedTracer.SamplingRules = append([]SamplingRule(nil), rules...)
+	}
+}
+
+// ConfigOTLPEndpoint populates the Config's OTLP.Endpoint setting, which sends
+// data to an OpenTelemetry collector using OTLP/HTTP instead of to New Relic.
+// The url is the base URL of the receiver, eg. "http://localhost:4318".
+func ConfigOTLPEndpoint(url string) ConfigOption {
+	return func(cfg *Config) { cfg.OTLP.Endpoint = url }
+}
+
+// ConfigGateway populates the Config's Gateway settings, which send the
+// requests made to New Relic through a local telemetry gateway.  The address
+// is either "unix:" followed by the path of a Unix domain socket, or a
+// loopback host and port.  The token may be empty.
+func ConfigGateway(address, token string) ConfigOption {
+	return func(cfg *Config) {
+		cfg.Gateway.Address = address
+		cfg.Gateway.Token = token
+	}
+}
+
+// ConfigAppLogForwardingEnabled populates the Config's
+// ApplicationLogging.Forwarding.Enabled setting, which sends the logs
+// recorded with Application.RecordLog and Transaction.RecordLog to New Relic.
+func ConfigAppLogForwardingEnabled(enabled bool) ConfigOption {
+	return func(cfg *Config) { cfg.ApplicationLogging.Forwarding.Enabled = enabled }
+}
+
+// ConfigShortLived enables the Config's ShortLived mode, which is optimized
+// for command line tools, cron jobs, and other processes which run for less
+// than a minute.  Call Shutdown before the process exits so that the data
+// recorded is sent:
+//
+//	app, _ := newrelic.NewApplication(
+//		newrelic.ConfigAppName("Nightly Report"),
+//		newrelic.ConfigLicense(os.Getenv("NEW_RELIC_LICENSE_KEY")),
+//		newrelic.ConfigShortLived(),
+//	)
+//	defer app.Shutdown(5 * time.Second)
+func ConfigShortLived() ConfigOption {
+	return func(cfg *Config) { cfg.ShortLived.Enabled = true }
+}
+
+// ConfigProfileHighThroughput sets a group of settings suited to services
+// handling many requests per second, where the overhead of the agent and the
+// size of its payloads matter more than the detail of each transaction.
+// Settings may be overridden by options which follow it:
+//
+//	app, _ := newrelic.NewApplication(
+//		newrelic.ConfigAppName("Checkout"),
+//		newrelic.ConfigLicense(os.Getenv("NEW_RELIC_LICENSE_KEY")),
+//		newrelic.ConfigProfileHighThroughput(),
+//		func(cfg *newrelic.Config) {
+//			cfg.DatastoreTracer.SlowQuery.Threshold = 20 * time.Millisecond
+//		},
+//	)
+//
+// Events are harvested adaptively and health check transactions are
+// ignored.  Trace segments, stack traces, and slow queries are only recorded
+// above higher thresholds, and datastore query parameters, code level
+// metrics, CPU time, contention profiling, checkpoints, and anomaly detection
+// are disabled.
+func ConfigProfileHighThroughput() ConfigOption {
+	return func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.Enabled = true
+		cfg.EventHarvest.Adaptive.Enabled = true
+		cfg.TransactionEvents.Enabled = true
+		cfg.TransactionEvents.MaxSamplesStored = internal.MaxTxnEvents
+		cfg.TransactionEvents.DistributedTracingIntrinsics = DistributedTracingIntrinsicsTrace
+		cfg.ErrorCollector.StratifyEvents = true
+		cfg.HealthChecks.Enabled = true
+		cfg.TransactionTracer.Enabled = true
+		cfg.TransactionTracer.Threshold.IsApdexFailing = true
+		cfg.TransactionTracer.Segments.Threshold = 5 * time.Millisecond
+		cfg.TransactionTracer.Segments.StackTraceThreshold = time.Second
+		cfg.DatastoreTracer.QueryParameters.Enabled = false
+		cfg.DatastoreTracer.SlowQuery.Enabled = true
+		cfg.DatastoreTracer.SlowQuery.Threshold = 50 * time.Millisecond
+		cfg.CodeLevelMetrics.Enabled = false
+		cfg.TransactionProcessCPUTime.Enabled = false
+		cfg.ContentionProfiling.Enabled = false
+		cfg.TransactionCheckpoints.Enabled = false
+		cfg.AnomalyDetection.Enabled = false
+		cfg.InfiniteTracing.SpanEvents.QueueSize = 100000
+	}
+}
+
+// ConfigProfileDebug sets a group of settings suited to development and
+// troubleshooting, where recording as much detail as possible matters more
+// than the overhead of the agent.  It should not be used for production
+// services under load.  Settings may be overridden by options which follow
+// it.  It does not set a Logger; use ConfigDebugLogger to see the agent's
+// own logs.
+//
+// Every transaction is eligible for a transaction trace, every segment is
+// recorded, and every datastore call longer than a millisecond is a slow
+// query with its parameters.  Code level metrics, CPU time, contention
+// profiling, checkpoints, anomaly detection, log forwarding, and the
+// recording of panics, cancelled contexts, and external errors are enabled.
+func ConfigProfileDebug() ConfigOption {
+	return func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.Enabled = true
+		cfg.EventHarvest.Adaptive.Enabled = false
+		cfg.StartupSummary.Enabled = true
+		cfg.TransactionTracer.Enabled = true
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.TransactionTracer.Segments.Threshold = 0
+		cfg.TransactionTracer.Segments.StackTraceThreshold = 100 * time.Millisecond
+		cfg.DatastoreTracer.QueryParameters.Enabled = true
+		cfg.DatastoreTracer.SlowQuery.Enabled = true
+		cfg.DatastoreTracer.SlowQuery.Threshold = time.Millisecond
+		cfg.CodeLevelMetrics.Enabled = true
+		cfg.TransactionProcessCPUTime.Enabled = true
+		cfg.ContentionProfiling.Enabled = true
+		cfg.TransactionCheckpoints.Enabled = true
+		cfg.AnomalyDetection.Enabled = true
+		cfg.ApplicationLogging.Forwarding.Enabled = true
+		cfg.ErrorCollector.RecordPanics = true
+		cfg.ContextCancellation.Enabled = true
+		cfg.ContextCancellation.NoticeErrors = true
+		cfg.ExternalErrors.ServerErrors = true
+		cfg.ExternalErrors.ClientErrors = true
+	}
+}
+
+// ConfigProfileBatch sets a group of settings suited to batch jobs and
+// background workers, whose transactions are long running and not requests
+// from users.  Settings may be overridden by options which follow it.  For
+// processes which exit within a minute, also use ConfigShortLived.
+//
+// Transaction traces are recorded for transactions longer than ten seconds
+// rather than those failing apdex, and only segments longer than ten
+// milliseconds are kept, to bound the size of traces of long transactions.
+// Checkpoints report the progress of long transactions, CPU time and panics
+// are recorded, and the web features, such as browser monitoring, client IP
+// addresses, health checks, and the scaling signal, are disabled.
+func ConfigProfileBatch() ConfigOption {
+	return func(cfg *Config) {
+		cfg.EventHarvest.Adaptive.Enabled = false
+		cfg.TransactionTracer.Enabled = true
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 10 * time.Second
+		cfg.TransactionTracer.Segments.Threshold = 10 * time.Millisecond
+		cfg.TransactionTracer.Segments.StackTraceThreshold = 5 * time.Second
+		cfg.DatastoreTracer.QueryParameters.Enabled = false
+		cfg.DatastoreTracer.SlowQuery.Enabled = true
+		cfg.DatastoreTracer.SlowQuery.Threshold = 500 * time.Millisecond
+		cfg.TransactionCheckpoints.Enabled = true
+		cfg.TransactionCheckpoints.Threshold = 5 * time.Minute
+		cfg.TransactionCheckpoints.Interval = time.Minute
+		cfg.TransactionProcessCPUTime.Enabled = true
+		cfg.RuntimeSampler.Enabled = true
+		cfg.ErrorCollector.RecordPanics = true
+		cfg.CodeLevelMetrics.Enabled = false
+		cfg.ContentionProfiling.Enabled = false
+		cfg.AnomalyDetection.Enabled = false
+		cfg.BrowserMonitoring.Enabled = false
+		cfg.ClientIP.Enabled = false
+		cfg.HealthChecks.Enabled = false
+		cfg.ScalingSignal.Enabled = false
+	}
+}
+
 // ConfigLogger populates the Config's Logger.
 func ConfigLogger(l Logger) ConfigOption {
 	return func(cfg *Config) { cfg.Logger = l }
@@ -54,10 +247,19 @@ func ConfigDebugLogger(w io.Writer) ConfigOption {
 // ConfigFromEnvironment populates the config based on environment variables:
 //
 //  NEW_RELIC_APP_NAME                                sets AppName
+//  NEW_RELIC_ALLOWED_REGIONS                         sets AllowedRegions using a comma-separated list, eg. "eu01"
+//  NEW_RELIC_ANOMALY_DETECTION_ENABLED               sets AnomalyDetection.Enabled using strconv.ParseBool
+//  NEW_RELIC_APPLICATION_LOGGING_FORWARDING_ENABLED  sets ApplicationLogging.Forwarding.Enabled using strconv.ParseBool
+//  NEW_RELIC_APPLICATION_LOGGING_FORWARDING_MAX      sets ApplicationLogging.Forwarding.MaxSamplesStored using strconv.Atoi
 //  NEW_RELIC_ATTRIBUTES_EXCLUDE                      sets Attributes.Exclude using a comma-separated list, eg. "request.headers.host,request.method"
 //  NEW_RELIC_ATTRIBUTES_INCLUDE                      sets Attributes.Include using a comma-separated list
+//  NEW_RELIC_CODE_LEVEL_METRICS_ENABLED              sets CodeLevelMetrics.Enabled using strconv.ParseBool
 //  NEW_RELIC_DISTRIBUTED_TRACING_ENABLED             sets DistributedTracer.Enabled using strconv.ParseBool
 //  NEW_RELIC_ENABLED                                 sets Enabled using strconv.ParseBool
+//  NEW_RELIC_FAILOVER_HOSTS                          sets FailoverHosts using a comma-separated list, eg. "collector-b.example.com,collector-c.example.com"
+//  NEW_RELIC_GATEWAY_ADDRESS                         sets Gateway.Address
+//  NEW_RELIC_GATEWAY_TOKEN                           sets Gateway.Token
+//  NEW_RELIC_HEALTH_CHECKS_ENABLED                   sets HealthChecks.Enabled using strconv.ParseBool
 //  NEW_RELIC_HIGH_SECURITY                           sets HighSecurity using strconv.ParseBool
 //  NEW_RELIC_HOST                                    sets Host
 //  NEW_RELIC_INFINITE_TRACING_SPAN_EVENTS_QUEUE_SIZE sets InfiniteTracing.SpanEvents.QueueSize using strconv.Atoi
@@ -67,10 +269,20 @@ func ConfigDebugLogger(w io.Writer) ConfigOption {
 //  NEW_RELIC_LICENSE_KEY                             sets License
 //  NEW_RELIC_LOG                                     sets Logger to log to either "stdout" or "stderr" (filenames are not supported)
 //  NEW_RELIC_LOG_LEVEL                               controls the NEW_RELIC_LOG level, must be "debug" for debug, or empty for info
+//  NEW_RELIC_OTLP_ENDPOINT                           sets OTLP.Endpoint
 //  NEW_RELIC_PROCESS_HOST_DISPLAY_NAME               sets HostDisplayName
+//  NEW_RELIC_SCALING_SIGNAL_ENABLED                  sets ScalingSignal.Enabled using strconv.ParseBool
 //  NEW_RELIC_SECURITY_POLICIES_TOKEN                 sets SecurityPoliciesToken
+//  NEW_RELIC_SHORT_LIVED_ENABLED                     sets ShortLived.Enabled using strconv.ParseBool
+//  NEW_RELIC_SPAN_EVENTS_ATTRIBUTE_NAMING            sets SpanEvents.AttributeNaming, eg. "otel"
+//  NEW_RELIC_SPAN_EVENTS_PROPAGATE_ATTRIBUTES        sets SpanEvents.PropagateAttributes using a comma-separated list, eg. "tenant,user"
+//  NEW_RELIC_SPOOL_DIRECTORY                         sets Spool.Directory
+//  NEW_RELIC_STARTUP_SUMMARY_ENABLED                 sets StartupSummary.Enabled using strconv.ParseBool
+//  NEW_RELIC_TRANSACTION_CHECKPOINTS_ENABLED         sets TransactionCheckpoints.Enabled using strconv.ParseBool
+//  NEW_RELIC_TRANSACTION_EVENTS_DT_INTRINSICS        sets TransactionEvents.DistributedTracingIntrinsics, eg. "trace"
 //  NEW_RELIC_UTILIZATION_BILLING_HOSTNAME            sets Utilization.BillingHostname
 //  NEW_RELIC_UTILIZATION_LOGICAL_PROCESSORS          sets Utilization.LogicalProcessors using strconv.Atoi
+//  NEW_RELIC_UTILIZATION_RETRIES                     sets Utilization.Retries using strconv.Atoi
 //  NEW_RELIC_UTILIZATION_TOTAL_RAM_MIB               sets Utilization.TotalRAMMIB using strconv.Atoi
 //
 // This function is strict and will assign Config.Error if any of the
@@ -112,17 +324,31 @@ func configFromEnvironment(getenv func(string) string) ConfigOption {
 		assignString(&cfg.AppName, "NEW_RELIC_APP_NAME")
 		assignString(&cfg.License, "NEW_RELIC_LICENSE_KEY")
 		assignBool(&cfg.DistributedTracer.Enabled, "NEW_RELIC_DISTRIBUTED_TRACING_ENABLED")
+		assignBool(&cfg.CodeLevelMetrics.Enabled, "NEW_RELIC_CODE_LEVEL_METRICS_ENABLED")
 		assignBool(&cfg.Enabled, "NEW_RELIC_ENABLED")
 		assignBool(&cfg.HighSecurity, "NEW_RELIC_HIGH_SECURITY")
+		assignBool(&cfg.HealthChecks.Enabled, "NEW_RELIC_HEALTH_CHECKS_ENABLED")
+		assignBool(&cfg.StartupSummary.Enabled, "NEW_RELIC_STARTUP_SUMMARY_ENABLED")
+		assignBool(&cfg.ScalingSignal.Enabled, "NEW_RELIC_SCALING_SIGNAL_ENABLED")
+		assignBool(&cfg.TransactionCheckpoints.Enabled, "NEW_RELIC_TRANSACTION_CHECKPOINTS_ENABLED")
+		assignBool(&cfg.AnomalyDetection.Enabled, "NEW_RELIC_ANOMALY_DETECTION_ENABLED")
+		assignBool(&cfg.ShortLived.Enabled, "NEW_RELIC_SHORT_LIVED_ENABLED")
 		assignString(&cfg.SecurityPoliciesToken, "NEW_RELIC_SECURITY_POLICIES_TOKEN")
 		assignString(&cfg.Host, "NEW_RELIC_HOST")
+		assignString(&cfg.OTLP.Endpoint, "NEW_RELIC_OTLP_ENDPOINT")
+		assignString(&cfg.Spool.Directory, "NEW_RELIC_SPOOL_DIRECTORY")
+		assignString(&cfg.Gateway.Address, "NEW_RELIC_GATEWAY_ADDRESS")
+		assignString(&cfg.Gateway.Token, "NEW_RELIC_GATEWAY_TOKEN")
 		assignString(&cfg.HostDisplayName, "NEW_RELIC_PROCESS_HOST_DISPLAY_NAME")
 		assignString(&cfg.Utilization.BillingHostname, "NEW_RELIC_UTILIZATION_BILLING_HOSTNAME")
 		assignString(&cfg.InfiniteTracing.TraceObserver.Host, "NEW_RELIC_INFINITE_TRACING_TRACE_OBSERVER_HOST")
 		assignInt(&cfg.InfiniteTracing.TraceObserver.Port, "NEW_RELIC_INFINITE_TRACING_TRACE_OBSERVER_PORT")
 		assignInt(&cfg.Utilization.LogicalProcessors, "NEW_RELIC_UTILIZATION_LOGICAL_PROCESSORS")
 		assignInt(&cfg.Utilization.TotalRAMMIB, "NEW_RELIC_UTILIZATION_TOTAL_RAM_MIB")
+		assignInt(&cfg.Utilization.Retries, "NEW_RELIC_UTILIZATION_RETRIES")
 		assignInt(&cfg.InfiniteTracing.SpanEvents.QueueSize, "NEW_RELIC_INFINITE_TRACING_SPAN_EVENTS_QUEUE_SIZE")
+		assignBool(&cfg.ApplicationLogging.Forwarding.Enabled, "NEW_RELIC_APPLICATION_LOGGING_FORWARDING_ENABLED")
+		assignInt(&cfg.ApplicationLogging.Forwarding.MaxSamplesStored, "NEW_RELIC_APPLICATION_LOGGING_FORWARDING_MAX")
 
 		if env := getenv("NEW_RELIC_LABELS"); env != "" {
 			if labels := getLabels(getenv("NEW_RELIC_LABELS")); len(labels) > 0 {
@@ -132,12 +358,29 @@ func configFromEnvironment(getenv func(string) string) ConfigOption {
 			}
 		}
 
+		if env := getenv("NEW_RELIC_TRANSACTION_EVENTS_DT_INTRINSICS"); env != "" {
+			cfg.TransactionEvents.DistributedTracingIntrinsics = DistributedTracingIntrinsics(env)
+		}
+		if env := getenv("NEW_RELIC_SPAN_EVENTS_ATTRIBUTE_NAMING"); env != "" {
+			cfg.SpanEvents.AttributeNaming = SpanAttributeNaming(env)
+		}
+
+		if env := getenv("NEW_RELIC_FAILOVER_HOSTS"); env != "" {
+			cfg.FailoverHosts = strings.Split(env, ",")
+		}
+		if env := getenv("NEW_RELIC_ALLOWED_REGIONS"); env != "" {
+			cfg.AllowedRegions = strings.Split(env, ",")
+		}
+
 		if env := getenv("NEW_RELIC_ATTRIBUTES_INCLUDE"); env != "" {
 			cfg.Attributes.Include = strings.Split(env, ",")
 		}
 		if env := getenv("NEW_RELIC_ATTRIBUTES_EXCLUDE"); env != "" {
 			cfg.Attributes.Exclude = strings.Split(env, ",")
 		}
+		if env := getenv("NEW_RELIC_SPAN_EVENTS_PROPAGATE_ATTRIBUTES"); env != "" {
+			cfg.SpanEvents.PropagateAttributes = strings.Split(env, ",")
+		}
 
 		if env := getenv("NEW_RELIC_LOG"); env != "" {
 			if dest := getLogDest(env); dest != nil {
@@ -193,16 +436,16 @@ func getLabels(env string) map[string]string {
 		if left == "" || right == "" {
 			return nil
 		}
-		if utf8.RuneCountInString(left) > 255 {
+		if utf8.RuneCountInString(left) > labelLengthLimit {
 			runes := []rune(left)
-			left = string(runes[:255])
+			left = string(runes[:labelLengthLimit])
 		}
-		if utf8.RuneCountInString(right) > 255 {
+		if utf8.RuneCountInString(right) > labelLengthLimit {
 			runes := []rune(right)
-			right = string(runes[:255])
+			right = string(runes[:labelLengthLimit])
 		}
 		out[left] = right
-		if len(out) >= 64 {
+		if len(out) >= maxLabels {
 			return out
 		}
 	}
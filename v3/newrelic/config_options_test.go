@@ -6,6 +6,7 @@ package newrelic
 import (
 	"reflect"
 	"testing"
+	"time"
 )
 
 func TestConfigFromEnvironment(t *testing.T) {
@@ -21,10 +22,40 @@ func TestConfigFromEnvironment(t *testing.T) {
 			return "false"
 		case "NEW_RELIC_HIGH_SECURITY":
 			return "1"
+		case "NEW_RELIC_HEALTH_CHECKS_ENABLED":
+			return "false"
 		case "NEW_RELIC_SECURITY_POLICIES_TOKEN":
 			return "my token"
+		case "NEW_RELIC_STARTUP_SUMMARY_ENABLED":
+			return "false"
+		case "NEW_RELIC_SCALING_SIGNAL_ENABLED":
+			return "true"
+		case "NEW_RELIC_TRANSACTION_CHECKPOINTS_ENABLED":
+			return "true"
+		case "NEW_RELIC_CODE_LEVEL_METRICS_ENABLED":
+			return "true"
+		case "NEW_RELIC_ANOMALY_DETECTION_ENABLED":
+			return "true"
+		case "NEW_RELIC_SHORT_LIVED_ENABLED":
+			return "true"
 		case "NEW_RELIC_HOST":
 			return "my host"
+		case "NEW_RELIC_OTLP_ENDPOINT":
+			return "http://localhost:4318"
+		case "NEW_RELIC_SPOOL_DIRECTORY":
+			return "/var/spool/newrelic"
+		case "NEW_RELIC_GATEWAY_ADDRESS":
+			return "unix:/var/run/nr-gateway.sock"
+		case "NEW_RELIC_GATEWAY_TOKEN":
+			return "my gateway token"
+		case "NEW_RELIC_TRANSACTION_EVENTS_DT_INTRINSICS":
+			return "trace"
+		case "NEW_RELIC_APPLICATION_LOGGING_FORWARDING_ENABLED":
+			return "true"
+		case "NEW_RELIC_APPLICATION_LOGGING_FORWARDING_MAX":
+			return "2500"
+		case "NEW_RELIC_FAILOVER_HOSTS":
+			return "host-b,host-c"
 		case "NEW_RELIC_PROCESS_HOST_DISPLAY_NAME":
 			return "my display host"
 		case "NEW_RELIC_UTILIZATION_BILLING_HOSTNAME":
@@ -33,12 +64,18 @@ func TestConfigFromEnvironment(t *testing.T) {
 			return "123"
 		case "NEW_RELIC_UTILIZATION_TOTAL_RAM_MIB":
 			return "456"
+		case "NEW_RELIC_UTILIZATION_RETRIES":
+			return "2"
 		case "NEW_RELIC_LABELS":
 			return "star:car;far:bar"
 		case "NEW_RELIC_ATTRIBUTES_INCLUDE":
 			return "zip,zap"
 		case "NEW_RELIC_ATTRIBUTES_EXCLUDE":
 			return "zop,zup,zep"
+		case "NEW_RELIC_SPAN_EVENTS_PROPAGATE_ATTRIBUTES":
+			return "tenant,user"
+		case "NEW_RELIC_SPAN_EVENTS_ATTRIBUTE_NAMING":
+			return "both"
 		case "NEW_RELIC_INFINITE_TRACING_TRACE_OBSERVER_HOST":
 			return "myhost.com"
 		case "NEW_RELIC_INFINITE_TRACING_TRACE_OBSERVER_PORT":
@@ -54,15 +91,33 @@ func TestConfigFromEnvironment(t *testing.T) {
 	expect.DistributedTracer.Enabled = true
 	expect.Enabled = false
 	expect.HighSecurity = true
+	expect.HealthChecks.Enabled = false
 	expect.SecurityPoliciesToken = "my token"
+	expect.StartupSummary.Enabled = false
+	expect.ScalingSignal.Enabled = true
+	expect.TransactionCheckpoints.Enabled = true
+	expect.CodeLevelMetrics.Enabled = true
+	expect.AnomalyDetection.Enabled = true
+	expect.ShortLived.Enabled = true
 	expect.Host = "my host"
+	expect.OTLP.Endpoint = "http://localhost:4318"
+	expect.Spool.Directory = "/var/spool/newrelic"
+	expect.Gateway.Address = "unix:/var/run/nr-gateway.sock"
+	expect.Gateway.Token = "my gateway token"
+	expect.TransactionEvents.DistributedTracingIntrinsics = DistributedTracingIntrinsicsTrace
+	expect.ApplicationLogging.Forwarding.Enabled = true
+	expect.ApplicationLogging.Forwarding.MaxSamplesStored = 2500
+	expect.FailoverHosts = []string{"host-b", "host-c"}
 	expect.HostDisplayName = "my display host"
 	expect.Utilization.BillingHostname = "my billing hostname"
 	expect.Utilization.LogicalProcessors = 123
 	expect.Utilization.TotalRAMMIB = 456
+	expect.Utilization.Retries = 2
 	expect.Labels = map[string]string{"star": "car", "far": "bar"}
 	expect.Attributes.Include = []string{"zip", "zap"}
 	expect.Attributes.Exclude = []string{"zop", "zup", "zep"}
+	expect.SpanEvents.PropagateAttributes = []string{"tenant", "user"}
+	expect.SpanEvents.AttributeNaming = SpanAttributeNamingBoth
 	expect.InfiniteTracing.TraceObserver.Host = "myhost.com"
 	expect.InfiniteTracing.TraceObserver.Port = 456
 	expect.InfiniteTracing.SpanEvents.QueueSize = 98765
@@ -236,3 +291,70 @@ func TestConfigFromEnvironmentLabelsSuccess(t *testing.T) {
 		t.Error(cfg.Labels)
 	}
 }
+
+func TestConfigProfiles(t *testing.T) {
+	testcases := []struct {
+		name    string
+		profile ConfigOption
+		check   func(Config) bool
+	}{
+		{
+			name:    "high throughput",
+			profile: ConfigProfileHighThroughput(),
+			check: func(cfg Config) bool {
+				return cfg.EventHarvest.Adaptive.Enabled &&
+					!cfg.DatastoreTracer.QueryParameters.Enabled &&
+					cfg.DatastoreTracer.SlowQuery.Threshold == 50*time.Millisecond
+			},
+		},
+		{
+			name:    "debug",
+			profile: ConfigProfileDebug(),
+			check: func(cfg Config) bool {
+				return !cfg.TransactionTracer.Threshold.IsApdexFailing &&
+					cfg.TransactionTracer.Segments.Threshold == 0 &&
+					cfg.CodeLevelMetrics.Enabled &&
+					cfg.ErrorCollector.RecordPanics
+			},
+		},
+		{
+			name:    "batch",
+			profile: ConfigProfileBatch(),
+			check: func(cfg Config) bool {
+				return cfg.TransactionCheckpoints.Enabled &&
+					cfg.TransactionTracer.Threshold.Duration == 10*time.Second &&
+					!cfg.HealthChecks.Enabled &&
+					!cfg.BrowserMonitoring.Enabled
+			},
+		},
+	}
+
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.AppName = "my app"
+		cfg.License = testLicenseKey
+		tc.profile(&cfg)
+		if err := cfg.validate(); nil != err {
+			t.Errorf("%s: %v", tc.name, err)
+		}
+		if !tc.check(cfg) {
+			t.Errorf("%s: settings not applied", tc.name)
+		}
+	}
+}
+
+func TestConfigProfileOverridden(t *testing.T) {
+	cfg := defaultConfig()
+	for _, opt := range []ConfigOption{
+		ConfigProfileHighThroughput(),
+		func(cfg *Config) { cfg.DatastoreTracer.SlowQuery.Threshold = 20 * time.Millisecond },
+	} {
+		opt(&cfg)
+	}
+	if cfg.DatastoreTracer.SlowQuery.Threshold != 20*time.Millisecond {
+		t.Error(cfg.DatastoreTracer.SlowQuery.Threshold)
+	}
+	if !cfg.EventHarvest.Adaptive.Enabled {
+		t.Error("profile setting not applied")
+	}
+}
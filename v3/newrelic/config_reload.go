@@ -0,0 +1,202 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"errors"
+	"sync"
+
+	"github.com/newrelic/go-agent/v3/internal/logger"
+)
+
+var (
+	errConfigNotReloadable = errors.New("UpdateConfig may only change the Logger, attribute, enabled, and sampling settings")
+)
+
+// reloadableLogger is the Logger of an application.  It allows UpdateConfig
+// to replace the Logger while it is used by the application's goroutines.
+type reloadableLogger struct {
+	sync.RWMutex
+	lg Logger
+}
+
+func newReloadableLogger(lg Logger) *reloadableLogger {
+	if r, ok := lg.(*reloadableLogger); ok {
+		return r
+	}
+	r := &reloadableLogger{}
+	r.set(lg)
+	return r
+}
+
+func (r *reloadableLogger) get() Logger {
+	r.RLock()
+	defer r.RUnlock()
+
+	return r.lg
+}
+
+func (r *reloadableLogger) set(lg Logger) {
+	if nil == lg {
+		lg = logger.ShimLogger{}
+	}
+	r.Lock()
+	defer r.Unlock()
+
+	r.lg = lg
+}
+
+func (r *reloadableLogger) Error(msg string, context map[string]interface{}) {
+	r.get().Error(msg, context)
+}
+
+func (r *reloadableLogger) Warn(msg string, context map[string]interface{}) {
+	r.get().Warn(msg, context)
+}
+
+func (r *reloadableLogger) Info(msg string, context map[string]interface{}) {
+	r.get().Info(msg, context)
+}
+
+func (r *reloadableLogger) Debug(msg string, context map[string]interface{}) {
+	r.get().Debug(msg, context)
+}
+
+func (r *reloadableLogger) DebugEnabled() bool {
+	return r.get().DebugEnabled()
+}
+
+// reloadableAttributes returns the attribute settings of every destination.
+func reloadableAttributes(c *Config) []*AttributeDestinationConfig {
+	return []*AttributeDestinationConfig{
+		&c.Attributes,
+		&c.TransactionEvents.Attributes,
+		&c.ErrorCollector.Attributes,
+		&c.TransactionTracer.Attributes,
+		&c.TransactionTracer.Segments.Attributes,
+		&c.BrowserMonitoring.Attributes,
+		&c.SpanEvents.Attributes,
+	}
+}
+
+// applyReloadable returns base with the settings which UpdateConfig may
+// change taken from updated.
+func applyReloadable(base, updated Config) Config {
+	c := base
+	updatedAttrs := reloadableAttributes(&updated)
+	for i, dest := range reloadableAttributes(&c) {
+		*dest = *updatedAttrs[i]
+		dest.Include = append([]string(nil), dest.Include...)
+		dest.Exclude = append([]string(nil), dest.Exclude...)
+	}
+	c.TransactionEvents.Enabled = updated.TransactionEvents.Enabled
+	c.ErrorCollector.Enabled = updated.ErrorCollector.Enabled
+	c.TransactionTracer.Enabled = updated.TransactionTracer.Enabled
+	c.DatastoreTracer.SlowQuery.Enabled = updated.DatastoreTracer.SlowQuery.Enabled
+	c.SpanEvents.Enabled = updated.SpanEvents.Enabled
+	c.CustomInsightsEvents.Enabled = updated.CustomInsightsEvents.Enabled
+	c.BrowserMonitoring.Enabled = updated.BrowserMonitoring.Enabled
+	c.DistributedTracer.ErrorSamplingBudget = updated.DistributedTracer.ErrorSamplingBudget
+	c.DistributedTracer.InboundSampled.Mode = updated.DistributedTracer.InboundSampled.Mode
+	c.DistributedTracer.InboundSampled.Rules = append([]InboundSampledRule(nil), updated.DistributedTracer.InboundSampled.Rules...)
+	c.DistributedTracer.SamplingRules = append([]SamplingRule(nil), updated.DistributedTracer.SamplingRules...)
+	c.DistributedTracer.TailSampling = updated.DistributedTracer.TailSampling
+	return c
+}
+
+// onlyReloadableChanged returns true if updated differs from base only in
+// the settings which UpdateConfig may change.  The settings are compared
+// using the same JSON which is sent to New Relic at connect, so func fields
+// are not compared.
+func onlyReloadableChanged(base, updated Config) bool {
+	expect, err := json.Marshal(settings(applyReloadable(base, updated)))
+	if nil != err {
+		return false
+	}
+	actual, err := json.Marshal(settings(updated))
+	if nil != err {
+		return false
+	}
+	return string(expect) == string(actual)
+}
+
+// currentConfig returns the configuration including the changes made by
+// UpdateConfig.
+func (app *app) currentConfig() config {
+	app.RLock()
+	defer app.RUnlock()
+
+	return app.reloaded
+}
+
+func (app *app) updateConfig(opts []ConfigOption) error {
+	app.reloadLock.Lock()
+	defer app.reloadLock.Unlock()
+
+	base := app.currentConfig()
+	updated := copyConfigReferenceFields(base.Config)
+	for _, dest := range reloadableAttributes(&updated) {
+		dest.Include = append([]string(nil), dest.Include...)
+		dest.Exclude = append([]string(nil), dest.Exclude...)
+	}
+	for _, fn := range opts {
+		if nil != fn {
+			fn(&updated)
+			if nil != updated.Error {
+				return updated.Error
+			}
+		}
+	}
+
+	lg := updated.Logger
+	if r, ok := lg.(*reloadableLogger); ok && r == app.logger {
+		lg = nil
+	}
+	updated.Logger = base.Logger
+	if !onlyReloadableChanged(base.Config, updated) {
+		return errConfigNotReloadable
+	}
+	if err := updated.validate(); nil != err {
+		return err
+	}
+
+	if nil != lg {
+		app.logger.set(lg)
+	}
+	app.reconfigure(updated)
+	for _, sub := range app.rollups.all() {
+		sub.reconfigure(updated)
+	}
+	app.Info("application configuration updated", map[string]interface{}{
+		"app": app.config.AppName,
+	})
+	return nil
+}
+
+// reconfigure replaces the runs of the application with runs using the
+// reloadable settings of updated.
+func (app *app) reconfigure(updated Config) {
+	app.Lock()
+	defer app.Unlock()
+
+	app.reloaded.Config = applyReloadable(app.reloaded.Config, updated)
+	app.placeholderRun = app.placeholderRun.reconfigure(app.reloaded)
+	if nil != app.run {
+		app.run = app.run.reconfigure(app.reloaded)
+	}
+}
+
+// reconfigure returns a copy of the run using the configuration provided.
+// The connect reply, transaction name cache, sampler, and harvest settings
+// of the run are kept.
+func (run *appRun) reconfigure(c config) *appRun {
+	next := newAppRun(c, run.Reply)
+	next.preconnectHost = run.preconnectHost
+	next.rulesCache = run.rulesCache
+	next.harvestConfig = run.harvestConfig
+	next.adaptiveSampler = run.adaptiveSampler
+	next.adaptiveSampler.setErrorBudget(next.Config.DistributedTracer.ErrorSamplingBudget)
+	return next
+}
@@ -0,0 +1,151 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestUpdateConfigAttributes(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("before")
+	txn.AddAttribute("zip", 1)
+	txn.End()
+
+	err := app.UpdateConfig(func(cfg *Config) {
+		cfg.TransactionEvents.Attributes.Exclude = append(cfg.TransactionEvents.Attributes.Exclude, "zip")
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	txn = app.StartTransaction("after")
+	txn.AddAttribute("zip", 1)
+	txn.End()
+
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics:     map[string]interface{}{"name": "OtherTransaction/Go/before"},
+		UserAttributes: map[string]interface{}{"zip": 1},
+	}, {
+		Intrinsics:     map[string]interface{}{"name": "OtherTransaction/Go/after"},
+		UserAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestUpdateConfigEnabledFlags(t *testing.T) {
+	app := testApp(nil, nil, t)
+	err := app.UpdateConfig(func(cfg *Config) {
+		cfg.CustomInsightsEvents.Enabled = false
+		cfg.TransactionEvents.Enabled = false
+	})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := internalApp(app).RecordCustomEvent("MyEvent", validParams); err != errCustomEventsDisabled {
+		t.Error(err)
+	}
+	app.StartTransaction("hello").End()
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+}
+
+func TestUpdateConfigErrorSamplingBudget(t *testing.T) {
+	app := testApp(nil, nil, t)
+	before, _ := internalApp(app).getState()
+	if err := app.UpdateConfig(func(cfg *Config) {
+		cfg.DistributedTracer.ErrorSamplingBudget = 5
+	}); nil != err {
+		t.Fatal(err)
+	}
+	after, _ := internalApp(app).getState()
+	if after == before || after.adaptiveSampler != before.adaptiveSampler {
+		t.Error("run should be replaced and the sampler kept")
+	}
+	if budget := after.adaptiveSampler.errorBudget; budget != 5 {
+		t.Error(budget)
+	}
+	if cfg := internalApp(app).currentConfig(); cfg.DistributedTracer.ErrorSamplingBudget != 5 {
+		t.Error(cfg.DistributedTracer.ErrorSamplingBudget)
+	}
+}
+
+func TestUpdateConfigNotReloadable(t *testing.T) {
+	app := testApp(nil, nil, t)
+	err := app.UpdateConfig(func(cfg *Config) {
+		cfg.SpanEvents.Enabled = false
+		cfg.AppName = "another app"
+	})
+	if err != errConfigNotReloadable {
+		t.Fatal(err)
+	}
+	cfg := internalApp(app).currentConfig()
+	if "my app" != cfg.AppName || !cfg.SpanEvents.Enabled {
+		t.Error(cfg.AppName, cfg.SpanEvents.Enabled)
+	}
+}
+
+func TestUpdateConfigInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	err := app.UpdateConfig(func(cfg *Config) {
+		cfg.DistributedTracer.InboundSampled.Mode = "sometimes"
+	})
+	if nil == err {
+		t.Fatal("invalid configuration should be an error")
+	}
+	if mode := internalApp(app).currentConfig().DistributedTracer.InboundSampled.Mode; mode != InboundSampledHonor {
+		t.Error(mode)
+	}
+}
+
+func TestUpdateConfigLogger(t *testing.T) {
+	app := testApp(nil, nil, t)
+	buf := &bytes.Buffer{}
+	if err := app.UpdateConfig(ConfigDebugLogger(buf)); nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "application configuration updated") {
+		t.Error(buf.String())
+	}
+	run, _ := internalApp(app).getState()
+	if !run.Config.Logger.DebugEnabled() {
+		t.Error("transactions should use the new logger")
+	}
+	if s := loggerSetting(run.Config.Logger); "*logger.logFile" != s {
+		t.Error(s)
+	}
+}
+
+func TestUpdateConfigRollups(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.AppName = "one;two"
+		cfg.ApplicationSubsets = []string{"one"}
+	}, t)
+	sub, err := app.app.rollups.get(app.app, []string{"one"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.UpdateConfig(func(cfg *Config) {
+		cfg.Attributes.Exclude = []string{"zip"}
+	}); nil != err {
+		t.Fatal(err)
+	}
+	cfg := sub.currentConfig()
+	if "one" != cfg.AppName || 1 != len(cfg.Attributes.Exclude) {
+		t.Error(cfg.AppName, cfg.Attributes.Exclude)
+	}
+}
+
+func TestUpdateConfigNilApplication(t *testing.T) {
+	var app *Application
+	if err := app.UpdateConfig(ConfigEnabled(false)); nil != err {
+		t.Error(err)
+	}
+	if err := (&Application{}).UpdateConfig(ConfigEnabled(false)); nil != err {
+		t.Error(err)
+	}
+}
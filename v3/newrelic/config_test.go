@@ -128,14 +128,27 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 		"agent_version":"0.2.2",
 		"host":"my-hostname",
 		"settings":{
+			"AllowedRegions":null,
+			"AnomalyDetection":{"Enabled":false,"Factor":3,"MinSamples":20,"Weight":0.1},
 			"AppName":"my appname",
+			"ApplicationLogging":{
+				"Enabled":true,
+				"Forwarding":{"Enabled":false,"MaxSamplesStored":10000},
+				"Metrics":{"Enabled":true}
+			},
+			"ApplicationSubsets":null,
+			"AttributeLimits":{"MaxCount":64,"MaxKeyLength":255,"MaxValueLength":255,"RejectInvalidStrings":false},
 			"Attributes":{"Enabled":true,"Exclude":["2"],"Include":["1"]},
 			"BrowserMonitoring":{
 				"Attributes":{"Enabled":false,"Exclude":["10"],"Include":["9"]},
 				"Enabled":true
 			},
+			"ClientIP":{"Anonymize":true,"Enabled":false,"TrustForwardedFor":false},
+			"CodeLevelMetrics":{"Depth":20,"Enabled":false,"IgnoredPrefixes":["net/http.","database/sql."]},
+			"ContentionProfiling":{"BlockProfileRate":10000,"Enabled":false,"MaxSites":5,"MutexProfileFraction":10},
+			"ContextCancellation":{"Enabled":true,"NoticeErrors":false},
 			"CrossApplicationTracer":{"Enabled":true},
-			"CustomInsightsEvents":{"Enabled":true},
+			"CustomInsightsEvents":{"Derivations":null,"Enabled":true},
 			"DatastoreTracer":{
 				"DatabaseNameReporting":{"Enabled":true},
 				"InstanceReporting":{"Enabled":true},
@@ -145,7 +158,8 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 					"Threshold":10000000
 				}
 			},
-			"DistributedTracer":{"Enabled":false,"ExcludeNewRelicHeader":false},
+			"DeadlineBudget":{"Enabled":true,"Fraction":0.5},
+			"DistributedTracer":{"AccountID":"","Enabled":false,"ErrorSamplingBudget":0,"ExcludeNewRelicHeader":false,"InboundSampled":{"Mode":"honor","Rules":null},"SamplingRules":null,"TailSampling":{"Enabled":false,"LatencyThreshold":1000000000},"TrustedAccountKey":""},
 			"Enabled":true,
 			"Error":null,
 			"ErrorCollector":{
@@ -153,7 +167,19 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 				"CaptureEvents":true,
 				"Enabled":true,
 				"IgnoreStatusCodes":[0,5,404,405],
-				"RecordPanics":false
+				"MaxAttributeDepth":3,
+				"RecordPanics":false,
+				"StratifyEvents":true
+			},
+			"EventHarvest":{"Adaptive":{"Enabled":false,"MinPeriod":5000000000}},
+			"ExternalEntities":null,
+			"ExternalErrors":{"ClientErrors":false,"Rules":null,"ServerErrors":false},
+			"FailoverHosts":null,
+			"Gateway":{"Address":""},
+			"HarvestSender":null,
+			"HealthChecks":{
+				"Enabled":true,
+				"UserAgents":["kube-probe/","ELB-HealthChecker/","GoogleHC/","Consul Health Check","Envoy/HC"]
 			},
 			"Heroku":{
 				"DynoNamePrefixesToShorten":["scheduler","run"],
@@ -169,9 +195,16 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 					"Port": 443
                 }
 			},
+			"IntegrationAttributes":{"Exclude":null},
+			"LabelAttributes":{"Enabled":false,"Prefix":"label."},
+			"LabelHierarchies":null,
 			"Labels":{"zip":"zap"},
+			"LicenseRefreshPeriod":600000000000,
 			"Logger":"*logger.logFile",
+			"ManualHarvest":{"Enabled":false},
+			"OTLP":{"Endpoint":"","Headers":null},
 			"RuntimeSampler":{"Enabled":true},
+			"ScalingSignal":{"Enabled":false,"TargetCPUUtilization":0.7,"TargetInFlightPerProc":8,"TargetQueueTime":50000000},
 			"SecurityPoliciesToken":"",
 			"ServerlessMode":{
 				"AccountID":"",
@@ -180,17 +213,25 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 				"PrimaryAppID":"",
 				"TrustedAccountKey":""
 			},
+			"ShortLived":{"Enabled":false},
 			"SpanEvents":{
+				"AttributeNaming":"newrelic",
 				"Attributes":{
 					"Enabled":true,"Exclude":["12"],"Include":["11"]
 				},
-				"Enabled":true
+				"Enabled":true,
+				"PropagateAttributes":null
 			},
+			"Spool":{"Directory":"","MaxBytes":104857600},
+			"StartupSummary":{"Enabled":true},
+			"TransactionCheckpoints":{"Enabled":false,"Interval":60000000000,"Threshold":300000000000},
 			"TransactionEvents":{
 				"Attributes":{"Enabled":true,"Exclude":["4"],"Include":["3"]},
+				"DistributedTracingIntrinsics":"all",
 				"Enabled":true,
 				"MaxSamplesStored": 10000
 			},
+			"TransactionProcessCPUTime":{"Enabled":false},
 			"TransactionTracer":{
 				"Attributes":{"Enabled":true,"Exclude":["8"],"Include":["7"]},
 				"Enabled":true,
@@ -207,6 +248,7 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 			"Transport":"*http.Transport",
 			"Utilization":{
 				"BillingHostname":"",
+				"CacheTTL":0,
 				"DetectAWS":true,
 				"DetectAzure":true,
 				"DetectDocker":true,
@@ -214,6 +256,8 @@ func TestCopyConfigReferenceFieldsPresent(t *testing.T) {
 				"DetectKubernetes":true,
 				"DetectPCF":true,
 				"LogicalProcessors":0,
+				"Retries":0,
+				"Timeout":500000000,
 				"TotalRAMMIB":0
 			},
 			"browser_monitoring.loader":"rum"
@@ -300,7 +344,16 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 		"agent_version":"0.2.2",
 		"host":"my-hostname",
 		"settings":{
+			"AllowedRegions":null,
+			"AnomalyDetection":{"Enabled":false,"Factor":3,"MinSamples":20,"Weight":0.1},
 			"AppName":"my appname",
+			"ApplicationLogging":{
+				"Enabled":true,
+				"Forwarding":{"Enabled":false,"MaxSamplesStored":10000},
+				"Metrics":{"Enabled":true}
+			},
+			"ApplicationSubsets":null,
+			"AttributeLimits":{"MaxCount":64,"MaxKeyLength":255,"MaxValueLength":255,"RejectInvalidStrings":false},
 			"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
 			"BrowserMonitoring":{
 				"Attributes":{
@@ -310,8 +363,12 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				},
 				"Enabled":true
 			},
+			"ClientIP":{"Anonymize":true,"Enabled":false,"TrustForwardedFor":false},
+			"CodeLevelMetrics":{"Depth":20,"Enabled":false,"IgnoredPrefixes":["net/http.","database/sql."]},
+			"ContentionProfiling":{"BlockProfileRate":10000,"Enabled":false,"MaxSites":5,"MutexProfileFraction":10},
+			"ContextCancellation":{"Enabled":true,"NoticeErrors":false},
 			"CrossApplicationTracer":{"Enabled":true},
-			"CustomInsightsEvents":{"Enabled":true},
+			"CustomInsightsEvents":{"Derivations":null,"Enabled":true},
 			"DatastoreTracer":{
 				"DatabaseNameReporting":{"Enabled":true},
 				"InstanceReporting":{"Enabled":true},
@@ -321,7 +378,8 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 					"Threshold":10000000
 				}
 			},
-			"DistributedTracer":{"Enabled":false,"ExcludeNewRelicHeader":false},
+			"DeadlineBudget":{"Enabled":true,"Fraction":0.5},
+			"DistributedTracer":{"AccountID":"","Enabled":false,"ErrorSamplingBudget":0,"ExcludeNewRelicHeader":false,"InboundSampled":{"Mode":"honor","Rules":null},"SamplingRules":null,"TailSampling":{"Enabled":false,"LatencyThreshold":1000000000},"TrustedAccountKey":""},
 			"Enabled":true,
 			"Error":null,
 			"ErrorCollector":{
@@ -329,7 +387,19 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				"CaptureEvents":true,
 				"Enabled":true,
 				"IgnoreStatusCodes":null,
-				"RecordPanics":false
+				"MaxAttributeDepth":3,
+				"RecordPanics":false,
+				"StratifyEvents":true
+			},
+			"EventHarvest":{"Adaptive":{"Enabled":false,"MinPeriod":5000000000}},
+			"ExternalEntities":null,
+			"ExternalErrors":{"ClientErrors":false,"Rules":null,"ServerErrors":false},
+			"FailoverHosts":null,
+			"Gateway":{"Address":""},
+			"HarvestSender":null,
+			"HealthChecks":{
+				"Enabled":true,
+				"UserAgents":["kube-probe/","ELB-HealthChecker/","GoogleHC/","Consul Health Check","Envoy/HC"]
 			},
 			"Heroku":{
 				"DynoNamePrefixesToShorten":["scheduler","run"],
@@ -345,9 +415,16 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 					"Port": 443
                 }
 			},
+			"IntegrationAttributes":{"Exclude":null},
+			"LabelAttributes":{"Enabled":false,"Prefix":"label."},
+			"LabelHierarchies":null,
 			"Labels":null,
+			"LicenseRefreshPeriod":600000000000,
 			"Logger":null,
+			"ManualHarvest":{"Enabled":false},
+			"OTLP":{"Endpoint":"","Headers":null},
 			"RuntimeSampler":{"Enabled":true},
+			"ScalingSignal":{"Enabled":false,"TargetCPUUtilization":0.7,"TargetInFlightPerProc":8,"TargetQueueTime":50000000},
 			"SecurityPoliciesToken":"",
 			"ServerlessMode":{
 				"AccountID":"",
@@ -356,15 +433,23 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				"PrimaryAppID":"",
 				"TrustedAccountKey":""
 			},
+			"ShortLived":{"Enabled":false},
 			"SpanEvents":{
+				"AttributeNaming":"newrelic",
 				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
-				"Enabled":true
+				"Enabled":true,
+				"PropagateAttributes":null
 			},
+			"Spool":{"Directory":"","MaxBytes":104857600},
+			"StartupSummary":{"Enabled":true},
+			"TransactionCheckpoints":{"Enabled":false,"Interval":60000000000,"Threshold":300000000000},
 			"TransactionEvents":{
 				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
+				"DistributedTracingIntrinsics":"all",
 				"Enabled":true,
 				"MaxSamplesStored": 10000
 			},
+			"TransactionProcessCPUTime":{"Enabled":false},
 			"TransactionTracer":{
 				"Attributes":{"Enabled":true,"Exclude":null,"Include":null},
 				"Enabled":true,
@@ -381,6 +466,7 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 			"Transport":null,
 			"Utilization":{
 				"BillingHostname":"",
+				"CacheTTL":0,
 				"DetectAWS":true,
 				"DetectAzure":true,
 				"DetectDocker":true,
@@ -388,6 +474,8 @@ func TestCopyConfigReferenceFieldsAbsent(t *testing.T) {
 				"DetectKubernetes":true,
 				"DetectPCF":true,
 				"LogicalProcessors":0,
+				"Retries":0,
+				"Timeout":500000000,
 				"TotalRAMMIB":0
 			},
 			"browser_monitoring.loader":"rum"
@@ -773,3 +861,103 @@ func TestNewInternalConfig(t *testing.T) {
 		t.Error(c.metadata)
 	}
 }
+
+func TestNewInternalConfigExpandsEnvironment(t *testing.T) {
+	labels := map[string]string{"region": "${REGION}", "${KEY}": "value"}
+	failover := []string{"${BACKUP}.example.com"}
+	cfg := defaultConfig()
+	cfg.License = "0123456789012345678901234567890123456789"
+	cfg.AppName = "my app (${ENV})"
+	cfg.HostDisplayName = "${POD}-$POD-${POD"
+	cfg.Labels = labels
+	cfg.FailoverHosts = failover
+	c, err := newInternalConfig(cfg, func(s string) string {
+		switch s {
+		case "ENV":
+			return "staging"
+		case "REGION":
+			return "us-east-1"
+		case "POD":
+			return "web-7"
+		case "BACKUP":
+			return "backup"
+		case "KEY":
+			return "zone"
+		}
+		return ""
+	}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c.AppName != "my app (staging)" {
+		t.Error(c.AppName)
+	}
+	if c.HostDisplayName != "web-7-$POD-${POD" {
+		t.Error(c.HostDisplayName)
+	}
+	if !reflect.DeepEqual(c.Labels, map[string]string{"region": "us-east-1", "zone": "value"}) {
+		t.Error(c.Labels)
+	}
+	if !reflect.DeepEqual(c.FailoverHosts, []string{"backup.example.com"}) {
+		t.Error(c.FailoverHosts)
+	}
+	if labels["region"] != "${REGION}" || failover[0] != "${BACKUP}.example.com" {
+		t.Error("input config should not be modified", labels, failover)
+	}
+}
+
+func TestHostDisplayNameTokens(t *testing.T) {
+	var util utilization.Data
+	if err := json.Unmarshal([]byte(`{
+		"hostname":"my-hostname",
+		"vendors":{"aws":{"availabilityZone":"us-east-1a"}}
+	}`), &util); nil != err {
+		t.Fatal(err)
+	}
+	getenv := func(key string) string {
+		if key == "HOSTNAME" {
+			return "web-7f9c"
+		}
+		return ""
+	}
+	testcases := []struct {
+		name   string
+		util   *utilization.Data
+		expect string
+	}{
+		{name: "", util: &util, expect: ""},
+		{name: "static", util: &util, expect: "static"},
+		{name: "{hostname}", util: &util, expect: "my-hostname"},
+		{name: "web-{region}-{pod}", util: &util, expect: "web-us-east-1-web-7f9c"},
+		{name: "{region}", util: &utilization.SampleData, expect: "unknown"},
+		{name: "{other}", util: &util, expect: "{other}"},
+	}
+	for _, tc := range testcases {
+		c := config{Config: Config{HostDisplayName: tc.name}}
+		if name := c.hostDisplayName(tc.util, getenv); name != tc.expect {
+			t.Errorf("%q: %q != %q", tc.name, name, tc.expect)
+		}
+	}
+
+	podName := func(key string) string {
+		if key == "POD_NAME" {
+			return "checkout-0"
+		}
+		return getenv(key)
+	}
+	c := config{Config: Config{HostDisplayName: "{pod}"}}
+	if name := c.hostDisplayName(&util, podName); name != "checkout-0" {
+		t.Error(name)
+	}
+}
+
+func TestPreconnectHosts(t *testing.T) {
+	cfg := config{Config: Config{
+		License:       "0123456789012345678901234567890123456789",
+		FailoverHosts: []string{"backup-1.example.com", "", preconnectHostDefault, "backup-2.example.com", "backup-1.example.com"},
+	}}
+	expect := []string{preconnectHostDefault, "backup-1.example.com", "backup-2.example.com"}
+	if got := cfg.preconnectHosts(); !reflect.DeepEqual(got, expect) {
+		t.Error(got)
+	}
+}
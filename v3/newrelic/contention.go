@@ -0,0 +1,166 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	contentionBlock = "block"
+	contentionMutex = "mutex"
+
+	// contentionSnapshotInterval is how long a snapshot of the profiles
+	// is reused.  Reading the profiles is expensive, so the transactions
+	// which start or end within the interval share a snapshot.
+	contentionSnapshotInterval = 100 * time.Millisecond
+)
+
+// contentionKey identifies a record in the block or mutex profile.
+type contentionKey struct {
+	profile string
+	stack   [32]uintptr
+}
+
+// contentionSnapshot maps the records of the block and mutex profiles to
+// their cumulative delay in cycles.
+type contentionSnapshot map[contentionKey]int64
+
+// contentionSnapshots caches the most recent snapshot of the profiles.
+type contentionSnapshots struct {
+	sync.Mutex
+	taken    time.Time
+	snapshot contentionSnapshot
+}
+
+// get returns a snapshot of the profiles taken at most
+// contentionSnapshotInterval before now.  Snapshots are never modified, so
+// they may be shared.
+func (c *contentionSnapshots) get(now time.Time) contentionSnapshot {
+	c.Lock()
+	defer c.Unlock()
+
+	if nil == c.snapshot || now.Sub(c.taken) >= contentionSnapshotInterval || now.Before(c.taken) {
+		c.snapshot = takeContentionSnapshot()
+		c.taken = now
+	}
+	return c.snapshot
+}
+
+// contentionSite returns the location of the first frame of the stack which
+// is outside of the runtime and sync packages.
+func contentionSite(stack []uintptr) string {
+	frames := runtime.CallersFrames(stack)
+	var site string
+	for {
+		frame, more := frames.Next()
+		site = fmt.Sprintf("%s:%d", frame.Function, frame.Line)
+		if !strings.HasPrefix(frame.Function, "runtime.") &&
+			!strings.HasPrefix(frame.Function, "sync.") &&
+			!strings.HasPrefix(frame.Function, "internal/") {
+			return site
+		}
+		if !more {
+			return site
+		}
+	}
+}
+
+// contentionDelay is the delay added at a contention site.
+type contentionDelay struct {
+	site  string
+	delay time.Duration
+}
+
+type byDecreasingDelay []contentionDelay
+
+func (s byDecreasingDelay) Len() int      { return len(s) }
+func (s byDecreasingDelay) Swap(i, j int) { s[i], s[j] = s[j], s[i] }
+func (s byDecreasingDelay) Less(i, j int) bool {
+	if s[i].delay != s[j].delay {
+		return s[i].delay > s[j].delay
+	}
+	return s[i].site < s[j].site
+}
+
+// contentionStats summarizes the contention between two snapshots.
+type contentionStats struct {
+	blockDelay time.Duration
+	mutexDelay time.Duration
+	// sites is sorted by decreasing delay.
+	sites []contentionDelay
+}
+
+func getContentionStats(start, end contentionSnapshot, cyclesPerSecond float64, maxSites int) contentionStats {
+	var stats contentionStats
+	if cyclesPerSecond <= 0 {
+		return stats
+	}
+	bySite := make(map[string]time.Duration)
+	for key, cycles := range end {
+		delta := cycles - start[key]
+		if delta <= 0 {
+			continue
+		}
+		delay := time.Duration(float64(delta) / cyclesPerSecond * float64(time.Second))
+		if contentionBlock == key.profile {
+			stats.blockDelay += delay
+		} else {
+			stats.mutexDelay += delay
+		}
+		stack := key.stack[:]
+		for i, pc := range stack {
+			if 0 == pc {
+				stack = stack[:i]
+				break
+			}
+		}
+		bySite[key.profile+" "+contentionSite(stack)] += delay
+	}
+	for site, delay := range bySite {
+		stats.sites = append(stats.sites, contentionDelay{site: site, delay: delay})
+	}
+	sort.Sort(byDecreasingDelay(stats.sites))
+	if maxSites < 0 {
+		maxSites = 0
+	}
+	if len(stats.sites) > maxSites {
+		stats.sites = stats.sites[:maxSites]
+	}
+	return stats
+}
+
+func (s contentionStats) sitesString() string {
+	parts := make([]string, len(s.sites))
+	for i, site := range s.sites {
+		parts[i] = fmt.Sprintf("%s %.3fs", site.site, site.delay.Seconds())
+	}
+	return strings.Join(parts, "; ")
+}
+
+// recordContention adds the contention since the transaction started as
+// agent attributes if the transaction is sampled.  It must be called while
+// the transaction is locked.
+func (txn *txn) recordContention() {
+	if nil == txn.contentionStart {
+		return
+	}
+	start := txn.contentionStart
+	txn.contentionStart = nil
+	if txn.BetterCAT.Enabled && !txn.BetterCAT.Sampled {
+		return
+	}
+	stats := getContentionStats(start, txn.app.contention.get(time.Now()), cyclesPerSecond(),
+		txn.Config.ContentionProfiling.MaxSites)
+	txn.Attrs.Agent.Add(AttributeContentionBlockDelay, "", stats.blockDelay.Seconds())
+	txn.Attrs.Agent.Add(AttributeContentionMutexDelay, "", stats.mutexDelay.Seconds())
+	if len(stats.sites) > 0 {
+		txn.Attrs.Agent.Add(AttributeContentionSites, stats.sitesString(), nil)
+	}
+}
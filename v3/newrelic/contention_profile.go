@@ -0,0 +1,82 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"bufio"
+	"bytes"
+	"runtime"
+	"runtime/pprof"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// enableContentionProfiling turns on the runtime's block and mutex
+// profiles.
+func enableContentionProfiling(blockRate, mutexFraction int) {
+	runtime.SetBlockProfileRate(blockRate)
+	runtime.SetMutexProfileFraction(mutexFraction)
+}
+
+func readContentionProfile(read func([]runtime.BlockProfileRecord) (int, bool)) []runtime.BlockProfileRecord {
+	n, _ := read(nil)
+	for {
+		// Allow for records added between calls.
+		records := make([]runtime.BlockProfileRecord, n+50)
+		m, ok := read(records)
+		if ok {
+			return records[:m]
+		}
+		n = m
+	}
+}
+
+// takeContentionSnapshot reads the block and mutex profiles.
+func takeContentionSnapshot() contentionSnapshot {
+	snapshot := make(contentionSnapshot)
+	for _, r := range readContentionProfile(runtime.BlockProfile) {
+		snapshot[contentionKey{profile: contentionBlock, stack: r.Stack0}] += r.Cycles
+	}
+	for _, r := range readContentionProfile(runtime.MutexProfile) {
+		snapshot[contentionKey{profile: contentionMutex, stack: r.Stack0}] += r.Cycles
+	}
+	return snapshot
+}
+
+var (
+	cyclesPerSecondOnce  sync.Once
+	cyclesPerSecondValue float64
+)
+
+// cyclesPerSecond returns the rate of the clock used to measure delays in
+// the block and mutex profiles.  The runtime does not export this rate, but
+// it is included in the header of the legacy text format of the profiles.
+// Zero is returned if the rate cannot be found.
+func cyclesPerSecond() float64 {
+	cyclesPerSecondOnce.Do(func() {
+		p := pprof.Lookup(contentionMutex)
+		if nil == p {
+			return
+		}
+		var buf bytes.Buffer
+		if err := p.WriteTo(&buf, 1); nil != err {
+			return
+		}
+		scanner := bufio.NewScanner(&buf)
+		for scanner.Scan() {
+			line := scanner.Text()
+			if strings.HasPrefix(line, "cycles/second=") {
+				v, err := strconv.ParseFloat(strings.TrimPrefix(line, "cycles/second="), 64)
+				if nil == err && v > 0 {
+					cyclesPerSecondValue = v
+				}
+				return
+			}
+		}
+	})
+	return cyclesPerSecondValue
+}
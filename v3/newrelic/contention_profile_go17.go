@@ -0,0 +1,15 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build !go1.8
+
+package newrelic
+
+// The mutex profile was added in Go 1.8, so contention is not recorded for
+// earlier versions.
+
+func enableContentionProfiling(blockRate, mutexFraction int) {}
+
+func takeContentionSnapshot() contentionSnapshot { return nil }
+
+func cyclesPerSecond() float64 { return 0 }
@@ -0,0 +1,97 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"reflect"
+	"runtime"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestContentionProfiling(t *testing.T) {
+	enableContentionProfiling(1, 1)
+	defer enableContentionProfiling(0, 0)
+
+	cfgfn := func(cfg *Config) { cfg.ContentionProfiling.Enabled = true }
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	var mu sync.Mutex
+	mu.Lock()
+	done := make(chan struct{})
+	go func() {
+		mu.Lock()
+		mu.Unlock()
+		close(done)
+	}()
+	time.Sleep(10 * time.Millisecond)
+	mu.Unlock()
+	<-done
+	// The snapshot taken when the transaction started is reused until
+	// the interval has elapsed.
+	time.Sleep(contentionSnapshotInterval)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			AttributeContentionBlockDelay: internal.MatchAnything,
+			AttributeContentionMutexDelay: internal.MatchAnything,
+			AttributeContentionSites:      internal.MatchAnything,
+		},
+	}})
+	if cyclesPerSecond() <= 0 {
+		t.Error(cyclesPerSecond())
+	}
+}
+
+func TestContentionSnapshotsCached(t *testing.T) {
+	enableContentionProfiling(1, 0)
+	defer enableContentionProfiling(0, 0)
+
+	var c contentionSnapshots
+	now := time.Now()
+	first := c.get(now)
+	if nil == first {
+		t.Fatal("no snapshot")
+	}
+	if s := c.get(now.Add(contentionSnapshotInterval / 2)); reflect.ValueOf(s).Pointer() != reflect.ValueOf(first).Pointer() {
+		t.Error("snapshot not reused within the interval")
+	}
+	if s := c.get(now.Add(contentionSnapshotInterval)); reflect.ValueOf(s).Pointer() == reflect.ValueOf(first).Pointer() {
+		t.Error("snapshot reused after the interval")
+	}
+}
+
+func TestTakeContentionSnapshot(t *testing.T) {
+	enableContentionProfiling(1, 0)
+	defer enableContentionProfiling(0, 0)
+
+	ch := make(chan struct{})
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		close(ch)
+	}()
+	<-ch
+	runtime.Gosched()
+
+	snapshot := takeContentionSnapshot()
+	found := false
+	for key := range snapshot {
+		if contentionBlock == key.profile {
+			found = true
+		}
+	}
+	if !found {
+		t.Error("block profile record missing")
+	}
+}
@@ -0,0 +1,57 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+)
+
+func contentionTestStack() [32]uintptr {
+	var stack [32]uintptr
+	runtime.Callers(1, stack[:])
+	return stack
+}
+
+func TestGetContentionStats(t *testing.T) {
+	blockKey := contentionKey{profile: contentionBlock, stack: contentionTestStack()}
+	mutexKey := contentionKey{profile: contentionMutex, stack: contentionTestStack()}
+	unchangedKey := contentionKey{profile: contentionMutex}
+	start := contentionSnapshot{
+		blockKey:     100,
+		unchangedKey: 500,
+	}
+	end := contentionSnapshot{
+		blockKey:     400,
+		mutexKey:     100,
+		unchangedKey: 500,
+	}
+	// 100 cycles per second makes each cycle 10ms.
+	stats := getContentionStats(start, end, 100, 5)
+	if stats.blockDelay != 3*time.Second || stats.mutexDelay != 1*time.Second {
+		t.Error(stats.blockDelay, stats.mutexDelay)
+	}
+	if len(stats.sites) != 2 {
+		t.Fatal(stats.sites)
+	}
+	sites := stats.sitesString()
+	if !strings.HasPrefix(sites, "block newrelic.contentionTestStack:") &&
+		!strings.HasPrefix(sites, "block github.com/newrelic/go-agent/v3/newrelic.contentionTestStack:") {
+		t.Error(sites)
+	}
+	if !strings.Contains(sites, " 3.000s; mutex ") || !strings.HasSuffix(sites, " 1.000s") {
+		t.Error(sites)
+	}
+
+	stats = getContentionStats(start, end, 100, 1)
+	if len(stats.sites) != 1 || stats.sites[0].delay != 3*time.Second {
+		t.Error(stats.sites)
+	}
+	stats = getContentionStats(start, end, 0, 5)
+	if stats.blockDelay != 0 || len(stats.sites) != 0 {
+		t.Error(stats)
+	}
+}
@@ -11,8 +11,18 @@ import (
 )
 
 // NewContext returns a new context.Context that carries the provided
-// transaction.
+// transaction.  If ctx is cancelled or its deadline passes before the
+// transaction ends, the transaction is annotated, see
+// Config.ContextCancellation.
 func NewContext(ctx context.Context, txn *Transaction) context.Context {
+	if nil != txn && nil != txn.thread {
+		if deadline, ok := ctx.Deadline(); ok {
+			// Errors are not logged since transactions are often
+			// added to contexts after they have ended.
+			txn.thread.SetDeadline(deadline)
+		}
+		txn.thread.watchContext(ctx)
+	}
 	return context.WithValue(ctx, internal.TransactionContextKey, txn)
 }
 
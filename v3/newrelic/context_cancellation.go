@@ -0,0 +1,61 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"time"
+)
+
+const (
+	contextCanceledErrorKlass         = "context.Canceled"
+	contextDeadlineExceededErrorKlass = "context.DeadlineExceeded"
+)
+
+// watchContext records the context whose cancellation is reported when the
+// transaction ends.  Contexts which can never be cancelled are ignored.
+func (txn *txn) watchContext(ctx context.Context) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if !txn.Config.ContextCancellation.Enabled {
+		return nil
+	}
+	if nil == txn.ctx && nil != ctx.Done() {
+		txn.ctx = ctx
+	}
+	return nil
+}
+
+// recordContextCancellation adds the context attributes, and notices an
+// error if configured, when the watched context is done.  It must be called
+// while the transaction is locked.
+func (thd *thread) recordContextCancellation() {
+	txn := thd.txn
+	if nil == txn.ctx {
+		return
+	}
+	var klass string
+	switch err := txn.ctx.Err(); err {
+	case context.Canceled:
+		txn.Attrs.Agent.Add(AttributeContextCancelled, "", true)
+		klass = contextCanceledErrorKlass
+	case context.DeadlineExceeded:
+		txn.Attrs.Agent.Add(AttributeContextDeadlineExceeded, "", true)
+		klass = contextDeadlineExceededErrorKlass
+	default:
+		return
+	}
+	if txn.Config.ContextCancellation.NoticeErrors {
+		thd.noticeErrorInternal(errorData{
+			When:  time.Now(),
+			Stack: getStackTrace(),
+			Msg:   txn.ctx.Err().Error(),
+			Klass: klass,
+		})
+	}
+}
@@ -0,0 +1,105 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestContextCancelledAttribute(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	ctx, cancel := context.WithCancel(context.Background())
+	NewContext(ctx, txn)
+	cancel()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			"context.cancelled": true,
+		},
+	}})
+	app.ExpectErrors(t, []internal.WantError{})
+}
+
+func TestContextDeadlineExceededNoticeErrors(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ContextCancellation.NoticeErrors = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+	req, _ := http.NewRequest("GET", "http://example.com/hello", nil)
+	txn.SetWebRequestHTTP(req.WithContext(ctx))
+	<-ctx.Done()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"nr.apdexPerfZone": "F",
+			"error":            true,
+		},
+		AgentAttributes: map[string]interface{}{
+			"context.deadline_exceeded": true,
+			"request.method":            "GET",
+			"request.uri":               "http://example.com/hello",
+			"request.headers.host":      "example.com",
+			"http.flavor":               "1.1",
+		},
+	}})
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "WebTransaction/Go/hello",
+		Msg:     "context deadline exceeded",
+		Klass:   contextDeadlineExceededErrorKlass,
+	}})
+}
+
+func TestContextCancellationFirstContextWatched(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ctx = NewContext(ctx, txn)
+	// Contexts derived for individual calls are not watched.
+	callCtx, callCancel := context.WithCancel(ctx)
+	NewContext(callCtx, txn)
+	callCancel()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestContextCancellationDisabled(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ContextCancellation.Enabled = false
+		cfg.ContextCancellation.NoticeErrors = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	ctx, cancel := context.WithCancel(context.Background())
+	NewContext(ctx, txn)
+	cancel()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{},
+	}})
+	app.ExpectErrors(t, []internal.WantError{})
+}
@@ -0,0 +1,36 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "github.com/newrelic/go-agent/v3/internal/sysinfo"
+
+// getCPUUsage returns the CPU usage of the process.  ok is false if the
+// usage could not be read, for example on unsupported platforms.
+func getCPUUsage() (usage sysinfo.Usage, ok bool) {
+	usage, err := sysinfo.GetUsage()
+	return usage, nil == err
+}
+
+// recordProcessCPUTime adds the CPU time used by the process since the
+// transaction started as agent attributes.  Go offers no measure of the CPU
+// time of a goroutine, and a transaction's goroutines are not tied to OS
+// threads, so the CPU time of concurrent transactions and background work is
+// included.  It must be called while the transaction is locked.
+func (txn *txn) recordProcessCPUTime() {
+	if !txn.cpuStartOK {
+		return
+	}
+	end, ok := getCPUUsage()
+	if !ok {
+		return
+	}
+	user := end.User - txn.cpuStart.User
+	system := end.System - txn.cpuStart.System
+	if user < 0 || system < 0 {
+		return
+	}
+	txn.Attrs.Agent.Add(AttributeProcessCPUTime, "", (user + system).Seconds())
+	txn.Attrs.Agent.Add(AttributeProcessCPUUserTime, "", user.Seconds())
+	txn.Attrs.Agent.Add(AttributeProcessCPUSystemTime, "", system.Seconds())
+}
@@ -0,0 +1,53 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestTransactionProcessCPUTime(t *testing.T) {
+	cfgfn := func(cfg *Config) { cfg.TransactionProcessCPUTime.Enabled = true }
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			AttributeProcessCPUTime:       internal.MatchAnything,
+			AttributeProcessCPUUserTime:   internal.MatchAnything,
+			AttributeProcessCPUSystemTime: internal.MatchAnything,
+		},
+	}})
+}
+
+func TestTransactionProcessCPUTimeDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestRecordProcessCPUTime(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.thread.cpuStartOK = true
+	txn.End()
+	// The CPU time is the process CPU time since the process started.
+	txn.thread.Lock()
+	defer txn.thread.Unlock()
+	if v, ok := txn.thread.Attrs.Agent[AttributeProcessCPUTime]; !ok || v.otherVal.(float64) <= 0 {
+		t.Error(v)
+	}
+}
@@ -30,6 +30,11 @@ type customEvent struct {
 	eventType       string
 	timestamp       time.Time
 	truncatedParams map[string]interface{}
+	// numTruncated and numCoerced are the number of attribute values which
+	// were truncated, and which contained invalid UTF-8 or control
+	// characters that were replaced.
+	numTruncated int
+	numCoerced   int
 }
 
 // WriteJSON prepares JSON in the format expected by the collector.
@@ -74,8 +79,12 @@ func eventTypeValidate(eventType string) error {
 	return nil
 }
 
-// CreateCustomEvent creates a custom event.
+// CreateCustomEvent creates a custom event using the default attribute limits.
 func createCustomEvent(eventType string, params map[string]interface{}, now time.Time) (*customEvent, error) {
+	return defaultAttributeLimits.createCustomEvent(eventType, params, now)
+}
+
+func (l attributeLimits) createCustomEvent(eventType string, params map[string]interface{}, now time.Time) (*customEvent, error) {
 	if err := eventTypeValidate(eventType); nil != err {
 		return nil, err
 	}
@@ -83,13 +92,23 @@ func createCustomEvent(eventType string, params map[string]interface{}, now time
 	if len(params) > customEventAttributeLimit {
 		return nil, errNumAttributes
 	}
+	if len(params) > l.count {
+		return nil, fmt.Errorf("maximum of %d attributes exceeded", l.count)
+	}
 
 	truncatedParams := make(map[string]interface{})
+	var numTruncated, numCoerced int
 	for key, val := range params {
-		val, err := validateUserAttribute(key, val)
+		val, fixes, err := l.validate(key, val)
 		if nil != err {
 			return nil, err
 		}
+		if fixes.truncated {
+			numTruncated++
+		}
+		if fixes.coerced {
+			numCoerced++
+		}
 		truncatedParams[key] = val
 	}
 
@@ -97,10 +116,18 @@ func createCustomEvent(eventType string, params map[string]interface{}, now time
 		eventType:       eventType,
 		timestamp:       now,
 		truncatedParams: truncatedParams,
+		numTruncated:    numTruncated,
+		numCoerced:      numCoerced,
 	}, nil
 }
 
 // MergeIntoHarvest implements Harvestable.
 func (e *customEvent) MergeIntoHarvest(h *harvest) {
 	h.CustomEvents.Add(e)
+	if e.numTruncated > 0 && nil != h.Metrics {
+		h.Metrics.addCount(supportAttributesTruncated, float64(e.numTruncated), forced)
+	}
+	if e.numCoerced > 0 && nil != h.Metrics {
+		h.Metrics.addCount(supportAttributesCoerced, float64(e.numCoerced), forced)
+	}
 }
@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "fmt"
+
+// CustomEventDerivation records an additional custom event, of another
+// event type, each time Application.RecordCustomEvent records a custom event
+// of the SourceType.  It allows one call to feed several event types, such
+// as a compact billing event derived from a verbose debug event.  See
+// Config.CustomInsightsEvents.Derivations.
+type CustomEventDerivation struct {
+	// SourceType is the event type of the custom events from which events
+	// are derived.
+	SourceType string
+	// EventType is the event type of the derived events.
+	EventType string
+	// Attributes lists the attributes of the source event copied to the
+	// derived event.  If it is empty, every attribute is copied.
+	Attributes []string
+	// Transform, if set, is called with a copy of the attributes selected
+	// by Attributes, which it may modify, and returns the attributes of
+	// the derived event.  No event is derived if it returns nil.
+	Transform func(params map[string]interface{}) map[string]interface{} `json:"-"`
+}
+
+// validateCustomEventDerivations checks the event types of the
+// Derivations.
+func (c Config) validateCustomEventDerivations() error {
+	for _, d := range c.CustomInsightsEvents.Derivations {
+		if err := eventTypeValidate(d.SourceType); nil != err {
+			return fmt.Errorf("invalid custom event derivation SourceType %q: %v", d.SourceType, err)
+		}
+		if err := eventTypeValidate(d.EventType); nil != err {
+			return fmt.Errorf("invalid custom event derivation EventType %q: %v", d.EventType, err)
+		}
+		if d.SourceType == d.EventType {
+			return fmt.Errorf("custom event derivation EventType %q is the same as its SourceType", d.EventType)
+		}
+	}
+	return nil
+}
+
+// derive returns the attributes of the event derived from a source event
+// with the params, or nil if no event is derived.
+func (d CustomEventDerivation) derive(params map[string]interface{}) map[string]interface{} {
+	var derived map[string]interface{}
+	if 0 == len(d.Attributes) {
+		derived = make(map[string]interface{}, len(params))
+		for key, val := range params {
+			derived[key] = val
+		}
+	} else {
+		derived = make(map[string]interface{}, len(d.Attributes))
+		for _, key := range d.Attributes {
+			if val, ok := params[key]; ok {
+				derived[key] = val
+			}
+		}
+	}
+	if nil != d.Transform {
+		derived = d.Transform(derived)
+	}
+	return derived
+}
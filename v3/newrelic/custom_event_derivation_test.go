@@ -0,0 +1,109 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestCustomEventDerivations(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.CustomInsightsEvents.Derivations = []CustomEventDerivation{
+			{
+				SourceType: "CheckoutDebug",
+				EventType:  "Billing",
+				Attributes: []string{"customer", "amount", "missing"},
+			},
+			{
+				SourceType: "CheckoutDebug",
+				EventType:  "LargeOrder",
+				Transform: func(params map[string]interface{}) map[string]interface{} {
+					if params["amount"].(float64) < 100 {
+						return nil
+					}
+					params["large"] = true
+					delete(params, "trace")
+					return params
+				},
+			},
+			{
+				SourceType: "OtherType",
+				EventType:  "Unused",
+			},
+		}
+	}
+	app := testApp(nil, cfgfn, t)
+	app.RecordCustomEvent("CheckoutDebug", map[string]interface{}{
+		"customer": "acme",
+		"amount":   250.0,
+		"trace":    "verbose details",
+	})
+	app.RecordCustomEvent("CheckoutDebug", map[string]interface{}{
+		"customer": "zap",
+		"amount":   10.0,
+	})
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{
+		{
+			Intrinsics:     map[string]interface{}{"type": "CheckoutDebug", "timestamp": internal.MatchAnything},
+			UserAttributes: map[string]interface{}{"customer": "acme", "amount": 250.0, "trace": "verbose details"},
+		},
+		{
+			Intrinsics:     map[string]interface{}{"type": "Billing", "timestamp": internal.MatchAnything},
+			UserAttributes: map[string]interface{}{"customer": "acme", "amount": 250.0},
+		},
+		{
+			Intrinsics:     map[string]interface{}{"type": "LargeOrder", "timestamp": internal.MatchAnything},
+			UserAttributes: map[string]interface{}{"customer": "acme", "amount": 250.0, "large": true},
+		},
+		{
+			Intrinsics:     map[string]interface{}{"type": "CheckoutDebug", "timestamp": internal.MatchAnything},
+			UserAttributes: map[string]interface{}{"customer": "zap", "amount": 10.0},
+		},
+		{
+			Intrinsics:     map[string]interface{}{"type": "Billing", "timestamp": internal.MatchAnything},
+			UserAttributes: map[string]interface{}{"customer": "zap", "amount": 10.0},
+		},
+	})
+}
+
+func TestCustomEventDerivationsNotRecorded(t *testing.T) {
+	// Events are not derived when the source event is not recorded.
+	cfgfn := func(cfg *Config) {
+		cfg.CustomInsightsEvents.Derivations = []CustomEventDerivation{
+			{SourceType: "myType", EventType: "derivedType"},
+		}
+	}
+	replyfn := func(reply *internal.ConnectReply) { reply.SecurityPolicies.CustomEvents.SetEnabled(false) }
+	app := testApp(replyfn, cfgfn, t)
+	app.RecordCustomEvent("myType", validParams)
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+func TestValidateCustomEventDerivations(t *testing.T) {
+	testcases := []struct {
+		derivation CustomEventDerivation
+		err        string
+	}{
+		{derivation: CustomEventDerivation{SourceType: "a", EventType: "b"}},
+		{derivation: CustomEventDerivation{SourceType: "", EventType: "b"}, err: "invalid custom event derivation SourceType"},
+		{derivation: CustomEventDerivation{SourceType: "a", EventType: "b!"}, err: "invalid custom event derivation EventType"},
+		{derivation: CustomEventDerivation{SourceType: "a", EventType: "a"}, err: "is the same as its SourceType"},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.CustomInsightsEvents.Derivations = []CustomEventDerivation{tc.derivation}
+		err := cfg.validateCustomEventDerivations()
+		if "" == tc.err {
+			if nil != err {
+				t.Error(tc.derivation, err)
+			}
+		} else if nil == err || !strings.Contains(err.Error(), tc.err) {
+			t.Error(tc.derivation, err)
+		}
+	}
+}
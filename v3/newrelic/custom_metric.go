@@ -3,6 +3,8 @@
 
 package newrelic
 
+import "math"
+
 // customMetric is a custom metric.
 type customMetric struct {
 	RawInputName string
@@ -13,3 +15,48 @@ type customMetric struct {
 func (m customMetric) MergeIntoHarvest(h *harvest) {
 	h.Metrics.addValue(customMetricName(m.RawInputName), "", m.Value, unforced)
 }
+
+// CustomMetricSummary aggregates values, such as the durations of many
+// operations, which are recorded together as a single custom metric using
+// Application.RecordCustomMetricSummary.  Recording a summary costs the
+// same as recording one value with Application.RecordCustomMetric, however
+// many values were added to it.  The zero value is an empty summary.  A
+// CustomMetricSummary is not safe for concurrent use.
+type CustomMetricSummary struct {
+	data metricData
+}
+
+// Add adds a value to the summary.  NaN and infinite values are ignored.
+func (s *CustomMetricSummary) Add(value float64) {
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	if 0 == s.data.countSatisfied {
+		s.data.min = value
+		s.data.max = value
+	}
+	s.data.aggregate(metricData{
+		countSatisfied:  1,
+		totalTolerated:  value,
+		exclusiveFailed: value,
+		min:             value,
+		max:             value,
+		sumSquares:      value * value,
+	})
+}
+
+// Count returns the number of values added to the summary.
+func (s *CustomMetricSummary) Count() int {
+	return int(s.data.countSatisfied)
+}
+
+// customMetricSummary is a custom metric of many values.
+type customMetricSummary struct {
+	RawInputName string
+	Data         metricData
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (m customMetricSummary) MergeIntoHarvest(h *harvest) {
+	h.Metrics.add(customMetricName(m.RawInputName), "", m.Data, unforced)
+}
@@ -10,6 +10,7 @@ type DatastoreProduct string
 // Datastore names used across New Relic agents:
 const (
 	DatastoreCassandra     DatastoreProduct = "Cassandra"
+	DatastoreClickHouse    DatastoreProduct = "ClickHouse"
 	DatastoreCouchDB       DatastoreProduct = "CouchDB"
 	DatastoreDerby         DatastoreProduct = "Derby"
 	DatastoreDynamoDB      DatastoreProduct = "DynamoDB"
@@ -22,6 +23,7 @@ const (
 	DatastoreMSSQL         DatastoreProduct = "MSSQL"
 	DatastoreMySQL         DatastoreProduct = "MySQL"
 	DatastoreNeptune       DatastoreProduct = "Neptune"
+	DatastoreOpenSearch    DatastoreProduct = "OpenSearch"
 	DatastoreOracle        DatastoreProduct = "Oracle"
 	DatastorePostgres      DatastoreProduct = "Postgres"
 	DatastoreRedis         DatastoreProduct = "Redis"
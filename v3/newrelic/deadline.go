@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+func (txn *txn) SetDeadline(deadline time.Time) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if !txn.Config.DeadlineBudget.Enabled {
+		return nil
+	}
+	if txn.deadline.IsZero() || deadline.Before(txn.deadline) {
+		txn.deadline = deadline
+	}
+	return nil
+}
+
+// addDeadlineAttributes records how much of the time remaining until the
+// transaction's deadline was used by the segment.
+func (t *txnData) addDeadlineAttributes(end *segmentEnd) {
+	if t.deadline.IsZero() {
+		return
+	}
+	remaining := t.deadline.Sub(end.start.Time)
+	end.agentAttributes.addFloat(SpanAttributeDeadlineRemaining, remaining.Seconds())
+	if remaining <= 0 {
+		end.agentAttributes.addBool(SpanAttributeDeadlineOverBudget, true)
+		return
+	}
+	consumed := end.duration.Seconds() / remaining.Seconds()
+	end.agentAttributes.addFloat(SpanAttributeDeadlineConsumed, consumed)
+	if t.deadlineBudgetFraction > 0 && consumed > t.deadlineBudgetFraction {
+		end.agentAttributes.addBool(SpanAttributeDeadlineOverBudget, true)
+	}
+}
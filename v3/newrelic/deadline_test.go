@@ -0,0 +1,198 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func deadlineTestApp(cfgfn func(*Config), t *testing.T) expectApp {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	return testApp(replyfn, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		if nil != cfgfn {
+			cfgfn(cfg)
+		}
+	}, t)
+}
+
+func TestDeadlineBudgetAttributes(t *testing.T) {
+	app := deadlineTestApp(nil, t)
+	txn := app.StartTransaction("hello")
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	ctx = NewContext(ctx, txn)
+
+	// This segment uses most of the remaining time.
+	segment := DatastoreSegment{
+		StartTime:  txn.StartSegmentNow(),
+		Product:    DatastoreMySQL,
+		Collection: "users",
+		Operation:  "SELECT",
+	}
+	<-ctx.Done()
+	segment.End()
+
+	// This segment starts after the deadline has passed.
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	StartExternalSegment(txn, req).End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "Datastore/statement/MySQL/users/SELECT",
+				"parentId":  internal.MatchAnything,
+				"category":  "datastore",
+				"component": "MySQL",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.collection":       "users",
+				"db.statement":        "'SELECT' on 'users' using 'MySQL'",
+				"deadline.remaining":  internal.MatchAnything,
+				"deadline.consumed":   internal.MatchAnything,
+				"deadline.overBudget": true,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "External/example.com/http/GET",
+				"parentId":  internal.MatchAnything,
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":            "http://example.com",
+				"http.method":         "GET",
+				"deadline.remaining":  internal.MatchAnything,
+				"deadline.overBudget": true,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"context.deadline_exceeded": true,
+			},
+		},
+	})
+}
+
+func TestDeadlineBudgetWithinBudget(t *testing.T) {
+	app := deadlineTestApp(nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDeadline(time.Now().Add(2 * time.Hour))
+	// The earliest deadline is used.
+	txn.SetDeadline(time.Now().Add(1 * time.Hour))
+	txn.SetDeadline(time.Now().Add(3 * time.Hour))
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	StartExternalSegment(txn, req).End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "External/example.com/http/GET",
+				"parentId":  internal.MatchAnything,
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":           "http://example.com",
+				"http.method":        "GET",
+				"deadline.remaining": internal.MatchAnything,
+				"deadline.consumed":  internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+	remaining := txn.thread.deadline.Sub(time.Now())
+	if remaining > time.Hour || remaining < 59*time.Minute {
+		t.Error(remaining)
+	}
+}
+
+func TestDeadlineBudgetDisabled(t *testing.T) {
+	app := deadlineTestApp(func(cfg *Config) {
+		cfg.DeadlineBudget.Enabled = false
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.SetDeadline(time.Now())
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	StartExternalSegment(txn, req).End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":      "External/example.com/http/GET",
+				"parentId":  internal.MatchAnything,
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.url":    "http://example.com",
+				"http.method": "GET",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSetDeadlineAfterEnd(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	txn.SetDeadline(time.Now())
+	app.expectSingleLoggedError(t, "unable to set deadline", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestSetDeadlineNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.SetDeadline(time.Now())
+	NewContext(context.Background(), txn)
+}
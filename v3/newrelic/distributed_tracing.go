@@ -32,7 +32,7 @@ const (
 )
 
 var (
-	currentDistTraceVersion = distTraceVersion([2]int{0 /* Major */, 1 /* Minor */})
+	currentDistTraceVersion = distTraceVersion([2]int{DistributedTracePayloadMajorVersion, DistributedTracePayloadMinorVersion})
 	callerUnknown           = payloadCaller{Type: "Unknown", App: "Unknown", Account: "Unknown", TransportType: "Unknown"}
 	traceParentRegex        = regexp.MustCompile(`^([a-f0-9]{2})-` + // version
 		`([a-f0-9]{32})-` + // traceId
@@ -179,11 +179,6 @@ var (
 	}()
 )
 
-const (
-	w3cVersion        = "00"
-	traceStateVersion = "0"
-)
-
 // W3CTraceParent returns the W3C TraceParent header for this payload
 func (p payload) W3CTraceParent() string {
 	var flags string
@@ -198,7 +193,7 @@ func (p payload) W3CTraceParent() string {
 	} else if idLen > internal.TraceIDHexStringLen {
 		traceID = traceID[idLen-internal.TraceIDHexStringLen:]
 	}
-	return w3cVersion + "-" + traceID + "-" + p.ID + "-" + flags
+	return W3CTraceParentVersion + "-" + traceID + "-" + p.ID + "-" + flags
 }
 
 // W3CTraceState returns the W3C TraceState header for this payload
@@ -211,7 +206,7 @@ func (p payload) W3CTraceState() string {
 		flags = "0"
 	}
 	state := p.TrustedAccountKey + "@nr=" +
-		traceStateVersion + "-" +
+		W3CTraceStateVersion + "-" +
 		typeMap[p.Type] + "-" +
 		p.Account + "-" +
 		p.App + "-" +
@@ -359,7 +354,7 @@ func processTraceParent(hdrs http.Header) (*payload, error) {
 }
 
 func validateVersionAndFlags(subMatches []string) bool {
-	if subMatches[1] == w3cVersion {
+	if subMatches[1] == W3CTraceParentVersion {
 		if subMatches[5] != "" {
 			return false
 		}
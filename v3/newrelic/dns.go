@@ -0,0 +1,166 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"context"
+	"net"
+)
+
+// These segment attributes are added to the segments created by Resolver.
+const (
+	// DNSAttributeName is the name looked up.
+	DNSAttributeName = "dns.name"
+	// DNSAttributeAnswers is the number of records returned by the lookup.
+	DNSAttributeAnswers = "dns.answers"
+	// DNSAttributeError is the error returned by the lookup, if any.
+	DNSAttributeError = "dns.error"
+)
+
+// Resolver wraps a net.Resolver to record DNS lookups.  When the context
+// passed to a lookup method contains a Transaction (see NewContext), the
+// lookup is recorded as a segment named "DNS/{method}", which creates
+// metrics such as "Custom/DNS/LookupHost".  Segments have the
+// DNSAttributeName, DNSAttributeAnswers, and DNSAttributeError attributes.
+//
+// To record the DNS lookups made by your own code, replace:
+//
+//	addrs, err := net.DefaultResolver.LookupHost(ctx, "example.com")
+//
+// With:
+//
+//	resolver := newrelic.NewResolver(nil)
+//	addrs, err := resolver.LookupHost(newrelic.NewContext(ctx, txn), "example.com")
+//
+// Every lookup method of net.Resolver is recorded, including LookupIP on Go
+// 1.15 and above and LookupNetIP on Go 1.18 and above.  Only the lookups
+// made through the Resolver are recorded: a net.Dialer, and so an
+// http.Client, resolves the addresses it dials itself, so the time spent
+// resolving the host of an external call is part of its external segment
+// but is not recorded separately.
+type Resolver struct {
+	resolver *net.Resolver
+}
+
+// NewResolver creates a Resolver which wraps r.  If r is nil then
+// net.DefaultResolver is used.
+func NewResolver(r *net.Resolver) *Resolver {
+	if nil == r {
+		r = net.DefaultResolver
+	}
+	return &Resolver{resolver: r}
+}
+
+func startDNSSegment(ctx context.Context, method, name string) *Segment {
+	txn := FromContext(ctx)
+	if nil == txn {
+		return nil
+	}
+	s := txn.StartSegment("DNS/" + method)
+	s.AddAttribute(DNSAttributeName, name)
+	return s
+}
+
+func endDNSSegment(s *Segment, answers int, err error) {
+	if nil == s {
+		return
+	}
+	s.AddAttribute(DNSAttributeAnswers, answers)
+	if nil != err {
+		s.AddAttribute(DNSAttributeError, err.Error())
+	}
+	s.End()
+}
+
+// LookupAddr performs a reverse lookup for the given address.
+func (r *Resolver) LookupAddr(ctx context.Context, addr string) ([]string, error) {
+	s := startDNSSegment(ctx, "LookupAddr", addr)
+	names, err := r.resolver.LookupAddr(ctx, addr)
+	endDNSSegment(s, len(names), err)
+	return names, err
+}
+
+// LookupCNAME returns the canonical name for the given host.
+func (r *Resolver) LookupCNAME(ctx context.Context, host string) (string, error) {
+	s := startDNSSegment(ctx, "LookupCNAME", host)
+	cname, err := r.resolver.LookupCNAME(ctx, host)
+	answers := 0
+	if "" != cname {
+		answers = 1
+	}
+	endDNSSegment(s, answers, err)
+	return cname, err
+}
+
+// LookupHost looks up the given host.  It returns a slice of that host's
+// addresses.
+func (r *Resolver) LookupHost(ctx context.Context, host string) ([]string, error) {
+	s := startDNSSegment(ctx, "LookupHost", host)
+	addrs, err := r.resolver.LookupHost(ctx, host)
+	endDNSSegment(s, len(addrs), err)
+	return addrs, err
+}
+
+// LookupIPAddr looks up host.  It returns a slice of that host's IPv4 and
+// IPv6 addresses.
+func (r *Resolver) LookupIPAddr(ctx context.Context, host string) ([]net.IPAddr, error) {
+	s := startDNSSegment(ctx, "LookupIPAddr", host)
+	addrs, err := r.resolver.LookupIPAddr(ctx, host)
+	endDNSSegment(s, len(addrs), err)
+	return addrs, err
+}
+
+// LookupMX returns the DNS MX records for the given domain name sorted by
+// preference.
+func (r *Resolver) LookupMX(ctx context.Context, name string) ([]*net.MX, error) {
+	s := startDNSSegment(ctx, "LookupMX", name)
+	records, err := r.resolver.LookupMX(ctx, name)
+	endDNSSegment(s, len(records), err)
+	return records, err
+}
+
+// LookupNS returns the DNS NS records for the given domain name.
+func (r *Resolver) LookupNS(ctx context.Context, name string) ([]*net.NS, error) {
+	s := startDNSSegment(ctx, "LookupNS", name)
+	records, err := r.resolver.LookupNS(ctx, name)
+	endDNSSegment(s, len(records), err)
+	return records, err
+}
+
+// LookupSRV tries to resolve an SRV query of the given service, protocol,
+// and domain name.  The name recorded is the name queried, eg.
+// "_xmpp-server._tcp.google.com".
+func (r *Resolver) LookupSRV(ctx context.Context, service, proto, name string) (string, []*net.SRV, error) {
+	query := name
+	if "" != service || "" != proto {
+		query = "_" + service + "._" + proto + "." + name
+	}
+	s := startDNSSegment(ctx, "LookupSRV", query)
+	cname, records, err := r.resolver.LookupSRV(ctx, service, proto, name)
+	endDNSSegment(s, len(records), err)
+	return cname, records, err
+}
+
+// LookupTXT returns the DNS TXT records for the given domain name.
+func (r *Resolver) LookupTXT(ctx context.Context, name string) ([]string, error) {
+	s := startDNSSegment(ctx, "LookupTXT", name)
+	records, err := r.resolver.LookupTXT(ctx, name)
+	endDNSSegment(s, len(records), err)
+	return records, err
+}
+
+// LookupPort looks up the port for the given network and service.  The
+// name recorded is the service.
+func (r *Resolver) LookupPort(ctx context.Context, network, service string) (int, error) {
+	s := startDNSSegment(ctx, "LookupPort", service)
+	port, err := r.resolver.LookupPort(ctx, network, service)
+	answers := 0
+	if nil == err {
+		answers = 1
+	}
+	endDNSSegment(s, answers, err)
+	return port, err
+}
@@ -0,0 +1,20 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.15
+
+package newrelic
+
+import (
+	"context"
+	"net"
+)
+
+// LookupIP looks up host for the given network.  It returns a slice of that
+// host's IP addresses of the type specified by network.
+func (r *Resolver) LookupIP(ctx context.Context, network, host string) ([]net.IP, error) {
+	s := startDNSSegment(ctx, "LookupIP", host)
+	addrs, err := r.resolver.LookupIP(ctx, network, host)
+	endDNSSegment(s, len(addrs), err)
+	return addrs, err
+}
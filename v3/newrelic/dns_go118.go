@@ -0,0 +1,20 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.18
+
+package newrelic
+
+import (
+	"context"
+	"net/netip"
+)
+
+// LookupNetIP looks up host for the given network.  It returns a slice of
+// that host's IP addresses of the type specified by network.
+func (r *Resolver) LookupNetIP(ctx context.Context, network, host string) ([]netip.Addr, error) {
+	s := startDNSSegment(ctx, "LookupNetIP", host)
+	addrs, err := r.resolver.LookupNetIP(ctx, network, host)
+	endDNSSegment(s, len(addrs), err)
+	return addrs, err
+}
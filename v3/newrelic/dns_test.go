@@ -0,0 +1,140 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"context"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestResolverLookupHost(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+
+	// IP addresses are returned without querying a DNS server.
+	addrs, err := NewResolver(nil).LookupHost(ctx, "127.0.0.1")
+	if nil != err || len(addrs) != 1 {
+		t.Fatal(addrs, err)
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/DNS/LookupHost", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/DNS/LookupHost", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "Custom/DNS/LookupHost",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{
+				DNSAttributeName:    "127.0.0.1",
+				DNSAttributeAnswers: 1,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
+
+func TestResolverLookupError(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+
+	// Reverse lookups of invalid addresses fail without querying a DNS
+	// server.
+	_, err := NewResolver(&net.Resolver{}).LookupAddr(ctx, "invalid")
+	if nil == err {
+		t.Fatal("expected an error")
+	}
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId": internal.MatchAnything,
+				"name":     "Custom/DNS/LookupAddr",
+				"category": "generic",
+			},
+			UserAttributes: map[string]interface{}{
+				DNSAttributeName:    "invalid",
+				DNSAttributeAnswers: 0,
+				DNSAttributeError:   err.Error(),
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+		},
+	})
+}
+
+func TestResolverWithoutTransaction(t *testing.T) {
+	addrs, err := NewResolver(nil).LookupIPAddr(context.Background(), "127.0.0.1")
+	if nil != err || len(addrs) != 1 {
+		t.Error(addrs, err)
+	}
+}
+
+func TestResolverWrapsEveryLookup(t *testing.T) {
+	// Lookup methods added to net.Resolver must be wrapped, since the
+	// Resolver no longer embeds it.
+	wrapped := reflect.TypeOf(&Resolver{})
+	original := reflect.TypeOf(&net.Resolver{})
+	for i := 0; i < original.NumMethod(); i++ {
+		name := original.Method(i).Name
+		if !strings.HasPrefix(name, "Lookup") {
+			continue
+		}
+		if _, ok := wrapped.MethodByName(name); !ok {
+			t.Error("lookup not wrapped", name)
+		}
+	}
+}
+
+func TestResolverLookupPort(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+	// Numeric ports are returned without any lookup.
+	if port, err := NewResolver(nil).LookupPort(ctx, "tcp", "8080"); nil != err || 8080 != port {
+		t.Error(port, err)
+	}
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/DNS/LookupPort", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
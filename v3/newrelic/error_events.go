@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"bytes"
+	"container/heap"
 	"time"
 )
 
@@ -26,11 +27,17 @@ func (e *errorEvent) WriteJSON(buf *bytes.Buffer) {
 	w.stringField("type", "TransactionError")
 	w.stringField("error.class", e.Klass)
 	w.stringField("error.message", e.Msg)
+	if e.Code != "" {
+		w.stringField("error.code", e.Code)
+	}
 	w.intField("timestamp", timeToIntMillis(e.When))
 	w.stringField("transactionName", e.FinalName)
 	if e.SpanID != "" {
 		w.stringField("spanId", e.SpanID)
 	}
+	if e.classSeen > 0 {
+		w.intField("error.classSeen", int64(e.classSeen))
+	}
 
 	sharedTransactionIntrinsics(&e.txnEvent, &w)
 	sharedBetterCATIntrinsics(&e.txnEvent, &w)
@@ -45,6 +52,12 @@ func (e *errorEvent) WriteJSON(buf *bytes.Buffer) {
 
 type errorEvents struct {
 	*analyticsEvents
+	// stratified controls whether the events kept when the reservoir is
+	// full are stratified by error class.  See
+	// Config.ErrorCollector.StratifyEvents.
+	stratified bool
+	// seenByClass counts the events seen of each error class.
+	seenByClass map[string]int
 }
 
 func newErrorEvents(max int) *errorEvents {
@@ -54,14 +67,98 @@ func newErrorEvents(max int) *errorEvents {
 }
 
 func (events *errorEvents) Add(e *errorEvent, p priority) {
-	events.addEvent(analyticsEvent{p, e})
+	events.countClass(e.Klass, 1)
+	events.keep(analyticsEvent{p, e})
+}
+
+func (events *errorEvents) countClass(class string, n int) {
+	if nil == events.seenByClass {
+		events.seenByClass = make(map[string]int)
+	}
+	events.seenByClass[class] += n
+}
+
+func errorEventClass(e analyticsEvent) string {
+	return e.jsonWriter.(*errorEvent).Klass
+}
+
+// keep adds the event to the reservoir.  When the reservoir is full and
+// stratified, an event of a class with fewer events kept than the most
+// common class replaces the lowest priority event of the most common class,
+// so that a flood of one class cannot crowd out the others.  Otherwise the
+// event only replaces a lower priority event of its own class.
+func (events *errorEvents) keep(e analyticsEvent) {
+	if !events.stratified || 0 == cap(events.events) || len(events.events) < cap(events.events) {
+		events.addEvent(e)
+		return
+	}
+	events.numSeen++
+
+	class := errorEventClass(e)
+	kept := make(map[string]int)
+	for _, ev := range events.events {
+		kept[errorEventClass(ev)]++
+	}
+	victimClass := class
+	for c, n := range kept {
+		if n > kept[victimClass] {
+			victimClass = c
+		}
+	}
+	victim := -1
+	for i, ev := range events.events {
+		if errorEventClass(ev) != victimClass {
+			continue
+		}
+		if victim < 0 || ev.priority.isLowerPriority(events.events[victim].priority) {
+			victim = i
+		}
+	}
+	if victim < 0 {
+		return
+	}
+	if victimClass == class && e.priority.isLowerPriority(events.events[victim].priority) {
+		return
+	}
+	events.events[victim] = e
+	heap.Fix(events.events, victim)
 }
 
 func (events *errorEvents) MergeIntoHarvest(h *harvest) {
-	h.ErrorEvents.mergeFailed(events.analyticsEvents)
+	fails := events.failedHarvests + 1
+	if fails >= failedEventsAttemptsLimit {
+		return
+	}
+	h.ErrorEvents.failedHarvests = fails
+
+	allSeen := h.ErrorEvents.numSeen + events.numSeen
+	for _, e := range events.events {
+		h.ErrorEvents.keep(e)
+	}
+	h.ErrorEvents.numSeen = allSeen
+	for class, n := range events.seenByClass {
+		h.ErrorEvents.countClass(class, n)
+	}
+}
+
+// recordClassSeen records the number of events seen of each class on the
+// kept events of the classes which had events dropped.
+func (events *errorEvents) recordClassSeen() {
+	kept := make(map[string]int)
+	for _, e := range events.events {
+		kept[errorEventClass(e)]++
+	}
+	for _, e := range events.events {
+		ev := e.jsonWriter.(*errorEvent)
+		ev.classSeen = 0
+		if seen := events.seenByClass[ev.Klass]; events.stratified && seen > kept[ev.Klass] {
+			ev.classSeen = seen
+		}
+	}
 }
 
 func (events *errorEvents) Data(agentRunID string, harvestStart time.Time) ([]byte, error) {
+	events.recordClassSeen()
 	return events.CollectorJSON(agentRunID)
 }
 
@@ -230,3 +230,122 @@ func TestErrorEventMarshalWithInboundCaller(t *testing.T) {
 		{}
 	]`)
 }
+
+func stratifiedErrorEvents(max int) *errorEvents {
+	events := newErrorEvents(max)
+	events.stratified = true
+	return events
+}
+
+func keptErrorClasses(events *errorEvents) map[string]int {
+	kept := make(map[string]int)
+	for _, e := range events.events {
+		kept[errorEventClass(e)]++
+	}
+	return kept
+}
+
+func TestErrorEventsStratified(t *testing.T) {
+	events := stratifiedErrorEvents(10)
+	for i := 0; i < 100; i++ {
+		events.Add(&errorEvent{errorData: errorData{Klass: "timeout"}}, priority(float32(i)/100.0+0.5))
+	}
+	for i := 0; i < 3; i++ {
+		events.Add(&errorEvent{errorData: errorData{Klass: "novel"}}, priority(0.1))
+	}
+	kept := keptErrorClasses(events)
+	if kept["timeout"] != 7 || kept["novel"] != 3 {
+		t.Error(kept)
+	}
+	// The timeouts with the highest priority are kept.
+	for _, e := range events.events {
+		if errorEventClass(e) == "timeout" && e.priority < 1.42 {
+			t.Error(e.priority)
+		}
+	}
+	if events.numSeen != 103 || events.seenByClass["timeout"] != 100 || events.seenByClass["novel"] != 3 {
+		t.Error(events.numSeen, events.seenByClass)
+	}
+
+	events.recordClassSeen()
+	for _, e := range events.events {
+		ev := e.jsonWriter.(*errorEvent)
+		if (ev.Klass == "timeout" && ev.classSeen != 100) || (ev.Klass == "novel" && ev.classSeen != 0) {
+			t.Error(ev.Klass, ev.classSeen)
+		}
+	}
+}
+
+func TestErrorEventsStratifiedBalanced(t *testing.T) {
+	// Once the classes are balanced, an event only replaces a lower
+	// priority event of its own class.
+	events := stratifiedErrorEvents(4)
+	for _, class := range []string{"a", "a", "b", "b"} {
+		events.Add(&errorEvent{errorData: errorData{Klass: class}}, 0.5)
+	}
+	events.Add(&errorEvent{errorData: errorData{Klass: "a"}}, 0.1)
+	events.Add(&errorEvent{errorData: errorData{Klass: "b"}}, 0.9)
+	if kept := keptErrorClasses(events); kept["a"] != 2 || kept["b"] != 2 {
+		t.Error(kept)
+	}
+	var total priority
+	for _, e := range events.events {
+		total += e.priority
+	}
+	if total != 2.4 {
+		t.Error(total)
+	}
+}
+
+func TestErrorEventsNotStratified(t *testing.T) {
+	events := newErrorEvents(10)
+	for i := 0; i < 100; i++ {
+		events.Add(&errorEvent{errorData: errorData{Klass: "timeout"}}, priority(float32(i)/100.0+0.5))
+	}
+	events.Add(&errorEvent{errorData: errorData{Klass: "novel"}}, priority(0.1))
+	if kept := keptErrorClasses(events); kept["timeout"] != 10 {
+		t.Error(kept)
+	}
+	events.recordClassSeen()
+	for _, e := range events.events {
+		if ev := e.jsonWriter.(*errorEvent); ev.classSeen != 0 {
+			t.Error(ev.classSeen)
+		}
+	}
+}
+
+func TestErrorEventsStratifiedMergeFailed(t *testing.T) {
+	h := newHarvest(time.Now(), harvestConfig{MaxErrorEvents: 4, StratifiedErrorEvents: true})
+	failed := stratifiedErrorEvents(4)
+	for i := 0; i < 10; i++ {
+		failed.Add(&errorEvent{errorData: errorData{Klass: "timeout"}}, 0.5)
+	}
+	h.ErrorEvents.Add(&errorEvent{errorData: errorData{Klass: "novel"}}, 0.1)
+	failed.MergeIntoHarvest(h)
+	if kept := keptErrorClasses(h.ErrorEvents); kept["timeout"] != 3 || kept["novel"] != 1 {
+		t.Error(kept)
+	}
+	if h.ErrorEvents.numSeen != 11 || h.ErrorEvents.seenByClass["timeout"] != 10 || h.ErrorEvents.failedHarvests != 1 {
+		t.Error(h.ErrorEvents.numSeen, h.ErrorEvents.seenByClass, h.ErrorEvents.failedHarvests)
+	}
+}
+
+func TestErrorEventMarshalClassSeen(t *testing.T) {
+	testErrorEventJSON(t, &errorEvent{
+		errorData: sampleErrorData,
+		txnEvent:  txnEvent{FinalName: "myName", Duration: 3 * time.Second},
+		classSeen: 42,
+	}, `[
+		{
+			"type":"TransactionError",
+			"error.class":"*errors.errorString",
+			"error.message":"hello",
+			"timestamp":1417136460000,
+			"transactionName":"myName",
+			"error.classSeen":42,
+			"duration":3
+		},
+		{},
+		{}
+	]`)
+}
@@ -15,6 +15,12 @@ type errorClasser interface {
 	ErrorClass() string
 }
 
+// errorCoder can be implemented by errors to provide a stable,
+// machine-readable code when using Transaction.NoticeError.
+type errorCoder interface {
+	ErrorCode() string
+}
+
 // errorAttributer can be implemented by errors to provide extra context when
 // using Transaction.NoticeError.
 type errorAttributer interface {
@@ -30,9 +36,17 @@ type Error struct {
 	Message string
 	// Class indicates how the error may be aggregated.
 	Class string
+	// Code is a stable, machine-readable code for the error, such as
+	// "PAYMENT_DECLINED", recorded as the "error.code" attribute.  Unlike
+	// the message, it does not change as the error's wording changes, so
+	// it is suitable for alert conditions and faceting.
+	Code string
 	// Attributes are attached to traced errors and error events for
 	// additional context.  These attributes are validated just like those
-	// added to Transaction.AddAttribute.
+	// added to Transaction.AddAttribute, except that values which are maps
+	// with string keys are flattened into one attribute per entry, eg.
+	// {"details": {"db": {"host": "x"}}} is recorded as "details.db.host".
+	// See Config.ErrorCollector.MaxAttributeDepth.
 	Attributes map[string]interface{}
 	// Stack is the stack trace.  Assign this field using NewStackTrace,
 	// or leave it nil to indicate that Transaction.NoticeError should
@@ -52,6 +66,9 @@ func (e Error) Error() string { return e.Message }
 // ErrorClass returns the error's class.
 func (e Error) ErrorClass() string { return e.Class }
 
+// ErrorCode returns the error's code.
+func (e Error) ErrorCode() string { return e.Code }
+
 // ErrorAttributes returns the error's extra attributes.
 func (e Error) ErrorAttributes() map[string]interface{} { return e.Attributes }
 
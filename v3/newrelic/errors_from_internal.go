@@ -60,7 +60,9 @@ type errorData struct {
 	ExtraAttributes map[string]interface{}
 	Msg             string
 	Klass           string
-	SpanID          string
+	// Code is the machine-readable code of the error, if any.
+	Code   string
+	SpanID string
 }
 
 // txnError combines error data with information about a transaction.  txnError is used for
@@ -72,7 +74,13 @@ type txnError struct {
 
 // errorEvent and tracedError are separate types so that error events and traced errors can have
 // different WriteJSON methods.
-type errorEvent txnError
+type errorEvent struct {
+	errorData
+	txnEvent
+	// classSeen is the number of error events of the same class seen,
+	// set when some of them were not kept.
+	classSeen int
+}
 
 type tracedError txnError
 
@@ -113,7 +121,7 @@ func (h *tracedError) WriteJSON(buf *bytes.Buffer) {
 	buf.WriteByte(',')
 	buf.WriteString(`"intrinsics"`)
 	buf.WriteByte(':')
-	intrinsicsJSON(&h.txnEvent, buf)
+	intrinsicsJSON(&h.txnEvent, buf, h.Code)
 	if nil != h.Stack {
 		buf.WriteByte(',')
 		buf.WriteString(`"stack_trace"`)
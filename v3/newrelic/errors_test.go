@@ -74,6 +74,45 @@ func TestErrorTraceMarshal(t *testing.T) {
 	testExpectedJSON(t, expect, string(js))
 }
 
+func TestErrorTraceMarshalCode(t *testing.T) {
+	he := &tracedError{
+		errorData: errorData{
+			When:  time.Date(2014, time.November, 28, 1, 1, 0, 0, time.UTC),
+			Stack: emptyStackTrace,
+			Msg:   "my_msg",
+			Klass: "my_class",
+			Code:  "my_code",
+		},
+		txnEvent: txnEvent{
+			FinalName: "my_txn_name",
+			Attrs:     nil,
+			TotalTime: 2 * time.Second,
+		},
+	}
+	js, err := json.Marshal(he)
+	if nil != err {
+		t.Error(err)
+	}
+
+	expect := `
+	[
+		1.41713646e+12,
+		"my_txn_name",
+		"my_msg",
+		"my_class",
+		{
+			"agentAttributes":{},
+			"userAttributes":{},
+			"intrinsics":{
+				"totalTime":2,
+				"error.code":"my_code"
+			},
+			"stack_trace":[]
+		}
+	]`
+	testExpectedJSON(t, expect, string(js))
+}
+
 func TestErrorTraceMarshalOldCAT(t *testing.T) {
 	he := &tracedError{
 		errorData: errorData{
@@ -0,0 +1,80 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"net"
+	"path"
+	"strings"
+)
+
+// ExternalEntityRule attributes the external segments to hosts matching a
+// pattern to a named entity, such as an internal service behind a shared
+// gateway or load balancer.  See Config.ExternalEntities.
+type ExternalEntityRule struct {
+	// HostPattern is matched against the host of each external segment,
+	// both with and without its port, using the syntax of path.Match.
+	// Matching is case insensitive.  For example,
+	// "internal-*.elb.amazonaws.com" matches every internal AWS load
+	// balancer.
+	HostPattern string
+	// EntityName is used in place of the host in the metrics and span
+	// names of matching external segments, eg. "External/{EntityName}/all".
+	EntityName string
+}
+
+func (r ExternalEntityRule) matches(host string) bool {
+	return hostMatches(r.HostPattern, host)
+}
+
+// hostMatches returns true if the host, with or without its port, matches
+// the path.Match pattern.  Matching is case insensitive.
+func hostMatches(pattern, host string) bool {
+	pattern = strings.ToLower(pattern)
+	host = strings.ToLower(host)
+	if ok, _ := path.Match(pattern, host); ok {
+		return true
+	}
+	if hostname, _, err := net.SplitHostPort(host); nil == err {
+		ok, _ := path.Match(pattern, hostname)
+		return ok
+	}
+	return false
+}
+
+func (r ExternalEntityRule) validate() error {
+	if "" == r.HostPattern {
+		return fmt.Errorf("external entity rule for %q has an empty HostPattern", r.EntityName)
+	}
+	if _, err := path.Match(r.HostPattern, ""); nil != err {
+		return fmt.Errorf("invalid external entity HostPattern %q: %v", r.HostPattern, err)
+	}
+	if "" == strings.TrimSpace(r.EntityName) {
+		return fmt.Errorf("external entity rule for %q has an empty EntityName", r.HostPattern)
+	}
+	return nil
+}
+
+// validateExternalEntities checks that each of the ExternalEntities rules has
+// a valid pattern and a name.
+func (c Config) validateExternalEntities() error {
+	for _, r := range c.ExternalEntities {
+		if err := r.validate(); nil != err {
+			return err
+		}
+	}
+	return nil
+}
+
+// externalEntityName returns the EntityName of the first of the
+// ExternalEntities rules which matches the host, or the host if none match.
+func (c Config) externalEntityName(host string) string {
+	for _, r := range c.ExternalEntities {
+		if r.matches(host) {
+			return r.EntityName
+		}
+	}
+	return host
+}
@@ -0,0 +1,75 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestExternalEntityName(t *testing.T) {
+	cfg := Config{ExternalEntities: []ExternalEntityRule{
+		{HostPattern: "billing.alb.internal", EntityName: "billing"},
+		{HostPattern: "*.alb.internal", EntityName: "gateway"},
+		{HostPattern: "search:9200", EntityName: "search"},
+	}}
+	testcases := []struct {
+		host   string
+		expect string
+	}{
+		{host: "billing.alb.internal", expect: "billing"},
+		{host: "Billing.ALB.internal:8080", expect: "billing"},
+		{host: "users.alb.internal", expect: "gateway"},
+		{host: "search:9200", expect: "search"},
+		{host: "search:9300", expect: "search:9300"},
+		{host: "example.com", expect: "example.com"},
+	}
+	for _, tc := range testcases {
+		if name := cfg.externalEntityName(tc.host); name != tc.expect {
+			t.Error(tc.host, name, tc.expect)
+		}
+	}
+}
+
+func TestValidateExternalEntities(t *testing.T) {
+	testcases := []struct {
+		rule  ExternalEntityRule
+		valid bool
+	}{
+		{rule: ExternalEntityRule{HostPattern: "*.internal", EntityName: "internal"}, valid: true},
+		{rule: ExternalEntityRule{HostPattern: "", EntityName: "internal"}, valid: false},
+		{rule: ExternalEntityRule{HostPattern: "[.internal", EntityName: "internal"}, valid: false},
+		{rule: ExternalEntityRule{HostPattern: "*.internal", EntityName: " "}, valid: false},
+	}
+	for _, tc := range testcases {
+		cfg := Config{ExternalEntities: []ExternalEntityRule{tc.rule}}
+		if err := cfg.validateExternalEntities(); (nil == err) != tc.valid {
+			t.Error(tc.rule, err)
+		}
+	}
+}
+
+func TestExternalEntitiesConfig(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ExternalEntities = []ExternalEntityRule{
+			{HostPattern: "example.com", EntityName: "example-service"},
+		}
+	}, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/users", nil)
+	s := StartExternalSegment(txn, req)
+	s.End()
+	s = StartExternalSegment(txn, nil)
+	s.Host = "other.com"
+	s.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/example-service/all", Scope: "", Forced: false, Data: nil},
+		{Name: "External/example-service/http/GET", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+		{Name: "External/other.com/all", Scope: "", Forced: false, Data: nil},
+	})
+}
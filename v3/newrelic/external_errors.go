@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+)
+
+// These attributes are added to the errors noticed for external segments
+// because of Config.ExternalErrors.
+const (
+	// ExternalErrorAttributeURL is the URL of the request, without its
+	// query string.
+	ExternalErrorAttributeURL = "external.url"
+	// ExternalErrorAttributeHost is the host of the request.
+	ExternalErrorAttributeHost = "external.host"
+	// ExternalErrorAttributeStatusCode is the status code of the response.
+	ExternalErrorAttributeStatusCode = "external.statusCode"
+)
+
+// ExternalErrorRule controls whether errors are noticed for the external
+// segments to hosts matching a pattern.  See Config.ExternalErrors.
+type ExternalErrorRule struct {
+	// HostPattern is matched against the host of each external segment,
+	// both with and without its port, using the syntax of path.Match.
+	// Matching is case insensitive.
+	HostPattern string
+	// ServerErrors controls whether errors are noticed for responses with
+	// 5xx status codes.
+	ServerErrors bool
+	// ClientErrors controls whether errors are noticed for responses with
+	// 4xx status codes.
+	ClientErrors bool
+}
+
+// validateExternalErrors checks that each of the ExternalErrors rules has a
+// valid pattern.
+func (c Config) validateExternalErrors() error {
+	for _, r := range c.ExternalErrors.Rules {
+		if "" == r.HostPattern {
+			return fmt.Errorf("external error rule has an empty HostPattern")
+		}
+		if _, err := path.Match(r.HostPattern, ""); nil != err {
+			return fmt.Errorf("invalid external error HostPattern %q: %v", r.HostPattern, err)
+		}
+	}
+	return nil
+}
+
+// isExternalError returns true if an error should be noticed for an external
+// segment to the host whose response has the status code.
+func (c Config) isExternalError(host string, code int) bool {
+	server := c.ExternalErrors.ServerErrors
+	client := c.ExternalErrors.ClientErrors
+	for _, r := range c.ExternalErrors.Rules {
+		if hostMatches(r.HostPattern, host) {
+			server = r.ServerErrors
+			client = r.ClientErrors
+			break
+		}
+	}
+	switch {
+	case code >= 500 && code < 600:
+		return server
+	case code >= 400 && code < 500:
+		return client
+	}
+	return false
+}
+
+// externalErrorStatusCode returns the status code of the response of the
+// external segment, or 0 if there is no response.
+func externalErrorStatusCode(s *ExternalSegment) int {
+	if nil != s.statusCode {
+		return *s.statusCode
+	}
+	if nil != s.Response {
+		return s.Response.StatusCode
+	}
+	return 0
+}
+
+// txnErrorFromExternal creates the error noticed for an external segment
+// whose response has an error status code.  The class is the status code, as
+// with the errors noticed for the transaction's own response codes.
+func txnErrorFromExternal(now time.Time, method, rawURL, host string, code int) errorData {
+	codeStr := strconv.Itoa(code)
+	msg := host + " responded with " + codeStr
+	if "" != method {
+		msg = method + " " + msg
+	}
+	if text := http.StatusText(code); "" != text {
+		msg += " " + text
+	}
+	attrs := map[string]interface{}{
+		ExternalErrorAttributeHost:       host,
+		ExternalErrorAttributeStatusCode: code,
+	}
+	if "" != rawURL {
+		attrs[ExternalErrorAttributeURL] = rawURL
+	}
+	return errorData{
+		When:            now,
+		Msg:             msg,
+		Klass:           codeStr,
+		ExtraAttributes: attrs,
+	}
+}
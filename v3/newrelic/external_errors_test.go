@@ -0,0 +1,167 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestIsExternalError(t *testing.T) {
+	cfg := Config{}
+	cfg.ExternalErrors.ServerErrors = true
+	cfg.ExternalErrors.Rules = []ExternalErrorRule{
+		{HostPattern: "users.example.com", ServerErrors: true, ClientErrors: true},
+		{HostPattern: "*.search.example.com"},
+	}
+	testcases := []struct {
+		host   string
+		code   int
+		expect bool
+	}{
+		{host: "example.com", code: 0, expect: false},
+		{host: "example.com", code: 200, expect: false},
+		{host: "example.com", code: 404, expect: false},
+		{host: "example.com", code: 503, expect: true},
+		{host: "users.example.com", code: 404, expect: true},
+		{host: "Users.Example.com:8080", code: 500, expect: true},
+		{host: "eu.search.example.com", code: 503, expect: false},
+		{host: "eu.search.example.com", code: 400, expect: false},
+	}
+	for _, tc := range testcases {
+		if out := cfg.isExternalError(tc.host, tc.code); out != tc.expect {
+			t.Error(tc.host, tc.code, out)
+		}
+	}
+}
+
+func TestValidateExternalErrors(t *testing.T) {
+	testcases := []struct {
+		rule  ExternalErrorRule
+		valid bool
+	}{
+		{rule: ExternalErrorRule{HostPattern: "*.internal"}, valid: true},
+		{rule: ExternalErrorRule{HostPattern: ""}, valid: false},
+		{rule: ExternalErrorRule{HostPattern: "[.internal"}, valid: false},
+	}
+	for _, tc := range testcases {
+		cfg := Config{}
+		cfg.ExternalErrors.Rules = []ExternalErrorRule{tc.rule}
+		if err := cfg.validateExternalErrors(); (nil == err) != tc.valid {
+			t.Error(tc.rule, err)
+		}
+	}
+}
+
+func TestExternalErrorNoticed(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.ExternalErrors.ServerErrors = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/users?secret=1", nil)
+	s := StartExternalSegment(txn, req)
+	s.Response = &http.Response{StatusCode: 503}
+	s.End()
+	// Client errors are not noticed by default.
+	s = StartExternalSegment(txn, req)
+	s.SetStatusCode(404)
+	s.End()
+	txn.End()
+
+	app.expectNoLoggedErrors(t)
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "GET example.com responded with 503 Service Unavailable",
+		Klass:   "503",
+		UserAttributes: map[string]interface{}{
+			ExternalErrorAttributeURL:        "http://example.com/users",
+			ExternalErrorAttributeHost:       "example.com",
+			ExternalErrorAttributeStatusCode: 503,
+		},
+	}})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "External/example.com/http/GET",
+				"category":      "http",
+				"component":     "http",
+				"span.kind":     "client",
+				"parentId":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"priority":      internal.MatchAnything,
+				"sampled":       true,
+				"timestamp":     internal.MatchAnything,
+				"duration":      internal.MatchAnything,
+			},
+			AgentAttributes: map[string]interface{}{
+				"http.url":        "http://example.com/users",
+				"http.method":     "GET",
+				"http.statusCode": 503,
+				"error.class":     "503",
+				"error.message":   "GET example.com responded with 503 Service Unavailable",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "External/example.com/http/GET",
+				"category":      "http",
+				"component":     "http",
+				"span.kind":     "client",
+				"parentId":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"priority":      internal.MatchAnything,
+				"sampled":       true,
+				"timestamp":     internal.MatchAnything,
+				"duration":      internal.MatchAnything,
+			},
+			AgentAttributes: map[string]interface{}{
+				"http.url":        "http://example.com/users",
+				"http.method":     "GET",
+				"http.statusCode": 404,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"category":         "generic",
+				"nr.entryPoint":    true,
+				"guid":             internal.MatchAnything,
+				"transactionId":    internal.MatchAnything,
+				"traceId":          internal.MatchAnything,
+				"priority":         internal.MatchAnything,
+				"sampled":          true,
+				"timestamp":        internal.MatchAnything,
+				"duration":         internal.MatchAnything,
+			},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestExternalErrorHighSecurity(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.HighSecurity = true
+		cfg.ExternalErrors.ClientErrors = true
+	}, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com", nil)
+	s := StartExternalSegment(txn, req)
+	s.SetStatusCode(404)
+	s.End()
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName:        "OtherTransaction/Go/hello",
+		Msg:            highSecurityErrorMsg,
+		Klass:          "404",
+		UserAttributes: map[string]interface{}{},
+	}})
+}
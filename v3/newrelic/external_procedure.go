@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/xml"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// procedureBodyLimit is the number of bytes of the request body read by
+// SOAPProcedure and XMLRPCProcedure.
+const procedureBodyLimit = 64 * 1024
+
+// externalProcedure returns the procedure of the request given by
+// Config.ExternalProcedure, or an empty string if it is not configured.
+func (txn *Transaction) externalProcedure(r *http.Request) string {
+	if nil == txn || nil == txn.thread || nil == r {
+		return ""
+	}
+	fn := txn.thread.Config.ExternalProcedure
+	if nil == fn {
+		return ""
+	}
+	return fn(r)
+}
+
+// SOAPProcedure returns the SOAP operation called by the request, for use
+// with Config.ExternalProcedure.  The operation is taken from the SOAPAction
+// header (SOAP 1.1), the action parameter of the Content-Type header (SOAP
+// 1.2), or the first element of the envelope's Body, whichever is found
+// first.  Actions which are URIs are shortened to their final path segment
+// or fragment, eg. "GetUser" for "http://example.com/UserService/GetUser".
+// Up to 64KiB of the body is read from a copy obtained using the request's
+// GetBody function, which http.NewRequest sets for bodies held in memory;
+// the body itself is left untouched, and the bodies of requests without
+// GetBody are not read.  An empty string is returned if no operation is
+// found.
+func SOAPProcedure(r *http.Request) string {
+	if nil == r {
+		return ""
+	}
+	if action := soapActionName(r.Header.Get("SOAPAction")); "" != action {
+		return action
+	}
+	if _, params, err := mime.ParseMediaType(r.Header.Get("Content-Type")); nil == err {
+		if action := soapActionName(params["action"]); "" != action {
+			return action
+		}
+	}
+	d := xml.NewDecoder(bytes.NewReader(peekRequestBody(r, procedureBodyLimit)))
+	inBody := false
+	for {
+		tok, err := d.Token()
+		if nil != err {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok {
+			if inBody {
+				return start.Name.Local
+			}
+			inBody = "Body" == start.Name.Local
+		}
+	}
+}
+
+// XMLRPCProcedure returns the method called by an XML-RPC request, for use
+// with Config.ExternalProcedure.  As with SOAPProcedure, up to 64KiB of a
+// copy of the body is read when the request's GetBody function is set.  An
+// empty string is returned if no methodName element is found.
+func XMLRPCProcedure(r *http.Request) string {
+	if nil == r {
+		return ""
+	}
+	d := xml.NewDecoder(bytes.NewReader(peekRequestBody(r, procedureBodyLimit)))
+	for {
+		tok, err := d.Token()
+		if nil != err {
+			return ""
+		}
+		if start, ok := tok.(xml.StartElement); ok && "methodName" == start.Name.Local {
+			var name string
+			if err := d.DecodeElement(&name, &start); nil != err {
+				return ""
+			}
+			return strings.TrimSpace(name)
+		}
+	}
+}
+
+// soapActionName returns the operation name from a SOAP action.
+func soapActionName(action string) string {
+	action = strings.Trim(strings.TrimSpace(action), `"`)
+	if idx := strings.LastIndexAny(action, "/#"); idx >= 0 {
+		action = action[idx+1:]
+	}
+	return action
+}
+
+// peekRequestBody returns up to n bytes from the start of a copy of the
+// request body.  Requests whose body cannot be copied, such as those with
+// streaming bodies, are not read and nil is returned.
+func peekRequestBody(r *http.Request, n int) []byte {
+	body := requestBodyCopy(r)
+	if nil == body {
+		return nil
+	}
+	defer body.Close()
+
+	buf := make([]byte, n)
+	read, _ := io.ReadFull(body, buf)
+	return buf[:read]
+}
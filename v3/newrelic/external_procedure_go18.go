@@ -0,0 +1,26 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"io"
+	"net/http"
+)
+
+// requestBodyCopy returns a new reader of the request body obtained using
+// the request's GetBody function, or nil if the request has no body or its
+// body cannot be copied.  Reading the copy does not consume the body which
+// is sent.
+func requestBodyCopy(r *http.Request) io.ReadCloser {
+	if nil == r.GetBody || nil == r.Body || http.NoBody == r.Body {
+		return nil
+	}
+	body, err := r.GetBody()
+	if nil != err {
+		return nil
+	}
+	return body
+}
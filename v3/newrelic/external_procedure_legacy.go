@@ -0,0 +1,17 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build !go1.8
+
+package newrelic
+
+import (
+	"io"
+	"net/http"
+)
+
+// requestBodyCopy returns nil since requests cannot be copied before Go 1.8,
+// which added Request.GetBody.
+func requestBodyCopy(r *http.Request) io.ReadCloser {
+	return nil
+}
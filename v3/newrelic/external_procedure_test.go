@@ -0,0 +1,164 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+const (
+	soapEnvelope = `<?xml version="1.0"?>
+<soap:Envelope xmlns:soap="http://www.w3.org/2003/05/soap-envelope">
+	<soap:Header><auth>token</auth></soap:Header>
+	<soap:Body><m:GetUser xmlns:m="http://example.com/users"><m:ID>1</m:ID></m:GetUser></soap:Body>
+</soap:Envelope>`
+	xmlrpcCall = `<?xml version="1.0"?>
+<methodCall>
+	<methodName> examples.getStateName </methodName>
+	<params><param><value><i4>41</i4></value></param></params>
+</methodCall>`
+)
+
+func newProcedureRequest(t *testing.T, body string) *http.Request {
+	req, err := http.NewRequest("POST", "http://example.com/service", strings.NewReader(body))
+	if nil != err {
+		t.Fatal(err)
+	}
+	return req
+}
+
+func expectBody(t *testing.T, req *http.Request, expect string) {
+	body, err := ioutil.ReadAll(req.Body)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if string(body) != expect {
+		t.Error("body not restored", string(body))
+	}
+}
+
+func TestSOAPProcedure(t *testing.T) {
+	req := newProcedureRequest(t, soapEnvelope)
+	req.Header.Set("SOAPAction", `"http://example.com/users/DeleteUser"`)
+	if p := SOAPProcedure(req); "DeleteUser" != p {
+		t.Error(p)
+	}
+
+	req = newProcedureRequest(t, soapEnvelope)
+	req.Header.Set("Content-Type", `application/soap+xml; charset=utf-8; action="urn:users#UpdateUser"`)
+	if p := SOAPProcedure(req); "UpdateUser" != p {
+		t.Error(p)
+	}
+
+	req = newProcedureRequest(t, soapEnvelope)
+	req.Header.Set("SOAPAction", `""`)
+	if p := SOAPProcedure(req); "GetUser" != p {
+		t.Error(p)
+	}
+	expectBody(t, req, soapEnvelope)
+
+	req = newProcedureRequest(t, "not xml")
+	if p := SOAPProcedure(req); "" != p {
+		t.Error(p)
+	}
+	expectBody(t, req, "not xml")
+
+	if p := SOAPProcedure(nil); "" != p {
+		t.Error(p)
+	}
+}
+
+func TestXMLRPCProcedure(t *testing.T) {
+	req := newProcedureRequest(t, xmlrpcCall)
+	if p := XMLRPCProcedure(req); "examples.getStateName" != p {
+		t.Error(p)
+	}
+	expectBody(t, req, xmlrpcCall)
+
+	req = newProcedureRequest(t, soapEnvelope)
+	if p := XMLRPCProcedure(req); "" != p {
+		t.Error(p)
+	}
+
+	req, _ = http.NewRequest("GET", "http://example.com", nil)
+	if p := XMLRPCProcedure(req); "" != p {
+		t.Error(p)
+	}
+}
+
+func TestPeekRequestBodyLimit(t *testing.T) {
+	req := newProcedureRequest(t, "hello world")
+	if peeked := peekRequestBody(req, 5); "hello" != string(peeked) {
+		t.Error(string(peeked))
+	}
+	expectBody(t, req, "hello world")
+}
+
+func TestPeekRequestBodyNotCopyable(t *testing.T) {
+	// Streaming bodies are not read.
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	req, _ := http.NewRequest("POST", "http://example.com/service", pr)
+	if peeked := peekRequestBody(req, 5); nil != peeked {
+		t.Error(string(peeked))
+	}
+	if req.Body != pr {
+		t.Error("body replaced", req.Body)
+	}
+
+	req, _ = http.NewRequest("POST", "http://example.com/service", nil)
+	if peeked := peekRequestBody(req, 5); nil != peeked {
+		t.Error(string(peeked))
+	}
+	if nil != req.Body {
+		t.Error("body added", req.Body)
+	}
+}
+
+func TestExternalProcedureConfig(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.ExternalProcedure = SOAPProcedure
+	}, t)
+	txn := app.StartTransaction("hello")
+	req := newProcedureRequest(t, soapEnvelope)
+	req = RequestWithTransactionContext(req, txn)
+	client := &http.Client{Transport: NewRoundTripper(roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+		expectBody(t, r, soapEnvelope)
+		return &http.Response{StatusCode: 200, Request: r}, nil
+	}))}
+	if _, err := client.Do(req); nil != err {
+		t.Fatal(err)
+	}
+
+	// Procedure set explicitly takes precedence.
+	s := StartExternalSegment(txn, newProcedureRequest(t, soapEnvelope))
+	s.Procedure = "ListUsers"
+	s.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/example.com/http/GetUser", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+		{Name: "External/example.com/http/ListUsers", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
+
+func TestExternalProcedureNotConfigured(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	s := StartExternalSegment(txn, newProcedureRequest(t, soapEnvelope))
+	if "" != s.Procedure {
+		t.Error(s.Procedure)
+	}
+	s.End()
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "External/example.com/http/POST", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
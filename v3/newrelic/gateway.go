@@ -0,0 +1,82 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	// gatewayUnixPrefix is the prefix of a Gateway.Address which is the
+	// path of a Unix domain socket.
+	gatewayUnixPrefix = "unix:"
+	// gatewayTokenHeader carries Gateway.Token in each request sent to the
+	// gateway.
+	gatewayTokenHeader = "X-NewRelic-Gateway-Token"
+)
+
+// validateGateway checks that Gateway.Address is either a Unix domain socket
+// path or a loopback host and port.  Requests, including the license key,
+// are sent to the gateway using plain HTTP, so remote gateways are not
+// allowed.
+func (c Config) validateGateway() error {
+	address := c.Gateway.Address
+	if "" == address {
+		return nil
+	}
+	if strings.HasPrefix(address, gatewayUnixPrefix) {
+		if "" == strings.TrimPrefix(address, gatewayUnixPrefix) {
+			return errGatewayAddress
+		}
+		return nil
+	}
+	host, _, err := net.SplitHostPort(address)
+	if nil != err || !isLoopbackHost(host) {
+		return errGatewayAddress
+	}
+	return nil
+}
+
+// isLoopbackHost returns true if the host is "localhost" or a loopback IP
+// address.  Other host names are not resolved.
+func isLoopbackHost(host string) bool {
+	if "localhost" == strings.ToLower(host) {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return nil != ip && ip.IsLoopback()
+}
+
+// gatewayNetwork returns the network and address to dial to reach the
+// gateway.
+func gatewayNetwork(address string) (string, string) {
+	if strings.HasPrefix(address, gatewayUnixPrefix) {
+		return "unix", strings.TrimPrefix(address, gatewayUnixPrefix)
+	}
+	return "tcp", address
+}
+
+// newGatewayTransport creates the http.Transport used when Gateway.Address is
+// set.  Every request is sent to the gateway, whatever the host of its URL,
+// so that the gateway may use the Host header to forward it.  Proxy
+// environment variables are not used.
+func newGatewayTransport(address string) *http.Transport {
+	network, addr := gatewayNetwork(address)
+	dialer := &net.Dialer{
+		Timeout:   30 * time.Second,
+		KeepAlive: 30 * time.Second,
+	}
+	return &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return dialer.DialContext(ctx, network, addr)
+		},
+		MaxIdleConns:        100,
+		MaxIdleConnsPerHost: 100,
+		IdleConnTimeout:     90 * time.Second,
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/logger"
+)
+
+func TestValidateGateway(t *testing.T) {
+	testcases := []struct {
+		address string
+		expect  error
+	}{
+		{address: "", expect: nil},
+		{address: "unix:/var/run/nr-gateway.sock", expect: nil},
+		{address: "localhost:8080", expect: nil},
+		{address: "127.0.0.1:8080", expect: nil},
+		{address: "[::1]:8080", expect: nil},
+		{address: "LOCALHOST:8080", expect: nil},
+		{address: "10.0.0.5:8080", expect: errGatewayAddress},
+		{address: "gateway.example.com:8080", expect: errGatewayAddress},
+		{address: "unix:", expect: errGatewayAddress},
+		{address: "localhost", expect: errGatewayAddress},
+		{address: "http://localhost:8080", expect: errGatewayAddress},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.Gateway.Address = tc.address
+		if err := cfg.validateGateway(); err != tc.expect {
+			t.Error(tc.address, err)
+		}
+	}
+}
+
+func TestGatewayLicenseOptional(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.AppName = "my app"
+	cfg.Gateway.Address = "localhost:8080"
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+	cfg.License = "invalid"
+	if err := cfg.validate(); errLicenseLen != err {
+		t.Error(err)
+	}
+}
+
+func TestGatewayTokenNotInSettings(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.Gateway.Address = "localhost:8080"
+	cfg.Gateway.Token = "my gateway token"
+	js, err := json.Marshal(settings(cfg))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"Gateway":{"Address":"localhost:8080"}`) || strings.Contains(string(js), "my gateway token") {
+		t.Error(string(js))
+	}
+}
+
+func TestCollectorRequestGateway(t *testing.T) {
+	cmd := rpmCmd{
+		Name:           "cmd_name",
+		Collector:      "collector.com",
+		RunID:          "run_id",
+		MaxPayloadSize: internal.MaxPayloadSizeInBytes,
+	}
+	cs := rpmControls{
+		Client: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				if u := r.URL.String(); u != "http://collector.com/agent_listener/invoke_raw_method?marshal_format=json&method=cmd_name&protocol_version=17&run_id=run_id" {
+					t.Error(u)
+				}
+				if token := r.Header.Get(gatewayTokenHeader); token != "my gateway token" {
+					t.Error(token)
+				}
+				return &http.Response{
+					StatusCode: 200,
+					Body:       ioutil.NopCloser(strings.NewReader("body")),
+				}, nil
+			}),
+		},
+		Logger:       logger.ShimLogger{},
+		Gateway:      true,
+		GatewayToken: "my gateway token",
+	}
+	if resp := collectorRequest(cmd, cs); nil != resp.Err {
+		t.Error(resp.Err)
+	}
+}
+
+func TestGatewayTransportUnixSocket(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gateway")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "gateway.sock")
+	ln, err := net.Listen("unix", path)
+	if nil != err {
+		t.Skip("unix domain sockets unavailable:", err)
+	}
+	defer ln.Close()
+	go http.Serve(ln, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Host))
+	}))
+
+	client := &http.Client{Transport: newGatewayTransport(gatewayUnixPrefix + path)}
+	resp, err := client.Get("http://collector.newrelic.com/agent_listener/invoke_raw_method")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	body, _ := ioutil.ReadAll(resp.Body)
+	if string(body) != "collector.newrelic.com" {
+		t.Error(string(body))
+	}
+}
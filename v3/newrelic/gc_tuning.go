@@ -0,0 +1,85 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "math"
+
+const (
+	// gcTuningEventType is the type of the custom event recorded when the
+	// garbage collector settings are first observed and when they change.
+	gcTuningEventType = "GoGCTuning"
+
+	gcTuningReasonStartup = "startup"
+	gcTuningReasonChange  = "change"
+
+	// gcOff is the GOGC value reported when the garbage collector is
+	// disabled.
+	gcOff = -1
+	// noMemoryLimit is the memory limit reported when no limit is set.
+	noMemoryLimit = math.MaxInt64
+)
+
+// gcTuning is a snapshot of the settings which pace the garbage collector.
+type gcTuning struct {
+	// gcPercent is the GOGC value, or gcOff.
+	gcPercent int64
+	// memoryLimit is the GOMEMLIMIT value in bytes, or noMemoryLimit.
+	memoryLimit int64
+	// heapGoal is the heap size in bytes at which the next collection
+	// will start.  It changes with every collection and is therefore not
+	// used to detect changes.
+	heapGoal uint64
+}
+
+func (t gcTuning) sameSettings(other gcTuning) bool {
+	return t.gcPercent == other.gcPercent && t.memoryLimit == other.memoryLimit
+}
+
+// gcTuningReporter detects changes to the garbage collector settings between
+// samples taken by the runtime sampler.
+type gcTuningReporter struct {
+	reported bool
+	previous gcTuning
+}
+
+// observe returns the parameters of the event to record for the current
+// settings, or nil if they have already been reported.
+func (r *gcTuningReporter) observe(current gcTuning) map[string]interface{} {
+	if r.reported && r.previous.sameSettings(current) {
+		r.previous = current
+		return nil
+	}
+	params := map[string]interface{}{
+		"gogc":     current.gcPercent,
+		"heapGoal": current.heapGoal,
+		"reason":   gcTuningReasonStartup,
+	}
+	if noMemoryLimit != current.memoryLimit {
+		params["gomemlimit"] = current.memoryLimit
+	}
+	if r.reported {
+		params["reason"] = gcTuningReasonChange
+		params["previousGogc"] = r.previous.gcPercent
+		if noMemoryLimit != r.previous.memoryLimit {
+			params["previousGomemlimit"] = r.previous.memoryLimit
+		}
+	}
+	r.reported = true
+	r.previous = current
+	return params
+}
+
+// recordGCTuning records a GoGCTuning event if the garbage collector
+// settings have not yet been reported or have changed since the last sample.
+func recordGCTuning(app *app, r *gcTuningReporter, current gcTuning) {
+	params := r.observe(current)
+	if nil == params {
+		return
+	}
+	if err := app.RecordCustomEvent(gcTuningEventType, params); nil != err {
+		app.Debug("unable to record gc tuning event", map[string]interface{}{
+			"reason": err.Error(),
+		})
+	}
+}
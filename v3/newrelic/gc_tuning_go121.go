@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.21
+
+package newrelic
+
+import "runtime/metrics"
+
+// readGCTuning reads the current garbage collector settings from the
+// runtime, which reflects changes made using debug.SetGCPercent and
+// debug.SetMemoryLimit as well as the GOGC and GOMEMLIMIT environment
+// variables.
+func readGCTuning() gcTuning {
+	samples := []metrics.Sample{
+		{Name: "/gc/gogc:percent"},
+		{Name: "/gc/gomemlimit:bytes"},
+		{Name: "/gc/heap/goal:bytes"},
+	}
+	metrics.Read(samples)
+
+	t := gcTuning{
+		gcPercent:   gcOff,
+		memoryLimit: noMemoryLimit,
+	}
+	if metrics.KindUint64 == samples[0].Value.Kind() {
+		// The runtime reports a disabled collector as the unsigned
+		// representation of -1.
+		t.gcPercent = int64(samples[0].Value.Uint64())
+		if t.gcPercent < 0 {
+			t.gcPercent = gcOff
+		}
+	}
+	if metrics.KindUint64 == samples[1].Value.Kind() {
+		t.memoryLimit = int64(samples[1].Value.Uint64())
+	}
+	if metrics.KindUint64 == samples[2].Value.Kind() {
+		t.heapGoal = samples[2].Value.Uint64()
+	}
+	return t
+}
@@ -0,0 +1,31 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.21
+
+package newrelic
+
+import (
+	"math"
+	"runtime/debug"
+	"testing"
+)
+
+func TestReadGCTuningDetectsRuntimeChanges(t *testing.T) {
+	oldPercent := debug.SetGCPercent(75)
+	defer debug.SetGCPercent(oldPercent)
+	oldLimit := debug.SetMemoryLimit(1 << 40)
+	defer debug.SetMemoryLimit(oldLimit)
+
+	tuning := readGCTuning()
+	if tuning.gcPercent != 75 || tuning.memoryLimit != 1<<40 || tuning.heapGoal == 0 {
+		t.Errorf("%+v", tuning)
+	}
+
+	debug.SetGCPercent(-1)
+	debug.SetMemoryLimit(math.MaxInt64)
+	tuning = readGCTuning()
+	if tuning.gcPercent != gcOff || tuning.memoryLimit != noMemoryLimit {
+		t.Errorf("%+v", tuning)
+	}
+}
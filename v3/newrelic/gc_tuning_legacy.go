@@ -0,0 +1,39 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build !go1.21
+
+package newrelic
+
+import (
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// readGCTuning reads the garbage collector settings from the GOGC
+// environment variable, since the runtime/metrics package does not report
+// them before Go 1.21.  Changes made using debug.SetGCPercent are not
+// detected.  Memory limits were added in Go 1.19 and are not reported.
+func readGCTuning() gcTuning {
+	var ms runtime.MemStats
+	runtime.ReadMemStats(&ms)
+	return gcTuning{
+		gcPercent:   gcPercentFromEnv(os.Getenv),
+		memoryLimit: noMemoryLimit,
+		heapGoal:    ms.NextGC,
+	}
+}
+
+// gcPercentFromEnv parses the GOGC environment variable as the runtime does.
+func gcPercentFromEnv(getenv func(string) string) int64 {
+	s := getenv("GOGC")
+	if "off" == strings.ToLower(s) {
+		return gcOff
+	}
+	if n, err := strconv.ParseInt(s, 10, 64); nil == err {
+		return n
+	}
+	return 100
+}
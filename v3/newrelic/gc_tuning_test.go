@@ -0,0 +1,80 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestGCTuningReporterObserve(t *testing.T) {
+	r := &gcTuningReporter{}
+	params := r.observe(gcTuning{gcPercent: 100, memoryLimit: noMemoryLimit, heapGoal: 4096})
+	if want := (map[string]interface{}{
+		"gogc":     int64(100),
+		"heapGoal": uint64(4096),
+		"reason":   "startup",
+	}); !reflect.DeepEqual(params, want) {
+		t.Error(params)
+	}
+	// The heap goal changes with every collection.
+	if params := r.observe(gcTuning{gcPercent: 100, memoryLimit: noMemoryLimit, heapGoal: 8192}); nil != params {
+		t.Error(params)
+	}
+	params = r.observe(gcTuning{gcPercent: gcOff, memoryLimit: 1 << 30, heapGoal: 8192})
+	if want := (map[string]interface{}{
+		"gogc":         int64(-1),
+		"gomemlimit":   int64(1 << 30),
+		"heapGoal":     uint64(8192),
+		"reason":       "change",
+		"previousGogc": int64(100),
+	}); !reflect.DeepEqual(params, want) {
+		t.Error(params)
+	}
+	params = r.observe(gcTuning{gcPercent: gcOff, memoryLimit: 1 << 29})
+	if params["previousGomemlimit"] != int64(1<<30) {
+		t.Error(params)
+	}
+}
+
+func TestRecordGCTuning(t *testing.T) {
+	app := testApp(nil, nil, t)
+	r := &gcTuningReporter{}
+	recordGCTuning(internalApp(app), r, gcTuning{gcPercent: 100, memoryLimit: noMemoryLimit})
+	recordGCTuning(internalApp(app), r, gcTuning{gcPercent: 100, memoryLimit: noMemoryLimit})
+	recordGCTuning(internalApp(app), r, gcTuning{gcPercent: 50, memoryLimit: noMemoryLimit})
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "GoGCTuning",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"gogc":     100,
+			"heapGoal": 0,
+			"reason":   "startup",
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "GoGCTuning",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"gogc":         50,
+			"heapGoal":     0,
+			"reason":       "change",
+			"previousGogc": 100,
+		},
+	}})
+}
+
+func TestRecordGCTuningCustomEventsDisabled(t *testing.T) {
+	cfgfn := func(cfg *Config) { cfg.CustomInsightsEvents.Enabled = false }
+	app := testApp(nil, cfgfn, t)
+	recordGCTuning(internalApp(app), &gcTuningReporter{}, readGCTuning())
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
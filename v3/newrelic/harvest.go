@@ -24,11 +24,12 @@ const (
 	harvestCustomEvents
 	harvestTxnEvents
 	harvestErrorEvents
+	harvestLogEvents
 )
 
 const (
 	// harvestTypesEvents includes all Event types
-	harvestTypesEvents = harvestSpanEvents | harvestCustomEvents | harvestTxnEvents | harvestErrorEvents
+	harvestTypesEvents = harvestSpanEvents | harvestCustomEvents | harvestTxnEvents | harvestErrorEvents | harvestLogEvents
 	// harvestTypesAll includes all harvest types
 	harvestTypesAll = harvestMetricsTraces | harvestTypesEvents
 )
@@ -39,11 +40,15 @@ type harvestTimer struct {
 }
 
 func newHarvestTimer(now time.Time, periods map[harvestTypes]time.Duration) *harvestTimer {
+	// The periods are copied since they may be adjusted by the adaptive
+	// harvest.
+	ps := make(map[harvestTypes]time.Duration, len(periods))
 	lastHarvest := make(map[harvestTypes]time.Time, len(periods))
-	for tp := range periods {
+	for tp, period := range periods {
+		ps[tp] = period
 		lastHarvest[tp] = now
 	}
-	return &harvestTimer{periods: periods, lastHarvest: lastHarvest}
+	return &harvestTimer{periods: ps, lastHarvest: lastHarvest}
 }
 
 func (timer *harvestTimer) ready(now time.Time) (ready harvestTypes) {
@@ -58,7 +63,8 @@ func (timer *harvestTimer) ready(now time.Time) (ready harvestTypes) {
 
 // harvest contains collected data.
 type harvest struct {
-	timer *harvestTimer
+	timer    *harvestTimer
+	adaptive *adaptiveHarvest
 
 	Metrics      *metricTable
 	ErrorTraces  harvestErrors
@@ -68,6 +74,7 @@ type harvest struct {
 	CustomEvents *customEvents
 	TxnEvents    *txnEvents
 	ErrorEvents  *errorEvents
+	LogEvents    *logEvents
 }
 
 const (
@@ -81,6 +88,9 @@ const (
 func (h *harvest) Ready(now time.Time) *harvest {
 	ready := &harvest{}
 
+	if nil != h.adaptive {
+		h.adaptive.observe(h, now)
+	}
 	types := h.timer.ready(now)
 	if 0 == types {
 		return nil
@@ -103,13 +113,28 @@ func (h *harvest) Ready(now time.Time) *harvest {
 		h.Metrics.addCount(errorEventsSent, h.ErrorEvents.NumSaved(), forced)
 		ready.ErrorEvents = h.ErrorEvents
 		h.ErrorEvents = newErrorEvents(h.ErrorEvents.capacity())
+		h.ErrorEvents.stratified = ready.ErrorEvents.stratified
 	}
 	if 0 != types&harvestSpanEvents {
 		h.Metrics.addCount(spanEventsSeen, h.SpanEvents.NumSeen(), forced)
 		h.Metrics.addCount(spanEventsSent, h.SpanEvents.NumSaved(), forced)
+		h.SpanEvents.droppedMetrics(h.Metrics)
 		ready.SpanEvents = h.SpanEvents
 		h.SpanEvents = newSpanEvents(h.SpanEvents.capacity())
 	}
+	if 0 != types&harvestLogEvents {
+		// Log forwarding is disabled unless the reservoir has capacity.
+		if h.LogEvents.capacity() > 0 {
+			h.Metrics.addCount(logEventsSeen, h.LogEvents.NumSeen(), forced)
+			h.Metrics.addCount(logEventsSent, h.LogEvents.NumSaved(), forced)
+		}
+		ready.LogEvents = h.LogEvents
+		h.LogEvents = newLogEvents(h.LogEvents.capacity())
+		h.LogEvents.common = ready.LogEvents.common
+	}
+	if nil != h.adaptive && 0 != types&h.adaptive.types {
+		h.adaptive.adjust(h, ready, now)
+	}
 	// NOTE! Metrics must happen after the event harvest conditionals to
 	// ensure that the metrics contain the event supportability metrics.
 	if 0 != types&harvestMetricsTraces {
@@ -139,6 +164,9 @@ func (h *harvest) Payloads(splitLargeTxnEvents bool) (ps []payloadCreator) {
 	if nil != h.SpanEvents {
 		ps = append(ps, h.SpanEvents)
 	}
+	if nil != h.LogEvents {
+		ps = append(ps, h.LogEvents)
+	}
 	if nil != h.Metrics {
 		ps = append(ps, h.Metrics)
 	}
@@ -167,11 +195,21 @@ type harvestConfig struct {
 	MaxCustomEvents int
 	MaxErrorEvents  int
 	MaxTxnEvents    int
+	// MaxLogEvents is zero unless log forwarding is enabled.
+	MaxLogEvents int
+	// LogCommon holds the attributes shared by the logs of each payload.
+	LogCommon logCommon
+	// StratifiedErrorEvents controls whether the error events kept are
+	// stratified by error class.
+	StratifiedErrorEvents bool
+	// AdaptiveMinPeriod is the shortest event report period used when
+	// the period adapts to load, or zero if it does not.
+	AdaptiveMinPeriod time.Duration
 }
 
 // newHarvest returns a new Harvest.
 func newHarvest(now time.Time, configurer harvestConfig) *harvest {
-	return &harvest{
+	h := &harvest{
 		timer:        newHarvestTimer(now, configurer.ReportPeriods),
 		Metrics:      newMetricTable(maxMetrics, now),
 		ErrorTraces:  newHarvestErrors(maxHarvestErrors),
@@ -181,7 +219,12 @@ func newHarvest(now time.Time, configurer harvestConfig) *harvest {
 		CustomEvents: newCustomEvents(configurer.MaxCustomEvents),
 		TxnEvents:    newTxnEvents(configurer.MaxTxnEvents),
 		ErrorEvents:  newErrorEvents(configurer.MaxErrorEvents),
+		LogEvents:    newLogEvents(configurer.MaxLogEvents),
 	}
+	h.LogEvents.common = configurer.LogCommon
+	h.ErrorEvents.stratified = configurer.StratifiedErrorEvents
+	h.adaptive = newAdaptiveHarvest(now, configurer)
+	return h
 }
 
 func createTrackUsageMetrics(metrics *metricTable) {
@@ -220,6 +263,9 @@ func (h *harvest) CreateFinalMetrics(reply *internal.ConnectReply, hc harvestCon
 	h.Metrics.addValue(supportCustomEventLimit, "", float64(hc.MaxCustomEvents), forced)
 	h.Metrics.addValue(supportErrorEventLimit, "", float64(hc.MaxErrorEvents), forced)
 	h.Metrics.addValue(supportSpanEventLimit, "", float64(hc.MaxSpanEvents), forced)
+	if hc.MaxLogEvents > 0 {
+		h.Metrics.addValue(supportLogEventLimit, "", float64(hc.MaxLogEvents), forced)
+	}
 
 	createTraceObserverMetrics(to, h.Metrics)
 	createTrackUsageMetrics(h.Metrics)
@@ -316,6 +362,15 @@ func createTxnMetrics(args *txnData, metrics *metricTable) {
 	if args.Queuing > 0 {
 		metrics.addDuration(queueMetric, "", args.Queuing, args.Queuing, forced)
 	}
+
+	// Clock Skew Metrics
+	if args.ClockSkew > 0 {
+		metrics.addDuration(supportClockSkewForward, "", args.ClockSkew, args.ClockSkew, forced)
+	} else if args.ClockSkew < 0 {
+		metrics.addDuration(supportClockSkewBackward, "", -args.ClockSkew, -args.ClockSkew, forced)
+	}
+
+	createOutcomeMetrics(args, metrics)
 }
 
 var (
@@ -0,0 +1,157 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"time"
+)
+
+var adaptiveEventTypes = []harvestTypes{
+	harvestTxnEvents,
+	harvestCustomEvents,
+	harvestErrorEvents,
+	harvestSpanEvents,
+	harvestLogEvents,
+}
+
+// adaptiveHarvest adjusts the report period of the event types which share
+// the server granted period.  See Config.EventHarvest.Adaptive.
+type adaptiveHarvest struct {
+	types   harvestTypes
+	granted time.Duration
+	min     time.Duration
+	period  time.Duration
+	// limits are the number of events of each type which may be sent in
+	// the granted period.
+	limits map[harvestTypes]int
+	// cycleStart is when the current cycle started and fullAt is when a
+	// reservoir first became full during it, or zero.
+	cycleStart time.Time
+	fullAt     time.Time
+}
+
+func newAdaptiveHarvest(now time.Time, configurer harvestConfig) *adaptiveHarvest {
+	if configurer.AdaptiveMinPeriod <= 0 {
+		return nil
+	}
+	for types, period := range configurer.ReportPeriods {
+		// Metrics and traces are always sent every minute, so the
+		// period of the event types harvested with them cannot change.
+		if 0 != types&harvestMetricsTraces || 0 == types&harvestTypesEvents {
+			continue
+		}
+		if period <= configurer.AdaptiveMinPeriod {
+			continue
+		}
+		return &adaptiveHarvest{
+			types:   types,
+			granted: period,
+			min:     configurer.AdaptiveMinPeriod,
+			period:  period,
+			limits: map[harvestTypes]int{
+				harvestTxnEvents:    configurer.MaxTxnEvents,
+				harvestCustomEvents: configurer.MaxCustomEvents,
+				harvestErrorEvents:  configurer.MaxErrorEvents,
+				harvestSpanEvents:   configurer.MaxSpanEvents,
+				harvestLogEvents:    configurer.MaxLogEvents,
+			},
+			cycleStart: now,
+		}
+	}
+	return nil
+}
+
+// eventReservoir returns the events of the given type.
+func (h *harvest) eventReservoir(tp harvestTypes) *analyticsEvents {
+	switch tp {
+	case harvestTxnEvents:
+		return h.TxnEvents.analyticsEvents
+	case harvestCustomEvents:
+		return h.CustomEvents.analyticsEvents
+	case harvestErrorEvents:
+		return h.ErrorEvents.analyticsEvents
+	case harvestSpanEvents:
+		return h.SpanEvents.analyticsEvents
+	case harvestLogEvents:
+		return h.LogEvents.analyticsEvents
+	}
+	return nil
+}
+
+// observe records when a reservoir first becomes full during the cycle.
+func (a *adaptiveHarvest) observe(h *harvest, now time.Time) {
+	if !a.fullAt.IsZero() {
+		return
+	}
+	for _, tp := range adaptiveEventTypes {
+		if 0 == a.types&tp {
+			continue
+		}
+		if events := h.eventReservoir(tp); events.capacity() > 0 && len(events.events) >= events.capacity() {
+			a.fullAt = now
+			return
+		}
+	}
+}
+
+// adjust chooses the period of the next cycle based on how the reservoirs of
+// the cycle which has just been harvested filled, and sets the capacity of
+// the new reservoirs to match.
+func (a *adaptiveHarvest) adjust(h, ready *harvest, now time.Time) {
+	switch {
+	case !a.fullAt.IsZero() && a.fullAt.Sub(a.cycleStart) < a.period/2:
+		a.period /= 2
+		if a.period < a.min {
+			a.period = a.min
+		}
+	case a.fullAt.IsZero() && a.maxFill(ready) < 0.25:
+		a.period *= 2
+		if a.period > a.granted {
+			a.period = a.granted
+		}
+	}
+	a.fullAt = time.Time{}
+	a.cycleStart = now
+	h.timer.periods[a.types] = a.period
+
+	for _, tp := range adaptiveEventTypes {
+		if 0 != a.types&tp {
+			h.eventReservoir(tp).setCapacity(a.capacity(tp))
+		}
+	}
+	h.Metrics.addDuration(supportAdaptiveReportPeriod, "", a.period, a.period, forced)
+}
+
+// maxFill returns the largest fraction of its capacity used by any of the
+// harvested reservoirs.
+func (a *adaptiveHarvest) maxFill(ready *harvest) float64 {
+	var max float64
+	for _, tp := range adaptiveEventTypes {
+		if 0 == a.types&tp {
+			continue
+		}
+		events := ready.eventReservoir(tp)
+		if nil == events || 0 == events.capacity() {
+			continue
+		}
+		if fill := float64(len(events.events)) / float64(events.capacity()); fill > max {
+			max = fill
+		}
+	}
+	return max
+}
+
+// capacity returns the number of events of the given type which may be kept
+// in a cycle of the current period without exceeding the granted limit.
+func (a *adaptiveHarvest) capacity(tp harvestTypes) int {
+	limit := a.limits[tp]
+	if a.period >= a.granted || limit <= 0 {
+		return limit
+	}
+	capacity := int(int64(limit) * int64(a.period) / int64(a.granted))
+	if capacity < 1 {
+		capacity = 1
+	}
+	return capacity
+}
@@ -0,0 +1,166 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func adaptiveHarvestConfig() harvestConfig {
+	return harvestConfig{
+		ReportPeriods: map[harvestTypes]time.Duration{
+			harvestMetricsTraces: fixedHarvestPeriod,
+			harvestTypesEvents:   time.Second * 60,
+		},
+		MaxTxnEvents:      40,
+		MaxCustomEvents:   4,
+		MaxErrorEvents:    40,
+		MaxSpanEvents:     40,
+		AdaptiveMinPeriod: 10 * time.Second,
+	}
+}
+
+func addCustomEvents(t *testing.T, h *harvest, n int) {
+	for i := 0; i < n; i++ {
+		ce, err := createCustomEvent("myEvent", map[string]interface{}{"zip": i}, time.Now())
+		if nil != err {
+			t.Fatal(err)
+		}
+		h.CustomEvents.Add(ce)
+	}
+}
+
+func TestAdaptiveHarvestDisabled(t *testing.T) {
+	cfg := adaptiveHarvestConfig()
+	cfg.AdaptiveMinPeriod = 0
+	if h := newHarvest(time.Now(), cfg); nil != h.adaptive {
+		t.Error("adaptive harvest should be disabled")
+	}
+	// Event types harvested with metrics cannot adapt.
+	if h := newHarvest(time.Now(), harvestConfig{
+		ReportPeriods:     map[harvestTypes]time.Duration{harvestTypesAll: fixedHarvestPeriod},
+		AdaptiveMinPeriod: 10 * time.Second,
+	}); nil != h.adaptive {
+		t.Error("adaptive harvest should be disabled")
+	}
+}
+
+func TestAdaptiveHarvestShortensWhenFull(t *testing.T) {
+	now := time.Now()
+	cfg := adaptiveHarvestConfig()
+	h := newHarvest(now, cfg)
+
+	addCustomEvents(t, h, 4)
+	if ready := h.Ready(now.Add(5 * time.Second)); nil != ready {
+		t.Fatal("events should not be ready", ready)
+	}
+	addCustomEvents(t, h, 2)
+	ready := h.Ready(now.Add(61 * time.Second))
+	if nil == ready || ready.CustomEvents.NumSaved() != 4 {
+		t.Fatal(ready)
+	}
+	if p := h.adaptive.period; p != 30*time.Second {
+		t.Error(p)
+	}
+	if c := h.CustomEvents.capacity(); c != 2 {
+		t.Error(c)
+	}
+	if c := h.SpanEvents.capacity(); c != 20 {
+		t.Error(c)
+	}
+	if p := cfg.ReportPeriods[harvestTypesEvents]; p != 60*time.Second {
+		t.Error("configured periods should not be modified", p)
+	}
+
+	// The next cycle uses the shorter period.
+	addCustomEvents(t, h, 2)
+	h.Ready(now.Add(62 * time.Second))
+	if ready := h.Ready(now.Add(91 * time.Second)); nil == ready || ready.CustomEvents.NumSaved() != 2 {
+		t.Fatal(ready)
+	}
+	if p := h.adaptive.period; p != 15*time.Second {
+		t.Error(p)
+	}
+
+	// The period is never shorter than the minimum.
+	addCustomEvents(t, h, 1)
+	h.Ready(now.Add(92 * time.Second))
+	h.Ready(now.Add(106 * time.Second))
+	if p := h.adaptive.period; p != 10*time.Second {
+		t.Error(p)
+	}
+	if c := h.CustomEvents.capacity(); c != 1 {
+		t.Error(c)
+	}
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: customEventsSeen, Scope: "", Forced: true, Data: nil},
+		{Name: customEventsSent, Scope: "", Forced: true, Data: nil},
+		{Name: txnEventsSeen, Scope: "", Forced: true, Data: nil},
+		{Name: txnEventsSent, Scope: "", Forced: true, Data: nil},
+		{Name: errorEventsSeen, Scope: "", Forced: true, Data: nil},
+		{Name: errorEventsSent, Scope: "", Forced: true, Data: nil},
+		{Name: spanEventsSeen, Scope: "", Forced: true, Data: nil},
+		{Name: spanEventsSent, Scope: "", Forced: true, Data: nil},
+		{Name: supportAdaptiveReportPeriod, Scope: "", Forced: true, Data: []float64{2, 25, 25, 10, 15, 325}},
+	})
+}
+
+func TestAdaptiveHarvestLengthensWhenIdle(t *testing.T) {
+	now := time.Now()
+	h := newHarvest(now, adaptiveHarvestConfig())
+	h.adaptive.period = 10 * time.Second
+	h.timer.periods[harvestTypesEvents] = 10 * time.Second
+
+	h.Ready(now.Add(11 * time.Second))
+	if p := h.adaptive.period; p != 20*time.Second {
+		t.Error(p)
+	}
+	h.Ready(now.Add(31 * time.Second))
+	if p := h.adaptive.period; p != 40*time.Second {
+		t.Error(p)
+	}
+	// The period is never longer than the granted period.
+	h.Ready(now.Add(71 * time.Second))
+	if p := h.adaptive.period; p != 60*time.Second {
+		t.Error(p)
+	}
+	if c := h.CustomEvents.capacity(); c != 4 {
+		t.Error(c)
+	}
+}
+
+func TestAdaptiveHarvestSteadyLoad(t *testing.T) {
+	now := time.Now()
+	h := newHarvest(now, adaptiveHarvestConfig())
+
+	// Reservoirs which fill late in the cycle, or are partly full, keep
+	// the period unchanged.
+	addCustomEvents(t, h, 4)
+	h.Ready(now.Add(45 * time.Second))
+	h.Ready(now.Add(61 * time.Second))
+	if p := h.adaptive.period; p != 60*time.Second {
+		t.Error(p)
+	}
+	addCustomEvents(t, h, 2)
+	h.Ready(now.Add(121 * time.Second))
+	if p := h.adaptive.period; p != 60*time.Second {
+		t.Error(p)
+	}
+}
+
+func TestAppRunAdaptiveHarvest(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	run := newAppRun(cfg, internal.ConnectReplyDefaults())
+	if p := run.harvestConfig.AdaptiveMinPeriod; 0 != p {
+		t.Error(p)
+	}
+	cfg.EventHarvest.Adaptive.Enabled = true
+	run = newAppRun(cfg, internal.ConnectReplyDefaults())
+	if p := run.harvestConfig.AdaptiveMinPeriod; 5*time.Second != p {
+		t.Error(p)
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// HarvestRequest is a request the application makes to New Relic.  See
+// HarvestSender.
+type HarvestRequest struct {
+	// Method is the collector method called, eg. "preconnect", "connect",
+	// "metric_data", or "span_event_data".
+	Method string
+	// URL is the URL of the request, which includes the license key and
+	// agent run id as query parameters.
+	URL string
+	// Header contains the request headers, including those the collector
+	// requires to be echoed back in each request after connecting.
+	Header http.Header
+	// Body is the gzip compressed payload.
+	Body []byte
+}
+
+// HarvestResponse is the response to a HarvestRequest.
+type HarvestResponse struct {
+	// StatusCode is the HTTP status code of the response.  A status code
+	// of 202 indicates that the request was accepted.
+	StatusCode int
+	// Body is the body of the response.  The responses to the preconnect
+	// and connect methods must be the collector's, since they contain the
+	// settings of the application.  The responses to other methods may be
+	// empty.
+	Body []byte
+}
+
+// HarvestSender sends the requests the application makes to New Relic in
+// place of the application's HTTP client.  Use it to intercept, buffer, or
+// reroute telemetry, for example through an internal proxy queue:
+//
+//	type queueSender struct{ queue *Queue }
+//
+//	func (s queueSender) Send(req newrelic.HarvestRequest) (newrelic.HarvestResponse, error) {
+//		if "preconnect" == req.Method || "connect" == req.Method {
+//			return forwardNow(req)
+//		}
+//		if err := s.queue.Push(req); nil != err {
+//			return newrelic.HarvestResponse{}, err
+//		}
+//		return newrelic.HarvestResponse{StatusCode: http.StatusAccepted}, nil
+//	}
+//
+// If Send returns an error the data is kept and sent again in the next
+// harvest.  Otherwise the status code is handled as if it came from the
+// collector.  Send is called from the application's background goroutines
+// and must be safe for concurrent use.
+type HarvestSender interface {
+	Send(req HarvestRequest) (HarvestResponse, error)
+}
+
+func harvestSenderSetting(s HarvestSender) interface{} {
+	if nil == s {
+		return nil
+	}
+	return fmt.Sprintf("%T", s)
+}
+
+// harvestSenderRequest makes the request using the HarvestSender.
+func harvestSenderRequest(s HarvestSender, cmd rpmCmd, req *http.Request, body []byte) rpmResponse {
+	resp, err := s.Send(HarvestRequest{
+		Method: cmd.Name,
+		URL:    req.URL.String(),
+		Header: req.Header,
+		Body:   body,
+	})
+	if nil != err {
+		return rpmResponse{
+			forceSaveHarvestData: true,
+			Err:                  err,
+		}
+	}
+	r := newRPMResponse(resp.StatusCode)
+	r.body = resp.Body
+	return r
+}
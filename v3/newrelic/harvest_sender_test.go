@@ -0,0 +1,121 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/logger"
+)
+
+type harvestSenderFunc func(HarvestRequest) (HarvestResponse, error)
+
+func (fn harvestSenderFunc) Send(req HarvestRequest) (HarvestResponse, error) {
+	return fn(req)
+}
+
+func TestHarvestSender(t *testing.T) {
+	cmd := rpmCmd{
+		Name:              cmdMetrics,
+		Collector:         "collector.com",
+		RunID:             "run_id",
+		Data:              []byte(`["data"]`),
+		RequestHeadersMap: map[string]string{"zip": "zap"},
+		MaxPayloadSize:    internal.MaxPayloadSizeInBytes,
+	}
+	var sent []HarvestRequest
+	cs := rpmControls{
+		License: "the_license",
+		Client: &http.Client{
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				t.Error("client should not be used")
+				return nil, errors.New("unexpected request")
+			}),
+		},
+		Logger: logger.ShimLogger{},
+		Sender: harvestSenderFunc(func(req HarvestRequest) (HarvestResponse, error) {
+			sent = append(sent, req)
+			return HarvestResponse{StatusCode: http.StatusAccepted, Body: []byte("{}")}, nil
+		}),
+	}
+	resp := collectorRequest(cmd, cs)
+	if nil != resp.Err || "{}" != string(resp.body) {
+		t.Error(resp.Err, string(resp.body))
+	}
+	if len(sent) != 1 {
+		t.Fatal(sent)
+	}
+	req := sent[0]
+	if cmdMetrics != req.Method {
+		t.Error(req.Method)
+	}
+	if u := "https://collector.com/agent_listener/invoke_raw_method?license_key=the_license&marshal_format=json&method=metric_data&protocol_version=17&run_id=run_id"; u != req.URL {
+		t.Error(req.URL)
+	}
+	if "zap" != req.Header.Get("zip") || "gzip" != req.Header.Get("Content-Encoding") {
+		t.Error(req.Header)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(req.Body))
+	if nil != err {
+		t.Fatal(err)
+	}
+	if body, _ := ioutil.ReadAll(r); `["data"]` != string(body) {
+		t.Error(string(body))
+	}
+}
+
+func TestHarvestSenderResponses(t *testing.T) {
+	cmd := rpmCmd{
+		Name:           cmdSpanEvents,
+		Collector:      "collector.com",
+		MaxPayloadSize: internal.MaxPayloadSizeInBytes,
+	}
+	sendErr := errors.New("queue full")
+	testcases := []struct {
+		code       int
+		err        error
+		save       bool
+		disconnect bool
+	}{
+		{code: http.StatusAccepted},
+		{err: sendErr, save: true},
+		{code: http.StatusServiceUnavailable, save: true},
+		{code: http.StatusGone, disconnect: true},
+	}
+	for _, tc := range testcases {
+		tc := tc
+		cs := rpmControls{
+			Logger: logger.ShimLogger{},
+			Sender: harvestSenderFunc(func(req HarvestRequest) (HarvestResponse, error) {
+				return HarvestResponse{StatusCode: tc.code}, tc.err
+			}),
+		}
+		resp := collectorRequest(cmd, cs)
+		if (nil == resp.Err) != (http.StatusAccepted == tc.code) {
+			t.Error(tc.code, resp.Err)
+		}
+		if resp.ShouldSaveHarvestData() != tc.save || resp.IsDisconnect() != tc.disconnect {
+			t.Error(tc.code, tc.err, resp)
+		}
+	}
+}
+
+func TestHarvestSenderSetting(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.HarvestSender = harvestSenderFunc(nil)
+	js, err := settings(cfg).MarshalJSON()
+	if nil != err {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(js), `"HarvestSender":"newrelic.harvestSenderFunc"`) {
+		t.Error(string(js))
+	}
+}
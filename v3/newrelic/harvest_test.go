@@ -181,7 +181,7 @@ func TestCreateFinalMetricsTraceObserver(t *testing.T) {
 func TestEmptyPayloads(t *testing.T) {
 	h := newHarvest(time.Now(), dfltHarvestCfgr)
 	payloads := h.Payloads(true)
-	if len(payloads) != 8 {
+	if len(payloads) != 9 {
 		t.Error(len(payloads))
 	}
 	for _, p := range payloads {
@@ -730,10 +730,10 @@ func TestHarvestSplitTxnEvents(t *testing.T) {
 	payloadsWithSplit := h.Payloads(true)
 	payloadsWithoutSplit := h.Payloads(false)
 
-	if len(payloadsWithSplit) != 9 {
+	if len(payloadsWithSplit) != 10 {
 		t.Error(len(payloadsWithSplit))
 	}
-	if len(payloadsWithoutSplit) != 8 {
+	if len(payloadsWithoutSplit) != 9 {
 		t.Error(len(payloadsWithoutSplit))
 	}
 }
@@ -0,0 +1,27 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"strings"
+)
+
+// isHealthCheck returns true if the request headers identify the request as
+// a health check which should not create a transaction.
+func (c Config) isHealthCheck(h http.Header) bool {
+	if !c.HealthChecks.Enabled || nil == h {
+		return false
+	}
+	userAgent := strings.ToLower(h.Get("User-Agent"))
+	if "" == userAgent {
+		return false
+	}
+	for _, prefix := range c.HealthChecks.UserAgents {
+		if "" != prefix && strings.HasPrefix(userAgent, strings.ToLower(prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,95 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestIsHealthCheck(t *testing.T) {
+	cfg := defaultConfig()
+	testcases := []struct {
+		userAgent string
+		expect    bool
+	}{
+		{userAgent: "", expect: false},
+		{userAgent: "kube-probe/1.18", expect: true},
+		{userAgent: "ELB-HealthChecker/2.0", expect: true},
+		{userAgent: "elb-healthchecker/2.0", expect: true},
+		{userAgent: "GoogleHC/1.0", expect: true},
+		{userAgent: "Consul Health Check", expect: true},
+		{userAgent: "Envoy/HC", expect: true},
+		{userAgent: "Mozilla/5.0 kube-probe/1.18", expect: false},
+		{userAgent: "curl/7.64.1", expect: false},
+	}
+	for _, tc := range testcases {
+		h := http.Header{}
+		h.Set("User-Agent", tc.userAgent)
+		if out := cfg.isHealthCheck(h); out != tc.expect {
+			t.Error(tc.userAgent, out, tc.expect)
+		}
+	}
+	if cfg.isHealthCheck(nil) {
+		t.Error("nil headers are not a health check")
+	}
+
+	cfg.HealthChecks.Enabled = false
+	h := http.Header{}
+	h.Set("User-Agent", "kube-probe/1.18")
+	if cfg.isHealthCheck(h) {
+		t.Error("health checks should not be detected when disabled")
+	}
+}
+
+func TestHealthCheckTransactionIgnored(t *testing.T) {
+	app := testApp(nil, nil, t)
+	req, _ := http.NewRequest("GET", "http://example.com/healthz", nil)
+	req.Header.Set("User-Agent", "kube-probe/1.18")
+	txn := app.StartTransaction("healthz")
+	txn.SetWebRequestHTTP(req)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+	app.ExpectMetrics(t, []internal.WantMetric{})
+}
+
+func TestHealthCheckCustomUserAgent(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.HealthChecks.UserAgents = append(cfg.HealthChecks.UserAgents, "my-monitor")
+	}
+	app := testApp(nil, cfgfn, t)
+	req, _ := http.NewRequest("GET", "http://example.com/healthz", nil)
+	req.Header.Set("User-Agent", "my-monitor/3")
+	txn := app.StartTransaction("healthz")
+	txn.SetWebRequestHTTP(req)
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+}
+
+func TestHealthCheckDisabled(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.HealthChecks.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	req, _ := http.NewRequest("GET", "http://example.com/healthz", nil)
+	req.Header.Set("User-Agent", "kube-probe/1.18")
+	txn := app.StartTransaction("healthz")
+	txn.SetWebRequestHTTP(req)
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/healthz",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.method":       "GET",
+			"request.uri":          "http://example.com/healthz",
+			"request.headers.host": "example.com",
+			"http.flavor":          "1.1",
+		},
+	}})
+}
@@ -0,0 +1,29 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+// importantPriorityBoost is added to the priority of transactions marked
+// using MarkImportant.  Since the priority of a sampled transaction is
+// between 1 and 2, important transactions outrank every ordinary one when
+// events are dropped.
+const importantPriorityBoost = 1.0
+
+func (txn *txn) MarkImportant(reason string) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	txn.Attrs.Agent.Add(AttributeRetentionHint, reason, nil)
+	if txn.important {
+		return nil
+	}
+	txn.important = true
+	if txn.BetterCAT.Enabled {
+		txn.forceSampled()
+		txn.BetterCAT.Priority += importantPriorityBoost
+	}
+	return nil
+}
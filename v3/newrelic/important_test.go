@@ -0,0 +1,66 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestMarkImportant(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	app := testApp(replyfn, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	if txn.IsSampled() {
+		t.Error("transaction should not be sampled")
+	}
+	txn.MarkImportant("onboarding")
+	txn.MarkImportant("payment failed")
+	if !txn.IsSampled() {
+		t.Error("important transaction should be sampled")
+	}
+	// The priority is raised once, above that of sampled transactions.
+	if p := txn.thread.BetterCAT.Priority; p < 2 || p >= 3 {
+		t.Error(p)
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/hello",
+			"sampled":  true,
+			"priority": internal.MatchAnything,
+			"guid":     internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"retention.hint": "payment failed",
+		},
+	}})
+}
+
+func TestMarkImportantDistributedTracingDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.MarkImportant("payment failed")
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			"retention.hint": "payment failed",
+		},
+	}})
+}
+
+func TestMarkImportantNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.MarkImportant("payment failed")
+}
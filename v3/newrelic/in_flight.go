@@ -0,0 +1,113 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+)
+
+// inFlightShards is the number of independently locked sets of transactions
+// kept by inFlight, so that transactions which start and end concurrently
+// rarely contend for the same lock.
+const inFlightShards = 32
+
+type inFlightShard struct {
+	sync.Mutex
+	txns map[*txn]struct{}
+}
+
+// inFlight tracks the transactions which have been started but not yet
+// ended so that they can be ended as truncated when the application is shut
+// down.
+//
+// A transaction is only tracked while a Transaction referring to it is
+// reachable: once the application has dropped every Transaction of a
+// transaction which it never ended, the transaction is no longer tracked
+// and can be garbage collected.
+type inFlight struct {
+	// n is the number of transactions tracked.  It is accessed
+	// atomically.
+	n int32
+	// next is used to spread the transactions across the shards.  It is
+	// accessed atomically.
+	next   uint32
+	shards [inFlightShards]inFlightShard
+}
+
+func (f *inFlight) add(t *txn) {
+	t.inFlightShard = atomic.AddUint32(&f.next, 1) % inFlightShards
+	s := &f.shards[t.inFlightShard]
+	s.Lock()
+	defer s.Unlock()
+
+	if nil == s.txns {
+		s.txns = make(map[*txn]struct{})
+	}
+	s.txns[t] = struct{}{}
+	atomic.AddInt32(&f.n, 1)
+}
+
+func (f *inFlight) remove(t *txn) {
+	s := &f.shards[t.inFlightShard]
+	s.Lock()
+	defer s.Unlock()
+
+	if _, ok := s.txns[t]; ok {
+		delete(s.txns, t)
+		atomic.AddInt32(&f.n, -1)
+	}
+}
+
+// count returns the number of transactions in progress.
+func (f *inFlight) count() int {
+	return int(atomic.LoadInt32(&f.n))
+}
+
+// list returns the transactions in progress.
+func (f *inFlight) list() []*txn {
+	txns := make([]*txn, 0, f.count())
+	for i := range f.shards {
+		s := &f.shards[i]
+		s.Lock()
+		for t := range s.txns {
+			txns = append(txns, t)
+		}
+		s.Unlock()
+	}
+	return txns
+}
+
+// truncate ends every transaction which is still in progress.  Each is
+// given the AttributeTruncated attribute.
+func (f *inFlight) truncate() int {
+	ended := 0
+	for _, t := range f.list() {
+		thd := &thread{txn: t, thread: &t.mainThread}
+		if nil == thd.endTruncated() {
+			ended++
+		}
+	}
+	return ended
+}
+
+// trackHandle counts the Transaction as a handle of its transaction.  When
+// the last handle of a transaction is garbage collected, the transaction is
+// no longer tracked as in progress.
+func trackHandle(handle *Transaction) {
+	t := handle.thread.txn
+	if nil == t || nil == t.app {
+		return
+	}
+	atomic.AddInt32(&t.handles, 1)
+	runtime.SetFinalizer(handle, releaseHandle)
+}
+
+func releaseHandle(handle *Transaction) {
+	t := handle.thread.txn
+	if 0 == atomic.AddInt32(&t.handles, -1) {
+		t.app.inFlight.remove(t)
+	}
+}
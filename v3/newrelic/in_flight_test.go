@@ -0,0 +1,84 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestShutdownTruncatesInFlightTransactions(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.StartTransaction("finished").End()
+	txn := app.StartTransaction("inFlight")
+	txn.StartSegment("segment")
+	app.Shutdown(10 * time.Second)
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/finished",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name": "OtherTransaction/Go/inFlight",
+			},
+			AgentAttributes: map[string]interface{}{
+				AttributeTruncated: true,
+			},
+		},
+	})
+
+	// Ending the transaction after shutdown has no effect.
+	txn.End()
+	if n := internalApp(app).inFlight.count(); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestShutdownNoInFlightTransactions(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.StartTransaction("hello").End()
+	if n := internalApp(app).inFlight.count(); n != 0 {
+		t.Error(n)
+	}
+	app.Shutdown(10 * time.Second)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+	}})
+}
+
+func TestInFlightAbandonedTransaction(t *testing.T) {
+	app := testApp(nil, nil, t)
+	func() {
+		txn := app.StartTransaction("abandoned")
+		txn.NewGoroutine().StartSegment("segment")
+	}()
+	kept := app.StartTransaction("kept")
+
+	// Finalizers run after the garbage collection which found the
+	// Transactions unreachable.
+	deadline := time.Now().Add(5 * time.Second)
+	for internalApp(app).inFlight.count() > 1 && time.Now().Before(deadline) {
+		runtime.GC()
+		time.Sleep(time.Millisecond)
+	}
+	if n := internalApp(app).inFlight.count(); n != 1 {
+		t.Fatal("abandoned transaction still tracked", n)
+	}
+	if txns := internalApp(app).inFlight.list(); len(txns) != 1 || txns[0] != kept.thread.txn {
+		t.Error(txns)
+	}
+	kept.End()
+	if n := internalApp(app).inFlight.count(); n != 0 {
+		t.Error(n)
+	}
+}
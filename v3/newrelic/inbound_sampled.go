@@ -0,0 +1,88 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"path"
+)
+
+// InboundSampledMode controls how the sampled decision of inbound distributed
+// tracing headers is used.  See Config.DistributedTracer.InboundSampled.
+type InboundSampledMode string
+
+const (
+	// InboundSampledHonor uses the sampled flag and priority of inbound
+	// headers, so that a transaction is sampled whenever its caller was.
+	// This is the default.
+	InboundSampledHonor InboundSampledMode = "honor"
+	// InboundSampledHint keeps the priority of inbound headers but lets
+	// the application's adaptive sampler decide whether the transaction is
+	// sampled.  The bonus which the caller added to the priority when it
+	// was sampled is removed before deciding, so the number sampled is
+	// limited by the sampling target and the priority is only raised again
+	// if the transaction is sampled.
+	InboundSampledHint InboundSampledMode = "hint"
+	// InboundSampledIgnore ignores the sampled flag and priority of inbound
+	// headers: the transaction is sampled as if it had no caller.  The
+	// trace id and parent are still used, so the trace remains connected.
+	InboundSampledIgnore InboundSampledMode = "ignore"
+)
+
+// InboundSampledRule sets the InboundSampledMode for the web transactions
+// whose request path matches a pattern.  See
+// Config.DistributedTracer.InboundSampled.
+type InboundSampledRule struct {
+	// RoutePattern is matched against the path of the request, eg.
+	// "/api/*", using the syntax of path.Match.
+	RoutePattern string
+	// Mode is the InboundSampledMode of matching transactions.
+	Mode InboundSampledMode
+}
+
+func validInboundSampledMode(m InboundSampledMode) bool {
+	switch m {
+	case "", InboundSampledHonor, InboundSampledHint, InboundSampledIgnore:
+		return true
+	}
+	return false
+}
+
+// validateInboundSampled checks the InboundSampled mode and rules.
+func (c Config) validateInboundSampled() error {
+	in := c.DistributedTracer.InboundSampled
+	if !validInboundSampledMode(in.Mode) {
+		return fmt.Errorf("invalid inbound sampled mode %q", in.Mode)
+	}
+	for _, r := range in.Rules {
+		if "" == r.RoutePattern {
+			return fmt.Errorf("inbound sampled rule has an empty RoutePattern")
+		}
+		if _, err := path.Match(r.RoutePattern, ""); nil != err {
+			return fmt.Errorf("invalid inbound sampled RoutePattern %q: %v", r.RoutePattern, err)
+		}
+		if "" == r.Mode || !validInboundSampledMode(r.Mode) {
+			return fmt.Errorf("invalid inbound sampled mode %q for RoutePattern %q", r.Mode, r.RoutePattern)
+		}
+	}
+	return nil
+}
+
+// inboundSampledMode returns the InboundSampledMode for a transaction with
+// the request path, which is empty for transactions which are not web
+// transactions.
+func (c Config) inboundSampledMode(requestPath string) InboundSampledMode {
+	in := c.DistributedTracer.InboundSampled
+	if "" != requestPath {
+		for _, r := range in.Rules {
+			if ok, _ := path.Match(r.RoutePattern, requestPath); ok {
+				return r.Mode
+			}
+		}
+	}
+	if "" == in.Mode {
+		return InboundSampledHonor
+	}
+	return in.Mode
+}
@@ -0,0 +1,155 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestValidateInboundSampled(t *testing.T) {
+	testcases := []struct {
+		mode   InboundSampledMode
+		rules  []InboundSampledRule
+		expect bool
+	}{
+		{mode: "", expect: true},
+		{mode: InboundSampledHonor, expect: true},
+		{mode: InboundSampledHint, expect: true},
+		{mode: InboundSampledIgnore, expect: true},
+		{mode: "always", expect: false},
+		{mode: InboundSampledHint, rules: []InboundSampledRule{{RoutePattern: "/api/*", Mode: InboundSampledHonor}}, expect: true},
+		{mode: InboundSampledHint, rules: []InboundSampledRule{{RoutePattern: "", Mode: InboundSampledHonor}}, expect: false},
+		{mode: InboundSampledHint, rules: []InboundSampledRule{{RoutePattern: "/api/[", Mode: InboundSampledHonor}}, expect: false},
+		{mode: InboundSampledHint, rules: []InboundSampledRule{{RoutePattern: "/api/*"}}, expect: false},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.DistributedTracer.InboundSampled.Mode = tc.mode
+		cfg.DistributedTracer.InboundSampled.Rules = tc.rules
+		if err := cfg.validateInboundSampled(); (nil == err) != tc.expect {
+			t.Error(tc.mode, tc.rules, err)
+		}
+	}
+}
+
+func TestInboundSampledModeRules(t *testing.T) {
+	cfg := defaultConfig()
+	if m := cfg.inboundSampledMode("/checkout/cart"); InboundSampledHonor != m {
+		t.Error(m)
+	}
+	cfg.DistributedTracer.InboundSampled.Mode = InboundSampledIgnore
+	cfg.DistributedTracer.InboundSampled.Rules = []InboundSampledRule{
+		{RoutePattern: "/checkout/*", Mode: InboundSampledHonor},
+		{RoutePattern: "/checkout/cart", Mode: InboundSampledHint},
+		{RoutePattern: "/search", Mode: InboundSampledHint},
+	}
+	testcases := []struct {
+		path   string
+		expect InboundSampledMode
+	}{
+		{path: "", expect: InboundSampledIgnore},
+		{path: "/checkout/cart", expect: InboundSampledHonor},
+		{path: "/checkout/cart/items", expect: InboundSampledIgnore},
+		{path: "/search", expect: InboundSampledHint},
+		{path: "/", expect: InboundSampledIgnore},
+	}
+	for _, tc := range testcases {
+		if m := cfg.inboundSampledMode(tc.path); m != tc.expect {
+			t.Error(tc.path, m, tc.expect)
+		}
+	}
+}
+
+// inboundSampledRequest returns a request whose trace context headers were
+// created by a caller which sampled the trace with a priority of 1.5.
+func inboundSampledRequest(path string) *http.Request {
+	req, _ := http.NewRequest("GET", "http://example.com"+path, nil)
+	now := strconv.FormatInt(timeToIntMillis(time.Now()), 10)
+	req.Header.Set(DistributedTraceW3CTraceParentHeader, "00-52fdfc072182654f163f5f0f9a621d72-9566c74d10d1e2c6-01")
+	req.Header.Set(DistributedTraceW3CTraceStateHeader, "123@nr=0-0-123-456-9566c74d10d1e2c6-52fdfc072182654f-1-1.5-"+now)
+	return req
+}
+
+func TestInboundSampledModes(t *testing.T) {
+	testcases := []struct {
+		mode          InboundSampledMode
+		sampleNothing bool
+		expectSampled bool
+		// expectPriority is zero if the priority is not the inbound
+		// priority.
+		expectPriority priority
+	}{
+		{mode: InboundSampledHonor, sampleNothing: true, expectSampled: true, expectPriority: 1.5},
+		// The inbound sampled bonus is removed, and only added again if
+		// the transaction is sampled.
+		{mode: InboundSampledHint, sampleNothing: true, expectSampled: false, expectPriority: 0.5},
+		{mode: InboundSampledHint, sampleNothing: false, expectSampled: true, expectPriority: 1.5},
+		{mode: InboundSampledIgnore, sampleNothing: true, expectSampled: false},
+	}
+	for _, tc := range testcases {
+		replyfn := func(reply *internal.ConnectReply) {
+			distributedTracingReplyFields(reply)
+			if tc.sampleNothing {
+				reply.SetSampleNothing()
+			}
+		}
+		cfgfn := func(cfg *Config) {
+			enableW3COnly(cfg)
+			cfg.DistributedTracer.InboundSampled.Mode = tc.mode
+		}
+		app := testApp(replyfn, cfgfn, t)
+		txn := app.StartTransaction("hello")
+		txn.SetWebRequestHTTP(inboundSampledRequest("/hello"))
+		if sampled := txn.IsSampled(); sampled != tc.expectSampled {
+			t.Error(tc.mode, tc.sampleNothing, sampled)
+		}
+		p := txn.thread.BetterCAT.Priority
+		if 0 != tc.expectPriority && p != tc.expectPriority {
+			t.Error(tc.mode, tc.sampleNothing, p)
+		}
+		if 0 == tc.expectPriority && p >= 1.0 {
+			t.Error(tc.mode, p)
+		}
+		if id := txn.GetTraceMetadata().TraceID; "52fdfc072182654f163f5f0f9a621d72" != id {
+			t.Error(tc.mode, id)
+		}
+		txn.End()
+		app.expectNoLoggedErrors(t)
+	}
+}
+
+func TestInboundSampledRouteRule(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		cfg.DistributedTracer.InboundSampled.Mode = InboundSampledIgnore
+		cfg.DistributedTracer.InboundSampled.Rules = []InboundSampledRule{
+			{RoutePattern: "/checkout/*", Mode: InboundSampledHonor},
+		}
+	}
+	app := testApp(replyfn, cfgfn, t)
+
+	txn := app.StartTransaction("checkout")
+	txn.SetWebRequestHTTP(inboundSampledRequest("/checkout/cart"))
+	if !txn.IsSampled() {
+		t.Error("checkout transaction should honor the inbound sampled flag")
+	}
+	txn.End()
+
+	txn = app.StartTransaction("assets")
+	txn.SetWebRequestHTTP(inboundSampledRequest("/assets/logo.png"))
+	if txn.IsSampled() {
+		t.Error("assets transaction should ignore the inbound sampled flag")
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+}
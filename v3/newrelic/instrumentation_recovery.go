@@ -0,0 +1,94 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"net/http"
+	"time"
+)
+
+// WrapHandleWithRecovery instruments http.Handler handlers with Transactions
+// like WrapHandle, and also recovers panics in the handler.  A recovered
+// panic is noticed as an error of the Transaction, with the stack trace of
+// the panic, and a 500 response is sent unless the handler has already
+// written the response header.  The response code error which would
+// usually accompany the 500 is not recorded, so the panic is the
+// Transaction's only error.  Config.ErrorCollector.RecordPanics does not need
+// to be set.
+//
+// If repanic is true the panic is propagated once the Transaction has ended,
+// eg. to reach outer middleware.  Otherwise the panic is stopped.  Panics of
+// http.ErrAbortHandler, which abort the response, are always propagated and
+// are not noticed as errors.
+//
+// The WrapHandleWithRecovery function is safe to call if app is nil, in
+// which case panics are still recovered.
+func WrapHandleWithRecovery(app *Application, pattern string, handler http.Handler, repanic bool) (string, http.Handler) {
+	return pattern, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var txn *Transaction
+		if nil != app {
+			txn = app.StartTransaction(r.Method + " " + pattern)
+			w = txn.SetWebResponse(w)
+			txn.SetWebRequestHTTP(r)
+			r = RequestWithTransactionContext(r, txn)
+		}
+		defer func() {
+			recovered := recover()
+			if http.ErrAbortHandler == recovered {
+				txn.End()
+				panic(recovered)
+			}
+			if nil != recovered && txn.recoverPanic(recovered) {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+			txn.End()
+			if nil != recovered && repanic {
+				panic(recovered)
+			}
+		}()
+
+		handler.ServeHTTP(w, r)
+	})
+}
+
+// WrapHandleFuncWithRecovery is WrapHandleWithRecovery for handler
+// functions.
+func WrapHandleFuncWithRecovery(app *Application, pattern string, handler func(http.ResponseWriter, *http.Request), repanic bool) (string, func(http.ResponseWriter, *http.Request)) {
+	p, h := WrapHandleWithRecovery(app, pattern, http.HandlerFunc(handler), repanic)
+	return p, func(w http.ResponseWriter, r *http.Request) { h.ServeHTTP(w, r) }
+}
+
+// recoverPanic notices a recovered panic as an error.  It reports whether a
+// 500 response should be written, which is the case unless the response
+// header has already been written.
+func (txn *Transaction) recoverPanic(recovered interface{}) bool {
+	if nil == txn || nil == txn.thread {
+		return true
+	}
+	return txn.thread.recoverPanic(recovered)
+}
+
+func (thd *thread) recoverPanic(recovered interface{}) bool {
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return true
+	}
+	e := txnErrorFromPanic(time.Now(), recovered)
+	e.Stack = getStackTrace()
+	thd.noticeErrorInternal(e)
+
+	if txn.wroteHeader {
+		return false
+	}
+	// Record the 500 response code without noticing it as a second
+	// error.
+	txn.wroteHeader = true
+	responseCodeAttribute(txn.Attrs, http.StatusInternalServerError)
+	return true
+}
@@ -0,0 +1,124 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.8
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func panicHandler(w http.ResponseWriter, req *http.Request) {
+	panic("my msg")
+}
+
+func TestWrapHandleWithRecovery(t *testing.T) {
+	app := testApp(nil, nil, t)
+	mux := http.NewServeMux()
+	mux.Handle(WrapHandleWithRecovery(app.Application, helloPath, http.HandlerFunc(panicHandler), false))
+	w := newCompatibleResponseRecorder()
+	mux.ServeHTTP(w, helloRequest)
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error(w.Code)
+	}
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "WebTransaction/Go/GET /hello",
+		Msg:     "my msg",
+		Klass:   panicErrorKlass,
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     panicErrorKlass,
+			"error.message":   "my msg",
+			"transactionName": "WebTransaction/Go/GET /hello",
+		},
+		AgentAttributes: mergeAttributes(helloRequestAttributes, map[string]interface{}{
+			"httpResponseCode": "500",
+			"http.statusCode":  500,
+		}),
+	}})
+}
+
+func TestWrapHandleWithRecoveryRepanic(t *testing.T) {
+	// RecordPanics must not record the propagated panic a second time.
+	app := testApp(nil, enableRecordPanics, t)
+	_, h := WrapHandleFuncWithRecovery(app.Application, helloPath, panicHandler, true)
+	w := newCompatibleResponseRecorder()
+	func() {
+		defer func() {
+			if r := recover(); "my msg" != r {
+				t.Error(r)
+			}
+		}()
+		h(w, helloRequest)
+	}()
+
+	if w.Code != http.StatusInternalServerError {
+		t.Error(w.Code)
+	}
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "WebTransaction/Go/GET /hello",
+		Msg:     "my msg",
+		Klass:   panicErrorKlass,
+	}})
+}
+
+func TestWrapHandleWithRecoveryHeaderWritten(t *testing.T) {
+	app := testApp(nil, nil, t)
+	_, h := WrapHandleFuncWithRecovery(app.Application, helloPath, func(w http.ResponseWriter, req *http.Request) {
+		w.WriteHeader(http.StatusAccepted)
+		panic("my msg")
+	}, false)
+	w := newCompatibleResponseRecorder()
+	h(w, helloRequest)
+
+	if w.Code != http.StatusAccepted {
+		t.Error(w.Code)
+	}
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     panicErrorKlass,
+			"error.message":   "my msg",
+			"transactionName": "WebTransaction/Go/GET /hello",
+		},
+		AgentAttributes: mergeAttributes(helloRequestAttributes, map[string]interface{}{
+			"httpResponseCode": "202",
+			"http.statusCode":  202,
+		}),
+	}})
+}
+
+func TestWrapHandleWithRecoveryAbortHandler(t *testing.T) {
+	app := testApp(nil, nil, t)
+	_, h := WrapHandleFuncWithRecovery(app.Application, helloPath, func(w http.ResponseWriter, req *http.Request) {
+		panic(http.ErrAbortHandler)
+	}, false)
+	func() {
+		defer func() {
+			if r := recover(); http.ErrAbortHandler != r {
+				t.Error(r)
+			}
+		}()
+		h(newCompatibleResponseRecorder(), helloRequest)
+	}()
+	app.ExpectErrors(t, []internal.WantError{})
+	app.ExpectTxnMetrics(t, internal.WantTxn{
+		Name:      "GET /hello",
+		IsWeb:     true,
+		NumErrors: 0,
+	})
+}
+
+func TestWrapHandleWithRecoveryNilApp(t *testing.T) {
+	_, h := WrapHandleFuncWithRecovery(nil, helloPath, panicHandler, false)
+	w := newCompatibleResponseRecorder()
+	h(w, helloRequest)
+	if w.Code != http.StatusInternalServerError {
+		t.Error(w.Code)
+	}
+}
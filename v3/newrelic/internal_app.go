@@ -12,9 +12,11 @@ import (
 	"os"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/utilization"
 )
 
 type appData struct {
@@ -27,14 +29,45 @@ type app struct {
 	config      config
 	rpmControls rpmControls
 	testHarvest *harvest
+	// testHarvestLock serializes the data merged into the testHarvest,
+	// since it is merged by the goroutines which record the data.
+	testHarvestLock sync.Mutex
+
+	// hosts tracks the health of the collector hosts used to connect.
+	hosts *collectorHosts
+
+	// rollups contains the applications used by transactions which report
+	// to a subset of the rollup application names, see
+	// Config.ApplicationSubsets.
+	rollups rollups
+
+	// inFlight contains the transactions which have not yet ended.
+	inFlight inFlight
+
+	// baselines contains the average duration of the transactions with
+	// each name, see Config.AnomalyDetection.
+	baselines baselines
+
+	// recentDurations contains the durations of the most recent web
+	// transactions, see Application.LoadStats.
+	recentDurations *recentDurations
+
+	// contention caches the snapshots of the block and mutex profiles
+	// used by Config.ContentionProfiling.
+	contention contentionSnapshots
+
+	// aggregates contains the Counters and Gauges, which are merged into
+	// each harvest of metrics.
+	aggregates *metricAggregates
 
 	trObserver traceObserver
 
 	// placeholderRun is used when the application is not connected.
 	placeholderRun *appRun
 
-	// initiateShutdown is used to tell the processor to shutdown.
-	initiateShutdown chan time.Duration
+	// initiateShutdown is used to tell the processor to shutdown.  It
+	// carries the deadline by which Shutdown returns.
+	initiateShutdown chan time.Time
 
 	// shutdownStarted and shutdownComplete are closed by the processor
 	// goroutine to indicate the shutdown status.  Two channels are used so
@@ -49,11 +82,21 @@ type app struct {
 	// Sends to these channels should not occur without a <-shutdownStarted
 	// select option to prevent deadlock.
 	dataChan           chan appData
-	collectorErrorChan chan rpmResponse
+	collectorErrorChan chan collectorError
 	connectChan        chan *appRun
-
-	// This mutex protects both `run` and `err`, both of which should only
-	// be accessed using getState and setState.
+	// harvestRequests is used by tick to take the data ready to be
+	// harvested when ManualHarvest is enabled.
+	harvestRequests chan harvestRequest
+
+	// tickLock serializes calls of tick, and protects tasks.
+	tickLock sync.Mutex
+	// tasks are the periodic tasks run during tick when ManualHarvest is
+	// enabled.
+	tasks []*periodicTask
+
+	// This mutex protects `run`, `err`, `placeholderRun`, and `reloaded`.
+	// `run` and `err` should only be accessed using getState and
+	// setState.
 	sync.RWMutex
 	// run is non-nil when the app is successfully connected.  It is
 	// immutable.
@@ -61,15 +104,51 @@ type app struct {
 	// err is non-nil if the application will never be connected again
 	// (disconnect, license exception, shutdown).
 	err error
+	// reloaded is config including the changes made by UpdateConfig.  It
+	// is used, rather than config, to create new runs.
+	reloaded config
+
+	// reloadLock serializes calls of UpdateConfig.
+	reloadLock sync.Mutex
+	// logger is the Logger of config, which UpdateConfig may replace.
+	logger *reloadableLogger
 
 	serverless *serverlessHarvest
+
+	// spool is non-nil if harvest payloads are spooled to disk.  See
+	// Config.Spool.
+	spool *harvestSpool
+
+	// scalingQueueTimes is non-nil if the ScalingSignal metrics are
+	// recorded.  See Config.ScalingSignal.
+	scalingQueueTimes *queueTimes
+
+	// connectOnce starts the connect of a short-lived application.  See
+	// Config.ShortLived.
+	connectOnce sync.Once
 }
 
 func (app *app) doHarvest(h *harvest, harvestStart time.Time, run *appRun) {
 	h.CreateFinalMetrics(run.Reply, run.harvestConfig, app.getObserver())
 
+	if "" != app.config.OTLP.Endpoint {
+		app.doOTLPHarvest(h, harvestStart, run)
+		return
+	}
+
+	// sent is true if the collector accepted any of the payloads, in which
+	// case spooled payloads are sent too.
+	sent := false
+	// ended is the response which ended the run, if any.  The payloads
+	// which it prevented from being sent are kept in unsent.
+	var ended *rpmResponse
+	var unsent []harvestable
 	payloads := h.Payloads(app.config.DistributedTracer.Enabled)
 	for _, p := range payloads {
+		if nil != ended {
+			unsent = append(unsent, p)
+			continue
+		}
 		cmd := p.EndpointMethod()
 		data, err := p.Data(run.Reply.RunID.String(), harvestStart)
 
@@ -93,14 +172,24 @@ func (app *app) doHarvest(h *harvest, harvestStart time.Time, run *appRun) {
 			MaxPayloadSize:    run.Reply.MaxPayloadSizeInBytes,
 		}
 
-		resp := collectorRequest(call, app.rpmControls)
+		resp := collectorRequest(call, app.getRPMControls())
+		unavailable := resp.isCollectorUnavailable()
 
-		if resp.IsDisconnect() || resp.IsRestartException() {
-			select {
-			case app.collectorErrorChan <- resp:
-			case <-app.shutdownStarted:
+		if app.hosts.harvestResult(run.preconnectHost, !unavailable, time.Now()) {
+			resp.failover = true
+		}
+		spooled := false
+		if nil != app.spool && unavailable {
+			app.spoolPayload(cmd, run, data)
+			spooled = true
+		}
+
+		if resp.IsDisconnect() || resp.IsRestartException() || resp.IsFailover() {
+			ended = &resp
+			if !spooled {
+				unsent = append(unsent, p)
 			}
-			return
+			continue
 		}
 
 		if nil != resp.Err {
@@ -111,20 +200,82 @@ func (app *app) doHarvest(h *harvest, harvestStart time.Time, run *appRun) {
 			})
 		}
 
-		if resp.ShouldSaveHarvestData() {
+		if nil == resp.Err {
+			sent = true
+		}
+		if resp.ShouldSaveHarvestData() && !spooled {
 			app.Consume(run.Reply.RunID, p)
 		}
 	}
+
+	if nil != ended {
+		app.endRun(run, collectorError{resp: *ended, unsent: unsent})
+		return
+	}
+
+	if sent && nil != app.spool {
+		select {
+		case <-app.shutdownStarted:
+			// Spooled payloads are sent after the application next
+			// starts rather than delaying shutdown.
+		default:
+			app.replaySpool(run)
+		}
+	}
+
+	if nil != h.Metrics && app.hosts.shouldFailBack(run.preconnectHost, time.Now()) {
+		app.endRun(run, collectorError{resp: rpmResponse{failover: true}})
+	}
+}
+
+// collectorError is a collector response which ends the current run, along
+// with the harvest data which was not sent because of it.
+type collectorError struct {
+	resp   rpmResponse
+	unsent []harvestable
+}
+
+// endRun reports the collector response which ends the run.  Harvests of the
+// same run may happen concurrently, so only the first response is reported
+// to ensure that the application reconnects once.
+func (app *app) endRun(run *appRun, e collectorError) {
+	if !atomic.CompareAndSwapInt32(&run.ended, 0, 1) {
+		return
+	}
+	select {
+	case app.collectorErrorChan <- e:
+	case <-app.shutdownStarted:
+	}
 }
 
 func (app *app) connectRoutine() {
+	if "" != app.config.OTLP.Endpoint {
+		// There is no connect when exporting using OTLP.
+		select {
+		case app.connectChan <- newAppRun(app.currentConfig(), newOTLPConnectReply(app.config)):
+		case <-app.shutdownStarted:
+		}
+		return
+	}
+
+	if nil != app.config.LicenseProvider && "" == app.getRPMControls().License {
+		if !app.awaitLicense() {
+			return
+		}
+	}
+
 	attempts := 0
 	for {
-		reply, resp := connectAttempt(app.config, app.rpmControls)
+		host := app.hosts.next(time.Now())
+		cfg := app.currentConfig()
+		reply, resp := connectAttempt(&cfg, host, app.getRPMControls())
 
 		if reply != nil {
+			app.hosts.markHealthy(host)
+			run := newAppRun(cfg, reply)
+			run.preconnectHost = host
 			select {
-			case app.connectChan <- newAppRun(app.config, reply):
+			case app.connectChan <- run:
 			case <-app.shutdownStarted:
 			}
 			return
@@ -132,7 +283,7 @@ func (app *app) connectRoutine() {
 
 		if resp.IsDisconnect() {
 			select {
-			case app.collectorErrorChan <- resp:
+			case app.collectorErrorChan <- collectorError{resp: resp}:
 			case <-app.shutdownStarted:
 			}
 			return
@@ -140,10 +291,19 @@ func (app *app) connectRoutine() {
 
 		if nil != resp.Err {
 			app.Warn("application connect failure", map[string]interface{}{
+				"host":  host,
 				"error": resp.Err.Error(),
 			})
 		}
 
+		if app.hosts.markFailed(host, time.Now()) {
+			app.Info("failing over to next collector host", map[string]interface{}{
+				"failed": host,
+				"next":   app.hosts.next(time.Now()),
+			})
+			continue
+		}
+
 		backoff := getConnectBackoffTime(attempts)
 		time.Sleep(time.Duration(backoff) * time.Second)
 		attempts++
@@ -163,7 +323,7 @@ func (app *app) connectTraceObserver(reply *internal.ConnectReply) {
 
 	observer, err := newTraceObserver(reply.RunID, reply.RequestHeadersMap, observerConfig{
 		endpoint:    endpoint,
-		license:     app.config.License,
+		license:     app.getRPMControls().License,
 		log:         app.config.Logger,
 		queueSize:   app.config.InfiniteTracing.SpanEvents.QueueSize,
 		appShutdown: app.shutdownComplete,
@@ -215,24 +375,57 @@ func (app *app) process() {
 	// and nil otherwise.
 	var h *harvest
 	var run *appRun
-
-	harvestTicker := time.NewTicker(time.Second)
-	defer harvestTicker.Stop()
+	// pending contains the data recorded before a short-lived app
+	// connected, and the data which was not sent before the collector
+	// ended the previous run.
+	var pending []harvestable
+
+	// The tick channel is nil, and so never receives, when the host
+	// application schedules harvests.
+	var tick <-chan time.Time
+	if !app.config.ManualHarvest.Enabled {
+		period := time.Second
+		if nil != app.config.HarvestClock {
+			// Harvests happen soon after a test advances the clock.
+			period = 10 * time.Millisecond
+		}
+		harvestTicker := time.NewTicker(period)
+		defer harvestTicker.Stop()
+		tick = harvestTicker.C
+	}
 
 	for {
 		select {
-		case <-harvestTicker.C:
+		case <-tick:
 			if nil != run {
-				now := time.Now()
-				if ready := h.Ready(now); nil != ready {
+				now := app.harvestNow()
+				if ready := app.readyHarvest(h, now); nil != ready {
 					go app.doHarvest(ready, now, run)
 				}
 			}
+		case req := <-app.harvestRequests:
+			var reply harvestReply
+			if nil != run {
+				reply.now = app.harvestNow()
+				if reply.ready = app.readyHarvest(h, reply.now); nil != reply.ready {
+					reply.run = run
+				}
+			}
+			req <- reply
 		case d := <-app.dataChan:
-			if nil != run && run.Reply.RunID == d.id {
+			// Only short-lived apps send data recorded before the
+			// connect, which has no run ID.
+			if nil != run && (run.Reply.RunID == d.id || "" == d.id) {
 				d.data.MergeIntoHarvest(h)
+			} else if "" == d.id {
+				pending = app.addPending(pending, d.data)
+			}
+		case deadline := <-app.initiateShutdown:
+			if nil == run && app.config.ShortLived.Enabled {
+				if run, pending = app.connectBeforeShutdown(deadline, pending); nil != run {
+					h = newHarvest(app.harvestNow(), run.harvestConfig)
+				}
 			}
-		case timeout := <-app.initiateShutdown:
 			close(app.shutdownStarted)
 
 			// Remove the run before merging any final data to
@@ -240,7 +433,7 @@ func (app *app) process() {
 			app.setState(nil, errors.New("application shut down"))
 
 			if obs := app.getObserver(); obs != nil {
-				if err := obs.shutdown(timeout); err != nil {
+				if err := obs.shutdown(deadline.Sub(time.Now())); err != nil {
 					app.Error("trace observer shutdown timeout exceeded", map[string]interface{}{
 						"err": err.Error(),
 					})
@@ -251,24 +444,37 @@ func (app *app) process() {
 				for done := false; !done; {
 					select {
 					case d := <-app.dataChan:
-						if run.Reply.RunID == d.id {
+						if run.Reply.RunID == d.id || "" == d.id {
 							d.data.MergeIntoHarvest(h)
 						}
 					default:
 						done = true
 					}
 				}
+				for _, data := range pending {
+					data.MergeIntoHarvest(h)
+				}
+				app.aggregates.MergeIntoHarvest(h)
 				app.doHarvest(h, time.Now(), run)
 			}
 
 			close(app.shutdownComplete)
 			app.setObserver(nil)
 			return
-		case resp := <-app.collectorErrorChan:
+		case e := <-app.collectorErrorChan:
+			resp := e.resp
 			run = nil
 			h = nil
 			app.setState(nil, nil)
 
+			// The data which was not sent is harvested once the
+			// application has reconnected.
+			if !resp.IsDisconnect() {
+				for _, data := range e.unsent {
+					pending = app.addPending(pending, data)
+				}
+			}
+
 			if resp.IsDisconnect() {
 				app.setState(nil, resp.Err)
 				app.Error("application disconnected", map[string]interface{}{
@@ -279,6 +485,11 @@ func (app *app) process() {
 					"app": app.config.AppName,
 				})
 				go app.connectRoutine()
+			} else if resp.IsFailover() {
+				app.Info("application reconnecting to new collector host", map[string]interface{}{
+					"app": app.config.AppName,
+				})
+				go app.connectRoutine()
 			}
 		case run = <-app.connectChan:
 			if shouldUseTraceObserver(run.Config) {
@@ -291,22 +502,146 @@ func (app *app) process() {
 					"server-SpanEvents.Enabled":        run.Config.SpanEvents.Enabled,
 				})
 			}
-			h = newHarvest(time.Now(), run.harvestConfig)
+			h = newHarvest(app.harvestNow(), run.harvestConfig)
+			for _, data := range pending {
+				data.MergeIntoHarvest(h)
+			}
+			pending = nil
 			app.setState(run, nil)
 
 			app.Info("application connected", map[string]interface{}{
 				"app": app.config.AppName,
 				"run": run.Reply.RunID.String(),
 			})
+			if effects := securityPolicyEffects(run.Config, run.Reply.SecurityPolicies); len(effects) > 0 {
+				app.Info("agent behavior restricted by security settings", map[string]interface{}{
+					"app":     app.config.AppName,
+					"effects": securityPolicySummary(effects),
+				})
+			}
 			processConnectMessages(run, app)
 		}
 	}
 }
 
+// readyHarvest returns the data of h which is ready to be harvested at now,
+// or nil if no data is ready.
+func (app *app) readyHarvest(h *harvest, now time.Time) *harvest {
+	ready := h.Ready(now)
+	if nil != ready && nil != ready.Metrics {
+		app.aggregates.MergeIntoHarvest(ready)
+	}
+	return ready
+}
+
+// harvestRequest is sent by tick to the processor, which replies with the
+// data ready to be harvested.
+type harvestRequest chan harvestReply
+
+type harvestReply struct {
+	now   time.Time
+	ready *harvest
+	run   *appRun
+}
+
+// periodicTask is a function run once per period by tick.
+type periodicTask struct {
+	period time.Duration
+	last   time.Time
+	fn     func(now time.Time)
+}
+
+// every calls fn once per period until the application is shut down.  When
+// ManualHarvest is enabled fn is called by tick, and otherwise by a
+// goroutine started for it.
+func (app *app) every(period time.Duration, fn func(now time.Time)) {
+	if app.config.ManualHarvest.Enabled {
+		app.tickLock.Lock()
+		defer app.tickLock.Unlock()
+
+		app.tasks = append(app.tasks, &periodicTask{period: period, last: time.Now(), fn: fn})
+		return
+	}
+	go func() {
+		t := time.NewTicker(period)
+		defer t.Stop()
+
+		for {
+			select {
+			case now := <-t.C:
+				fn(now)
+			case <-app.shutdownStarted:
+				return
+			}
+		}
+	}()
+}
+
+// tick harvests the data which is ready to be harvested, and runs the
+// periodic tasks which are due, using the calling goroutine.  It is used in
+// place of the agent's own harvest scheduling when ManualHarvest is enabled.
+func (app *app) tick() {
+	if nil == app || !app.config.Enabled || app.config.ServerlessMode.Enabled ||
+		!app.config.ManualHarvest.Enabled {
+		return
+	}
+	app.tickLock.Lock()
+	defer app.tickLock.Unlock()
+
+	now := time.Now()
+	for _, task := range app.tasks {
+		if now.Sub(task.last) >= task.period {
+			task.last = now
+			task.fn(now)
+		}
+	}
+
+	req := make(harvestRequest, 1)
+	select {
+	case app.harvestRequests <- req:
+	case <-app.shutdownStarted:
+		return
+	}
+	if reply := <-req; nil != reply.ready {
+		app.doHarvest(reply.ready, reply.now, reply.run)
+	}
+
+	for _, sub := range app.rollups.all() {
+		sub.tick()
+	}
+}
+
+// harvestNow returns the time used to decide when data is harvested.
+func (app *app) harvestNow() time.Time {
+	if nil != app.config.HarvestClock {
+		return app.config.HarvestClock()
+	}
+	return time.Now()
+}
+
+// SecurityPolicyEffects implements newrelic.Application's
+// SecurityPolicyEffects.
+func (app *app) SecurityPolicyEffects() []SecurityPolicyEffect {
+	if nil == app {
+		return nil
+	}
+	run, _ := app.getState()
+	return securityPolicyEffects(run.Config, run.Reply.SecurityPolicies)
+}
+
 func (app *app) Shutdown(timeout time.Duration) {
 	if nil == app {
 		return
 	}
+
+	// End transactions which are still in progress so that they are
+	// included in the final harvest rather than discarded.
+	if n := app.inFlight.truncate(); n > 0 {
+		app.Info("truncated in-progress transactions", map[string]interface{}{
+			"count": n,
+		})
+	}
+
 	if !app.config.Enabled {
 		return
 	}
@@ -314,42 +649,63 @@ func (app *app) Shutdown(timeout time.Duration) {
 		return
 	}
 
+	rollupsDone := make(chan struct{})
+	go func() {
+		app.rollups.shutdown(timeout)
+		close(rollupsDone)
+	}()
+
+	// The processor and this goroutine share the deadline, so that the
+	// time spent connecting a short-lived application is not waited for
+	// twice.
+	deadline := time.Now().Add(timeout)
 	select {
-	case app.initiateShutdown <- timeout:
+	case app.initiateShutdown <- deadline:
 	default:
 	}
 
-	// Block until shutdown is done or timeout occurs.
-	t := time.NewTimer(timeout)
+	// Block until shutdown is done or the deadline passes.
+	t := time.NewTimer(deadline.Sub(time.Now()))
 	select {
 	case <-app.shutdownComplete:
 	case <-t.C:
 	}
 	t.Stop()
+	<-rollupsDone
 
 	app.Info("application shutdown", map[string]interface{}{
 		"app": app.config.AppName,
 	})
 }
 
-func runSampler(app *app, period time.Duration) {
-	previous := getSystemSample(time.Now(), app)
-	t := time.NewTicker(period)
-	for {
-		select {
-		case now := <-t.C:
-			current := getSystemSample(now, app)
-			run, _ := app.getState()
-			app.Consume(run.Reply.RunID, getSystemStats(systemSamples{
-				Previous: previous,
-				Current:  current,
-			}))
-			previous = current
-		case <-app.shutdownStarted:
-			t.Stop()
-			return
-		}
+// runtimeSampler records the runtime statistics of the period since its
+// previous sample.
+type runtimeSampler struct {
+	app        *app
+	previous   *systemSample
+	gcReporter *gcTuningReporter
+}
+
+func newRuntimeSampler(app *app, now time.Time) *runtimeSampler {
+	return &runtimeSampler{
+		app:        app,
+		previous:   getSystemSample(now, app),
+		gcReporter: &gcTuningReporter{},
+	}
+}
+
+func (s *runtimeSampler) sample(now time.Time) {
+	current := getSystemSample(now, s.app)
+	run, _ := s.app.getState()
+	s.app.Consume(run.Reply.RunID, getSystemStats(systemSamples{
+		Previous: s.previous,
+		Current:  current,
+	}))
+	// Wait until connected so the initial settings are not dropped.
+	if "" != run.Reply.RunID {
+		recordGCTuning(s.app, s.gcReporter, readGCTuning())
 	}
+	s.previous = current
 }
 
 func (app *app) WaitForConnection(timeout time.Duration) error {
@@ -362,6 +718,7 @@ func (app *app) WaitForConnection(timeout time.Duration) error {
 	if app.config.ServerlessMode.Enabled {
 		return nil
 	}
+	app.startConnect()
 	deadline := time.Now().Add(timeout)
 	pollPeriod := 50 * time.Millisecond
 
@@ -391,27 +748,47 @@ func newApp(c config) *app {
 	if nil == transport {
 		transport = collectorDefaultTransport
 	}
+	if "" != c.Gateway.Address {
+		transport = newGatewayTransport(c.Gateway.Address)
+	}
+	lg := newReloadableLogger(c.Logger)
+	c.Logger = lg
+	c.utilization = newUtilizationCache(c.Utilization.CacheTTL, c.utilizationWait(), func() *utilization.Data {
+		return utilization.Gather(c.utilizationConfig(), c.Logger)
+	}, func() *utilization.Data {
+		return utilization.GatherLocal(c.utilizationConfig(), c.Logger)
+	})
 	app := &app{
-		Logger:         c.Logger,
-		config:         c,
-		placeholderRun: newPlaceholderAppRun(c),
+		Logger:          lg,
+		logger:          lg,
+		config:          c,
+		reloaded:        c,
+		placeholderRun:  newPlaceholderAppRun(c),
+		aggregates:      newMetricAggregates(),
+		recentDurations: newRecentDurations(),
+		hosts:           newCollectorHosts(c.preconnectHosts()),
 
 		// This channel must be buffered since Shutdown makes a
 		// non-blocking send attempt.
-		initiateShutdown: make(chan time.Duration, 1),
+		initiateShutdown: make(chan time.Time, 1),
 
 		shutdownStarted:    make(chan struct{}),
 		shutdownComplete:   make(chan struct{}),
 		connectChan:        make(chan *appRun, 1),
-		collectorErrorChan: make(chan rpmResponse, 1),
+		collectorErrorChan: make(chan collectorError, 1),
 		dataChan:           make(chan appData, appDataChanSize),
+		harvestRequests:    make(chan harvestRequest),
 		rpmControls: rpmControls{
 			License: c.License,
 			Client: &http.Client{
 				Transport: transport,
 				Timeout:   collectorTimeout,
 			},
-			Logger: c.Logger,
+			Logger:          c.Logger,
+			Sender:          c.HarvestSender,
+			Gateway:         "" != c.Gateway.Address,
+			GatewayToken:    c.Gateway.Token,
+			ResponseHandler: c.CollectorResponseHandler,
 		},
 	}
 
@@ -421,17 +798,55 @@ func newApp(c config) *app {
 		"enabled":      app.config.Enabled,
 		"grpc-version": grpcVersion,
 	})
+	if app.config.StartupSummary.Enabled {
+		app.Info("agent configuration summary", app.config.startupSummary())
+	}
+	if "" != app.config.Spool.Directory && app.config.Enabled {
+		spool, err := newHarvestSpool(app.config.Spool.Directory, app.config.Spool.MaxBytes)
+		if nil != err {
+			app.Warn("unable to create spool directory", map[string]interface{}{
+				"directory": app.config.Spool.Directory,
+				"error":     err.Error(),
+			})
+		} else {
+			app.spool = spool
+		}
+	}
 
+	if !app.config.ServerlessMode.Enabled {
+		app.rollups.connect(app)
+	}
 	if app.config.Enabled {
+		if app.config.ContentionProfiling.Enabled {
+			enableContentionProfiling(app.config.ContentionProfiling.BlockProfileRate,
+				app.config.ContentionProfiling.MutexProfileFraction)
+		}
 		if app.config.ServerlessMode.Enabled {
 			reply := newServerlessConnectReply(c)
 			app.run = newAppRun(c, reply)
 			app.serverless = newServerlessHarvest(c.Logger, os.Getenv)
 		} else {
+			if "" == app.config.OTLP.Endpoint {
+				// Gather the utilization data before connecting,
+				// so that the connect rarely waits for it.
+				app.config.utilization.start()
+			}
 			go app.process()
-			go app.connectRoutine()
+			if !app.config.ShortLived.Enabled {
+				go app.connectRoutine()
+			}
 			if app.config.RuntimeSampler.Enabled {
-				go runSampler(app, runtimeSamplerPeriod)
+				app.every(runtimeSamplerPeriod, newRuntimeSampler(app, time.Now()).sample)
+			}
+			if app.config.ScalingSignal.Enabled {
+				app.scalingQueueTimes = &queueTimes{}
+				app.every(scalingSignalPeriod, scalingSignal(app))
+			}
+			if app.config.TransactionCheckpoints.Enabled {
+				app.every(app.config.TransactionCheckpoints.Interval, app.recordCheckpoints)
+			}
+			if nil != app.config.LicenseProvider && app.config.LicenseRefreshPeriod > 0 {
+				app.every(app.config.LicenseRefreshPeriod, app.refreshLicense)
 			}
 		}
 	}
@@ -455,6 +870,7 @@ func (app *app) HarvestTesting(replyfn func(*internal.ConnectReply)) {
 		app.placeholderRun = newAppRun(app.config, reply)
 	}
 	app.testHarvest = newHarvest(time.Now(), app.placeholderRun.harvestConfig)
+	app.rollups.harvestTesting(app)
 }
 
 func (app *app) getState() (*appRun, error) {
@@ -489,10 +905,12 @@ func (app *app) setObserver(observer traceObserver) {
 }
 
 func newTransaction(thd *thread) *Transaction {
-	return &Transaction{
+	txn := &Transaction{
 		Private: thd,
 		thread:  thd,
 	}
+	trackHandle(txn)
+	return txn
 }
 
 // StartTransaction implements newrelic.Application's StartTransaction.
@@ -519,16 +937,16 @@ func (app *app) RecordCustomEvent(eventType string, params map[string]interface{
 		return errHighSecurityEnabled
 	}
 
-	if !app.config.CustomInsightsEvents.Enabled {
+	run, _ := app.getState()
+	if !run.Config.CustomInsightsEvents.Enabled {
 		return errCustomEventsDisabled
 	}
 
-	event, e := createCustomEvent(eventType, params, time.Now())
+	event, e := run.AttributeConfig.attributeLimits().createCustomEvent(eventType, params, time.Now())
 	if nil != e {
 		return e
 	}
 
-	run, _ := app.getState()
 	if !run.Reply.CollectCustomEvents {
 		return errCustomEventsRemoteDisabled
 	}
@@ -539,6 +957,51 @@ func (app *app) RecordCustomEvent(eventType string, params map[string]interface{
 
 	app.Consume(run.Reply.RunID, event)
 
+	for _, d := range run.Config.CustomInsightsEvents.Derivations {
+		if d.SourceType != eventType {
+			continue
+		}
+		derived := d.derive(params)
+		if nil == derived {
+			continue
+		}
+		e, err := run.AttributeConfig.attributeLimits().createCustomEvent(d.EventType, derived, event.timestamp)
+		if nil != err {
+			app.Warn("unable to record derived custom event", map[string]interface{}{
+				"event-type": d.EventType,
+				"source":     eventType,
+				"reason":     err.Error(),
+			})
+			continue
+		}
+		app.Consume(run.Reply.RunID, e)
+	}
+
+	return nil
+}
+
+// RecordLog implements newrelic.Application's RecordLog.
+func (app *app) RecordLog(log LogData) error {
+	if nil == app {
+		return nil
+	}
+	now := time.Now()
+	if txn := FromContext(log.Context); nil != txn && nil != txn.thread {
+		if err := txn.thread.RecordLog(log, now); errAlreadyEnded != err {
+			return err
+		}
+		// Logs written after the transaction has ended are recorded
+		// without being linked to it.
+	}
+
+	run, _ := app.getState()
+	event, err := createLogEvent(run.Config, log, now)
+	if nil != err || nil == event {
+		return err
+	}
+	event.priority = newPriority()
+	app.Consume(run.Reply.RunID, event)
+
 	return nil
 }
 
@@ -574,6 +1037,29 @@ func (app *app) RecordCustomMetric(name string, value float64) error {
 	return nil
 }
 
+// RecordCustomMetricSummary implements newrelic.Application's
+// RecordCustomMetricSummary.
+func (app *app) RecordCustomMetricSummary(name string, summary CustomMetricSummary) error {
+	if nil == app {
+		return nil
+	}
+	if app.config.ServerlessMode.Enabled {
+		return errMetricServerless
+	}
+	if "" == name {
+		return errMetricNameEmpty
+	}
+	if 0 == summary.Count() {
+		return nil
+	}
+	run, _ := app.getState()
+	app.Consume(run.Reply.RunID, customMetricSummary{
+		RawInputName: name,
+		Data:         summary.data,
+	})
+	return nil
+}
+
 var (
 	_ internal.ServerlessWriter = &app{}
 )
@@ -587,11 +1073,13 @@ func (app *app) Consume(id internal.AgentRunID, data harvestable) {
 	app.serverless.Consume(data)
 
 	if nil != app.testHarvest {
+		app.testHarvestLock.Lock()
 		data.MergeIntoHarvest(app.testHarvest)
+		app.testHarvestLock.Unlock()
 		return
 	}
 
-	if "" == id {
+	if "" == id && !app.config.ShortLived.Enabled {
 		return
 	}
 
@@ -627,11 +1115,17 @@ func (app *app) ExpectTxnEvents(t internal.Validator, want []internal.WantEvent)
 
 func (app *app) ExpectMetrics(t internal.Validator, want []internal.WantMetric) {
 	t = extendValidator(t, "metrics")
+	app.testHarvestLock.Lock()
+	app.aggregates.MergeIntoHarvest(app.testHarvest)
+	app.testHarvestLock.Unlock()
 	expectMetrics(t, app.testHarvest.Metrics, want)
 }
 
 func (app *app) ExpectMetricsPresent(t internal.Validator, want []internal.WantMetric) {
 	t = extendValidator(t, "metrics")
+	app.testHarvestLock.Lock()
+	app.aggregates.MergeIntoHarvest(app.testHarvest)
+	app.testHarvestLock.Unlock()
 	expectMetricsPresent(t, app.testHarvest.Metrics, want)
 }
 
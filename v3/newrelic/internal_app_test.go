@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/logger"
 )
 
 func TestConnectBackoff(t *testing.T) {
@@ -71,3 +74,94 @@ func TestConfigOptionError(t *testing.T) {
 		t.Error("app not nil")
 	}
 }
+
+func TestHarvestRestartKeepsUnsentData(t *testing.T) {
+	requests := 0
+	cfg := config{Config: defaultConfig()}
+	cfg.Enabled = false
+	cfg.Logger = logger.ShimLogger{}
+	cfg.HarvestSender = harvestSenderFunc(func(req HarvestRequest) (HarvestResponse, error) {
+		requests++
+		return HarvestResponse{StatusCode: 409}, nil
+	})
+	app := newApp(cfg)
+	reply := internal.ConnectReplyDefaults()
+	reply.RunID = "run1"
+	reply.Collector = "collector.com"
+	run := newAppRun(cfg, reply)
+
+	harvest := func() {
+		h := newHarvest(time.Now(), dfltHarvestCfgr)
+		h.Metrics.addCount("Custom/metric", 1, forced)
+		h.CustomEvents.Add(&customEvent{eventType: "myEvent"})
+		app.doHarvest(h, time.Now(), run)
+	}
+
+	harvest()
+	if requests != 1 {
+		t.Error("payloads sent after the run ended", requests)
+	}
+	select {
+	case e := <-app.collectorErrorChan:
+		if !e.resp.IsRestartException() {
+			t.Error(e.resp)
+		}
+		// The payload which was rejected is kept along with the ones
+		// which were not sent.
+		if len(e.unsent) < 2 {
+			t.Error("unsent data dropped", len(e.unsent))
+		}
+	default:
+		t.Fatal("restart not reported")
+	}
+
+	// A concurrent harvest of the same run does not report the restart
+	// again.
+	harvest()
+	select {
+	case e := <-app.collectorErrorChan:
+		t.Error("restart reported twice", e.resp)
+	default:
+	}
+}
+
+func TestManualHarvestRunsPeriodicTasksDuringTick(t *testing.T) {
+	app, err := NewApplication(
+		ConfigAppName("my app"),
+		ConfigLicense(testLicenseKey),
+		func(cfg *Config) {
+			cfg.HarvestSender = &collectorSender{}
+			cfg.ManualHarvest.Enabled = true
+			cfg.ScalingSignal.Enabled = true
+			cfg.TransactionCheckpoints.Enabled = true
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(10 * time.Second)
+
+	// The runtime sampler, the scaling signal, and the transaction
+	// checkpoints are run by tick rather than by their own goroutines.
+	app.app.tickLock.Lock()
+	tasks := len(app.app.tasks)
+	app.app.tickLock.Unlock()
+	if 3 != tasks {
+		t.Error(tasks)
+	}
+
+	var ran int
+	app.app.every(time.Hour, func(time.Time) { ran++ })
+	app.Tick()
+	if 0 != ran {
+		t.Error("task run before its period elapsed", ran)
+	}
+	app.app.tickLock.Lock()
+	app.app.tasks[tasks].last = time.Now().Add(-time.Hour)
+	app.app.tickLock.Unlock()
+	app.Tick()
+	app.Tick()
+	if 1 != ran {
+		t.Error(ran)
+	}
+}
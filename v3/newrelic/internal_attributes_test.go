@@ -8,6 +8,7 @@ import (
 	"math"
 	"net/http"
 	"net/url"
+	"strings"
 	"testing"
 
 	"github.com/newrelic/go-agent/v3/internal"
@@ -224,6 +225,7 @@ var (
 		AttributeRequestContentLength:   753,
 		AttributeRequestHost:            "my_domain.com",
 		AttributeRequestURI:             "/hello",
+		AttributeHTTPFlavor:             "1.1",
 	}
 	// Agent attributes expected in errors and traces from usualAttributeTestTransaction.
 	agent2 = mergeAttributes(agent1, map[string]interface{}{
@@ -435,6 +437,7 @@ var (
 		AttributeRequestUserAgent,
 		AttributeRequestUserAgentDeprecated,
 		AttributeRequestReferer,
+		AttributeHTTPFlavor,
 	}
 )
 
@@ -1032,3 +1035,161 @@ func TestAddSpanAttr_ValidatedValues(t *testing.T) {
 		},
 	})
 }
+
+func TestAttributeLimitsValueLength(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.AttributeLimits.MaxValueLength = 1024
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	long := strings.Repeat("a", 2048)
+	txn.AddAttribute("fits", strings.Repeat("b", 1024))
+	txn.AddAttribute("long", long)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{
+			"fits": strings.Repeat("b", 1024),
+			"long": long[:1024],
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Attributes/Truncated", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestAttributeLimitsCountAndKeyLength(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.AttributeLimits.MaxCount = 1
+		cfg.AttributeLimits.MaxKeyLength = 4
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("zip", 1)
+	txn.AddAttribute("zap", 2)
+	app.expectSingleLoggedError(t, "unable to add attribute", map[string]interface{}{
+		"reason": userAttributeLimitErr{key: "zap", limit: 1}.Error(),
+	})
+	txn.AddAttribute("zip", 3)
+	txn.AddAttribute("toolong", 4)
+	app.expectSingleLoggedError(t, "unable to add attribute", map[string]interface{}{
+		"reason": invalidAttributeKeyErr{key: "toolong", limit: 4}.Error(),
+	})
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": 3,
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Attributes/Dropped", Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestAttributeLimitsCustomEvent(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.AttributeLimits.MaxValueLength = 300
+	}
+	app := testApp(nil, cfgfn, t)
+	long := strings.Repeat("a", 500)
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"long": long})
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"long": long[:300],
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Attributes/Truncated", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestAttributeInvalidStringsCoerced(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("valid", "caf\u00e9\tbar\n")
+	txn.AddAttribute("invalid", "a\xffb\x00c\u0085d")
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{
+			"valid":   "caf\u00e9\tbar\n",
+			"invalid": "a\ufffdb\ufffdc\ufffdd",
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Attributes/Coerced", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+
+	app.RecordCustomEvent("myEvent", map[string]interface{}{"invalid": "\xc3("})
+	app.expectNoLoggedErrors(t)
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"invalid": "\ufffd(",
+		},
+	}})
+}
+
+func TestAttributeInvalidStringsRejected(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.AttributeLimits.RejectInvalidStrings = true
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("invalid", "a\xffb")
+	app.expectSingleLoggedError(t, "unable to add attribute", map[string]interface{}{
+		"reason": invalidStringAttrValue{key: "invalid"}.Error(),
+	})
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: map[string]interface{}{},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/Attributes/Dropped", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestAgentAttributeInvalidStringsCoerced(t *testing.T) {
+	attrs := make(agentAttributes)
+	attrs.Add(AttributeRequestUserAgent, "bot\xff\x1b[0m", nil)
+	if v := attrs[AttributeRequestUserAgent].stringVal; "bot\ufffd\ufffd[0m" != v {
+		t.Error(v)
+	}
+}
+
+func TestAttributeLimitsInvalid(t *testing.T) {
+	for _, fn := range []func(cfg *Config){
+		func(cfg *Config) { cfg.AttributeLimits.MaxCount = attributeUserLimit + 1 },
+		func(cfg *Config) { cfg.AttributeLimits.MaxKeyLength = attributeKeyLengthLimit + 1 },
+		func(cfg *Config) { cfg.AttributeLimits.MaxValueLength = attributeValueLengthMax + 1 },
+		func(cfg *Config) { cfg.AttributeLimits.MaxValueLength = -1 },
+	} {
+		cfg := defaultConfig()
+		cfg.License = "0123456789012345678901234567890123456789"
+		cfg.AppName = "my app"
+		fn(&cfg)
+		if err := cfg.validate(); err != errAttributeLimits {
+			t.Error(err)
+		}
+	}
+}
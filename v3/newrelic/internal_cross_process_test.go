@@ -83,6 +83,7 @@ func TestCrossProcessWriteHeaderSuccess(t *testing.T) {
 		Intrinsics: catIntrinsics,
 		AgentAttributes: map[string]interface{}{
 			"request.method":   "GET",
+			"http.flavor":      "1.1",
 			"httpResponseCode": 200,
 			"http.statusCode":  200,
 			"request.uri":      "newrelic.com",
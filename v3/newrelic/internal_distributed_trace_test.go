@@ -152,6 +152,24 @@ func TestPayloadConnection(t *testing.T) {
 	}})
 }
 
+func TestAcceptDistributedTraceHeadersBeforeConnect(t *testing.T) {
+	// Test that the configured account is used to accept payloads before
+	// the application connects.
+	app := testApp(nil, func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.DistributedTracer.AccountID = "123"
+	}, t)
+	hdrs := makeHeaders(t)
+	txn := app.StartTransaction("hello")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Supportability/TraceContext/Accept/Success", Scope: "", Forced: true, Data: nil},
+	})
+}
+
 func TestAcceptMultiple(t *testing.T) {
 	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
 	hdrs := getDTHeaders(app.Application)
@@ -64,7 +64,7 @@ func TestStackTrace(t *testing.T) {
 	}
 
 	for idx, tc := range testcases {
-		data, err := errDataFromError(tc.Error)
+		data, err := errDataFromError(tc.Error, defaultAttributeLimits)
 		if err != nil {
 			t.Errorf("testcase %d: got error: %v", idx, err)
 			continue
@@ -5,6 +5,8 @@ package newrelic
 
 import (
 	"encoding/json"
+	"fmt"
+	"reflect"
 	"runtime"
 	"strconv"
 	"testing"
@@ -574,6 +576,99 @@ func TestTooManyExtraErrorAttributes(t *testing.T) {
 	app.ExpectMetrics(t, backgroundMetrics)
 }
 
+func TestNestedExtraErrorAttributesFlattened(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(Error{
+		Message: "my msg",
+		Class:   "my class",
+		Attributes: map[string]interface{}{
+			"zip": "zap",
+			"details": map[string]interface{}{
+				"db":      map[string]string{"host": "db-1", "name": "orders"},
+				"retries": 2,
+				"invalid": struct{}{},
+			},
+		},
+	})
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	attrs := map[string]interface{}{
+		"zip":             "zap",
+		"details.db.host": "db-1",
+		"details.db.name": "orders",
+		"details.retries": 2,
+	}
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName:        "OtherTransaction/Go/hello",
+		Msg:            "my msg",
+		Klass:          "my class",
+		UserAttributes: attrs,
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "my class",
+			"error.message":   "my msg",
+			"transactionName": "OtherTransaction/Go/hello",
+		},
+		UserAttributes: attrs,
+	}})
+	app.ExpectMetrics(t, backgroundErrorMetrics)
+}
+
+func TestFlattenErrorAttributesDepth(t *testing.T) {
+	nested := map[string]interface{}{
+		"a": map[string]interface{}{
+			"b": map[string]interface{}{"c": 1},
+			"d": 2,
+		},
+		"e": 3,
+	}
+	testcases := []struct {
+		depth  int
+		expect map[string]interface{}
+	}{
+		{depth: 0, expect: map[string]interface{}{"e": 3}},
+		{depth: 1, expect: map[string]interface{}{"a.d": 2, "e": 3}},
+		{depth: 2, expect: map[string]interface{}{"a.b.c": 1, "a.d": 2, "e": 3}},
+	}
+	for _, tc := range testcases {
+		limits := defaultAttributeLimits
+		limits.errorDepth = tc.depth
+		data, err := errDataFromError(Error{Message: "my msg", Attributes: nested}, limits)
+		if nil != err {
+			t.Fatal(tc.depth, err)
+		}
+		if !reflect.DeepEqual(tc.expect, data.ExtraAttributes) {
+			t.Errorf("depth %d: expected %v got %v", tc.depth, tc.expect, data.ExtraAttributes)
+		}
+	}
+}
+
+func TestFlattenErrorAttributesLimit(t *testing.T) {
+	nested := make(map[string]int)
+	for i := 0; i < attributeErrorLimit; i++ {
+		nested[fmt.Sprintf("%02d", i)] = i
+	}
+	data, err := errDataFromError(Error{
+		Message:    "my msg",
+		Attributes: map[string]interface{}{"a": 1, "b": nested},
+	}, defaultAttributeLimits)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if len(data.ExtraAttributes) != attributeErrorLimit {
+		t.Fatal(len(data.ExtraAttributes))
+	}
+	if _, ok := data.ExtraAttributes["a"]; !ok {
+		t.Error("top level attribute missing", data.ExtraAttributes)
+	}
+	last := fmt.Sprintf("b.%02d", attributeErrorLimit-1)
+	if _, ok := data.ExtraAttributes[last]; ok {
+		t.Error("attribute beyond the limit kept", data.ExtraAttributes)
+	}
+}
+
 type basicError struct{}
 
 func (e basicError) Error() string { return "something went wrong" }
@@ -592,6 +687,15 @@ func (e withClassAndCause) Error() string      { return e.cause.Error() }
 func (e withClassAndCause) Unwrap() error      { return e.cause }
 func (e withClassAndCause) ErrorClass() string { return e.class }
 
+type withCode struct {
+	cause error
+	code  string
+}
+
+func (e withCode) Error() string     { return e.cause.Error() }
+func (e withCode) Unwrap() error     { return e.cause }
+func (e withCode) ErrorCode() string { return e.code }
+
 type withCause struct{ cause error }
 
 func (e withCause) Error() string { return e.cause.Error() }
@@ -620,7 +724,7 @@ func TestErrorClass(t *testing.T) {
 	}
 
 	for idx, tc := range testcases {
-		data, err := errDataFromError(tc.Error)
+		data, err := errDataFromError(tc.Error, defaultAttributeLimits)
 		if err != nil {
 			t.Errorf("testcase %d: got error: %v", idx, err)
 			continue
@@ -631,6 +735,82 @@ func TestErrorClass(t *testing.T) {
 	}
 }
 
+func TestErrorCode(t *testing.T) {
+	// First choice is any ErrorCode() of the immediate error.
+	// Second choice is any ErrorCode() of the error's cause.
+	testcases := []struct {
+		Error  error
+		Expect string
+	}{
+		{Error: basicError{}, Expect: ""},
+		{Error: withCode{cause: basicError{}, code: "zap"}, Expect: "zap"},
+		{Error: withCode{cause: Error{Code: "zap"}, code: "zip"}, Expect: "zip"},
+		{Error: withCode{cause: Error{Code: "zap"}, code: ""}, Expect: "zap"},
+		{Error: wrapError(withCode{cause: basicError{}, code: "zap"}), Expect: ""},
+		{Error: wrapError(Error{Code: "zap"}), Expect: "zap"},
+	}
+
+	for idx, tc := range testcases {
+		data, err := errDataFromError(tc.Error, defaultAttributeLimits)
+		if err != nil {
+			t.Errorf("testcase %d: got error: %v", idx, err)
+			continue
+		}
+		if data.Code != tc.Expect {
+			t.Errorf("testcase %d: expected %s got %s", idx, tc.Expect, data.Code)
+		}
+	}
+}
+
+func TestNewrelicErrorCode(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(Error{
+		Message: "my msg",
+		Class:   "my class",
+		Code:    "PAYMENT_DECLINED",
+	})
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectErrors(t, []internal.WantError{{
+		TxnName: "OtherTransaction/Go/hello",
+		Msg:     "my msg",
+		Klass:   "my class",
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "my class",
+			"error.message":   "my msg",
+			"error.code":      "PAYMENT_DECLINED",
+			"guid":            internal.MatchAnything,
+			"priority":        internal.MatchAnything,
+			"sampled":         internal.MatchAnything,
+			"spanId":          internal.MatchAnything,
+			"traceId":         internal.MatchAnything,
+			"transactionName": "OtherTransaction/Go/hello",
+		},
+	}})
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"priority":         internal.MatchAnything,
+			"guid":             internal.MatchAnything,
+			"transactionId":    internal.MatchAnything,
+			"nr.entryPoint":    true,
+			"traceId":          internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			"error.class":   "my class",
+			"error.message": "my msg",
+			"error.code":    "PAYMENT_DECLINED",
+		},
+	}})
+}
+
 func TestNoticeErrorSpanID(t *testing.T) {
 	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
 	txn := app.StartTransaction("hello")
@@ -8,11 +8,15 @@ import (
 	"io"
 	"net"
 	"net/http"
+	"time"
 )
 
 type replacementResponseWriter struct {
 	thd      *thread
 	original http.ResponseWriter
+	// timing, if not nil, accumulates the time spent writing the
+	// response.  It is set by WrapServer.
+	timing *requestTiming
 }
 
 func (rw *replacementResponseWriter) Header() http.Header {
@@ -26,6 +30,9 @@ func (rw *replacementResponseWriter) Write(b []byte) (n int, err error) {
 	// times; see also the commentary in addCrossProcessHeaders().
 	addCrossProcessHeaders(rw.thd.txn, hdr)
 
+	if nil != rw.timing {
+		defer rw.timing.addWrite(time.Now())
+	}
 	n, err = rw.original.Write(b)
 
 	headersJustWritten(rw.thd, http.StatusOK, hdr)
@@ -47,12 +54,18 @@ func (rw *replacementResponseWriter) CloseNotify() <-chan bool {
 	return rw.original.(http.CloseNotifier).CloseNotify()
 }
 func (rw *replacementResponseWriter) Flush() {
+	if nil != rw.timing {
+		defer rw.timing.addWrite(time.Now())
+	}
 	rw.original.(http.Flusher).Flush()
 }
 func (rw *replacementResponseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
 	return rw.original.(http.Hijacker).Hijack()
 }
 func (rw *replacementResponseWriter) ReadFrom(r io.Reader) (int64, error) {
+	if nil != rw.timing {
+		defer rw.timing.addWrite(time.Now())
+	}
 	return rw.original.(io.ReaderFrom).ReadFrom(r)
 }
 
@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/url"
 	"testing"
@@ -137,7 +138,9 @@ func TestSetWebRequestHTTPRequest(t *testing.T) {
 		{Name: "DurationByCaller/Unknown/Unknown/Unknown/HTTP/allWeb", Scope: "", Forced: false, Data: nil},
 	})
 	app.ExpectTxnEvents(t, []internal.WantEvent{{
-		AgentAttributes: sampleRequestAgentAttributes,
+		AgentAttributes: mergeAttributes(sampleRequestAgentAttributes, map[string]interface{}{
+			AttributeHTTPFlavor: "1.1",
+		}),
 		Intrinsics: map[string]interface{}{
 			"name":             "WebTransaction/Go/hello",
 			"guid":             internal.MatchAnything,
@@ -288,3 +291,74 @@ func TestSetWebRequestIncompleteRequest(t *testing.T) {
 		},
 	}})
 }
+
+func TestSetWebRequestHTTPClientIP(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.ClientIP.Enabled = true
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "https://www.newrelic.com", nil)
+	req.RemoteAddr = "192.0.2.123:5000"
+	req.TLS = &tls.ConnectionState{Version: tls.VersionTLS12, NegotiatedProtocol: "http/1.1"}
+	txn.SetWebRequestHTTP(req)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			AttributeRequestMethod:   "GET",
+			AttributeRequestURI:      "https://www.newrelic.com",
+			AttributeRequestHost:     "www.newrelic.com",
+			AttributeHTTPFlavor:      "1.1",
+			AttributeTLSVersion:      "1.2",
+			AttributeTLSALPN:         "http/1.1",
+			AttributeRequestClientIP: "192.0.2.0",
+		},
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+	}})
+}
+
+func TestSetWebRequestClientIPNotAnonymized(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.ClientIP.Enabled = true
+		cfg.ClientIP.Anonymize = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequest(WebRequest{RemoteAddr: "192.0.2.123:5000"})
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			AttributeRequestClientIP: "192.0.2.123",
+		},
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/hello",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+	}})
+}
+
+func TestSetWebRequestClientIPDisabled(t *testing.T) {
+	for _, cfgfn := range []func(*Config){
+		nil,
+		func(cfg *Config) {
+			cfg.ClientIP.Enabled = true
+			cfg.HighSecurity = true
+		},
+	} {
+		app := testApp(nil, cfgfn, t)
+		txn := app.StartTransaction("hello")
+		txn.SetWebRequest(WebRequest{RemoteAddr: "192.0.2.123:5000"})
+		txn.End()
+		app.ExpectTxnEvents(t, []internal.WantEvent{{
+			AgentAttributes: map[string]interface{}{},
+			Intrinsics: map[string]interface{}{
+				"name":             "WebTransaction/Go/hello",
+				"nr.apdexPerfZone": internal.MatchAnything,
+			},
+		}})
+	}
+}
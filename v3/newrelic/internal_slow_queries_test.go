@@ -411,6 +411,46 @@ func TestSlowQueryWithQueryParameters(t *testing.T) {
 	}})
 }
 
+func TestSlowQueryIntegrationAttributesExclude(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+		cfg.IntegrationAttributes.Exclude = map[string][]string{
+			"MySQL": {SpanAttributeDBStatement, SpanAttributeDBInstance, SpanAttributePeerAddress},
+		}
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	params := map[string]interface{}{
+		"str": "zap",
+	}
+	s1 := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+		QueryParameters:    params,
+		Host:               "db-server-1",
+		PortPathOrID:       "3306",
+		DatabaseName:       "production",
+	}
+	s1.End()
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:        1,
+		MetricName:   "Datastore/statement/MySQL/users/INSERT",
+		Query:        "'INSERT' on 'users' using 'MySQL'",
+		TxnName:      "WebTransaction/Go/hello",
+		TxnURL:       "/hello",
+		DatabaseName: "",
+		Host:         "",
+		PortPathOrID: "",
+		Params:       params,
+	}})
+}
+
 func TestSlowQueryHighSecurity(t *testing.T) {
 	cfgfn := func(cfg *Config) {
 		cfg.DatastoreTracer.SlowQuery.Threshold = 0
@@ -4,8 +4,10 @@
 package newrelic
 
 import (
+	"errors"
 	"net/http"
 	"testing"
+	"time"
 
 	"github.com/newrelic/go-agent/v3/internal"
 )
@@ -184,6 +186,92 @@ func TestSpanEventDatastoreExternal(t *testing.T) {
 	})
 }
 
+func TestSpanEventIntegrationAttributesExclude(t *testing.T) {
+	// Test that IntegrationAttributes.Exclude only removes attributes from
+	// the segments of the named integrations.
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.IntegrationAttributes.Exclude = map[string][]string{
+			"mongodb": {SpanAttributeDBStatement},
+			"HTTP":    {SpanAttributeHTTPURL},
+		}
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	for _, product := range []DatastoreProduct{DatastoreMongoDB, DatastoreMySQL} {
+		segment := DatastoreSegment{
+			StartTime:          txn.StartSegmentNow(),
+			Product:            product,
+			Collection:         "mycollection",
+			Operation:          "myoperation",
+			ParameterizedQuery: "myquery",
+		}
+		segment.End()
+	}
+	req, _ := http.NewRequest("GET", "http://example.com?ignore=me", nil)
+	s := StartExternalSegment(txn, req)
+	s.End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"sampled":   true,
+				"name":      "Datastore/statement/MongoDB/mycollection/myoperation",
+				"category":  "datastore",
+				"component": "MongoDB",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.collection": "mycollection",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"sampled":   true,
+				"name":      "Datastore/statement/MySQL/mycollection/myoperation",
+				"category":  "datastore",
+				"component": "MySQL",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"db.statement":  "myquery",
+				"db.collection": "mycollection",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"http.method": "GET",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
 func TestSpanEventAttributesDisabled(t *testing.T) {
 	// Test that SpanEvents.Attributes.Enabled correctly disables span
 	// attributes.
@@ -403,6 +491,7 @@ func TestSpanAttributesFromTxnExcludedOnTxn(t *testing.T) {
 		cfg.DistributedTracer.Enabled = true
 		cfg.TransactionEvents.Attributes.Exclude = []string{
 			AttributeRequestMethod,
+			AttributeHTTPFlavor,
 			AttributeRequestURI,
 			AttributeRequestHost,
 		}
@@ -438,6 +527,7 @@ func TestSpanAttributesFromTxnExcludedOnTxn(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":       "GET",
+				"http.flavor":          "1.1",
 				"request.uri":          "http://example.com",
 				"request.headers.host": "example.com",
 			},
@@ -473,6 +563,7 @@ func TestSpanAttributesFromTxnExcludedByDefault(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":       "GET",
+				"http.flavor":          "1.1",
 				"request.uri":          "http://example.com",
 				"request.headers.host": "example.com",
 			},
@@ -490,6 +581,7 @@ func TestSpanAttributesFromTxnExcludedByDefault(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":       "GET",
+				"http.flavor":          "1.1",
 				"request.uri":          "http://example.com",
 				"request.headers.host": "example.com",
 			},
@@ -519,6 +611,7 @@ func TestSpanAttributesFromTxnExcludedByDefault(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":       "GET",
+				"http.flavor":          "1.1",
 				"request.uri":          "http://example.com",
 				"request.headers.host": "example.com",
 			},
@@ -536,6 +629,7 @@ func TestSpanAttributesFromTxnExcludedByDefault(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":            "GET",
+				"http.flavor":               "1.1",
 				"request.uri":               "http://example.com",
 				"request.headers.userAgent": "sample user agent",
 				"request.headers.host":      "example.com",
@@ -554,6 +648,7 @@ func TestSpanAttributesFromTxnExcludedOnSpan(t *testing.T) {
 		cfg.DistributedTracer.Enabled = true
 		cfg.SpanEvents.Attributes.Exclude = []string{
 			AttributeRequestMethod,
+			AttributeHTTPFlavor,
 			AttributeRequestURI,
 			AttributeRequestHost,
 		}
@@ -576,6 +671,7 @@ func TestSpanAttributesFromTxnExcludedOnSpan(t *testing.T) {
 			UserAttributes: map[string]interface{}{},
 			AgentAttributes: map[string]interface{}{
 				"request.method":       "GET",
+				"http.flavor":          "1.1",
 				"request.uri":          "http://example.com",
 				"request.headers.host": "example.com",
 			},
@@ -606,6 +702,7 @@ func TestSpanAttributesFromTxnExcludedGlobally(t *testing.T) {
 		cfg.DistributedTracer.Enabled = true
 		cfg.Attributes.Exclude = []string{
 			AttributeRequestMethod,
+			AttributeHTTPFlavor,
 			AttributeRequestURI,
 			AttributeRequestHost,
 		}
@@ -1160,3 +1257,399 @@ func TestAddSpanAttributeSecurityPolicyDisablesParameters(t *testing.T) {
 		},
 	})
 }
+
+func TestSpanEventsErrorSamplingBudget(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleNothing()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.DistributedTracer.ErrorSamplingBudget = 1
+	}
+	app := testApp(replyfn, cfgfn, t)
+
+	txn := app.StartTransaction("first")
+	txn.StartSegment("beforeError").End()
+	txn.NoticeError(errors.New("oops"))
+	if !txn.IsSampled() {
+		t.Error("transaction with an error should be sampled")
+	}
+	txn.StartSegment("afterError").End()
+	txn.End()
+
+	// The budget has been used up.
+	txn = app.StartTransaction("second")
+	txn.NoticeError(errors.New("oops"))
+	if txn.IsSampled() {
+		t.Error("error sampling budget exceeded")
+	}
+	txn.End()
+
+	// Spans which ended before the error was noticed are kept as well.
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "Custom/beforeError",
+				"sampled":       true,
+				"category":      "generic",
+				"priority":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"parentId":      internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "Custom/afterError",
+				"sampled":       true,
+				"category":      "generic",
+				"priority":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"parentId":      internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/first",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"transactionId":    internal.MatchAnything,
+				"nr.entryPoint":    true,
+				"traceId":          internal.MatchAnything,
+				"transaction.name": "OtherTransaction/Go/first",
+			},
+			AgentAttributes: map[string]interface{}{
+				"error.class":   "*errors.errorString",
+				"error.message": "oops",
+			},
+		},
+	})
+}
+
+func TestSpanEventPropagateAttributes(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.PropagateAttributes = []string{"tenant", "user", "request.uri", "missing"}
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	txn.AddAttribute("tenant", "acme")
+	txn.AddAttribute("user", 123)
+	txn.AddAttribute("other", "not copied")
+	segment := txn.StartSegment("mySegment")
+	segment.AddAttribute("user", 456)
+	segment.End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "Custom/mySegment",
+				"sampled":       true,
+				"category":      "generic",
+				"priority":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"parentId":      internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				"tenant": "acme",
+				"user":   456,
+			},
+			AgentAttributes: map[string]interface{}{
+				"request.uri": "/hello",
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "WebTransaction/Go/hello",
+				"transaction.name": "WebTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"transactionId":    internal.MatchAnything,
+				"nr.entryPoint":    true,
+				"traceId":          internal.MatchAnything,
+			},
+			UserAttributes: map[string]interface{}{
+				"tenant": "acme",
+				"user":   123,
+				"other":  "not copied",
+			},
+			AgentAttributes: map[string]interface{}{
+				"request.method":                "GET",
+				"request.uri":                   "/hello",
+				"request.headers.accept":        "text/plain",
+				"request.headers.contentType":   "text/html; charset=utf-8",
+				"request.headers.contentLength": 753,
+				"request.headers.host":          "my_domain.com",
+				"http.flavor":                   "1.1",
+			},
+		},
+	})
+}
+
+func TestSpanEventPropagateAttributesExcluded(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.PropagateAttributes = []string{"tenant"}
+		cfg.SpanEvents.Attributes.Exclude = []string{"tenant"}
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("tenant", "acme")
+	segment := txn.StartSegment("mySegment")
+	segment.End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "Custom/mySegment",
+				"sampled":       true,
+				"category":      "generic",
+				"priority":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"parentId":      internal.MatchAnything,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"transactionId":    internal.MatchAnything,
+				"nr.entryPoint":    true,
+				"traceId":          internal.MatchAnything,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestLabelAttributes(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.Labels = map[string]string{"Server": "One", "DataCenter": "Primary"}
+		cfg.LabelAttributes.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	segment := txn.StartSegment("mySegment")
+	segment.End()
+	txn.End()
+	labels := map[string]interface{}{
+		"label.Server":     "One",
+		"label.DataCenter": "Primary",
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/hello",
+			"guid":     internal.MatchAnything,
+			"priority": internal.MatchAnything,
+			"sampled":  internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+		},
+		UserAttributes:  labels,
+		AgentAttributes: map[string]interface{}{},
+	}})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"name":          "Custom/mySegment",
+				"sampled":       true,
+				"category":      "generic",
+				"priority":      internal.MatchAnything,
+				"guid":          internal.MatchAnything,
+				"transactionId": internal.MatchAnything,
+				"traceId":       internal.MatchAnything,
+				"parentId":      internal.MatchAnything,
+			},
+			UserAttributes:  labels,
+			AgentAttributes: map[string]interface{}{},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"priority":         internal.MatchAnything,
+				"guid":             internal.MatchAnything,
+				"transactionId":    internal.MatchAnything,
+				"nr.entryPoint":    true,
+				"traceId":          internal.MatchAnything,
+			},
+			UserAttributes:  labels,
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestLabelAttributesHighSecurity(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.HighSecurity = true
+		cfg.Labels = map[string]string{"Server": "One"}
+		cfg.LabelAttributes.Enabled = true
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":     "OtherTransaction/Go/hello",
+			"guid":     internal.MatchAnything,
+			"priority": internal.MatchAnything,
+			"sampled":  internal.MatchAnything,
+			"traceId":  internal.MatchAnything,
+		},
+		UserAttributes:  map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestSpanEventsDroppedByTransaction(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	internalApp(app).testHarvest.SpanEvents = newSpanEvents(1)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("mySegment").End()
+	txn.End()
+
+	spans := internalApp(app).testHarvest.SpanEvents
+	if spans.NumSeen() != 2 || spans.NumSaved() != 1 {
+		t.Fatal(spans.NumSeen(), spans.NumSaved())
+	}
+	mt := newMetricTable(100, time.Now())
+	spans.droppedMetrics(mt)
+	expectMetrics(t, mt, []internal.WantMetric{
+		{Name: spanEventsDropped + "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestSpanEventsTailSampling(t *testing.T) {
+	testcases := []struct {
+		name      string
+		sleep     time.Duration
+		err       error
+		important bool
+		expect    bool
+	}{
+		{name: "fast", expect: false},
+		{name: "slow", sleep: 20 * time.Millisecond, expect: true},
+		{name: "failed", err: errors.New("oops"), expect: true},
+		{name: "important", important: true, expect: true},
+	}
+	for _, tc := range testcases {
+		replyfn := func(reply *internal.ConnectReply) {
+			reply.SetSampleEverything()
+		}
+		cfgfn := func(cfg *Config) {
+			cfg.DistributedTracer.Enabled = true
+			cfg.DistributedTracer.TailSampling.Enabled = true
+			cfg.DistributedTracer.TailSampling.LatencyThreshold = 10 * time.Millisecond
+		}
+		app := testApp(replyfn, cfgfn, t)
+		txn := app.StartTransaction(tc.name)
+		txn.StartSegment("child").End()
+		time.Sleep(tc.sleep)
+		if nil != tc.err {
+			txn.NoticeError(tc.err)
+		}
+		if tc.important {
+			txn.MarkImportant("payment failed")
+		}
+		txn.End()
+
+		var want []internal.WantEvent
+		if tc.expect {
+			want = []internal.WantEvent{{
+				Intrinsics: map[string]interface{}{
+					"name":          "Custom/child",
+					"sampled":       true,
+					"category":      "generic",
+					"priority":      internal.MatchAnything,
+					"guid":          internal.MatchAnything,
+					"transactionId": internal.MatchAnything,
+					"traceId":       internal.MatchAnything,
+					"parentId":      internal.MatchAnything,
+				},
+			}, {
+				Intrinsics: map[string]interface{}{
+					"name":             "OtherTransaction/Go/" + tc.name,
+					"sampled":          true,
+					"category":         "generic",
+					"priority":         internal.MatchAnything,
+					"guid":             internal.MatchAnything,
+					"transactionId":    internal.MatchAnything,
+					"nr.entryPoint":    true,
+					"traceId":          internal.MatchAnything,
+					"transaction.name": "OtherTransaction/Go/" + tc.name,
+				},
+			}}
+		}
+		app.ExpectSpanEvents(t, want)
+	}
+}
+
+func TestSpanEventsTailSamplingKeepsUnsampled(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleNothing()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.DistributedTracer.TailSampling.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	if txn.IsSampled() {
+		t.Error("head sampling decision should not be changed")
+	}
+	txn.NoticeError(errors.New("oops"))
+	txn.End()
+	if !txn.thread.BetterCAT.Sampled || !txn.thread.tailKept {
+		t.Error("transaction with an error should be kept")
+	}
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"priority":         internal.MatchAnything,
+			"guid":             internal.MatchAnything,
+			"transactionId":    internal.MatchAnything,
+			"nr.entryPoint":    true,
+			"traceId":          internal.MatchAnything,
+			"transaction.name": "OtherTransaction/Go/hello",
+		},
+	}})
+}
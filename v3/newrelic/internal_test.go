@@ -4,7 +4,10 @@
 package newrelic
 
 import (
+	"bytes"
 	"encoding/json"
+	"io"
+	"io/ioutil"
 	"math"
 	"net/http"
 	"net/http/httptest"
@@ -195,6 +198,7 @@ var (
 		"request.headers.User-Agent":    "Mozilla/5.0",
 		"request.headers.userAgent":     "Mozilla/5.0",
 		"request.headers.contentType":   "text/html; charset=utf-8",
+		"http.flavor":                   "1.1",
 	}
 )
 
@@ -396,6 +400,35 @@ func TestRecordCustomMetricNegativeInf(t *testing.T) {
 	})
 }
 
+func TestRecordCustomMetricSummary(t *testing.T) {
+	app := testApp(nil, nil, t)
+	var summary CustomMetricSummary
+	summary.Add(2.0)
+	summary.Add(math.NaN())
+	summary.Add(-1.0)
+	summary.Add(5.0)
+	if 3 != summary.Count() {
+		t.Error(summary.Count())
+	}
+	app.RecordCustomMetricSummary("myMetric", summary)
+	app.RecordCustomMetricSummary("empty", CustomMetricSummary{})
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Custom/myMetric", Scope: "", Forced: false, Data: []float64{3, 6, 6, -1, 5, 30}},
+	})
+}
+
+func TestRecordCustomMetricSummaryNameEmpty(t *testing.T) {
+	app := testApp(nil, nil, t)
+	var summary CustomMetricSummary
+	summary.Add(1.0)
+	app.RecordCustomMetricSummary("", summary)
+	app.expectSingleLoggedError(t, "unable to record custom metric", map[string]interface{}{
+		"metric-name": "",
+		"reason":      errMetricNameEmpty.Error(),
+	})
+}
+
 type sampleResponseWriter struct {
 	code    int
 	written int
@@ -812,6 +845,7 @@ func TestQueueTime(t *testing.T) {
 		AgentAttributes: map[string]interface{}{
 			"request.uri":    "/hello",
 			"request.method": "GET",
+			"http.flavor":    "1.1",
 		},
 	}})
 	app.ExpectMetrics(t, append([]internal.WantMetric{
@@ -1176,6 +1210,23 @@ func TestTraceDatastore(t *testing.T) {
 	}})
 }
 
+func TestTraceDatastoreAt(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	end := time.Now()
+	s := DatastoreSegment{}
+	s.StartTime = txn.StartSegmentAt(end.Add(-2 * time.Second))
+	s.Product = DatastoreMySQL
+	s.Collection = "my_table"
+	s.Operation = "SELECT"
+	s.EndAt(end)
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/statement/MySQL/my_table/SELECT", Scope: "OtherTransaction/Go/hello", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+}
+
 func TestTraceDatastoreBackground(t *testing.T) {
 	app := testApp(nil, nil, t)
 	txn := app.StartTransaction("hello")
@@ -1413,6 +1464,7 @@ func TestExternalSegmentCustomFieldsWithURL(t *testing.T) {
 				"request.headers.contentType":   "text/html; charset=utf-8",
 				"request.headers.host":          "my_domain.com",
 				"request.method":                "GET",
+				"http.flavor":                   "1.1",
 				"request.headers.contentLength": 753,
 				"request.headers.accept":        "text/plain",
 			},
@@ -1477,6 +1529,7 @@ func TestExternalSegmentCustomFieldsWithRequest(t *testing.T) {
 				"request.headers.contentType":   "text/html; charset=utf-8",
 				"request.headers.host":          "my_domain.com",
 				"request.method":                "GET",
+				"http.flavor":                   "1.1",
 				"request.headers.contentLength": 753,
 				"request.headers.accept":        "text/plain",
 			},
@@ -1549,6 +1602,7 @@ func TestExternalSegmentCustomFieldsWithResponse(t *testing.T) {
 				"request.headers.contentType":   "text/html; charset=utf-8",
 				"request.headers.host":          "my_domain.com",
 				"request.method":                "GET",
+				"http.flavor":                   "1.1",
 				"request.headers.contentLength": 753,
 				"request.headers.accept":        "text/plain",
 			},
@@ -1978,6 +2032,76 @@ func TestAsync(t *testing.T) {
 	})
 }
 
+func TestAsyncFanOut(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	parent := txn.StartSegment("parent")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func(txn *Transaction) {
+			defer wg.Done()
+			s := txn.StartSegment("child")
+			txn.AddAttribute("zip", "zap")
+			txn.StartSegment("grandchild").End()
+			s.End()
+		}(txn.NewGoroutine())
+	}
+	wg.Wait()
+	parent.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/hello", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/parent", Scope: "", Forced: false, Data: []float64{1}},
+		{Name: "Custom/parent", Scope: "OtherTransaction/Go/hello", Forced: false, Data: []float64{1}},
+		{Name: "Custom/child", Scope: "", Forced: false, Data: []float64{10}},
+		{Name: "Custom/child", Scope: "OtherTransaction/Go/hello", Forced: false, Data: []float64{10}},
+		{Name: "Custom/grandchild", Scope: "", Forced: false, Data: []float64{10}},
+		{Name: "Custom/grandchild", Scope: "OtherTransaction/Go/hello", Forced: false, Data: []float64{10}},
+	})
+}
+
+func TestAsyncSpanParent(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	app := testApp(replyfn, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	parent := txn.StartSegment("parent")
+	done := make(chan struct{})
+	go func(txn *Transaction) {
+		defer close(done)
+		txn.StartSegment("child").End()
+	}(txn.NewGoroutine())
+	<-done
+	parent.End()
+	txn.NewGoroutine().StartSegment("sibling").End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	spans := make(map[string]*spanEvent)
+	for _, evt := range internalApp(app).testHarvest.SpanEvents.events {
+		span := evt.jsonWriter.(*spanEvent)
+		spans[span.Name] = span
+	}
+	root := spans["OtherTransaction/Go/hello"].GUID
+	if id := spans["Custom/parent"].ParentID; id != root {
+		t.Error(id, root)
+	}
+	// Segments started in a new goroutine are children of the segment
+	// which was current when NewGoroutine was called.
+	if id := spans["Custom/child"].ParentID; id != spans["Custom/parent"].GUID {
+		t.Error(id, spans["Custom/parent"].GUID)
+	}
+	if id := spans["Custom/sibling"].ParentID; id != root {
+		t.Error(id, root)
+	}
+}
+
 func TestMessageProducerSegmentBasic(t *testing.T) {
 	replyfn := func(reply *internal.ConnectReply) {
 		reply.SetSampleEverything()
@@ -2134,3 +2258,98 @@ func TestMessageProducerSegmentNilSegment(t *testing.T) {
 	var s *MessageProducerSegment
 	s.End()
 }
+
+func TestIOSegment(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	s := IOSegment{
+		StartTime:    txn.StartSegmentNow(),
+		Product:      IOProductS3,
+		Operation:    IOOperationWrite,
+		Location:     "media/video.mp4",
+		StorageClass: "STANDARD",
+	}
+	var buf bytes.Buffer
+	io.Copy(s.Writer(&buf), strings.NewReader("hello world"))
+	s.End()
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	if s.Bytes != 11 {
+		t.Error(s.Bytes)
+	}
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "OtherTransaction/Go/hello", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransaction/all", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime", Scope: "", Forced: true, Data: nil},
+		{Name: "OtherTransactionTotalTime/Go/hello", Scope: "", Forced: false, Data: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/all", Scope: "", Forced: false, Data: nil},
+		{Name: "DurationByCaller/Unknown/Unknown/Unknown/Unknown/allOther", Scope: "", Forced: false, Data: nil},
+		{Name: "IO/all", Scope: "", Forced: true, Data: nil},
+		{Name: "IO/allOther", Scope: "", Forced: true, Data: nil},
+		{Name: "IO/S3/all", Scope: "", Forced: false, Data: nil},
+		{Name: "IO/S3/Write", Scope: "", Forced: false, Data: nil},
+		{Name: "IO/S3/Write", Scope: "OtherTransaction/Go/hello", Forced: false, Data: nil},
+	})
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "IO/S3/Write",
+				"category":  "generic",
+				"component": "S3",
+			},
+			UserAttributes: map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{
+				"io.location":     "media/video.mp4",
+				"io.storageClass": "STANDARD",
+				"io.bytes":        11,
+				"io.throughput":   internal.MatchAnything,
+			},
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestIOSegmentDefaults(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWebRequestHTTP(helloRequest)
+	s := IOSegment{StartTime: txn.StartSegmentNow()}
+	ioutil.ReadAll(s.Reader(strings.NewReader("hello")))
+	s.End()
+	app.expectNoLoggedErrors(t)
+	txn.End()
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "IO/all", Scope: "", Forced: true, Data: nil},
+		{Name: "IO/allWeb", Scope: "", Forced: true, Data: nil},
+		{Name: "IO/File/all", Scope: "", Forced: false, Data: nil},
+		{Name: "IO/File/Read", Scope: "", Forced: false, Data: nil},
+		{Name: "IO/File/Read", Scope: "WebTransaction/Go/hello", Forced: false, Data: nil},
+	})
+}
+
+func TestIOSegmentNil(t *testing.T) {
+	var s *IOSegment
+	r := strings.NewReader("hello")
+	if s.Reader(r) != io.Reader(r) {
+		t.Error("nil segment should return the original reader")
+	}
+	s.AddAttribute("key", "val")
+	s.End()
+}
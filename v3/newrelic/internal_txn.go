@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
@@ -11,16 +12,23 @@ import (
 	"net/url"
 	"reflect"
 	"runtime/debug"
+	"sort"
 	"sync"
 	"time"
 
 	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/sysinfo"
 )
 
 type txn struct {
 	app *app
 	*appRun
 
+	// rollup is the application which receives the transaction's data
+	// when SetApplications has been used to report to a subset of the
+	// rollup application names.
+	rollup *app
+
 	// This mutex is required since the consumer may call the public API
 	// interface functions from different routines.
 	sync.Mutex
@@ -30,8 +38,53 @@ type txn struct {
 	numPayloadsCreated uint32
 	sampledCalculated  bool
 
+	// tailDecided and tailKept record the decision of
+	// Config.DistributedTracer.TailSampling, which is made when the
+	// transaction ends.
+	tailDecided bool
+	tailKept    bool
+
+	// important is set by MarkImportant.
+	important bool
+
+	// checkpoints is the number of Config.TransactionCheckpoints events
+	// recorded.
+	checkpoints int
+
+	// inFlightShard is the shard of app.inFlight which tracks the
+	// transaction.  handles is the number of reachable Transactions of the
+	// transaction, accessed atomically.  See inFlight.
+	inFlightShard uint32
+	handles       int32
+
+	// workflowID and workflowStep are set by SetWorkflow or by accepting
+	// the workflow baggage of the previous step.
+	workflowID   string
+	workflowStep int
+
+	// ctx is the context watched for Config.ContextCancellation.
+	ctx context.Context
+
 	ignore bool
 
+	// requestPath is the path of the web request set by SetWebRequest.  It
+	// selects the Config.DistributedTracer.InboundSampled rule used when
+	// inbound headers are accepted.
+	requestPath string
+
+	// tracingDetail is set by SetTracingDetail.
+	tracingDetail TracingDetail
+
+	// cpuStart is the process CPU usage when the transaction started.  It
+	// is only populated when Config.TransactionProcessCPUTime.Enabled is true.
+	cpuStart   sysinfo.Usage
+	cpuStartOK bool
+
+	// contentionStart is a snapshot of the block and mutex profiles when
+	// the transaction started.  It is nil unless
+	// Config.ContentionProfiling.Enabled is true.
+	contentionStart contentionSnapshot
+
 	// wroteHeader prevents capturing multiple response code errors if the
 	// user erroneously calls WriteHeader multiple times.
 	wroteHeader bool
@@ -57,6 +110,16 @@ func (txn *txn) markStart(now time.Time) {
 }
 
 func (txn *txn) markEnd(now time.Time, thread *tracingThread) {
+	// Round(0) strips the monotonic clock reading, so that Sub uses the
+	// wall clock.  Times without monotonic clock readings give no skew.
+	txn.ClockSkew = measureClockSkew(now.Round(0).Sub(txn.Start.Round(0)), now.Sub(txn.Start))
+	now = txn.correctTime(now)
+	if now.Before(txn.Start) {
+		// Without a monotonic clock reading a wall clock which jumped
+		// backwards would give a negative duration.
+		txn.ClockSkew = now.Sub(txn.Start)
+		now = txn.Start
+	}
 	txn.Stop = now
 	// The thread on which End() was called is considered active now.
 	thread.RecordActivity(now)
@@ -98,11 +161,21 @@ func newTxn(app *app, run *appRun, name string) *thread {
 	}
 
 	txn.Attrs.Agent.Add(AttributeHostDisplayName, txn.Config.HostDisplayName, nil)
+	txn.addLabelAttributes()
 	txn.TxnTrace.Enabled = txn.Config.TransactionTracer.Enabled
 	txn.TxnTrace.SegmentThreshold = txn.Config.TransactionTracer.Segments.Threshold
 	txn.TxnTrace.StackTraceThreshold = txn.Config.TransactionTracer.Segments.StackTraceThreshold
 	txn.SlowQueriesEnabled = txn.Config.DatastoreTracer.SlowQuery.Enabled
 	txn.SlowQueryThreshold = txn.Config.DatastoreTracer.SlowQuery.Threshold
+	txn.codeLevelMetrics = newCodeLevelMetrics(txn.Config.Config)
+	txn.deadlineBudgetFraction = txn.Config.DeadlineBudget.Fraction
+
+	if txn.Config.TransactionProcessCPUTime.Enabled {
+		txn.cpuStart, txn.cpuStartOK = getCPUUsage()
+	}
+	if txn.Config.ContentionProfiling.Enabled {
+		txn.contentionStart = app.contention.get(txn.Start)
+	}
 
 	// Synthetics support is tied up with a transaction's Old CAT field,
 	// CrossProcess. To support Synthetics with either BetterCAT or Old CAT,
@@ -112,6 +185,8 @@ func newTxn(app *app, run *appRun, name string) *thread {
 	noGUID := txn.Config.DistributedTracer.Enabled
 	txn.CrossProcess.Init(doOldCAT, noGUID, run.Reply)
 
+	app.inFlight.add(txn)
+
 	return &thread{
 		txn:    txn,
 		thread: &txn.mainThread,
@@ -136,19 +211,55 @@ func (txn *txn) shouldCollectSpanEvents() bool {
 	if !txn.Config.DistributedTracer.Enabled {
 		return false
 	}
+	if TracingDetailMetrics == txn.tracingDetail {
+		return false
+	}
 	if !txn.Config.SpanEvents.Enabled {
 		return false
 	}
 	if shouldUseTraceObserver(txn.Config) {
 		return true
 	}
+	if txn.Config.DistributedTracer.TailSampling.Enabled {
+		return txn.tailSampled()
+	}
 	return txn.lazilyCalculateSampled()
 }
 
+// tailSampled returns whether span events should be collected when tail
+// sampling is enabled: every transaction buffers its span events until it
+// ends, when decideTailSampling decides whether they are kept.
+func (txn *txn) tailSampled() bool {
+	if !txn.tailDecided {
+		return true
+	}
+	return txn.tailKept
+}
+
+// decideTailSampling keeps the span events of the transaction if it noticed
+// an error or was slow.  Kept transactions are marked as sampled so that
+// their span events have the priority of sampled transactions.
+func (txn *txn) decideTailSampling() {
+	if !txn.Config.DistributedTracer.TailSampling.Enabled || txn.tailDecided {
+		return
+	}
+	txn.tailKept = txn.HasErrors() || txn.important ||
+		txn.Duration >= txn.Config.DistributedTracer.TailSampling.LatencyThreshold
+	txn.tailDecided = true
+	if txn.tailKept {
+		txn.forceSampled()
+	} else {
+		txn.SpanEvents = nil
+	}
+}
+
 func (txn *txn) shouldCreateSpanGUID() bool {
 	if !txn.Config.DistributedTracer.Enabled {
 		return false
 	}
+	if TracingDetailMetrics == txn.tracingDetail {
+		return false
+	}
 	if !txn.Config.SpanEvents.Enabled {
 		return false
 	}
@@ -166,7 +277,11 @@ func (txn *txn) lazilyCalculateSampled() bool {
 	if txn.sampledCalculated {
 		return txn.BetterCAT.Sampled
 	}
-	txn.BetterCAT.Sampled = txn.appRun.adaptiveSampler.computeSampled(txn.BetterCAT.Priority.Float32(), time.Now())
+	if rule, ok := txn.Config.samplingRule(txn.Name); ok {
+		txn.BetterCAT.Sampled = computeRuleSampled(rule)
+	} else {
+		txn.BetterCAT.Sampled = txn.appRun.adaptiveSampler.computeSampled(txn.BetterCAT.Priority.Float32(), time.Now())
+	}
 	if txn.BetterCAT.Sampled {
 		txn.BetterCAT.Priority += 1.0
 	}
@@ -174,6 +289,17 @@ func (txn *txn) lazilyCalculateSampled() bool {
 	return txn.BetterCAT.Sampled
 }
 
+// sampleForError marks an unsampled transaction which has noticed an error
+// as sampled if the sampler's error budget allows it.
+func (txn *txn) sampleForError() {
+	if !txn.BetterCAT.Enabled || txn.lazilyCalculateSampled() {
+		return
+	}
+	if txn.appRun.adaptiveSampler.computeErrorSampled(time.Now()) {
+		txn.forceSampled()
+	}
+}
+
 func (txn *txn) SetWebRequest(r WebRequest) error {
 	txn.Lock()
 	defer txn.Unlock()
@@ -185,14 +311,35 @@ func (txn *txn) SetWebRequest(r WebRequest) error {
 	// Any call to SetWebRequest should indicate a web transaction.
 	txn.IsWeb = true
 
+	if nil != r.URL {
+		txn.requestPath = r.URL.Path
+	}
+
 	h := r.Header
 	if nil != h {
 		txn.Queuing = queueDuration(h, txn.Start)
+		if nil != txn.app.scalingQueueTimes && txn.Queuing > 0 {
+			txn.app.scalingQueueTimes.add(txn.Queuing)
+		}
 		txn.acceptDistributedTraceHeadersLocked(r.Transport, h)
 		txn.CrossProcess.InboundHTTPRequest(h)
 	}
 
+	if txn.Config.isHealthCheck(h) {
+		txn.ignore = true
+	}
+
 	requestAgentAttributes(txn.Attrs, r.Method, h, r.URL, r.Host)
+	requestProtocolAgentAttributes(txn.Attrs, r.Proto, r.TLS)
+
+	if txn.Config.ClientIP.Enabled && !txn.Config.HighSecurity {
+		if ip := requestClientIP(r.RemoteAddr, h, txn.Config.ClientIP.TrustForwardedFor); nil != ip {
+			if txn.Config.ClientIP.Anonymize {
+				ip = anonymizeIP(ip)
+			}
+			txn.Attrs.Agent.Add(AttributeRequestClientIP, ip.String(), nil)
+		}
+	}
 
 	return nil
 }
@@ -206,6 +353,10 @@ func (rw dummyResponseWriter) Write(b []byte) (int, error) { return 0, nil }
 func (rw dummyResponseWriter) WriteHeader(code int) {}
 
 func (thd *thread) SetWebResponse(w http.ResponseWriter) http.ResponseWriter {
+	return thd.setWebResponse(w, nil)
+}
+
+func (thd *thread) setWebResponse(w http.ResponseWriter, timing *requestTiming) http.ResponseWriter {
 	txn := thd.txn
 	txn.Lock()
 	defer txn.Unlock()
@@ -223,6 +374,7 @@ func (thd *thread) SetWebResponse(w http.ResponseWriter) http.ResponseWriter {
 	return upgradeResponseWriter(&replacementResponseWriter{
 		thd:      thd,
 		original: w,
+		timing:   timing,
 	})
 }
 
@@ -241,6 +393,12 @@ func (txn *txn) getsApdex() bool {
 }
 
 func (txn *txn) shouldSaveTrace() bool {
+	switch txn.tracingDetail {
+	case TracingDetailMetrics:
+		return false
+	case TracingDetailStacks, TracingDetailFull:
+		return true
+	}
 	if !txn.Config.TransactionTracer.Enabled {
 		return false
 	}
@@ -257,15 +415,20 @@ func (txn *txn) MergeIntoHarvest(h *harvest) {
 		priority = txn.BetterCAT.Priority
 	} else {
 		priority = newPriority()
+		if txn.important {
+			priority += importantPriorityBoost
+		}
 	}
 
 	createTxnMetrics(&txn.txnData, h.Metrics)
 	mergeBreakdownMetrics(&txn.txnData, h.Metrics)
+	txn.Attrs.createLimitMetrics(h.Metrics)
 
 	if txn.Config.TransactionEvents.Enabled {
 		// Allocate a new TxnEvent to prevent a reference to the large transaction.
 		alloc := new(txnEvent)
 		*alloc = txn.txnData.txnEvent
+		alloc.DTIntrinsics = txn.Config.TransactionEvents.DistributedTracingIntrinsics
 		h.TxnEvents.AddTxnEvent(alloc, priority)
 	}
 
@@ -317,6 +480,9 @@ func headersJustWritten(thd *thread, code int, hdr http.Header) {
 
 	responseHeaderAttributes(txn.Attrs, hdr)
 	responseCodeAttribute(txn.Attrs, code)
+	if http.StatusRequestEntityTooLarge == code {
+		txn.recordRequestTooLarge()
+	}
 
 	if txn.appRun.responseCodeIsError(code) {
 		e := txnErrorFromResponseCode(time.Now(), code)
@@ -376,6 +542,7 @@ func (thd *thread) End(recovered interface{}) error {
 	}
 
 	txn.finished = true
+	txn.app.inFlight.remove(txn)
 
 	if nil != recovered {
 		e := txnErrorFromPanic(time.Now(), recovered)
@@ -383,12 +550,20 @@ func (thd *thread) End(recovered interface{}) error {
 		thd.noticeErrorInternal(e)
 		log.Println(string(debug.Stack()))
 	}
+	thd.recordContextCancellation()
 
 	txn.markEnd(time.Now(), thd.thread)
 	txn.freezeName()
+	txn.recordProcessCPUTime()
+	txn.recordAnomaly()
+	if txn.IsWeb && !txn.ignore {
+		txn.app.recentDurations.add(txn.Duration)
+	}
 	// Make a sampling decision if there have been no segments or outbound
 	// payloads.
 	txn.lazilyCalculateSampled()
+	txn.decideTailSampling()
+	txn.recordContention()
 
 	// Finalise the CAT state.
 	if err := txn.CrossProcess.Finalise(txn.Name, txn.Config.AppName); err != nil {
@@ -420,6 +595,10 @@ func (thd *thread) End(recovered interface{}) error {
 		})
 	}
 
+	if sanitize := txn.Config.TransactionTracer.Sanitize; nil != sanitize && txn.shouldSaveTrace() {
+		txn.TxnTrace.sanitize(sanitize)
+	}
+
 	if txn.shouldCollectSpanEvents() {
 		root := &spanEvent{
 			GUID:         txn.GetRootSpanID(),
@@ -436,6 +615,7 @@ func (thd *thread) End(recovered interface{}) error {
 		if txn.rootSpanErrData != nil {
 			root.AgentAttributes.addString(SpanAttributeErrorClass, txn.rootSpanErrData.Klass)
 			root.AgentAttributes.addString(SpanAttributeErrorMessage, txn.rootSpanErrData.Msg)
+			root.AgentAttributes.addString(SpanAttributeErrorCode, txn.rootSpanErrData.Code)
 		}
 		if p := txn.BetterCAT.Inbound; nil != p {
 			root.ParentID = txn.BetterCAT.Inbound.ID
@@ -450,6 +630,7 @@ func (thd *thread) End(recovered interface{}) error {
 			root.AgentAttributes.addString("parent.transportType", txn.BetterCAT.TransportType)
 		}
 		root.AgentAttributes = txn.Attrs.filterSpanAttributes(root.AgentAttributes, destSpan)
+		txn.propagateSpanAttributes()
 		txn.SpanEvents = append(txn.SpanEvents, root)
 
 		// Add transaction tracing fields to span events at the end of
@@ -460,12 +641,15 @@ func (thd *thread) End(recovered interface{}) error {
 			evt.TransactionID = txn.BetterCAT.TxnID
 			evt.Sampled = txn.BetterCAT.Sampled
 			evt.Priority = txn.BetterCAT.Priority
+			evt.OwnerTxnName = txn.FinalName
+			applySpanAttributeNaming(txn.Config.SpanEvents.AttributeNaming, evt)
 		}
 	}
 
 	if !txn.ignore {
-		txn.app.Consume(txn.Reply.RunID, txn)
-		if observer := txn.app.getObserver(); nil != observer {
+		consumer, runID := txn.consumer()
+		consumer.Consume(runID, txn)
+		if observer := consumer.getObserver(); nil != observer {
 			for _, evt := range txn.SpanEvents {
 				observer.consumeSpan(evt)
 			}
@@ -481,6 +665,90 @@ func (thd *thread) End(recovered interface{}) error {
 	return nil
 }
 
+// consumer returns the application which receives the transaction's data
+// and the run ID with which it is sent.
+func (txn *txn) consumer() (*app, internal.AgentRunID) {
+	if nil != txn.rollup {
+		run, _ := txn.rollup.getState()
+		return txn.rollup, run.Reply.RunID
+	}
+	return txn.app, txn.Reply.RunID
+}
+
+// addLabelAttributes adds the Labels as user attributes when
+// Config.LabelAttributes is enabled.
+func (txn *txn) addLabelAttributes() {
+	if txn.Config.HighSecurity || !txn.Reply.SecurityPolicies.CustomParameters.Enabled() {
+		return
+	}
+	for _, key := range txn.Config.labelAttributeKeys() {
+		name := txn.Config.LabelAttributes.Prefix + key
+		if err := addUserAttribute(txn.Attrs, name, txn.Config.Labels[key], destAll); nil != err {
+			txn.Config.Logger.Debug("unable to add label attribute", map[string]interface{}{
+				"name":  name,
+				"error": err.Error(),
+			})
+		}
+	}
+}
+
+// propagateSpanAttributes copies the transaction attributes listed in
+// Config.SpanEvents.PropagateAttributes, and the label attributes added when
+// Config.LabelAttributes is enabled, onto the span events of the
+// transaction's segments.  The root span event already has every transaction
+// attribute.
+func (txn *txn) propagateSpanAttributes() {
+	names := txn.Config.SpanEvents.PropagateAttributes
+	if keys := txn.Config.labelAttributeKeys(); 0 != len(keys) {
+		names = append([]string(nil), names...)
+		for _, key := range keys {
+			names = append(names, txn.Config.LabelAttributes.Prefix+key)
+		}
+	}
+	for _, name := range names {
+		if attr, ok := txn.Attrs.user[name]; ok {
+			if 0 == attr.dests&destSpan {
+				continue
+			}
+			for _, evt := range txn.SpanEvents {
+				if _, ok := evt.UserAttributes[name]; !ok {
+					addAttr(&evt.UserAttributes, name, attr.value)
+				}
+			}
+			continue
+		}
+		stringVal, otherVal := txn.Attrs.GetAgentValue(name, destSpan)
+		if "" == stringVal && nil == otherVal {
+			continue
+		}
+		for _, evt := range txn.SpanEvents {
+			if _, ok := evt.AgentAttributes[name]; ok {
+				continue
+			}
+			if "" != stringVal {
+				evt.AgentAttributes.addString(name, stringVal)
+			} else {
+				addAttr(&evt.AgentAttributes, name, otherVal)
+			}
+		}
+	}
+}
+
+// endTruncated ends a transaction which is still in progress when the
+// application is shut down.
+func (thd *thread) endTruncated() error {
+	txn := thd.txn
+	txn.Lock()
+	if txn.finished {
+		txn.Unlock()
+		return errAlreadyEnded
+	}
+	txn.Attrs.Agent.Add(AttributeTruncated, "", true)
+	txn.Unlock()
+
+	return thd.End(nil)
+}
+
 func (txn *txn) AddAttribute(name string, value interface{}) error {
 	txn.Lock()
 	defer txn.Unlock()
@@ -532,6 +800,8 @@ func (thd *thread) noticeErrorInternal(err errorData) error {
 		err.Msg = securityPolicyErrorMsg
 	}
 
+	txn.sampleForError()
+
 	if txn.shouldCollectSpanEvents() {
 		err.SpanID = txn.CurrentSpanIdentifier(thd.thread)
 		addErrorAttrs(thd, err)
@@ -544,6 +814,7 @@ func (thd *thread) noticeErrorInternal(err errorData) error {
 var errorAttrs = []string{
 	SpanAttributeErrorClass,
 	SpanAttributeErrorMessage,
+	SpanAttributeErrorCode,
 }
 
 func addErrorAttrs(t *thread, err errorData) {
@@ -557,6 +828,9 @@ func addErrorAttrs(t *thread, err errorData) {
 	}
 	t.thread.AddAgentSpanAttribute(SpanAttributeErrorClass, err.Klass)
 	t.thread.AddAgentSpanAttribute(SpanAttributeErrorMessage, err.Msg)
+	if "" != err.Code {
+		t.thread.AddAgentSpanAttribute(SpanAttributeErrorCode, err.Code)
+	}
 }
 
 var (
@@ -584,6 +858,13 @@ func errorClassMethod(err error) string {
 	return ""
 }
 
+func errorCodeMethod(err error) string {
+	if ec, ok := err.(errorCoder); ok {
+		return ec.ErrorCode()
+	}
+	return ""
+}
+
 func errorStackTraceMethod(err error) stackTrace {
 	if st, ok := err.(stackTracer); ok {
 		return st.StackTrace()
@@ -598,7 +879,7 @@ func errorAttributesMethod(err error) map[string]interface{} {
 	return nil
 }
 
-func errDataFromError(input error) (data errorData, err error) {
+func errDataFromError(input error, limits attributeLimits) (data errorData, err error) {
 	cause := errorCause(input)
 
 	data = errorData{
@@ -617,6 +898,14 @@ func errDataFromError(input error) (data errorData, err error) {
 		data.Klass = reflect.TypeOf(cause).String()
 	}
 
+	if c := errorCodeMethod(input); "" != c {
+		// If the error implements ErrorCoder, use that.
+		data.Code = c
+	} else {
+		// Otherwise, if the error's cause implements ErrorCoder, use that.
+		data.Code = errorCodeMethod(cause)
+	}
+
 	if st := errorStackTraceMethod(input); nil != st {
 		// If the error implements StackTracer, use that.
 		data.Stack = st
@@ -642,19 +931,61 @@ func errDataFromError(input error) (data errorData, err error) {
 			return
 		}
 
+		keys := make([]string, 0, len(unvetted))
+		for key := range unvetted {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
 		data.ExtraAttributes = make(map[string]interface{})
-		for key, val := range unvetted {
-			val, err = validateUserAttribute(key, val)
+		for _, key := range keys {
+			err = flattenErrorAttribute(data.ExtraAttributes, key, unvetted[key], 0, limits)
 			if nil != err {
 				return
 			}
-			data.ExtraAttributes[key] = val
 		}
 	}
 
 	return data, nil
 }
 
+// flattenErrorAttribute adds an extra attribute of an error to attrs.  Maps
+// with string keys are flattened into one attribute per entry, named by
+// joining the keys with dots, in key order so that the attributes kept once
+// attributeErrorLimit is reached are deterministic.  Nested values which are
+// deeper than limits.errorDepth or invalid are dropped; only invalid top level
+// values cause an error.
+func flattenErrorAttribute(attrs map[string]interface{}, key string, val interface{}, depth int, limits attributeLimits) error {
+	v := reflect.ValueOf(val)
+	if reflect.Map != v.Kind() || reflect.String != v.Type().Key().Kind() {
+		if len(attrs) >= attributeErrorLimit {
+			return nil
+		}
+		val, _, err := limits.validate(key, val)
+		if nil != err {
+			return err
+		}
+		attrs[key] = val
+		return nil
+	}
+	if depth >= limits.errorDepth {
+		return nil
+	}
+
+	entries := make(map[string]reflect.Value, v.Len())
+	names := make([]string, 0, v.Len())
+	for _, k := range v.MapKeys() {
+		entries[k.String()] = k
+		names = append(names, k.String())
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		// Errors are ignored: invalid nested values are dropped.
+		flattenErrorAttribute(attrs, key+"."+name, v.MapIndex(entries[name]).Interface(), depth+1, limits)
+	}
+	return nil
+}
+
 func (thd *thread) NoticeError(input error) error {
 	txn := thd.txn
 	txn.Lock()
@@ -667,8 +998,11 @@ func (thd *thread) NoticeError(input error) error {
 	if nil == input {
 		return errNilError
 	}
+	if isRequestTooLarge(input) {
+		txn.recordRequestTooLarge()
+	}
 
-	data, err := errDataFromError(input)
+	data, err := errDataFromError(input, txn.Attrs.config.attributeLimits())
 	if nil != err {
 		return err
 	}
@@ -692,6 +1026,24 @@ func (txn *txn) SetName(name string) error {
 	return nil
 }
 
+func (txn *txn) SetApplications(names []string) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	rollup, err := txn.app.rollups.get(txn.app, names)
+	if nil != err {
+		return err
+	}
+	if rollup == txn.app {
+		rollup = nil
+	}
+	txn.rollup = rollup
+	return nil
+}
+
 func (txn *txn) Ignore() error {
 	txn.Lock()
 	defer txn.Unlock()
@@ -800,8 +1152,12 @@ func (thd *thread) NewGoroutine() *Transaction {
 		// If the transaction has finished, return the same thread.
 		return newTransaction(thd)
 	}
+	newThread := createThread(txn)
+	if fn := txn.ShouldCreateSpanGUID; nil != fn && fn() {
+		newThread.parentSpanID = txn.CurrentSpanIdentifier(thd.thread)
+	}
 	return newTransaction(&thread{
-		thread: createThread(txn),
+		thread: newThread,
 		txn:    txn,
 	})
 }
@@ -823,7 +1179,7 @@ func endBasic(s *Segment) error {
 	return err
 }
 
-func endDatastore(s *DatastoreSegment) error {
+func endDatastore(s *DatastoreSegment, now time.Time) error {
 	thd := s.StartTime.thread
 	if nil == thd {
 		return nil
@@ -838,7 +1194,7 @@ func endDatastore(s *DatastoreSegment) error {
 	if txn.Config.HighSecurity {
 		s.QueryParameters = nil
 	}
-	if !txn.Config.DatastoreTracer.QueryParameters.Enabled {
+	if !txn.Config.DatastoreTracer.QueryParameters.Enabled && TracingDetailFull != txn.tracingDetail {
 		s.QueryParameters = nil
 	}
 	if txn.Reply.SecurityPolicies.RecordSQL.IsSet() {
@@ -858,7 +1214,7 @@ func endDatastore(s *DatastoreSegment) error {
 		TxnData:            &txn.txnData,
 		Thread:             thd.thread,
 		Start:              s.StartTime.start,
-		Now:                time.Now(),
+		Now:                now,
 		Product:            string(s.Product),
 		Collection:         s.Collection,
 		Operation:          s.Operation,
@@ -922,6 +1278,23 @@ func endExternal(s *ExternalSegment) error {
 	if nil != err {
 		return err
 	}
+	host := s.Host
+	if "" == host && nil != u {
+		host = u.Host
+	}
+	if code := externalErrorStatusCode(s); "" != host && txn.Config.isExternalError(host, code) {
+		// The error is noticed before the segment ends so that it is
+		// added to the segment's span.
+		e := txnErrorFromExternal(time.Now(), externalSegmentMethod(s), safeURL(u), host, code)
+		e.Stack = getStackTrace()
+		if txn.Config.HighSecurity || !txn.Reply.SecurityPolicies.CustomParameters.Enabled() {
+			e.ExtraAttributes = nil
+		}
+		thd.noticeErrorInternal(e)
+	}
+	if "" != host {
+		host = txn.Config.externalEntityName(host)
+	}
 	return endExternalSegment(endExternalParams{
 		TxnData:    &txn.txnData,
 		Thread:     thd.thread,
@@ -930,7 +1303,7 @@ func endExternal(s *ExternalSegment) error {
 		Logger:     txn.Config.Logger,
 		Response:   s.Response,
 		URL:        u,
-		Host:       s.Host,
+		Host:       host,
 		Library:    s.Library,
 		Method:     externalSegmentMethod(s),
 		StatusCode: s.statusCode,
@@ -967,6 +1340,39 @@ func endMessage(s *MessageProducerSegment) error {
 	})
 }
 
+func endIO(s *IOSegment) error {
+	thd := s.StartTime.thread
+	if nil == thd {
+		return nil
+	}
+	txn := thd.txn
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+
+	if "" == s.Product {
+		s.Product = IOProductFile
+	}
+	if "" == s.Operation {
+		s.Operation = IOOperationRead
+	}
+
+	return endIOSegment(endIOParams{
+		TxnData:      &txn.txnData,
+		Thread:       thd.thread,
+		Start:        s.StartTime.start,
+		Now:          time.Now(),
+		Product:      s.Product,
+		Operation:    string(s.Operation),
+		Location:     s.Location,
+		StorageClass: s.StorageClass,
+		Bytes:        s.Bytes,
+	})
+}
+
 // oldCATOutboundHeaders generates the Old CAT and Synthetics headers, depending
 // on whether Old CAT is enabled or any Synthetics functionality has been
 // triggered in the agent.
@@ -1032,6 +1438,8 @@ func (thd *thread) CreateDistributedTracePayload(hdrs http.Header) {
 		return
 	}
 
+	txn.insertWorkflowBaggage(hdrs)
+
 	if "" == txn.Reply.AccountID || "" == txn.Reply.TrustedAccountKey {
 		// We can't create a payload:  The application is not yet
 		// connected or serverless distributed tracing configuration was
@@ -1137,6 +1545,8 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 		return nil
 	}
 
+	txn.acceptWorkflowBaggage(hdrs)
+
 	if "" == txn.Reply.AccountID || "" == txn.Reply.TrustedAccountKey {
 		// We can't accept a payload:  The application is not yet
 		// connected or serverless distributed tracing configuration was
@@ -1169,12 +1579,23 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 		return errTrustedAccountKey
 	}
 
-	if 0 != payload.Priority {
+	mode := txn.Config.inboundSampledMode(txn.requestPath)
+
+	if 0 != payload.Priority && InboundSampledIgnore != mode {
 		txn.BetterCAT.Priority = payload.Priority
+		// The caller added 1 to the priority when it was sampled.  In
+		// hint mode the sampled decision is made again, and adds it
+		// again if the transaction is sampled.
+		if InboundSampledHint == mode && nil != payload.Sampled && *payload.Sampled && txn.BetterCAT.Priority >= 1.0 {
+			txn.BetterCAT.Priority -= 1.0
+		}
+		if txn.important {
+			txn.BetterCAT.Priority += importantPriorityBoost
+		}
 	}
 
 	// a nul payload.Sampled means the a field wasn't provided
-	if nil != payload.Sampled {
+	if nil != payload.Sampled && InboundSampledHonor == mode {
 		txn.BetterCAT.Sampled = *payload.Sampled
 		txn.sampledCalculated = true
 	}
@@ -1190,6 +1611,12 @@ func (txn *txn) acceptDistributedTraceHeadersLocked(t TransportType, hdrs http.H
 }
 
 func (txn *txn) Application() *Application {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if nil != txn.rollup {
+		return newApplication(txn.rollup)
+	}
 	return newApplication(txn.app)
 }
 
@@ -1219,6 +1646,10 @@ func (thd *thread) AddUserSpanAttribute(key string, val interface{}) error {
 		return errSecurityPolicy
 	}
 
+	val, err := txn.Attrs.validateUserAttribute(key, val)
+	if nil != err {
+		return err
+	}
 	thd.thread.AddUserSpanAttribute(key, val)
 	return nil
 }
@@ -1261,8 +1692,16 @@ func (thd *thread) GetTraceMetadata() (metadata TraceMetadata) {
 func (thd *thread) GetLinkingMetadata() (metadata LinkingMetadata) {
 	txn := thd.txn
 	metadata.EntityName = txn.appRun.firstAppName
-	metadata.EntityType = "SERVICE"
 	metadata.EntityGUID = txn.appRun.Reply.EntityGUID
+	txn.Lock()
+	if nil != txn.rollup {
+		// The entity is the one of the subset's own connection.
+		run, _ := txn.rollup.getState()
+		metadata.EntityName = run.firstAppName
+		metadata.EntityGUID = run.Reply.EntityGUID
+	}
+	txn.Unlock()
+	metadata.EntityType = "SERVICE"
 	metadata.Hostname = txn.appRun.Config.hostname
 
 	md := thd.GetTraceMetadata()
@@ -13,7 +13,9 @@ func addOptionalStringField(w *jsonFieldsWriter, key, value string) {
 	}
 }
 
-func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer) {
+// intrinsicsJSON writes the intrinsics of a transaction trace or traced error.
+// The errorCode is only non-empty for traced errors.
+func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer, errorCode string) {
 	w := jsonFieldsWriter{buf: buf}
 
 	buf.WriteByte('{')
@@ -40,5 +42,7 @@ func intrinsicsJSON(e *txnEvent, buf *bytes.Buffer) {
 		addOptionalStringField(&w, "synthetics_monitor_id", e.CrossProcess.Synthetics.MonitorID)
 	}
 
+	addOptionalStringField(&w, "error.code", errorCode)
+
 	buf.WriteByte('}')
 }
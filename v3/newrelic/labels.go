@@ -0,0 +1,141 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// LabelLevel is a single level of a LabelHierarchy.
+type LabelLevel struct {
+	Key   string
+	Value string
+}
+
+// LabelHierarchy is a label made up of ordered levels, from the broadest to
+// the most specific.  For example:
+//
+//	newrelic.LabelHierarchy{
+//		{Key: "Region", Value: "us-east-1"},
+//		{Key: "AZ", Value: "us-east-1a"},
+//	}
+type LabelHierarchy []LabelLevel
+
+const (
+	// maxLabels and labelLengthLimit are the collector limits for labels.
+	maxLabels        = 64
+	labelLengthLimit = 255
+
+	labelHierarchySeparator = "/"
+)
+
+var (
+	errLabelsLimit              = fmt.Errorf("max of %d labels", maxLabels)
+	errLabelEmpty               = errors.New("label keys and values must not be empty")
+	errLabelLength              = fmt.Errorf("label keys and values must be %d characters or less", labelLengthLimit)
+	errLabelCharacters          = errors.New("label keys and values must not contain ':' or ';'")
+	errLabelHierarchyEmpty      = errors.New("label hierarchies must have at least one level")
+	errLabelHierarchyCharacters = errors.New("label hierarchy keys and values must not contain '/'")
+)
+
+// label returns the key and value used to report the hierarchy.
+func (h LabelHierarchy) label() (string, string) {
+	keys := make([]string, len(h))
+	values := make([]string, len(h))
+	for i, level := range h {
+		keys[i] = level.Key
+		values[i] = level.Value
+	}
+	return strings.Join(keys, labelHierarchySeparator), strings.Join(values, labelHierarchySeparator)
+}
+
+func (h LabelHierarchy) validate() error {
+	if 0 == len(h) {
+		return errLabelHierarchyEmpty
+	}
+	for _, level := range h {
+		if "" == level.Key || "" == level.Value {
+			return fmt.Errorf("%v: %q", errLabelEmpty, level.Key)
+		}
+		if strings.Contains(level.Key, labelHierarchySeparator) ||
+			strings.Contains(level.Value, labelHierarchySeparator) {
+			return fmt.Errorf("%v: %q", errLabelHierarchyCharacters, level.Key)
+		}
+	}
+	return nil
+}
+
+// labels returns Labels combined with the labels created from
+// LabelHierarchies.
+func (c Config) labels() map[string]string {
+	if 0 == len(c.LabelHierarchies) {
+		return c.Labels
+	}
+	out := make(map[string]string, len(c.Labels)+len(c.LabelHierarchies))
+	for key, val := range c.Labels {
+		out[key] = val
+	}
+	for _, h := range c.LabelHierarchies {
+		key, val := h.label()
+		out[key] = val
+	}
+	return out
+}
+
+// labelAttributeKeys returns the sorted keys of the Labels which are added
+// as attributes when LabelAttributes is enabled.
+func (c Config) labelAttributeKeys() []string {
+	if !c.LabelAttributes.Enabled || 0 == len(c.Labels) {
+		return nil
+	}
+	keys := make([]string, 0, len(c.Labels))
+	for key := range c.Labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func validateLabel(key, val string) error {
+	if "" == key || "" == val {
+		return errLabelEmpty
+	}
+	if utf8.RuneCountInString(key) > labelLengthLimit ||
+		utf8.RuneCountInString(val) > labelLengthLimit {
+		return errLabelLength
+	}
+	if strings.ContainsAny(key, ":;") || strings.ContainsAny(val, ":;") {
+		return errLabelCharacters
+	}
+	return nil
+}
+
+// validateLabels checks LabelHierarchies against the limits enforced by the
+// collector so that invalid hierarchies are reported when the application is
+// created rather than being changed by the collector.  Labels are not
+// checked: as before LabelHierarchies were added, labels which exceed the
+// limits are truncated by the collector, and the warning it returns is
+// logged.
+func (c Config) validateLabels() error {
+	if 0 == len(c.LabelHierarchies) {
+		return nil
+	}
+	for _, h := range c.LabelHierarchies {
+		if err := h.validate(); nil != err {
+			return err
+		}
+		key, val := h.label()
+		if err := validateLabel(key, val); nil != err {
+			return fmt.Errorf("%v: %q", err, key)
+		}
+	}
+	if len(c.labels()) > maxLabels {
+		return errLabelsLimit
+	}
+	return nil
+}
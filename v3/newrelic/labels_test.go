@@ -0,0 +1,111 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestValidateLabels(t *testing.T) {
+	atLimit := make(map[string]string, maxLabels)
+	for i := 0; i < maxLabels; i++ {
+		atLimit["key"+strconv.Itoa(i)] = "value"
+	}
+	tooMany := map[string]string{"extra": "value"}
+	for key, val := range atLimit {
+		tooMany[key] = val
+	}
+	long := strings.Repeat("a", labelLengthLimit+1)
+	testcases := []struct {
+		name        string
+		labels      map[string]string
+		hierarchies []LabelHierarchy
+		err         string
+	}{
+		{name: "none"},
+		{name: "at limit", labels: atLimit},
+		{name: "valid", labels: map[string]string{"Server": "East", "Data Center": "Primary"}},
+		// Labels which exceed the limits are left for the collector to
+		// truncate.
+		{name: "too many", labels: tooMany},
+		{name: "empty value", labels: map[string]string{"Server": ""}},
+		{name: "long key", labels: map[string]string{long: "value"}},
+		{name: "long value", labels: map[string]string{"Server": long}},
+		{name: "colon", labels: map[string]string{"Server": "a:b"}},
+		{name: "semicolon", labels: map[string]string{"a;b": "East"}},
+		{
+			name: "hierarchy",
+			hierarchies: []LabelHierarchy{{
+				{Key: "Region", Value: "us-east-1"},
+				{Key: "AZ", Value: "us-east-1a"},
+			}},
+		},
+		{name: "empty hierarchy", hierarchies: []LabelHierarchy{{}}, err: errLabelHierarchyEmpty.Error()},
+		{
+			name:        "hierarchy separator",
+			hierarchies: []LabelHierarchy{{{Key: "Region", Value: "us/east"}}},
+			err:         errLabelHierarchyCharacters.Error() + `: "Region"`,
+		},
+		{
+			name:        "hierarchy empty level",
+			hierarchies: []LabelHierarchy{{{Key: "Region", Value: ""}}},
+			err:         errLabelEmpty.Error() + `: "Region"`,
+		},
+		{
+			name:        "hierarchy long value",
+			hierarchies: []LabelHierarchy{{{Key: "Region", Value: long}}},
+			err:         errLabelLength.Error() + `: "Region"`,
+		},
+		{
+			name:        "hierarchy colon",
+			hierarchies: []LabelHierarchy{{{Key: "Region", Value: "a:b"}}},
+			err:         errLabelCharacters.Error() + `: "Region"`,
+		},
+		{
+			name:        "hierarchy counts toward limit",
+			labels:      atLimit,
+			hierarchies: []LabelHierarchy{{{Key: "Region", Value: "us-east-1"}}},
+			err:         errLabelsLimit.Error(),
+		},
+	}
+	for _, tc := range testcases {
+		cfg := Config{Labels: tc.labels, LabelHierarchies: tc.hierarchies}
+		err := cfg.validateLabels()
+		var msg string
+		if nil != err {
+			msg = err.Error()
+		}
+		if msg != tc.err {
+			t.Errorf("%s: %q != %q", tc.name, msg, tc.err)
+		}
+	}
+}
+
+func TestLabelHierarchiesConnectJSON(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.License = "0123456789012345678901234567890123456789"
+	cfg.AppName = "my app"
+	cfg.Labels = map[string]string{"Server": "East"}
+	cfg.LabelHierarchies = []LabelHierarchy{{
+		{Key: "Region", Value: "us-east-1"},
+		{Key: "AZ", Value: "us-east-1a"},
+	}}
+	c, err := newInternalConfig(cfg, func(string) string { return "" }, nil)
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect := map[string]string{
+		"Server":    "East",
+		"Region/AZ": "us-east-1/us-east-1a",
+	}
+	if !reflect.DeepEqual(c.Labels, expect) {
+		t.Error(c.Labels)
+	}
+	if len(cfg.Labels) != 1 {
+		t.Error("input labels should not be modified", cfg.Labels)
+	}
+}
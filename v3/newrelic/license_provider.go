@@ -0,0 +1,255 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	// licenseProviderTimeout limits the time spent in each call to a
+	// LicenseProvider.
+	licenseProviderTimeout = 30 * time.Second
+	// vaultDefaultLicenseKey is the key of the license within the Vault
+	// secret if VaultLicenseConfig.Key is empty.
+	vaultDefaultLicenseKey = "license_key"
+)
+
+var (
+	errVaultAddressMissing = errors.New("vault address missing")
+	errVaultPathMissing    = errors.New("vault secret path missing")
+)
+
+// EnvironmentLicenseProvider returns a Config.LicenseProvider which reads the
+// license from the environment variable with the given name each time it is
+// called.
+func EnvironmentLicenseProvider(name string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		license, ok := os.LookupEnv(name)
+		if !ok {
+			return "", fmt.Errorf("environment variable %s is not set", name)
+		}
+		return license, nil
+	}
+}
+
+// FileLicenseProvider returns a Config.LicenseProvider which reads the license
+// from the file at the given path each time it is called.  Surrounding
+// whitespace is removed.  This is useful when the license is a secret mounted
+// into the container, for example by Kubernetes.
+func FileLicenseProvider(path string) func(context.Context) (string, error) {
+	return func(context.Context) (string, error) {
+		b, err := ioutil.ReadFile(path)
+		if nil != err {
+			return "", err
+		}
+		return string(b), nil
+	}
+}
+
+// VaultLicenseConfig configures VaultLicenseProvider.
+type VaultLicenseConfig struct {
+	// Address is the address of the Vault server, eg.
+	// "https://vault.example.com:8200".  If empty, the VAULT_ADDR
+	// environment variable is used.
+	Address string
+	// Token is the Vault token used to read the secret.  If empty, the
+	// VAULT_TOKEN environment variable is used.
+	Token string
+	// Namespace is the Vault Enterprise namespace of the secret, if any.
+	Namespace string
+	// Path is the API path of the secret, without the "/v1/" prefix.  For
+	// secrets in a version 2 key/value engine this includes "data", eg.
+	// "secret/data/newrelic".
+	Path string
+	// Key is the key of the license within the secret.  If empty,
+	// "license_key" is used.
+	Key string
+	// Client is used to make requests to Vault.  If nil,
+	// http.DefaultClient is used.
+	Client *http.Client
+}
+
+// VaultLicenseProvider returns a Config.LicenseProvider which reads the
+// license from a HashiCorp Vault secret each time it is called.  Secrets in
+// both version 1 and version 2 key/value engines are supported.
+func VaultLicenseProvider(cfg VaultLicenseConfig) func(context.Context) (string, error) {
+	return func(ctx context.Context) (string, error) {
+		return cfg.read(ctx)
+	}
+}
+
+func (cfg VaultLicenseConfig) read(ctx context.Context) (string, error) {
+	address := cfg.Address
+	if "" == address {
+		address = os.Getenv("VAULT_ADDR")
+	}
+	if "" == address {
+		return "", errVaultAddressMissing
+	}
+	path := strings.Trim(cfg.Path, "/")
+	if "" == path {
+		return "", errVaultPathMissing
+	}
+	token := cfg.Token
+	if "" == token {
+		token = os.Getenv("VAULT_TOKEN")
+	}
+	key := cfg.Key
+	if "" == key {
+		key = vaultDefaultLicenseKey
+	}
+	client := cfg.Client
+	if nil == client {
+		client = http.DefaultClient
+	}
+
+	req, err := http.NewRequest("GET", strings.TrimRight(address, "/")+"/v1/"+path, nil)
+	if nil != err {
+		return "", err
+	}
+	req = req.WithContext(ctx)
+	req.Header.Set("X-Vault-Token", token)
+	if "" != cfg.Namespace {
+		req.Header.Set("X-Vault-Namespace", cfg.Namespace)
+	}
+	resp, err := client.Do(req)
+	if nil != err {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("vault responded with status code %d", resp.StatusCode)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	if nil != err {
+		return "", err
+	}
+	return vaultSecretValue(body, key)
+}
+
+// vaultSecretValue returns the string value of the key within the Vault
+// secret response body.  Version 2 key/value engines nest the secret inside a
+// second "data" field.
+func vaultSecretValue(body []byte, key string) (string, error) {
+	var secret struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	if err := json.Unmarshal(body, &secret); nil != err {
+		return "", fmt.Errorf("unable to parse vault response: %v", err)
+	}
+	data := secret.Data
+	if _, ok := data[key]; !ok {
+		if nested, ok := data["data"].(map[string]interface{}); ok {
+			data = nested
+		}
+	}
+	value, ok := data[key].(string)
+	if !ok {
+		return "", fmt.Errorf("vault secret does not contain string key %q", key)
+	}
+	return value, nil
+}
+
+// fetchLicense calls the provider and validates the license it returns.
+func fetchLicense(provider func(context.Context) (string, error)) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), licenseProviderTimeout)
+	defer cancel()
+
+	license, err := provider(ctx)
+	if nil != err {
+		return "", err
+	}
+	license = strings.TrimSpace(license)
+	if len(license) != licenseLength {
+		return "", errLicenseLen
+	}
+	return license, nil
+}
+
+// setLicense uses the license for later requests, and for the browser
+// timing headers of the transactions started afterwards.  It returns false
+// if the license is unchanged.
+func (app *app) setLicense(license string) bool {
+	app.Lock()
+	defer app.Unlock()
+
+	if license == app.rpmControls.License {
+		return false
+	}
+	app.rpmControls.License = license
+	app.reloaded.License = license
+	app.placeholderRun = app.placeholderRun.reconfigure(app.reloaded)
+	if nil != app.run {
+		app.run = app.run.reconfigure(app.reloaded)
+	}
+	return true
+}
+
+// awaitLicense calls the LicenseProvider until it returns a license, which
+// is then used to connect.  It returns false if the application is shut
+// down first, or if the collector host of the license is not in the
+// AllowedRegions.
+func (app *app) awaitLicense() bool {
+	for attempts := 0; ; attempts++ {
+		license, err := fetchLicense(app.config.LicenseProvider)
+		if nil == err {
+			app.setLicense(license)
+			cfg := app.currentConfig()
+			if err := cfg.checkCollectorRegion(cfg.preconnectHost()); nil != err {
+				app.setState(nil, err)
+				app.Error("unable to connect", map[string]interface{}{
+					"error": err.Error(),
+				})
+				return false
+			}
+			app.hosts.reset(cfg.preconnectHosts())
+			return true
+		}
+		app.Error("unable to get license from LicenseProvider", map[string]interface{}{
+			"error": err.Error(),
+		})
+		backoff := time.Duration(getConnectBackoffTime(attempts)) * time.Second
+		select {
+		case <-time.After(backoff):
+		case <-app.shutdownStarted:
+			return false
+		}
+	}
+}
+
+// refreshLicense calls the LicenseProvider, and uses the license returned
+// for later requests.  It is called every Config.LicenseRefreshPeriod.
+func (app *app) refreshLicense(time.Time) {
+	if "" == app.getRPMControls().License {
+		// The initial license is fetched by the connect, which also
+		// chooses the collector host using it.
+		return
+	}
+	license, err := fetchLicense(app.config.LicenseProvider)
+	if nil != err {
+		app.Warn("unable to refresh license", map[string]interface{}{
+			"error": err.Error(),
+		})
+		return
+	}
+	if app.setLicense(license) {
+		app.Info("license refreshed", nil)
+	}
+}
+
+func (app *app) getRPMControls() rpmControls {
+	app.RLock()
+	defer app.RUnlock()
+
+	return app.rpmControls
+}
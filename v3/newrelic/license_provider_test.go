@@ -0,0 +1,239 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testRotatedLicense = "9876543210987654321098765432109876543210"
+
+func TestLicenseProviderSetsLicense(t *testing.T) {
+	// The provider is called in the background when connecting, so that
+	// NewApplication does not wait for it.
+	release := make(chan struct{})
+	sender := &collectorSender{}
+	app, err := NewApplication(
+		ConfigAppName("my app"),
+		ConfigLicenseProvider(func(context.Context) (string, error) {
+			<-release
+			return " " + testLicenseKey + "\n", nil
+		}),
+		func(cfg *Config) {
+			cfg.HarvestSender = sender
+			cfg.RuntimeSampler.Enabled = false
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer app.Shutdown(10 * time.Second)
+	if license := app.app.getRPMControls().License; "" != license {
+		t.Error(license)
+	}
+	close(release)
+	if err := app.WaitForConnection(10 * time.Second); nil != err {
+		t.Fatal(err)
+	}
+	if license := app.app.getRPMControls().License; license != testLicenseKey {
+		t.Error(license)
+	}
+	if license := app.app.currentConfig().License; license != testLicenseKey {
+		t.Error(license)
+	}
+}
+
+func TestLicenseProviderErrorRetried(t *testing.T) {
+	sender := &collectorSender{}
+	app, err := NewApplication(
+		ConfigAppName("my app"),
+		ConfigLicenseProvider(func(context.Context) (string, error) {
+			return "", errors.New("access denied")
+		}),
+		func(cfg *Config) {
+			cfg.HarvestSender = sender
+			cfg.RuntimeSampler.Enabled = false
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(50 * time.Millisecond); nil == err {
+		t.Error("connected without a license")
+	}
+	// Shutting down stops the provider being retried.
+	start := time.Now()
+	app.Shutdown(10 * time.Second)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error(elapsed)
+	}
+	if sent := sender.sent(); 0 != len(sent) {
+		t.Error(sent)
+	}
+}
+
+func TestFetchLicenseInvalid(t *testing.T) {
+	_, err := fetchLicense(func(context.Context) (string, error) {
+		return "too short", nil
+	})
+	if err != errLicenseLen {
+		t.Error(err)
+	}
+}
+
+func TestSetLicenseUpdatesBrowserLicense(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.LicenseProvider = func(context.Context) (string, error) {
+			return testLicenseKey, nil
+		}
+	}, t)
+	if !app.app.setLicense(testLicenseKey) || app.app.setLicense(testLicenseKey) {
+		t.Error("license change not reported")
+	}
+	app.app.setLicense(testRotatedLicense)
+	txn := app.StartTransaction("hello")
+	if license := txn.thread.txn.Config.License; license != testRotatedLicense {
+		t.Error(license)
+	}
+}
+
+func TestUpdateLicense(t *testing.T) {
+	license := testLicenseKey
+	var providerErr error
+	app := testApp(nil, func(cfg *Config) {
+		cfg.LicenseProvider = func(context.Context) (string, error) {
+			return license, providerErr
+		}
+	}, t)
+
+	// The license is not refreshed before it has been fetched by the
+	// connect.
+	app.app.refreshLicense(time.Now())
+	if l := app.app.getRPMControls().License; "" != l {
+		t.Error(l)
+	}
+	app.app.setLicense(testLicenseKey)
+
+	license = testRotatedLicense
+	app.app.refreshLicense(time.Now())
+	if l := app.app.getRPMControls().License; l != testRotatedLicense {
+		t.Error(l)
+	}
+	app.expectNoLoggedErrors(t)
+
+	license = testLicenseKey
+	providerErr = errors.New("access denied")
+	app.app.refreshLicense(time.Now())
+	if l := app.app.getRPMControls().License; l != testRotatedLicense {
+		t.Error("previous license should be retained", l)
+	}
+}
+
+func TestEnvironmentLicenseProvider(t *testing.T) {
+	const name = "NEW_RELIC_TEST_LICENSE_PROVIDER"
+	provider := EnvironmentLicenseProvider(name)
+	os.Unsetenv(name)
+	if _, err := provider(context.Background()); nil == err {
+		t.Error("missing environment variable should return an error")
+	}
+	os.Setenv(name, testLicenseKey)
+	defer os.Unsetenv(name)
+	if license, err := provider(context.Background()); nil != err || license != testLicenseKey {
+		t.Error(license, err)
+	}
+}
+
+func TestFileLicenseProvider(t *testing.T) {
+	dir, err := ioutil.TempDir("", "license")
+	if nil != err {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "license")
+
+	provider := FileLicenseProvider(path)
+	if _, err := provider(context.Background()); nil == err {
+		t.Error("missing file should return an error")
+	}
+	ioutil.WriteFile(path, []byte(testLicenseKey+"\n"), 0600)
+	license, err := fetchLicense(provider)
+	if nil != err || license != testLicenseKey {
+		t.Error(license, err)
+	}
+}
+
+func TestVaultLicenseProvider(t *testing.T) {
+	testcases := []struct {
+		path string
+		key  string
+		body string
+	}{
+		{path: "secret/newrelic", body: `{"data":{"license_key":"` + testLicenseKey + `"}}`},
+		{path: "secret/data/newrelic", body: `{"data":{"data":{"license_key":"` + testLicenseKey + `"},"metadata":{"version":3}}}`},
+		{path: "/kv/apm/", key: "nr", body: `{"data":{"nr":"` + testLicenseKey + `"}}`},
+	}
+	for _, tc := range testcases {
+		srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/v1/"+strings.Trim(tc.path, "/") {
+				t.Error(r.URL.Path)
+			}
+			if tok := r.Header.Get("X-Vault-Token"); tok != "my-token" {
+				t.Error(tok)
+			}
+			if ns := r.Header.Get("X-Vault-Namespace"); ns != "apm" {
+				t.Error(ns)
+			}
+			w.Write([]byte(tc.body))
+		}))
+		provider := VaultLicenseProvider(VaultLicenseConfig{
+			Address:   srv.URL + "/",
+			Token:     "my-token",
+			Namespace: "apm",
+			Path:      tc.path,
+			Key:       tc.key,
+		})
+		license, err := provider(context.Background())
+		if nil != err || license != testLicenseKey {
+			t.Error(tc.path, license, err)
+		}
+		srv.Close()
+	}
+}
+
+func TestVaultLicenseProviderErrors(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/v1/secret/forbidden" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		w.Write([]byte(`{"data":{"other":"value"}}`))
+	}))
+	defer srv.Close()
+
+	testcases := []struct {
+		cfg    VaultLicenseConfig
+		errMsg string
+	}{
+		{cfg: VaultLicenseConfig{Path: "secret/newrelic"}, errMsg: errVaultAddressMissing.Error()},
+		{cfg: VaultLicenseConfig{Address: srv.URL}, errMsg: errVaultPathMissing.Error()},
+		{cfg: VaultLicenseConfig{Address: srv.URL, Path: "secret/forbidden"}, errMsg: "vault responded with status code 403"},
+		{cfg: VaultLicenseConfig{Address: srv.URL, Path: "secret/newrelic"}, errMsg: `vault secret does not contain string key "license_key"`},
+	}
+	os.Unsetenv("VAULT_ADDR")
+	for _, tc := range testcases {
+		_, err := VaultLicenseProvider(tc.cfg)(context.Background())
+		if nil == err || err.Error() != tc.errMsg {
+			t.Error(tc.errMsg, err)
+		}
+	}
+}
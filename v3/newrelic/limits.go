@@ -19,6 +19,12 @@ const (
 	appDataChanSize           = 200
 	failedMetricAttemptsLimit = 5
 	failedEventsAttemptsLimit = 10
+	// failoverCooldown is how long a collector host that has failed is
+	// skipped before it is preferred again.
+	failoverCooldown = 5 * time.Minute
+	// failoverHarvestFailures is the number of consecutive harvest requests
+	// that must fail before the application fails over to another host.
+	failoverHarvestFailures = 3
 
 	// transaction behavior
 	maxStackTraceFrames = 100
@@ -31,23 +37,33 @@ const (
 	maxTxnTraceNodes      = 256
 
 	// harvest data
-	maxMetrics          = 2 * 1000
+	maxMetrics          = MaxMetrics
 	maxRegularTraces    = 1
 	maxSyntheticsTraces = 20
-	maxHarvestErrors    = 20
-	maxHarvestSlowSQLs  = 10
+	maxHarvestErrors    = MaxHarvestErrors
+	maxHarvestSlowSQLs  = MaxHarvestSlowSQLs
 	// maxSpanEvents is the maximum number of Span Events that can be captured
 	// per 60-second harvest cycle
-	maxSpanEvents = 1000
+	maxSpanEvents = MaxSpanEvents
 
 	// attributes
-	attributeKeyLengthLimit   = 255
-	attributeValueLengthLimit = 255
-	attributeUserLimit        = 64
+	attributeKeyLengthLimit   = AttributeKeyLengthLimit
+	attributeValueLengthLimit = AttributeValueLengthLimit
+	attributeUserLimit        = AttributeUserLimit
+	// attributeValueLengthMax is the longest attribute value accepted by
+	// the collector.  Config.AttributeLimits.MaxValueLength may be raised
+	// up to this limit.
+	attributeValueLengthMax = AttributeValueLengthMax
 	// attributeErrorLimit limits the number of extra attributes that can be
 	// provided when noticing an error.
 	attributeErrorLimit       = 32
-	customEventAttributeLimit = 64
+	customEventAttributeLimit = CustomEventAttributeLimit
+
+	// errorAttributeDepthDefault is the default number of levels of nested
+	// maps flattened in the extra attributes of errors.
+	errorAttributeDepthDefault = 3
+
+	// Exported protocol versions and limits are found in protocol.go.
 
 	// Limits affecting Config validation are found in the config package.
 
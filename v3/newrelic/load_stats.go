@@ -0,0 +1,103 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	// loadStatsSamples is the number of the most recent web transaction
+	// durations from which the LoadStats percentile is computed.
+	loadStatsSamples = 1000
+	// loadStatsRefresh is how long a computed percentile is reused, so
+	// that LoadStats is cheap enough to call for every request.
+	loadStatsRefresh = time.Second
+)
+
+// LoadStats describes the current load on an Application.  See
+// Application.LoadStats.
+type LoadStats struct {
+	// InFlight is the number of transactions which have started but not
+	// yet ended.
+	InFlight int
+	// P99 is the 99th percentile duration of the most recent 1000 web
+	// transactions.  It is updated at most once per second, and is zero
+	// until a web transaction has ended.
+	P99 time.Duration
+}
+
+type durations []time.Duration
+
+func (d durations) Len() int           { return len(d) }
+func (d durations) Less(i, j int) bool { return d[i] < d[j] }
+func (d durations) Swap(i, j int)      { d[i], d[j] = d[j], d[i] }
+
+// recentDurations holds the durations of the most recent web transactions.
+// It is lock free, since every web transaction adds its duration: each
+// field is accessed atomically.  The 64 bit fields come first so that they
+// are aligned when the recentDurations is allocated.
+type recentDurations struct {
+	// durations is a ring buffer of the durations in nanoseconds.
+	durations [loadStatsSamples]int64
+	// p99 is the percentile computed at computed, in Unix nanoseconds.
+	p99      int64
+	computed int64
+	// added is the number of durations added, and full is one once
+	// the ring buffer has been filled.
+	added uint32
+	full  uint32
+}
+
+func newRecentDurations() *recentDurations {
+	return &recentDurations{}
+}
+
+func (r *recentDurations) add(d time.Duration) {
+	i := (atomic.AddUint32(&r.added, 1) - 1) % loadStatsSamples
+	atomic.StoreInt64(&r.durations[i], int64(d))
+	if loadStatsSamples-1 == i {
+		atomic.StoreUint32(&r.full, 1)
+	}
+}
+
+// percentile99 returns the 99th percentile of the durations, computed at
+// most once per loadStatsRefresh.  Durations added while it is computed
+// may or may not be included.
+func (r *recentDurations) percentile99(now time.Time) time.Duration {
+	computed := atomic.LoadInt64(&r.computed)
+	if 0 != computed && now.UnixNano()-computed < int64(loadStatsRefresh) {
+		return time.Duration(atomic.LoadInt64(&r.p99))
+	}
+	n := int(atomic.LoadUint32(&r.added))
+	if 0 != atomic.LoadUint32(&r.full) || n > loadStatsSamples {
+		n = loadStatsSamples
+	}
+	if 0 == n {
+		return 0
+	}
+	// Only one caller computes the percentile; the others use the
+	// previous one meanwhile.
+	if !atomic.CompareAndSwapInt64(&r.computed, computed, now.UnixNano()) {
+		return time.Duration(atomic.LoadInt64(&r.p99))
+	}
+	sorted := make(durations, n)
+	for i := range sorted {
+		sorted[i] = time.Duration(atomic.LoadInt64(&r.durations[i]))
+	}
+	sort.Sort(sorted)
+	p99 := sorted[int(math.Ceil(0.99*float64(n)))-1]
+	atomic.StoreInt64(&r.p99, int64(p99))
+	return p99
+}
+
+func (app *app) LoadStats() LoadStats {
+	return LoadStats{
+		InFlight: app.inFlight.count(),
+		P99:      app.recentDurations.percentile99(time.Now()),
+	}
+}
@@ -0,0 +1,80 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestRecentDurationsPercentile(t *testing.T) {
+	r := newRecentDurations()
+	now := time.Now()
+	if p99 := r.percentile99(now); 0 != p99 {
+		t.Error(p99)
+	}
+	for i := 1; i <= 200; i++ {
+		r.add(time.Duration(i) * time.Millisecond)
+	}
+	if p99 := r.percentile99(now); 198*time.Millisecond != p99 {
+		t.Error(p99)
+	}
+	// The previous percentile is used until it is refreshed.
+	r.add(time.Hour)
+	r.add(time.Hour)
+	r.add(time.Hour)
+	if p99 := r.percentile99(now.Add(loadStatsRefresh / 2)); 198*time.Millisecond != p99 {
+		t.Error(p99)
+	}
+	if p99 := r.percentile99(now.Add(loadStatsRefresh)); time.Hour != p99 {
+		t.Error(p99)
+	}
+}
+
+func TestRecentDurationsRing(t *testing.T) {
+	r := newRecentDurations()
+	for i := 0; i < loadStatsSamples; i++ {
+		r.add(time.Hour)
+	}
+	for i := 0; i < loadStatsSamples; i++ {
+		r.add(time.Millisecond)
+	}
+	if n := r.added; 2*loadStatsSamples != n {
+		t.Error(n)
+	}
+	if p99 := r.percentile99(time.Now()); time.Millisecond != p99 {
+		t.Error(p99)
+	}
+}
+
+func TestLoadStats(t *testing.T) {
+	app := testApp(nil, nil, t)
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	web := app.StartTransaction("web")
+	web.SetWebRequestHTTP(req)
+	background := app.StartTransaction("background")
+	if stats := app.LoadStats(); 2 != stats.InFlight || 0 != stats.P99 {
+		t.Error(stats)
+	}
+	web.End()
+	background.End()
+	stats := app.LoadStats()
+	if 0 != stats.InFlight || 0 == stats.P99 {
+		t.Error(stats)
+	}
+	if n := internalApp(app).recentDurations.added; 1 != n {
+		t.Error(n)
+	}
+}
+
+func TestLoadStatsNilApplication(t *testing.T) {
+	var app *Application
+	if stats := app.LoadStats(); (LoadStats{}) != stats {
+		t.Error(stats)
+	}
+	if stats := (&Application{}).LoadStats(); (LoadStats{}) != stats {
+		t.Error(stats)
+	}
+}
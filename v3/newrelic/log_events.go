@@ -0,0 +1,213 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"time"
+)
+
+const (
+	// maxLogMessageLength is the number of bytes of a log message which
+	// are kept.  Longer messages are truncated.
+	maxLogMessageLength = 32 * 1024
+	// logSeverityUnknown is the severity of logs recorded without one.
+	logSeverityUnknown = "UNKNOWN"
+)
+
+var (
+	errLogMessageEmpty = errors.New("log message is empty")
+)
+
+// LogData is a log recorded with Application.RecordLog or
+// Transaction.RecordLog.  Logs recorded within a transaction are linked to
+// it by the trace.id and span.id attributes.
+type LogData struct {
+	// Timestamp is when the log was written, in milliseconds since the
+	// Unix epoch.  The time RecordLog is called is used if it is zero.
+	Timestamp int64
+	// Severity is the level of the log, eg. "INFO" or "ERROR".  "UNKNOWN"
+	// is used if it is empty.
+	Severity string
+	// Message is the text of the log.  Messages longer than 32KB are
+	// truncated.
+	Message string
+	// Context is used by Application.RecordLog to find the transaction
+	// the log was written within.  See NewContext.
+	Context context.Context
+}
+
+// logEvent is a log forwarded to the log_event_data endpoint.
+type logEvent struct {
+	priority  priority
+	timestamp int64
+	severity  string
+	message   string
+	traceID   string
+	spanID    string
+	// forward and lineMetrics record whether the event is sent and
+	// whether it is counted by the Logging/lines metrics.
+	forward     bool
+	lineMetrics bool
+}
+
+// createLogEvent validates the log.  It returns nil if the configuration
+// neither forwards nor counts logs.
+func createLogEvent(c config, log LogData, now time.Time) (*logEvent, error) {
+	logging := c.ApplicationLogging
+	if !logging.Enabled || (!logging.Forwarding.Enabled && !logging.Metrics.Enabled) {
+		return nil, nil
+	}
+	if "" == log.Message {
+		return nil, errLogMessageEmpty
+	}
+	e := &logEvent{
+		timestamp:   log.Timestamp,
+		severity:    strings.ToUpper(strings.TrimSpace(log.Severity)),
+		message:     stringLengthByteLimit(log.Message, maxLogMessageLength),
+		forward:     logging.Forwarding.Enabled,
+		lineMetrics: logging.Metrics.Enabled,
+	}
+	if 0 == e.timestamp {
+		e.timestamp = timeToIntMillis(now)
+	}
+	if "" == e.severity {
+		e.severity = logSeverityUnknown
+	}
+	return e, nil
+}
+
+// WriteJSON prepares JSON in the format expected by the collector.
+func (e *logEvent) WriteJSON(buf *bytes.Buffer) {
+	w := jsonFieldsWriter{buf: buf}
+	buf.WriteByte('{')
+	w.stringField("level", e.severity)
+	w.stringField("message", e.message)
+	w.intField("timestamp", e.timestamp)
+	if "" != e.traceID {
+		w.stringField("trace.id", e.traceID)
+	}
+	if "" != e.spanID {
+		w.stringField("span.id", e.spanID)
+	}
+	buf.WriteByte('}')
+}
+
+// MarshalJSON is used for testing.
+func (e *logEvent) MarshalJSON() ([]byte, error) {
+	buf := bytes.NewBuffer(make([]byte, 0, 256))
+
+	e.WriteJSON(buf)
+
+	return buf.Bytes(), nil
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (e *logEvent) MergeIntoHarvest(h *harvest) {
+	if e.lineMetrics && nil != h.Metrics {
+		h.Metrics.addSingleCount(logLinesAll, forced)
+		h.Metrics.addSingleCount(logLinesPrefix+e.severity, forced)
+	}
+	if e.forward {
+		h.LogEvents.Add(e)
+	}
+}
+
+// logCommon holds the attributes shared by every log of a payload.
+type logCommon struct {
+	entityGUID string
+	entityName string
+	hostname   string
+}
+
+type logEvents struct {
+	*analyticsEvents
+	common logCommon
+}
+
+func newLogEvents(max int) *logEvents {
+	return &logEvents{
+		analyticsEvents: newAnalyticsEvents(max),
+	}
+}
+
+func (events *logEvents) Add(e *logEvent) {
+	events.addEvent(analyticsEvent{priority: e.priority, jsonWriter: e})
+}
+
+func (events *logEvents) MergeIntoHarvest(h *harvest) {
+	h.LogEvents.mergeFailed(events.analyticsEvents)
+}
+
+func (events *logEvents) Data(agentRunID string, harvestStart time.Time) ([]byte, error) {
+	if 0 == len(events.events) {
+		return nil, nil
+	}
+	buf := bytes.NewBuffer(make([]byte, 0, 256*len(events.events)))
+	buf.WriteString(`[{"common":{"attributes":{`)
+	w := jsonFieldsWriter{buf: buf}
+	if "" != events.common.entityGUID {
+		w.stringField("entity.guid", events.common.entityGUID)
+	}
+	if "" != events.common.entityName {
+		w.stringField("entity.name", events.common.entityName)
+	}
+	if "" != events.common.hostname {
+		w.stringField("hostname", events.common.hostname)
+	}
+	buf.WriteString(`}},"logs":[`)
+	for i, e := range events.events {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		e.WriteJSON(buf)
+	}
+	buf.WriteString(`]}]`)
+	return buf.Bytes(), nil
+}
+
+func (events *logEvents) EndpointMethod() string {
+	return cmdLogEvents
+}
+
+// RecordLog records the log within the transaction.  The log is given the
+// transaction's priority, so that logs are kept along with the transaction
+// they are linked to.
+func (thd *thread) RecordLog(log LogData, now time.Time) error {
+	txn := thd.txn
+	txn.Lock()
+	if txn.finished {
+		txn.Unlock()
+		return errAlreadyEnded
+	}
+	e, err := createLogEvent(txn.Config, log, now)
+	if nil != err || nil == e {
+		txn.Unlock()
+		return err
+	}
+	thd.linkLogEvent(e)
+	app, id := txn.consumer()
+	txn.Unlock()
+
+	app.Consume(id, e)
+	return nil
+}
+
+// linkLogEvent links the log event to the trace and the current span of the
+// thread.  It must be called with the transaction locked.
+func (thd *thread) linkLogEvent(e *logEvent) {
+	txn := thd.txn
+	if txn.BetterCAT.Enabled {
+		e.traceID = txn.BetterCAT.TraceID
+		if txn.shouldCollectSpanEvents() {
+			e.spanID = txn.CurrentSpanIdentifier(thd.thread)
+		}
+		e.priority = txn.BetterCAT.Priority
+	} else {
+		e.priority = newPriority()
+	}
+}
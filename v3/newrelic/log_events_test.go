@@ -0,0 +1,189 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func enableLogForwarding(cfg *Config) {
+	cfg.ApplicationLogging.Forwarding.Enabled = true
+}
+
+func TestCreateLogEvent(t *testing.T) {
+	now := timeFromUnixMilliseconds(1488393111000)
+	cfg := config{Config: defaultConfig()}
+
+	e, err := createLogEvent(cfg, LogData{Severity: " warn", Message: "hello"}, now)
+	if nil != err || nil == e {
+		t.Fatal(e, err)
+	}
+	if e.severity != "WARN" || e.timestamp != 1488393111000 || e.forward || !e.lineMetrics {
+		t.Error(e)
+	}
+
+	e, _ = createLogEvent(cfg, LogData{Timestamp: 123, Message: strings.Repeat("a", maxLogMessageLength+1)}, now)
+	if e.severity != logSeverityUnknown || e.timestamp != 123 || len(e.message) != maxLogMessageLength {
+		t.Error(e.severity, e.timestamp, len(e.message))
+	}
+
+	if e, err := createLogEvent(cfg, LogData{Severity: "INFO"}, now); nil != e || errLogMessageEmpty != err {
+		t.Error(e, err)
+	}
+
+	cfg.ApplicationLogging.Metrics.Enabled = false
+	if e, err := createLogEvent(cfg, LogData{Message: "hello"}, now); nil != e || nil != err {
+		t.Error(e, err)
+	}
+}
+
+func TestLogEventsData(t *testing.T) {
+	events := newLogEvents(10)
+	if js, err := events.Data("agentRunID", time.Now()); nil != js || nil != err {
+		t.Error(string(js), err)
+	}
+	events.common = logCommon{entityGUID: "guid", entityName: "my app", hostname: "my host"}
+	events.Add(&logEvent{timestamp: 123, severity: "INFO", message: "hello"})
+	events.Add(&logEvent{timestamp: 456, severity: "ERROR", message: "oops", traceID: "trace-id", spanID: "span-id"})
+	js, err := events.Data("agentRunID", time.Now())
+	if nil != err {
+		t.Fatal(err)
+	}
+	expect := compactJSONString(`[{
+		"common":{"attributes":{"entity.guid":"guid","entity.name":"my app","hostname":"my host"}},
+		"logs":[
+			{"level":"INFO","message":"hello","timestamp":123},
+			{"level":"ERROR","message":"oops","timestamp":456,"trace.id":"trace-id","span.id":"span-id"}
+		]
+	}]`)
+	if string(js) != expect {
+		t.Errorf("\nexpect=%s\nactual=%s\n", expect, string(js))
+	}
+	if m := events.EndpointMethod(); m != "log_event_data" {
+		t.Error(m)
+	}
+}
+
+func TestRecordLogLinkedToTransaction(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		enableLogForwarding(cfg)
+	}
+	app := testApp(replyfn, cfgfn, t)
+	app.RecordLog(LogData{Severity: "info", Message: "outside"})
+	txn := app.StartTransaction("hello")
+	ctx := NewContext(context.Background(), txn)
+	app.RecordLog(LogData{Severity: "error", Message: "inside", Context: ctx})
+	txn.RecordLog(LogData{Severity: "error", Message: "direct"})
+	traceID := txn.GetTraceMetadata().TraceID
+	txn.End()
+	app.RecordLog(LogData{Severity: "info", Message: "after", Context: ctx})
+	app.expectNoLoggedErrors(t)
+
+	events := internalApp(app).testHarvest.LogEvents.events
+	if len(events) != 4 {
+		t.Fatal(len(events))
+	}
+	for i, linked := range []bool{false, true, true, false} {
+		e := events[i].jsonWriter.(*logEvent)
+		if linked != (traceID == e.traceID && "" != e.spanID) {
+			t.Error(i, e.message, e.traceID, e.spanID)
+		}
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Logging/lines", Scope: "", Forced: true, Data: []float64{4, 0, 0, 0, 0, 0}},
+		{Name: "Logging/lines/INFO", Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "Logging/lines/ERROR", Scope: "", Forced: true, Data: []float64{2, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestRecordLogForwardingDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	app.RecordLog(LogData{Message: "hello"})
+	app.expectNoLoggedErrors(t)
+	if n := internalApp(app).testHarvest.LogEvents.NumSeen(); 0 != n {
+		t.Error(n)
+	}
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Logging/lines", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "Logging/lines/UNKNOWN", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestRecordLogEmptyMessage(t *testing.T) {
+	app := testApp(nil, enableLogForwarding, t)
+	app.RecordLog(LogData{Severity: "INFO"})
+	app.expectSingleLoggedError(t, "unable to record log", map[string]interface{}{
+		"reason": errLogMessageEmpty.Error(),
+	})
+}
+
+func TestRecordLogNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.RecordLog(LogData{Message: "hello"})
+	var app *Application
+	app.RecordLog(LogData{Message: "hello"})
+}
+
+func TestLogEventsHarvestConfig(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	if hc := newAppRun(cfg, internal.ConnectReplyDefaults()).harvestConfig; 0 != hc.MaxLogEvents {
+		t.Error(hc.MaxLogEvents)
+	}
+	if limit := cfg.eventHarvestConfig().Limits.LogEvents; nil != limit {
+		t.Error(*limit)
+	}
+
+	cfg.ApplicationLogging.Forwarding.Enabled = true
+	cfg.ApplicationLogging.Forwarding.MaxSamplesStored = 500
+	if limit := cfg.eventHarvestConfig().Limits.LogEvents; nil == limit || 500 != *limit {
+		t.Error(limit)
+	}
+	reply, err := internal.UnmarshalConnectReply([]byte(`{"return_value":{
+			"entity_guid": "guid",
+			"event_harvest_config": {
+				"report_period_ms": 5000,
+				"harvest_limits": { "log_event_data": 50 }
+			}}}`), internal.PreconnectReply{})
+	if nil != err {
+		t.Fatal(err)
+	}
+	cfg.AppName = "one;two"
+	hc := newAppRun(cfg, reply).harvestConfig
+	if 50 != hc.MaxLogEvents {
+		t.Error(hc.MaxLogEvents)
+	}
+	if hc.LogCommon.entityGUID != "guid" || hc.LogCommon.entityName != "one" {
+		t.Error(hc.LogCommon)
+	}
+	if period := hc.ReportPeriods[harvestLogEvents]; 5*time.Second != period {
+		t.Error(hc.ReportPeriods)
+	}
+
+	h := newHarvest(time.Now(), hc)
+	h.LogEvents.Add(&logEvent{message: "hello"})
+	ready := h.Ready(time.Now().Add(10 * time.Second))
+	if nil == ready || 1 != ready.LogEvents.NumSaved() || "guid" != h.LogEvents.common.entityGUID {
+		t.Fatal(ready)
+	}
+	js, _ := ready.LogEvents.Data("agentRunID", time.Now())
+	var payload []map[string]interface{}
+	if err := json.Unmarshal(js, &payload); nil != err || 1 != len(payload) {
+		t.Error(string(js), err)
+	}
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Supportability/Logging/Forwarding/Seen", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "Supportability/Logging/Forwarding/Sent", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
@@ -0,0 +1,149 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	"sync"
+	"sync/atomic"
+)
+
+// Counter is a custom metric which counts occurrences of something, such as
+// cache hits, in code too hot to call Application.RecordCustomMetric.
+// Adding to a Counter is a single atomic operation: the count is merged into
+// the harvest when metrics are sent to New Relic, every minute.  Create
+// Counters using Application.NewCounter.  The methods of a nil Counter do
+// nothing, and all methods are safe to call concurrently.
+type Counter struct {
+	name  string
+	count int64
+}
+
+// Add adds n to the count.
+func (c *Counter) Add(n int64) {
+	if nil == c {
+		return
+	}
+	atomic.AddInt64(&c.count, n)
+}
+
+// Inc adds one to the count.
+func (c *Counter) Inc() {
+	c.Add(1)
+}
+
+// Gauge is a custom metric which reports the most recent value of something,
+// such as a queue length, set in code too hot to call
+// Application.RecordCustomMetric.  Setting a Gauge is a single atomic
+// operation: the value at the time metrics are sent to New Relic, every
+// minute, is reported.  Create Gauges using Application.NewGauge.  The
+// methods of a nil Gauge do nothing, and all methods are safe to call
+// concurrently.
+type Gauge struct {
+	name string
+	bits uint64
+	// set is one once a value has been set.
+	set uint32
+}
+
+// Set sets the value of the Gauge.  NaN and infinite values are ignored.
+func (g *Gauge) Set(value float64) {
+	if nil == g || math.IsNaN(value) || math.IsInf(value, 0) {
+		return
+	}
+	atomic.StoreUint64(&g.bits, math.Float64bits(value))
+	atomic.StoreUint32(&g.set, 1)
+}
+
+// metricAggregates contains the Counters and Gauges of an application, and
+// the connections of the servers instrumented by WrapServer.  The lock is
+// only held when one of these is added and when they are merged into a
+// harvest.
+type metricAggregates struct {
+	sync.Mutex
+	counters map[string]*Counter
+	gauges   map[string]*Gauge
+	servers  []*serverConnections
+}
+
+func newMetricAggregates() *metricAggregates {
+	return &metricAggregates{
+		counters: make(map[string]*Counter),
+		gauges:   make(map[string]*Gauge),
+	}
+}
+
+func (ma *metricAggregates) counter(name string) *Counter {
+	ma.Lock()
+	defer ma.Unlock()
+
+	c, ok := ma.counters[name]
+	if !ok {
+		c = &Counter{name: customMetricName(name)}
+		ma.counters[name] = c
+	}
+	return c
+}
+
+func (ma *metricAggregates) gauge(name string) *Gauge {
+	ma.Lock()
+	defer ma.Unlock()
+
+	g, ok := ma.gauges[name]
+	if !ok {
+		g = &Gauge{name: customMetricName(name)}
+		ma.gauges[name] = g
+	}
+	return g
+}
+
+func (ma *metricAggregates) addServer(sc *serverConnections) {
+	ma.Lock()
+	defer ma.Unlock()
+
+	ma.servers = append(ma.servers, sc)
+}
+
+// MergeIntoHarvest implements Harvestable.  The counts of the Counters are
+// reset.
+func (ma *metricAggregates) MergeIntoHarvest(h *harvest) {
+	ma.Lock()
+	defer ma.Unlock()
+
+	for _, c := range ma.counters {
+		if n := atomic.SwapInt64(&c.count, 0); 0 != n {
+			h.Metrics.addCount(c.name, float64(n), unforced)
+		}
+	}
+	for _, g := range ma.gauges {
+		if 0 != atomic.LoadUint32(&g.set) {
+			h.Metrics.addGauge(g.name, math.Float64frombits(atomic.LoadUint64(&g.bits)), unforced)
+		}
+	}
+	for _, sc := range ma.servers {
+		sc.MergeIntoHarvest(h)
+	}
+}
+
+// NewCounter implements newrelic.Application's NewCounter.
+func (app *app) NewCounter(name string) (*Counter, error) {
+	if app.config.ServerlessMode.Enabled {
+		return nil, errMetricServerless
+	}
+	if "" == name {
+		return nil, errMetricNameEmpty
+	}
+	return app.aggregates.counter(name), nil
+}
+
+// NewGauge implements newrelic.Application's NewGauge.
+func (app *app) NewGauge(name string) (*Gauge, error) {
+	if app.config.ServerlessMode.Enabled {
+		return nil, errMetricServerless
+	}
+	if "" == name {
+		return nil, errMetricNameEmpty
+	}
+	return app.aggregates.gauge(name), nil
+}
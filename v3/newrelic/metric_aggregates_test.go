@@ -0,0 +1,107 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestCounter(t *testing.T) {
+	app := testApp(nil, nil, t)
+	c := app.NewCounter("hits")
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			c.Inc()
+			c.Add(2)
+		}()
+	}
+	wg.Wait()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Custom/hits", Scope: "", Forced: false, Data: []float64{30, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestCounterSameName(t *testing.T) {
+	app := testApp(nil, nil, t)
+	if c1, c2 := app.NewCounter("hits"), app.NewCounter("hits"); c1 != c2 {
+		t.Error("counters with the same name should be the same", c1, c2)
+	}
+	if g1, g2 := app.NewGauge("depth"), app.NewGauge("depth"); g1 != g2 {
+		t.Error("gauges with the same name should be the same", g1, g2)
+	}
+}
+
+func TestGauge(t *testing.T) {
+	app := testApp(nil, nil, t)
+	g := app.NewGauge("depth")
+	g.Set(3)
+	g.Set(5)
+	g.Set(math.NaN())
+	g.Set(math.Inf(1))
+	app.NewGauge("unset")
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "Custom/depth", Scope: "", Forced: false, Data: []float64{1, 5, 5, 5, 5, 25}},
+	})
+}
+
+func TestMetricAggregatesMergeResetsCounters(t *testing.T) {
+	ma := newMetricAggregates()
+	ma.counter("hits").Add(4)
+	ma.gauge("depth").Set(2)
+
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	ma.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Custom/hits", Scope: "", Forced: false, Data: []float64{4, 0, 0, 0, 0, 0}},
+		{Name: "Custom/depth", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+
+	h = newHarvest(time.Now(), dfltHarvestCfgr)
+	ma.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Custom/depth", Scope: "", Forced: false, Data: []float64{1, 2, 2, 2, 2, 4}},
+	})
+}
+
+func TestNewCounterNameEmpty(t *testing.T) {
+	app := testApp(nil, nil, t)
+	if c := app.NewCounter(""); nil != c {
+		t.Error(c)
+	}
+	app.expectSingleLoggedError(t, "unable to create counter", map[string]interface{}{
+		"metric-name": "",
+		"reason":      errMetricNameEmpty.Error(),
+	})
+}
+
+func TestNewGaugeServerless(t *testing.T) {
+	cfgFn := func(cfg *Config) { cfg.ServerlessMode.Enabled = true }
+	app := testApp(nil, cfgFn, t)
+	if g := app.NewGauge("depth"); nil != g {
+		t.Error(g)
+	}
+	app.expectSingleLoggedError(t, "unable to create gauge", map[string]interface{}{
+		"metric-name": "depth",
+		"reason":      errMetricServerless.Error(),
+	})
+}
+
+func TestCounterGaugeNil(t *testing.T) {
+	var app *Application
+	c := app.NewCounter("hits")
+	c.Inc()
+	c.Add(3)
+	g := app.NewGauge("depth")
+	g.Set(1)
+}
@@ -3,6 +3,8 @@
 
 package newrelic
 
+import "strings"
+
 const (
 	apdexRollup = "Apdex"
 	apdexPrefix = "Apdex/"
@@ -42,8 +44,24 @@ const (
 	spanEventsSeen = "Supportability/SpanEvent/TotalEventsSeen"
 	spanEventsSent = "Supportability/SpanEvent/TotalEventsSent"
 
+	logEventsSeen = "Supportability/Logging/Forwarding/Seen"
+	logEventsSent = "Supportability/Logging/Forwarding/Sent"
+	// logLinesAll counts the logs recorded with RecordLog and
+	// logLinesPrefix is followed by their severity.
+	logLinesAll    = "Logging/lines"
+	logLinesPrefix = "Logging/lines/"
+	// spanEventsDropped is followed by the name of a transaction whose
+	// span events were dropped because the reservoir was full.
+	spanEventsDropped      = "Supportability/SpanEvent/DroppedByTransaction/"
+	spanEventsDroppedOther = spanEventsDropped + "Other"
+
 	supportabilityDropped = "Supportability/MetricsDropped"
 
+	// Clock skew metrics record the transactions during which the wall
+	// clock jumped, and how far it jumped.
+	supportClockSkewForward  = "Supportability/Go/ClockSkew/Forward"
+	supportClockSkewBackward = "Supportability/Go/ClockSkew/Backward"
+
 	// Runtime/System Metrics
 	memoryPhysical       = "Memory/Physical"
 	heapObjectsAllocated = "Memory/Heap/AllocatedObjects"
@@ -54,13 +72,39 @@ const (
 	runGoroutine         = "Go/Runtime/Goroutines"
 	gcPauseFraction      = "GC/System/Pause Fraction"
 	gcPauses             = "GC/System/Pauses"
+	runSchedulerLatency  = "Go/Runtime/Scheduler/Latency"
+	runGCCPUFraction     = "Go/Runtime/GC/CPU Fraction"
+	runMutexWait         = "Go/Runtime/Mutex/Wait"
+
+	circuitBreakerShortCircuitedAll = "CircuitBreaker/all/ShortCircuited"
 
 	// Configurable event harvest supportability metrics
-	supportReportPeriod     = "Supportability/EventHarvest/ReportPeriod"
-	supportTxnEventLimit    = "Supportability/EventHarvest/AnalyticEventData/HarvestLimit"
-	supportCustomEventLimit = "Supportability/EventHarvest/CustomEventData/HarvestLimit"
-	supportErrorEventLimit  = "Supportability/EventHarvest/ErrorEventData/HarvestLimit"
-	supportSpanEventLimit   = "Supportability/EventHarvest/SpanEventData/HarvestLimit"
+	supportReportPeriod         = "Supportability/EventHarvest/ReportPeriod"
+	supportTxnEventLimit        = "Supportability/EventHarvest/AnalyticEventData/HarvestLimit"
+	supportCustomEventLimit     = "Supportability/EventHarvest/CustomEventData/HarvestLimit"
+	supportErrorEventLimit      = "Supportability/EventHarvest/ErrorEventData/HarvestLimit"
+	supportSpanEventLimit       = "Supportability/EventHarvest/SpanEventData/HarvestLimit"
+	supportLogEventLimit        = "Supportability/EventHarvest/LogEventData/HarvestLimit"
+	supportAdaptiveReportPeriod = "Supportability/EventHarvest/AdaptiveReportPeriod"
+
+	// Attribute limit supportability metrics
+	supportAttributesTruncated = "Supportability/Attributes/Truncated"
+	supportAttributesCoerced   = "Supportability/Attributes/Coerced"
+	supportAttributesDropped   = "Supportability/Attributes/Dropped"
+
+	// http.Server connection metrics recorded by WrapServer
+	serverConnectionsNew      = "HttpServer/Connections/New"
+	serverConnectionsActive   = "HttpServer/Connections/Active"
+	serverConnectionsIdle     = "HttpServer/Connections/Idle"
+	serverConnectionsHijacked = "HttpServer/Connections/Hijacked"
+	serverConnectionsClosed   = "HttpServer/Connections/Closed"
+	serverTLSHandshake        = "HttpServer/TLS/Handshake"
+	serverRequestRead         = "HttpServer/Request/Read"
+	serverResponseWrite       = "HttpServer/Response/Write"
+
+	// Transaction outcome metrics recorded when Transaction.SetOutcome is
+	// used
+	outcomeMetricPrefix = "TransactionOutcome/"
 )
 
 // distributedTracingSupport is used to track distributed tracing activity for
@@ -155,6 +199,8 @@ var (
 	// source.datanerd.us/agents/agent-specs/blob/master/Datastore-Metrics-PORTED.md
 	datastoreRollupMetric = newRollupMetric("Datastore/")
 
+	ioRollupMetric = newRollupMetric("IO/")
+
 	datastoreProductMetricsCache = map[string]rollupMetric{
 		"Cassandra":     newRollupMetric("Datastore/Cassandra/"),
 		"Derby":         newRollupMetric("Datastore/Derby/"),
@@ -189,6 +235,12 @@ func customMetricName(customerInput string) string {
 	return "Custom/" + customerInput
 }
 
+// metricNameSegment replaces the slashes of a name given by the application,
+// which would otherwise add segments to the metric names containing it.
+func metricNameSegment(name string) string {
+	return strings.Replace(name, "/", "_", -1)
+}
+
 // datastoreMetricKey contains the fields by which datastore metrics are
 // aggregated.
 type datastoreMetricKey struct {
@@ -207,6 +259,21 @@ type externalMetricKey struct {
 	ExternalTransactionName string
 }
 
+type ioMetricKey struct {
+	Product   string
+	Operation string
+}
+
+// IO/{product}/{operation}
+func (key ioMetricKey) scopedMetric() string {
+	return "IO/" + key.Product + "/" + key.Operation
+}
+
+// IO/{product}/all
+func (key ioMetricKey) productMetric() string {
+	return "IO/" + key.Product + "/all"
+}
+
 func datastoreScopedMetric(key datastoreMetricKey) string {
 	if "" != key.Collection {
 		return datastoreStatementMetric(key)
@@ -48,6 +48,9 @@ func metricDataFromDuration(duration, exclusive time.Duration) metricData {
 type metric struct {
 	forced metricForce
 	data   metricData
+	// gauge is true for sampled values, such as the memory in use, as
+	// opposed to the totals of the harvest period.
+	gauge bool
 }
 
 type metricTable struct {
@@ -160,6 +163,27 @@ func (mt *metricTable) addValue(name, scope string, total float64, force metricF
 	mt.addValueExclusive(name, scope, total, total, force)
 }
 
+// addGaugeExclusive adds a sampled value.  It is recorded like a value
+// added using addValueExclusive, but is exported by OTLP as a gauge.
+func (mt *metricTable) addGaugeExclusive(name string, total, exclusive float64, force metricForce) {
+	mt.mergeMetric(metricID{Name: name}, metric{
+		forced: force,
+		gauge:  true,
+		data: metricData{
+			countSatisfied:  1,
+			totalTolerated:  total,
+			exclusiveFailed: exclusive,
+			min:             total,
+			max:             total,
+			sumSquares:      total * total,
+		},
+	})
+}
+
+func (mt *metricTable) addGauge(name string, total float64, force metricForce) {
+	mt.addGaugeExclusive(name, total, total, force)
+}
+
 func (mt *metricTable) addApdex(name, scope string, apdexThreshold time.Duration, zone apdexZone, force metricForce) {
 	apdexSeconds := apdexThreshold.Seconds()
 	data := metricData{min: apdexSeconds, max: apdexSeconds}
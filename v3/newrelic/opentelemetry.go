@@ -0,0 +1,130 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+)
+
+// SpanContext is the W3C trace context shared between a Transaction and an
+// OpenTelemetry span.  Its fields have the same underlying types as the
+// TraceID, SpanID, and TraceFlags of go.opentelemetry.io/otel/trace, so that
+// converting between the two does not require this package to depend on
+// OpenTelemetry:
+//
+//	// Continue the OpenTelemetry trace in a Transaction.
+//	otelSC := trace.SpanContextFromContext(ctx)
+//	txn.AcceptSpanContext(newrelic.SpanContext{
+//		TraceID:    otelSC.TraceID(),
+//		SpanID:     otelSC.SpanID(),
+//		Sampled:    otelSC.IsSampled(),
+//		TraceState: otelSC.TraceState().String(),
+//	})
+//
+//	// Start OpenTelemetry spans beneath the Transaction's current segment.
+//	sc := txn.SpanContext()
+//	ctx = trace.ContextWithRemoteSpanContext(ctx, trace.NewSpanContext(trace.SpanContextConfig{
+//		TraceID:    sc.TraceID,
+//		SpanID:     sc.SpanID,
+//		TraceFlags: trace.TraceFlags(sc.TraceFlags()),
+//		Remote:     true,
+//	}))
+type SpanContext struct {
+	// TraceID identifies the entire distributed trace.
+	TraceID [16]byte
+	// SpanID identifies the parent span: the OpenTelemetry span when
+	// passed to AcceptSpanContext, or the Transaction's current segment
+	// when returned by Transaction.SpanContext.
+	SpanID [8]byte
+	// Sampled indicates whether the trace is sampled.
+	Sampled bool
+	// TraceState is the W3C tracestate header value, eg. the value of
+	// OpenTelemetry's TraceState().String().  It is optional.
+	TraceState string
+}
+
+var errInvalidSpanContext = errors.New("span context must have non-zero trace and span ids")
+
+// IsValid returns true if the SpanContext has non-zero trace and span ids.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID != [16]byte{} && sc.SpanID != [8]byte{}
+}
+
+// TraceFlags returns the W3C trace flags of the SpanContext: 1 if it is
+// sampled and 0 otherwise.  The result can be converted to OpenTelemetry's
+// trace.TraceFlags.
+func (sc SpanContext) TraceFlags() byte {
+	if sc.Sampled {
+		return 1
+	}
+	return 0
+}
+
+// traceParent returns the W3C traceparent header value of the SpanContext.
+func (sc SpanContext) traceParent() string {
+	return fmt.Sprintf("00-%s-%s-%02x", hex.EncodeToString(sc.TraceID[:]),
+		hex.EncodeToString(sc.SpanID[:]), sc.TraceFlags())
+}
+
+// decodeTraceContextID decodes the hex id into dst, left padding ids which
+// are shorter than dst with zeros.
+func decodeTraceContextID(dst []byte, id string) bool {
+	if len(id) > 2*len(dst) || 0 != len(id)%2 {
+		return false
+	}
+	b, err := hex.DecodeString(id)
+	if nil != err {
+		return false
+	}
+	copy(dst[len(dst)-len(b):], b)
+	return true
+}
+
+// AcceptSpanContext links the Transaction to an OpenTelemetry span, or any
+// other W3C trace context, making the Transaction a child of the span in the
+// distributed trace.  It is equivalent to calling
+// AcceptDistributedTraceHeaders with the W3C traceparent and tracestate
+// headers of the SpanContext, and so has the same restrictions: it should be
+// called as early in the Transaction as possible, and not after
+// InsertDistributedTraceHeaders.  Distributed tracing must be enabled.
+func (txn *Transaction) AcceptSpanContext(sc SpanContext) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	if !sc.IsValid() {
+		txn.thread.logAPIError(errInvalidSpanContext, "accept span context", nil)
+		return
+	}
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CTraceParentHeader, sc.traceParent())
+	if "" != sc.TraceState {
+		hdrs.Set(DistributedTraceW3CTraceStateHeader, sc.TraceState)
+	}
+	txn.AcceptDistributedTraceHeaders(TransportOther, hdrs)
+}
+
+// SpanContext returns the W3C trace context of the Transaction's current
+// segment, for use as the parent of OpenTelemetry spans.  An invalid
+// SpanContext, with zero ids, is returned if distributed tracing or span
+// events are disabled, or if the Transaction has ended.  Unlike
+// InsertDistributedTraceHeaders, SpanContext does not create a New Relic
+// tracestate entry, so it may be called before AcceptSpanContext or
+// AcceptDistributedTraceHeaders.
+func (txn *Transaction) SpanContext() SpanContext {
+	var sc SpanContext
+	md := txn.GetTraceMetadata()
+	if !decodeTraceContextID(sc.TraceID[:], md.TraceID) ||
+		!decodeTraceContextID(sc.SpanID[:], md.SpanID) ||
+		!sc.IsValid() {
+		return SpanContext{}
+	}
+	sc.Sampled = txn.IsSampled()
+	return sc
+}
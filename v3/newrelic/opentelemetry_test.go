@@ -0,0 +1,121 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/hex"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+var (
+	otelTraceID = [16]byte{0x4b, 0xf9, 0x2f, 0x35, 0x77, 0xb3, 0x4d, 0xa6, 0xa3, 0xce, 0x92, 0x9d, 0x0e, 0x0e, 0x47, 0x36}
+	otelSpanID  = [8]byte{0x00, 0xf0, 0x67, 0xaa, 0x0b, 0xa9, 0x02, 0xb7}
+)
+
+func TestSpanContextTraceParent(t *testing.T) {
+	sc := SpanContext{TraceID: otelTraceID, SpanID: otelSpanID, Sampled: true}
+	if tp := sc.traceParent(); tp != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01" {
+		t.Error(tp)
+	}
+	sc.Sampled = false
+	if tp := sc.traceParent(); tp != "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00" {
+		t.Error(tp)
+	}
+}
+
+func TestAcceptSpanContext(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.AcceptSpanContext(SpanContext{
+		TraceID:    otelTraceID,
+		SpanID:     otelSpanID,
+		Sampled:    true,
+		TraceState: "vendor=value",
+	})
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"transaction.name": "OtherTransaction/Go/hello",
+			"sampled":          internal.MatchAnything,
+			"priority":         internal.MatchAnything,
+			"category":         "generic",
+			"parentId":         "00f067aa0ba902b7",
+			"nr.entryPoint":    true,
+			"guid":             internal.MatchAnything,
+			"transactionId":    internal.MatchAnything,
+			"traceId":          "4bf92f3577b34da6a3ce929d0e0e4736",
+			"tracingVendors":   "vendor",
+		},
+		UserAttributes: map[string]interface{}{},
+		AgentAttributes: map[string]interface{}{
+			"parent.transportType": "Other",
+		},
+	}})
+}
+
+func TestAcceptSpanContextInvalid(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.AcceptSpanContext(SpanContext{SpanID: otelSpanID})
+	app.expectSingleLoggedError(t, "unable to accept span context", map[string]interface{}{
+		"reason": errInvalidSpanContext.Error(),
+	})
+}
+
+func TestSpanContext(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.AcceptSpanContext(SpanContext{TraceID: otelTraceID, SpanID: otelSpanID, Sampled: true})
+	seg := txn.StartSegment("segment")
+	sc := txn.SpanContext()
+	md := txn.GetTraceMetadata()
+	seg.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	if !sc.IsValid() {
+		t.Fatal("span context should be valid", sc)
+	}
+	if sc.TraceID != otelTraceID {
+		t.Error(sc.TraceID)
+	}
+	if spanID := hex.EncodeToString(sc.SpanID[:]); spanID != md.SpanID {
+		t.Error(spanID, md.SpanID)
+	}
+	if !sc.Sampled || 1 != sc.TraceFlags() {
+		t.Error("span context should be sampled")
+	}
+}
+
+func TestSpanContextDistributedTracingDisabled(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	if sc := txn.SpanContext(); sc.IsValid() || sc != (SpanContext{}) {
+		t.Error(sc)
+	}
+}
+
+func TestSpanContextNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.AcceptSpanContext(SpanContext{TraceID: otelTraceID, SpanID: otelSpanID})
+	if sc := txn.SpanContext(); sc.IsValid() {
+		t.Error(sc)
+	}
+}
+
+func TestDecodeTraceContextID(t *testing.T) {
+	var id [8]byte
+	if !decodeTraceContextID(id[:], "f067aa0ba902b7") || id != otelSpanID {
+		t.Error(id)
+	}
+	for _, invalid := range []string{"zz", "abc", "00f067aa0ba902b700"} {
+		if decodeTraceContextID(id[:], invalid) {
+			t.Error(invalid)
+		}
+	}
+}
@@ -0,0 +1,608 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+// https://opentelemetry.io/docs/specs/otlp/#otlphttp
+
+const (
+	otlpTracesPath  = "/v1/traces"
+	otlpMetricsPath = "/v1/metrics"
+	otlpLogsPath    = "/v1/logs"
+
+	// otlpRunID is the agent run id used when exporting using OTLP, since
+	// the application never connects to New Relic.
+	otlpRunID = "otlp"
+	// otlpDefaultAccountID is used for distributed tracing payloads if
+	// DistributedTracer.AccountID is not configured.
+	otlpDefaultAccountID = "0"
+	otlpScopeName        = "github.com/newrelic/go-agent/v3/newrelic"
+
+	// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+	otlpSeverityError = 17
+)
+
+// otlpSeverityNumbers maps the severities of logs recorded with RecordLog to
+// severity numbers.  Other severities are exported without a number.
+//
+// https://opentelemetry.io/docs/specs/otel/logs/data-model/#field-severitynumber
+var otlpSeverityNumbers = map[string]int{
+	"TRACE":    1,
+	"DEBUG":    5,
+	"INFO":     9,
+	"WARN":     13,
+	"WARNING":  13,
+	"ERROR":    otlpSeverityError,
+	"FATAL":    21,
+	"CRITICAL": 21,
+}
+
+// https://github.com/open-telemetry/opentelemetry-proto/blob/main/opentelemetry/proto/trace/v1/trace.proto
+var otlpSpanKinds = map[string]int{
+	"internal": 1,
+	"server":   2,
+	"client":   3,
+	"producer": 4,
+	"consumer": 5,
+}
+
+func (c Config) validateOTLP() error {
+	if "" == c.OTLP.Endpoint {
+		return nil
+	}
+	if c.ServerlessMode.Enabled {
+		return errOTLPServerless
+	}
+	u, err := url.Parse(c.OTLP.Endpoint)
+	if nil != err || ("http" != u.Scheme && "https" != u.Scheme) || "" == u.Host {
+		return errOTLPEndpoint
+	}
+	return nil
+}
+
+// newOTLPConnectReply creates the reply used in place of connecting to New
+// Relic when data is exported using OTLP.
+func newOTLPConnectReply(config config) *internal.ConnectReply {
+	reply := internal.ConnectReplyDefaults()
+	reply.RunID = otlpRunID
+	reply.AccountID = config.DistributedTracer.AccountID
+	if "" == reply.AccountID {
+		reply.AccountID = otlpDefaultAccountID
+	}
+	reply.PrimaryAppID = serverlessDefaultPrimaryAppID
+	return reply
+}
+
+type otlpAnyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *int64   `json:"intValue,string,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
+
+type otlpKeyValue struct {
+	Key   string       `json:"key"`
+	Value otlpAnyValue `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpScope struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+type otlpSpan struct {
+	TraceID           string         `json:"traceId"`
+	SpanID            string         `json:"spanId"`
+	ParentSpanID      string         `json:"parentSpanId,omitempty"`
+	Name              string         `json:"name"`
+	Kind              int            `json:"kind"`
+	StartTimeUnixNano int64          `json:"startTimeUnixNano,string"`
+	EndTimeUnixNano   int64          `json:"endTimeUnixNano,string"`
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+}
+
+type otlpScopeSpans struct {
+	Scope otlpScope  `json:"scope"`
+	Spans []otlpSpan `json:"spans"`
+}
+
+type otlpResourceSpans struct {
+	Resource   otlpResource     `json:"resource"`
+	ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+}
+
+type otlpTraces struct {
+	ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+}
+
+type otlpNumberDataPoint struct {
+	Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+	StartTimeUnixNano int64          `json:"startTimeUnixNano,string"`
+	TimeUnixNano      int64          `json:"timeUnixNano,string"`
+	AsDouble          float64        `json:"asDouble"`
+}
+
+// otlpTemporalityDelta is the aggregation temporality of sums which cover
+// only the harvest period.
+const otlpTemporalityDelta = 1
+
+type otlpSum struct {
+	DataPoints             []otlpNumberDataPoint `json:"dataPoints"`
+	AggregationTemporality int                   `json:"aggregationTemporality"`
+	IsMonotonic            bool                  `json:"isMonotonic"`
+}
+
+type otlpGauge struct {
+	DataPoints []otlpNumberDataPoint `json:"dataPoints"`
+}
+
+type otlpMetric struct {
+	Name  string     `json:"name"`
+	Sum   *otlpSum   `json:"sum,omitempty"`
+	Gauge *otlpGauge `json:"gauge,omitempty"`
+}
+
+type otlpScopeMetrics struct {
+	Scope   otlpScope    `json:"scope"`
+	Metrics []otlpMetric `json:"metrics"`
+}
+
+type otlpResourceMetrics struct {
+	Resource     otlpResource       `json:"resource"`
+	ScopeMetrics []otlpScopeMetrics `json:"scopeMetrics"`
+}
+
+type otlpMetrics struct {
+	ResourceMetrics []otlpResourceMetrics `json:"resourceMetrics"`
+}
+
+type otlpLogRecord struct {
+	TimeUnixNano   int64          `json:"timeUnixNano,string"`
+	SeverityNumber int            `json:"severityNumber,omitempty"`
+	SeverityText   string         `json:"severityText,omitempty"`
+	Body           *otlpAnyValue  `json:"body,omitempty"`
+	Attributes     []otlpKeyValue `json:"attributes,omitempty"`
+	TraceID        string         `json:"traceId,omitempty"`
+	SpanID         string         `json:"spanId,omitempty"`
+}
+
+type otlpScopeLogs struct {
+	Scope      otlpScope       `json:"scope"`
+	LogRecords []otlpLogRecord `json:"logRecords"`
+}
+
+type otlpResourceLogs struct {
+	Resource  otlpResource    `json:"resource"`
+	ScopeLogs []otlpScopeLogs `json:"scopeLogs"`
+}
+
+type otlpLogs struct {
+	ResourceLogs []otlpResourceLogs `json:"resourceLogs"`
+}
+
+// otlpValue converts an attribute value.  Values of unsupported types are
+// converted to strings.
+func otlpValue(val interface{}) otlpAnyValue {
+	var v otlpAnyValue
+	switch x := val.(type) {
+	case string:
+		v.StringValue = &x
+	case bool:
+		v.BoolValue = &x
+	case int:
+		i := int64(x)
+		v.IntValue = &i
+	case int64:
+		v.IntValue = &x
+	case float64:
+		v.DoubleValue = &x
+	case json.Number:
+		if i, err := x.Int64(); nil == err {
+			v.IntValue = &i
+		} else if f, err := x.Float64(); nil == err {
+			v.DoubleValue = &f
+		} else {
+			s := x.String()
+			v.StringValue = &s
+		}
+	default:
+		s := fmt.Sprint(x)
+		v.StringValue = &s
+	}
+	return v
+}
+
+// otlpAttributes converts the attributes, sorted by key.
+func otlpAttributes(attrs map[string]interface{}) []otlpKeyValue {
+	if 0 == len(attrs) {
+		return nil
+	}
+	keys := make([]string, 0, len(attrs))
+	for key := range attrs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	kvs := make([]otlpKeyValue, len(keys))
+	for i, key := range keys {
+		kvs[i] = otlpKeyValue{Key: key, Value: otlpValue(attrs[key])}
+	}
+	return kvs
+}
+
+// otlpResourceFromConfig describes the application using the OpenTelemetry
+// semantic conventions.  Labels are added as resource attributes.
+func otlpResourceFromConfig(c config) otlpResource {
+	attrs := make(map[string]interface{}, len(c.Labels)+5)
+	for key, val := range c.Labels {
+		attrs[key] = val
+	}
+	attrs["service.name"] = strings.Split(c.AppName, ";")[0]
+	attrs["host.name"] = c.hostname
+	attrs["telemetry.sdk.name"] = "newrelic"
+	attrs["telemetry.sdk.language"] = "go"
+	attrs["telemetry.sdk.version"] = Version
+	return otlpResource{Attributes: otlpAttributes(attrs)}
+}
+
+func otlpTraceID(id string) string {
+	if n := 32 - len(id); n > 0 {
+		return strings.Repeat("0", n) + id
+	}
+	return id
+}
+
+func otlpSpanFromEvent(e *spanEvent) otlpSpan {
+	attrs := e.UserAttributes.values()
+	for key, val := range e.AgentAttributes.values() {
+		attrs[key] = val
+	}
+	attrs["category"] = string(e.Category)
+	if e.IsEntrypoint {
+		attrs["nr.entryPoint"] = true
+	}
+	if "" != e.Component {
+		attrs["component"] = e.Component
+	}
+	if "" != e.TxnName {
+		attrs["transaction.name"] = e.TxnName
+	}
+	kind, ok := otlpSpanKinds[e.Kind]
+	if !ok {
+		kind = otlpSpanKinds["internal"]
+	}
+	return otlpSpan{
+		TraceID:           otlpTraceID(e.TraceID),
+		SpanID:            e.GUID,
+		ParentSpanID:      e.ParentID,
+		Name:              e.Name,
+		Kind:              kind,
+		StartTimeUnixNano: e.Timestamp.UnixNano(),
+		EndTimeUnixNano:   e.Timestamp.Add(e.Duration).UnixNano(),
+		Attributes:        otlpAttributes(attrs),
+	}
+}
+
+func otlpTracesJSON(resource otlpResource, events *spanEvents) ([]byte, error) {
+	scope := otlpScopeSpans{Scope: otlpScope{Name: otlpScopeName, Version: Version}}
+	for _, evt := range events.events {
+		if e, ok := evt.jsonWriter.(*spanEvent); ok {
+			scope.Spans = append(scope.Spans, otlpSpanFromEvent(e))
+		}
+	}
+	return json.Marshal(otlpTraces{ResourceSpans: []otlpResourceSpans{{
+		Resource:   resource,
+		ScopeSpans: []otlpScopeSpans{scope},
+	}}})
+}
+
+// otlpIsApdex returns whether the metric is an apdex metric, whose data are
+// the counts of satisfied, tolerating, and frustrated transactions.
+func otlpIsApdex(name string) bool {
+	return apdexRollup == name || strings.HasPrefix(name, apdexPrefix)
+}
+
+// otlpMetricsJSON converts the metrics.  Sampled values, such as the memory
+// in use, become gauges of their average during the harvest period.
+// Apdex metrics become sums of the number of transactions in each zone,
+// given by the "apdex.zone" attribute.  Other metrics become two sums with
+// delta temporality: the metric's total, eg. the time spent in seconds, and
+// its count, named with the ".count" suffix.  Metrics with the same name and
+// different scopes become data points of the same metric with a "scope"
+// attribute.
+func otlpMetricsJSON(resource otlpResource, mt *metricTable, harvestStart time.Time) ([]byte, error) {
+	metrics := make(map[string]*otlpMetric)
+	get := func(name string, gauge bool) *otlpMetric {
+		m, ok := metrics[name]
+		if !ok {
+			m = &otlpMetric{Name: name}
+			if gauge {
+				m.Gauge = &otlpGauge{}
+			} else {
+				m.Sum = &otlpSum{AggregationTemporality: otlpTemporalityDelta, IsMonotonic: true}
+			}
+			metrics[name] = m
+		}
+		return m
+	}
+	for id, m := range mt.metrics {
+		point := func(value float64, attrs map[string]interface{}) otlpNumberDataPoint {
+			if "" != id.Scope {
+				attrs["scope"] = id.Scope
+			}
+			return otlpNumberDataPoint{
+				Attributes:        otlpAttributes(attrs),
+				StartTimeUnixNano: mt.metricPeriodStart.UnixNano(),
+				TimeUnixNano:      harvestStart.UnixNano(),
+				AsDouble:          value,
+			}
+		}
+		switch {
+		case m.gauge:
+			g := get(id.Name, true).Gauge
+			var avg float64
+			if 0 != m.data.countSatisfied {
+				avg = m.data.totalTolerated / m.data.countSatisfied
+			}
+			g.DataPoints = append(g.DataPoints, point(avg, map[string]interface{}{}))
+		case otlpIsApdex(id.Name):
+			s := get(id.Name, false).Sum
+			s.DataPoints = append(s.DataPoints,
+				point(m.data.countSatisfied, map[string]interface{}{"apdex.zone": "satisfied"}),
+				point(m.data.totalTolerated, map[string]interface{}{"apdex.zone": "tolerated"}),
+				point(m.data.exclusiveFailed, map[string]interface{}{"apdex.zone": "frustrated"}))
+		default:
+			total := get(id.Name, false).Sum
+			total.DataPoints = append(total.DataPoints, point(m.data.totalTolerated, map[string]interface{}{}))
+			count := get(id.Name+".count", false).Sum
+			count.DataPoints = append(count.DataPoints, point(m.data.countSatisfied, map[string]interface{}{}))
+		}
+	}
+	names := make([]string, 0, len(metrics))
+	for name := range metrics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	scope := otlpScopeMetrics{
+		Scope:   otlpScope{Name: otlpScopeName, Version: Version},
+		Metrics: make([]otlpMetric, len(names)),
+	}
+	for i, name := range names {
+		scope.Metrics[i] = *metrics[name]
+	}
+	return json.Marshal(otlpMetrics{ResourceMetrics: []otlpResourceMetrics{{
+		Resource:     resource,
+		ScopeMetrics: []otlpScopeMetrics{scope},
+	}}})
+}
+
+// otlpLogRecordFromLog converts a log recorded with RecordLog.
+func otlpLogRecordFromLog(e *logEvent) otlpLogRecord {
+	body := otlpValue(e.message)
+	record := otlpLogRecord{
+		TimeUnixNano:   e.timestamp * int64(time.Millisecond),
+		SeverityNumber: otlpSeverityNumbers[e.severity],
+		SeverityText:   e.severity,
+		Body:           &body,
+		SpanID:         e.spanID,
+	}
+	if "" != e.traceID {
+		record.TraceID = otlpTraceID(e.traceID)
+	}
+	return record
+}
+
+// otlpLogRecordFromEvent converts a log, error event, or custom event to a
+// log record.  Events are converted using their collector JSON: the event
+// type is recorded as the "event.name" attribute, and the message of error
+// events is recorded as the body.
+func otlpLogRecordFromEvent(w jsonWriter) (otlpLogRecord, error) {
+	if e, ok := w.(*logEvent); ok {
+		return otlpLogRecordFromLog(e), nil
+	}
+	var record otlpLogRecord
+	buf := &bytes.Buffer{}
+	w.WriteJSON(buf)
+
+	var parts []map[string]interface{}
+	dec := json.NewDecoder(buf)
+	dec.UseNumber()
+	if err := dec.Decode(&parts); nil != err {
+		return record, err
+	}
+	attrs := make(map[string]interface{})
+	for _, part := range parts {
+		for key, val := range part {
+			attrs[key] = val
+		}
+	}
+	if ts, ok := attrs["timestamp"].(json.Number); ok {
+		if ms, err := ts.Int64(); nil == err {
+			record.TimeUnixNano = ms * int64(time.Millisecond)
+		}
+	}
+	delete(attrs, "timestamp")
+	if eventType, ok := attrs["type"]; ok {
+		attrs["event.name"] = eventType
+		delete(attrs, "type")
+	}
+	if id, ok := attrs["traceId"].(string); ok {
+		record.TraceID = otlpTraceID(id)
+		delete(attrs, "traceId")
+	}
+	if id, ok := attrs["spanId"].(string); ok {
+		record.SpanID = id
+		delete(attrs, "spanId")
+	}
+	if _, ok := w.(*errorEvent); ok {
+		record.SeverityNumber = otlpSeverityError
+		record.SeverityText = "ERROR"
+		if msg, ok := attrs["error.message"]; ok {
+			body := otlpValue(msg)
+			record.Body = &body
+		}
+	}
+	record.Attributes = otlpAttributes(attrs)
+	return record, nil
+}
+
+func otlpLogsJSON(resource otlpResource, events ...*analyticsEvents) ([]byte, error) {
+	scope := otlpScopeLogs{Scope: otlpScope{Name: otlpScopeName, Version: Version}}
+	for _, evts := range events {
+		for _, evt := range evts.events {
+			record, err := otlpLogRecordFromEvent(evt.jsonWriter)
+			if nil != err {
+				return nil, err
+			}
+			scope.LogRecords = append(scope.LogRecords, record)
+		}
+	}
+	return json.Marshal(otlpLogs{ResourceLogs: []otlpResourceLogs{{
+		Resource:  resource,
+		ScopeLogs: []otlpScopeLogs{scope},
+	}}})
+}
+
+// otlpExport is a request to the OTLP endpoint.  The harvestables are merged
+// into the next harvest if the request fails and may be retried.
+type otlpExport struct {
+	path   string
+	data   func() ([]byte, error)
+	retain []harvestable
+}
+
+// otlpExports returns the requests needed to export the harvest.
+func otlpExports(c config, h *harvest, harvestStart time.Time) []otlpExport {
+	resource := otlpResourceFromConfig(c)
+	var exports []otlpExport
+	if nil != h.SpanEvents && len(h.SpanEvents.events) > 0 {
+		exports = append(exports, otlpExport{
+			path:   otlpTracesPath,
+			data:   func() ([]byte, error) { return otlpTracesJSON(resource, h.SpanEvents) },
+			retain: []harvestable{h.SpanEvents},
+		})
+	}
+	if nil != h.Metrics && len(h.Metrics.metrics) > 0 {
+		exports = append(exports, otlpExport{
+			path:   otlpMetricsPath,
+			data:   func() ([]byte, error) { return otlpMetricsJSON(resource, h.Metrics, harvestStart) },
+			retain: []harvestable{h.Metrics},
+		})
+	}
+	var logEvents []*analyticsEvents
+	var retainLogs []harvestable
+	if nil != h.LogEvents && len(h.LogEvents.events) > 0 {
+		logEvents = append(logEvents, h.LogEvents.analyticsEvents)
+		retainLogs = append(retainLogs, h.LogEvents)
+	}
+	if nil != h.ErrorEvents && len(h.ErrorEvents.events) > 0 {
+		logEvents = append(logEvents, h.ErrorEvents.analyticsEvents)
+		retainLogs = append(retainLogs, h.ErrorEvents)
+	}
+	if nil != h.CustomEvents && len(h.CustomEvents.events) > 0 {
+		logEvents = append(logEvents, h.CustomEvents.analyticsEvents)
+		retainLogs = append(retainLogs, h.CustomEvents)
+	}
+	if len(logEvents) > 0 {
+		exports = append(exports, otlpExport{
+			path:   otlpLogsPath,
+			data:   func() ([]byte, error) { return otlpLogsJSON(resource, logEvents...) },
+			retain: retainLogs,
+		})
+	}
+	return exports
+}
+
+// otlpRetryable returns true if a request which received the status code
+// may be retried.
+func otlpRetryable(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests,
+		http.StatusBadGateway,
+		http.StatusServiceUnavailable,
+		http.StatusGatewayTimeout:
+		return true
+	}
+	return false
+}
+
+// otlpRequest sends the data to the OTLP endpoint.  retry is true if the
+// request failed and may be retried.
+func otlpRequest(c config, client *http.Client, path string, data []byte) (retry bool, err error) {
+	endpoint := strings.TrimRight(c.OTLP.Endpoint, "/") + path
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(data))
+	if nil != err {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "NewRelic-Go-Agent/"+Version)
+	for key, val := range c.OTLP.Headers {
+		req.Header.Set(key, val)
+	}
+	resp, err := client.Do(req)
+	if nil != err {
+		return true, err
+	}
+	defer resp.Body.Close()
+	io.Copy(ioutil.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return otlpRetryable(resp.StatusCode), fmt.Errorf("otlp endpoint responded with status code %d", resp.StatusCode)
+	}
+	return false, nil
+}
+
+// doOTLPHarvest exports the harvest to the OTLP endpoint instead of the
+// collector.
+func (app *app) doOTLPHarvest(h *harvest, harvestStart time.Time, run *appRun) {
+	client := app.getRPMControls().Client
+	for _, export := range otlpExports(app.config, h, harvestStart) {
+		data, err := export.data()
+		if nil != err {
+			app.Warn("unable to create harvest data", map[string]interface{}{
+				"path":  export.path,
+				"error": err.Error(),
+			})
+			continue
+		}
+		if app.DebugEnabled() {
+			app.Debug("otlp request", map[string]interface{}{
+				"path":    export.path,
+				"payload": jsonString(data),
+			})
+		}
+		retry, err := otlpRequest(app.config, client, export.path, data)
+		if nil == err {
+			continue
+		}
+		app.Warn("harvest failure", map[string]interface{}{
+			"path":        export.path,
+			"error":       err.Error(),
+			"retain_data": retry,
+		})
+		if retry {
+			for _, data := range export.retain {
+				app.Consume(run.Reply.RunID, data)
+			}
+		}
+	}
+}
@@ -0,0 +1,407 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestValidateOTLP(t *testing.T) {
+	testcases := []struct {
+		endpoint   string
+		serverless bool
+		expect     error
+	}{
+		{endpoint: "", expect: nil},
+		{endpoint: "http://localhost:4318", expect: nil},
+		{endpoint: "https://otlp.example.com/", expect: nil},
+		{endpoint: "localhost:4318", expect: errOTLPEndpoint},
+		{endpoint: "ftp://localhost", expect: errOTLPEndpoint},
+		{endpoint: "http://", expect: errOTLPEndpoint},
+		{endpoint: "http://localhost:4318", serverless: true, expect: errOTLPServerless},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.OTLP.Endpoint = tc.endpoint
+		cfg.ServerlessMode.Enabled = tc.serverless
+		if err := cfg.validateOTLP(); err != tc.expect {
+			t.Error(tc.endpoint, err)
+		}
+	}
+}
+
+func TestValidateOTLPLicenseOptional(t *testing.T) {
+	c := Config{
+		AppName: "my app",
+		Enabled: true,
+	}
+	c.OTLP.Endpoint = "http://localhost:4318"
+	if err := c.validate(); nil != err {
+		t.Error(err)
+	}
+	c.License = "tooshort"
+	if err := c.validate(); err != errLicenseLen {
+		t.Error(err)
+	}
+}
+
+// otlpReceiver records the requests made to an OTLP endpoint.
+type otlpReceiver struct {
+	sync.Mutex
+	requests map[string][]map[string]interface{}
+	headers  http.Header
+	status   int
+}
+
+func (r *otlpReceiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	body, _ := ioutil.ReadAll(req.Body)
+	var payload map[string]interface{}
+	json.Unmarshal(body, &payload)
+
+	r.Lock()
+	defer r.Unlock()
+	if nil == r.requests {
+		r.requests = make(map[string][]map[string]interface{})
+	}
+	r.requests[req.URL.Path] = append(r.requests[req.URL.Path], payload)
+	r.headers = req.Header
+	if 0 != r.status {
+		w.WriteHeader(r.status)
+	}
+}
+
+// otlpRecords returns the spans, metrics, or log records of the first scope
+// of the first resource of the payload.
+func otlpRecords(payload map[string]interface{}, resource, scope, records string) []interface{} {
+	r := payload[resource].([]interface{})[0].(map[string]interface{})
+	s := r[scope].([]interface{})[0].(map[string]interface{})
+	out, _ := s[records].([]interface{})
+	return out
+}
+
+func otlpAttribute(record interface{}, key string) map[string]interface{} {
+	attrs, _ := record.(map[string]interface{})["attributes"].([]interface{})
+	for _, a := range attrs {
+		kv := a.(map[string]interface{})
+		if key == kv["key"] {
+			return kv["value"].(map[string]interface{})
+		}
+	}
+	return nil
+}
+
+func TestOTLPExport(t *testing.T) {
+	receiver := &otlpReceiver{}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	app, err := NewApplication(
+		ConfigAppName("my app;rollup"),
+		ConfigOTLPEndpoint(srv.URL),
+		ConfigDistributedTracerEnabled(true),
+		func(cfg *Config) {
+			cfg.OTLP.Headers = map[string]string{"api-key": "secret"}
+			cfg.RuntimeSampler.Enabled = false
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if err := app.WaitForConnection(time.Second); nil != err {
+		t.Fatal(err)
+	}
+	txn := app.StartTransaction("hello")
+	txn.AddAttribute("zip", 1)
+	txn.StartSegment("child").End()
+	txn.NoticeError(errors.New("oops"))
+	txn.End()
+	app.RecordCustomEvent("MyEvent", map[string]interface{}{"zap": "zop"})
+	app.Shutdown(10 * time.Second)
+
+	receiver.Lock()
+	defer receiver.Unlock()
+
+	if h := receiver.headers.Get("api-key"); "secret" != h {
+		t.Error(h)
+	}
+	if ct := receiver.headers.Get("Content-Type"); "application/json" != ct {
+		t.Error(ct)
+	}
+
+	traces := receiver.requests[otlpTracesPath]
+	if len(traces) != 1 {
+		t.Fatal(receiver.requests)
+	}
+	resource := traces[0]["resourceSpans"].([]interface{})[0].(map[string]interface{})["resource"]
+	if v := otlpAttribute(resource, "service.name"); nil == v || "my app" != v["stringValue"] {
+		t.Error(resource)
+	}
+	spans := otlpRecords(traces[0], "resourceSpans", "scopeSpans", "spans")
+	if len(spans) != 2 {
+		t.Fatal(spans)
+	}
+	var root, child map[string]interface{}
+	for _, s := range spans {
+		span := s.(map[string]interface{})
+		if "OtherTransaction/Go/hello" == span["name"] {
+			root = span
+		} else {
+			child = span
+		}
+	}
+	if nil == root || nil == child {
+		t.Fatal(spans)
+	}
+	if "Custom/child" != child["name"] || root["spanId"] != child["parentSpanId"] || root["traceId"] != child["traceId"] {
+		t.Error(root, child)
+	}
+	if id, _ := root["traceId"].(string); len(id) != 32 {
+		t.Error(root)
+	}
+	if v := otlpAttribute(root, "zip"); nil == v || "1" != v["intValue"] {
+		t.Error(root)
+	}
+
+	metrics := receiver.requests[otlpMetricsPath]
+	if len(metrics) != 1 {
+		t.Fatal(receiver.requests)
+	}
+	found := false
+	for _, m := range otlpRecords(metrics[0], "resourceMetrics", "scopeMetrics", "metrics") {
+		metric := m.(map[string]interface{})
+		if "OtherTransaction/Go/hello.count" != metric["name"] {
+			continue
+		}
+		found = true
+		points := otlpDataPoints(metric, "sum")
+		if len(points) != 1 || 1.0 != points[0]["asDouble"] {
+			t.Error(metric)
+		}
+	}
+	if !found {
+		t.Error("transaction metric missing")
+	}
+
+	logs := receiver.requests[otlpLogsPath]
+	if len(logs) != 1 {
+		t.Fatal(receiver.requests)
+	}
+	records := otlpRecords(logs[0], "resourceLogs", "scopeLogs", "logRecords")
+	if len(records) != 2 {
+		t.Fatal(records)
+	}
+	for _, r := range records {
+		record := r.(map[string]interface{})
+		name := otlpAttribute(record, "event.name")
+		switch name["stringValue"] {
+		case "TransactionError":
+			if "ERROR" != record["severityText"] || "oops" != record["body"].(map[string]interface{})["stringValue"] {
+				t.Error(record)
+			}
+			if root["traceId"] != record["traceId"] {
+				t.Error(record)
+			}
+		case "MyEvent":
+			if v := otlpAttribute(record, "zap"); nil == v || "zop" != v["stringValue"] {
+				t.Error(record)
+			}
+		default:
+			t.Error(record)
+		}
+	}
+}
+
+func TestOTLPExportLogs(t *testing.T) {
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	h.LogEvents = newLogEvents(10)
+	h.LogEvents.Add(&logEvent{
+		timestamp: 1500,
+		severity:  "WARN",
+		message:   "hello",
+		traceID:   "abc",
+		spanID:    "def",
+		forward:   true,
+	})
+	var data []byte
+	for _, e := range otlpExports(config{Config: defaultConfig()}, h, time.Now()) {
+		if otlpLogsPath == e.path {
+			data, _ = e.data()
+		}
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); nil != err {
+		t.Fatal(err, string(data))
+	}
+	records := otlpRecords(payload, "resourceLogs", "scopeLogs", "logRecords")
+	if len(records) != 1 {
+		t.Fatal(records)
+	}
+	record := records[0].(map[string]interface{})
+	if "1500000000" != record["timeUnixNano"] ||
+		"WARN" != record["severityText"] ||
+		float64(13) != record["severityNumber"] ||
+		"hello" != record["body"].(map[string]interface{})["stringValue"] ||
+		otlpTraceID("abc") != record["traceId"] ||
+		"def" != record["spanId"] {
+		t.Error(record)
+	}
+}
+
+func TestOTLPRequestRetryable(t *testing.T) {
+	receiver := &otlpReceiver{status: http.StatusServiceUnavailable}
+	srv := httptest.NewServer(receiver)
+	defer srv.Close()
+
+	cfg := config{Config: defaultConfig()}
+	cfg.OTLP.Endpoint = srv.URL + "/"
+	retry, err := otlpRequest(cfg, http.DefaultClient, otlpMetricsPath, []byte("{}"))
+	if !retry || nil == err {
+		t.Error(retry, err)
+	}
+	if len(receiver.requests[otlpMetricsPath]) != 1 {
+		t.Error(receiver.requests)
+	}
+
+	receiver.status = http.StatusBadRequest
+	retry, err = otlpRequest(cfg, http.DefaultClient, otlpMetricsPath, []byte("{}"))
+	if retry || nil == err {
+		t.Error(retry, err)
+	}
+
+	receiver.status = http.StatusOK
+	retry, err = otlpRequest(cfg, http.DefaultClient, otlpMetricsPath, []byte("{}"))
+	if retry || nil != err {
+		t.Error(retry, err)
+	}
+}
+
+// otlpDataPoints returns the data points of the metric of the given type,
+// ie. "sum" or "gauge".
+func otlpDataPoints(metric map[string]interface{}, typ string) []map[string]interface{} {
+	data, _ := metric[typ].(map[string]interface{})
+	raw, _ := data["dataPoints"].([]interface{})
+	points := make([]map[string]interface{}, len(raw))
+	for i, p := range raw {
+		points[i] = p.(map[string]interface{})
+	}
+	return points
+}
+
+func TestOTLPMetricsScope(t *testing.T) {
+	start := time.Now()
+	mt := newMetricTable(100, start)
+	mt.addDuration("WebTransaction/Go/hello", "", 2*time.Second, time.Second, unforced)
+	mt.addDuration("Custom/child", "WebTransaction/Go/hello", time.Second, time.Second, unforced)
+	mt.addDuration("Custom/child", "", time.Second, time.Second, unforced)
+	mt.addDuration("Custom/child", "", 3*time.Second, time.Second, unforced)
+
+	data, err := otlpMetricsJSON(otlpResource{}, mt, start.Add(time.Minute))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); nil != err {
+		t.Fatal(err)
+	}
+	metrics := otlpRecords(payload, "resourceMetrics", "scopeMetrics", "metrics")
+	if len(metrics) != 4 {
+		t.Fatal(string(data))
+	}
+	want := map[string]float64{"Custom/child": 4, "Custom/child.count": 2}
+	for _, m := range metrics[:2] {
+		metric := m.(map[string]interface{})
+		name, _ := metric["name"].(string)
+		sum, _ := metric["sum"].(map[string]interface{})
+		if nil == sum || 1.0 != sum["aggregationTemporality"] || true != sum["isMonotonic"] {
+			t.Fatal(metric)
+		}
+		points := otlpDataPoints(metric, "sum")
+		if len(points) != 2 {
+			t.Fatal(points)
+		}
+		for _, point := range points {
+			if scope := otlpAttribute(point, "scope"); nil != scope {
+				if "WebTransaction/Go/hello" != scope["stringValue"] || 1.0 != point["asDouble"] {
+					t.Error(name, point)
+				}
+				continue
+			}
+			if want[name] != point["asDouble"] {
+				t.Error(name, point)
+			}
+		}
+	}
+}
+
+func TestOTLPMetricsGauge(t *testing.T) {
+	start := time.Now()
+	mt := newMetricTable(100, start)
+	mt.addGauge("Memory/Physical", 10, forced)
+	mt.addGauge("Memory/Physical", 20, forced)
+
+	data, err := otlpMetricsJSON(otlpResource{}, mt, start.Add(time.Minute))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); nil != err {
+		t.Fatal(err)
+	}
+	metrics := otlpRecords(payload, "resourceMetrics", "scopeMetrics", "metrics")
+	if len(metrics) != 1 {
+		t.Fatal(string(data))
+	}
+	metric := metrics[0].(map[string]interface{})
+	if _, ok := metric["sum"]; ok {
+		t.Error(metric)
+	}
+	points := otlpDataPoints(metric, "gauge")
+	if len(points) != 1 || 15.0 != points[0]["asDouble"] {
+		t.Error(metric)
+	}
+}
+
+func TestOTLPMetricsApdex(t *testing.T) {
+	start := time.Now()
+	mt := newMetricTable(100, start)
+	mt.addApdex(apdexRollup, "", time.Second, apdexSatisfying, forced)
+	mt.addApdex(apdexRollup, "", time.Second, apdexSatisfying, forced)
+	mt.addApdex(apdexRollup, "", time.Second, apdexTolerating, forced)
+	mt.addApdex(apdexRollup, "", time.Second, apdexFailing, forced)
+
+	data, err := otlpMetricsJSON(otlpResource{}, mt, start.Add(time.Minute))
+	if nil != err {
+		t.Fatal(err)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(data, &payload); nil != err {
+		t.Fatal(err)
+	}
+	metrics := otlpRecords(payload, "resourceMetrics", "scopeMetrics", "metrics")
+	if len(metrics) != 1 {
+		t.Fatal(string(data))
+	}
+	metric := metrics[0].(map[string]interface{})
+	if apdexRollup != metric["name"] {
+		t.Fatal(metric)
+	}
+	want := map[interface{}]float64{"satisfied": 2, "tolerated": 1, "frustrated": 1}
+	points := otlpDataPoints(metric, "sum")
+	if len(points) != 3 {
+		t.Fatal(points)
+	}
+	for _, point := range points {
+		zone := otlpAttribute(point, "apdex.zone")
+		if nil == zone || want[zone["stringValue"]] != point["asDouble"] {
+			t.Error(point)
+		}
+	}
+}
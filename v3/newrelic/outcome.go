@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "errors"
+
+// Outcome classifies how a transaction finished, independently of any HTTP
+// status code.  See Transaction.SetOutcome.
+type Outcome int
+
+const (
+	// OutcomeUnset is the Outcome of transactions for which SetOutcome
+	// has not been called.  No outcome attribute or metrics are recorded.
+	OutcomeUnset Outcome = iota
+	// OutcomeSuccess indicates the work was completed.
+	OutcomeSuccess
+	// OutcomeClientError indicates the work failed because of the caller,
+	// for example an invalid or unauthorized request.
+	OutcomeClientError
+	// OutcomeServerError indicates the work failed because of this
+	// application or one of its dependencies.
+	OutcomeServerError
+	// OutcomeCancelled indicates the caller abandoned the work before it
+	// completed.
+	OutcomeCancelled
+	// OutcomeTimeout indicates the work did not complete before its
+	// deadline.
+	OutcomeTimeout
+	// OutcomeRequestTooLarge indicates the request was rejected because
+	// its body was too large.  It is set automatically, see
+	// AttributeRequestTooLarge.
+	OutcomeRequestTooLarge
+)
+
+// requestTooLargeMessage is the message of the error returned when more
+// than the limit of an http.MaxBytesReader is read.
+const requestTooLargeMessage = "http: request body too large"
+
+var errInvalidOutcome = errors.New("invalid outcome")
+
+// String returns the name of the Outcome used in the
+// AttributeTransactionOutcome attribute and the outcome metrics, eg.
+// "ClientError".
+func (o Outcome) String() string {
+	switch o {
+	case OutcomeSuccess:
+		return "Success"
+	case OutcomeClientError:
+		return "ClientError"
+	case OutcomeServerError:
+		return "ServerError"
+	case OutcomeCancelled:
+		return "Cancelled"
+	case OutcomeTimeout:
+		return "Timeout"
+	case OutcomeRequestTooLarge:
+		return "RequestTooLarge"
+	}
+	return ""
+}
+
+func (o Outcome) valid() bool {
+	return o >= OutcomeUnset && o <= OutcomeRequestTooLarge
+}
+
+func (txn *txn) SetOutcome(o Outcome) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if !o.valid() {
+		return errInvalidOutcome
+	}
+	txn.Outcome = o
+	if OutcomeUnset == o {
+		delete(txn.Attrs.Agent, AttributeTransactionOutcome)
+	} else {
+		txn.Attrs.Agent.Add(AttributeTransactionOutcome, o.String(), nil)
+	}
+	return nil
+}
+
+// isRequestTooLarge returns true if the error, or the error it wraps, was
+// returned by an http.MaxBytesReader whose limit was exceeded.
+func isRequestTooLarge(err error) bool {
+	return requestTooLargeMessage == errorCause(err).Error()
+}
+
+// recordRequestTooLarge adds the AttributeRequestTooLarge attribute and sets
+// the outcome to OutcomeRequestTooLarge, unless SetOutcome has been called.
+// It must be called while the transaction is locked.
+func (txn *txn) recordRequestTooLarge() {
+	txn.Attrs.Agent.Add(AttributeRequestTooLarge, "", true)
+	if OutcomeUnset == txn.Outcome {
+		txn.Outcome = OutcomeRequestTooLarge
+		txn.Attrs.Agent.Add(AttributeTransactionOutcome, txn.Outcome.String(), nil)
+	}
+}
+
+// createOutcomeMetrics counts the transaction by its outcome, eg.
+// "TransactionOutcome/ClientError/all".
+func createOutcomeMetrics(args *txnData, metrics *metricTable) {
+	if OutcomeUnset == args.Outcome {
+		return
+	}
+	prefix := outcomeMetricPrefix + args.Outcome.String() + "/"
+	metrics.addSingleCount(prefix+"all", unforced)
+	if args.IsWeb {
+		metrics.addSingleCount(prefix+"allWeb", unforced)
+	} else {
+		metrics.addSingleCount(prefix+"allOther", unforced)
+	}
+	metrics.addSingleCount(prefix+args.FinalName, unforced)
+}
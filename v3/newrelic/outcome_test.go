@@ -0,0 +1,177 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestSetOutcome(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetOutcome(OutcomeSuccess)
+	txn.SetOutcome(OutcomeTimeout)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			AttributeTransactionOutcome: "Timeout",
+		},
+	}})
+	app.ExpectMetrics(t, append([]internal.WantMetric{
+		{Name: "TransactionOutcome/Timeout/all", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/Timeout/allOther", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/Timeout/OtherTransaction/Go/hello", Scope: "", Forced: false, Data: singleCount},
+	}, backgroundMetrics...))
+}
+
+func TestSetOutcomeWeb(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "/hello", nil)
+	txn.SetWebRequestHTTP(req)
+	txn.SetOutcome(OutcomeClientError)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "TransactionOutcome/ClientError/all", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/ClientError/allWeb", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/ClientError/WebTransaction/Go/hello", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestSetOutcomeUnset(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetOutcome(OutcomeServerError)
+	txn.SetOutcome(OutcomeUnset)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{},
+	}})
+	app.ExpectMetrics(t, backgroundMetrics)
+}
+
+func TestSetOutcomeInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetOutcome(Outcome(42))
+	app.expectSingleLoggedError(t, "unable to set outcome", map[string]interface{}{
+		"reason": errInvalidOutcome.Error(),
+	})
+}
+
+func TestSetOutcomeAfterEnd(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	txn.SetOutcome(OutcomeSuccess)
+	app.expectSingleLoggedError(t, "unable to set outcome", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestSetOutcomeNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.SetOutcome(OutcomeSuccess)
+}
+
+func TestOutcomeString(t *testing.T) {
+	testcases := map[Outcome]string{
+		OutcomeUnset:           "",
+		OutcomeSuccess:         "Success",
+		OutcomeClientError:     "ClientError",
+		OutcomeServerError:     "ServerError",
+		OutcomeCancelled:       "Cancelled",
+		OutcomeTimeout:         "Timeout",
+		OutcomeRequestTooLarge: "RequestTooLarge",
+		Outcome(42):            "",
+	}
+	for o, expect := range testcases {
+		if s := o.String(); s != expect {
+			t.Error(int(o), s, expect)
+		}
+	}
+}
+
+func TestRequestTooLargeResponseCode(t *testing.T) {
+	app := testApp(nil, nil, t)
+	_, handler := WrapHandleFunc(app.Application, "/upload", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+	})
+	req, _ := http.NewRequest("POST", "/upload", nil)
+	handler(httptest.NewRecorder(), req)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/POST /upload",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.method":            "POST",
+			"request.uri":               "/upload",
+			"http.flavor":               "1.1",
+			"http.statusCode":           413,
+			"httpResponseCode":          "413",
+			AttributeRequestTooLarge:    true,
+			AttributeTransactionOutcome: "RequestTooLarge",
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "TransactionOutcome/RequestTooLarge/all", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/RequestTooLarge/allWeb", Scope: "", Forced: false, Data: singleCount},
+		{Name: "TransactionOutcome/RequestTooLarge/WebTransaction/Go/POST /upload", Scope: "", Forced: false, Data: singleCount},
+	})
+}
+
+func TestRequestTooLargeNoticeError(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	w := txn.SetWebResponse(httptest.NewRecorder())
+	body := http.MaxBytesReader(w, ioutil.NopCloser(strings.NewReader("too large")), 3)
+	_, err := ioutil.ReadAll(body)
+	txn.NoticeError(err)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			AttributeRequestTooLarge:    true,
+			AttributeTransactionOutcome: "RequestTooLarge",
+		},
+	}})
+}
+
+func TestRequestTooLargeOutcomeSet(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetOutcome(OutcomeClientError)
+	txn.SetWebResponse(httptest.NewRecorder()).WriteHeader(http.StatusRequestEntityTooLarge)
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+		AgentAttributes: map[string]interface{}{
+			"http.statusCode":           413,
+			"httpResponseCode":          "413",
+			AttributeRequestTooLarge:    true,
+			AttributeTransactionOutcome: "ClientError",
+		},
+	}})
+}
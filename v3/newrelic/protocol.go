@@ -0,0 +1,68 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "github.com/newrelic/go-agent/v3/internal"
+
+// These constants describe the protocol and payload formats used by the Go
+// Agent.  They are exported so that tools which receive or validate the
+// agent's payloads, such as proxies and gateways, need not duplicate them.
+const (
+	// CollectorProtocolVersion is the version of the collector protocol,
+	// sent as the "protocol_version" query parameter of every request.
+	CollectorProtocolVersion = 17
+	// ServerlessMetadataVersion is the "metadata_version" of the payloads
+	// written in serverless mode.
+	ServerlessMetadataVersion = 2
+	// DistributedTracePayloadMajorVersion and
+	// DistributedTracePayloadMinorVersion are the version of the New Relic
+	// distributed tracing header payload, sent as its "v" field.
+	DistributedTracePayloadMajorVersion = 0
+	DistributedTracePayloadMinorVersion = 1
+	// W3CTraceParentVersion is the version of the W3C traceparent header.
+	W3CTraceParentVersion = "00"
+	// W3CTraceStateVersion is the version of the New Relic entry of the
+	// W3C tracestate header.
+	W3CTraceStateVersion = "0"
+)
+
+// These constants are the limits of the data collected by the Go Agent.  The
+// event limits are the default maximums per 60 second harvest cycle, which
+// may be lowered by Config or by New Relic at connect.
+const (
+	// MaxPayloadSizeInBytes is the maximum size of the uncompressed
+	// payload of a collector request, unless New Relic provides a
+	// different limit at connect.
+	MaxPayloadSizeInBytes = internal.MaxPayloadSizeInBytes
+	// MaxTxnEvents is the maximum number of transaction events.
+	MaxTxnEvents = internal.MaxTxnEvents
+	// MaxCustomEvents is the maximum number of custom events.
+	MaxCustomEvents = internal.MaxCustomEvents
+	// MaxErrorEvents is the maximum number of error events.
+	MaxErrorEvents = internal.MaxErrorEvents
+	// MaxSpanEvents is the maximum number of span events.
+	MaxSpanEvents = 1000
+	// MaxMetrics is the maximum number of metrics, excluding
+	// supportability metrics, in a harvest.
+	MaxMetrics = 2 * 1000
+	// MaxHarvestErrors and MaxHarvestSlowSQLs are the maximum number of
+	// error traces and slow queries in a harvest.
+	MaxHarvestErrors   = 20
+	MaxHarvestSlowSQLs = 10
+
+	// AttributeKeyLengthLimit is the maximum length in bytes of an
+	// attribute key.
+	AttributeKeyLengthLimit = 255
+	// AttributeValueLengthLimit is the default maximum length in bytes of
+	// an attribute value.  Config.AttributeLimits.MaxValueLength may raise
+	// it up to AttributeValueLengthMax.
+	AttributeValueLengthLimit = 255
+	AttributeValueLengthMax   = 4095
+	// AttributeUserLimit is the maximum number of user attributes on an
+	// event or trace.
+	AttributeUserLimit = 64
+	// CustomEventAttributeLimit is the maximum number of attributes on a
+	// custom event.
+	CustomEventAttributeLimit = 64
+)
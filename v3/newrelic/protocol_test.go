@@ -0,0 +1,41 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestProtocolVersionsUsed(t *testing.T) {
+	u := rpmURL(rpmCmd{Name: cmdConnect, Collector: "collector.newrelic.com"}, rpmControls{License: testLicenseKey})
+	if !strings.Contains(u, "protocol_version="+strconv.Itoa(CollectorProtocolVersion)) {
+		t.Error(u)
+	}
+	if v := currentDistTraceVersion; v.major() != DistributedTracePayloadMajorVersion || v.minor() != DistributedTracePayloadMinorVersion {
+		t.Error(v)
+	}
+	p := payload{TracedID: "0123456789abcdef0123456789abcdef", ID: "0123456789abcdef"}
+	if tp := p.W3CTraceParent(); !strings.HasPrefix(tp, W3CTraceParentVersion+"-") {
+		t.Error(tp)
+	}
+}
+
+func TestLimitsUsed(t *testing.T) {
+	run := newAppRun(config{Config: defaultConfig()}, internal.ConnectReplyDefaults())
+	if run.MaxTxnEvents() != MaxTxnEvents || run.MaxCustomEvents() != MaxCustomEvents ||
+		run.MaxErrorEvents() != MaxErrorEvents || run.MaxSpanEvents() != MaxSpanEvents {
+		t.Error(run.MaxTxnEvents(), run.MaxCustomEvents(), run.MaxErrorEvents(), run.MaxSpanEvents())
+	}
+	if run.Reply.MaxPayloadSizeInBytes != MaxPayloadSizeInBytes {
+		t.Error(run.Reply.MaxPayloadSizeInBytes)
+	}
+	cfg := defaultConfig()
+	if cfg.AttributeLimits.MaxValueLength != AttributeValueLengthLimit {
+		t.Error(cfg.AttributeLimits.MaxValueLength)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rollups holds the applications created to report data to the subsets of
+// the rollup application names listed in Config.ApplicationSubsets.  Each
+// subset is reported using its own connection, which is created along with
+// the parent application.
+type rollups struct {
+	sync.Mutex
+	apps map[string]*app
+}
+
+var (
+	errNoApplications         = errors.New("no application names provided")
+	errRollupServerless       = errors.New("application subsets are not supported in serverless mode")
+	errRollupApplicationNames = errors.New("application names must be a subset of the configured AppName")
+	errRollupNotConfigured    = errors.New("application subset not listed in ApplicationSubsets")
+)
+
+// appNames returns the rollup application names in Config.AppName.
+func (c config) appNames() []string {
+	return strings.Split(c.AppName, ";")
+}
+
+// rollupAppName validates that the names provided are a subset of the
+// configured rollup application names and returns the AppName to use for the
+// subset.  Names are ordered as they are in Config.AppName so that the same
+// subset always creates the same connection.
+func (c config) rollupAppName(names []string) (string, error) {
+	if 0 == len(names) {
+		return "", errNoApplications
+	}
+	requested := make(map[string]bool, len(names))
+	for _, n := range names {
+		requested[n] = true
+	}
+	var subset []string
+	for _, n := range c.appNames() {
+		if requested[n] {
+			subset = append(subset, n)
+			delete(requested, n)
+		}
+	}
+	if len(requested) > 0 {
+		return "", errRollupApplicationNames
+	}
+	return strings.Join(subset, ";"), nil
+}
+
+// validateApplicationSubsets checks that each of the ApplicationSubsets is
+// a subset of the rollup application names in AppName.
+func (c Config) validateApplicationSubsets() error {
+	if 0 == len(c.ApplicationSubsets) {
+		return nil
+	}
+	if c.ServerlessMode.Enabled {
+		return errRollupServerless
+	}
+	cfg := config{Config: c}
+	for _, subset := range c.ApplicationSubsets {
+		if _, err := cfg.rollupAppName(strings.Split(subset, ";")); nil != err {
+			return fmt.Errorf("invalid application subset %q: %v", subset, err)
+		}
+	}
+	return nil
+}
+
+// connect creates the applications of the parent's ApplicationSubsets, so
+// that they connect along with the parent.
+func (r *rollups) connect(parent *app) {
+	r.Lock()
+	defer r.Unlock()
+
+	for _, subset := range parent.config.ApplicationSubsets {
+		appName, err := parent.config.rollupAppName(strings.Split(subset, ";"))
+		if nil != err || appName == parent.config.AppName {
+			continue
+		}
+		if _, ok := r.apps[appName]; ok {
+			continue
+		}
+		if nil == r.apps {
+			r.apps = make(map[string]*app)
+		}
+		c := parent.config
+		c.Config = copyConfigReferenceFields(c.Config)
+		c.AppName = appName
+		c.ApplicationSubsets = nil
+		// Runtime statistics and the scaling signal are reported by
+		// the parent application.
+		c.RuntimeSampler.Enabled = false
+		c.ScalingSignal.Enabled = false
+		r.apps[appName] = newApp(c)
+	}
+}
+
+// harvestTesting makes the applications record their data for the tests of
+// the parent application, using its connect reply.
+func (r *rollups) harvestTesting(parent *app) {
+	for _, sub := range r.all() {
+		sub.placeholderRun = newAppRun(sub.config, parent.placeholderRun.Reply)
+		sub.testHarvest = newHarvest(time.Now(), sub.placeholderRun.harvestConfig)
+	}
+}
+
+// get returns the app which reports data to the subset of parent's
+// application names provided.  The subset must be listed in the parent's
+// ApplicationSubsets.
+func (r *rollups) get(parent *app, names []string) (*app, error) {
+	if parent.config.ServerlessMode.Enabled {
+		return nil, errRollupServerless
+	}
+	appName, err := parent.config.rollupAppName(names)
+	if nil != err {
+		return nil, fmt.Errorf("%v: %v", err, names)
+	}
+	if appName == parent.config.AppName {
+		return parent, nil
+	}
+
+	r.Lock()
+	defer r.Unlock()
+
+	if sub, ok := r.apps[appName]; ok {
+		return sub, nil
+	}
+	return nil, fmt.Errorf("%v: %s", errRollupNotConfigured, appName)
+}
+
+// all returns every application created by connect.
+func (r *rollups) all() []*app {
+	r.Lock()
+	defer r.Unlock()
+
+	apps := make([]*app, 0, len(r.apps))
+	for _, sub := range r.apps {
+		apps = append(apps, sub)
+	}
+	return apps
+}
+
+// shutdown shuts down every application created by connect.
+func (r *rollups) shutdown(timeout time.Duration) {
+	var wg sync.WaitGroup
+	for _, sub := range r.all() {
+		wg.Add(1)
+		go func(sub *app) {
+			defer wg.Done()
+			sub.Shutdown(timeout)
+		}(sub)
+	}
+	wg.Wait()
+}
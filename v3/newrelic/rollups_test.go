@@ -0,0 +1,186 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestRollupAppName(t *testing.T) {
+	c := config{Config: Config{AppName: "one;two;three"}}
+	testcases := []struct {
+		names  []string
+		expect string
+		err    bool
+	}{
+		{names: []string{"one"}, expect: "one"},
+		{names: []string{"three", "one"}, expect: "one;three"},
+		{names: []string{"two", "two"}, expect: "two"},
+		{names: []string{"three", "two", "one"}, expect: "one;two;three"},
+		{names: nil, err: true},
+		{names: []string{"one", "four"}, err: true},
+	}
+	for _, tc := range testcases {
+		name, err := c.rollupAppName(tc.names)
+		if tc.err != (nil != err) {
+			t.Error(tc.names, err)
+		}
+		if name != tc.expect {
+			t.Error(tc.names, name, tc.expect)
+		}
+	}
+}
+
+func TestSetApplicationsSubset(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.AppName = "one;two;three"
+		cfg.ApplicationSubsets = []string{"three;one", "two"}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.SetApplications([]string{"three", "one"})
+	if md := txn.GetLinkingMetadata(); md.EntityName != "one" {
+		t.Error(md.EntityName)
+	}
+	txn.Application().RecordCustomEvent("myEvent", map[string]interface{}{"zip": 1})
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	// The parent application receives nothing.
+	app.ExpectTxnEvents(t, []internal.WantEvent{})
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+
+	parent := internalApp(app)
+	sub, err := parent.rollups.get(parent, []string{"one", "three"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	if sub.config.AppName != "one;three" {
+		t.Error(sub.config.AppName)
+	}
+	sub.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+	}})
+	sub.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "myEvent",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"zip": 1,
+		},
+	}})
+}
+
+func TestSetApplicationsNotConfigured(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) {
+		cfg.AppName = "one;two;three"
+		cfg.ApplicationSubsets = []string{"one;two"}
+	}, t)
+	txn := app.StartTransaction("hello")
+	txn.SetApplications([]string{"three"})
+	txn.End()
+	// The transaction is reported to every application.
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+	}})
+	if n := len(internalApp(app).rollups.apps); n != 1 {
+		t.Error(n)
+	}
+}
+
+func TestSetApplicationsEntityGUID(t *testing.T) {
+	app := testApp(func(reply *internal.ConnectReply) {
+		reply.EntityGUID = "parent-guid"
+	}, func(cfg *Config) {
+		cfg.AppName = "one;two"
+		cfg.ApplicationSubsets = []string{"two"}
+	}, t)
+	parent := internalApp(app)
+	sub, err := parent.rollups.get(parent, []string{"two"})
+	if nil != err {
+		t.Fatal(err)
+	}
+	reply := internal.ConnectReplyDefaults()
+	reply.EntityGUID = "sub-guid"
+	sub.placeholderRun = newAppRun(sub.config, reply)
+
+	txn := app.StartTransaction("hello")
+	txn.SetApplications([]string{"two"})
+	if md := txn.GetLinkingMetadata(); "two" != md.EntityName || "sub-guid" != md.EntityGUID {
+		t.Error(md.EntityName, md.EntityGUID)
+	}
+	txn.End()
+}
+
+func TestValidateApplicationSubsets(t *testing.T) {
+	testcases := []struct {
+		subsets    []string
+		serverless bool
+		err        bool
+	}{
+		{subsets: nil},
+		{subsets: []string{"one", "two;one"}},
+		{subsets: []string{"one;four"}, err: true},
+		{subsets: []string{""}, err: true},
+		{subsets: []string{"one"}, serverless: true, err: true},
+	}
+	for _, tc := range testcases {
+		c := Config{AppName: "one;two;three", ApplicationSubsets: tc.subsets}
+		c.ServerlessMode.Enabled = tc.serverless
+		if err := c.validateApplicationSubsets(); tc.err != (nil != err) {
+			t.Error(tc.subsets, err)
+		}
+	}
+}
+
+func TestSetApplicationsAll(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) { cfg.AppName = "one;two" }, t)
+	txn := app.StartTransaction("hello")
+	txn.SetApplications([]string{"two", "one"})
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+	}})
+	if n := len(internalApp(app).rollups.apps); n != 0 {
+		t.Error(n)
+	}
+}
+
+func TestSetApplicationsUnknownName(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) { cfg.AppName = "one;two" }, t)
+	txn := app.StartTransaction("hello")
+	txn.SetApplications([]string{"three"})
+	app.expectSingleLoggedError(t, "unable to set transaction applications", map[string]interface{}{
+		"reason": errRollupApplicationNames.Error(),
+	})
+	txn.End()
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name": "OtherTransaction/Go/hello",
+		},
+	}})
+}
+
+func TestSetApplicationsAfterEnd(t *testing.T) {
+	app := testApp(nil, func(cfg *Config) { cfg.AppName = "one;two" }, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	txn.SetApplications([]string{"one"})
+	app.expectSingleLoggedError(t, "unable to set transaction applications", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func internalApp(a expectApp) *app {
+	return a.Private.(*app)
+}
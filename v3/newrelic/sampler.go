@@ -17,6 +17,7 @@ type systemSample struct {
 	usage        sysinfo.Usage
 	numGoroutine int
 	numCPU       int
+	runtime      runtimeMetricsSample
 }
 
 func bytesToMebibytesFloat(bts uint64) float64 {
@@ -40,6 +41,7 @@ func getSystemSample(now time.Time, lg Logger) *systemSample {
 	}
 
 	runtime.ReadMemStats(&s.memStats)
+	s.runtime = readRuntimeMetrics()
 
 	return &s
 }
@@ -61,6 +63,7 @@ type systemStats struct {
 	deltaPauseTotal time.Duration
 	minPause        time.Duration
 	maxPause        time.Duration
+	runtime         runtimeMetricsStats
 }
 
 // systemSamples is used as the parameter to getSystemStats to avoid mixing up the previous
@@ -80,6 +83,7 @@ func getSystemStats(ss systemSamples) systemStats {
 		numGoroutine: cur.numGoroutine,
 		allocBytes:   cur.memStats.Alloc,
 		heapObjects:  cur.memStats.HeapObjects,
+		runtime:      getRuntimeMetricsStats(prev.runtime, cur.runtime),
 	}
 
 	// CPU Utilization
@@ -126,14 +130,14 @@ func getSystemStats(ss systemSamples) systemStats {
 
 // MergeIntoHarvest implements Harvestable.
 func (s systemStats) MergeIntoHarvest(h *harvest) {
-	h.Metrics.addValue(heapObjectsAllocated, "", float64(s.heapObjects), forced)
-	h.Metrics.addValue(runGoroutine, "", float64(s.numGoroutine), forced)
-	h.Metrics.addValueExclusive(memoryPhysical, "", bytesToMebibytesFloat(s.allocBytes), 0, forced)
-	h.Metrics.addValueExclusive(cpuUserUtilization, "", s.user.fraction, 0, forced)
-	h.Metrics.addValueExclusive(cpuSystemUtilization, "", s.system.fraction, 0, forced)
+	h.Metrics.addGauge(heapObjectsAllocated, float64(s.heapObjects), forced)
+	h.Metrics.addGauge(runGoroutine, float64(s.numGoroutine), forced)
+	h.Metrics.addGaugeExclusive(memoryPhysical, bytesToMebibytesFloat(s.allocBytes), 0, forced)
+	h.Metrics.addGaugeExclusive(cpuUserUtilization, s.user.fraction, 0, forced)
+	h.Metrics.addGaugeExclusive(cpuSystemUtilization, s.system.fraction, 0, forced)
 	h.Metrics.addValue(cpuUserTime, "", s.user.used.Seconds(), forced)
 	h.Metrics.addValue(cpuSystemTime, "", s.system.used.Seconds(), forced)
-	h.Metrics.addValueExclusive(gcPauseFraction, "", s.gcPauseFraction, 0, forced)
+	h.Metrics.addGaugeExclusive(gcPauseFraction, s.gcPauseFraction, 0, forced)
 	if s.deltaNumGC > 0 {
 		h.Metrics.add(gcPauses, "", metricData{
 			countSatisfied:  float64(s.deltaNumGC),
@@ -144,4 +148,5 @@ func (s systemStats) MergeIntoHarvest(h *harvest) {
 			sumSquares:      s.deltaPauseTotal.Seconds() * s.deltaPauseTotal.Seconds(),
 		}, forced)
 	}
+	s.runtime.MergeIntoHarvest(h)
 }
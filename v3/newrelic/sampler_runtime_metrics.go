@@ -0,0 +1,108 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"math"
+	"time"
+)
+
+// runtimeMetricsSample contains the cumulative values read from the
+// runtime/metrics package which are not available from runtime.MemStats.
+type runtimeMetricsSample struct {
+	// ok is false if the runtime does not support these metrics.
+	ok bool
+	// schedulerLatency is the distribution of the time goroutines have
+	// spent waiting to run after becoming runnable.
+	schedulerLatency histogram
+	gcCPUSeconds     float64
+	totalCPUSeconds  float64
+	mutexWaitSeconds float64
+}
+
+// histogram is a copy of a runtime/metrics Float64Histogram.  There is one
+// more bucket boundary than there are counts: counts[i] is the number of
+// values in the range [buckets[i], buckets[i+1]).
+type histogram struct {
+	counts  []uint64
+	buckets []float64
+}
+
+// runtimeMetricsStats contains the runtime/metrics information for a period
+// of time.
+type runtimeMetricsStats struct {
+	ok               bool
+	schedulerLatency metricData
+	gcCPUFraction    float64
+	mutexWait        time.Duration
+}
+
+// bucketValue returns the value used to represent the values in bucket i:
+// the midpoint of the bucket, or its finite boundary if the bucket is
+// unbounded.
+func (h histogram) bucketValue(i int) float64 {
+	lower, upper := h.buckets[i], h.buckets[i+1]
+	if math.IsInf(lower, -1) {
+		return upper
+	}
+	if math.IsInf(upper, 1) {
+		return lower
+	}
+	return (lower + upper) / 2
+}
+
+// deltaMetricData summarizes the values added to the histogram since prev.
+func (h histogram) deltaMetricData(prev histogram) metricData {
+	var data metricData
+	if len(h.buckets) != len(h.counts)+1 || len(prev.counts) != len(h.counts) {
+		return data
+	}
+	for i, count := range h.counts {
+		if count <= prev.counts[i] {
+			continue
+		}
+		n := float64(count - prev.counts[i])
+		v := h.bucketValue(i)
+		if 0 == data.countSatisfied || v < data.min {
+			data.min = v
+		}
+		if v > data.max {
+			data.max = v
+		}
+		data.countSatisfied += n
+		data.totalTolerated += n * v
+		data.sumSquares += n * v * v
+	}
+	return data
+}
+
+// getRuntimeMetricsStats combines two runtimeMetricsSamples.
+func getRuntimeMetricsStats(prev, cur runtimeMetricsSample) runtimeMetricsStats {
+	if !prev.ok || !cur.ok {
+		return runtimeMetricsStats{}
+	}
+	s := runtimeMetricsStats{
+		ok:               true,
+		schedulerLatency: cur.schedulerLatency.deltaMetricData(prev.schedulerLatency),
+	}
+	if total := cur.totalCPUSeconds - prev.totalCPUSeconds; total > 0 {
+		s.gcCPUFraction = (cur.gcCPUSeconds - prev.gcCPUSeconds) / total
+	}
+	if wait := cur.mutexWaitSeconds - prev.mutexWaitSeconds; wait > 0 {
+		s.mutexWait = time.Duration(wait * float64(time.Second))
+	}
+	return s
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (s runtimeMetricsStats) MergeIntoHarvest(h *harvest) {
+	if !s.ok {
+		return
+	}
+	if s.schedulerLatency.countSatisfied > 0 {
+		h.Metrics.add(runSchedulerLatency, "", s.schedulerLatency, forced)
+	}
+	h.Metrics.addGaugeExclusive(runGCCPUFraction, s.gcCPUFraction, 0, forced)
+	h.Metrics.addValue(runMutexWait, "", s.mutexWait.Seconds(), forced)
+}
@@ -0,0 +1,47 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.20
+
+package newrelic
+
+import "runtime/metrics"
+
+var runtimeMetricsSamples = []string{
+	"/sched/latencies:seconds",
+	"/cpu/classes/gc/total:cpu-seconds",
+	"/cpu/classes/total:cpu-seconds",
+	"/sync/mutex/wait/total:seconds",
+}
+
+// readRuntimeMetrics reads the runtime metrics which are not available from
+// runtime.MemStats.
+func readRuntimeMetrics() runtimeMetricsSample {
+	samples := make([]metrics.Sample, len(runtimeMetricsSamples))
+	for i, name := range runtimeMetricsSamples {
+		samples[i].Name = name
+	}
+	metrics.Read(samples)
+
+	if metrics.KindFloat64Histogram != samples[0].Value.Kind() {
+		return runtimeMetricsSample{}
+	}
+	for _, sample := range samples[1:] {
+		if metrics.KindFloat64 != sample.Value.Kind() {
+			return runtimeMetricsSample{}
+		}
+	}
+	// The histogram returned may be reused by later calls to Read and
+	// so is copied.
+	latency := samples[0].Value.Float64Histogram()
+	return runtimeMetricsSample{
+		ok: true,
+		schedulerLatency: histogram{
+			counts:  append([]uint64(nil), latency.Counts...),
+			buckets: append([]float64(nil), latency.Buckets...),
+		},
+		gcCPUSeconds:     samples[1].Value.Float64(),
+		totalCPUSeconds:  samples[2].Value.Float64(),
+		mutexWaitSeconds: samples[3].Value.Float64(),
+	}
+}
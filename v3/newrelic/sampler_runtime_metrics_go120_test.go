@@ -0,0 +1,21 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build go1.20
+
+package newrelic
+
+import "testing"
+
+func TestReadRuntimeMetrics(t *testing.T) {
+	sample := readRuntimeMetrics()
+	if !sample.ok {
+		t.Fatal("runtime metrics not read")
+	}
+	if len(sample.schedulerLatency.buckets) != len(sample.schedulerLatency.counts)+1 {
+		t.Error(len(sample.schedulerLatency.buckets), len(sample.schedulerLatency.counts))
+	}
+	if sample.totalCPUSeconds < sample.gcCPUSeconds {
+		t.Error(sample.totalCPUSeconds, sample.gcCPUSeconds)
+	}
+}
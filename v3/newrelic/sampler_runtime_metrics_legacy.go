@@ -0,0 +1,12 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+// +build !go1.20
+
+package newrelic
+
+// readRuntimeMetrics returns an empty sample since the CPU and mutex
+// metrics were added to the runtime/metrics package in Go 1.20.
+func readRuntimeMetrics() runtimeMetricsSample {
+	return runtimeMetricsSample{}
+}
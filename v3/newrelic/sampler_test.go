@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"math"
 	"testing"
 	"time"
 
@@ -87,3 +88,43 @@ func TestMetricsCreatedEmpty(t *testing.T) {
 		{Name: "GC/System/Pause Fraction", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
 	})
 }
+
+func TestRuntimeMetricsStats(t *testing.T) {
+	inf := math.Inf(1)
+	prev := runtimeMetricsSample{
+		ok: true,
+		schedulerLatency: histogram{
+			counts:  []uint64{1, 0, 0},
+			buckets: []float64{math.Inf(-1), 0.001, 0.003, inf},
+		},
+		gcCPUSeconds:     1,
+		totalCPUSeconds:  10,
+		mutexWaitSeconds: 0.5,
+	}
+	cur := runtimeMetricsSample{
+		ok: true,
+		schedulerLatency: histogram{
+			counts:  []uint64{2, 2, 1},
+			buckets: []float64{math.Inf(-1), 0.001, 0.003, inf},
+		},
+		gcCPUSeconds:     1.5,
+		totalCPUSeconds:  12,
+		mutexWaitSeconds: 0.75,
+	}
+	stats := getRuntimeMetricsStats(prev, cur)
+
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	stats.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "Go/Runtime/Scheduler/Latency", Scope: "", Forced: true, Data: []float64{4, 0.008, 0, 0.001, 0.003, 0.000018}},
+		{Name: "Go/Runtime/GC/CPU Fraction", Scope: "", Forced: true, Data: []float64{1, 0.25, 0, 0.25, 0.25, 0.0625}},
+		{Name: "Go/Runtime/Mutex/Wait", Scope: "", Forced: true, Data: []float64{1, 0.25, 0.25, 0.25, 0.25, 0.0625}},
+	})
+}
+
+func TestRuntimeMetricsStatsUnsupported(t *testing.T) {
+	stats := getRuntimeMetricsStats(runtimeMetricsSample{}, runtimeMetricsSample{ok: true})
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	stats.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{})
+}
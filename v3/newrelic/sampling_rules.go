@@ -0,0 +1,55 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"fmt"
+	"path"
+)
+
+// SamplingRule sets the fraction of the transactions with matching names
+// which are sampled.  See Config.DistributedTracer.SamplingRules.
+type SamplingRule struct {
+	// NamePattern is matched against the name of the transaction, as
+	// provided to Application.StartTransaction or Transaction.SetName,
+	// using the syntax of path.Match.  For example, "* /checkout" matches
+	// the transactions created by WrapHandle for the "/checkout" pattern,
+	// which are named "GET /checkout", "POST /checkout", and so on.
+	NamePattern string
+	// Rate is the fraction of matching transactions which are sampled,
+	// from 0 (none) to 1 (all).
+	Rate float64
+}
+
+// validateSamplingRules checks the SamplingRules.
+func (c Config) validateSamplingRules() error {
+	for _, r := range c.DistributedTracer.SamplingRules {
+		if "" == r.NamePattern {
+			return fmt.Errorf("sampling rule has an empty NamePattern")
+		}
+		if _, err := path.Match(r.NamePattern, ""); nil != err {
+			return fmt.Errorf("invalid sampling rule NamePattern %q: %v", r.NamePattern, err)
+		}
+		if r.Rate < 0 || r.Rate > 1 {
+			return fmt.Errorf("invalid sampling rule Rate %v for NamePattern %q: must be between 0 and 1", r.Rate, r.NamePattern)
+		}
+	}
+	return nil
+}
+
+// samplingRule returns the first SamplingRule matching the transaction name.
+func (c Config) samplingRule(name string) (SamplingRule, bool) {
+	for _, r := range c.DistributedTracer.SamplingRules {
+		if ok, _ := path.Match(r.NamePattern, name); ok {
+			return r, true
+		}
+	}
+	return SamplingRule{}, false
+}
+
+// computeRuleSampled decides whether a transaction matching the rule is
+// sampled.
+func computeRuleSampled(r SamplingRule) bool {
+	return float64(randFloat32()) < r.Rate
+}
@@ -0,0 +1,118 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestValidateSamplingRules(t *testing.T) {
+	testcases := []struct {
+		rules  []SamplingRule
+		expect bool
+	}{
+		{rules: nil, expect: true},
+		{rules: []SamplingRule{{NamePattern: "* /checkout", Rate: 1}}, expect: true},
+		{rules: []SamplingRule{{NamePattern: "GET /healthz", Rate: 0}}, expect: true},
+		{rules: []SamplingRule{{NamePattern: "", Rate: 1}}, expect: false},
+		{rules: []SamplingRule{{NamePattern: "GET /[", Rate: 1}}, expect: false},
+		{rules: []SamplingRule{{NamePattern: "GET /healthz", Rate: -0.1}}, expect: false},
+		{rules: []SamplingRule{{NamePattern: "GET /healthz", Rate: 1.5}}, expect: false},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		ConfigSamplingRules(tc.rules)(&cfg)
+		if err := cfg.validateSamplingRules(); (nil == err) != tc.expect {
+			t.Error(tc.rules, err)
+		}
+	}
+}
+
+func TestSamplingRuleMatch(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.DistributedTracer.SamplingRules = []SamplingRule{
+		{NamePattern: "* /checkout", Rate: 1},
+		{NamePattern: "POST /*", Rate: 0.5},
+		{NamePattern: "GET /healthz", Rate: 0.01},
+	}
+	testcases := []struct {
+		name   string
+		expect float64
+		match  bool
+	}{
+		{name: "GET /checkout", expect: 1, match: true},
+		{name: "POST /checkout", expect: 1, match: true},
+		{name: "POST /cart", expect: 0.5, match: true},
+		{name: "GET /healthz", expect: 0.01, match: true},
+		{name: "GET /checkout/cart", match: false},
+		{name: "background job", match: false},
+	}
+	for _, tc := range testcases {
+		r, ok := cfg.samplingRule(tc.name)
+		if ok != tc.match || r.Rate != tc.expect {
+			t.Error(tc.name, r, ok)
+		}
+	}
+}
+
+func TestSamplingRulesTransactions(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		ConfigSamplingRules([]SamplingRule{
+			{NamePattern: "* /checkout", Rate: 1},
+			{NamePattern: "GET /healthz", Rate: 0},
+		})(cfg)
+	}
+	app := testApp(replyfn, cfgfn, t)
+
+	testcases := []struct {
+		name   string
+		expect bool
+	}{
+		{name: "POST /checkout", expect: true},
+		{name: "GET /healthz", expect: false},
+		{name: "GET /search", expect: true},
+	}
+	for _, tc := range testcases {
+		txn := app.StartTransaction(tc.name)
+		if sampled := txn.IsSampled(); sampled != tc.expect {
+			t.Error(tc.name, sampled)
+		}
+		if p := txn.thread.BetterCAT.Priority; (p >= 1) != tc.expect {
+			t.Error(tc.name, p)
+		}
+		txn.End()
+	}
+	app.expectNoLoggedErrors(t)
+}
+
+func TestSamplingRulesBypassAdaptiveSampler(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	cfgfn := func(cfg *Config) {
+		enableW3COnly(cfg)
+		cfg.DistributedTracer.SamplingRules = []SamplingRule{{NamePattern: "* /checkout", Rate: 1}}
+	}
+	app := testApp(replyfn, cfgfn, t)
+
+	txn := app.StartTransaction("GET /checkout")
+	if !txn.IsSampled() {
+		t.Error("checkout transaction should be sampled by its rule")
+	}
+	txn.End()
+	txn = app.StartTransaction("GET /search")
+	if txn.IsSampled() {
+		t.Error("search transaction should use the adaptive sampler")
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+}
@@ -0,0 +1,133 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"runtime"
+	"sync"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal/sysinfo"
+)
+
+const (
+	// scalingSignalPeriod is how often the ScalingSignal metrics are
+	// recorded.  It is shorter than the harvest period so that the
+	// metrics' maximum reflects short bursts.
+	scalingSignalPeriod = 5 * time.Second
+
+	scalingQueueTime      = "ScalingSignal/QueueTime"
+	scalingInFlight       = "ScalingSignal/InFlight"
+	scalingCPUUtilization = "ScalingSignal/CPU Utilization"
+	scalingPressure       = "ScalingSignal/Pressure"
+)
+
+// validateScalingSignal checks the ScalingSignal targets if the metrics are
+// enabled.
+func (c Config) validateScalingSignal() error {
+	s := c.ScalingSignal
+	if !s.Enabled {
+		return nil
+	}
+	if s.TargetQueueTime <= 0 || s.TargetInFlightPerProc <= 0 ||
+		s.TargetCPUUtilization <= 0 || s.TargetCPUUtilization > 1 {
+		return errScalingSignalTargets
+	}
+	return nil
+}
+
+// queueTimes accumulates the queue times of the web requests which arrived
+// since the last scaling signal sample.
+type queueTimes struct {
+	sync.Mutex
+	total time.Duration
+	count int
+}
+
+func (q *queueTimes) add(d time.Duration) {
+	q.Lock()
+	defer q.Unlock()
+
+	q.total += d
+	q.count++
+}
+
+// reset returns the average queue time and clears the accumulated times.
+func (q *queueTimes) reset() time.Duration {
+	q.Lock()
+	defer q.Unlock()
+
+	var avg time.Duration
+	if q.count > 0 {
+		avg = q.total / time.Duration(q.count)
+	}
+	q.total = 0
+	q.count = 0
+	return avg
+}
+
+// scalingSample is a single measurement of the ScalingSignal metrics.
+type scalingSample struct {
+	queueTime      time.Duration
+	inFlight       float64
+	cpuUtilization float64
+	pressure       float64
+}
+
+// newScalingSample combines the components into a scalingSample.  The
+// Pressure is the largest component relative to its target.
+func newScalingSample(c Config, queueTime time.Duration, inFlight int, cpu time.Duration, elapsed time.Duration, procs int) scalingSample {
+	s := scalingSample{queueTime: queueTime}
+	if procs > 0 {
+		s.inFlight = float64(inFlight) / float64(procs)
+		if elapsed > 0 {
+			s.cpuUtilization = cpu.Seconds() / (elapsed.Seconds() * float64(procs))
+		}
+	}
+	target := c.ScalingSignal
+	for _, p := range []float64{
+		queueTime.Seconds() / target.TargetQueueTime.Seconds(),
+		s.inFlight / target.TargetInFlightPerProc,
+		s.cpuUtilization / target.TargetCPUUtilization,
+	} {
+		if p > s.pressure {
+			s.pressure = p
+		}
+	}
+	return s
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (s scalingSample) MergeIntoHarvest(h *harvest) {
+	h.Metrics.addValue(scalingQueueTime, "", s.queueTime.Seconds(), forced)
+	h.Metrics.addValue(scalingInFlight, "", s.inFlight, forced)
+	h.Metrics.addValue(scalingCPUUtilization, "", s.cpuUtilization, forced)
+	h.Metrics.addValue(scalingPressure, "", s.pressure, forced)
+}
+
+// scalingSignal returns the function which records the ScalingSignal
+// metrics of the period since it was previously called.
+func scalingSignal(app *app) func(now time.Time) {
+	previous, _ := sysinfo.GetUsage()
+	previousTime := time.Now()
+
+	return func(now time.Time) {
+		usage, err := sysinfo.GetUsage()
+		var cpu time.Duration
+		if nil == err && previous.User != 0 {
+			cpu = (usage.User - previous.User) + (usage.System - previous.System)
+		}
+		run, _ := app.getState()
+		app.Consume(run.Reply.RunID, newScalingSample(app.config.Config,
+			app.scalingQueueTimes.reset(),
+			app.inFlight.count(),
+			cpu,
+			now.Sub(previousTime),
+			runtime.GOMAXPROCS(0)))
+		if nil == err {
+			previous = usage
+		}
+		previousTime = now
+	}
+}
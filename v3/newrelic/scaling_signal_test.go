@@ -0,0 +1,106 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestValidateScalingSignal(t *testing.T) {
+	testcases := []struct {
+		fn     func(cfg *Config)
+		expect bool
+	}{
+		{fn: func(cfg *Config) {}, expect: true},
+		{fn: func(cfg *Config) { cfg.ScalingSignal.TargetQueueTime = 0 }, expect: false},
+		{fn: func(cfg *Config) { cfg.ScalingSignal.TargetInFlightPerProc = -1 }, expect: false},
+		{fn: func(cfg *Config) { cfg.ScalingSignal.TargetCPUUtilization = 0 }, expect: false},
+		{fn: func(cfg *Config) { cfg.ScalingSignal.TargetCPUUtilization = 1.5 }, expect: false},
+		{fn: func(cfg *Config) { cfg.ScalingSignal.TargetCPUUtilization = 1 }, expect: true},
+		{fn: func(cfg *Config) { cfg.ScalingSignal = Config{}.ScalingSignal }, expect: true},
+	}
+	for i, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.ScalingSignal.Enabled = true
+		tc.fn(&cfg)
+		if err := cfg.validateScalingSignal(); (nil == err) != tc.expect {
+			t.Error(i, err)
+		}
+	}
+}
+
+func TestQueueTimesReset(t *testing.T) {
+	q := &queueTimes{}
+	if avg := q.reset(); 0 != avg {
+		t.Error(avg)
+	}
+	q.add(100 * time.Millisecond)
+	q.add(300 * time.Millisecond)
+	if avg := q.reset(); 200*time.Millisecond != avg {
+		t.Error(avg)
+	}
+	if avg := q.reset(); 0 != avg {
+		t.Error(avg)
+	}
+}
+
+func TestScalingSampleMetrics(t *testing.T) {
+	cfg := defaultConfig()
+	testcases := []struct {
+		queueTime time.Duration
+		inFlight  int
+		cpu       time.Duration
+		expect    scalingSample
+	}{
+		// Each component in turn is the largest relative to its target.
+		{queueTime: 100 * time.Millisecond, inFlight: 8, cpu: 2 * time.Second,
+			expect: scalingSample{queueTime: 100 * time.Millisecond, inFlight: 2, cpuUtilization: 0.1, pressure: 2}},
+		{queueTime: 0, inFlight: 48, cpu: 2 * time.Second,
+			expect: scalingSample{inFlight: 12, cpuUtilization: 0.1, pressure: 1.5}},
+		{queueTime: 0, inFlight: 0, cpu: 14 * time.Second,
+			expect: scalingSample{cpuUtilization: 0.7, pressure: 1}},
+	}
+	for _, tc := range testcases {
+		s := newScalingSample(cfg, tc.queueTime, tc.inFlight, tc.cpu, 5*time.Second, 4)
+		if s != tc.expect {
+			t.Error(s, tc.expect)
+		}
+	}
+
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	scalingSample{queueTime: 500 * time.Millisecond, inFlight: 2, cpuUtilization: 0.25, pressure: 10}.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "ScalingSignal/QueueTime", Scope: "", Forced: true, Data: []float64{1, 0.5, 0.5, 0.5, 0.5, 0.25}},
+		{Name: "ScalingSignal/InFlight", Scope: "", Forced: true, Data: []float64{1, 2, 2, 2, 2, 4}},
+		{Name: "ScalingSignal/CPU Utilization", Scope: "", Forced: true, Data: []float64{1, 0.25, 0.25, 0.25, 0.25, 0.0625}},
+		{Name: "ScalingSignal/Pressure", Scope: "", Forced: true, Data: []float64{1, 10, 10, 10, 10, 100}},
+	})
+}
+
+func TestScalingSignalQueueTime(t *testing.T) {
+	app := testApp(nil, nil, t)
+	internalApp(app).scalingQueueTimes = &queueTimes{}
+
+	txn := app.StartTransaction("hello")
+	req, _ := http.NewRequest("GET", "http://example.com/hello", nil)
+	start := time.Now().Add(-2 * time.Second)
+	req.Header.Set("X-Request-Start", "t="+strconv.FormatInt(start.UnixNano()/int64(time.Microsecond), 10))
+	txn.SetWebRequestHTTP(req)
+	if n := internalApp(app).inFlight.count(); 1 != n {
+		t.Error(n)
+	}
+	txn.End()
+	if n := internalApp(app).inFlight.count(); 0 != n {
+		t.Error(n)
+	}
+
+	if avg := internalApp(app).scalingQueueTimes.reset(); avg < time.Second || avg > 3*time.Second {
+		t.Error(avg)
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+// SecurityPolicyEffect describes agent behavior which has been restricted by
+// Config.HighSecurity or by a security policy set for the account.
+//
+// https://docs.newrelic.com/docs/agents/manage-apm-agents/configuration/high-security-mode
+// https://docs.newrelic.com/docs/agents/manage-apm-agents/configuration/enable-configurable-security-policies
+type SecurityPolicyEffect struct {
+	// Policy is the name of the restricted setting, for example
+	// "record_sql".
+	Policy string
+	// HighSecurity is true if the restriction is caused by
+	// Config.HighSecurity rather than by a security policy.
+	HighSecurity bool
+	// Effect describes how the agent's behavior has changed.
+	Effect string
+}
+
+func (e SecurityPolicyEffect) String() string {
+	source := "security policy"
+	if e.HighSecurity {
+		source = "high security"
+	}
+	return e.Policy + ": " + e.Effect + " (" + source + ")"
+}
+
+const (
+	policyRecordSQL                 = "record_sql"
+	policyAttributesInclude         = "attributes_include"
+	policyAllowRawExceptionMessages = "allow_raw_exception_messages"
+	policyCustomEvents              = "custom_events"
+	policyCustomParameters          = "custom_parameters"
+)
+
+// hasAttributeIncludes returns true if any attribute include rules are
+// configured.
+func (c config) hasAttributeIncludes() bool {
+	for _, dc := range []AttributeDestinationConfig{
+		c.Attributes,
+		c.ErrorCollector.Attributes,
+		c.TransactionEvents.Attributes,
+		c.TransactionTracer.Attributes,
+		c.BrowserMonitoring.Attributes,
+		c.SpanEvents.Attributes,
+		c.TransactionTracer.Segments.Attributes,
+	} {
+		if len(dc.Include) > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// securityPolicyEffects returns the configured behavior which is restricted
+// by high security mode or the security policies provided.  Only settings
+// whose behavior actually changes are included.
+func securityPolicyEffects(c config, sp internal.SecurityPolicies) []SecurityPolicyEffect {
+	var effects []SecurityPolicyEffect
+	add := func(policy string, highSecurity bool, effect string) {
+		effects = append(effects, SecurityPolicyEffect{
+			Policy:       policy,
+			HighSecurity: highSecurity,
+			Effect:       effect,
+		})
+	}
+
+	if c.HighSecurity {
+		if c.DatastoreTracer.QueryParameters.Enabled {
+			add(policyRecordSQL, true, "datastore query parameters are not recorded")
+		}
+		add(policyAllowRawExceptionMessages, true, "error messages are replaced")
+		if c.CustomInsightsEvents.Enabled {
+			add(policyCustomEvents, true, "custom events are not recorded")
+		}
+		add(policyCustomParameters, true, "custom attributes are not recorded")
+		return effects
+	}
+
+	if sp.RecordSQL.IsSet() {
+		if !sp.RecordSQL.Enabled() {
+			add(policyRecordSQL, false, "SQL queries are not recorded")
+		} else if c.DatastoreTracer.QueryParameters.Enabled {
+			add(policyRecordSQL, false, "datastore query parameters are not recorded")
+		}
+	}
+	if !sp.AttributesInclude.Enabled() && c.hasAttributeIncludes() {
+		add(policyAttributesInclude, false, "attribute include rules are ignored")
+	}
+	if !sp.AllowRawExceptionMessages.Enabled() {
+		add(policyAllowRawExceptionMessages, false, "error messages are replaced")
+	}
+	if !sp.CustomEvents.Enabled() && c.CustomInsightsEvents.Enabled {
+		add(policyCustomEvents, false, "custom events are not recorded")
+	}
+	if !sp.CustomParameters.Enabled() {
+		add(policyCustomParameters, false, "custom attributes are not recorded")
+	}
+	return effects
+}
+
+// securityPolicySummary joins the effects into a single log friendly string.
+func securityPolicySummary(effects []SecurityPolicyEffect) string {
+	s := make([]string, len(effects))
+	for i, e := range effects {
+		s[i] = e.String()
+	}
+	return strings.Join(s, "; ")
+}
@@ -0,0 +1,90 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestSecurityPolicyEffectsNone(t *testing.T) {
+	c := config{Config: defaultConfig()}
+	if effects := securityPolicyEffects(c, internal.SecurityPolicies{}); nil != effects {
+		t.Error(effects)
+	}
+}
+
+func TestSecurityPolicyEffectsHighSecurity(t *testing.T) {
+	c := config{Config: defaultConfig()}
+	c.HighSecurity = true
+	c.CustomInsightsEvents.Enabled = false
+	effects := securityPolicyEffects(c, internal.SecurityPolicies{})
+	expect := []SecurityPolicyEffect{
+		{Policy: "record_sql", HighSecurity: true, Effect: "datastore query parameters are not recorded"},
+		{Policy: "allow_raw_exception_messages", HighSecurity: true, Effect: "error messages are replaced"},
+		{Policy: "custom_parameters", HighSecurity: true, Effect: "custom attributes are not recorded"},
+	}
+	if !reflect.DeepEqual(effects, expect) {
+		t.Error(effects)
+	}
+}
+
+func TestSecurityPolicyEffectsPolicies(t *testing.T) {
+	c := config{Config: defaultConfig()}
+	c.Attributes.Include = []string{"zip"}
+	var sp internal.SecurityPolicies
+	sp.RecordSQL.SetEnabled(false)
+	sp.AttributesInclude.SetEnabled(false)
+	sp.AllowRawExceptionMessages.SetEnabled(true)
+	sp.CustomEvents.SetEnabled(false)
+	sp.CustomParameters.SetEnabled(false)
+	effects := securityPolicyEffects(c, sp)
+	expect := []SecurityPolicyEffect{
+		{Policy: "record_sql", Effect: "SQL queries are not recorded"},
+		{Policy: "attributes_include", Effect: "attribute include rules are ignored"},
+		{Policy: "custom_events", Effect: "custom events are not recorded"},
+		{Policy: "custom_parameters", Effect: "custom attributes are not recorded"},
+	}
+	if !reflect.DeepEqual(effects, expect) {
+		t.Error(effects)
+	}
+	summary := "record_sql: SQL queries are not recorded (security policy); " +
+		"attributes_include: attribute include rules are ignored (security policy); " +
+		"custom_events: custom events are not recorded (security policy); " +
+		"custom_parameters: custom attributes are not recorded (security policy)"
+	if s := securityPolicySummary(effects); s != summary {
+		t.Error(s)
+	}
+
+	// Policies which do not change configured behavior are omitted.
+	c.Attributes.Include = nil
+	c.CustomInsightsEvents.Enabled = false
+	sp.RecordSQL.SetEnabled(true)
+	c.DatastoreTracer.QueryParameters.Enabled = false
+	if effects := securityPolicyEffects(c, sp); !reflect.DeepEqual(effects, []SecurityPolicyEffect{
+		{Policy: "custom_parameters", Effect: "custom attributes are not recorded"},
+	}) {
+		t.Error(effects)
+	}
+}
+
+func TestApplicationSecurityPolicyEffects(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SecurityPolicies.CustomParameters.SetEnabled(false)
+	}
+	app := testApp(replyfn, nil, t)
+	effects := app.SecurityPolicyEffects()
+	if !reflect.DeepEqual(effects, []SecurityPolicyEffect{
+		{Policy: "custom_parameters", Effect: "custom attributes are not recorded"},
+	}) {
+		t.Error(effects)
+	}
+
+	var nilApp *Application
+	if effects := nilApp.SecurityPolicyEffects(); nil != effects {
+		t.Error(effects)
+	}
+}
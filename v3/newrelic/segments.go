@@ -4,7 +4,9 @@
 package newrelic
 
 import (
+	"io"
 	"net/http"
+	"time"
 )
 
 // SegmentStartTime is created by Transaction.StartSegmentNow and marks the
@@ -91,7 +93,8 @@ type ExternalSegment struct {
 	// Procedure is an optional field that can be set to the remote
 	// procedure being called.  If set, this value will be used in metrics,
 	// transaction trace segment names, and span event names.  If unset, the
-	// request's http method is used.
+	// request's http method is used.  StartExternalSegment sets this field
+	// using Config.ExternalProcedure, if configured.
 	Procedure string
 	// Library is an optional field that defaults to "http".  It is used for
 	// external metrics and the "component" span attribute.  It should be
@@ -122,6 +125,49 @@ type MessageProducerSegment struct {
 	DestinationTemporary bool
 }
 
+// IOSegment instruments reads and writes of files and streaming to and from
+// object storage.  Use it for large transfers, such as media files, whose
+// time would otherwise be attributed to the surrounding code.  IOSegments
+// create metrics named "IO/{Product}/{Operation}".
+type IOSegment struct {
+	StartTime SegmentStartTime
+
+	// Product is the storage system, eg. IOProductFile or IOProductS3.  It
+	// defaults to IOProductFile.
+	Product string
+	// Operation is the operation performed.  It defaults to
+	// IOOperationRead.
+	Operation IOOperation
+	// Location is an optional field which can be set to the file path, or
+	// bucket and object key.  It is not used in metric names.
+	Location string
+	// StorageClass is an optional field which can be set to the storage
+	// class of the object, eg. "STANDARD" or "GLACIER".
+	StorageClass string
+	// Bytes is the number of bytes transferred.  It is used to calculate
+	// the throughput of the segment.  Bytes is updated automatically when
+	// using the io.Reader and io.Writer returned by the Reader and Writer
+	// methods.
+	Bytes int64
+}
+
+// IOOperation is used for the IOSegment.Operation field.
+type IOOperation string
+
+// These constants are used for the IOSegment.Operation field.
+const (
+	IOOperationRead  IOOperation = "Read"
+	IOOperationWrite IOOperation = "Write"
+)
+
+// These constants are used for the IOSegment.Product field.
+const (
+	IOProductFile      = "File"
+	IOProductS3        = "S3"
+	IOProductGCS       = "GCS"
+	IOProductAzureBlob = "AzureBlob"
+)
+
 // MessageDestinationType is used for the MessageSegment.DestinationType field.
 type MessageDestinationType string
 
@@ -169,10 +215,18 @@ func (s *DatastoreSegment) AddAttribute(key string, val interface{}) {
 
 // End finishes the datastore segment.
 func (s *DatastoreSegment) End() {
+	s.EndAt(time.Now())
+}
+
+// EndAt finishes the datastore segment at the time provided rather than
+// now.  Together with Transaction.StartSegmentAt, it records calls which are
+// observed after they have completed, such as through the observer hooks of
+// a database driver.
+func (s *DatastoreSegment) EndAt(end time.Time) {
 	if nil == s {
 		return
 	}
-	if err := endDatastore(s); err != nil {
+	if err := endDatastore(s, end); err != nil {
 		s.StartTime.thread.logAPIError(err, "end datastore segment", map[string]interface{}{
 			"product":    s.Product,
 			"collection": s.Collection,
@@ -234,6 +288,72 @@ func (s *MessageProducerSegment) End() {
 	}
 }
 
+// AddAttribute adds a key value pair to the current IOSegment.
+//
+// The key must contain fewer than than 255 bytes.  The value must be a
+// number, string, or boolean.
+func (s *IOSegment) AddAttribute(key string, val interface{}) {
+	if nil == s {
+		return
+	}
+	addSpanAttr(s.StartTime, key, val)
+}
+
+// End finishes the IO segment.
+func (s *IOSegment) End() {
+	if nil == s {
+		return
+	}
+	if err := endIO(s); err != nil {
+		s.StartTime.thread.logAPIError(err, "end io segment", map[string]interface{}{
+			"product":   s.Product,
+			"operation": s.Operation,
+		})
+	}
+}
+
+// Reader returns an io.Reader which reads from r and adds the number of bytes
+// read to the segment's Bytes field.  The io.Reader must not be used
+// concurrently with End.
+func (s *IOSegment) Reader(r io.Reader) io.Reader {
+	if nil == s {
+		return r
+	}
+	return ioSegmentReader{r: r, s: s}
+}
+
+// Writer returns an io.Writer which writes to w and adds the number of bytes
+// written to the segment's Bytes field.  The io.Writer must not be used
+// concurrently with End.
+func (s *IOSegment) Writer(w io.Writer) io.Writer {
+	if nil == s {
+		return w
+	}
+	return ioSegmentWriter{w: w, s: s}
+}
+
+type ioSegmentReader struct {
+	r io.Reader
+	s *IOSegment
+}
+
+func (r ioSegmentReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.s.Bytes += int64(n)
+	return n, err
+}
+
+type ioSegmentWriter struct {
+	w io.Writer
+	s *IOSegment
+}
+
+func (w ioSegmentWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.s.Bytes += int64(n)
+	return n, err
+}
+
 // SetStatusCode sets the status code for the response of this ExternalSegment.
 // This status code will be included as an attribute on Span Events.  If status
 // code is not set using this method, then the status code found on the
@@ -286,6 +406,7 @@ func StartExternalSegment(txn *Transaction, request *http.Request) *ExternalSegm
 	s := &ExternalSegment{
 		StartTime: txn.StartSegmentNow(),
 		Request:   request,
+		Procedure: txn.externalProcedure(request),
 	}
 
 	if request != nil && request.Header != nil {
@@ -303,13 +424,10 @@ func addSpanAttr(start SegmentStartTime, key string, val interface{}) {
 	if nil == start.thread {
 		return
 	}
-	validatedVal, err := validateUserAttribute(key, val)
-	if nil != err {
-		start.thread.logAPIError(err, "add segment attribute", map[string]interface{}{})
-		return
-	}
 	// This call locks the thread for us, so we don't need to.
-	if err := start.thread.AddUserSpanAttribute(key, validatedVal); err != nil {
+	if err := start.thread.AddUserSpanAttribute(key, val); err != nil {
 		start.thread.logAPIError(err, "add segment attribute", map[string]interface{}{})
+		return
 	}
+	start.thread.reportAttributeOverflow(key, val, destSpan)
 }
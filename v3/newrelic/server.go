@@ -0,0 +1,283 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"crypto/tls"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// WrapServer instruments an http.Server.  Each request handled by the server
+// is given a Transaction, as with WrapHandle, and the server's connections
+// are tracked using its ConnState hook to record the following metrics:
+//
+//	HttpServer/Connections/New       connections accepted
+//	HttpServer/Connections/Active    connections currently serving a request
+//	HttpServer/Connections/Idle      connections waiting for a request
+//	HttpServer/Connections/Hijacked  connections taken over by a handler
+//	HttpServer/Connections/Closed    connections closed by the server
+//	HttpServer/TLS/Handshake         duration of TLS handshakes, measured
+//	                                 from when the connection is accepted
+//	                                 until its first request is read
+//
+// The connections are counted as the server changes their state, and the
+// counts are reported when metrics are harvested, so the active and idle
+// connections are those at the time of each harvest.
+//
+// To help distinguish slow clients from slow handlers, the time spent
+// reading the request body and writing the response is recorded using the
+// HttpServer/Request/Read and HttpServer/Response/Write metrics and the
+// AttributeRequestReadDuration and AttributeResponseWriteDuration
+// attributes.
+//
+// WrapServer must be called before the server is started:
+//
+//	srv := &http.Server{Addr: ":8000", Handler: mux}
+//	newrelic.WrapServer(app, srv)
+//	srv.ListenAndServe()
+//
+// Transactions are named using the request method and, when the server's
+// handler is an http.ServeMux (or nil, meaning http.DefaultServeMux), the
+// pattern which matched the request.  Otherwise use Transaction.SetName
+// within the handler to name the Transaction.  Any existing ConnState hook
+// continues to be called.  Connections which are hijacked, for example by
+// websocket handlers, are no longer counted as active or idle.
+//
+// The WrapServer function is safe to call if app or server is nil.
+func WrapServer(app *Application, server *http.Server) {
+	if nil == app || nil == app.app || nil == server {
+		return
+	}
+	handler := server.Handler
+	if nil == handler {
+		handler = http.DefaultServeMux
+	}
+	server.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		txn := app.app.StartTransaction(serverTransactionName(handler, r))
+		defer txn.End()
+
+		timing := &requestTiming{}
+		defer timing.record(txn.thread)
+
+		w = txn.thread.setWebResponse(w, timing)
+		txn.SetWebRequestHTTP(r)
+
+		if nil != r.Body {
+			r.Body = timedBody{ReadCloser: r.Body, timing: timing}
+		}
+		r = RequestWithTransactionContext(r, txn)
+
+		handler.ServeHTTP(w, r)
+	})
+
+	conns := newServerConnections()
+	app.app.aggregates.addServer(conns)
+	original := server.ConnState
+	server.ConnState = func(c net.Conn, state http.ConnState) {
+		conns.transition(c, state)
+		if nil != original {
+			original(c, state)
+		}
+	}
+}
+
+func serverTransactionName(handler http.Handler, r *http.Request) string {
+	mux, ok := handler.(*http.ServeMux)
+	if !ok {
+		return r.Method
+	}
+	_, pattern := mux.Handler(r)
+	if "" == pattern {
+		return r.Method
+	}
+	// Patterns may already begin with a method, eg. "GET /users".
+	if strings.HasPrefix(pattern, r.Method+" ") {
+		return pattern
+	}
+	return r.Method + " " + pattern
+}
+
+// serverConnections tracks the state of the connections of a server
+// instrumented by WrapServer.  The counts are kept using atomic operations
+// and reported when metrics are harvested, rather than recorded for every
+// transition.
+type serverConnections struct {
+	// These counts are accessed atomically.  They are first in the struct
+	// so that they are 64-bit aligned on 32-bit platforms.
+	opened   int64
+	hijacked int64
+	closed   int64
+	active   int64
+	idle     int64
+
+	sync.Mutex
+	states map[net.Conn]http.ConnState
+	// accepted contains when the TLS connections which have not yet
+	// read a request were accepted.  The server performs the handshake
+	// before reading the first request.
+	accepted map[net.Conn]time.Time
+	// handshakes contains the durations of the TLS handshakes since the
+	// previous harvest.
+	handshakes metricData
+}
+
+func newServerConnections() *serverConnections {
+	return &serverConnections{
+		states:   make(map[net.Conn]http.ConnState),
+		accepted: make(map[net.Conn]time.Time),
+	}
+}
+
+func (sc *serverConnections) adjust(state http.ConnState, delta int64) {
+	switch state {
+	case http.StateActive:
+		atomic.AddInt64(&sc.active, delta)
+	case http.StateIdle:
+		atomic.AddInt64(&sc.idle, delta)
+	}
+}
+
+func (sc *serverConnections) transition(c net.Conn, state http.ConnState) {
+	switch state {
+	case http.StateNew:
+		atomic.AddInt64(&sc.opened, 1)
+	case http.StateHijacked:
+		atomic.AddInt64(&sc.hijacked, 1)
+	case http.StateClosed:
+		atomic.AddInt64(&sc.closed, 1)
+	}
+
+	sc.Lock()
+	defer sc.Unlock()
+
+	sc.timeHandshake(c, state, time.Now())
+	if previous, ok := sc.states[c]; ok {
+		sc.adjust(previous, -1)
+	}
+	switch state {
+	case http.StateHijacked, http.StateClosed:
+		// No further transitions happen for hijacked or closed
+		// connections.
+		delete(sc.states, c)
+	default:
+		sc.states[c] = state
+		sc.adjust(state, 1)
+	}
+}
+
+// MergeIntoHarvest implements Harvestable.  The counts of the connections
+// opened, hijacked, and closed, and the handshake durations, are reset.
+func (sc *serverConnections) MergeIntoHarvest(h *harvest) {
+	counts := []struct {
+		name  string
+		count *int64
+	}{
+		{name: serverConnectionsNew, count: &sc.opened},
+		{name: serverConnectionsHijacked, count: &sc.hijacked},
+		{name: serverConnectionsClosed, count: &sc.closed},
+	}
+	for _, c := range counts {
+		if n := atomic.SwapInt64(c.count, 0); 0 != n {
+			h.Metrics.addCount(c.name, float64(n), unforced)
+		}
+	}
+	h.Metrics.addGauge(serverConnectionsActive, float64(atomic.LoadInt64(&sc.active)), unforced)
+	h.Metrics.addGauge(serverConnectionsIdle, float64(atomic.LoadInt64(&sc.idle)), unforced)
+
+	sc.Lock()
+	handshakes := sc.handshakes
+	sc.handshakes = metricData{}
+	sc.Unlock()
+
+	if 0 != handshakes.countSatisfied {
+		h.Metrics.add(serverTLSHandshake, "", handshakes, unforced)
+	}
+}
+
+// timeHandshake records the duration of the TLS handshake of the connection
+// when the server reads its first request, which happens once the server
+// has completed the handshake.  It must be called with the lock held.
+func (sc *serverConnections) timeHandshake(c net.Conn, state http.ConnState, now time.Time) {
+	tc, ok := c.(*tls.Conn)
+	if !ok {
+		return
+	}
+	if http.StateNew == state {
+		sc.accepted[c] = now
+		return
+	}
+	accepted, ok := sc.accepted[c]
+	if !ok {
+		return
+	}
+	delete(sc.accepted, c)
+	if http.StateActive != state || !tc.ConnectionState().HandshakeComplete {
+		return
+	}
+	d := now.Sub(accepted)
+	data := metricDataFromDuration(d, d)
+	if 0 == sc.handshakes.countSatisfied {
+		sc.handshakes = data
+	} else {
+		sc.handshakes.aggregate(data)
+	}
+}
+
+// requestTiming accumulates the time spent reading the body and writing the
+// response of a request handled by a server instrumented by WrapServer.  The
+// fields are accessed atomically since the body may be read in a different
+// goroutine than the handler.
+type requestTiming struct {
+	read  int64
+	write int64
+}
+
+func (rt *requestTiming) addRead(start time.Time) {
+	atomic.AddInt64(&rt.read, int64(time.Since(start)))
+}
+
+func (rt *requestTiming) addWrite(start time.Time) {
+	atomic.AddInt64(&rt.write, int64(time.Since(start)))
+}
+
+func (rt *requestTiming) record(thd *thread) {
+	m := serverRequestMetrics{
+		read:  time.Duration(atomic.LoadInt64(&rt.read)),
+		write: time.Duration(atomic.LoadInt64(&rt.write)),
+	}
+	thd.AddAgentAttribute(AttributeRequestReadDuration, "", m.read.Seconds())
+	thd.AddAgentAttribute(AttributeResponseWriteDuration, "", m.write.Seconds())
+	run, _ := thd.app.getState()
+	thd.app.Consume(run.Reply.RunID, m)
+}
+
+// timedBody is a request body which records the time spent reading it.
+type timedBody struct {
+	io.ReadCloser
+	timing *requestTiming
+}
+
+func (b timedBody) Read(p []byte) (int, error) {
+	defer b.timing.addRead(time.Now())
+	return b.ReadCloser.Read(p)
+}
+
+// serverRequestMetrics records the time spent reading a request body and
+// writing its response.
+type serverRequestMetrics struct {
+	read  time.Duration
+	write time.Duration
+}
+
+// MergeIntoHarvest implements Harvestable.
+func (m serverRequestMetrics) MergeIntoHarvest(h *harvest) {
+	h.Metrics.addDuration(serverRequestRead, "", m.read, m.read, unforced)
+	h.Metrics.addDuration(serverResponseWrite, "", m.write, m.write, unforced)
+}
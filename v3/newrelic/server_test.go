@@ -0,0 +1,199 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"crypto/tls"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestWrapServerNilApplication(t *testing.T) {
+	srv := &http.Server{}
+	WrapServer(nil, srv)
+	if nil != srv.Handler || nil != srv.ConnState {
+		t.Error("server should not be modified")
+	}
+	WrapServer(nil, nil)
+}
+
+func TestWrapServerNamesTransactionsUsingMux(t *testing.T) {
+	app := testApp(nil, nil, t)
+	mux := http.NewServeMux()
+	mux.HandleFunc(helloPath, func(w http.ResponseWriter, r *http.Request) {
+		if nil == FromContext(r.Context()) {
+			t.Error("transaction missing from request context")
+		}
+		w.Write([]byte("hello"))
+	})
+	srv := &http.Server{Handler: mux}
+	WrapServer(app.Application, srv)
+
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, helloRequest)
+	if out := w.Body.String(); "hello" != out {
+		t.Error(out)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/GET /hello",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+	}})
+}
+
+func TestWrapServerCustomHandler(t *testing.T) {
+	app := testApp(nil, nil, t)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {})}
+	WrapServer(app.Application, srv)
+	srv.Handler.ServeHTTP(httptest.NewRecorder(), helloRequest)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/GET",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+	}})
+}
+
+func TestWrapServerConnectionMetrics(t *testing.T) {
+	app := testApp(nil, nil, t)
+	var states []http.ConnState
+	srv := &http.Server{
+		ConnState: func(c net.Conn, state http.ConnState) {
+			states = append(states, state)
+		},
+	}
+	WrapServer(app.Application, srv)
+
+	c1, _ := net.Pipe()
+	c2, _ := net.Pipe()
+	srv.ConnState(c1, http.StateNew)
+	srv.ConnState(c2, http.StateNew)
+	srv.ConnState(c1, http.StateActive)
+	srv.ConnState(c2, http.StateActive)
+	srv.ConnState(c1, http.StateIdle)
+	srv.ConnState(c1, http.StateClosed)
+	// Hijacked connections are not closed by the server.
+	srv.ConnState(c2, http.StateHijacked)
+
+	if len(states) != 7 {
+		t.Error("original ConnState not called", states)
+	}
+	// The counts are reported when metrics are harvested, and the active
+	// and idle connections are those at the time of the harvest.
+	app.ExpectMetrics(t, []internal.WantMetric{
+		{Name: "HttpServer/Connections/New", Scope: "", Forced: false, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "HttpServer/Connections/Closed", Scope: "", Forced: false, Data: singleCount},
+		{Name: "HttpServer/Connections/Hijacked", Scope: "", Forced: false, Data: singleCount},
+		{Name: "HttpServer/Connections/Active", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+		{Name: "HttpServer/Connections/Idle", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestServerConnectionsCountsAtHarvest(t *testing.T) {
+	conns := newServerConnections()
+	c1, _ := net.Pipe()
+	c2, _ := net.Pipe()
+	conns.transition(c1, http.StateNew)
+	conns.transition(c2, http.StateNew)
+	conns.transition(c1, http.StateActive)
+	conns.transition(c2, http.StateActive)
+	conns.transition(c1, http.StateIdle)
+
+	h := newHarvest(time.Now(), dfltHarvestCfgr)
+	conns.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "HttpServer/Connections/New", Scope: "", Forced: false, Data: []float64{2, 0, 0, 0, 0, 0}},
+		{Name: "HttpServer/Connections/Active", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+		{Name: "HttpServer/Connections/Idle", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+	})
+
+	// The counts of new connections are reset by the harvest.
+	conns.transition(c1, http.StateClosed)
+	h = newHarvest(time.Now(), dfltHarvestCfgr)
+	conns.MergeIntoHarvest(h)
+	expectMetrics(t, h.Metrics, []internal.WantMetric{
+		{Name: "HttpServer/Connections/Closed", Scope: "", Forced: false, Data: singleCount},
+		{Name: "HttpServer/Connections/Active", Scope: "", Forced: false, Data: []float64{1, 1, 1, 1, 1, 1}},
+		{Name: "HttpServer/Connections/Idle", Scope: "", Forced: false, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestWrapServerRequestTiming(t *testing.T) {
+	app := testApp(nil, nil, t)
+	srv := &http.Server{Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := ioutil.ReadAll(r.Body)
+		w.Write(body)
+	})}
+	WrapServer(app.Application, srv)
+
+	req, _ := http.NewRequest("POST", helloPath, strings.NewReader("hello"))
+	w := httptest.NewRecorder()
+	srv.Handler.ServeHTTP(w, req)
+	if out := w.Body.String(); "hello" != out {
+		t.Error(out)
+	}
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "WebTransaction/Go/POST",
+			"nr.apdexPerfZone": internal.MatchAnything,
+		},
+		AgentAttributes: map[string]interface{}{
+			"request.method":               "POST",
+			"http.flavor":                  "1.1",
+			"request.uri":                  "/hello",
+			"httpResponseCode":             "200",
+			"http.statusCode":              "200",
+			"response.headers.contentType": "text/plain; charset=utf-8",
+			AttributeRequestReadDuration:   internal.MatchAnything,
+			AttributeResponseWriteDuration: internal.MatchAnything,
+		},
+	}})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "HttpServer/Request/Read", Scope: "", Forced: false, Data: nil},
+		{Name: "HttpServer/Response/Write", Scope: "", Forced: false, Data: nil},
+	})
+}
+
+func TestWrapServerTLSHandshake(t *testing.T) {
+	app := testApp(nil, nil, t)
+	ts := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	WrapServer(app.Application, ts.Config)
+	ts.StartTLS()
+	defer ts.Close()
+
+	resp, err := ts.Client().Get(ts.URL)
+	if nil != err {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "HttpServer/TLS/Handshake", Scope: "", Forced: false, Data: []float64{1}},
+	})
+}
+
+func TestServerConnectionsHandshakeFailed(t *testing.T) {
+	app := testApp(nil, nil, t)
+	conns := newServerConnections()
+	internalApp(app).aggregates.addServer(conns)
+	c1, _ := net.Pipe()
+	c := tls.Server(c1, &tls.Config{})
+	conns.transition(c, http.StateNew)
+	conns.transition(c, http.StateClosed)
+	if len(conns.accepted) != 0 {
+		t.Error(conns.accepted)
+	}
+	app.ExpectMetricsPresent(t, nil)
+	if _, ok := internalApp(app).testHarvest.Metrics.metrics[metricID{Name: serverTLSHandshake}]; ok {
+		t.Error("handshake recorded for a connection which was never active")
+	}
+}
@@ -17,8 +17,6 @@ import (
 const (
 	// agentLanguage is used in the connect JSON and the Lambda JSON.
 	agentLanguage = "go"
-
-	lambdaMetadataVersion = 2
 )
 
 // serverlessHarvest is used to store and log data when the agent is running in
@@ -129,7 +127,7 @@ func (sh *serverlessHarvest) Write(arn string, writer io.Writer) {
 	gz.Close()
 
 	js, err := json.Marshal([]interface{}{
-		lambdaMetadataVersion,
+		ServerlessMetadataVersion,
 		"NR_LAMBDA_MONITORING",
 		struct {
 			MetadataVersion      int    `json:"metadata_version"`
@@ -139,8 +137,8 @@ func (sh *serverlessHarvest) Write(arn string, writer io.Writer) {
 			AgentVersion         string `json:"agent_version"`
 			AgentLanguage        string `json:"agent_language"`
 		}{
-			MetadataVersion:      lambdaMetadataVersion,
-			ProtocolVersion:      procotolVersion,
+			MetadataVersion:      ServerlessMetadataVersion,
+			ProtocolVersion:      CollectorProtocolVersion,
 			AgentVersion:         Version,
 			ExecutionEnvironment: sh.awsExecutionEnv,
 			ARN:                  arn,
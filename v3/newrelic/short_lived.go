@@ -0,0 +1,71 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// maxPendingHarvestables limits the data kept while a short-lived
+// application connects.  See Config.ShortLived.
+const maxPendingHarvestables = 10 * 1000
+
+// startConnect starts the connect of a short-lived application, which is
+// deferred until data is recorded or the connect is waited for.
+func (app *app) startConnect() {
+	if app.config.ShortLived.Enabled {
+		app.connectOnce.Do(func() { go app.connectRoutine() })
+	}
+}
+
+// addPending keeps data recorded while the application is not connected so
+// that it can be harvested once connected.
+func (app *app) addPending(pending []harvestable, data harvestable) []harvestable {
+	app.startConnect()
+	if len(pending) >= maxPendingHarvestables {
+		return pending
+	}
+	return append(pending, data)
+}
+
+// connectBeforeShutdown waits for a short-lived application to connect so
+// that the data recorded before the connect can be harvested.  It waits for
+// at most half of the time remaining before the shutdown deadline, leaving
+// the rest for the harvest.  It must be called by the process goroutine.
+func (app *app) connectBeforeShutdown(deadline time.Time, pending []harvestable) (*appRun, []harvestable) {
+	// Data may have been sent before the shutdown was initiated but not
+	// yet received.
+	for done := false; !done; {
+		select {
+		case d := <-app.dataChan:
+			if "" == d.id {
+				pending = app.addPending(pending, d.data)
+			}
+		default:
+			done = true
+		}
+	}
+	if 0 == len(pending) {
+		return nil, pending
+	}
+	app.startConnect()
+	t := time.NewTimer(deadline.Sub(time.Now()) / 2)
+	defer t.Stop()
+	for {
+		select {
+		case run := <-app.connectChan:
+			return run, pending
+		case d := <-app.dataChan:
+			if "" == d.id {
+				pending = app.addPending(pending, d.data)
+			}
+		case <-app.collectorErrorChan:
+			return nil, pending
+		case <-t.C:
+			app.Warn("application not connected before shutdown timeout", map[string]interface{}{
+				"app":     app.config.AppName,
+				"pending": len(pending),
+			})
+			return nil, pending
+		}
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+type collectorSender struct {
+	sync.Mutex
+	methods []string
+	// block, if non-nil, delays each response until it is closed.
+	block chan struct{}
+}
+
+func (s *collectorSender) Send(req HarvestRequest) (HarvestResponse, error) {
+	if nil != s.block {
+		<-s.block
+	}
+	s.Lock()
+	s.methods = append(s.methods, req.Method)
+	s.Unlock()
+	switch req.Method {
+	case cmdPreconnect:
+		return HarvestResponse{StatusCode: http.StatusOK, Body: []byte(redirectBody)}, nil
+	case cmdConnect:
+		return HarvestResponse{StatusCode: http.StatusOK, Body: []byte(connectBody)}, nil
+	}
+	return HarvestResponse{StatusCode: http.StatusAccepted}, nil
+}
+
+func (s *collectorSender) sent() map[string]int {
+	s.Lock()
+	defer s.Unlock()
+	sent := make(map[string]int)
+	for _, m := range s.methods {
+		sent[m]++
+	}
+	return sent
+}
+
+func shortLivedApp(t *testing.T, sender *collectorSender) *Application {
+	app, err := NewApplication(
+		ConfigAppName("my app"),
+		ConfigLicense(testLicenseKey),
+		ConfigShortLived(),
+		func(cfg *Config) {
+			cfg.HarvestSender = sender
+			cfg.RuntimeSampler.Enabled = false
+		},
+	)
+	if nil != err {
+		t.Fatal(err)
+	}
+	return app
+}
+
+func TestShortLivedHarvestsDataBeforeConnect(t *testing.T) {
+	sender := &collectorSender{}
+	app := shortLivedApp(t, sender)
+	app.StartTransaction("hello").End()
+	app.Shutdown(10 * time.Second)
+
+	sent := sender.sent()
+	if 1 != sent[cmdPreconnect] || 1 != sent[cmdConnect] {
+		t.Error(sent)
+	}
+	if 1 != sent[cmdTxnEvents] || 1 != sent[cmdMetrics] {
+		t.Error(sent)
+	}
+}
+
+func TestShortLivedConnectsWhenWaited(t *testing.T) {
+	sender := &collectorSender{}
+	app := shortLivedApp(t, sender)
+	if err := app.WaitForConnection(10 * time.Second); nil != err {
+		t.Fatal(err)
+	}
+	app.StartTransaction("hello").End()
+	app.Shutdown(10 * time.Second)
+
+	if sent := sender.sent(); 1 != sent[cmdConnect] || 1 != sent[cmdTxnEvents] {
+		t.Error(sent)
+	}
+}
+
+func TestShortLivedNoData(t *testing.T) {
+	sender := &collectorSender{}
+	app := shortLivedApp(t, sender)
+	app.Shutdown(10 * time.Second)
+
+	if sent := sender.sent(); 0 != len(sent) {
+		t.Error(sent)
+	}
+}
+
+func TestShortLivedShutdownTimeout(t *testing.T) {
+	sender := &collectorSender{block: make(chan struct{})}
+	defer close(sender.block)
+	app := shortLivedApp(t, sender)
+	app.StartTransaction("hello").End()
+
+	start := time.Now()
+	app.Shutdown(50 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Error(elapsed)
+	}
+}
+
+func TestShortLivedShutdownLeavesTimeToHarvest(t *testing.T) {
+	// The connect is given up on halfway to the shutdown deadline, so
+	// that a connect which succeeds leaves time for the harvest.
+	sender := &collectorSender{block: make(chan struct{})}
+	defer close(sender.block)
+	app := shortLivedApp(t, sender)
+	app.StartTransaction("hello").End()
+
+	start := time.Now()
+	app.Shutdown(400 * time.Millisecond)
+	if elapsed := time.Since(start); elapsed >= 350*time.Millisecond {
+		t.Error(elapsed)
+	}
+}
+
+func TestShortLivedSkipsUtilizationDetection(t *testing.T) {
+	cfg := defaultConfig()
+	c := config{Config: cfg}
+	if util := c.utilizationConfig(); !util.DetectAWS || !util.DetectDocker {
+		t.Error(util)
+	}
+	c.ShortLived.Enabled = true
+	c.Utilization.LogicalProcessors = 4
+	util := c.utilizationConfig()
+	if util.DetectAWS || util.DetectAzure || util.DetectPCF || util.DetectGCP || util.DetectDocker || util.DetectKubernetes {
+		t.Error(util)
+	}
+	if 4 != util.LogicalProcessors {
+		t.Error(util)
+	}
+}
@@ -60,6 +60,27 @@ type slowQueryInstance struct {
 	txnEvent
 }
 
+// excludeAttributes removes the fields which hold the same values as the
+// excluded datastore segment attributes.  An excluded statement is replaced
+// by unknownQuery, since slow queries are aggregated by their query.
+func (slow *slowQueryInstance) excludeAttributes(excluded map[string]bool, unknownQuery string) {
+	if excluded[SpanAttributeDBStatement] {
+		slow.ParameterizedQuery = unknownQuery
+	}
+	if excluded[spanAttributeQueryParameters] {
+		slow.QueryParameters = nil
+	}
+	if excluded[SpanAttributeDBInstance] {
+		slow.DatabaseName = ""
+	}
+	if excluded[SpanAttributePeerHostname] || excluded[SpanAttributePeerAddress] {
+		slow.Host = ""
+	}
+	if excluded[SpanAttributePeerAddress] {
+		slow.PortPathOrID = ""
+	}
+}
+
 // Aggregation is performed to avoid reporting multiple slow queries with same
 // query string.  Since some datastore segments may be below the slow query
 // threshold, the aggregation fields Count, Total, and Min should be taken with
@@ -0,0 +1,124 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// SpanAttributeNaming controls the vocabulary of the names of the agent
+// attributes of span events.  See Config.SpanEvents.AttributeNaming.
+type SpanAttributeNaming string
+
+const (
+	// SpanAttributeNamingNewRelic uses only the New Relic names, eg.
+	// "http.method" and "peer.hostname".  This is the default.
+	SpanAttributeNamingNewRelic SpanAttributeNaming = "newrelic"
+	// SpanAttributeNamingOTel uses only the OpenTelemetry semantic
+	// convention names, eg. "http.request.method" and "server.address".
+	SpanAttributeNamingOTel SpanAttributeNaming = "otel"
+	// SpanAttributeNamingBoth adds the OpenTelemetry semantic convention
+	// names alongside the New Relic names.  Use it while migrating
+	// dashboards and alerts from one vocabulary to the other.
+	SpanAttributeNamingBoth SpanAttributeNaming = "both"
+)
+
+// otelSpanAttributeNames maps the New Relic names of span agent attributes
+// to their OpenTelemetry semantic convention names.
+var otelSpanAttributeNames = map[string]string{
+	SpanAttributeHTTPMethod:     "http.request.method",
+	SpanAttributeHTTPURL:        "url.full",
+	SpanAttributeHTTPStatusCode: "http.response.status_code",
+	SpanAttributeDBStatement:    "db.query.text",
+	SpanAttributeDBInstance:     "db.namespace",
+	SpanAttributeDBCollection:   "db.collection.name",
+	SpanAttributePeerHostname:   "server.address",
+	SpanAttributeErrorClass:     "error.type",
+	SpanAttributeCodeFunction:   "code.function.name",
+	SpanAttributeCodeFilepath:   "code.file.path",
+	SpanAttributeCodeLineno:     "code.line.number",
+	AttributeRequestMethod:      "http.request.method",
+	AttributeRequestURI:         "url.path",
+	AttributeRequestUserAgent:   "user_agent.original",
+}
+
+// otelDBSystems contains the OpenTelemetry db.system values of the
+// datastore products whose value is not their lowercase name.
+var otelDBSystems = map[string]string{
+	string(DatastorePostgres): "postgresql",
+	string(DatastoreIBMDB2):   "db2",
+}
+
+// validateSpanAttributeNaming checks that SpanEvents.AttributeNaming is a
+// known value.  The empty string is treated as SpanAttributeNamingNewRelic.
+func (c Config) validateSpanAttributeNaming() error {
+	switch c.SpanEvents.AttributeNaming {
+	case "", SpanAttributeNamingNewRelic, SpanAttributeNamingOTel, SpanAttributeNamingBoth:
+		return nil
+	default:
+		return errSpanAttributeNaming
+	}
+}
+
+// applySpanAttributeNaming adds the OpenTelemetry semantic convention names
+// of the span event's agent attributes, and removes the New Relic names
+// unless both are wanted.
+func applySpanAttributeNaming(naming SpanAttributeNaming, e *spanEvent) {
+	if SpanAttributeNamingOTel != naming && SpanAttributeNamingBoth != naming {
+		return
+	}
+	attrs := e.AgentAttributes
+	renamed := make(spanAttributeMap, len(attrs)+3)
+	for key, val := range attrs {
+		name, ok := otelSpanAttributeNames[key]
+		if ok {
+			renamed[name] = val
+		}
+		if !ok || SpanAttributeNamingBoth == naming {
+			renamed[key] = val
+		}
+	}
+	if spanCategoryDatastore == e.Category && "" != e.Component {
+		system, ok := otelDBSystems[e.Component]
+		if !ok {
+			system = strings.ToLower(e.Component)
+		}
+		renamed.addString("db.system", system)
+	}
+	if _, ok := renamed["server.address"]; !ok {
+		// External spans identify the server only by their URL.
+		if u, ok := attrs[SpanAttributeHTTPURL].(stringJSONWriter); ok {
+			if parsed, err := url.Parse(string(u)); nil == err && "" != parsed.Host {
+				host, port := splitServerAddress(parsed.Host)
+				renamed.addString("server.address", host)
+				if port > 0 {
+					renamed.addInt("server.port", port)
+				}
+			}
+		}
+	} else if addr, ok := attrs[SpanAttributePeerAddress].(stringJSONWriter); ok {
+		if _, port := splitServerAddress(string(addr)); port > 0 {
+			renamed.addInt("server.port", port)
+		}
+	}
+	if SpanAttributeNamingOTel == naming {
+		// peer.address is replaced by server.address and server.port.
+		delete(renamed, SpanAttributePeerAddress)
+	}
+	e.AgentAttributes = renamed
+}
+
+// splitServerAddress splits the host and the numeric port, which is zero if
+// there is no numeric port.
+func splitServerAddress(hostport string) (string, int) {
+	host, p, err := net.SplitHostPort(hostport)
+	if nil != err {
+		return hostport, 0
+	}
+	port, _ := strconv.Atoi(p)
+	return host, port
+}
@@ -0,0 +1,137 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func testSpanAttributeNaming(t *testing.T, naming SpanAttributeNaming, datastore, external map[string]interface{}) {
+	replyfn := func(reply *internal.ConnectReply) {
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.SpanEvents.AttributeNaming = naming
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	segment := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastorePostgres,
+		Collection:         "users",
+		Operation:          "select",
+		ParameterizedQuery: "SELECT * FROM users",
+		Host:               "db.example.com",
+		PortPathOrID:       "5432",
+		DatabaseName:       "shop",
+	}
+	segment.End()
+	req, _ := http.NewRequest("GET", "http://example.com:8080/path?ignore=me", nil)
+	StartExternalSegment(txn, req).End()
+	txn.End()
+	app.ExpectSpanEvents(t, []internal.WantEvent{
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"sampled":   true,
+				"name":      "Datastore/statement/Postgres/users/select",
+				"category":  "datastore",
+				"component": "Postgres",
+				"span.kind": "client",
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: datastore,
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"parentId":  internal.MatchAnything,
+				"name":      "External/example.com:8080/http/GET",
+				"category":  "http",
+				"component": "http",
+				"span.kind": "client",
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: external,
+		},
+		{
+			Intrinsics: map[string]interface{}{
+				"name":             "OtherTransaction/Go/hello",
+				"transaction.name": "OtherTransaction/Go/hello",
+				"sampled":          true,
+				"category":         "generic",
+				"nr.entryPoint":    true,
+			},
+			UserAttributes:  map[string]interface{}{},
+			AgentAttributes: map[string]interface{}{},
+		},
+	})
+}
+
+func TestSpanAttributeNamingOTel(t *testing.T) {
+	testSpanAttributeNaming(t, SpanAttributeNamingOTel, map[string]interface{}{
+		"db.query.text":      "SELECT * FROM users",
+		"db.namespace":       "shop",
+		"db.collection.name": "users",
+		"db.system":          "postgresql",
+		"server.address":     "db.example.com",
+		"server.port":        5432,
+	}, map[string]interface{}{
+		"url.full":            "http://example.com:8080/path",
+		"http.request.method": "GET",
+		"server.address":      "example.com",
+		"server.port":         8080,
+	})
+}
+
+func TestSpanAttributeNamingBoth(t *testing.T) {
+	testSpanAttributeNaming(t, SpanAttributeNamingBoth, map[string]interface{}{
+		"db.statement":       "SELECT * FROM users",
+		"db.instance":        "shop",
+		"db.collection":      "users",
+		"peer.address":       "db.example.com:5432",
+		"peer.hostname":      "db.example.com",
+		"db.query.text":      "SELECT * FROM users",
+		"db.namespace":       "shop",
+		"db.collection.name": "users",
+		"db.system":          "postgresql",
+		"server.address":     "db.example.com",
+		"server.port":        5432,
+	}, map[string]interface{}{
+		"http.url":            "http://example.com:8080/path",
+		"http.method":         "GET",
+		"url.full":            "http://example.com:8080/path",
+		"http.request.method": "GET",
+		"server.address":      "example.com",
+		"server.port":         8080,
+	})
+}
+
+func TestSpanAttributeNamingNewRelic(t *testing.T) {
+	testSpanAttributeNaming(t, SpanAttributeNamingNewRelic, map[string]interface{}{
+		"db.statement":  "SELECT * FROM users",
+		"db.instance":   "shop",
+		"db.collection": "users",
+		"peer.address":  "db.example.com:5432",
+		"peer.hostname": "db.example.com",
+	}, map[string]interface{}{
+		"http.url":    "http://example.com:8080/path",
+		"http.method": "GET",
+	})
+}
+
+func TestSpanAttributeNamingInvalid(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.SpanEvents.AttributeNaming = "semconv"
+	if err := cfg.validateSpanAttributeNaming(); errSpanAttributeNaming != err {
+		t.Error(err)
+	}
+	cfg.SpanEvents.AttributeNaming = ""
+	if err := cfg.validateSpanAttributeNaming(); nil != err {
+		t.Error(err)
+	}
+}
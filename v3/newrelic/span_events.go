@@ -5,9 +5,19 @@ package newrelic
 
 import (
 	"bytes"
+	"sort"
 	"time"
 )
 
+const (
+	// spanEventsDroppedTopN is the number of transactions whose dropped
+	// span events are reported individually each harvest.
+	spanEventsDroppedTopN = 10
+	// spanEventsDroppedMaxNames limits the number of transaction names
+	// tracked between harvests.
+	spanEventsDroppedMaxNames = 1000
+)
+
 // https://source.datanerd.us/agents/agent-specs/blob/master/Span-Events.md
 
 type spanCategory string
@@ -39,6 +49,10 @@ type spanEvent struct {
 	TracingVendors  string
 	AgentAttributes spanAttributeMap
 	UserAttributes  spanAttributeMap
+	// OwnerTxnName is the name of the transaction which created the span.
+	// Unlike TxnName, which is only populated for the root span, it is
+	// populated for every span.  It is not sent.
+	OwnerTxnName string
 }
 
 // WriteJSON prepares JSON in the format expected by the collector.
@@ -111,6 +125,10 @@ func (e *spanEvent) MarshalJSON() ([]byte, error) {
 
 type spanEvents struct {
 	*analyticsEvents
+	// dropped counts the span events which were not kept because the
+	// reservoir was full, by the name of the transaction which created
+	// them.
+	dropped map[string]int
 }
 
 func newSpanEvents(max int) *spanEvents {
@@ -120,7 +138,66 @@ func newSpanEvents(max int) *spanEvents {
 }
 
 func (events *spanEvents) addEventPopulated(e *spanEvent) {
-	events.analyticsEvents.addEvent(analyticsEvent{priority: e.Priority, jsonWriter: e})
+	dropped, ok := events.analyticsEvents.addEvent(analyticsEvent{priority: e.Priority, jsonWriter: e})
+	if !ok {
+		return
+	}
+	if evt, ok := dropped.jsonWriter.(*spanEvent); ok {
+		events.recordDropped(evt.OwnerTxnName)
+	}
+}
+
+func (events *spanEvents) recordDropped(txnName string) {
+	if nil == events.dropped {
+		events.dropped = make(map[string]int)
+	}
+	if _, ok := events.dropped[txnName]; !ok && len(events.dropped) >= spanEventsDroppedMaxNames {
+		txnName = ""
+	}
+	events.dropped[txnName]++
+}
+
+// droppedSpanCounts sorts transaction names by the number of span events
+// dropped, most first.
+type droppedSpanCounts struct {
+	names  []string
+	counts map[string]int
+}
+
+func (d droppedSpanCounts) Len() int      { return len(d.names) }
+func (d droppedSpanCounts) Swap(i, j int) { d.names[i], d.names[j] = d.names[j], d.names[i] }
+func (d droppedSpanCounts) Less(i, j int) bool {
+	ci, cj := d.counts[d.names[i]], d.counts[d.names[j]]
+	if ci != cj {
+		return ci > cj
+	}
+	return d.names[i] < d.names[j]
+}
+
+// droppedMetrics records the number of span events dropped for each of the
+// spanEventsDroppedTopN transactions which lost the most span events.  The
+// span events dropped for other transactions are combined.
+func (events *spanEvents) droppedMetrics(mt *metricTable) {
+	names := make([]string, 0, len(events.dropped))
+	other := 0
+	for name, count := range events.dropped {
+		if "" == name {
+			other += count
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Sort(droppedSpanCounts{names: names, counts: events.dropped})
+	for i, name := range names {
+		if i < spanEventsDroppedTopN {
+			mt.addCount(spanEventsDropped+name, float64(events.dropped[name]), forced)
+		} else {
+			other += events.dropped[name]
+		}
+	}
+	if other > 0 {
+		mt.addCount(spanEventsDroppedOther, float64(other), forced)
+	}
 }
 
 // MergeSpanEvents merges the span events from a transaction into the
@@ -5,6 +5,7 @@ package newrelic
 
 import (
 	"encoding/json"
+	"fmt"
 	"testing"
 	"time"
 
@@ -244,3 +245,56 @@ func TestSpanEventsMerge(t *testing.T) {
 		},
 	})
 }
+
+func TestSpanEventsDroppedMetrics(t *testing.T) {
+	spanEvents := newSpanEvents(2)
+	add := func(txnName string, p priority, count int) {
+		for i := 0; i < count; i++ {
+			spanEvents.MergeSpanEvents([]*spanEvent{{Priority: p, OwnerTxnName: txnName}})
+		}
+	}
+	add("WebTransaction/Go/low", 0.1, 3)
+	add("WebTransaction/Go/high", 0.9, 2)
+	add("WebTransaction/Go/mid", 0.5, 1)
+
+	mt := newMetricTable(100, time.Now())
+	spanEvents.droppedMetrics(mt)
+	expectMetrics(t, mt, []internal.WantMetric{
+		{Name: spanEventsDropped + "WebTransaction/Go/low", Scope: "", Forced: true, Data: []float64{3, 0, 0, 0, 0, 0}},
+		{Name: spanEventsDropped + "WebTransaction/Go/mid", Scope: "", Forced: true, Data: []float64{1, 0, 0, 0, 0, 0}},
+	})
+}
+
+func TestSpanEventsDroppedMetricsTopN(t *testing.T) {
+	spanEvents := newSpanEvents(0)
+	for i := 0; i < spanEventsDroppedTopN+2; i++ {
+		for j := 0; j <= i; j++ {
+			spanEvents.MergeSpanEvents([]*spanEvent{{OwnerTxnName: fmt.Sprintf("OtherTransaction/Go/%02d", i)}})
+		}
+	}
+
+	mt := newMetricTable(100, time.Now())
+	spanEvents.droppedMetrics(mt)
+	var expect []internal.WantMetric
+	for i := spanEventsDroppedTopN + 1; i > 1; i-- {
+		expect = append(expect, internal.WantMetric{
+			Name: spanEventsDropped + fmt.Sprintf("OtherTransaction/Go/%02d", i), Scope: "", Forced: true,
+			Data: []float64{float64(i + 1), 0, 0, 0, 0, 0},
+		})
+	}
+	expect = append(expect, internal.WantMetric{Name: spanEventsDroppedOther, Scope: "", Forced: true, Data: []float64{3, 0, 0, 0, 0, 0}})
+	expectMetrics(t, mt, expect)
+}
+
+func TestSpanEventsDroppedMaxNames(t *testing.T) {
+	spanEvents := newSpanEvents(0)
+	for i := 0; i < spanEventsDroppedMaxNames+5; i++ {
+		spanEvents.MergeSpanEvents([]*spanEvent{{OwnerTxnName: fmt.Sprintf("OtherTransaction/Go/%d", i)}})
+	}
+	if n := len(spanEvents.dropped); n != spanEventsDroppedMaxNames+1 {
+		t.Error(n)
+	}
+	if n := spanEvents.dropped[""]; n != 5 {
+		t.Error(n)
+	}
+}
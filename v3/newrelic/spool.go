@@ -0,0 +1,234 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	spoolDefaultMaxBytes = 100 * 1024 * 1024
+	spoolFileSuffix      = ".spool"
+)
+
+var (
+	errSpoolPayloadTooLarge = errors.New("payload is larger than Spool.MaxBytes")
+)
+
+// harvestSpool persists the harvest payloads which could not be sent because
+// the collector was unavailable.  Each payload is written to its own file,
+// named so that the files sort in the order they were written.  See
+// Config.Spool.
+type harvestSpool struct {
+	dir      string
+	maxBytes int64
+
+	sync.Mutex
+	seq       int
+	replaying bool
+}
+
+// spooledPayload is the contents of a spool file.
+type spooledPayload struct {
+	Method string          `json:"method"`
+	RunID  string          `json:"run_id"`
+	Data   json.RawMessage `json:"data"`
+}
+
+func newHarvestSpool(dir string, maxBytes int64) (*harvestSpool, error) {
+	if err := os.MkdirAll(dir, 0700); nil != err {
+		return nil, err
+	}
+	if maxBytes <= 0 {
+		maxBytes = spoolDefaultMaxBytes
+	}
+	return &harvestSpool{dir: dir, maxBytes: maxBytes}, nil
+}
+
+// files returns the spool files, oldest first, and their total size.
+func (s *harvestSpool) files() ([]os.FileInfo, int64, error) {
+	infos, err := ioutil.ReadDir(s.dir)
+	if nil != err {
+		return nil, 0, err
+	}
+	var files []os.FileInfo
+	var total int64
+	for _, info := range infos {
+		if info.IsDir() || !strings.HasSuffix(info.Name(), spoolFileSuffix) {
+			continue
+		}
+		files = append(files, info)
+		total += info.Size()
+	}
+	return files, total, nil
+}
+
+// write persists the payload, removing the oldest payloads if needed to stay
+// within maxBytes.  It returns the number of payloads removed.
+func (s *harvestSpool) write(method, runID string, data []byte) (int, error) {
+	js, err := json.Marshal(spooledPayload{
+		Method: method,
+		RunID:  runID,
+		Data:   json.RawMessage(data),
+	})
+	if nil != err {
+		return 0, err
+	}
+	size := int64(len(js))
+	if size > s.maxBytes {
+		return 0, errSpoolPayloadTooLarge
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	files, total, err := s.files()
+	if nil != err {
+		return 0, err
+	}
+	removed := 0
+	for len(files) > 0 && total+size > s.maxBytes {
+		if err := os.Remove(filepath.Join(s.dir, files[0].Name())); nil == err {
+			removed++
+		}
+		total -= files[0].Size()
+		files = files[1:]
+	}
+
+	s.seq++
+	name := fmt.Sprintf("%020d-%06d-%s%s", time.Now().UnixNano(), s.seq, method, spoolFileSuffix)
+	path := filepath.Join(s.dir, name)
+	// The payload is written to a temporary file which is then renamed so
+	// that a partially written payload is never replayed.
+	tmp := path + ".tmp"
+	if err := ioutil.WriteFile(tmp, js, 0600); nil != err {
+		os.Remove(tmp)
+		return removed, err
+	}
+	return removed, os.Rename(tmp, path)
+}
+
+// replay sends the spooled payloads, oldest first, replacing the agent run id
+// they were created with by runID.  Payloads are removed once sent, or if the
+// collector rejects them.  Replay stops at the first payload which cannot be
+// sent because the collector is unavailable.  It returns the number of
+// payloads sent.
+func (s *harvestSpool) replay(runID string, send func(method string, data []byte) rpmResponse) (int, error) {
+	s.Lock()
+	if s.replaying {
+		s.Unlock()
+		return 0, nil
+	}
+	s.replaying = true
+	files, _, err := s.files()
+	s.Unlock()
+
+	defer func() {
+		s.Lock()
+		s.replaying = false
+		s.Unlock()
+	}()
+
+	if nil != err {
+		return 0, err
+	}
+	sent := 0
+	for _, f := range files {
+		path := filepath.Join(s.dir, f.Name())
+		js, err := ioutil.ReadFile(path)
+		if nil != err {
+			// The file was removed to make space for a newer
+			// payload.
+			continue
+		}
+		var p spooledPayload
+		if err := json.Unmarshal(js, &p); nil != err {
+			os.Remove(path)
+			continue
+		}
+		resp := send(p.Method, replaceRunID(p.Data, p.RunID, runID))
+		if resp.isCollectorUnavailable() {
+			return sent, resp.Err
+		}
+		os.Remove(path)
+		if nil == resp.Err {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// replaceRunID replaces the agent run id which begins most payloads.
+// Payloads which do not begin with the old run id are returned unchanged.
+func replaceRunID(data []byte, oldID, newID string) []byte {
+	if oldID == newID {
+		return data
+	}
+	var elems []json.RawMessage
+	if err := json.Unmarshal(data, &elems); nil != err || 0 == len(elems) {
+		return data
+	}
+	var first string
+	if err := json.Unmarshal(elems[0], &first); nil != err || first != oldID {
+		return data
+	}
+	elems[0], _ = json.Marshal(newID)
+	out, err := json.Marshal(elems)
+	if nil != err {
+		return data
+	}
+	return out
+}
+
+// spoolPayload persists a payload which could not be sent.
+func (app *app) spoolPayload(cmd string, run *appRun, data []byte) {
+	removed, err := app.spool.write(cmd, run.Reply.RunID.String(), data)
+	if nil != err {
+		app.Warn("unable to spool harvest data", map[string]interface{}{
+			"cmd":   cmd,
+			"error": err.Error(),
+		})
+		return
+	}
+	if removed > 0 {
+		app.Warn("spool full, oldest harvest data discarded", map[string]interface{}{
+			"removed": removed,
+		})
+	}
+	app.Debug("harvest data spooled", map[string]interface{}{
+		"cmd": cmd,
+	})
+}
+
+// replaySpool sends the spooled payloads using the current run.
+func (app *app) replaySpool(run *appRun) {
+	sent, err := app.spool.replay(run.Reply.RunID.String(), func(method string, data []byte) rpmResponse {
+		return collectorRequest(rpmCmd{
+			Collector:         run.Reply.Collector,
+			RunID:             run.Reply.RunID.String(),
+			Name:              method,
+			Data:              data,
+			RequestHeadersMap: run.Reply.RequestHeadersMap,
+			MaxPayloadSize:    run.Reply.MaxPayloadSizeInBytes,
+		}, app.getRPMControls())
+	})
+	if sent > 0 {
+		app.Info("spooled harvest data sent", map[string]interface{}{
+			"count": sent,
+		})
+	}
+	if nil != err {
+		app.Warn("unable to send spooled harvest data", map[string]interface{}{
+			"error": err.Error(),
+		})
+	}
+}
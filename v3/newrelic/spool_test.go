@@ -0,0 +1,191 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+	"github.com/newrelic/go-agent/v3/internal/logger"
+)
+
+func spoolTestDir(t *testing.T) string {
+	dir, err := ioutil.TempDir("", "spool")
+	if nil != err {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestSpoolWriteReplay(t *testing.T) {
+	dir := spoolTestDir(t)
+	defer os.RemoveAll(dir)
+
+	spool, err := newHarvestSpool(filepath.Join(dir, "nested"), 0)
+	if nil != err {
+		t.Fatal(err)
+	}
+	if _, err := spool.write(cmdMetrics, "run1", []byte(`["run1",1,2,[]]`)); nil != err {
+		t.Fatal(err)
+	}
+	if _, err := spool.write(cmdSlowSQLs, "run1", []byte(`[[]]`)); nil != err {
+		t.Fatal(err)
+	}
+
+	var methods, payloads []string
+	sent, err := spool.replay("run2", func(method string, data []byte) rpmResponse {
+		methods = append(methods, method)
+		payloads = append(payloads, string(data))
+		return newRPMResponse(200)
+	})
+	if 2 != sent || nil != err {
+		t.Error(sent, err)
+	}
+	if strings.Join(methods, ",") != "metric_data,sql_trace_data" {
+		t.Error(methods)
+	}
+	if payloads[0] != `["run2",1,2,[]]` || payloads[1] != `[[]]` {
+		t.Error(payloads)
+	}
+	if files, _, _ := spool.files(); len(files) != 0 {
+		t.Error(files)
+	}
+}
+
+func TestSpoolReplayStopsWhenUnavailable(t *testing.T) {
+	dir := spoolTestDir(t)
+	defer os.RemoveAll(dir)
+
+	spool, _ := newHarvestSpool(dir, 0)
+	spool.write(cmdMetrics, "run1", []byte(`["run1"]`))
+	spool.write(cmdSpanEvents, "run1", []byte(`["run1"]`))
+	spool.write(cmdErrorEvents, "run1", []byte(`["run1"]`))
+
+	calls := 0
+	sent, err := spool.replay("run2", func(method string, data []byte) rpmResponse {
+		calls++
+		switch method {
+		case cmdMetrics:
+			// Rejected payloads are removed.
+			return newRPMResponse(400)
+		case cmdSpanEvents:
+			return newRPMResponse(503)
+		}
+		return newRPMResponse(200)
+	})
+	if 0 != sent || nil == err || 2 != calls {
+		t.Error(sent, err, calls)
+	}
+	files, _, _ := spool.files()
+	if len(files) != 2 || !strings.HasSuffix(files[0].Name(), cmdSpanEvents+spoolFileSuffix) {
+		t.Error(files)
+	}
+}
+
+func TestSpoolMaxBytes(t *testing.T) {
+	dir := spoolTestDir(t)
+	defer os.RemoveAll(dir)
+
+	data := []byte(`["run1","` + strings.Repeat("x", 100) + `"]`)
+	spool, _ := newHarvestSpool(dir, 400)
+	for i := 0; i < 3; i++ {
+		if _, err := spool.write(cmdMetrics, "run1", data); nil != err {
+			t.Fatal(err)
+		}
+	}
+	files, total, _ := spool.files()
+	if len(files) != 2 || total > 400 {
+		t.Error(len(files), total)
+	}
+	if _, err := spool.write(cmdMetrics, "run1", []byte(`["`+strings.Repeat("x", 400)+`"]`)); err != errSpoolPayloadTooLarge {
+		t.Error(err)
+	}
+}
+
+func TestReplaceRunID(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{input: `["old",{"a":1},[]]`, expect: `["new",{"a":1},[]]`},
+		{input: `["other",{"a":1},[]]`, expect: `["other",{"a":1},[]]`},
+		{input: `[[["old"]]]`, expect: `[[["old"]]]`},
+		{input: `[]`, expect: `[]`},
+		{input: `{"a":1}`, expect: `{"a":1}`},
+	}
+	for _, tc := range testcases {
+		if out := string(replaceRunID([]byte(tc.input), "old", "new")); out != tc.expect {
+			t.Error(tc.input, out)
+		}
+	}
+}
+
+func TestHarvestSpooledDuringOutage(t *testing.T) {
+	dir := spoolTestDir(t)
+	defer os.RemoveAll(dir)
+
+	var requests []HarvestRequest
+	outage := true
+	cfg := config{Config: defaultConfig()}
+	cfg.Enabled = false
+	cfg.Logger = logger.ShimLogger{}
+	cfg.HarvestSender = harvestSenderFunc(func(req HarvestRequest) (HarvestResponse, error) {
+		if outage {
+			return HarvestResponse{}, errors.New("collector unreachable")
+		}
+		requests = append(requests, req)
+		return HarvestResponse{StatusCode: 200}, nil
+	})
+	app := newApp(cfg)
+	spool, _ := newHarvestSpool(dir, 0)
+	app.spool = spool
+
+	harvestMetric := func(runID string) {
+		reply := internal.ConnectReplyDefaults()
+		reply.RunID = internal.AgentRunID(runID)
+		reply.Collector = "collector.com"
+		run := newAppRun(cfg, reply)
+		h := newHarvest(time.Now(), dfltHarvestCfgr)
+		h.Metrics.addCount("Custom/"+runID, 1, forced)
+		app.doHarvest(h, time.Now(), run)
+	}
+
+	harvestMetric("run1")
+	if files, _, _ := spool.files(); len(files) != 1 {
+		t.Fatal(files)
+	}
+	select {
+	case d := <-app.dataChan:
+		t.Error("spooled data should not be kept in memory", d)
+	default:
+	}
+
+	outage = false
+	harvestMetric("run2")
+	if len(requests) != 2 {
+		t.Fatal(requests)
+	}
+	r, err := gzip.NewReader(bytes.NewReader(requests[1].Body))
+	if nil != err {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(r)
+	if !strings.HasPrefix(string(body), `["run2",`) || !strings.Contains(string(body), "Custom/run1") {
+		t.Error(string(body))
+	}
+	if !strings.Contains(requests[1].URL, "run_id=run2") {
+		t.Error(requests[1].URL)
+	}
+	if files, _, _ := spool.files(); len(files) != 0 {
+		t.Error(files)
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+const integrationUsagePrefix = "Supportability/integration/"
+
+// startupSummary returns the fields of the log line written when the
+// application is created if StartupSummary.Enabled is true.
+func (c config) startupSummary() map[string]interface{} {
+	host := c.preconnectHost()
+	region := collectorRegion(host)
+	if "" == region {
+		region = "unknown"
+	}
+	return map[string]interface{}{
+		"app":           c.AppName,
+		"version":       Version,
+		"enabled":       c.Enabled,
+		"host":          host,
+		"region":        region,
+		"high-security": c.HighSecurity,
+		"fingerprint":   c.fingerprint,
+		"features":      strings.Join(c.enabledFeatures(), ","),
+		"integrations":  strings.Join(usedIntegrations(internal.GetUsageSupportabilityMetrics()), ","),
+	}
+}
+
+// enabledFeatures returns the sorted names of the enabled agent features.
+func (c config) enabledFeatures() []string {
+	var features []string
+	add := func(enabled bool, name string) {
+		if enabled {
+			features = append(features, name)
+		}
+	}
+	add(c.DistributedTracer.Enabled, "distributed-tracing")
+	add(c.DistributedTracer.Enabled && c.SpanEvents.Enabled, "span-events")
+	add(shouldUseTraceObserver(c), "infinite-tracing")
+	add(c.CrossApplicationTracer.Enabled && !c.DistributedTracer.Enabled, "cross-application-tracing")
+	add(c.TransactionEvents.Enabled, "transaction-events")
+	add(c.CustomInsightsEvents.Enabled, "custom-events")
+	add(c.ErrorCollector.Enabled, "error-collector")
+	add(c.TransactionTracer.Enabled, "transaction-traces")
+	add(c.DatastoreTracer.SlowQuery.Enabled, "slow-queries")
+	add(c.BrowserMonitoring.Enabled, "browser-monitoring")
+	add(c.RuntimeSampler.Enabled, "runtime-sampler")
+	add(c.ContentionProfiling.Enabled, "contention-profiling")
+	add(c.ServerlessMode.Enabled, "serverless")
+	sort.Strings(features)
+	return features
+}
+
+// usedIntegrations returns the sorted names of the integrations which have
+// recorded their use with internal.TrackUsage, eg. "framework/gin/v1".
+func usedIntegrations(usage []string) []string {
+	seen := make(map[string]bool)
+	var integrations []string
+	for _, m := range usage {
+		if !strings.HasPrefix(m, integrationUsagePrefix) {
+			continue
+		}
+		name := strings.TrimPrefix(m, integrationUsagePrefix)
+		if !seen[name] {
+			seen[name] = true
+			integrations = append(integrations, name)
+		}
+	}
+	sort.Strings(integrations)
+	return integrations
+}
@@ -0,0 +1,84 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStartupSummary(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.AppName = "my app"
+	cfg.License = "eu01xx6789012345678901234567890123456789"
+	cfg.HighSecurity = true
+	cfg.DistributedTracer.Enabled = true
+	cfg.TransactionTracer.Enabled = false
+	cfg.BrowserMonitoring.Enabled = false
+	cfg.DatastoreTracer.SlowQuery.Enabled = false
+
+	summary := cfg.startupSummary()
+	delete(summary, "integrations")
+	expect := map[string]interface{}{
+		"app":           "my app",
+		"version":       Version,
+		"enabled":       true,
+		"host":          "collector.eu01.nr-data.net",
+		"region":        "eu01",
+		"high-security": true,
+		"fingerprint":   "",
+		"features":      "custom-events,distributed-tracing,error-collector,runtime-sampler,span-events,transaction-events",
+	}
+	if !reflect.DeepEqual(summary, expect) {
+		t.Error(summary)
+	}
+}
+
+func TestStartupSummaryUnknownRegion(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	cfg.Host = "collector.example.com"
+	cfg.CrossApplicationTracer.Enabled = true
+	summary := cfg.startupSummary()
+	if region := summary["region"]; region != "unknown" {
+		t.Error(region)
+	}
+	if features := summary["features"].(string); !strings.Contains(features, "cross-application-tracing") {
+		t.Error(features)
+	}
+}
+
+func TestUsedIntegrations(t *testing.T) {
+	integrations := usedIntegrations([]string{
+		"Supportability/Go/Version/3.9.0",
+		"Supportability/integration/logging/zap",
+		"Supportability/integration/framework/gin/v1",
+		"Supportability/integration/logging/zap",
+	})
+	expect := []string{"framework/gin/v1", "logging/zap"}
+	if !reflect.DeepEqual(integrations, expect) {
+		t.Error(integrations)
+	}
+}
+
+func TestStartupSummaryLogged(t *testing.T) {
+	for _, enabled := range []bool{true, false} {
+		buf := &bytes.Buffer{}
+		_, err := NewApplication(
+			ConfigAppName("my app"),
+			ConfigLicense(testLicenseKey),
+			ConfigEnabled(false),
+			ConfigInfoLogger(buf),
+			func(cfg *Config) { cfg.StartupSummary.Enabled = enabled },
+		)
+		if nil != err {
+			t.Fatal(err)
+		}
+		logged := strings.Contains(buf.String(), `"msg":"agent configuration summary"`)
+		if logged != enabled {
+			t.Error(enabled, buf.String())
+		}
+	}
+}
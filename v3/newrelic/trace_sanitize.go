@@ -0,0 +1,89 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "sort"
+
+// TraceSegment is a segment of a completed transaction trace passed to
+// Config.TransactionTracer.Sanitize.  Use its methods to rename the segment,
+// redact or remove its attributes, or drop it from the trace along with the
+// segments beneath it.  A TraceSegment must not be retained after Sanitize
+// returns.
+type TraceSegment struct {
+	node    *traceNode
+	dropped bool
+}
+
+// Name returns the name of the segment, eg. "Custom/mySegment" or
+// "Datastore/statement/MySQL/users/SELECT".
+func (s *TraceSegment) Name() string {
+	return s.node.name
+}
+
+// SetName renames the segment in the transaction trace.  The segment's
+// metrics and span event are not affected.
+func (s *TraceSegment) SetName(name string) {
+	s.node.name = name
+}
+
+// Attributes returns a copy of the attributes of the segment, eg.
+// SpanAttributeDBStatement for datastore segments.  Values are strings,
+// ints, float64s, or bools, with the exception of the query parameters of
+// datastore segments, which are a map[string]interface{} under the key
+// "query_parameters".
+func (s *TraceSegment) Attributes() map[string]interface{} {
+	return spanAttributeMap(s.node.attributes).values()
+}
+
+// SetAttribute adds or replaces an attribute of the segment.  Use it to
+// redact a value, for example:
+//
+//	segment.SetAttribute(newrelic.SpanAttributeDBStatement, "[redacted]")
+//
+// Values must be strings, bools, or numbers.  Empty strings are ignored: use
+// RemoveAttribute to remove an attribute.
+func (s *TraceSegment) SetAttribute(key string, value interface{}) {
+	attrs := spanAttributeMap(s.node.attributes)
+	addAttr(&attrs, key, value)
+	s.node.attributes = attrs
+}
+
+// RemoveAttribute removes an attribute from the segment.
+func (s *TraceSegment) RemoveAttribute(key string) {
+	delete(s.node.attributes, key)
+}
+
+// Drop removes the segment from the transaction trace along with every
+// segment beneath it.  The segments beneath it are not passed to Sanitize.
+func (s *TraceSegment) Drop() {
+	s.dropped = true
+}
+
+// sanitize calls fn with each segment of the trace, ordered by thread and
+// then start time, and removes the segments dropped.  A segment's children are the
+// following segments of the same thread which start before it stops.
+func (trace *txnTrace) sanitize(fn func(*TraceSegment)) {
+	nodes := make(sortedTraceNodes, len(trace.nodes))
+	for i := range trace.nodes {
+		nodes[i] = &trace.nodes[i]
+	}
+	sort.Sort(nodes)
+
+	kept := make(traceNodeHeap, 0, len(nodes))
+	for i := 0; i < len(nodes); {
+		node := nodes[i]
+		segment := &TraceSegment{node: node}
+		fn(segment)
+		i++
+		if !segment.dropped {
+			kept = append(kept, *node)
+			continue
+		}
+		for i < len(nodes) && nodes[i].threadID == node.threadID &&
+			nodes[i].start.Stamp < node.stop.Stamp {
+			i++
+		}
+	}
+	trace.nodes = kept
+}
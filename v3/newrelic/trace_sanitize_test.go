@@ -0,0 +1,111 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestTraceSanitize(t *testing.T) {
+	var seen []map[string]interface{}
+	cfgfn := func(cfg *Config) {
+		cfg.TransactionTracer.Segments.Threshold = 0
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.TransactionTracer.Sanitize = func(s *TraceSegment) {
+			seen = append(seen, s.Attributes())
+			switch {
+			case "Custom/vendor.secret" == s.Name():
+				s.SetName("Custom/vendor")
+			case "Custom/drop" == s.Name():
+				s.Drop()
+			case strings.HasPrefix(s.Name(), "Datastore/"):
+				s.SetAttribute(SpanAttributeDBStatement, "[redacted]")
+				s.RemoveAttribute("query_parameters")
+			}
+		}
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.StartSegment("vendor.secret").End()
+	dropped := txn.StartSegment("drop")
+	txn.StartSegment("child").End()
+	dropped.End()
+	ds := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "SELECT",
+		ParameterizedQuery: "SELECT * FROM users WHERE email = ?",
+		QueryParameters:    map[string]interface{}{"email": "me@example.com"},
+	}
+	ds.End()
+	txn.End()
+
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName: "OtherTransaction/Go/hello",
+		Root: internal.WantTraceSegment{
+			SegmentName: "ROOT",
+			Attributes:  map[string]interface{}{},
+			Children: []internal.WantTraceSegment{{
+				SegmentName: "OtherTransaction/Go/hello",
+				Attributes:  map[string]interface{}{"exclusive_duration_millis": internal.MatchAnything},
+				Children: []internal.WantTraceSegment{
+					{
+						SegmentName: "Custom/vendor",
+						Attributes:  map[string]interface{}{},
+					},
+					{
+						SegmentName: "Datastore/statement/MySQL/users/SELECT",
+						Attributes: map[string]interface{}{
+							"db.statement": "[redacted]",
+						},
+					},
+				},
+			}},
+		},
+	}})
+	// The child of the dropped segment is not passed to Sanitize.
+	if len(seen) != 3 {
+		t.Fatal(seen)
+	}
+	params, ok := seen[2]["query_parameters"].(map[string]interface{})
+	if !ok || "me@example.com" != params["email"] {
+		t.Error(seen[2])
+	}
+	if "SELECT * FROM users WHERE email = ?" != seen[2][SpanAttributeDBStatement] {
+		t.Error(seen[2])
+	}
+	// Metrics are not affected.
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Custom/vendor.secret", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/drop", Scope: "", Forced: false, Data: nil},
+		{Name: "Custom/child", Scope: "", Forced: false, Data: nil},
+	})
+}
+
+func TestTraceSanitizeDropSubtree(t *testing.T) {
+	trace := &txnTrace{}
+	trace.nodes = traceNodeHeap{
+		{start: segmentTime{Stamp: 1}, stop: segmentTime{Stamp: 6}, name: "parent"},
+		{start: segmentTime{Stamp: 2}, stop: segmentTime{Stamp: 3}, name: "child"},
+		{start: segmentTime{Stamp: 4}, stop: segmentTime{Stamp: 5}, name: "grandchild", threadID: 1},
+		{start: segmentTime{Stamp: 7}, stop: segmentTime{Stamp: 8}, name: "sibling"},
+	}
+	trace.sanitize(func(s *TraceSegment) {
+		if "parent" == s.Name() {
+			s.Drop()
+		}
+	})
+	var names []string
+	for _, n := range trace.nodes {
+		names = append(names, n.name)
+	}
+	if got := strings.Join(names, ","); "sibling,grandchild" != got {
+		t.Error(got)
+	}
+}
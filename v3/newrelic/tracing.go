@@ -35,6 +35,9 @@ type txnEvent struct {
 	CrossProcess       txnCrossProcess
 	BetterCAT          betterCAT
 	HasError           bool
+	// DTIntrinsics selects the distributed tracing intrinsics written
+	// to the transaction event.
+	DTIntrinsics DistributedTracingIntrinsics
 }
 
 // betterCAT stores the transaction's priority and all fields related
@@ -69,9 +72,16 @@ type txnData struct {
 	Errors         txnErrors // Lazily initialized.
 	Stop           time.Time
 	ApdexThreshold time.Duration
+	// ClockSkew is how far the wall clock jumped during the transaction
+	// relative to the monotonic clock, or zero if it did not jump.  The
+	// times recorded by the transaction are corrected for it.
+	ClockSkew time.Duration
 
 	stamp           segmentStamp
 	threadIDCounter uint64
+	// segmentCount is the number of segments started, which is reported
+	// by Config.TransactionCheckpoints.
+	segmentCount int
 
 	TraceIDGenerator        *internal.TraceIDGenerator
 	ShouldCollectSpanEvents func() bool
@@ -84,6 +94,7 @@ type txnData struct {
 	datastoreSegments map[datastoreMetricKey]*metricData
 	externalSegments  map[externalMetricKey]*metricData
 	messageSegments   map[internal.MessageMetricKey]*metricData
+	ioSegments        map[ioMetricKey]*metricData
 
 	TxnTrace txnTrace
 
@@ -91,6 +102,18 @@ type txnData struct {
 	SlowQueryThreshold time.Duration
 	SlowQueries        *slowQueries
 
+	// codeLevelMetrics controls the code location attributes added to
+	// segments.
+	codeLevelMetrics codeLevelMetrics
+
+	// deadline is the time by which the transaction must complete, or
+	// zero if there is no deadline.
+	deadline               time.Time
+	deadlineBudgetFraction float64
+
+	// Outcome is set by SetOutcome.
+	Outcome Outcome
+
 	// These better CAT supportability fields are left outside of
 	// TxnEvent.BetterCAT to minimize the size of transaction event memory.
 	DistributedTracingSupport distributedTracingSupport
@@ -106,6 +129,10 @@ func (t *txnData) saveTraceSegment(end segmentEnd, name string, attrs spanAttrib
 type tracingThread struct {
 	threadID uint64
 	stack    []segmentFrame
+	// parentSpanID is the span which was current in the goroutine which
+	// called Transaction.NewGoroutine to create this tracingThread.  It is
+	// the parent of the spans of the segments at the bottom of the stack.
+	parentSpanID string
 	// start and end are used to track the TotalTime this tracingThread was active.
 	start time.Time
 	end   time.Time
@@ -224,6 +251,31 @@ func (m spanAttributeMap) copy() spanAttributeMap {
 	return cpy
 }
 
+// values returns a copy of the attributes as strings, ints, float64s, bools,
+// and, for query parameters, maps.
+func (m spanAttributeMap) values() map[string]interface{} {
+	vals := make(map[string]interface{}, len(m))
+	for key, val := range m {
+		switch v := val.(type) {
+		case stringJSONWriter:
+			vals[key] = string(v)
+		case intJSONWriter:
+			vals[key] = int(v)
+		case floatJSONWriter:
+			vals[key] = float64(v)
+		case boolJSONWriter:
+			vals[key] = bool(v)
+		case queryParameters:
+			params := make(map[string]interface{}, len(v))
+			for k, p := range v {
+				params[k] = p
+			}
+			vals[key] = params
+		}
+	}
+	return vals
+}
+
 func (m *spanAttributeMap) addUserAttrs(attrs map[string]userAttribute) {
 	for key, val := range attrs {
 		if val.dests&destSpan > 0 {
@@ -328,7 +380,7 @@ func (t *txnData) time(now time.Time) segmentTime {
 	// Update the stamp before using it so that a 0 stamp can be special.
 	t.stamp++
 	return segmentTime{
-		Time:  now,
+		Time:  t.correctTime(now),
 		Stamp: t.stamp,
 	}
 }
@@ -365,10 +417,17 @@ func (thread *tracingThread) RemoveErrorSpanAttribute(key string) {
 // startSegment begins a segment.
 func startSegment(t *txnData, thread *tracingThread, now time.Time) segmentStartTime {
 	tm := t.time(now)
-	thread.stack = append(thread.stack, segmentFrame{
+	t.segmentCount++
+	frame := segmentFrame{
 		segmentTime: tm,
 		children:    0,
-	})
+	}
+	// The caller is only looked up for segments which will be recorded as
+	// spans, since runtime.Callers is expensive.
+	if fn := t.ShouldCollectSpanEvents; t.codeLevelMetrics.enabled && nil != fn && fn() {
+		t.codeLevelMetrics.addAttributes(&frame.agentAttributes)
+	}
+	thread.stack = append(thread.stack, frame)
 
 	return segmentStartTime{
 		Stamp: tm.Stamp,
@@ -388,6 +447,9 @@ func (t *txnData) GetRootSpanID() string {
 // segment stack.
 func (t *txnData) CurrentSpanIdentifier(thread *tracingThread) string {
 	if 0 == len(thread.stack) {
+		if "" != thread.parentSpanID {
+			return thread.parentSpanID
+		}
 		return t.GetRootSpanID()
 	}
 	if "" == thread.stack[len(thread.stack)-1].spanID {
@@ -458,12 +520,11 @@ func endSegment(t *txnData, thread *tracingThread, start segmentStartTime, now t
 		if "" == s.SpanID {
 			s.SpanID = t.TraceIDGenerator.GenerateSpanID()
 		}
-	}
-
-	if fn := t.ShouldCollectSpanEvents; nil != fn && fn() {
 		// Note that the current span identifier is the parent's
 		// identifier because we've already popped the segment that's
-		// ending off of the stack.
+		// ending off of the stack.  The parent is recorded even if the
+		// transaction is not yet sampled, since noticing an error may
+		// cause it to be sampled later.
 		s.ParentID = t.CurrentSpanIdentifier(thread)
 	}
 
@@ -531,6 +592,7 @@ func endExternalSegment(p endExternalParams) error {
 	if nil != err {
 		return err
 	}
+	t.addDeadlineAttributes(&end)
 
 	// Use the Host field if present, otherwise use host in the URL.
 	if p.Host == "" && p.URL != nil {
@@ -594,6 +656,7 @@ func endExternalSegment(p endExternalParams) error {
 		if p.Library == "http" {
 			attributes.addString(SpanAttributeHTTPURL, safeURL(p.URL))
 		}
+		t.Attrs.excludeIntegrationAttributes(p.Library, attributes)
 		t.saveTraceSegment(end, key.scopedMetric(), attributes, transactionGUID)
 	}
 
@@ -611,6 +674,7 @@ func endExternalSegment(p endExternalParams) error {
 		} else if p.Response != nil {
 			evt.AgentAttributes.addInt(SpanAttributeHTTPStatusCode, p.Response.StatusCode)
 		}
+		t.Attrs.excludeIntegrationAttributes(p.Library, evt.AgentAttributes)
 		t.saveSpanEvent(evt)
 	}
 
@@ -661,12 +725,82 @@ func endMessageSegment(p endMessageParams) error {
 
 	if t.TxnTrace.considerNode(end) {
 		attributes := end.agentAttributes.copy()
+		t.Attrs.excludeIntegrationAttributes(p.Library, attributes)
 		t.saveTraceSegment(end, key.Name(), attributes, "")
 	}
 
 	if evt := end.spanEvent(); evt != nil {
 		evt.Name = key.Name()
 		evt.Category = spanCategoryGeneric
+		t.Attrs.excludeIntegrationAttributes(p.Library, evt.AgentAttributes)
+		t.saveSpanEvent(evt)
+	}
+
+	return nil
+}
+
+// endIOParams contains the parameters for endIOSegment.
+type endIOParams struct {
+	TxnData      *txnData
+	Thread       *tracingThread
+	Start        segmentStartTime
+	Now          time.Time
+	Product      string
+	Operation    string
+	Location     string
+	StorageClass string
+	Bytes        int64
+}
+
+// endIOSegment ends a file or object storage I/O segment.
+func endIOSegment(p endIOParams) error {
+	t := p.TxnData
+	end, err := endSegment(t, p.Thread, p.Start, p.Now)
+	if nil != err {
+		return err
+	}
+
+	key := ioMetricKey{
+		Product:   p.Product,
+		Operation: p.Operation,
+	}
+
+	if nil == t.ioSegments {
+		t.ioSegments = make(map[ioMetricKey]*metricData)
+	}
+	m := metricDataFromDuration(end.duration, end.exclusive)
+	if data, ok := t.ioSegments[key]; ok {
+		data.aggregate(m)
+	} else {
+		// Use `new` in place of &m so that m is not
+		// automatically moved to the heap.
+		cpy := new(metricData)
+		*cpy = m
+		t.ioSegments[key] = cpy
+	}
+
+	addIOAttributes := func(attrs *spanAttributeMap) {
+		attrs.addString(SpanAttributeIOLocation, p.Location)
+		attrs.addString(SpanAttributeIOStorageClass, p.StorageClass)
+		if p.Bytes > 0 {
+			attrs.addInt(SpanAttributeIOBytes, int(p.Bytes))
+			if seconds := end.duration.Seconds(); seconds > 0 {
+				attrs.addFloat(SpanAttributeIOThroughput, float64(p.Bytes)/seconds)
+			}
+		}
+	}
+
+	if t.TxnTrace.considerNode(end) {
+		attributes := end.agentAttributes.copy()
+		addIOAttributes(&attributes)
+		t.saveTraceSegment(end, key.scopedMetric(), attributes, "")
+	}
+
+	if evt := end.spanEvent(); evt != nil {
+		evt.Name = key.scopedMetric()
+		evt.Category = spanCategoryGeneric
+		evt.Component = p.Product
+		addIOAttributes(&evt.AgentAttributes)
 		t.saveSpanEvent(evt)
 	}
 
@@ -721,12 +855,23 @@ func datastoreSpanAddress(host, portPathOrID string) string {
 	return portPathOrID
 }
 
+// unknownDatastoreQuery describes the query of a slow query whose
+// ParameterizedQuery was not provided or is excluded.
+func unknownDatastoreQuery(p endDatastoreParams) string {
+	collection := p.Collection
+	if "" == collection {
+		collection = "unknown"
+	}
+	return fmt.Sprintf(`'%s' on '%s' using '%s'`, p.Operation, collection, p.Product)
+}
+
 // endDatastoreSegment ends a datastore segment.
 func endDatastoreSegment(p endDatastoreParams) error {
 	end, err := endSegment(p.TxnData, p.Thread, p.Start, p.Now)
 	if nil != err {
 		return err
 	}
+	p.TxnData.addDeadlineAttributes(&end)
 	if p.Operation == "" {
 		p.Operation = datastoreOperationUnknown
 	}
@@ -747,12 +892,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 	// a Query string (or it has been removed by LASP) since the stack trace
 	// has value.
 	if p.ParameterizedQuery == "" {
-		collection := p.Collection
-		if "" == collection {
-			collection = "unknown"
-		}
-		p.ParameterizedQuery = fmt.Sprintf(`'%s' on '%s' using '%s'`,
-			p.Operation, collection, p.Product)
+		p.ParameterizedQuery = unknownDatastoreQuery(p)
 	}
 
 	key := datastoreMetricKey{
@@ -791,6 +931,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		if len(queryParams) > 0 {
 			attributes.add(spanAttributeQueryParameters, queryParams)
 		}
+		p.TxnData.Attrs.excludeIntegrationAttributes(p.Product, attributes)
 		p.TxnData.saveTraceSegment(end, scopedMetric, attributes, "")
 	}
 
@@ -798,7 +939,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		if nil == p.TxnData.SlowQueries {
 			p.TxnData.SlowQueries = newSlowQueries(maxTxnSlowQueries)
 		}
-		p.TxnData.SlowQueries.observeInstance(slowQueryInstance{
+		slow := slowQueryInstance{
 			Duration:           end.duration,
 			DatastoreMetric:    scopedMetric,
 			ParameterizedQuery: p.ParameterizedQuery,
@@ -807,7 +948,11 @@ func endDatastoreSegment(p endDatastoreParams) error {
 			PortPathOrID:       p.PortPathOrID,
 			DatabaseName:       p.Database,
 			StackTrace:         getStackTrace(),
-		})
+		}
+		if excluded := p.TxnData.Attrs.integrationExcludes(p.Product); len(excluded) > 0 {
+			slow.excludeAttributes(excluded, unknownDatastoreQuery(p))
+		}
+		p.TxnData.SlowQueries.observeInstance(slow)
 	}
 
 	if evt := end.spanEvent(); evt != nil {
@@ -820,6 +965,7 @@ func endDatastoreSegment(p endDatastoreParams) error {
 		evt.AgentAttributes.addString(SpanAttributePeerAddress, datastoreSpanAddress(p.Host, p.PortPathOrID))
 		evt.AgentAttributes.addString(SpanAttributePeerHostname, p.Host)
 		evt.AgentAttributes.addString(SpanAttributeDBCollection, p.Collection)
+		p.TxnData.Attrs.excludeIntegrationAttributes(p.Product, evt.AgentAttributes)
 		p.TxnData.saveSpanEvent(evt)
 	}
 
@@ -890,4 +1036,14 @@ func mergeBreakdownMetrics(t *txnData, metrics *metricTable) {
 		metrics.add(metric, scope, *data, unforced)
 		metrics.add(metric, "", *data, unforced)
 	}
+	// IO Segment Metrics
+	for key, data := range t.ioSegments {
+		metrics.add(ioRollupMetric.all, "", *data, forced)
+		metrics.add(ioRollupMetric.webOrOther(isWeb), "", *data, forced)
+		metrics.add(key.productMetric(), "", *data, unforced)
+
+		metric := key.scopedMetric()
+		metrics.add(metric, scope, *data, unforced)
+		metrics.add(metric, "", *data, unforced)
+	}
 }
@@ -0,0 +1,92 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"math"
+	"time"
+)
+
+// TracingDetail controls how much detail is captured for a single
+// transaction.  See Transaction.SetTracingDetail.
+type TracingDetail int
+
+const (
+	// TracingDetailDefault captures the detail dictated by the
+	// application's configuration.
+	TracingDetailDefault TracingDetail = iota
+	// TracingDetailMetrics records only metrics and events: no
+	// transaction trace, slow queries, or span events are captured.
+	TracingDetailMetrics
+	// TracingDetailSpans samples the transaction so that its span events
+	// are captured.  Transaction traces are captured as configured but
+	// without stack traces.
+	TracingDetailSpans
+	// TracingDetailStacks captures span events and a transaction trace
+	// containing every segment along with its stack trace.
+	TracingDetailStacks
+	// TracingDetailFull captures everything TracingDetailStacks does,
+	// records every datastore query as a slow query, and keeps query
+	// parameters.  Config.HighSecurity and security policies still apply.
+	TracingDetailFull
+)
+
+var errInvalidTracingDetail = errors.New("invalid tracing detail")
+
+// noStackTraceThreshold is used as the stack trace threshold when stack
+// traces should never be captured.
+const noStackTraceThreshold = time.Duration(math.MaxInt64)
+
+func (d TracingDetail) valid() bool {
+	return d >= TracingDetailDefault && d <= TracingDetailFull
+}
+
+// forceSampled marks the transaction as sampled regardless of the adaptive
+// sampler's decision.
+func (txn *txn) forceSampled() {
+	if !txn.BetterCAT.Enabled || txn.lazilyCalculateSampled() {
+		return
+	}
+	txn.BetterCAT.Sampled = true
+	txn.BetterCAT.Priority += 1.0
+}
+
+func (txn *txn) SetTracingDetail(detail TracingDetail) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if !detail.valid() {
+		return errInvalidTracingDetail
+	}
+	txn.tracingDetail = detail
+
+	txn.TxnTrace.Enabled = txn.Config.TransactionTracer.Enabled
+	txn.TxnTrace.SegmentThreshold = txn.Config.TransactionTracer.Segments.Threshold
+	txn.TxnTrace.StackTraceThreshold = txn.Config.TransactionTracer.Segments.StackTraceThreshold
+	txn.SlowQueriesEnabled = txn.Config.DatastoreTracer.SlowQuery.Enabled
+	txn.SlowQueryThreshold = txn.Config.DatastoreTracer.SlowQuery.Threshold
+
+	switch detail {
+	case TracingDetailMetrics:
+		txn.TxnTrace.Enabled = false
+		txn.SlowQueriesEnabled = false
+	case TracingDetailSpans:
+		txn.TxnTrace.StackTraceThreshold = noStackTraceThreshold
+		txn.forceSampled()
+	case TracingDetailStacks, TracingDetailFull:
+		txn.TxnTrace.Enabled = true
+		txn.TxnTrace.SegmentThreshold = 0
+		txn.TxnTrace.StackTraceThreshold = 0
+		txn.forceSampled()
+		if TracingDetailFull == detail {
+			txn.SlowQueriesEnabled = true
+			txn.SlowQueryThreshold = 0
+		}
+	}
+	return nil
+}
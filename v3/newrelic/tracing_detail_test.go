@@ -0,0 +1,169 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestTracingDetailMetrics(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+		cfg.TransactionTracer.Threshold.IsApdexFailing = false
+		cfg.TransactionTracer.Threshold.Duration = 0
+		cfg.DatastoreTracer.SlowQuery.Threshold = 0
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetailMetrics)
+	s := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+	}
+	s.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{})
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{})
+	app.ExpectSpanEvents(t, []internal.WantEvent{})
+	app.ExpectMetricsPresent(t, []internal.WantMetric{
+		{Name: "Datastore/statement/MySQL/users/INSERT", Scope: "", Forced: false, Data: nil},
+	})
+}
+
+func TestTracingDetailSpans(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleNothing()
+	}
+	cfgfn := func(cfg *Config) {
+		cfg.DistributedTracer.Enabled = true
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetailSpans)
+	if !txn.IsSampled() {
+		t.Error("transaction should be sampled")
+	}
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectSpanEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":             "OtherTransaction/Go/hello",
+			"sampled":          true,
+			"category":         "generic",
+			"priority":         internal.MatchAnything,
+			"guid":             internal.MatchAnything,
+			"transactionId":    internal.MatchAnything,
+			"nr.entryPoint":    true,
+			"traceId":          internal.MatchAnything,
+			"transaction.name": "OtherTransaction/Go/hello",
+		},
+	}})
+}
+
+func TestTracingDetailFull(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.DatastoreTracer.SlowQuery.Threshold = 1 * time.Hour
+		cfg.DatastoreTracer.QueryParameters.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetailFull)
+	s := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+		QueryParameters:    map[string]interface{}{"name": "zap"},
+	}
+	s.End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{{
+		MetricName:  "OtherTransaction/Go/hello",
+		NumSegments: 1,
+	}})
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/MySQL/users/INSERT",
+		Query:      "INSERT INTO users (name, age) VALUES ($1, $2)",
+		TxnName:    "OtherTransaction/Go/hello",
+		TxnURL:     "",
+		Params:     map[string]interface{}{"name": "zap"},
+	}})
+}
+
+func TestTracingDetailFullHighSecurity(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		cfg.HighSecurity = true
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetailFull)
+	s := DatastoreSegment{
+		StartTime:          txn.StartSegmentNow(),
+		Product:            DatastoreMySQL,
+		Collection:         "users",
+		Operation:          "INSERT",
+		ParameterizedQuery: "INSERT INTO users (name, age) VALUES ($1, $2)",
+		QueryParameters:    map[string]interface{}{"name": "zap"},
+	}
+	s.End()
+	txn.End()
+
+	app.ExpectSlowQueries(t, []internal.WantSlowQuery{{
+		Count:      1,
+		MetricName: "Datastore/statement/MySQL/users/INSERT",
+		Query:      "INSERT INTO users (name, age) VALUES ($1, $2)",
+		TxnName:    "OtherTransaction/Go/hello",
+		TxnURL:     "",
+		Params:     nil,
+	}})
+}
+
+func TestTracingDetailDefaultRestoresConfig(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetailStacks)
+	txn.SetTracingDetail(TracingDetailDefault)
+	txn.StartSegment("segment").End()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnTraces(t, []internal.WantTxnTrace{})
+}
+
+func TestTracingDetailInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetTracingDetail(TracingDetail(42))
+	app.expectSingleLoggedError(t, "unable to set tracing detail", map[string]interface{}{
+		"reason": errInvalidTracingDetail.Error(),
+	})
+}
+
+func TestTracingDetailAfterEnd(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.End()
+	txn.SetTracingDetail(TracingDetailFull)
+	app.expectSingleLoggedError(t, "unable to set tracing detail", map[string]interface{}{
+		"reason": errAlreadyEnded.Error(),
+	})
+}
+
+func TestTracingDetailNilTransaction(t *testing.T) {
+	var txn *Transaction
+	txn.SetTracingDetail(TracingDetailFull)
+}
@@ -4,6 +4,7 @@
 package newrelic
 
 import (
+	"crypto/tls"
 	"net/http"
 	"net/url"
 	"strings"
@@ -64,6 +65,125 @@ func (txn *Transaction) SetName(name string) {
 	txn.thread.logAPIError(txn.thread.SetName(name), "set transaction name", nil)
 }
 
+// SetApplications directs the Transaction's data to a subset of the rollup
+// application names in Config.AppName.  For example, when AppName is
+// "a;b;c", calling SetApplications([]string{"a", "c"}) will report this
+// Transaction, its events, and any data recorded using the Application
+// returned by Transaction.Application only to applications "a" and "c".
+// The subset must be listed in Config.ApplicationSubsets: each subset uses
+// its own connection to New Relic, which is established when the
+// Application is created.  SetApplications must be called before End.
+func (txn *Transaction) SetApplications(names []string) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetApplications(names), "set transaction applications", nil)
+}
+
+// SetTracingDetail raises or lowers the detail captured for this
+// Transaction, overriding the application's tracing configuration.  For
+// example, use TracingDetailFull to capture everything about a request
+// being debugged, or TracingDetailMetrics to avoid tracing a noisy
+// request.  Segments which have already ended are not affected, so
+// SetTracingDetail should be called immediately after the Transaction is
+// started.
+func (txn *Transaction) SetTracingDetail(detail TracingDetail) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetTracingDetail(detail), "set tracing detail", nil)
+}
+
+// SetDeadline records the time by which the Transaction must complete.
+// External and datastore segments then record the time remaining until the
+// deadline, see Config.DeadlineBudget.  The earliest deadline given is
+// used.  SetDeadline does not need to be called when the deadline is set on
+// the context passed to NewContext or the request passed to
+// SetWebRequestHTTP.
+func (txn *Transaction) SetDeadline(deadline time.Time) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetDeadline(deadline), "set deadline", nil)
+}
+
+// SetOutcome records how the Transaction finished: OutcomeSuccess,
+// OutcomeClientError, OutcomeServerError, OutcomeCancelled,
+// OutcomeTimeout, or OutcomeRequestTooLarge.  The outcome is independent of any HTTP status code and
+// errors noticed, so that web and background transactions can be analyzed
+// the same way.  It is recorded as the AttributeTransactionOutcome
+// attribute and counted by metrics such as
+// "TransactionOutcome/Timeout/all", "TransactionOutcome/Timeout/allWeb", and
+// "TransactionOutcome/Timeout/{transaction name}".  The last outcome set
+// before the Transaction ends is used.
+func (txn *Transaction) SetOutcome(outcome Outcome) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetOutcome(outcome), "set outcome", nil)
+}
+
+// MarkImportant flags the Transaction as a business-critical event, eg. a
+// failed payment, so that its data is preferentially kept when data is
+// sampled or dropped.  The reason is recorded as the AttributeRetentionHint
+// attribute.  The Transaction is sampled, so that its span events are
+// collected, and its priority is raised above that of ordinary
+// transactions.  Call MarkImportant before making outbound calls so that the
+// raised priority is passed on in the distributed tracing headers.
+func (txn *Transaction) MarkImportant(reason string) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.MarkImportant(reason), "mark important", nil)
+}
+
+// SetWorkflow links the Transaction to the other steps of a multi-step
+// workflow, such as a saga, by adding the AttributeWorkflowID and
+// AttributeWorkflowStep attributes.  The step is the index of the
+// Transaction within the workflow.  When distributed tracing is enabled, the
+// workflow is propagated in the W3C baggage header added by
+// InsertDistributedTraceHeaders, and a Transaction which accepts the header
+// using AcceptDistributedTraceHeaders or SetWebRequest joins the workflow as
+// the next step.  Call SetWorkflow before making outbound calls.
+func (txn *Transaction) SetWorkflow(id string, step int) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.SetWorkflow(id, step), "set workflow", nil)
+}
+
+// RecordLog records a log written within the Transaction.  When
+// Config.ApplicationLogging.Forwarding is enabled, the log is sent to New
+// Relic with the trace.id and span.id of the Transaction, so that it appears
+// alongside the Transaction's traces.  See Application.RecordLog.
+func (txn *Transaction) RecordLog(log LogData) {
+	if nil == txn {
+		return
+	}
+	if nil == txn.thread {
+		return
+	}
+	txn.thread.logAPIError(txn.thread.RecordLog(log, time.Now()), "record log", nil)
+}
+
 // NoticeError records an error.  The Transaction saves the first five
 // errors.  For more control over the recorded error fields, see the
 // newrelic.Error type.
@@ -83,12 +203,16 @@ func (txn *Transaction) SetName(name string) {
 //   // ErrorClass sets the error's class
 //   ErrorClass() string
 //
+//   // ErrorCode sets the error's machine-readable code, recorded as the
+//   // "error.code" attribute
+//   ErrorCode() string
+//
 //   // ErrorAttributes sets the errors attributes
 //   ErrorAttributes() map[string]interface{}
 //
 // The newrelic.Error type, which implements these methods, is the recommended
-// way to directly control the recorded error's message, class, stacktrace,
-// and attributes.
+// way to directly control the recorded error's message, class, code,
+// stacktrace, and attributes.
 func (txn *Transaction) NoticeError(err error) {
 	if nil == txn {
 		return
@@ -114,7 +238,11 @@ func (txn *Transaction) AddAttribute(key string, value interface{}) {
 	if nil == txn.thread {
 		return
 	}
-	txn.thread.logAPIError(txn.thread.AddAttribute(key, value), "add attribute", nil)
+	err := txn.thread.AddAttribute(key, value)
+	if nil == err {
+		txn.thread.reportAttributeOverflow(key, value, destAll)
+	}
+	txn.thread.logAPIError(err, "add attribute", nil)
 }
 
 // SetWebRequestHTTP marks the transaction as a web transaction.  If
@@ -131,10 +259,19 @@ func (txn *Transaction) SetWebRequestHTTP(r *http.Request) {
 		Header:    r.Header,
 		URL:       r.URL,
 		Method:    r.Method,
-		Transport: transport(r),
-		Host:      r.Host,
+		Transport:  transport(r),
+		Host:       r.Host,
+		Proto:      r.Proto,
+		RemoteAddr: r.RemoteAddr,
+		TLS:        r.TLS,
 	}
 	txn.SetWebRequest(wr)
+	if deadline, ok := r.Context().Deadline(); ok {
+		txn.SetDeadline(deadline)
+	}
+	if nil != txn && nil != txn.thread {
+		txn.thread.watchContext(r.Context())
+	}
 }
 
 func transport(r *http.Request) TransportType {
@@ -191,10 +328,14 @@ func (txn *Transaction) SetWebResponse(w http.ResponseWriter) http.ResponseWrite
 // ExternalSegment.  The returned SegmentStartTime is safe to use even  when the
 // Transaction receiver is nil.  In this case, the segment will have no effect.
 func (txn *Transaction) StartSegmentNow() SegmentStartTime {
-	return txn.startSegmentAt(time.Now())
+	return txn.StartSegmentAt(time.Now())
 }
 
-func (txn *Transaction) startSegmentAt(at time.Time) SegmentStartTime {
+// StartSegmentAt is like StartSegmentNow, but the segment starts at the time
+// provided.  It is used to record calls which are observed after they have
+// completed, together with DatastoreSegment.EndAt.  The segment must be
+// ended before another segment is started using the Transaction.
+func (txn *Transaction) StartSegmentAt(at time.Time) SegmentStartTime {
 	if nil == txn {
 		return SegmentStartTime{}
 	}
@@ -224,6 +365,33 @@ func (txn *Transaction) StartSegment(name string) *Segment {
 	}
 }
 
+// StartBackgroundSegment starts a segment for work done in a goroutine which
+// may outlive the Transaction, eg. fire-and-forget processing spawned by a
+// handler:
+//
+//	segment := txn.StartBackgroundSegment("sendEmail")
+//	go func() {
+//		defer segment.End()
+//		// ... work which may continue after txn.End ...
+//	}()
+//
+// Unlike segments started with StartSegment, which are dropped if they end
+// after the Transaction, a BackgroundSegment may be ended from any goroutine
+// at any time.  It is recorded as a span event, a child of the span which
+// was current when StartBackgroundSegment was called, with the
+// SpanAttributeAsync attribute.  It is recorded only if the Transaction's
+// span events are, and does not create metrics or transaction trace
+// segments.
+func (txn *Transaction) StartBackgroundSegment(name string) *BackgroundSegment {
+	s := &BackgroundSegment{Name: name, start: time.Now()}
+	if nil == txn || nil == txn.thread {
+		return s
+	}
+	s.thread = txn.thread
+	txn.thread.logAPIError(txn.thread.startBackgroundSegment(s), "start background segment", nil)
+	return s
+}
+
 // InsertDistributedTraceHeaders adds the Distributed Trace headers used to
 // link transactions.  InsertDistributedTraceHeaders should be called every
 // time an outbound call is made since the payload contains a timestamp.
@@ -313,7 +481,27 @@ func (txn *Transaction) BrowserTimingHeader() *BrowserTimingHeader {
 // goroutine. It does not matter if you call this before or after the
 // other goroutine has started.
 //
-// All Transaction methods can be used in any Transaction reference.
+// All Transaction methods can be used in any Transaction reference, and
+// the references may be used concurrently: the agent serializes access to
+// the Transaction internally.  Each reference tracks its own segments, so
+// segments may be started and ended in different goroutines at the same
+// time, eg. by a handler which fans out work:
+//
+//	var wg sync.WaitGroup
+//	for _, item := range items {
+//		wg.Add(1)
+//		go func(txn *newrelic.Transaction, item string) {
+//			defer wg.Done()
+//			defer txn.StartSegment("process").End()
+//			// ... process the item ...
+//		}(txn.NewGoroutine(), item)
+//	}
+//	wg.Wait()
+//
+// The spans of the segments started using the new reference are children
+// of the span of the segment which was in progress in the calling
+// goroutine when NewGoroutine was called.
+//
 // The Transaction will end when End() is called in any goroutine.
 // Note that any segments that end after the transaction ends will not
 // be reported.
@@ -375,6 +563,9 @@ const (
 	// DistributedTraceW3CTraceParentHeader is one of two headers used by W3C
 	// trace context
 	DistributedTraceW3CTraceParentHeader = "Traceparent"
+	// DistributedTraceW3CBaggageHeader is the W3C baggage header used to
+	// propagate the workflow set using Transaction.SetWorkflow.
+	DistributedTraceW3CBaggageHeader = "Baggage"
 )
 
 // TransportType is used in Transaction.AcceptDistributedTraceHeaders to
@@ -421,6 +612,16 @@ type WebRequest struct {
 	// This is the value of the `Host` header. Go does not add it to the
 	// http.Header object and so must be passed separately.
 	Host string
+	// Proto is the request's protocol, eg. "HTTP/1.1" or "HTTP/2.0".  It
+	// is recorded as AttributeHTTPFlavor.
+	Proto string
+	// RemoteAddr is the network address of the client, eg.
+	// "192.0.2.1:1234".  It is recorded as AttributeRequestClientIP when
+	// Config.ClientIP.Enabled is true.
+	RemoteAddr string
+	// TLS is the state of the request's TLS connection, if any.  It is
+	// used to record AttributeTLSVersion and AttributeTLSALPN.
+	TLS *tls.ConnectionState
 }
 
 // LinkingMetadata is returned by Transaction.GetLinkingMetadata.  It contains
@@ -0,0 +1,81 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import "time"
+
+// txnCheckpointEventType is the type of the custom events recorded by
+// Config.TransactionCheckpoints.
+const txnCheckpointEventType = "TransactionCheckpoint"
+
+// checkpoint creates a checkpoint event for the transaction.  It returns
+// nil if the transaction is a web transaction, has ended, or has not yet
+// run for Config.TransactionCheckpoints.Threshold, or if custom events may
+// not be recorded.
+func (txn *txn) checkpoint(now time.Time) *customEvent {
+	if txn.finished || txn.IsWeb || txn.ignore {
+		return nil
+	}
+	elapsed := now.Sub(txn.Start)
+	if elapsed < txn.Config.TransactionCheckpoints.Threshold {
+		return nil
+	}
+	if txn.Config.HighSecurity ||
+		!txn.Config.CustomInsightsEvents.Enabled ||
+		!txn.Reply.CollectCustomEvents ||
+		!txn.Reply.SecurityPolicies.CustomEvents.Enabled() {
+		return nil
+	}
+	name := txn.FinalName
+	if "" == name {
+		name = txn.appRun.createTransactionName(txn.Name, txn.IsWeb)
+		if "" == name {
+			return nil
+		}
+	}
+	txn.checkpoints++
+
+	params := make(map[string]interface{})
+	params["transactionName"] = name
+	params["elapsed"] = elapsed.Seconds()
+	params["segmentCount"] = txn.segmentCount
+	params["checkpoint"] = txn.checkpoints
+	if txn.BetterCAT.Enabled {
+		params["guid"] = txn.BetterCAT.TxnID
+		params["traceId"] = txn.BetterCAT.TraceID
+	}
+	for key, attr := range txn.Attrs.user {
+		if len(params) >= customEventAttributeLimit {
+			break
+		}
+		if 0 == attr.dests&destTxnEvent {
+			continue
+		}
+		if _, ok := params[key]; !ok {
+			params[key] = attr.value
+		}
+	}
+
+	e, err := txn.AttributeConfig.attributeLimits().createCustomEvent(txnCheckpointEventType, params, now)
+	if nil != err {
+		return nil
+	}
+	return e
+}
+
+// recordCheckpoints records a checkpoint event for each background
+// transaction in progress which has run for at least
+// Config.TransactionCheckpoints.Threshold.
+func (app *app) recordCheckpoints(now time.Time) {
+	for _, t := range app.inFlight.list() {
+		t.Lock()
+		e := t.checkpoint(now)
+		consumer, id := t.consumer()
+		t.Unlock()
+
+		if nil != e {
+			consumer.Consume(id, e)
+		}
+	}
+}
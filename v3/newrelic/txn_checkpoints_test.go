@@ -0,0 +1,120 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func enableTxnCheckpoints(cfg *Config) {
+	cfg.TransactionCheckpoints.Enabled = true
+}
+
+func TestTxnCheckpoints(t *testing.T) {
+	app := testApp(nil, enableTxnCheckpoints, t)
+	txn := app.StartTransaction("etl")
+	txn.AddAttribute("rows", 100)
+	txn.StartSegment("extract").End()
+	web := app.StartTransaction("web")
+	web.SetWebRequestHTTP(&http.Request{Method: "GET"})
+
+	start := time.Now()
+	internalApp(app).recordCheckpoints(start.Add(time.Minute))
+	internalApp(app).recordCheckpoints(start.Add(10 * time.Minute))
+	txn.AddAttribute("rows", 200)
+	txn.StartSegment("transform").End()
+	internalApp(app).recordCheckpoints(start.Add(11 * time.Minute))
+	txn.End()
+	web.End()
+	internalApp(app).recordCheckpoints(start.Add(12 * time.Minute))
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "TransactionCheckpoint",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"transactionName": "OtherTransaction/Go/etl",
+			"elapsed":         internal.MatchAnything,
+			"segmentCount":    1,
+			"checkpoint":      1,
+			"rows":            100,
+		},
+	}, {
+		Intrinsics: map[string]interface{}{
+			"type":      "TransactionCheckpoint",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"transactionName": "OtherTransaction/Go/etl",
+			"elapsed":         internal.MatchAnything,
+			"segmentCount":    2,
+			"checkpoint":      2,
+			"rows":            200,
+		},
+	}})
+}
+
+func TestTxnCheckpointsDistributedTracing(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		enableTxnCheckpoints(cfg)
+	}
+	app := testApp(distributedTracingReplyFields, cfgfn, t)
+	txn := app.StartTransaction("etl")
+	internalApp(app).recordCheckpoints(time.Now().Add(time.Hour))
+	md := txn.GetTraceMetadata()
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectCustomEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"type":      "TransactionCheckpoint",
+			"timestamp": internal.MatchAnything,
+		},
+		UserAttributes: map[string]interface{}{
+			"transactionName": "OtherTransaction/Go/etl",
+			"elapsed":         internal.MatchAnything,
+			"segmentCount":    0,
+			"checkpoint":      1,
+			"guid":            internal.MatchAnything,
+			"traceId":         md.TraceID,
+		},
+	}})
+}
+
+func TestTxnCheckpointsCustomEventsDisabled(t *testing.T) {
+	cfgfn := func(cfg *Config) {
+		enableTxnCheckpoints(cfg)
+		cfg.CustomInsightsEvents.Enabled = false
+	}
+	app := testApp(nil, cfgfn, t)
+	txn := app.StartTransaction("etl")
+	internalApp(app).recordCheckpoints(time.Now().Add(time.Hour))
+	txn.End()
+	app.ExpectCustomEvents(t, []internal.WantEvent{})
+}
+
+func TestValidateTxnCheckpoints(t *testing.T) {
+	cfg := defaultConfig()
+	cfg.License = testLicenseKey
+	cfg.AppName = "my app"
+	cfg.TransactionCheckpoints.Enabled = true
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+	cfg.TransactionCheckpoints.Interval = 0
+	if err := cfg.validate(); errTxnCheckpoints != err {
+		t.Error(err)
+	}
+	cfg.TransactionCheckpoints.Enabled = false
+	if err := cfg.validate(); nil != err {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,63 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+// DistributedTracingIntrinsics controls which distributed tracing
+// intrinsics are added to transaction events.  See
+// Config.TransactionEvents.DistributedTracingIntrinsics.
+type DistributedTracingIntrinsics string
+
+const (
+	// DistributedTracingIntrinsicsAll adds every distributed tracing
+	// intrinsic: the parent.* intrinsics, parentId, parentSpanId, guid,
+	// traceId, priority, and sampled.
+	DistributedTracingIntrinsicsAll DistributedTracingIntrinsics = "all"
+	// DistributedTracingIntrinsicsTrace adds only traceId, priority, and
+	// sampled, which are enough to find the trace of a transaction.
+	DistributedTracingIntrinsicsTrace DistributedTracingIntrinsics = "trace"
+	// DistributedTracingIntrinsicsNone adds no distributed tracing
+	// intrinsics.
+	DistributedTracingIntrinsicsNone DistributedTracingIntrinsics = "none"
+)
+
+// validateTxnEventIntrinsics checks that
+// TransactionEvents.DistributedTracingIntrinsics is a known value.  The
+// empty string is treated as DistributedTracingIntrinsicsAll.
+func (c Config) validateTxnEventIntrinsics() error {
+	switch c.TransactionEvents.DistributedTracingIntrinsics {
+	case "", DistributedTracingIntrinsicsAll, DistributedTracingIntrinsicsTrace, DistributedTracingIntrinsicsNone:
+		return nil
+	default:
+		return errTxnEventIntrinsics
+	}
+}
+
+// txnEventBetterCATIntrinsics reports the distributed tracing intrinsics of
+// a transaction event which are selected by its DTIntrinsics.
+func txnEventBetterCATIntrinsics(e *txnEvent, w *jsonFieldsWriter) {
+	if !e.BetterCAT.Enabled {
+		return
+	}
+	switch e.DTIntrinsics {
+	case DistributedTracingIntrinsicsNone:
+		return
+	case DistributedTracingIntrinsicsTrace:
+		w.stringField("traceId", e.BetterCAT.TraceID)
+		w.writerField("priority", e.BetterCAT.Priority)
+		w.boolField("sampled", e.BetterCAT.Sampled)
+		return
+	}
+
+	sharedBetterCATIntrinsics(e, w)
+
+	if p := e.BetterCAT.Inbound; nil != p {
+		if "" != p.TransactionID {
+			w.stringField("parentId", p.TransactionID)
+		}
+
+		if "" != p.ID {
+			w.stringField("parentSpanId", p.ID)
+		}
+	}
+}
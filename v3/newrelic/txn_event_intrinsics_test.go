@@ -0,0 +1,115 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestValidateTxnEventIntrinsics(t *testing.T) {
+	testcases := []struct {
+		intrinsics DistributedTracingIntrinsics
+		expect     bool
+	}{
+		{intrinsics: "", expect: true},
+		{intrinsics: DistributedTracingIntrinsicsAll, expect: true},
+		{intrinsics: DistributedTracingIntrinsicsTrace, expect: true},
+		{intrinsics: DistributedTracingIntrinsicsNone, expect: true},
+		{intrinsics: "parent", expect: false},
+	}
+	for _, tc := range testcases {
+		cfg := defaultConfig()
+		cfg.TransactionEvents.DistributedTracingIntrinsics = tc.intrinsics
+		if err := cfg.validateTxnEventIntrinsics(); (nil == err) != tc.expect {
+			t.Error(tc.intrinsics, err)
+		}
+	}
+}
+
+func sampleTxnEventWithInbound() txnEvent {
+	e := sampleTxnEvent
+	e.BetterCAT.Inbound = &payload{
+		Type:                 "App",
+		App:                  "caller-app",
+		Account:              "caller-account",
+		ID:                   "caller-id",
+		TransactionID:        "caller-parent-id",
+		TransportDuration:    2 * time.Second,
+		HasNewRelicTraceInfo: true,
+	}
+	e.BetterCAT.TransportType = "HTTP"
+	return e
+}
+
+func TestTxnEventMarshalTraceIntrinsics(t *testing.T) {
+	e := sampleTxnEventWithInbound()
+	e.DTIntrinsics = DistributedTracingIntrinsicsTrace
+	testTxnEventJSON(t, &e, `[
+	{
+		"type":"Transaction",
+		"name":"myName",
+		"timestamp":1488393111000,
+		"error":false,
+		"duration":2,
+		"totalTime":3,
+		"traceId":"trace-id",
+		"priority":0.500000,
+		"sampled":false
+	},
+	{},
+	{}]`)
+}
+
+func TestTxnEventMarshalNoIntrinsics(t *testing.T) {
+	e := sampleTxnEventWithInbound()
+	e.DTIntrinsics = DistributedTracingIntrinsicsNone
+	testTxnEventJSON(t, &e, `[
+	{
+		"type":"Transaction",
+		"name":"myName",
+		"timestamp":1488393111000,
+		"error":false,
+		"duration":2,
+		"totalTime":3
+	},
+	{},
+	{}]`)
+}
+
+func TestTxnEventIntrinsicsErrorEventUnaffected(t *testing.T) {
+	replyfn := func(reply *internal.ConnectReply) {
+		distributedTracingReplyFields(reply)
+		reply.SetSampleEverything()
+	}
+	cfgfn := func(cfg *Config) {
+		enableBetterCAT(cfg)
+		cfg.TransactionEvents.DistributedTracingIntrinsics = DistributedTracingIntrinsicsNone
+	}
+	app := testApp(replyfn, cfgfn, t)
+	txn := app.StartTransaction("hello")
+	txn.NoticeError(myError{})
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"name":  "OtherTransaction/Go/hello",
+			"error": true,
+		},
+	}})
+	app.ExpectErrorEvents(t, []internal.WantEvent{{
+		Intrinsics: map[string]interface{}{
+			"error.class":     "newrelic.myError",
+			"error.message":   "my msg",
+			"transactionName": "OtherTransaction/Go/hello",
+			"sampled":         true,
+			"priority":        internal.MatchAnything,
+			"guid":            internal.MatchAnything,
+			"traceId":         internal.MatchAnything,
+			"spanId":          internal.MatchAnything,
+		},
+	}})
+}
@@ -31,19 +31,7 @@ func (e *txnEvent) WriteJSON(buf *bytes.Buffer) {
 	w.floatField("totalTime", e.TotalTime.Seconds())
 
 	// Write better CAT intrinsics if enabled
-	sharedBetterCATIntrinsics(e, &w)
-
-	if e.BetterCAT.Enabled {
-		if p := e.BetterCAT.Inbound; nil != p {
-			if "" != p.TransactionID {
-				w.stringField("parentId", p.TransactionID)
-			}
-
-			if "" != p.ID {
-				w.stringField("parentSpanId", p.ID)
-			}
-		}
-	}
+	txnEventBetterCATIntrinsics(e, &w)
 
 	// Write old CAT intrinsics if enabled
 	oldCATIntrinsics(e, &w)
@@ -11,6 +11,7 @@ import (
 	"strconv"
 	"strings"
 	"time"
+	"unicode/utf8"
 )
 
 // jsonString assists in logging JSON:  Based on the formatter used to log
@@ -65,6 +66,53 @@ func getContentLengthFromHeader(h http.Header) int64 {
 	return -1
 }
 
+// invalidRune returns true for the result of decoding invalid UTF-8 and for
+// control characters other than tab, newline, and carriage return.
+func invalidRune(r rune, size int) bool {
+	switch {
+	case utf8.RuneError == r && 1 == size:
+		return true
+	case r < 0x20:
+		return '\t' != r && '\n' != r && '\r' != r
+	default:
+		// DEL and the C1 control characters.
+		return r >= 0x7f && r <= 0x9f
+	}
+}
+
+// isValidString returns false if the string contains invalid UTF-8 or
+// control characters other than tab, newline, and carriage return.
+func isValidString(str string) bool {
+	for i := 0; i < len(str); {
+		if b := str[i]; b < utf8.RuneSelf && b >= 0x20 && b < 0x7f {
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if invalidRune(r, size) {
+			return false
+		}
+		i += size
+	}
+	return true
+}
+
+// coerceString replaces invalid UTF-8 and control characters other than
+// tab, newline, and carriage return with the Unicode replacement character.
+func coerceString(str string) string {
+	buf := make([]byte, 0, len(str)+2*utf8.UTFMax)
+	for i := 0; i < len(str); {
+		r, size := utf8.DecodeRuneInString(str[i:])
+		if invalidRune(r, size) {
+			buf = append(buf, string(utf8.RuneError)...)
+		} else {
+			buf = append(buf, str[i:i+size]...)
+		}
+		i += size
+	}
+	return string(buf)
+}
+
 // stringLengthByteLimit truncates strings using a byte-limit boundary and
 // avoids terminating in the middle of a multibyte character.
 func stringLengthByteLimit(str string, byteLimit int) string {
@@ -78,6 +78,31 @@ func TestGetContentLengthFromHeader(t *testing.T) {
 	}
 }
 
+func TestCoerceString(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{input: "", expect: ""},
+		{input: "hello world", expect: "hello world"},
+		{input: "tab\tnewline\nreturn\r", expect: "tab\tnewline\nreturn\r"},
+		{input: "\u65e5\u672c\u8a9e", expect: "\u65e5\u672c\u8a9e"},
+		{input: "nul\x00", expect: "nul\ufffd"},
+		{input: "del\x7f", expect: "del\ufffd"},
+		{input: "c1\u0085", expect: "c1\ufffd"},
+		{input: "bad\xff\xfebytes", expect: "bad\ufffd\ufffdbytes"},
+		{input: "cut\xe6\x97", expect: "cut\ufffd\ufffd"},
+	}
+	for _, tc := range testcases {
+		if valid := isValidString(tc.input); valid != (tc.input == tc.expect) {
+			t.Errorf("%q: %t", tc.input, valid)
+		}
+		if out := coerceString(tc.input); out != tc.expect {
+			t.Errorf("%q: %q", tc.input, out)
+		}
+	}
+}
+
 func TestStringLengthByteLimit(t *testing.T) {
 	testcases := []struct {
 		input  string
@@ -0,0 +1,112 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sync"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal/utilization"
+)
+
+// utilizationCache gathers utilization data in the background so that
+// connecting does not wait for slow cloud provider metadata endpoints, and
+// keeps the data for Config.Utilization.CacheTTL.
+type utilizationCache struct {
+	gather func() *utilization.Data
+	ttl    time.Duration
+	// wait is the longest get waits for gathering to complete before
+	// returning the data of partial instead.  If it is zero, get waits
+	// until gathering completes.
+	wait    time.Duration
+	partial func() *utilization.Data
+
+	sync.Mutex
+	data     *utilization.Data
+	gathered time.Time
+	// used is true once data has been returned by get.  Data which has
+	// not been used is returned even when ttl is zero, so that the data
+	// gathered when the application is created is used by the first
+	// connect.
+	used bool
+	// pending is closed when the gathering in progress completes.  It is
+	// nil when no gathering is in progress.
+	pending chan struct{}
+}
+
+func newUtilizationCache(ttl, wait time.Duration, gather, partial func() *utilization.Data) *utilizationCache {
+	return &utilizationCache{
+		gather:  gather,
+		ttl:     ttl,
+		wait:    wait,
+		partial: partial,
+	}
+}
+
+// startLocked begins gathering in the background unless gathering is
+// already in progress, and returns the channel closed when it completes.
+// It must be called with the lock held.
+func (u *utilizationCache) startLocked() chan struct{} {
+	if nil == u.pending {
+		done := make(chan struct{})
+		u.pending = done
+		go func() {
+			data := u.gather()
+			u.Lock()
+			u.data = data
+			u.gathered = time.Now()
+			u.used = false
+			u.pending = nil
+			u.Unlock()
+			close(done)
+		}()
+	}
+	return u.pending
+}
+
+// start begins gathering in the background.
+func (u *utilizationCache) start() {
+	u.Lock()
+	defer u.Unlock()
+
+	u.startLocked()
+}
+
+// get returns the utilization data.  Unused data, and data gathered within
+// the ttl, is returned immediately.  If the ttl is positive, expired data is
+// also returned immediately while fresh data is gathered in the background.
+// Otherwise get waits for fresh data to be gathered, for at most the wait,
+// after which the partial data is returned.  The data gathered is then
+// returned by the next get.
+func (u *utilizationCache) get() *utilization.Data {
+	u.Lock()
+	if nil != u.data && (!u.used || u.ttl > 0) {
+		if u.ttl > 0 && time.Since(u.gathered) >= u.ttl {
+			u.startLocked()
+		}
+		u.used = true
+		data := u.data
+		u.Unlock()
+		return data
+	}
+	done := u.startLocked()
+	u.Unlock()
+
+	if u.wait > 0 {
+		timer := time.NewTimer(u.wait)
+		defer timer.Stop()
+		select {
+		case <-done:
+		case <-timer.C:
+			return u.partial()
+		}
+	} else {
+		<-done
+	}
+
+	u.Lock()
+	defer u.Unlock()
+	u.used = true
+	return u.data
+}
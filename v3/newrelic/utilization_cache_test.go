@@ -0,0 +1,126 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/newrelic/go-agent/v3/internal/utilization"
+)
+
+func countingGather(count *int32) func() *utilization.Data {
+	return func() *utilization.Data {
+		n := atomic.AddInt32(count, 1)
+		return &utilization.Data{MetadataVersion: int(n)}
+	}
+}
+
+func TestUtilizationCacheStartUsedByFirstGet(t *testing.T) {
+	var count int32
+	u := newUtilizationCache(0, 0, countingGather(&count), nil)
+	u.start()
+	if d := u.get(); 1 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+	// Without a ttl, each later get gathers fresh data.
+	if d := u.get(); 2 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+	if n := atomic.LoadInt32(&count); 2 != n {
+		t.Error(n)
+	}
+}
+
+func TestUtilizationCacheTTL(t *testing.T) {
+	var count int32
+	u := newUtilizationCache(time.Hour, 0, countingGather(&count), nil)
+	for i := 0; i < 3; i++ {
+		if d := u.get(); 1 != d.MetadataVersion {
+			t.Error(i, d.MetadataVersion)
+		}
+	}
+	if n := atomic.LoadInt32(&count); 1 != n {
+		t.Error(n)
+	}
+}
+
+func TestUtilizationCacheExpired(t *testing.T) {
+	var count int32
+	release := make(chan struct{})
+	u := newUtilizationCache(time.Hour, 0, func() *utilization.Data {
+		if 2 == atomic.AddInt32(&count, 1) {
+			<-release
+		}
+		return &utilization.Data{MetadataVersion: int(atomic.LoadInt32(&count))}
+	}, nil)
+	u.get()
+	u.Lock()
+	u.gathered = time.Now().Add(-2 * time.Hour)
+	u.Unlock()
+	// The expired data is returned without waiting for the refresh.
+	if d := u.get(); 1 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+	close(release)
+	u.Lock()
+	done := u.pending
+	u.Unlock()
+	if nil != done {
+		<-done
+	}
+	if d := u.get(); 2 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+}
+
+func TestUtilizationCachePartialAfterWait(t *testing.T) {
+	release := make(chan struct{})
+	u := newUtilizationCache(0, 10*time.Millisecond, func() *utilization.Data {
+		<-release
+		return &utilization.Data{MetadataVersion: 1}
+	}, func() *utilization.Data {
+		return &utilization.Data{MetadataVersion: 2}
+	})
+	u.start()
+	if d := u.get(); 2 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+	close(release)
+	u.Lock()
+	done := u.pending
+	u.Unlock()
+	if nil != done {
+		<-done
+	}
+	// The complete data is used by the next get.
+	if d := u.get(); 1 != d.MetadataVersion {
+		t.Error(d.MetadataVersion)
+	}
+}
+
+func TestUtilizationWait(t *testing.T) {
+	cfg := config{Config: defaultConfig()}
+	if w := cfg.utilizationWait(); time.Second != w {
+		t.Error(w)
+	}
+	cfg.Utilization.Timeout = 0
+	cfg.Utilization.Retries = 2
+	if w := cfg.utilizationWait(); 3*time.Second != w {
+		t.Error(w)
+	}
+}
+
+func TestNewAppStartsGatheringUtilization(t *testing.T) {
+	app := shortLivedApp(t, &collectorSender{})
+	defer app.Shutdown(10 * time.Second)
+	u := app.app.config.utilization
+	u.Lock()
+	started := nil != u.pending || nil != u.data
+	u.Unlock()
+	if !started {
+		t.Error("utilization not gathered when the application was created")
+	}
+}
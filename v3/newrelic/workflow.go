@@ -0,0 +1,128 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// The members of the W3C baggage header which propagate the workflow of a
+// transaction.  The step is that of the transaction which inserted the
+// header.
+const (
+	workflowBaggageID   = "newrelic.workflow.id"
+	workflowBaggageStep = "newrelic.workflow.step"
+)
+
+var (
+	errWorkflowIDEmpty = errors.New("workflow ID is empty")
+	errWorkflowStep    = errors.New("workflow step must not be negative")
+)
+
+func (txn *txn) SetWorkflow(id string, step int) error {
+	txn.Lock()
+	defer txn.Unlock()
+
+	if txn.finished {
+		return errAlreadyEnded
+	}
+	if "" == id {
+		return errWorkflowIDEmpty
+	}
+	if step < 0 {
+		return errWorkflowStep
+	}
+	txn.setWorkflowLocked(id, step)
+	return nil
+}
+
+func (txn *txn) setWorkflowLocked(id string, step int) {
+	txn.workflowID = id
+	txn.workflowStep = step
+	txn.Attrs.Agent.Add(AttributeWorkflowID, id, nil)
+	txn.Attrs.Agent.Add(AttributeWorkflowStep, "", step)
+}
+
+// escapeBaggageValue percent-encodes a baggage member's value.  Spaces are
+// encoded as %20 rather than +, which baggage does not treat as a space.
+func escapeBaggageValue(s string) string {
+	return strings.Replace(url.QueryEscape(s), "+", "%20", -1)
+}
+
+func unescapeBaggageValue(s string) (string, error) {
+	return url.QueryUnescape(strings.Replace(s, "+", "%2B", -1))
+}
+
+// baggageMemberKey returns the key of a baggage list member, which has the
+// form "key=value;property".
+func baggageMemberKey(member string) string {
+	if idx := strings.IndexByte(member, '='); idx >= 0 {
+		member = member[:idx]
+	}
+	return strings.TrimSpace(member)
+}
+
+// insertWorkflowBaggage adds the transaction's workflow to the baggage
+// header.  The other members of the header are kept.
+func (txn *txn) insertWorkflowBaggage(hdrs http.Header) {
+	if "" == txn.workflowID {
+		return
+	}
+	var members []string
+	for _, value := range hdrs[DistributedTraceW3CBaggageHeader] {
+		for _, member := range strings.Split(value, ",") {
+			switch baggageMemberKey(member) {
+			case "", workflowBaggageID, workflowBaggageStep:
+				continue
+			}
+			members = append(members, strings.TrimSpace(member))
+		}
+	}
+	members = append(members,
+		workflowBaggageID+"="+escapeBaggageValue(txn.workflowID),
+		workflowBaggageStep+"="+strconv.Itoa(txn.workflowStep))
+	hdrs.Set(DistributedTraceW3CBaggageHeader, strings.Join(members, ","))
+}
+
+// acceptWorkflowBaggage joins the transaction to the workflow found in the
+// baggage header as the step after the step of the caller.  It does nothing
+// if the transaction's workflow has already been set.
+func (txn *txn) acceptWorkflowBaggage(hdrs http.Header) {
+	if "" != txn.workflowID {
+		return
+	}
+	var id string
+	step := -1
+	for _, value := range hdrs[DistributedTraceW3CBaggageHeader] {
+		for _, member := range strings.Split(value, ",") {
+			idx := strings.IndexByte(member, '=')
+			if idx < 0 {
+				continue
+			}
+			val := member[idx+1:]
+			if semi := strings.IndexByte(val, ';'); semi >= 0 {
+				val = val[:semi]
+			}
+			val = strings.TrimSpace(val)
+			switch baggageMemberKey(member) {
+			case workflowBaggageID:
+				if v, err := unescapeBaggageValue(val); nil == err {
+					id = v
+				}
+			case workflowBaggageStep:
+				if v, err := strconv.Atoi(val); nil == err && v >= 0 {
+					step = v
+				}
+			}
+		}
+	}
+	if "" == id {
+		return
+	}
+	txn.setWorkflowLocked(id, step+1)
+}
@@ -0,0 +1,78 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/newrelic/go-agent/v3/internal"
+)
+
+func TestWorkflowPropagation(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("step0")
+	txn.SetWorkflow("order 42+1", 0)
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CBaggageHeader, "other=1;prop, newrelic.workflow.step=9")
+	txn.InsertDistributedTraceHeaders(hdrs)
+	txn.End()
+	expect := "other=1;prop,newrelic.workflow.id=order%2042%2B1,newrelic.workflow.step=0"
+	if baggage := hdrs.Get(DistributedTraceW3CBaggageHeader); baggage != expect {
+		t.Error(baggage)
+	}
+
+	txn = app.StartTransaction("step1")
+	txn.AcceptDistributedTraceHeaders(TransportQueue, hdrs)
+	txn.End()
+
+	txn = app.StartTransaction("unrelated")
+	txn.AcceptDistributedTraceHeaders(TransportQueue, http.Header{})
+	txn.End()
+	app.expectNoLoggedErrors(t)
+
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"workflow.id":   "order 42+1",
+			"workflow.step": 0,
+		},
+	}, {
+		AgentAttributes: map[string]interface{}{
+			"workflow.id":   "order 42+1",
+			"workflow.step": 1,
+		},
+	}, {
+		AgentAttributes: map[string]interface{}{},
+	}})
+}
+
+func TestWorkflowSetOverridesBaggage(t *testing.T) {
+	app := testApp(distributedTracingReplyFields, enableBetterCAT, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWorkflow("mine", 3)
+	hdrs := http.Header{}
+	hdrs.Set(DistributedTraceW3CBaggageHeader, "newrelic.workflow.id=theirs,newrelic.workflow.step=1")
+	txn.AcceptDistributedTraceHeaders(TransportHTTP, hdrs)
+	txn.End()
+	app.expectNoLoggedErrors(t)
+	app.ExpectTxnEvents(t, []internal.WantEvent{{
+		AgentAttributes: map[string]interface{}{
+			"workflow.id":   "mine",
+			"workflow.step": 3,
+		},
+	}})
+}
+
+func TestSetWorkflowInvalid(t *testing.T) {
+	app := testApp(nil, nil, t)
+	txn := app.StartTransaction("hello")
+	txn.SetWorkflow("", 0)
+	app.expectSingleLoggedError(t, "unable to set workflow", map[string]interface{}{
+		"reason": errWorkflowIDEmpty.Error(),
+	})
+	txn.End()
+
+	var nilTxn *Transaction
+	nilTxn.SetWorkflow("id", 0)
+}
@@ -0,0 +1,420 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var yamlNumberRegex = regexp.MustCompile(`^-?(0|[1-9][0-9]*)(\.[0-9]+)?([eE][-+]?[0-9]+)?$`)
+
+// parseYAML parses the subset of YAML used by configuration files into the
+// values produced by encoding/json when numbers are decoded using UseNumber:
+// map[string]interface{}, []interface{}, string, bool, json.Number, and nil.
+// Block mappings, block sequences, flow collections, quoted and plain
+// scalars, and comments are supported.  Anchors, tags, multi-document files,
+// and multi-line scalars are not.
+func parseYAML(data []byte) (interface{}, error) {
+	var lines []yamlLine
+	for i, text := range strings.Split(string(data), "\n") {
+		text = strings.TrimRight(stripYAMLComment(text), " \t\r")
+		trimmed := strings.TrimLeft(text, " ")
+		if "" == trimmed || (0 == len(lines) && "---" == trimmed) {
+			continue
+		}
+		if strings.HasPrefix(trimmed, "\t") {
+			return nil, fmt.Errorf("line %d: tabs cannot be used for indentation", i+1)
+		}
+		if "---" == trimmed || "..." == trimmed {
+			return nil, fmt.Errorf("line %d: multiple documents are not supported", i+1)
+		}
+		lines = append(lines, yamlLine{
+			num:    i + 1,
+			indent: len(text) - len(trimmed),
+			text:   trimmed,
+		})
+	}
+	if 0 == len(lines) {
+		return nil, nil
+	}
+	p := &yamlParser{lines: lines}
+	v, err := p.parseNode(0)
+	if nil != err {
+		return nil, err
+	}
+	if p.pos < len(p.lines) {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return v, nil
+}
+
+type yamlLine struct {
+	num    int
+	indent int
+	text   string
+}
+
+type yamlParser struct {
+	lines []yamlLine
+	pos   int
+}
+
+func (p *yamlParser) errorf(format string, args ...interface{}) error {
+	num := p.lines[len(p.lines)-1].num
+	if p.pos < len(p.lines) {
+		num = p.lines[p.pos].num
+	}
+	return fmt.Errorf("line %d: %s", num, fmt.Sprintf(format, args...))
+}
+
+// stripYAMLComment removes a comment, which begins with a '#' at the start of
+// the line or after whitespace, outside of quoted scalars.
+func stripYAMLComment(text string) string {
+	var quote byte
+	for i := 0; i < len(text); i++ {
+		c := text[i]
+		switch {
+		case 0 != quote:
+			if '\\' == c && '"' == quote {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case '"' == c || '\'' == c:
+			// Quotes only begin a scalar at the start of a token, so
+			// that apostrophes in plain scalars are allowed.
+			if prev := strings.TrimRight(text[:i], " "); "" == prev || strings.IndexByte(":-[{,", prev[len(prev)-1]) >= 0 {
+				quote = c
+			}
+		case '#' == c:
+			if 0 == i || ' ' == text[i-1] || '\t' == text[i-1] {
+				return text[:i]
+			}
+		}
+	}
+	return text
+}
+
+func isYAMLSequenceItem(text string) bool {
+	return "-" == text || strings.HasPrefix(text, "- ")
+}
+
+// yamlMappingColon returns the index of the colon separating the key of a
+// mapping entry from its value, or -1 if the text is not a mapping entry.
+func yamlMappingColon(text string) int {
+	if strings.HasPrefix(text, "[") || strings.HasPrefix(text, "{") {
+		return -1
+	}
+	i := 0
+	if strings.HasPrefix(text, `"`) || strings.HasPrefix(text, "'") {
+		_, n, err := parseYAMLQuoted(text)
+		if nil != err {
+			return -1
+		}
+		i = n
+		for i < len(text) && ' ' == text[i] {
+			i++
+		}
+		if i < len(text) && ':' == text[i] && (i+1 == len(text) || ' ' == text[i+1]) {
+			return i
+		}
+		return -1
+	}
+	for ; i < len(text); i++ {
+		if ':' == text[i] && (i+1 == len(text) || ' ' == text[i+1]) {
+			return i
+		}
+	}
+	return -1
+}
+
+// parseNode parses the node beginning at the current line, which must be
+// indented by at least minIndent.
+func (p *yamlParser) parseNode(minIndent int) (interface{}, error) {
+	if p.pos >= len(p.lines) || p.lines[p.pos].indent < minIndent {
+		return nil, nil
+	}
+	line := p.lines[p.pos]
+	if isYAMLSequenceItem(line.text) {
+		return p.parseSequence(line.indent)
+	}
+	if yamlMappingColon(line.text) >= 0 {
+		return p.parseMapping(line.indent)
+	}
+	p.pos++
+	v, err := parseYAMLValue(line.text)
+	if nil != err {
+		p.pos--
+		return nil, p.errorf("%v", err)
+	}
+	return v, nil
+}
+
+func (p *yamlParser) parseSequence(indent int) (interface{}, error) {
+	seq := []interface{}{}
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text) {
+		line := p.lines[p.pos]
+		rest := strings.TrimLeft(line.text[1:], " ")
+		if "" == rest {
+			p.pos++
+			item, err := p.parseNode(indent + 1)
+			if nil != err {
+				return nil, err
+			}
+			seq = append(seq, item)
+			continue
+		}
+		// The item begins on the same line as the dash.  Replacing the
+		// line with the remainder, at its own indentation, allows a
+		// mapping item to continue on the following lines.
+		p.lines[p.pos] = yamlLine{
+			num:    line.num,
+			indent: line.indent + len(line.text) - len(rest),
+			text:   rest,
+		}
+		item, err := p.parseNode(indent + 1)
+		if nil != err {
+			return nil, err
+		}
+		seq = append(seq, item)
+	}
+	if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+		return nil, p.errorf("unexpected indentation")
+	}
+	return seq, nil
+}
+
+func (p *yamlParser) parseMapping(indent int) (interface{}, error) {
+	m := make(map[string]interface{})
+	for p.pos < len(p.lines) && p.lines[p.pos].indent == indent {
+		text := p.lines[p.pos].text
+		if isYAMLSequenceItem(text) {
+			return nil, p.errorf("unexpected sequence item")
+		}
+		colon := yamlMappingColon(text)
+		if colon < 0 {
+			return nil, p.errorf("expected a mapping entry")
+		}
+		key := strings.TrimSpace(text[:colon])
+		if strings.HasPrefix(key, `"`) || strings.HasPrefix(key, "'") {
+			key, _, _ = parseYAMLQuoted(key)
+		}
+		if _, ok := m[key]; ok {
+			return nil, p.errorf("duplicate key %q", key)
+		}
+		value := strings.TrimSpace(text[colon+1:])
+		if strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">") {
+			return nil, p.errorf("multi-line scalars are not supported")
+		}
+		if strings.HasPrefix(value, "&") || strings.HasPrefix(value, "*") || strings.HasPrefix(value, "!") {
+			return nil, p.errorf("anchors, aliases, and tags are not supported")
+		}
+		p.pos++
+		if "" != value {
+			v, err := parseYAMLValue(value)
+			if nil != err {
+				p.pos--
+				return nil, p.errorf("%v", err)
+			}
+			m[key] = v
+			if p.pos < len(p.lines) && p.lines[p.pos].indent > indent {
+				return nil, p.errorf("unexpected indentation")
+			}
+			continue
+		}
+		switch {
+		case p.pos < len(p.lines) && p.lines[p.pos].indent > indent:
+			v, err := p.parseNode(indent + 1)
+			if nil != err {
+				return nil, err
+			}
+			m[key] = v
+		case p.pos < len(p.lines) && p.lines[p.pos].indent == indent && isYAMLSequenceItem(p.lines[p.pos].text):
+			// Sequences which are mapping values may have the
+			// same indentation as the key.
+			v, err := p.parseSequence(indent)
+			if nil != err {
+				return nil, err
+			}
+			m[key] = v
+		default:
+			m[key] = nil
+		}
+	}
+	return m, nil
+}
+
+// parseYAMLValue parses a value which occupies the rest of a line:  a flow
+// collection, a quoted scalar, or a plain scalar.
+func parseYAMLValue(text string) (interface{}, error) {
+	switch text[0] {
+	case '[', '{':
+		f := &yamlFlow{text: text}
+		v, err := f.parse()
+		if nil != err {
+			return nil, err
+		}
+		f.skipSpace()
+		if f.pos < len(f.text) {
+			return nil, fmt.Errorf("unexpected %q after flow collection", f.text[f.pos:])
+		}
+		return v, nil
+	case '"', '\'':
+		s, n, err := parseYAMLQuoted(text)
+		if nil != err {
+			return nil, err
+		}
+		if "" != strings.TrimSpace(text[n:]) {
+			return nil, fmt.Errorf("unexpected %q after quoted scalar", text[n:])
+		}
+		return s, nil
+	}
+	return yamlPlainScalar(text), nil
+}
+
+// parseYAMLQuoted parses the quoted scalar at the start of text.  It returns
+// the string and the number of bytes consumed.
+func parseYAMLQuoted(text string) (string, int, error) {
+	quote := text[0]
+	for i := 1; i < len(text); i++ {
+		switch {
+		case '\\' == text[i] && '"' == quote:
+			i++
+		case '\'' == quote && '\'' == text[i] && i+1 < len(text) && '\'' == text[i+1]:
+			i++
+		case text[i] == quote:
+			if '\'' == quote {
+				return strings.Replace(text[1:i], "''", "'", -1), i + 1, nil
+			}
+			s, err := strconv.Unquote(text[:i+1])
+			if nil != err {
+				return "", 0, fmt.Errorf("invalid quoted scalar %s", text[:i+1])
+			}
+			return s, i + 1, nil
+		}
+	}
+	return "", 0, fmt.Errorf("unterminated quoted scalar %s", text)
+}
+
+// yamlPlainScalar resolves an unquoted scalar using the YAML 1.2 core schema.
+func yamlPlainScalar(s string) interface{} {
+	switch s {
+	case "", "~", "null", "Null", "NULL":
+		return nil
+	case "true", "True", "TRUE":
+		return true
+	case "false", "False", "FALSE":
+		return false
+	}
+	if yamlNumberRegex.MatchString(s) {
+		// The number is kept as text so that values such as licenses
+		// are not altered when decoded into string fields.
+		return json.Number(s)
+	}
+	return s
+}
+
+// yamlFlow parses flow collections, eg. "[a, b]" and "{a: 1, b: 2}".
+type yamlFlow struct {
+	text string
+	pos  int
+}
+
+func (f *yamlFlow) skipSpace() {
+	for f.pos < len(f.text) && ' ' == f.text[f.pos] {
+		f.pos++
+	}
+}
+
+func (f *yamlFlow) parse() (interface{}, error) {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return nil, fmt.Errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case '[':
+		f.pos++
+		seq := []interface{}{}
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && ']' == f.text[f.pos] {
+				f.pos++
+				return seq, nil
+			}
+			v, err := f.parse()
+			if nil != err {
+				return nil, err
+			}
+			seq = append(seq, v)
+			if err := f.separator(']'); nil != err {
+				return nil, err
+			}
+		}
+	case '{':
+		f.pos++
+		m := make(map[string]interface{})
+		for {
+			f.skipSpace()
+			if f.pos < len(f.text) && '}' == f.text[f.pos] {
+				f.pos++
+				return m, nil
+			}
+			k, err := f.parse()
+			if nil != err {
+				return nil, err
+			}
+			key, ok := k.(string)
+			if !ok {
+				key = fmt.Sprint(k)
+			}
+			f.skipSpace()
+			if f.pos >= len(f.text) || ':' != f.text[f.pos] {
+				return nil, fmt.Errorf("expected ':' after flow mapping key %q", key)
+			}
+			f.pos++
+			v, err := f.parse()
+			if nil != err {
+				return nil, err
+			}
+			m[key] = v
+			if err := f.separator('}'); nil != err {
+				return nil, err
+			}
+		}
+	case '"', '\'':
+		s, n, err := parseYAMLQuoted(f.text[f.pos:])
+		if nil != err {
+			return nil, err
+		}
+		f.pos += n
+		return s, nil
+	}
+	start := f.pos
+	for f.pos < len(f.text) && strings.IndexByte(",]}", f.text[f.pos]) < 0 &&
+		!(':' == f.text[f.pos] && (f.pos+1 == len(f.text) || ' ' == f.text[f.pos+1])) {
+		f.pos++
+	}
+	return yamlPlainScalar(strings.TrimSpace(f.text[start:f.pos])), nil
+}
+
+// separator consumes the comma between the entries of a flow collection, or
+// the closing character, which is left for the caller.
+func (f *yamlFlow) separator(end byte) error {
+	f.skipSpace()
+	if f.pos >= len(f.text) {
+		return fmt.Errorf("unterminated flow collection")
+	}
+	switch f.text[f.pos] {
+	case ',':
+		f.pos++
+		return nil
+	case end:
+		return nil
+	}
+	return fmt.Errorf("unexpected %q in flow collection", f.text[f.pos:])
+}
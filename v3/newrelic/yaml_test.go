@@ -0,0 +1,64 @@
+// Copyright 2020 New Relic Corporation. All rights reserved.
+// SPDX-License-Identifier: Apache-2.0
+
+package newrelic
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseYAML(t *testing.T) {
+	testcases := []struct {
+		input  string
+		expect string
+	}{
+		{input: "", expect: `null`},
+		{input: "# only a comment\n", expect: `null`},
+		{input: "hello", expect: `"hello"`},
+		{input: "a: 1\nb: true\nc: ~\nd: 1.5\ne: hello world\nf:", expect: `{"a":1,"b":true,"c":null,"d":1.5,"e":"hello world","f":null}`},
+		{input: "---\na: 1 # comment\n# comment\nb: \"x # y\"", expect: `{"a":1,"b":"x # y"}`},
+		{input: "a: Bob's app # comment", expect: `{"a":"Bob's app"}`},
+		{input: "a: 'it''s'\nb: \"tab\\tnewline\\n\"", expect: `{"a":"it's","b":"tab\tnewline\n"}`},
+		{input: "a: http://example.com:8080/path", expect: `{"a":"http://example.com:8080/path"}`},
+		{input: "\"quoted key\": 1", expect: `{"quoted key":1}`},
+		{input: "a:\n  b:\n    c: 1\n  d: 2\ne: 3", expect: `{"a":{"b":{"c":1},"d":2},"e":3}`},
+		{input: "a:\n  - 1\n  - two\nb:\n- x\n- y", expect: `{"a":[1,"two"],"b":["x","y"]}`},
+		{input: "rules:\n  - host: a.com\n    enabled: true\n  - host: b.com\n", expect: `{"rules":[{"enabled":true,"host":"a.com"},{"host":"b.com"}]}`},
+		{input: "- - 1\n  - 2\n- 3", expect: `[[1,2],3]`},
+		{input: "-\n  a: 1", expect: `[{"a":1}]`},
+		{input: "a: [1, \"two, three\", [x]]\nb: {c: 1, d: [e]}\nc: []\nd: {}", expect: `{"a":[1,"two, three",["x"]],"b":{"c":1,"d":["e"]},"c":[],"d":{}}`},
+	}
+	for _, tc := range testcases {
+		v, err := parseYAML([]byte(tc.input))
+		if nil != err {
+			t.Error(tc.input, err)
+			continue
+		}
+		js, _ := json.Marshal(v)
+		if string(js) != tc.expect {
+			t.Errorf("input=%q got=%s expect=%s", tc.input, js, tc.expect)
+		}
+	}
+}
+
+func TestParseYAMLErrors(t *testing.T) {
+	testcases := []string{
+		"a: 1\n  b: 2",
+		"a:\n\tb: 1",
+		"a: 1\na: 2",
+		"a: |\n  text",
+		"a: &anchor 1",
+		"a: [1, 2",
+		"a: {b 1}",
+		"a: \"unterminated",
+		"a: 1\n- b",
+		"a: 1\n---\nb: 2",
+		"a:\n  b: 1\n c: 2",
+	}
+	for _, input := range testcases {
+		if v, err := parseYAML([]byte(input)); nil == err {
+			t.Errorf("input=%q expected error, got %v", input, v)
+		}
+	}
+}